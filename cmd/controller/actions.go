@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/Zhichengu1/mock-control-plane/pkg/provider"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// DEVICE ACTIONS
+// =============================================================================
+// Operators need more than CRUD during a show: restart an encoder that
+// froze, reboot a camera, take a feed off air without stopping the device.
+//
+//   GET  /resources/{id}/actions   → actions the resource's vendor supports
+//   POST /resources/{id}/actions   {"action": "restart"}
+//
+// Actions are restart, reboot, start_stream and stop_stream (see
+// models.DeviceActions), run through the vendor's optional
+// provider.ProviderAction. Like :stop and :start they respect locks,
+// If-Match and maintenance windows (?urgent=true runs one anyway), and the
+// device's resulting status is stored and returned.
+// =============================================================================
+
+// actionsTarget resolves the ProviderAction for resource id.
+func (c *Controller) actionsTarget(id string) (provider.ProviderAction, string, error) {
+	c.mu.RLock()
+	stored, exists := c.ResourceDB[id]
+	var vendorType string
+	if exists {
+		vendorType = stored.Spec.VendorType
+	}
+	c.mu.RUnlock()
+	if !exists {
+		return nil, "", errResourceNotFound
+	}
+	selectedProvider, exists := c.config().Providers[vendorType]
+	if !exists {
+		return nil, "", fmt.Errorf("provider %s not configured", vendorType)
+	}
+	actions, supported := selectedProvider.(provider.ProviderAction)
+	if !supported {
+		return nil, "", fmt.Errorf("device actions: %w (%s)", errUnsupported, vendorType)
+	}
+	return actions, vendorType, nil
+}
+
+// runAction runs action on resource id and stores the device's resulting
+// status. detail is appended to the event message.
+func (c *Controller) runAction(parent context.Context, id, action, detail string) (*models.ForgeResource, error) {
+	actions, vendorType, err := c.actionsTarget(id)
+	if err != nil {
+		return nil, err
+	}
+	if !containsString(actions.Actions(), action) {
+		return nil, fmt.Errorf("%s: %w (%s)", action, errUnsupported, vendorType)
+	}
+
+	c.mu.RLock()
+	stored, exists := c.ResourceDB[id]
+	var vendorID, phase string
+	var lockErr error
+	if exists {
+		vendorID, phase = stored.Status.VendorID, stored.Status.Phase
+		lockErr = c.lockConflictLocked(parent, id)
+		if lockErr == nil {
+			lockErr = c.preconditionLocked(parent, stored)
+		}
+	}
+	c.mu.RUnlock()
+	switch {
+	case !exists:
+		return nil, errResourceNotFound
+	case lockErr != nil:
+		return nil, lockErr
+	case vendorID == "":
+		return nil, errNoVendorDevice
+	case phase == phaseTerminating:
+		return nil, errResourceTerminating
+	}
+
+	ctx, cancel := context.WithTimeout(parent, 30*time.Second)
+	defer cancel()
+	release, err := c.acquireVendor(ctx, vendorType)
+	if err != nil {
+		return nil, err
+	}
+	status, err := actions.RunAction(ctx, vendorID, action)
+	release()
+	c.forgetVendorRead(vendorType, vendorID)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if stored, exists = c.ResourceDB[id]; !exists {
+		return nil, errResourceGone
+	}
+	if err != nil {
+		c.recordEvent(stored, models.EventWarning, models.ReasonActionFailed, fmt.Sprintf("Action %s failed%s: %v", action, detail, err), "", "")
+		return nil, err
+	}
+	oldStatus := stored.Status
+	stored.Status = *status
+	c.config().HealthPolicy.Apply(stored)
+	c.applyMaintenanceCondition(stored)
+	stored.UpdatedAt = c.Clock.Now()
+	c.recordRevision(stored, "action-"+action, false)
+	c.recordEvent(stored, models.EventNormal, models.ReasonActionRun, "Action "+action+detail, "", "")
+	c.recordStatusEvents(stored, oldStatus)
+	return stored.DeepCopy(), nil
+}
+
+// HandleListActions handles GET /resources/{id}/actions
+func (c *Controller) HandleListActions(w http.ResponseWriter, r *http.Request) {
+	actions, _, err := c.actionsTarget(mux.Vars(r)["id"])
+	if err != nil {
+		writeOperationError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"actions": actions.Actions()})
+}
+
+// HandleRunAction handles POST /resources/{id}/actions
+func (c *Controller) HandleRunAction(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var req models.ActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+	if !containsString(models.DeviceActions, req.Action) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "action must be one of: " + strings.Join(models.DeviceActions, ", ")})
+		return
+	}
+
+	detail := ""
+	if principal, ok := principalFrom(r.Context()); ok {
+		detail = " by " + principal.Name
+	}
+	if c.rejectIfLocked(w, r, id) || c.rejectIfPreconditionFailed(w, r, id) || c.deferForMaintenance(w, r, id, req.Action, detail, nil) {
+		return
+	}
+	res, err := c.runAction(vendorContext(r), id, req.Action, detail)
+	if err != nil {
+		writeOperationError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	setResourceETag(w, res)
+	redact := c.redactionFor(r)
+	redact.announce(w)
+	json.NewEncoder(w).Encode(redact.resource(res))
+}
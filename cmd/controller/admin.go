@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/logging"
+)
+
+// =============================================================================
+// ADMIN API - RUNTIME LOG LEVEL CONTROL
+// =============================================================================
+// These handlers let operators change log verbosity without restarting the
+// controller. Typical use: a single provider is misbehaving in production,
+// so an on-call engineer turns on debug logging for "providers" for 15
+// minutes, captures the vendor payloads, and the level reverts on its own.
+//
+// Endpoints:
+//   GET    /admin/loglevel                  → current levels and overrides
+//   PUT    /admin/loglevel                  → set a temporary override
+//   DELETE /admin/loglevel?component=name   → revert an override immediately
+// =============================================================================
+
+// maxLogLevelOverride caps how long a runtime override may last.
+// WHY A CAP: Debug logging is expensive; a typo like "100h" should not
+// leave production in debug mode for four days.
+const maxLogLevelOverride = 24 * time.Hour
+
+// LogLevelRequest is the body accepted by PUT /admin/loglevel.
+//
+// Example:
+//
+//	{"level": "debug", "component": "providers", "duration": "10m"}
+type LogLevelRequest struct {
+	// Level is the new level: "debug", "info", "warn", or "error".
+	Level string `json:"level"`
+
+	// Component limits the change to one component. Empty means all.
+	// Valid: "controller", "providers", "client", "reconciler"
+	Component string `json:"component,omitempty"`
+
+	// Duration is how long the override lasts before reverting, in Go
+	// duration syntax ("30s", "15m"). Defaults to the controller's
+	// configured LOG_LEVEL_OVERRIDE_TTL.
+	Duration string `json:"duration,omitempty"`
+}
+
+// HandleGetLogLevel returns the baseline level, effective level per
+// component, and all active overrides with their expiry times.
+func (c *Controller) HandleGetLogLevel(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(logging.Snapshot())
+}
+
+// HandleSetLogLevel applies a temporary log level override.
+func (c *Controller) HandleSetLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req LogLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+
+	level, err := logging.ParseLevel(req.Level)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	// WHY VALIDATE COMPONENT: A typo ("provider" vs "providers") would
+	// otherwise silently create an override nobody reads
+	if req.Component != "" && !logging.IsKnownComponent(req.Component) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "unknown component: " + req.Component})
+		return
+	}
+
+	ttl := c.LogOverrideTTL
+	if req.Duration != "" {
+		ttl, err = time.ParseDuration(req.Duration)
+		if err != nil || ttl <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "duration must be a positive Go duration like \"15m\""})
+			return
+		}
+	}
+	if ttl > maxLogLevelOverride {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "duration may not exceed " + maxLogLevelOverride.String()})
+		return
+	}
+
+	expiresAt := logging.Override(req.Component, level, ttl)
+	target := req.Component
+	if target == "" {
+		target = "all components"
+	}
+	logger.Infof("Log level for %s set to %s until %s", target, level, expiresAt.Format(time.RFC3339))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(logging.Snapshot())
+}
+
+// HandleResetLogLevel removes an override so the component reverts to the
+// baseline level right away instead of waiting for the TTL.
+func (c *Controller) HandleResetLogLevel(w http.ResponseWriter, r *http.Request) {
+	component := r.URL.Query().Get("component")
+	if component != "" && !logging.IsKnownComponent(component) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "unknown component: " + component})
+		return
+	}
+
+	if !logging.Reset(component) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "no active override"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(logging.Snapshot())
+}
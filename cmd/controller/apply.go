@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/Zhichengu1/mock-control-plane/pkg/validation"
+)
+
+// =============================================================================
+// DECLARATIVE APPLY (POST /resources:apply)
+// =============================================================================
+// A GitOps pipeline keeps each device as a manifest in a repository and
+// applies it on every commit. It doesn't know (or want to know) whether
+// the device exists yet, or its ID:
+//
+//   POST /resources:apply
+//   {"name": "cam-1", "type": "camera", "namespace": "studio-a",
+//    "labels": {"floor": "2"}, "spec": {"vendor_type": "sony", ...}}
+//
+// The resource is looked up by namespace and name:
+//
+//   - absent: it is created exactly like POST /resources (201)
+//   - present: the manifest is diffed against it and only what differs is
+//     changed, like a PATCH (200): the spec goes to the vendor through
+//     updateResourceSpec, metadata, labels, annotations and owner_ref are
+//     stored without a vendor call
+//   - present and identical: nothing is stored, no revision, no event (200)
+//
+// Forge-Applied says which (created, updated or unchanged). The manifest
+// is the whole desired state: labels and annotations it leaves out are
+// removed, and a spec without vendor_type keeps the current vendor. The
+// type can't change. Finalizers are only taken on create: they belong to
+// the systems that remove them (see finalizers.go), and a manifest
+// re-applied mid-teardown must not put them back.
+//
+// WHY NO IDEMPOTENCY KEY: Applying the same manifest twice is already
+// harmless; a concurrent apply of a new name fails the name's uniqueness
+// claim (409) and the retry finds it.
+// =============================================================================
+
+// HeaderApplied names what POST /resources:apply did.
+const HeaderApplied = "Forge-Applied"
+
+// Outcomes of an apply (in Forge-Applied).
+const (
+	appliedCreated   = "created"
+	appliedUpdated   = "updated"
+	appliedUnchanged = "unchanged"
+)
+
+// errApplyTerminating refuses an apply to a resource being deleted.
+var errApplyTerminating = errors.New("resource is being deleted; apply again once it is gone (or restore it)")
+
+// HandleApplyResource handles POST /resources:apply
+func (c *Controller) HandleApplyResource(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Step 1: Decode the manifest
+	var manifest models.ForgeResource
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxResourceBodyBytes)).Decode(&manifest); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+	duplicateStrategy := r.URL.Query().Get("onDuplicate")
+	if duplicateStrategy != "" && !validDuplicateStrategy(duplicateStrategy) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "onDuplicate must be one of: " + strings.Join(duplicateStrategies, ", ")})
+		return
+	}
+	if rejectBadCircuitOpenParam(w, r) {
+		return
+	}
+	if manifest.Name == "" {
+		writeCreateError(w, &createError{http.StatusBadRequest, "name is required"})
+		return
+	}
+	if ns := routeNamespace(r); ns != "" {
+		if manifest.Namespace != "" && namespaceKey(manifest.Namespace) != namespaceKey(ns) {
+			writeCreateError(w, &createError{http.StatusBadRequest, fmt.Sprintf("namespace %q doesn't match the route's %q", manifest.Namespace, ns)})
+			return
+		}
+		manifest.Namespace = ns
+	}
+
+	// Step 2: Find the resource by namespace and name
+	c.mu.RLock()
+	var current models.ForgeResource
+	exists := false
+	for _, res := range c.ResourceDB {
+		if res.Name == manifest.Name && namespaceKey(res.Namespace) == namespaceKey(manifest.Namespace) {
+			current, exists = *res.DeepCopy(), true
+			break
+		}
+	}
+	c.mu.RUnlock()
+
+	// Step 3: Absent - create it like POST /resources
+	if !exists {
+		if err := c.createResource(vendorContext(r), r, &manifest, duplicateStrategy); err != nil {
+			writeCreateError(w, err)
+			return
+		}
+		status := http.StatusCreated
+		if manifest.Status.Phase == phaseQueued {
+			status = http.StatusAccepted
+		}
+		logger.Infof("%s: applied (created %q)", manifest.ID, manifest.Name)
+		w.Header().Set(HeaderApplied, appliedCreated)
+		c.writeCreated(w, r, &manifest, status)
+		return
+	}
+
+	// Step 4: Present - diff the manifest against it
+	if current.Status.Phase == phaseTerminating {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": errApplyTerminating.Error()})
+		return
+	}
+	if manifest.Type != "" && manifest.Type != current.Type {
+		writeOperationError(w, validation.Violations{{Field: "type", Rule: "immutable",
+			Message: fmt.Sprintf("type can't be changed (is %q)", current.Type)}})
+		return
+	}
+	patched, err := c.diffManifest(&current, &manifest)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if len(patched.specChanged) == 0 && !patched.forgeOnly() {
+		w.Header().Set(HeaderApplied, appliedUnchanged)
+		setResourceETag(w, &current)
+		redact := c.redactionFor(r)
+		redact.announce(w)
+		json.NewEncoder(w).Encode(redact.resource(&current))
+		return
+	}
+
+	// Step 5: Store what differs, like a PATCH
+	if c.rejectIfLocked(w, r, current.ID) || c.rejectIfPreconditionFailed(w, r, current.ID) {
+		return
+	}
+	res, ok := c.savePatch(w, r, current.ID, current, patched, "applied")
+	if !ok {
+		return
+	}
+	w.Header().Set(HeaderApplied, appliedUpdated)
+	setResourceETag(w, res)
+	redact := c.redactionFor(r)
+	redact.announce(w)
+	json.NewEncoder(w).Encode(redact.resource(res))
+}
+
+// diffManifest compares manifest with current and returns the changes as
+// a patch of current (finalizers are never changed, see above).
+func (c *Controller) diffManifest(current, manifest *models.ForgeResource) (patchResult, error) {
+	result := patchResult{metadata: current.Metadata, labels: manifest.Labels, annotations: manifest.Annotations,
+		finalizers: current.Finalizers, ownerRef: manifest.OwnerRef}
+
+	// WHY THE OVERLAY: The stored spec has it merged (see profiles.go); the
+	// manifest must be compared as it would be stored
+	spec := manifest.Spec
+	if spec.VendorType == "" {
+		spec.VendorType = current.Spec.VendorType
+	}
+	spec, err := c.config().Profiles.apply(spec)
+	if err != nil {
+		return result, err
+	}
+	result.spec = validation.NormalizeNetwork(spec)
+	result.specChanged = changedKeys(specFields(current.Spec), specFields(result.spec))
+
+	if manifest.Metadata.Notes != current.Metadata.Notes {
+		result.metadata.Notes = manifest.Metadata.Notes
+		result.metaChanged = append(result.metaChanged, "notes")
+	}
+	if manifest.Metadata.RunbookURL != current.Metadata.RunbookURL {
+		result.metadata.RunbookURL = manifest.Metadata.RunbookURL
+		result.metaChanged = append(result.metaChanged, "runbook_url")
+	}
+	if len(result.labels) == 0 {
+		result.labels = nil
+	}
+	if len(result.annotations) == 0 {
+		result.annotations = nil
+	}
+	result.labelsChanged = changedKeys(current.Labels, result.labels)
+	result.annotationsChanged = changedKeys(current.Annotations, result.annotations)
+	result.ownerRefChanged = result.ownerRef != current.OwnerRef
+	return result, nil
+}
+
+// changedKeys returns the keys whose value differs between before and
+// after (set, changed or removed), sorted.
+func changedKeys(before, after map[string]string) []string {
+	var changed []string
+	for key, value := range after {
+		if old, set := before[key]; !set || old != value {
+			changed = append(changed, key)
+		}
+	}
+	for key := range before {
+		if _, set := after[key]; !set {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
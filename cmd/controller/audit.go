@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/audit"
+	"github.com/Zhichengu1/mock-control-plane/pkg/client"
+)
+
+// =============================================================================
+// AUDIT TRAIL AND REQUEST IDS
+// =============================================================================
+// Every API request gets an ID (X-Request-ID). The ID travels in the
+// context to the providers and the HTTP client, which report each
+// outbound vendor call to the audit log. GET /audit?request_id=... then
+// answers "what exactly did this API call send to the vendor?".
+//
+// WHAT IS RECORDED PER VENDOR CALL (see pkg/client/recorder.go):
+// method, URL (secrets redacted), SHA-256 + size of the payload, status
+// code, duration, retry attempt. Never the payload or headers themselves.
+//
+// TAMPER EVIDENCE (see pkg/audit/chain.go): Entries are hash-chained.
+// With AUDIT_SIGNING_KEY set, a signed checkpoint is taken every
+// AUDIT_CHECKPOINT_EVERY entries (default 100; GET /audit/checkpoints).
+// POST /admin/audit:verify checks the live log, or an exported one sent
+// in the body:
+//
+//   {"entries": [...GET /audit items...], "checkpoints": [...]}
+// =============================================================================
+
+// RequestIDMiddleware assigns each request an ID, echoes it in the
+// X-Request-ID response header and stores it in the request context.
+//
+// WHY HONOR AN INCOMING ID: Callers (or a gateway in front of us) can
+// supply their own ID to correlate logs across systems.
+func (c *Controller) RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = c.RequestIDs.NewID("req")
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(audit.WithRequestID(r.Context(), id)))
+	})
+}
+
+// vendorContext returns a context for vendor calls made on behalf of r.
+//
+// WHY NOT r.Context(): Vendor operations must finish even if the API
+// client disconnects mid-request (a half-created device is worse than a
+// slow response). The context is detached from r but keeps its request ID,
+// principal (resource locks tell the lock owner from others) and
+// precondition (see preconditions.go).
+func vendorContext(r *http.Request) context.Context {
+	ctx := audit.WithRequestID(context.Background(), audit.RequestID(r.Context()))
+	if principal, ok := principalFrom(r.Context()); ok {
+		ctx = context.WithValue(ctx, principalKey{}, principal)
+	}
+	if p := requestPrecondition(r); p.set() {
+		ctx = context.WithValue(ctx, preconditionKey{}, p)
+	}
+	return ctx
+}
+
+// installCallRecorder routes outbound vendor call records into c.Audit.
+func (c *Controller) installCallRecorder() {
+	client.SetCallRecorder(func(rec client.CallRecord) {
+		entry := audit.Entry{
+			Time:          rec.Started,
+			Kind:          audit.KindVendorCall,
+			RequestID:     rec.RequestID,
+			ResourceID:    rec.ResourceID,
+			Method:        rec.Method,
+			URL:           rec.URL,
+			PayloadSHA256: rec.PayloadSHA256,
+			PayloadBytes:  rec.PayloadBytes,
+			StatusCode:    rec.StatusCode,
+			DurationMS:    rec.Duration.Milliseconds(),
+			Attempt:       rec.Attempt,
+		}
+		if rec.Err != nil {
+			entry.Error = rec.Err.Error()
+		}
+		c.Audit.Record(entry)
+	})
+}
+
+// configureAuditSigning turns on signed checkpoints if AUDIT_SIGNING_KEY
+// is set.
+func (c *Controller) configureAuditSigning() {
+	key := os.Getenv("AUDIT_SIGNING_KEY")
+	if key == "" {
+		return
+	}
+	every := envInt("AUDIT_CHECKPOINT_EVERY", 100)
+	c.Audit.SetSigning([]byte(key), every)
+	logger.Infof("Audit checkpoints signed every %d entries", every)
+}
+
+// auditExport is the body of POST /admin/audit:verify for an exported log.
+type auditExport struct {
+	Entries     []audit.Entry      `json:"entries"`
+	Checkpoints []audit.Checkpoint `json:"checkpoints"`
+}
+
+// HandleVerifyAudit handles POST /admin/audit:verify
+// Without a body the live log is verified; with one, the exported
+// entries (consecutive, oldest first) and checkpoints in it.
+func (c *Controller) HandleVerifyAudit(w http.ResponseWriter, r *http.Request) {
+	var export auditExport
+	var verification audit.Verification
+	if err := json.NewDecoder(r.Body).Decode(&export); errors.Is(err, io.EOF) {
+		verification = c.Audit.Verify()
+	} else if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	} else {
+		sort.SliceStable(export.Entries, func(i, j int) bool { return export.Entries[i].ID < export.Entries[j].ID })
+		verification = audit.VerifyChain(export.Entries, export.Checkpoints, []byte(os.Getenv("AUDIT_SIGNING_KEY")))
+	}
+	if !verification.Valid {
+		logger.Warnf("Audit verify found %d problems in entries %d-%d", len(verification.Problems), verification.FirstID, verification.LastID)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(verification)
+}
+
+// HandleListAuditCheckpoints handles GET /audit/checkpoints
+func (c *Controller) HandleListAuditCheckpoints(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": c.Audit.Checkpoints()})
+}
+
+// HandleAuditCheckpoint handles POST /admin/audit:checkpoint
+// WHY: Signs the head right before an export, so the whole export is
+// covered
+func (c *Controller) HandleAuditCheckpoint(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	checkpoint, ok := c.Audit.Checkpoint()
+	if !ok {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "no signing key (AUDIT_SIGNING_KEY) or no entries to sign"})
+		return
+	}
+	json.NewEncoder(w).Encode(checkpoint)
+}
+
+// HandleListAudit handles GET /audit
+// Query parameters (all optional): request_id, resource_id, kind, actor,
+// since (RFC3339 or Unix seconds), limit (default 100, most recent).
+func (c *Controller) HandleListAudit(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := audit.Filter{
+		RequestID:  query.Get("request_id"),
+		ResourceID: query.Get("resource_id"),
+		Kind:       query.Get("kind"),
+		Actor:      query.Get("actor"),
+		Limit:      100,
+	}
+
+	if v := query.Get("since"); v != "" {
+		since, err := parseAsOf(v)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid since: " + err.Error()})
+			return
+		}
+		filter.Since = since
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "limit must be a positive integer"})
+			return
+		}
+		filter.Limit = limit
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": c.Audit.Query(filter)})
+}
+
+// validRequestID accepts caller-supplied IDs that are short and printable,
+// so they are safe to echo in headers and logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, ch := range id {
+		if ch < 0x21 || ch > 0x7e {
+			return false
+		}
+	}
+	return true
+}
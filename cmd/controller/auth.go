@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// =============================================================================
+// API KEYS AND ROLES
+// =============================================================================
+// Sensitive endpoints (e.g. vendor passthrough) require an authenticated
+// principal with a sufficient role. Keys are configured with
+//
+//	FORGE_API_KEYS="alice:admin:<key>,ci:operator:<key>,grafana:viewer:<key>"
+//
+// and presented as "Authorization: Bearer <key>" or "X-API-Key: <key>".
+//
+// Roles are ordered: viewer < operator < admin. Routes that don't require
+// a role still work without a key; a key that IS presented must be valid.
+//
+// WHY FAIL CLOSED: Without FORGE_API_KEYS nobody can be an admin, so
+// admin-only endpoints refuse every request rather than allowing all.
+// =============================================================================
+
+// Roles, lowest to highest privilege.
+const (
+	RoleViewer   = "viewer"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+)
+
+var roleRank = map[string]int{RoleViewer: 1, RoleOperator: 2, RoleAdmin: 3}
+
+// Principal is an authenticated API caller.
+type Principal struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+// HasRole reports whether p's role is at least role.
+func (p Principal) HasRole(role string) bool {
+	return roleRank[p.Role] >= roleRank[role]
+}
+
+type principalKey struct{}
+
+// principalFrom returns the authenticated principal for ctx, if any.
+func principalFrom(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// parseAPIKeys parses FORGE_API_KEYS into a map keyed by hashKey(key).
+//
+// WHY HASHED: Keys aren't kept in memory in the clear, and lookups by
+// hash don't leak key prefixes through timing.
+func parseAPIKeys(spec string) (map[string]Principal, error) {
+	keys := make(map[string]Principal)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		// WHY SplitN: The key itself may contain ':'
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("entry %q must be name:role:key", redactKeyEntry(entry))
+		}
+		if _, ok := roleRank[parts[1]]; !ok {
+			return nil, fmt.Errorf("entry for %q has unknown role %q (want viewer, operator or admin)", parts[0], parts[1])
+		}
+		hash := hashKey(parts[2])
+		if _, dup := keys[hash]; dup {
+			return nil, fmt.Errorf("entry for %q reuses another principal's key", parts[0])
+		}
+		keys[hash] = Principal{Name: parts[0], Role: parts[1]}
+	}
+	return keys, nil
+}
+
+// redactKeyEntry keeps the name of a malformed entry for the error
+// message but never the key.
+func redactKeyEntry(entry string) string {
+	if i := strings.Index(entry, ":"); i >= 0 {
+		return entry[:i] + ":..."
+	}
+	return "..."
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// presentedKey extracts the API key from the request, or "".
+func presentedKey(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	return r.Header.Get("X-API-Key")
+}
+
+// AuthMiddleware attaches the principal for a presented API key to the
+// request context. Invalid keys are rejected with 401.
+func (c *Controller) AuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := presentedKey(r)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		principal, ok := c.config().APIKeys[hashKey(key)]
+		if !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid API key"})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, principal)))
+	})
+}
+
+// requireRole wraps h so it only runs for principals with at least role.
+func (c *Controller) requireRole(role string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(c.config().APIKeys) == 0 {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]string{"error": "this endpoint requires API keys; configure FORGE_API_KEYS"})
+			return
+		}
+		principal, ok := principalFrom(r.Context())
+		if !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "authentication required"})
+			return
+		}
+		if !principal.HasRole(role) {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("%s role required (you are %s)", role, principal.Role)})
+			return
+		}
+		h(w, r)
+	}
+}
@@ -0,0 +1,495 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/fanout"
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// DEPENDENCIES AND BATCH DELETE
+// =============================================================================
+// Resources may depend on others (spec: "depends_on": ["res-camera"]).
+// A resource can't be deleted while something depends on it, so tearing
+// down a whole setup means deleting in reverse dependency order:
+//
+//   POST /resources:batchDelete
+//   {"ids": ["res-camera"], "include_dependents": true, "parallelism": 4}
+//
+//   wave 1: encoders and recorders that depend on the camera (in parallel)
+//   wave 2: the camera itself
+//
+// Resources owned by a deleted one (owner_ref, see owners.go) are always
+// included and go in the waves before their owner.
+//
+// Failures don't stop the batch (continue-on-error). A resource whose
+// dependent failed is skipped, because deleting it would break the
+// survivor. The response reports what happened to every resource.
+//
+// Tearing down a whole event selects the resources by filter instead:
+//
+//   DELETE /resources?namespace=event-42&vendor_type=sony&phase=Running,Failed
+//
+// takes the same filters as GET /resources/watch (namespace, vendor_type,
+// phase, type) plus include_dependents and parallelism, and answers with
+// the same report. At least one filter is required, or ?all=true.
+//
+// With DELETE_GRACE_PERIOD both only schedule the deletions, like DELETE
+// /resources/{id} (see softdelete.go): every resource turns Terminating
+// with delete_at set, is reported "scheduled" with its task, and can be
+// restored until then. When the period is over, a resource's vendor
+// delete waits for its dependents in the batch to be gone, as the waves
+// would; if one of them was restored (or failed), it is restored too.
+// =============================================================================
+
+// Batch delete parallelism bounds.
+// WHY A CAP: Vendor slots already bound concurrent vendor calls; beyond
+// that, more goroutines only queue.
+const (
+	defaultBatchParallelism = 4
+	maxBatchParallelism     = 32
+)
+
+// Batch delete item outcomes.
+// WHY "scheduled": With DELETE_GRACE_PERIOD nothing is gone yet
+const (
+	batchDeleted   = "deleted"
+	batchScheduled = "scheduled"
+	batchFailed    = "failed"
+	batchSkipped   = "skipped"
+	batchNotFound  = "not_found"
+)
+
+// BatchDeleteRequest is the body accepted by POST /resources:batchDelete.
+type BatchDeleteRequest struct {
+	// IDs are the resources to delete.
+	IDs []string `json:"ids"`
+
+	// IncludeDependents also deletes everything that (transitively)
+	// depends on IDs. Without it, resources with dependents are skipped.
+	IncludeDependents bool `json:"include_dependents,omitempty"`
+
+	// Parallelism bounds deletes in flight within a wave (default 4, max 32).
+	Parallelism int `json:"parallelism,omitempty"`
+}
+
+// BatchDeleteItem is the outcome for one resource.
+type BatchDeleteItem struct {
+	ID     string `json:"id"`
+	Name   string `json:"name,omitempty"`
+	Status string `json:"status"`
+	// Wave is the 1-based teardown wave the resource was deleted (or
+	// scheduled) in; TaskID the delete task of a scheduled one
+	Wave   int    `json:"wave,omitempty"`
+	TaskID string `json:"task_id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BatchDeleteReport is the response of POST /resources:batchDelete.
+type BatchDeleteReport struct {
+	Items      []BatchDeleteItem `json:"items"`
+	Deleted    int               `json:"deleted"`
+	Scheduled  int               `json:"scheduled"`
+	Failed     int               `json:"failed"`
+	Skipped    int               `json:"skipped"`
+	NotFound   int               `json:"not_found"`
+	Waves      int               `json:"waves"`
+	DurationMS int64             `json:"duration_ms"`
+}
+
+// notDeleted returns the IDs of the resources the batch tried to delete
+// but didn't (failed or skipped).
+func (report *BatchDeleteReport) notDeleted() []string {
+	var ids []string
+	for _, item := range report.Items {
+		if item.Status == batchFailed || item.Status == batchSkipped {
+			ids = append(ids, item.ID)
+		}
+	}
+	return ids
+}
+
+// checkDependencies de-duplicates res.DependsOn and verifies every
+// dependency exists. Returns an error message, or "" if valid.
+func (c *Controller) checkDependencies(res *models.ForgeResource) string {
+	if len(res.DependsOn) == 0 {
+		return ""
+	}
+	seen := make(map[string]bool, len(res.DependsOn))
+	deps := make([]string, 0, len(res.DependsOn))
+	for _, id := range res.DependsOn {
+		if !seen[id] {
+			seen[id] = true
+			deps = append(deps, id)
+		}
+	}
+	sort.Strings(deps)
+	res.DependsOn = deps
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, id := range deps {
+		if _, exists := c.ResourceDB[id]; !exists {
+			return "depends_on: resource " + id + " not found"
+		}
+	}
+	return ""
+}
+
+// dependentsLocked returns the IDs of resources that depend on id, sorted.
+// Caller must hold c.mu.
+func (c *Controller) dependentsLocked(id string) []string {
+	var dependents []string
+	for _, res := range c.ResourceDB {
+		for _, dep := range res.DependsOn {
+			if dep == id {
+				dependents = append(dependents, res.ID)
+				break
+			}
+		}
+	}
+	sort.Strings(dependents)
+	return dependents
+}
+
+// HandleBatchDelete handles POST /resources:batchDelete
+func (c *Controller) HandleBatchDelete(w http.ResponseWriter, r *http.Request) {
+	var req BatchDeleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+	if len(req.IDs) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "ids is required"})
+		return
+	}
+	parallelism := req.Parallelism
+	if parallelism <= 0 {
+		parallelism = defaultBatchParallelism
+	}
+	if parallelism > maxBatchParallelism {
+		parallelism = maxBatchParallelism
+	}
+
+	if c.guardMassDelete(w, r, massOpDelete, c.deleteTargets(req.IDs, req.IncludeDependents)) {
+		return
+	}
+
+	started := c.Clock.Now()
+	report := c.batchDelete(vendorContext(r), req.IDs, req.IncludeDependents, parallelism)
+	c.refundMassDelete(r, report.notDeleted())
+	report.DurationMS = c.Clock.Since(started).Milliseconds()
+	logger.Infof("Batch delete: %d deleted, %d failed, %d skipped, %d not found in %d waves",
+		report.Deleted, report.Failed, report.Skipped, report.NotFound, report.Waves)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// HandleDeleteResources handles DELETE /resources
+// Query parameters: namespace, vendor_type, phase, type, name_prefix,
+// labelSelector (filters; vendor_type, phase and type take comma-separated lists),
+// all=true (no filter),
+// include_dependents=true, parallelism.
+func (c *Controller) HandleDeleteResources(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Parse the filter
+	// WHY REQUIRE A FILTER: A bare DELETE /resources would wipe everything
+	query := r.URL.Query()
+	filter, err := parseWatchFilter(r)
+	if err == nil && filter.empty() && query.Get("all") != "true" {
+		err = errors.New("a filter (namespace, vendor_type, phase, type, name_prefix, labelSelector) is required; use all=true to delete every resource")
+	}
+	parallelism := defaultBatchParallelism
+	if err == nil && query.Has("parallelism") {
+		if parallelism, err = strconv.Atoi(query.Get("parallelism")); err != nil || parallelism < 1 {
+			err = errors.New("parallelism must be a positive integer")
+		}
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if parallelism > maxBatchParallelism {
+		parallelism = maxBatchParallelism
+	}
+
+	// Step 2: Select the matching resources
+	c.mu.RLock()
+	var ids []string
+	for id, res := range c.ResourceDB {
+		if filter.matches(res) {
+			ids = append(ids, id)
+		}
+	}
+	c.mu.RUnlock()
+	sort.Strings(ids)
+
+	// Step 3: Delete them like POST /resources:batchDelete
+	// WHY GUARDED: A filter that matches more than meant is the classic
+	// mass delete (see massdelete.go)
+	includeDependents := query.Get("include_dependents") == "true"
+	if c.guardMassDelete(w, r, massOpDelete, c.deleteTargets(ids, includeDependents)) {
+		return
+	}
+	started := c.Clock.Now()
+	report := c.batchDelete(vendorContext(r), ids, includeDependents, parallelism)
+	c.refundMassDelete(r, report.notDeleted())
+	report.DurationMS = c.Clock.Since(started).Milliseconds()
+	logger.Infof("Delete by filter %s: %d matched, %d deleted, %d failed, %d skipped",
+		r.URL.RawQuery, len(ids), report.Deleted, report.Failed, report.Skipped)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// batchDelete deletes ids (and optionally their dependents, always what
+// they own) in reverse dependency order.
+func (c *Controller) batchDelete(parent context.Context, ids []string, includeDependents bool, parallelism int) *BatchDeleteReport {
+	results := make(map[string]*BatchDeleteItem)
+
+	// Step 1: Snapshot the targets and the dependency graph
+	c.mu.RLock()
+	targets := make(map[string]*models.ForgeResource)
+	queue := append([]string(nil), ids...)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if _, done := targets[id]; done || results[id] != nil {
+			continue
+		}
+		res, exists := c.ResourceDB[id]
+		if !exists {
+			results[id] = &BatchDeleteItem{ID: id, Status: batchNotFound}
+			continue
+		}
+		targets[id] = res.DeepCopy()
+		if includeDependents {
+			queue = append(queue, c.dependentsLocked(id)...)
+		}
+		// Owned resources always go with their owner (see owners.go)
+		queue = append(queue, c.ownedLocked(id)...)
+	}
+	// WHY OWNED ONES TOO: They are torn down before their owner, like
+	// dependents
+	dependents := make(map[string][]string, len(targets))
+	for id := range targets {
+		dependents[id] = append(c.dependentsLocked(id), c.ownedLocked(id)...)
+	}
+	c.mu.RUnlock()
+
+	// Step 2: Delete in waves. A resource is ready once all its dependents
+	// are deleted; it's skipped once one of them can't be.
+	pending := make(map[string]bool, len(targets))
+	for id := range targets {
+		pending[id] = true
+	}
+	wave := 0
+	for len(pending) > 0 {
+		var ready []string
+		progressed := false
+		for _, id := range sortedKeys(pending) {
+			blocker, waiting := "", false
+			for _, dep := range dependents[id] {
+				switch {
+				case results[dep] != nil && (results[dep].Status == batchDeleted || results[dep].Status == batchScheduled):
+					continue
+				case pending[dep]:
+					waiting = true
+				default:
+					blocker = dep
+				}
+				if blocker != "" {
+					break
+				}
+			}
+			switch {
+			case blocker != "":
+				results[id] = &BatchDeleteItem{ID: id, Name: targets[id].Name, Status: batchSkipped,
+					Error: "still required by " + blocker + blockerReason(results[blocker])}
+				delete(pending, id)
+				progressed = true
+			case !waiting:
+				ready = append(ready, id)
+			}
+		}
+
+		if len(ready) == 0 {
+			if progressed {
+				continue
+			}
+			// Only a dependency cycle can leave everything waiting
+			for _, id := range sortedKeys(pending) {
+				results[id] = &BatchDeleteItem{ID: id, Name: targets[id].Name, Status: batchSkipped, Error: "dependency cycle"}
+			}
+			break
+		}
+
+		wave++
+		for id, item := range c.deleteWave(parent, ready, targets, parallelism) {
+			item.Wave = wave
+			results[id] = item
+			delete(pending, id)
+		}
+	}
+
+	// Step 3: Report in teardown order (by wave, then ID); items that
+	// weren't deleted come last
+	report := &BatchDeleteReport{Items: []BatchDeleteItem{}, Waves: wave}
+	for _, id := range sortedKeys(results) {
+		item := results[id]
+		report.Items = append(report.Items, *item)
+		switch item.Status {
+		case batchDeleted:
+			report.Deleted++
+		case batchScheduled:
+			report.Scheduled++
+		case batchFailed:
+			report.Failed++
+		case batchSkipped:
+			report.Skipped++
+		case batchNotFound:
+			report.NotFound++
+		}
+	}
+	sort.SliceStable(report.Items, func(i, j int) bool { return waveOrder(report.Items[i]) < waveOrder(report.Items[j]) })
+	return report
+}
+
+// deleteWave deletes ids concurrently, at most parallelism at a time, or
+// schedules their deletion with a grace period.
+func (c *Controller) deleteWave(parent context.Context, ids []string, targets map[string]*models.ForgeResource, parallelism int) map[string]*BatchDeleteItem {
+	if c.Deletion.GracePeriod > 0 {
+		return c.scheduleWave(parent, ids, targets)
+	}
+	branches, _ := fanout.Map(parent, ids, fanout.Options{Limit: parallelism}, func(ctx context.Context, id string) (*BatchDeleteItem, error) {
+		res := targets[id]
+		item := &BatchDeleteItem{ID: res.ID, Name: res.Name, Status: batchDeleted}
+		if err := c.teardownResource(ctx, res, "batch-delete"); err != nil {
+			item.Status = batchFailed
+			item.Error = err.Error()
+		}
+		return item, nil
+	})
+	results := make(map[string]*BatchDeleteItem, len(ids))
+	for i, branch := range branches {
+		item := branch.Value
+		if branch.Err != nil {
+			item = &BatchDeleteItem{ID: ids[i], Name: targets[ids[i]].Name, Status: batchFailed, Error: branch.Err.Error()}
+		}
+		results[ids[i]] = item
+	}
+	return results
+}
+
+// scheduleWave schedules the deletion of ids after the grace period (see
+// softdelete.go). Nothing reaches the vendor yet, so there is nothing to
+// run concurrently.
+func (c *Controller) scheduleWave(parent context.Context, ids []string, targets map[string]*models.ForgeResource) map[string]*BatchDeleteItem {
+	// WHY DROP THE PRECONDITION: An If-Match can't name the version of
+	// every resource in a batch (teardownResource ignores it too)
+	ctx := context.WithValue(parent, preconditionKey{}, precondition{})
+	principal, _ := principalFrom(parent)
+	results := make(map[string]*BatchDeleteItem, len(ids))
+	for _, id := range ids {
+		res := targets[id]
+		item := &BatchDeleteItem{ID: res.ID, Name: res.Name, Status: batchScheduled}
+		task, err := c.beginDeletion(ctx, id, principal.Name)
+		switch {
+		case errors.Is(err, errResourceNotFound):
+			// Deleted concurrently by someone else
+			item.Status = batchDeleted
+		case err != nil:
+			item.Status = batchFailed
+			item.Error = err.Error()
+		default:
+			item.TaskID = task.ID
+		}
+		results[id] = item
+	}
+	return results
+}
+
+// teardownResource deletes res from the vendor, waits for the vendor's
+// teardown, then deletes it from the controller. reason is recorded on
+// the tombstone revision. Resources with finalizers are refused (see
+// finalizers.go).
+func (c *Controller) teardownResource(parent context.Context, res *models.ForgeResource, reason string) error {
+	if err := c.checkLock(parent, res.ID); err != nil {
+		return err
+	}
+	if len(res.Finalizers) > 0 {
+		return fmt.Errorf("resource has finalizers (%s); DELETE /resources/%s waits for them", strings.Join(res.Finalizers, ", "), res.ID)
+	}
+	if res.Status.VendorID != "" {
+		selectedProvider, exists := c.config().Providers[res.Spec.VendorType]
+		if !exists {
+			return fmt.Errorf("provider %s not configured", res.Spec.VendorType)
+		}
+		ctx, cancel := context.WithTimeout(parent, 30*time.Second)
+		defer cancel()
+		release, err := c.acquireVendor(ctx, res.Spec.VendorType)
+		if err != nil {
+			return err
+		}
+		err = selectedProvider.Delete(ctx, res.Status.VendorID)
+		release()
+		if err != nil {
+			return fmt.Errorf("failed to delete from vendor: %w", err)
+		}
+		// WHY WAIT: The next wave may only go once its dependents are
+		// really gone from the vendor, not just on their way out
+		if err := c.waitForTeardown(parent, selectedProvider, res.Spec.VendorType, res.Status.VendorID, nil); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stored, exists := c.ResourceDB[res.ID]
+	if !exists {
+		// Deleted concurrently by someone else; the vendor side is gone too
+		return nil
+	}
+	return c.withTxLocked(func(tx *storeTx) error {
+		c.removeResource(tx, stored, reason, "Deleted from vendor and controller ("+reason+")")
+		return nil
+	})
+}
+
+// blockerReason explains why a blocking dependent wasn't deleted.
+func blockerReason(item *BatchDeleteItem) string {
+	if item == nil {
+		return " (not part of this batch; set include_dependents)"
+	}
+	return " (" + item.Status + ")"
+}
+
+// waveOrder sorts deleted items by wave, everything else last.
+func waveOrder(item BatchDeleteItem) int {
+	if item.Wave == 0 {
+		return math.MaxInt
+	}
+	return item.Wave
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/fanout"
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/Zhichengu1/mock-control-plane/pkg/validation"
+)
+
+// =============================================================================
+// BATCH CREATE
+// =============================================================================
+// Setting up an event means creating dozens of cameras, encoders and
+// recorders. Instead of one POST /resources per device:
+//
+//   POST /resources:batch
+//   [{"name": "cam1", ...}, {"name": "cam2", ...}]
+//
+//   POST /resources:batch?onDuplicate=rename
+//   {"items": [{"name": "cam1", ...}], "parallelism": 8}
+//
+// Every item goes through the same steps as a single create (validation,
+// capacity, duplicate names, preflight, vendor slots), concurrently with
+// at most parallelism creates in flight. Failures don't stop the batch;
+// the response reports every item in request order:
+//
+//   {"items": [{"index": 0, "status": "created", "id": "res-...", ...},
+//              {"index": 1, "status": "failed", "error": "..."}],
+//    "created": 1, "failed": 1, "duration_ms": 840}
+//
+// An item whose vendor call failed is still stored (phase Failed, as with
+// a single create), so it is reported as failed with its ID. Each item is
+// stored in its own transaction (see tx.go); the batch as a whole isn't
+// one, since its vendor devices can't be created atomically either.
+// =============================================================================
+
+// Batch create bounds.
+// WHY: The whole body is buffered; a larger setup can be split into batches
+const (
+	maxBatchCreateItems     = 100
+	maxBatchCreateBodyBytes = 16 << 20 // 16 MiB
+)
+
+// batchCreated is the outcome of a created item (failures are batchFailed).
+const batchCreated = "created"
+
+// BatchCreateRequest is the object form of the POST /resources:batch body;
+// a bare array of resources is accepted too.
+type BatchCreateRequest struct {
+	Items []models.ForgeResource `json:"items"`
+
+	// Parallelism bounds creates in flight (default 4, max 32).
+	Parallelism int `json:"parallelism,omitempty"`
+}
+
+// BatchCreateItem is the outcome for one resource of the batch.
+type BatchCreateItem struct {
+	// Index is the item's position in the request
+	Index  int    `json:"index"`
+	Name   string `json:"name,omitempty"`
+	Status string `json:"status"`
+	ID     string `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+
+	// Violations are set when the item failed validation
+	Violations validation.Violations `json:"violations,omitempty"`
+
+	// Resource is the stored resource (created, or failed on the vendor)
+	Resource *models.ForgeResource `json:"resource,omitempty"`
+}
+
+// BatchCreateReport is the response of POST /resources:batch.
+type BatchCreateReport struct {
+	Items      []BatchCreateItem `json:"items"`
+	Created    int               `json:"created"`
+	Failed     int               `json:"failed"`
+	DurationMS int64             `json:"duration_ms"`
+}
+
+// HandleBatchCreate handles POST /resources:batch
+func (c *Controller) HandleBatchCreate(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Decode the array or object form
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBatchCreateBodyBytes))
+	if err != nil {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	var req BatchCreateRequest
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &req.Items)
+	} else {
+		err = json.Unmarshal(body, &req)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+	if len(req.Items) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "items is required"})
+		return
+	}
+	if len(req.Items) > maxBatchCreateItems {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("at most %d resources per batch", maxBatchCreateItems)})
+		return
+	}
+	duplicateStrategy := r.URL.Query().Get("onDuplicate")
+	if duplicateStrategy != "" && !validDuplicateStrategy(duplicateStrategy) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "onDuplicate must be one of: " + strings.Join(duplicateStrategies, ", ")})
+		return
+	}
+	if rejectBadCircuitOpenParam(w, r) {
+		return
+	}
+	parallelism := req.Parallelism
+	if parallelism <= 0 {
+		parallelism = defaultBatchParallelism
+	}
+	if parallelism > maxBatchParallelism {
+		parallelism = maxBatchParallelism
+	}
+
+	// Step 2: Create concurrently; each branch owns its item
+	started := c.Clock.Now()
+	indexes := make([]int, len(req.Items))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	branches, _ := fanout.Map(vendorContext(r), indexes, fanout.Options{Limit: parallelism}, func(ctx context.Context, i int) (BatchCreateItem, error) {
+		resource := &req.Items[i]
+		item := BatchCreateItem{Index: i, Name: resource.Name, Status: batchCreated}
+		if err := c.createResource(ctx, r, resource, duplicateStrategy); err != nil {
+			item.Status = batchFailed
+			item.Error = err.Error()
+			errors.As(err, &item.Violations)
+		} else {
+			// WHY UNDER THE LOCK: The resource is stored; the
+			// reconciler may already be updating it
+			c.mu.RLock()
+			item.ID, item.Name, item.Resource = resource.ID, resource.Name, resource.DeepCopy()
+			c.mu.RUnlock()
+			if item.Resource.Status.Phase == "Failed" {
+				item.Status = batchFailed
+				item.Error = item.Resource.Status.Message
+			}
+		}
+		return item, nil
+	})
+	items := make([]BatchCreateItem, len(branches))
+	for i, branch := range branches {
+		items[i] = branch.Value
+		if branch.Err != nil {
+			items[i] = BatchCreateItem{Index: i, Name: req.Items[i].Name, Status: batchFailed, Error: branch.Err.Error()}
+		}
+	}
+
+	// Step 3: Report in request order
+	report := BatchCreateReport{Items: items}
+	for _, item := range items {
+		if item.Status == batchCreated {
+			report.Created++
+		} else {
+			report.Failed++
+		}
+	}
+	report.DurationMS = c.Clock.Since(started).Milliseconds()
+	logger.Infof("Batch create: %d created, %d failed", report.Created, report.Failed)
+
+	w.Header().Set("Content-Type", "application/json")
+	redact := c.redactionFor(r)
+	redact.announce(w)
+	for _, item := range report.Items {
+		redact.resource(item.Resource)
+	}
+	json.NewEncoder(w).Encode(report)
+}
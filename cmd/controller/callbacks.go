@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/client"
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/Zhichengu1/mock-control-plane/pkg/webhook"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// VENDOR CALLBACKS (POST /callbacks/{vendor})
+// =============================================================================
+// A device that drops off air shouldn't wait for the next reconciler pass
+// to show it. Vendors that can push state changes call back:
+//
+//   POST /callbacks/sony
+//   X-Forge-Source: sony
+//   X-Forge-Timestamp: 1767225600
+//   X-Forge-Nonce: 8f14e45fceea167a
+//   X-Forge-Signature: v1=<hex HMAC-SHA256>
+//   {"vendor_id": "dev-42", "event": "stream_stopped", "message": "encoder lost input"}
+//
+// The request is signed as described in pkg/webhook (signature,
+// timestamp window, nonce replay check). Each vendor's shared secret is
+// <VENDOR>_CALLBACK_SECRET in the secrets backend (SECRETS_DIR, else the
+// environment; see signing.go), read on every request so a rotated
+// secret file applies at once. A vendor without one can't call back
+// (401), and a vendor's secret only signs its own callbacks (403).
+//
+// A callback doesn't carry the new state: the controller records a
+// VendorCallback event and reads the device from the vendor (the same
+// refresh as GET /resources/{id}), so a forged body could at worst cause
+// a read. 404 for a device no resource manages.
+//
+// WHY NO API KEY: Vendors aren't principals; the signature is the
+// credential (as the share token is for /share/{token}).
+// =============================================================================
+
+// callbackSecrets looks up each vendor's callback secret in the secrets
+// backend.
+type callbackSecrets struct {
+	secrets client.Secrets
+}
+
+// Secret implements webhook.Secrets.
+func (s callbackSecrets) Secret(source string) ([]byte, bool) {
+	secret, ok := s.secrets.Secret(strings.ToUpper(source) + "_CALLBACK_SECRET")
+	return []byte(secret), ok && secret != ""
+}
+
+// CallbackRequest is the body of POST /callbacks/{vendor}.
+type CallbackRequest struct {
+	// VendorID is the device the callback is about
+	VendorID string `json:"vendor_id"`
+
+	// Event and Message say what happened, for the event list
+	Event   string `json:"event"`
+	Message string `json:"message,omitempty"`
+}
+
+// CallbackResponse is the response of POST /callbacks/{vendor}.
+type CallbackResponse struct {
+	ResourceID string `json:"resource_id"`
+	Refresh    string `json:"refresh"`
+}
+
+// HandleVendorCallback handles POST /callbacks/{vendor}
+// Wrapped in c.callbacks.Middleware, which checks the signature.
+func (c *Controller) HandleVendorCallback(w http.ResponseWriter, r *http.Request) {
+	vendor := mux.Vars(r)["vendor"]
+	w.Header().Set("Content-Type", "application/json")
+
+	// Step 1: The signing vendor may only call back for itself
+	if source, _ := webhook.SourceFrom(r.Context()); source != vendor {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("callback signed by %s can't report for %s", source, vendor)})
+		return
+	}
+	var req CallbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+	if req.VendorID == "" || req.Event == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "vendor_id and event are required"})
+		return
+	}
+
+	// Step 2: Find the resource that manages the device
+	c.mu.Lock()
+	var stored *models.ForgeResource
+	for _, id := range sortedKeys(c.ResourceDB) {
+		if res := c.ResourceDB[id]; res.Spec.VendorType == vendor && res.Status.VendorID == req.VendorID {
+			stored = res
+			break
+		}
+	}
+	if stored == nil {
+		c.mu.Unlock()
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("no resource manages %s device %s", vendor, req.VendorID)})
+		return
+	}
+	message := "Vendor reported " + req.Event
+	if req.Message != "" {
+		message += ": " + req.Message
+	}
+	c.recordEvent(stored, models.EventNormal, models.ReasonVendorCallback, message, "", "")
+	id := stored.ID
+	c.mu.Unlock()
+
+	// Step 3: Read the device's new state, as the reconciler would
+	// WHY ASYNC: The vendor is waiting on us; its own API may be slow to
+	// reflect the change
+	go func() {
+		if _, err := c.refreshStatus(context.Background(), id); err != nil {
+			logger.Warnf("%s: refresh after %s callback failed: %v", id, vendor, err)
+		}
+	}()
+	logger.Infof("%s: %s callback %q for device %s", id, vendor, req.Event, req.VendorID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(CallbackResponse{ResourceID: id, Refresh: "queued"})
+}
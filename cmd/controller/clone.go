@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// CLONE (POST /resources/{id}/clone)
+// =============================================================================
+// Adding "camera 7" to a show usually means "like camera 6, but with its
+// own address". Instead of copying camera 6's spec by hand:
+//
+//   POST /resources/res-cam6/clone
+//   {"name": "cam-7", "spec": {"config": {"ip_address": "10.0.1.57"}}}
+//
+// The clone gets the source's type, spec, labels, annotations, notes,
+// runbook, depends_on and owner_ref, and is created like any
+// POST /resources: validation, uniqueness, capacity and a new vendor
+// device (?onDuplicate= and ?onCircuitOpen= work too). Overrides:
+//
+//   name         the clone's name; without one the source's trailing
+//                number counts up ("cam-6" → "cam-7", or "cam-6-2" for a
+//                name without one) to the first free name
+//   namespace    another namespace (the source's by default); owner_ref
+//                isn't copied across namespaces
+//   spec         a JSON merge patch on the source's spec (null removes)
+//   labels,      merged over the source's ("" removes a key)
+//   annotations
+//
+// Not copied: finalizers (they belong to the systems that set them), the
+// status and the history.
+//
+// WHY NOT DROP UNIQUE VALUES: A clone that keeps the source's ip_address
+// or stream_url is rejected (address in use, or 409 from the uniqueness
+// check) instead of quietly getting none; which address or destination
+// it should have is the caller's call.
+// =============================================================================
+
+// CloneRequest is the body of POST /resources/{id}/clone (all optional).
+type CloneRequest struct {
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+
+	// Spec is a JSON merge patch on the source's spec
+	Spec map[string]interface{} `json:"spec,omitempty"`
+
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// HandleCloneResource handles POST /resources/{id}/clone
+func (c *Controller) HandleCloneResource(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	w.Header().Set("Content-Type", "application/json")
+
+	// Step 1: Decode the overrides (an empty body clones as is)
+	var req CloneRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxResourceBodyBytes)).Decode(&req); err != nil && r.ContentLength > 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+			return
+		}
+	}
+	duplicateStrategy := r.URL.Query().Get("onDuplicate")
+	if duplicateStrategy != "" && !validDuplicateStrategy(duplicateStrategy) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "onDuplicate must be one of: " + strings.Join(duplicateStrategies, ", ")})
+		return
+	}
+	if rejectBadCircuitOpenParam(w, r) {
+		return
+	}
+
+	// Step 2: Copy the source
+	c.mu.RLock()
+	stored, exists := c.ResourceDB[id]
+	var source models.ForgeResource
+	if exists {
+		source = *stored.DeepCopy()
+	}
+	c.mu.RUnlock()
+	if !exists {
+		writeOperationError(w, errResourceNotFound)
+		return
+	}
+	clone, err := cloneResource(&source, &req)
+	if err != nil {
+		writeCreateError(w, &createError{http.StatusBadRequest, err.Error()})
+		return
+	}
+	if clone.Name == "" {
+		clone.Name = c.nextCloneName(source.Name, clone.Namespace)
+	}
+
+	// Step 3: Create it like POST /resources
+	// WHY IN THE CREATE'S TRANSACTION: The clone never shows up without
+	// the note of where it came from
+	cloned := func(tx *storeTx, res *models.ForgeResource) {
+		tx.OnCommit(func() {
+			c.recordEvent(res, models.EventNormal, models.ReasonCloned,
+				fmt.Sprintf("Cloned from %s (%s)", source.ID, source.Name), "", "")
+		})
+	}
+	if err := c.createResourceWith(vendorContext(r), r, clone, duplicateStrategy, cloned); err != nil {
+		writeCreateError(w, err)
+		return
+	}
+	logger.Infof("%s: cloned from %s as %q", clone.ID, source.ID, clone.Name)
+
+	status := http.StatusCreated
+	if clone.Status.Phase == phaseQueued {
+		status = http.StatusAccepted
+	}
+	c.writeCreated(w, r, clone, status)
+}
+
+// cloneResource builds the resource to create from source and req.
+func cloneResource(source *models.ForgeResource, req *CloneRequest) (*models.ForgeResource, error) {
+	clone := &models.ForgeResource{
+		Type:        source.Type,
+		Name:        req.Name,
+		Namespace:   source.Namespace,
+		DependsOn:   source.DependsOn,
+		OwnerRef:    source.OwnerRef,
+		Labels:      mergeStrings(source.Labels, req.Labels),
+		Annotations: mergeStrings(source.Annotations, req.Annotations),
+	}
+	clone.Metadata.Notes, clone.Metadata.RunbookURL = source.Metadata.Notes, source.Metadata.RunbookURL
+	if req.Namespace != "" && namespaceKey(req.Namespace) != namespaceKey(source.Namespace) {
+		// WHY: An owner must be in its resources' namespace (see owners.go)
+		clone.Namespace, clone.OwnerRef = req.Namespace, ""
+	}
+	// WHY ALWAYS PATCH: It also deep-copies the spec (config is a map)
+	spec, err := patchSpec(source.Spec, req.Spec)
+	if err != nil {
+		return nil, err
+	}
+	clone.Spec = spec
+	return clone, nil
+}
+
+// mergeStrings returns base with overrides applied ("" removes a key), or
+// nil if empty.
+func mergeStrings(base, overrides map[string]string) map[string]string {
+	merged := make(map[string]string, len(base)+len(overrides))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		if value == "" {
+			delete(merged, key)
+		} else {
+			merged[key] = value
+		}
+	}
+	if len(merged) == 0 {
+		return nil
+	}
+	return merged
+}
+
+// nextCloneName returns the first name after name that is free in
+// namespace: its trailing number counted up ("cam-6" → "cam-7"), or
+// "-2", "-3", ... appended.
+func (c *Controller) nextCloneName(name, namespace string) string {
+	stem, number := name, 1
+	if i := strings.LastIndexFunc(name, func(r rune) bool { return r < '0' || r > '9' }); i < len(name)-1 {
+		if n, err := strconv.Atoi(name[i+1:]); err == nil {
+			stem, number = name[:i+1], n
+		}
+	}
+	if stem == name {
+		stem += "-"
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	taken := make(map[string]bool)
+	for _, res := range c.ResourceDB {
+		if namespaceKey(res.Namespace) == namespaceKey(namespace) {
+			taken[res.Name] = true
+		}
+	}
+	for attempt := 1; attempt <= maxRenameAttempts; attempt++ {
+		if candidate := stem + strconv.Itoa(number+attempt); !taken[candidate] {
+			return candidate
+		}
+	}
+	// The create's name check reports it
+	return stem + strconv.Itoa(number+1)
+}
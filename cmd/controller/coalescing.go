@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/Zhichengu1/mock-control-plane/pkg/provider"
+	"github.com/Zhichengu1/mock-control-plane/pkg/singleflight"
+)
+
+// =============================================================================
+// VENDOR CALL COALESCING
+// =============================================================================
+// Every GET /resources/{id} reads the device from the vendor, so a
+// dashboard polling one camera from ten browser tabs, or a burst of
+// retries, becomes ten identical vendor calls, each taking a vendor slot.
+// Identical calls in flight are merged instead (see pkg/singleflight):
+//
+//   - Reads of one device (vendor + vendor ID) share one provider.Read.
+//   - Health checks of one provider (GET /health, GET /providers/health)
+//     share one provider.HealthCheck.
+//
+// VENDOR_READ_COALESCE_WINDOW (default 0: only calls in flight are merged)
+// also hands a finished read to reads starting within the window, at the
+// cost of a status up to that old. A write to the device (update, stop,
+// start) drops its read so the next GET sees the change.
+//
+// GET /admin/coalescing reports calls, vendor calls made, and calls that
+// were merged.
+// =============================================================================
+
+// newVendorReads returns the read coalescing group.
+func newVendorReads(c *Controller) *singleflight.Group[*models.ResourceStatus] {
+	return &singleflight.Group[*models.ResourceStatus]{
+		Window: envDuration("VENDOR_READ_COALESCE_WINDOW", 0),
+		Clock:  c.Clock,
+	}
+}
+
+// vendorReadKey identifies one device across resources.
+func vendorReadKey(vendor, vendorID string) string {
+	return vendor + "/" + vendorID
+}
+
+// readWithSlot performs provider.Read while holding a vendor concurrency
+// slot, merged with identical reads in flight. Every caller gets its own
+// copy of the status.
+// NOTE: A merged read runs on the first caller's context; if that client
+// goes away, the others see its error (the GET falls back to the cache).
+func (c *Controller) readWithSlot(ctx context.Context, p provider.VendorProvider, vendor, vendorID string) (*models.ResourceStatus, error) {
+	status, err, _ := c.vendorReads.Do(vendorReadKey(vendor, vendorID), func() (*models.ResourceStatus, error) {
+		release, err := c.acquireVendor(ctx, vendor)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return p.Read(ctx, vendorID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return status.DeepCopy(), nil
+}
+
+// forgetVendorRead drops the device's coalesced read after a write to it.
+func (c *Controller) forgetVendorRead(vendor, vendorID string) {
+	c.vendorReads.Forget(vendorReadKey(vendor, vendorID))
+}
+
+// checkProvider runs the provider's health check, merged with a check of
+// the same provider in flight.
+func (c *Controller) checkProvider(ctx context.Context, name string) error {
+	_, err, _ := c.healthChecks.Do(name, func() (struct{}, error) {
+		return struct{}{}, c.config().Providers[name].HealthCheck(ctx)
+	})
+	return err
+}
+
+// HandleGetCoalescing handles GET /admin/coalescing
+func (c *Controller) HandleGetCoalescing(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"vendor_reads":       c.vendorReads.Stats(),
+		"vendor_read_window": c.vendorReads.Window.String(),
+		"provider_health":    c.healthChecks.Stats(),
+		"metric_reads":       c.metricReads.Stats(),
+	})
+}
@@ -0,0 +1,464 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// STORE COMPACTION
+// =============================================================================
+// Revisions, events and the audit trail only ever grow (history even
+// outlives deleted resources), and the per-resource caps don't bound a
+// fleet that churns through resources. A background compactor enforces a
+// retention policy per data type:
+//
+//   max age   entries older than this are removed
+//   target    total entries to stay under; past it the oldest go first
+//
+// Compaction runs every COMPACT_INTERVAL, but only inside the low-traffic
+// window COMPACT_WINDOW ("02:00-05:00", UTC; unset = any time), unless a
+// data type is over its size target, which can't wait for the night.
+// POST /admin/compact runs it immediately; GET /admin/compact shows the
+// policy and what each run reclaimed.
+//
+// WHAT IS ALWAYS KEPT: The latest revision of a live resource (it is the
+// baseline for ?asOf= and revision diffs). A deleted resource's history
+// goes entirely once its tombstone is older than the max age.
+//
+// Reclaimed bytes are the JSON-encoded size of what was removed, an
+// estimate of the memory freed, not an exact figure.
+// =============================================================================
+
+// Compactable data types.
+const (
+	compactRevisions = "revisions"
+	compactEvents    = "events"
+	compactAudit     = "audit"
+)
+
+// compactTypes lists the data types in the order they are compacted.
+var compactTypes = []string{compactRevisions, compactEvents, compactAudit}
+
+// RetentionPolicy bounds one data type. Zero disables a limit.
+type RetentionPolicy struct {
+	MaxAge time.Duration
+	Target int
+}
+
+// CompactionPolicy configures scheduled compaction.
+type CompactionPolicy struct {
+	// Interval is how often the compactor wakes up (0 disables it)
+	Interval time.Duration
+
+	// Window is the low-traffic window ("" = any time)
+	Window string
+
+	// windowStart/windowEnd are Window's bounds, in minutes after midnight UTC
+	windowStart, windowEnd int
+
+	Retention map[string]RetentionPolicy
+}
+
+// CompactionStats describes what compaction did for one data type.
+type CompactionStats struct {
+	Entries int `json:"entries"`
+
+	Runs    int       `json:"runs"`
+	LastRun time.Time `json:"last_run,omitempty"`
+
+	// LastTrigger is why the last run happened ("window", "target",
+	// "manual")
+	LastTrigger string `json:"last_trigger,omitempty"`
+
+	LastRemoved        int   `json:"last_removed"`
+	LastReclaimedBytes int64 `json:"last_reclaimed_bytes"`
+	TotalRemoved       int   `json:"total_removed"`
+	TotalReclaimed     int64 `json:"total_reclaimed_bytes"`
+}
+
+// compactionState holds per-type stats. Guarded by its own mutex so
+// reading stats never waits on the store lock.
+type compactionState struct {
+	mu    sync.Mutex
+	stats map[string]*CompactionStats
+
+	// running serializes compactions (scheduled and manual)
+	running sync.Mutex
+}
+
+func newCompactionState() *compactionState {
+	stats := make(map[string]*CompactionStats)
+	for _, t := range compactTypes {
+		stats[t] = &CompactionStats{}
+	}
+	return &compactionState{stats: stats}
+}
+
+// loadCompactionPolicy reads COMPACT_INTERVAL, COMPACT_WINDOW and
+// COMPACT_<TYPE>_MAX_AGE / COMPACT_<TYPE>_TARGET for each data type.
+func loadCompactionPolicy() CompactionPolicy {
+	policy := CompactionPolicy{
+		Interval: envDuration("COMPACT_INTERVAL", time.Hour),
+		// WHY THESE DEFAULTS: A month of history covers most post-incident
+		// questions; events and vendor calls are for recent debugging
+		Retention: map[string]RetentionPolicy{
+			compactRevisions: {
+				MaxAge: envDuration("COMPACT_REVISIONS_MAX_AGE", 30*24*time.Hour),
+				Target: envInt("COMPACT_REVISIONS_TARGET", 100000),
+			},
+			compactEvents: {
+				MaxAge: envDuration("COMPACT_EVENTS_MAX_AGE", 7*24*time.Hour),
+				Target: envInt("COMPACT_EVENTS_TARGET", 50000),
+			},
+			compactAudit: {
+				MaxAge: envDuration("COMPACT_AUDIT_MAX_AGE", 7*24*time.Hour),
+				// AUDIT_MAX_ENTRIES already caps the audit log
+				Target: envInt("COMPACT_AUDIT_TARGET", 0),
+			},
+		},
+	}
+	if v := os.Getenv("COMPACT_WINDOW"); v != "" {
+		start, end, err := parseCompactionWindow(v)
+		if err != nil {
+			logger.Warnf("Ignoring COMPACT_WINDOW=%q: %v", v, err)
+		} else {
+			policy.Window, policy.windowStart, policy.windowEnd = v, start, end
+		}
+	}
+	return policy
+}
+
+// parseCompactionWindow parses "HH:MM-HH:MM" into minutes after midnight.
+// The window may wrap past midnight ("22:00-04:00").
+func parseCompactionWindow(v string) (int, int, error) {
+	from, to, ok := strings.Cut(v, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("want HH:MM-HH:MM")
+	}
+	minutes := func(s string) (int, error) {
+		t, err := time.Parse("15:04", strings.TrimSpace(s))
+		if err != nil {
+			return 0, fmt.Errorf("want HH:MM-HH:MM")
+		}
+		return t.Hour()*60 + t.Minute(), nil
+	}
+	start, err := minutes(from)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := minutes(to)
+	if err != nil {
+		return 0, 0, err
+	}
+	if start == end {
+		return 0, 0, fmt.Errorf("window is empty")
+	}
+	return start, end, nil
+}
+
+// inWindow reports whether now falls in the low-traffic window.
+func (p CompactionPolicy) inWindow(now time.Time) bool {
+	if p.Window == "" {
+		return true
+	}
+	now = now.UTC()
+	m := now.Hour()*60 + now.Minute()
+	if p.windowStart < p.windowEnd {
+		return m >= p.windowStart && m < p.windowEnd
+	}
+	return m >= p.windowStart || m < p.windowEnd
+}
+
+// startCompactor runs scheduled compaction in the background.
+func (c *Controller) startCompactor(ctx context.Context) {
+	policy := c.Compaction
+	if policy.Interval <= 0 {
+		logger.Infof("Compactor disabled (COMPACT_INTERVAL=0)")
+		return
+	}
+	window := policy.Window
+	if window == "" {
+		window = "any time"
+	}
+	logger.Infof("Compactor enabled: every %s, window %s", policy.Interval, window)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-c.Clock.After(policy.Interval):
+			}
+			inWindow := policy.inWindow(c.Clock.Now())
+			counts := c.compactionCounts()
+			for _, t := range compactTypes {
+				trigger := "window"
+				if !inWindow {
+					target := policy.Retention[t].Target
+					if target <= 0 || counts[t] <= target {
+						continue
+					}
+					trigger = "target"
+				}
+				stats := c.compact(t, trigger)
+				if stats.LastRemoved > 0 {
+					logger.Infof("Compacted %s (%s): removed %d, reclaimed ~%d bytes",
+						t, trigger, stats.LastRemoved, stats.LastReclaimedBytes)
+				}
+			}
+		}
+	}()
+}
+
+// compactionCounts returns the current number of entries per data type.
+func (c *Controller) compactionCounts() map[string]int {
+	counts := map[string]int{compactAudit: c.Audit.Len()}
+	c.mu.RLock()
+	for _, revisions := range c.History {
+		counts[compactRevisions] += len(revisions)
+	}
+	for _, events := range c.Events {
+		counts[compactEvents] += len(events)
+	}
+	c.mu.RUnlock()
+	return counts
+}
+
+// compact applies the retention policy to one data type and records the
+// outcome. Returns the type's updated stats.
+func (c *Controller) compact(dataType, trigger string) CompactionStats {
+	c.compaction.running.Lock()
+	defer c.compaction.running.Unlock()
+	defer c.profileLoop("compactor."+dataType, time.Now())
+
+	retention := c.Compaction.Retention[dataType]
+	var cutoff time.Time
+	if retention.MaxAge > 0 {
+		cutoff = c.Clock.Now().Add(-retention.MaxAge)
+	}
+
+	// WHY SIZE AFTER: Removed entries are immutable snapshots, so they are
+	// measured after the store lock is released
+	var removed []interface{}
+	switch dataType {
+	case compactRevisions:
+		for _, rev := range c.compactRevisions(cutoff, retention.Target) {
+			removed = append(removed, rev)
+		}
+	case compactEvents:
+		for _, event := range c.compactEvents(cutoff, retention.Target) {
+			removed = append(removed, event)
+		}
+	case compactAudit:
+		for _, entry := range c.Audit.Prune(cutoff, retention.Target) {
+			removed = append(removed, entry)
+		}
+	}
+	var reclaimed int64
+	for _, item := range removed {
+		if data, err := json.Marshal(item); err == nil {
+			reclaimed += int64(len(data))
+		}
+	}
+	entries := c.compactionCounts()[dataType]
+
+	c.compaction.mu.Lock()
+	defer c.compaction.mu.Unlock()
+	stats := c.compaction.stats[dataType]
+	stats.Entries = entries
+	stats.Runs++
+	stats.LastRun = c.Clock.Now()
+	stats.LastTrigger = trigger
+	stats.LastRemoved = len(removed)
+	stats.LastReclaimedBytes = reclaimed
+	stats.TotalRemoved += len(removed)
+	stats.TotalReclaimed += reclaimed
+	return *stats
+}
+
+// compactRevisions removes revisions recorded before cutoff, then the
+// oldest ones while more than target remain. The latest revision of a
+// live resource is never removed.
+func (c *Controller) compactRevisions(cutoff time.Time, target int) []models.ResourceRevision {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	type candidate struct {
+		id    string
+		index int
+		at    time.Time
+	}
+	var candidates []candidate
+	total := 0
+	for id, revisions := range c.History {
+		total += len(revisions)
+		removable := len(revisions)
+		if _, live := c.ResourceDB[id]; live {
+			removable--
+		}
+		for i := 0; i < removable; i++ {
+			candidates = append(candidates, candidate{id, i, revisions[i].Timestamp})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if !candidates[i].at.Equal(candidates[j].at) {
+			return candidates[i].at.Before(candidates[j].at)
+		}
+		if candidates[i].id != candidates[j].id {
+			return candidates[i].id < candidates[j].id
+		}
+		return candidates[i].index < candidates[j].index
+	})
+
+	// drop[id] = how many of id's oldest revisions go
+	// WHY A PREFIX: Candidates are taken oldest first, and a resource's
+	// revisions are in time order, so each resource loses a prefix
+	drop := make(map[string]int)
+	for _, cand := range candidates {
+		expired := !cutoff.IsZero() && cand.at.Before(cutoff)
+		overTarget := target > 0 && total > target
+		if !expired && !overTarget {
+			break
+		}
+		drop[cand.id]++
+		total--
+	}
+
+	var removed []models.ResourceRevision
+	for id, n := range drop {
+		revisions := c.History[id]
+		removed = append(removed, revisions[:n]...)
+		if n == len(revisions) {
+			delete(c.History, id)
+			continue
+		}
+		// WHY COPY: Re-slicing would keep the old backing array alive
+		kept := make([]models.ResourceRevision, len(revisions)-n)
+		copy(kept, revisions[n:])
+		c.History[id] = kept
+	}
+	return removed
+}
+
+// compactEvents removes events recorded before cutoff, then the oldest
+// ones while more than target remain.
+func (c *Controller) compactEvents(cutoff time.Time, target int) []models.Event {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	type candidate struct {
+		id    string
+		index int
+		at    time.Time
+	}
+	var candidates []candidate
+	for id, events := range c.Events {
+		for i, event := range events {
+			candidates = append(candidates, candidate{id, i, event.Time})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if !candidates[i].at.Equal(candidates[j].at) {
+			return candidates[i].at.Before(candidates[j].at)
+		}
+		if candidates[i].id != candidates[j].id {
+			return candidates[i].id < candidates[j].id
+		}
+		return candidates[i].index < candidates[j].index
+	})
+
+	total := len(candidates)
+	drop := make(map[string]int)
+	for _, cand := range candidates {
+		expired := !cutoff.IsZero() && cand.at.Before(cutoff)
+		overTarget := target > 0 && total > target
+		if !expired && !overTarget {
+			break
+		}
+		drop[cand.id]++
+		total--
+	}
+
+	var removed []models.Event
+	for id, n := range drop {
+		events := c.Events[id]
+		removed = append(removed, events[:n]...)
+		if n == len(events) {
+			delete(c.Events, id)
+			continue
+		}
+		kept := make([]models.Event, len(events)-n)
+		copy(kept, events[n:])
+		c.Events[id] = kept
+	}
+	return removed
+}
+
+// compactionReport returns the policy and per-type stats.
+func (c *Controller) compactionReport() map[string]interface{} {
+	counts := c.compactionCounts()
+	c.compaction.mu.Lock()
+	defer c.compaction.mu.Unlock()
+	stats := make(map[string]CompactionStats, len(compactTypes))
+	for _, t := range compactTypes {
+		s := *c.compaction.stats[t]
+		s.Entries = counts[t]
+		stats[t] = s
+	}
+	retention := make(map[string]interface{}, len(compactTypes))
+	for t, p := range c.Compaction.Retention {
+		retention[t] = map[string]interface{}{"max_age": p.MaxAge.String(), "target": p.Target}
+	}
+	return map[string]interface{}{
+		"policy": map[string]interface{}{
+			"interval":  c.Compaction.Interval.String(),
+			"window":    c.Compaction.Window,
+			"retention": retention,
+		},
+		"in_window": c.Compaction.inWindow(c.Clock.Now()),
+		"stats":     stats,
+	}
+}
+
+// HandleGetCompaction handles GET /admin/compact
+func (c *Controller) HandleGetCompaction(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.compactionReport())
+}
+
+// HandleCompact handles POST /admin/compact
+// Query parameters: type (comma-separated data types; default all).
+// Runs immediately, whatever the window.
+func (c *Controller) HandleCompact(w http.ResponseWriter, r *http.Request) {
+	types := compactTypes
+	if v := r.URL.Query().Get("type"); v != "" {
+		types = nil
+		for _, t := range strings.Split(v, ",") {
+			t = strings.TrimSpace(t)
+			if _, ok := c.Compaction.Retention[t]; !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "type must be one of: " + strings.Join(compactTypes, ", ")})
+				return
+			}
+			types = append(types, t)
+		}
+	}
+
+	results := make(map[string]CompactionStats, len(types))
+	for _, t := range types {
+		results[t] = c.compact(t, "manual")
+	}
+	logger.Infof("Manual compaction of %s", strings.Join(types, ", "))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": results})
+}
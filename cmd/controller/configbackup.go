@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/Zhichengu1/mock-control-plane/pkg/provider"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// DEVICE CONFIGURATION BACKUP AND RESTORE
+// =============================================================================
+// After a factory reset or a hardware swap, the spec alone doesn't bring a
+// device back: presets and everything else set on the device are gone.
+// Providers that can dump a device's full settings (provider.ConfigBackuper,
+// e.g. Sony) get them saved as numbered snapshots:
+//
+//   POST /resources/{id}:backupConfig             → take a snapshot now
+//   GET  /resources/{id}/configSnapshots          → list (without the dumps)
+//   GET  /resources/{id}/configSnapshots/{n}      → one snapshot, with its dump
+//   POST /resources/{id}:restoreConfig?snapshot=N → push snapshot N back
+//
+// Each snapshot records the resource_version it was taken at, so it can be
+// matched to the spec in GET /resources/{id}/revisions. With
+// CONFIG_BACKUP_ON_CHANGE (default true) a snapshot is also taken after
+// every create and spec change. CONFIG_BACKUP_MAX_SNAPSHOTS (default 20)
+// caps how many are kept per resource; the oldest go first.
+//
+// A restore is refused while the resource is locked by someone else,
+// like any other change, and is recorded as an event.
+// =============================================================================
+
+// ConfigBackupPolicy configures configuration snapshots.
+type ConfigBackupPolicy struct {
+	// OnChange takes a snapshot after every create and spec change
+	OnChange bool
+
+	// MaxSnapshots caps snapshots kept per resource (0 = unlimited)
+	MaxSnapshots int
+}
+
+// loadConfigBackupPolicy reads the backup configuration from the
+// environment.
+func loadConfigBackupPolicy() ConfigBackupPolicy {
+	onChange := true
+	if v := os.Getenv("CONFIG_BACKUP_ON_CHANGE"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			logger.Warnf("Ignoring invalid CONFIG_BACKUP_ON_CHANGE %q", v)
+		} else {
+			onChange = parsed
+		}
+	}
+	return ConfigBackupPolicy{
+		OnChange:     onChange,
+		MaxSnapshots: envInt("CONFIG_BACKUP_MAX_SNAPSHOTS", 20),
+	}
+}
+
+// Snapshot triggers.
+const (
+	snapshotManual = "manual"
+	snapshotChange = "change"
+)
+
+// backupTarget resolves a resource and its ConfigBackuper.
+func (c *Controller) backupTarget(id string) (models.ForgeResource, provider.ConfigBackuper, error) {
+	c.mu.RLock()
+	stored, exists := c.ResourceDB[id]
+	var res models.ForgeResource
+	if exists {
+		res = *stored.DeepCopy()
+	}
+	c.mu.RUnlock()
+
+	if !exists {
+		return res, nil, errResourceNotFound
+	}
+	if res.Status.VendorID == "" {
+		return res, nil, fmt.Errorf("%w (phase %s)", errNoVendorDevice, res.Status.Phase)
+	}
+	p, exists := c.config().Providers[res.Spec.VendorType]
+	if !exists {
+		return res, nil, fmt.Errorf("provider %s not configured", res.Spec.VendorType)
+	}
+	backuper, supported := p.(provider.ConfigBackuper)
+	if !supported {
+		return res, nil, fmt.Errorf("configuration backup is %w %s", errUnsupported, res.Spec.VendorType)
+	}
+	return res, backuper, nil
+}
+
+// takeConfigSnapshot exports the device's settings and saves them as the
+// resource's next snapshot.
+func (c *Controller) takeConfigSnapshot(parent context.Context, id, trigger string) (*models.ConfigSnapshot, error) {
+	res, backuper, err := c.backupTarget(id)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(parent, 30*time.Second)
+	defer cancel()
+	release, err := c.acquireVendor(ctx, res.Spec.VendorType)
+	if err != nil {
+		return nil, err
+	}
+	config, err := backuper.ExportConfig(ctx, res.Status.VendorID)
+	release()
+	if err != nil {
+		c.recordWarningEvent(id, models.ReasonConfigBackupFailed, fmt.Sprintf("Configuration backup failed: %v", err))
+		return nil, err
+	}
+
+	digest := sha256.Sum256(config.Data)
+	snapshot := models.ConfigSnapshot{
+		ResourceID:      id,
+		ResourceVersion: res.ResourceVersion,
+		Trigger:         trigger,
+		TakenAt:         c.Clock.Now(),
+		Format:          config.Format,
+		SizeBytes:       len(config.Data),
+		Digest:          hex.EncodeToString(digest[:]),
+		Config:          config,
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stored, exists := c.ResourceDB[id]
+	if !exists {
+		return nil, errResourceGone
+	}
+	snapshots := c.configSnapshots[id]
+	snapshot.Number = 1
+	if len(snapshots) > 0 {
+		snapshot.Number = snapshots[len(snapshots)-1].Number + 1
+	}
+	snapshots = append(snapshots, snapshot)
+	if c.ConfigBackup.MaxSnapshots > 0 && len(snapshots) > c.ConfigBackup.MaxSnapshots {
+		snapshots = snapshots[len(snapshots)-c.ConfigBackup.MaxSnapshots:]
+	}
+	c.configSnapshots[id] = snapshots
+	c.recordEvent(stored, models.EventNormal, models.ReasonConfigBackedUp,
+		fmt.Sprintf("Configuration snapshot %d taken (%s, %d bytes, resource version %d)",
+			snapshot.Number, trigger, snapshot.SizeBytes, snapshot.ResourceVersion), "", "")
+	return &snapshot, nil
+}
+
+// recordWarningEvent records a Warning event on id if it still exists.
+func (c *Controller) recordWarningEvent(id, reason, message string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if res, exists := c.ResourceDB[id]; exists {
+		c.recordEvent(res, models.EventWarning, reason, message, "", "")
+	}
+}
+
+// snapshotAfterChangeLocked schedules a snapshot of res if its spec just
+// changed (prev is the previous revision, nil on create). Caller must hold
+// c.mu; the export runs in the background.
+func (c *Controller) snapshotAfterChangeLocked(res, prev *models.ForgeResource) {
+	if !c.ConfigBackup.OnChange || res.Status.VendorID == "" {
+		return
+	}
+	if prev != nil && prev.Status.VendorID != "" && reflect.DeepEqual(prev.Spec, res.Spec) {
+		return
+	}
+	if _, supported := c.config().Providers[res.Spec.VendorType].(provider.ConfigBackuper); !supported {
+		return
+	}
+	id := res.ID
+	go func() {
+		if _, err := c.takeConfigSnapshot(context.Background(), id, snapshotChange); err != nil {
+			logger.Warnf("Configuration snapshot of %s after a change failed: %v", id, err)
+		}
+	}()
+}
+
+// withoutConfig returns snapshot without its dump, for lists.
+func withoutConfig(snapshot models.ConfigSnapshot) models.ConfigSnapshot {
+	snapshot.Config = nil
+	return snapshot
+}
+
+// HandleBackupConfig handles POST /resources/{id}:backupConfig
+func (c *Controller) HandleBackupConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	snapshot, err := c.takeConfigSnapshot(vendorContext(r), mux.Vars(r)["id"], snapshotManual)
+	if err != nil {
+		writeOperationError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(withoutConfig(*snapshot))
+}
+
+// HandleListConfigSnapshots handles GET /resources/{id}/configSnapshots
+// Oldest first; the dumps are left out (see HandleGetConfigSnapshot).
+func (c *Controller) HandleListConfigSnapshots(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	c.mu.RLock()
+	_, exists := c.ResourceDB[id]
+	items := make([]models.ConfigSnapshot, 0, len(c.configSnapshots[id]))
+	for _, snapshot := range c.configSnapshots[id] {
+		items = append(items, withoutConfig(snapshot))
+	}
+	c.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if !exists && len(items) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "resource not found"})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+}
+
+// findConfigSnapshot returns snapshot number of resource id.
+func (c *Controller) findConfigSnapshot(id string, number int) (models.ConfigSnapshot, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, snapshot := range c.configSnapshots[id] {
+		if snapshot.Number == number {
+			return snapshot, true
+		}
+	}
+	return models.ConfigSnapshot{}, false
+}
+
+// snapshotNumber parses a snapshot number, writing a 400 if it is invalid.
+func snapshotNumber(w http.ResponseWriter, value string) (int, bool) {
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "snapshot must be a positive snapshot number"})
+		return 0, false
+	}
+	return number, true
+}
+
+// HandleGetConfigSnapshot handles GET /resources/{id}/configSnapshots/{n}
+func (c *Controller) HandleGetConfigSnapshot(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	w.Header().Set("Content-Type", "application/json")
+	number, ok := snapshotNumber(w, vars["n"])
+	if !ok {
+		return
+	}
+	snapshot, found := c.findConfigSnapshot(vars["id"], number)
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("no configuration snapshot %d", number)})
+		return
+	}
+	json.NewEncoder(w).Encode(snapshot)
+}
+
+// HandleRestoreConfig handles POST /resources/{id}:restoreConfig?snapshot=N
+// The snapshot must be named: restoring the wrong one onto live hardware
+// is worse than restoring none.
+func (c *Controller) HandleRestoreConfig(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	w.Header().Set("Content-Type", "application/json")
+	if !r.URL.Query().Has("snapshot") {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "snapshot is required (see GET /resources/" + id + "/configSnapshots)"})
+		return
+	}
+	number, ok := snapshotNumber(w, r.URL.Query().Get("snapshot"))
+	if !ok {
+		return
+	}
+	if c.rejectIfLocked(w, r, id) {
+		return
+	}
+	snapshot, found := c.findConfigSnapshot(id, number)
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("no configuration snapshot %d", number)})
+		return
+	}
+	res, backuper, err := c.backupTarget(id)
+	if err != nil {
+		writeOperationError(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(vendorContext(r), 30*time.Second)
+	defer cancel()
+	release, err := c.acquireVendor(ctx, res.Spec.VendorType)
+	if err != nil {
+		writeOperationError(w, err)
+		return
+	}
+	status, err := backuper.ImportConfig(ctx, res.Status.VendorID, snapshot.Config)
+	release()
+	c.forgetVendorRead(res.Spec.VendorType, res.Status.VendorID)
+	if err != nil {
+		c.recordWarningEvent(id, models.ReasonConfigRestoreFailed, fmt.Sprintf("Restoring configuration snapshot %d failed: %v", number, err))
+		writeOperationError(w, err)
+		return
+	}
+
+	c.mu.RLock()
+	stored, exists := c.ResourceDB[id]
+	c.mu.RUnlock()
+	if exists {
+		c.storeReadStatus(stored, status)
+	}
+	c.recordResourceEvent(id, models.ReasonConfigRestored,
+		fmt.Sprintf("Configuration snapshot %d (resource version %d) restored to the device", number, snapshot.ResourceVersion))
+
+	json.NewEncoder(w).Encode(models.ConfigRestore{Snapshot: withoutConfig(snapshot), Status: *status})
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/convert"
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/Zhichengu1/mock-control-plane/pkg/provider"
+	"github.com/Zhichengu1/mock-control-plane/pkg/validation"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// CONFIG KEY CHECKS AND CAPABILITIES
+// =============================================================================
+// spec.config keys are checked against the vendor's registered keys (see
+// pkg/validation/configkeys.go): unknown keys ("sony_modle"), values of
+// the wrong type and values that aren't allowed. CONFIG_KEY_POLICY says
+// what a finding does:
+//
+//   warn    the spec is accepted; the resource gets a ConfigWarning event
+//           (default)
+//   reject  the create or update fails with 400 and the violations
+//   off     nothing is checked
+//
+//   CONFIG_KEY_POLICY="reject"                      everywhere
+//   CONFIG_KEY_POLICY="*=warn,prod=reject"          per namespace
+//
+// An update is only judged on the keys it adds or changes, so a spec
+// stored before the policy tightened can still be updated.
+// POST /resources:simulate reports findings as a config-keys check.
+//
+// GET /capabilities lists, for every configured vendor, the optional
+// operations its provider supports, what it converts to (see
+// pkg/convert) and its config keys; GET /capabilities/{vendor} one.
+// =============================================================================
+
+// Config key policies.
+const (
+	configKeysWarn   = "warn"
+	configKeysReject = "reject"
+	configKeysOff    = "off"
+)
+
+// ConfigKeyPolicy decides what config key findings do, per namespace.
+type ConfigKeyPolicy struct {
+	// Default applies to namespaces without an entry
+	Default string `json:"default"`
+
+	// Namespaces maps namespace → policy
+	Namespaces map[string]string `json:"namespaces,omitempty"`
+}
+
+// loadConfigKeyPolicy reads CONFIG_KEY_POLICY: a policy, or
+// "namespace=policy" entries with "*" for the default.
+func loadConfigKeyPolicy() ConfigKeyPolicy {
+	policy := ConfigKeyPolicy{Default: configKeysWarn, Namespaces: make(map[string]string)}
+	for _, entry := range strings.Split(os.Getenv("CONFIG_KEY_POLICY"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		namespace, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			namespace, value = "*", entry
+		}
+		if value != configKeysWarn && value != configKeysReject && value != configKeysOff {
+			logger.Warnf("Ignoring CONFIG_KEY_POLICY entry %q: policy must be %s, %s or %s", entry, configKeysWarn, configKeysReject, configKeysOff)
+			continue
+		}
+		if namespace == "*" {
+			policy.Default = value
+		} else {
+			policy.Namespaces[namespaceKey(namespace)] = value
+		}
+	}
+	return policy
+}
+
+// policyFor returns the policy of namespace.
+func (p ConfigKeyPolicy) policyFor(namespace string) string {
+	if value, ok := p.Namespaces[namespaceKey(namespace)]; ok {
+		return value
+	}
+	return p.Default
+}
+
+// checkConfigKeys checks the config keys of spec that differ from
+// previous (nil for a create) and splits the findings by the namespace's
+// policy: rejected ones fail the request, warnings are recorded.
+func (c *Controller) checkConfigKeys(namespace string, previous map[string]interface{}, spec models.ResourceSpec) (rejected, warnings validation.Violations) {
+	policy := c.config().ConfigKeyPolicy.policyFor(namespace)
+	if policy == configKeysOff || len(spec.Config) == 0 {
+		return nil, nil
+	}
+	changed := make(map[string]interface{}, len(spec.Config))
+	for key, value := range spec.Config {
+		if old, had := previous[key]; !had || !reflect.DeepEqual(old, value) {
+			changed[key] = value
+		}
+	}
+	findings := c.ConfigKeys.Check(spec.VendorType, changed)
+	if policy == configKeysReject {
+		return findings, nil
+	}
+	return nil, findings
+}
+
+// warnConfigKeysLocked records warnings as a ConfigWarning event on res.
+// Caller must hold c.mu.
+func (c *Controller) warnConfigKeysLocked(res *models.ForgeResource, warnings validation.Violations) {
+	if len(warnings) == 0 {
+		return
+	}
+	messages := make([]string, len(warnings))
+	for i, v := range warnings {
+		messages[i] = v.Message
+	}
+	logger.Warnf("%s: config keys: %s", res.ID, strings.Join(messages, "; "))
+	c.recordEvent(res, models.EventWarning, models.ReasonConfigWarning,
+		"spec.config: "+strings.Join(messages, "; "), "", "")
+}
+
+// optionalCapabilities names the optional provider interfaces (see
+// pkg/provider/interface.go) for GET /capabilities.
+var optionalCapabilities = []struct {
+	name     string
+	supports func(p provider.VendorProvider) bool
+}{
+	{"actions", func(p provider.VendorProvider) bool { _, ok := p.(provider.ProviderAction); return ok }},
+	{"capacity", func(p provider.VendorProvider) bool { _, ok := p.(provider.CapacityReporter); return ok }},
+	{"config-backup", func(p provider.VendorProvider) bool { _, ok := p.(provider.ConfigBackuper); return ok }},
+	{"connection-diagnosis", func(p provider.VendorProvider) bool { _, ok := p.(provider.ConnectionDiagnoser); return ok }},
+	{"destination-probe", func(p provider.VendorProvider) bool { _, ok := p.(provider.DestinationProber); return ok }},
+	{"discovery", func(p provider.VendorProvider) bool { _, ok := p.(provider.Discoverer); return ok }},
+	{"hosts", func(p provider.VendorProvider) bool { _, ok := p.(provider.HostLister); return ok }},
+	{"metrics", func(p provider.VendorProvider) bool { _, ok := p.(provider.MetricsReporter); return ok }},
+	{"paged-discovery", func(p provider.VendorProvider) bool { _, ok := p.(provider.PagedDiscoverer); return ok }},
+	{"passthrough", func(p provider.VendorProvider) bool { _, ok := p.(provider.Passthrough); return ok }},
+	{"power", func(p provider.VendorProvider) bool { _, ok := p.(provider.PowerController); return ok }},
+	{"preflight", func(p provider.VendorProvider) bool { _, ok := p.(provider.Preflighter); return ok }},
+	{"ptz", func(p provider.VendorProvider) bool { _, ok := p.(provider.CameraController); return ok }},
+	{"recording", func(p provider.VendorProvider) bool { _, ok := p.(provider.Recorder); return ok }},
+	{"teardown-progress", func(p provider.VendorProvider) bool { _, ok := p.(provider.TeardownTracker); return ok }},
+}
+
+// VendorCapabilities is one vendor in GET /capabilities.
+type VendorCapabilities struct {
+	Vendor      string `json:"vendor"`
+	Description string `json:"description,omitempty"`
+
+	// Operations are the optional operations the provider supports
+	Operations []string `json:"operations"`
+
+	// Resolutions, Codecs and StreamSchemes come from the vendor's
+	// conversion profile, if it has one
+	Resolutions   []string `json:"resolutions,omitempty"`
+	Codecs        []string `json:"codecs,omitempty"`
+	StreamSchemes []string `json:"stream_schemes,omitempty"`
+
+	// ConfigKeys are the spec.config keys it understands; ConfigKeysChecked
+	// is false if they aren't registered (unknown keys aren't reported)
+	ConfigKeys        []validation.ConfigKey `json:"config_keys"`
+	ConfigKeysChecked bool                   `json:"config_keys_checked"`
+}
+
+// vendorCapabilities describes the configured vendor name.
+func (c *Controller) vendorCapabilities(name string) VendorCapabilities {
+	caps := VendorCapabilities{
+		Vendor:            name,
+		Operations:        []string{},
+		ConfigKeys:        c.ConfigKeys.Keys(name),
+		ConfigKeysChecked: c.ConfigKeys.Known(name),
+	}
+	for _, capability := range optionalCapabilities {
+		if capability.supports(c.config().Providers[name]) {
+			caps.Operations = append(caps.Operations, capability.name)
+		}
+	}
+	if profile, ok := convert.Profiles[name]; ok {
+		caps.Description = profile.Description
+		caps.Resolutions, caps.Codecs, caps.StreamSchemes = profile.Resolutions, profile.Codecs, profile.StreamSchemes
+	}
+	return caps
+}
+
+// HandleListCapabilities handles GET /capabilities
+func (c *Controller) HandleListCapabilities(w http.ResponseWriter, r *http.Request) {
+	cfg := c.config()
+	names := make([]string, 0, len(cfg.Providers))
+	for name := range cfg.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	vendors := make([]VendorCapabilities, 0, len(names))
+	for _, name := range names {
+		vendors = append(vendors, c.vendorCapabilities(name))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"vendors":           vendors,
+		"config_key_policy": cfg.ConfigKeyPolicy,
+	})
+}
+
+// HandleGetCapabilities handles GET /capabilities/{vendor}
+func (c *Controller) HandleGetCapabilities(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	name := mux.Vars(r)["vendor"]
+	if _, exists := c.config().Providers[name]; !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("vendor %s not configured", name)})
+		return
+	}
+	json.NewEncoder(w).Encode(c.vendorCapabilities(name))
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/convert"
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/Zhichengu1/mock-control-plane/pkg/validation"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// SPEC CONVERSION
+// =============================================================================
+// POST /resources/{id}:convert?to=aws previews the resource's spec
+// translated for another vendor (see pkg/convert). Nothing is created;
+// the caller reviews the changes and unmapped fields, then creates the
+// new resource with the returned spec.
+//
+// For ?to=aws the response also shows the MediaLive channel the spec maps
+// to (aws_channel: video descriptions per ladder rendition, output
+// groups, destinations), so an ABR ladder can be checked rung by rung.
+// =============================================================================
+
+// ConvertResponse is the conversion result plus any validation problems
+// the converted spec still has (e.g. an SRT stream that lost its latency).
+type ConvertResponse struct {
+	ResourceID string `json:"resource_id"`
+	*convert.Result
+	Violations validation.Violations `json:"violations,omitempty"`
+
+	// AWSChannel is the MediaLive CreateChannel request for ?to=aws
+	AWSChannel *models.AWSResourceRequest `json:"aws_channel,omitempty"`
+}
+
+// HandleConvertResource handles POST /resources/{id}:convert?to={vendor}
+func (c *Controller) HandleConvertResource(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	to := r.URL.Query().Get("to")
+	if to == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "to is required (e.g. ?to=aws)"})
+		return
+	}
+
+	c.mu.RLock()
+	stored, exists := c.ResourceDB[id]
+	var spec models.ResourceSpec
+	var resourceType, name string
+	if exists {
+		spec, resourceType, name = stored.DeepCopy().Spec, stored.Type, stored.Name
+	}
+	c.mu.RUnlock()
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "resource not found"})
+		return
+	}
+
+	result, err := convert.Convert(spec, to)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	response := ConvertResponse{
+		ResourceID: id,
+		Result:     result,
+		Violations: c.Validators.Validate(resourceType, result.Spec),
+	}
+	if result.To == "aws" {
+		channel := convert.MediaLiveChannel(name, result.Spec)
+		response.AWSChannel = &channel
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/Zhichengu1/mock-control-plane/pkg/provider"
+)
+
+// =============================================================================
+// ASYNC DELETION
+// =============================================================================
+// Deleting a cloud channel can take minutes on the vendor side, far longer
+// than a client should hold a request open. DELETE /resources/{id} now:
+//
+//   1. marks the resource Terminating and answers 202 with a task
+//      (GET /tasks/{id}); a second DELETE returns the same task
+//   2. a worker asks the vendor to delete, then polls its teardown
+//      (provider.TeardownTracker) every DELETE_POLL_INTERVAL, reporting
+//      progress on the task and as DeleteProgress events
+//   3. once the vendor reports the device gone, the finalizer removes the
+//      record (tombstone revision + Deleted event)
+//
+// If the vendor refuses the delete, the resource goes back to its previous
+// status. If teardown fails or doesn't finish within DELETE_TIMEOUT, the
+// resource is marked Failed; DELETE it again to retry.
+//
+// While Terminating, the status isn't refreshed from the vendor and spec
+// updates and stop/start are refused.
+//
+// With DELETE_GRACE_PERIOD, step 2 waits out the grace period first and
+// the deletion can be restored until then (see softdelete.go); a resource
+// with finalizers waits for them to be removed (see finalizers.go).
+// =============================================================================
+
+// phaseTerminating marks a resource whose vendor device is being deleted.
+const phaseTerminating = "Terminating"
+
+// errResourceTerminating refuses operations on a resource being deleted.
+var errResourceTerminating = errors.New("resource is being deleted")
+
+// DeletePolicy configures async deletion.
+type DeletePolicy struct {
+	// PollInterval is how often vendor teardown progress is checked
+	PollInterval time.Duration
+
+	// Timeout is how long teardown may take before the delete fails
+	Timeout time.Duration
+
+	// GracePeriod delays the vendor delete so it can be restored
+	// (0 = delete right away; see softdelete.go)
+	GracePeriod time.Duration
+}
+
+// loadDeletePolicy reads the deletion configuration from the environment.
+func loadDeletePolicy() DeletePolicy {
+	return DeletePolicy{
+		PollInterval: envDuration("DELETE_POLL_INTERVAL", 2*time.Second),
+		// WHY 15m: The slowest vendor teardowns we've seen (cloud channels
+		// with several inputs) take about five minutes
+		Timeout:     envDuration("DELETE_TIMEOUT", 15*time.Minute),
+		GracePeriod: envDuration("DELETE_GRACE_PERIOD", 0),
+	}
+}
+
+// beginDeletion marks resource id Terminating and starts the deletion
+// worker, or schedules it after the grace period. If a deletion is
+// already running (or scheduled), its task is returned instead.
+// ctx carries the request ID for audit records and the principal for
+// resource locks; it isn't cancelled with the request.
+func (c *Controller) beginDeletion(ctx context.Context, id, requestedBy string) (*models.Task, error) {
+	c.mu.Lock()
+	stored, exists := c.ResourceDB[id]
+	if !exists {
+		c.mu.Unlock()
+		return nil, errResourceNotFound
+	}
+	if err := c.lockConflictLocked(ctx, id); err != nil {
+		c.mu.Unlock()
+		return nil, err
+	}
+	if err := c.preconditionLocked(ctx, stored); err != nil {
+		c.mu.Unlock()
+		return nil, err
+	}
+	if task := c.runningTaskLocked(models.TaskDelete, id); task != nil {
+		snapshot := *task
+		c.mu.Unlock()
+		return &snapshot, nil
+	}
+	previous := stored.Status
+	if c.Deletion.GracePeriod > 0 && !cascaded(ctx) {
+		task := c.scheduleDeletionLocked(ctx, stored, requestedBy, previous, c.Clock.Now().Add(c.Deletion.GracePeriod))
+		snapshot := *task
+		c.mu.Unlock()
+		return &snapshot, nil
+	}
+	task := c.startTaskLocked(models.TaskDelete, stored, requestedBy, "Waiting for the vendor to accept the delete")
+	stored.Status.Phase = phaseTerminating
+	stored.Status.Message = "Deleting from vendor (task " + task.ID + ")"
+	stored.UpdatedAt = c.Clock.Now()
+	owned := c.cascadeLocked(stored, task.ID)
+	finalizing := c.awaitFinalizersLocked(ctx, stored, task.ID, previous)
+	c.recordRevision(stored, "terminating", false)
+	if !finalizing {
+		c.recordEvent(stored, models.EventNormal, models.ReasonDeleting,
+			fmt.Sprintf("Deleting %s device %s (task %s)", stored.Spec.VendorType, stored.Status.VendorID, task.ID),
+			phaseTerminating, "")
+	}
+	res := stored.DeepCopy()
+	snapshot := *task
+	c.mu.Unlock()
+
+	c.deleteOwned(ctx, id, owned, requestedBy)
+	if !finalizing {
+		go c.runDeletion(ctx, task.ID, res, previous)
+	}
+	return &snapshot, nil
+}
+
+// runDeletion deletes res's vendor device, waits for the teardown and
+// then removes the record (the finalizer). previous is the status to
+// restore if the vendor refuses the delete.
+func (c *Controller) runDeletion(ctx context.Context, taskID string, res *models.ForgeResource, previous models.ResourceStatus) {
+	vendor, vendorID := res.Spec.VendorType, res.Status.VendorID
+	if vendorID == "" {
+		// Nothing at the vendor: only finalizers held the resource
+		c.finalizeDeletion(taskID, res.ID, "Deleted from controller (no vendor device)")
+		return
+	}
+	selectedProvider, exists := c.config().Providers[vendor]
+	if !exists {
+		c.failDeletion(taskID, res.ID, fmt.Errorf("provider %s not configured", vendor), &previous)
+		return
+	}
+
+	// Step 1: Ask the vendor to delete
+	callCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	release, err := c.acquireVendor(callCtx, vendor)
+	if err == nil {
+		err = selectedProvider.Delete(callCtx, vendorID)
+		release()
+	}
+	cancel()
+	if err != nil {
+		c.failDeletion(taskID, res.ID, fmt.Errorf("failed to delete from vendor: %w", err), &previous)
+		return
+	}
+	c.updateTask(taskID, 0, "Vendor teardown started")
+
+	// Step 2: Wait for the device to go away
+	// WHY EVENTS AT QUARTERS: A poll every few seconds would flood the
+	// event list; the task has the fine-grained progress
+	reported := 0
+	err = c.waitForTeardown(ctx, selectedProvider, vendor, vendorID, func(progress models.TeardownProgress) {
+		c.updateTask(taskID, progress.Percent, progress.Message)
+		if quarter := progress.Percent / 25 * 25; !progress.Done && quarter > reported {
+			reported = quarter
+			c.mu.Lock()
+			if stored, exists := c.ResourceDB[res.ID]; exists {
+				c.recordEvent(stored, models.EventNormal, models.ReasonDeleteProgress,
+					fmt.Sprintf("Vendor teardown %d%% complete: %s", progress.Percent, progress.Message),
+					phaseTerminating, "")
+			}
+			c.mu.Unlock()
+		}
+	})
+	if err != nil {
+		c.failDeletion(taskID, res.ID, err, nil)
+		return
+	}
+
+	// Step 3: Finalize - the vendor device is gone, remove the record
+	c.finalizeDeletion(taskID, res.ID, "Deleted from vendor and controller")
+	logger.Infof("Deleted %s (%s device %s, task %s)", res.ID, vendor, vendorID, taskID)
+}
+
+// finalizeDeletion removes resource id's record once its deletion is done
+// and finishes the task.
+func (c *Controller) finalizeDeletion(taskID, id, message string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if stored, exists := c.ResourceDB[id]; exists {
+		// WHY TOMBSTONE: History outlives the resource for incident analysis
+		c.withTxLocked(func(tx *storeTx) error {
+			c.removeResource(tx, stored, "deleted", message)
+			return nil
+		})
+	}
+	c.finishTaskLocked(taskID, nil, "Deleted")
+}
+
+// failDeletion records a failed deletion. With restore, the resource goes
+// back to that status (nothing was deleted); otherwise it is marked
+// Failed, since the vendor device may be half torn down.
+func (c *Controller) failDeletion(taskID, id string, err error, restore *models.ResourceStatus) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	logger.Errorf("Delete of %s failed (task %s): %v", id, taskID, err)
+	if stored, exists := c.ResourceDB[id]; exists && stored.Status.Phase == phaseTerminating {
+		if restore != nil {
+			stored.Status = *restore
+		} else {
+			stored.Status.Phase = "Failed"
+			stored.Status.HealthStatus = "unhealthy"
+			stored.Status.Message = "Delete failed; DELETE again to retry: " + err.Error()
+		}
+		stored.UpdatedAt = c.Clock.Now()
+		c.recordRevision(stored, "delete-failed", false)
+		c.recordEvent(stored, models.EventWarning, models.ReasonDeleteFailed,
+			fmt.Sprintf("Delete failed (task %s): %v", taskID, err), stored.Status.Phase, stored.Status.HealthStatus)
+	}
+	c.finishTaskLocked(taskID, err, "Delete failed")
+}
+
+// waitForTeardown polls the vendor until vendorID's teardown is done,
+// passing each report to onProgress. Providers without TeardownTracker
+// delete synchronously, so there is nothing to wait for. Vendor errors
+// are retried until DELETE_TIMEOUT, except a device that isn't being
+// deleted at all.
+func (c *Controller) waitForTeardown(ctx context.Context, p provider.VendorProvider, vendor, vendorID string, onProgress func(models.TeardownProgress)) error {
+	tracker, ok := p.(provider.TeardownTracker)
+	if !ok {
+		return nil
+	}
+	deadline := c.Clock.Now().Add(c.Deletion.Timeout)
+	for {
+		callCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		release, err := c.acquireVendor(callCtx, vendor)
+		var progress *models.TeardownProgress
+		if err == nil {
+			progress, err = tracker.TeardownProgress(callCtx, vendorID)
+			release()
+		}
+		cancel()
+
+		switch {
+		case err == nil:
+			if onProgress != nil {
+				onProgress(*progress)
+			}
+			if progress.Done {
+				return nil
+			}
+		case errors.Is(err, provider.ErrConflict):
+			return fmt.Errorf("vendor teardown stopped: %w", err)
+		default:
+			logger.Warnf("Checking teardown of %s device %s: %v", vendor, vendorID, err)
+		}
+
+		if !c.Clock.Now().Before(deadline) {
+			return fmt.Errorf("vendor teardown of %s not finished after %s", vendorID, c.Deletion.Timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.Clock.After(c.Deletion.PollInterval):
+		}
+	}
+}
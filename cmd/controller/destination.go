@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/Zhichengu1/mock-control-plane/pkg/probe"
+	"github.com/Zhichengu1/mock-control-plane/pkg/provider"
+)
+
+// =============================================================================
+// DESTINATION PROBE ON CREATE
+// =============================================================================
+// With "spec.probe_destination": true, HandleCreateResource checks that
+// spec.stream_url answers before anything is provisioned (RTMP or SRT
+// handshake, else a TCP connect; see pkg/probe). A device pointed at a
+// dead ingest would report Running and stream into the void:
+//
+//   422 {"error": "destination unreachable: srt://ingest:9000: no SRT
+//        handshake from ingest:9000 before the timeout", "probe": {...}}
+//
+// The probe runs from the vendor's side when the provider can (Sony probes
+// from the site network, which is where the device streams from), else
+// from the controller. DESTINATION_PROBE_FROM=controller always probes
+// from the controller. If the vendor can't run the probe, the controller
+// probes instead. Each probe waits at most DESTINATION_PROBE_TIMEOUT
+// (default 3s).
+//
+// Destinations that can't be probed from the caller's side (SRT listener
+// mode, udp://) pass; the log says they were skipped.
+// =============================================================================
+
+// probeFromController is the probe origin reported for the controller.
+const probeFromController = "controller"
+
+// destinationUnreachableError aborts a create whose stream destination
+// didn't answer.
+type destinationUnreachableError struct {
+	probe *models.DestinationProbe
+}
+
+func (e *destinationUnreachableError) Error() string {
+	return "destination unreachable: " + e.probe.URL + ": " + e.probe.Message
+}
+
+// writeDestinationUnreachable reports a failed probe with its details.
+func writeDestinationUnreachable(w http.ResponseWriter, e *destinationUnreachableError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]interface{}{"error": e.Error(), "probe": e.probe})
+}
+
+// probeDestination probes resource's stream destination and returns an
+// error if the create must not go ahead.
+func (c *Controller) probeDestination(parent context.Context, selectedProvider provider.VendorProvider, resource *models.ForgeResource) error {
+	if resource.Spec.StreamURL == "" {
+		return &createError{http.StatusBadRequest, "probe_destination requires a stream_url"}
+	}
+
+	// Step 1: From the vendor's side, if it can
+	var result *models.DestinationProbe
+	prober, canProbe := selectedProvider.(provider.DestinationProber)
+	if canProbe && c.DestinationProbeFrom != probeFromController {
+		ctx, cancel := context.WithTimeout(parent, c.DestinationProbeTimeout+5*time.Second)
+		release, err := c.acquireVendor(ctx, resource.Spec.VendorType)
+		if err == nil {
+			result, err = prober.ProbeDestination(ctx, resource.Spec.StreamURL)
+			release()
+		}
+		cancel()
+		if err != nil {
+			logger.Warnf("%s couldn't probe %s, probing from the controller: %v", resource.Spec.VendorType, resource.Spec.StreamURL, err)
+			result = nil
+		}
+	}
+
+	// Step 2: ... else from the controller
+	if result == nil {
+		ctx, cancel := context.WithTimeout(parent, c.DestinationProbeTimeout)
+		var err error
+		result, err = probe.Destination(ctx, resource.Spec.StreamURL)
+		cancel()
+		if err != nil {
+			return &createError{http.StatusBadRequest, err.Error()}
+		}
+		result.From = probeFromController
+	}
+
+	switch {
+	case result.Method == models.ProbeSkipped:
+		logger.Infof("Skipped probing %s for %q: %s", result.URL, resource.Name, result.Message)
+	case !result.Reachable:
+		logger.Infof("Create of %q aborted: %s unreachable from %s (%s)", resource.Name, result.URL, result.From, result.Message)
+		return &destinationUnreachableError{probe: result}
+	default:
+		logger.Debugf("%s reachable from %s for %q (%s, %.1fms)", result.URL, result.From, resource.Name, result.Method, result.RTTMillis)
+	}
+	return nil
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/audit"
+	"github.com/Zhichengu1/mock-control-plane/pkg/client"
+	"github.com/Zhichengu1/mock-control-plane/pkg/logging"
+	"github.com/Zhichengu1/mock-control-plane/pkg/provider"
+)
+
+// =============================================================================
+// DIAGNOSTIC BUNDLE
+// =============================================================================
+// GET /admin/diagnostics returns a .tar.gz to attach to a support ticket,
+// so nobody has to collect context by hand from a failing pod:
+//
+//   config.json            environment (secrets redacted) and settings
+//   logs.txt               the last DIAGNOSTICS_LOG_LINES log lines
+//   provider-health.json   current provider health, plus vendor call
+//                          stats per 5 minutes over ?window= (default 1h)
+//   goroutines.txt         a full goroutine dump
+//   store.json             resource, history, event, audit and queue counts
+//   manifest.json          SHA-256 and size of every file above
+//   manifest.sig           HMAC-SHA256 of manifest.json
+//
+// WHY SIGNED: A bundle passes through email and ticket systems; support
+// verifies manifest.sig with DIAGNOSTICS_SIGNING_KEY, and the manifest's
+// checksums, to know the files are what this controller produced. Without
+// a key the bundle is still produced, with "signed": false.
+//
+// REDACTION: Environment variables whose names look secret are replaced
+// by REDACTED, URLs lose their userinfo and secret query values, and every
+// redacted value is also scrubbed from all other files (a key that found
+// its way into a log line doesn't leak through logs.txt).
+// =============================================================================
+
+// diagnosticsHistoryBucket is the width of each provider call stats bucket.
+const diagnosticsHistoryBucket = 5 * time.Minute
+
+// secretEnvNames are substrings of environment variable names whose values
+// never leave the controller.
+var secretEnvNames = []string{"KEY", "SECRET", "TOKEN", "PASSWORD", "PASSPHRASE", "CREDENTIAL", "WEBHOOK", "AUTH"}
+
+// diagnosticsFile is one file in the bundle.
+type diagnosticsFile struct {
+	name string
+	data []byte
+}
+
+// ProviderCallBucket is one provider's vendor call stats for a time slice.
+type ProviderCallBucket struct {
+	Start time.Time `json:"start"`
+	ProviderCallStats
+}
+
+// HandleDiagnostics handles GET /admin/diagnostics
+// Query parameters: window (provider call history to include, default 1h).
+func (c *Controller) HandleDiagnostics(w http.ResponseWriter, r *http.Request) {
+	window := time.Hour
+	if v := r.URL.Query().Get("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "window must be a positive duration like \"1h\""})
+			return
+		}
+		window = d
+	}
+
+	// Step 1: Collect (health checks talk to the vendors)
+	ctx, cancel := context.WithTimeout(vendorContext(r), 5*time.Second)
+	defer cancel()
+	now := c.Clock.Now()
+	environment, secrets := sanitizedEnvironment()
+	files := []diagnosticsFile{
+		{"config.json", diagnosticsJSON(map[string]interface{}{
+			"environment": environment,
+			"settings":    c.diagnosticsSettings(),
+		})},
+		{"logs.txt", []byte(strings.Join(c.LogTail.Lines(), "\n") + "\n")},
+		{"provider-health.json", diagnosticsJSON(c.diagnosticsProviderHealth(ctx, now, window))},
+		{"goroutines.txt", goroutineDump()},
+		{"store.json", diagnosticsJSON(c.diagnosticsStore())},
+	}
+
+	// Step 2: Scrub secrets that leaked outside the environment
+	for i := range files {
+		files[i].data = scrubSecrets(files[i].data, secrets)
+	}
+
+	// Step 3: Manifest and signature
+	manifest, signature := c.diagnosticsManifest(files, now)
+	files = append(files, diagnosticsFile{"manifest.json", manifest})
+	if signature != "" {
+		files = append(files, diagnosticsFile{"manifest.sig", []byte(signature + "\n")})
+	}
+
+	bundle, err := writeTarGz(files, now)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "failed to build bundle: " + err.Error()})
+		return
+	}
+
+	principal, _ := principalFrom(r.Context())
+	logger.Infof("Diagnostic bundle (%d bytes) downloaded by %s", len(bundle), principal.Name)
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf("attachment; filename=\"forge-diagnostics-%s.tar.gz\"", now.UTC().Format("20060102T150405Z")))
+	if signature != "" {
+		w.Header().Set("X-Forge-Signature", signature)
+	}
+	w.Write(bundle)
+}
+
+// sanitizedEnvironment returns the process environment with secrets
+// redacted, and the redacted values (for scrubSecrets).
+func sanitizedEnvironment() (map[string]string, []string) {
+	environment := make(map[string]string)
+	var secrets []string
+	for _, entry := range os.Environ() {
+		name, value, _ := strings.Cut(entry, "=")
+		if isSecretEnvName(name) {
+			environment[name] = "REDACTED"
+			if value != "" {
+				secrets = append(secrets, value)
+			}
+			// WHY SPLIT: FORGE_API_KEYS holds "name:role:key,..."; each key
+			// must be scrubbed on its own wherever it appears
+			for _, part := range strings.FieldsFunc(value, func(r rune) bool { return strings.ContainsRune(",;: ", r) }) {
+				if len(part) >= 8 {
+					secrets = append(secrets, part)
+				}
+			}
+			continue
+		}
+		if u, err := url.Parse(value); err == nil && u.Scheme != "" && u.Host != "" {
+			value = client.RedactURL(u)
+		}
+		environment[name] = value
+	}
+	return environment, secrets
+}
+
+// isSecretEnvName reports whether an environment variable name looks secret.
+func isSecretEnvName(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, secret := range secretEnvNames {
+		if strings.Contains(upper, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// scrubSecrets replaces every occurrence of a secret in data.
+// Longest first, so a secret containing another is replaced whole.
+func scrubSecrets(data []byte, secrets []string) []byte {
+	sorted := append([]string(nil), secrets...)
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	for _, secret := range sorted {
+		data = bytes.ReplaceAll(data, []byte(secret), []byte("REDACTED"))
+	}
+	return data
+}
+
+// diagnosticsSettings reports the controller's effective configuration.
+func (c *Controller) diagnosticsSettings() map[string]interface{} {
+	cfg := c.config()
+	return map[string]interface{}{
+		"go_version":    runtime.Version(),
+		"providers":     sortedKeys(cfg.Providers),
+		"base_path":     c.BasePath,
+		"api_keys":      len(cfg.APIKeys),
+		"log_levels":    logging.Snapshot(),
+		"max_revisions": c.MaxRevisions,
+		"limits": map[string]int{
+			"max_resources":               cfg.MaxResources,
+			"max_resources_per_namespace": cfg.MaxResourcesPerNamespace,
+			"max_events_per_resource":     c.MaxEventsPerResource,
+		},
+		"reconciler": map[string]interface{}{
+			"interval":           c.Reconcile.Interval.String(),
+			"workers":            c.Reconcile.Workers,
+			"vendor_concurrency": c.Reconcile.VendorConcurrency,
+		},
+		"compaction": c.compactionReport(),
+	}
+}
+
+// diagnosticsProviderHealth reports current provider health and vendor
+// call stats per provider in diagnosticsHistoryBucket slices over window.
+func (c *Controller) diagnosticsProviderHealth(ctx context.Context, now time.Time, window time.Duration) map[string]interface{} {
+	overall, items := c.providersHealth(ctx, defaultProviderHealthWindow)
+
+	since := now.Add(-window)
+	calls := c.Audit.Query(audit.Filter{Kind: audit.KindVendorCall, Since: since})
+	history := make(map[string][]ProviderCallBucket)
+	for name, p := range c.config().Providers {
+		hosts := make(map[string]bool)
+		if lister, ok := p.(provider.HostLister); ok {
+			for _, host := range lister.APIHosts() {
+				hosts[host] = true
+			}
+		}
+		buckets := []ProviderCallBucket{}
+		for start := since.Truncate(diagnosticsHistoryBucket); start.Before(now); start = start.Add(diagnosticsHistoryBucket) {
+			end := start.Add(diagnosticsHistoryBucket)
+			var slice []audit.Entry
+			for _, call := range calls {
+				if !call.Time.Before(start) && call.Time.Before(end) {
+					slice = append(slice, call)
+				}
+			}
+			buckets = append(buckets, ProviderCallBucket{Start: start, ProviderCallStats: providerCallStats(slice, hosts)})
+		}
+		history[name] = buckets
+	}
+	return map[string]interface{}{
+		"status":  overall,
+		"items":   items,
+		"window":  window.String(),
+		"bucket":  diagnosticsHistoryBucket.String(),
+		"history": history,
+	}
+}
+
+// goroutineDump returns every goroutine's stack.
+func goroutineDump() []byte {
+	var buf bytes.Buffer
+	pprof.Lookup("goroutine").WriteTo(&buf, 2)
+	return buf.Bytes()
+}
+
+// diagnosticsStore reports store sizes and runtime memory.
+func (c *Controller) diagnosticsStore() map[string]interface{} {
+	counts := c.compactionCounts()
+
+	c.mu.RLock()
+	byVendor := make(map[string]int)
+	byPhase := make(map[string]int)
+	byNamespace := make(map[string]int)
+	for _, res := range c.ResourceDB {
+		byVendor[res.Spec.VendorType]++
+		byPhase[res.Status.Phase]++
+		byNamespace[namespaceKey(res.Namespace)]++
+	}
+	resources := map[string]interface{}{
+		"total":        len(c.ResourceDB),
+		"by_vendor":    byVendor,
+		"by_phase":     byPhase,
+		"by_namespace": byNamespace,
+	}
+	adoptions, rollouts, histories := len(c.Adoptions), len(c.Rollouts), len(c.History)
+	c.mu.RUnlock()
+
+	c.watch.mu.Lock()
+	watchers := len(c.watch.subs)
+	c.watch.mu.Unlock()
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	return map[string]interface{}{
+		"resources":          resources,
+		"resource_histories": histories,
+		"revisions":          counts[compactRevisions],
+		"events":             counts[compactEvents],
+		"audit_entries":      counts[compactAudit],
+		"adoptions":          adoptions,
+		"rollouts":           rollouts,
+		"watchers":           watchers,
+		"reconciler_queues":  c.reconcileQueue.Stats(),
+		"runtime": map[string]interface{}{
+			"goroutines":     runtime.NumGoroutine(),
+			"heap_alloc":     mem.HeapAlloc,
+			"heap_objects":   mem.HeapObjects,
+			"sys":            mem.Sys,
+			"num_gc":         mem.NumGC,
+			"pause_total_ns": mem.PauseTotalNs,
+		},
+	}
+}
+
+// diagnosticsManifest lists every file's checksum and returns the manifest
+// and its signature ("" without DIAGNOSTICS_SIGNING_KEY).
+func (c *Controller) diagnosticsManifest(files []diagnosticsFile, now time.Time) ([]byte, string) {
+	type manifestFile struct {
+		Name   string `json:"name"`
+		Size   int    `json:"size"`
+		SHA256 string `json:"sha256"`
+	}
+	entries := make([]manifestFile, 0, len(files))
+	for _, f := range files {
+		sum := sha256.Sum256(f.data)
+		entries = append(entries, manifestFile{Name: f.name, Size: len(f.data), SHA256: hex.EncodeToString(sum[:])})
+	}
+	hostname, _ := os.Hostname()
+	manifest := diagnosticsJSON(map[string]interface{}{
+		"created_at": now,
+		"host":       hostname,
+		"signed":     len(c.DiagnosticsSigningKey) > 0,
+		"files":      entries,
+	})
+	if len(c.DiagnosticsSigningKey) == 0 {
+		return manifest, ""
+	}
+	mac := hmac.New(sha256.New, c.DiagnosticsSigningKey)
+	mac.Write(manifest)
+	return manifest, "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// diagnosticsJSON encodes v as indented JSON (bundles are read by people).
+func diagnosticsJSON(v interface{}) []byte {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		data, _ = json.Marshal(map[string]string{"error": err.Error()})
+	}
+	return append(data, '\n')
+}
+
+// writeTarGz packs files into a gzipped tarball under a
+// forge-diagnostics/ directory.
+func writeTarGz(files []diagnosticsFile, modTime time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, f := range files {
+		header := &tar.Header{
+			Name:    "forge-diagnostics/" + f.name,
+			Mode:    0o644,
+			Size:    int64(len(f.data)),
+			ModTime: modTime,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/Zhichengu1/mock-control-plane/pkg/provider"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// DISCOVERY AND ADOPTION
+// =============================================================================
+// Discovery asks every provider that implements provider.Discoverer for its
+// full device inventory and compares it against ResourceDB. Devices the
+// controller doesn't manage become AdoptionProposals in "pending-adoption".
+//
+// WHY NOT ADOPT AUTOMATICALLY:
+// - Other teams configure devices by hand in the vendor console
+// - Silently taking ownership means a later Forge delete could tear down a
+//   camera someone else is using on air
+// - An operator must look at the reverse-mapped spec and approve it
+//
+// Endpoints:
+//   POST /discovery/scan?vendor=sony     → scan vendors, create proposals
+//   GET  /adoptions?state=...            → list proposals
+//   GET  /adoptions/{id}                 → show one proposal (incl. spec)
+//   POST /adoptions/{id}/approve         → adopt (requires fingerprint)
+//   POST /adoptions/{id}/reject          → ignore the device
+// =============================================================================
+
+// ScanResult summarizes one discovery run.
+type ScanResult struct {
+	// Scanned is the total number of vendor devices seen.
+	Scanned int `json:"scanned"`
+
+	// Managed is how many of those are already ForgeResources.
+	Managed int `json:"managed"`
+
+	// Proposals lists the pending proposals created or refreshed by this scan.
+	Proposals []*models.AdoptionProposal `json:"proposals"`
+
+	// Errors maps vendor name → error for vendors that couldn't be listed.
+	Errors map[string]string `json:"errors,omitempty"`
+
+	// Resumed lists vendors whose paged sync continued from a saved cursor.
+	Resumed []string `json:"resumed,omitempty"`
+}
+
+// ApproveAdoptionRequest is the body for POST /adoptions/{id}/approve.
+type ApproveAdoptionRequest struct {
+	// Fingerprint must match the proposal's current fingerprint.
+	// WHY: Proves the approver saw the spec being adopted. If the device
+	// changed since (re-scan), approval fails and the new diff is shown.
+	Fingerprint string `json:"fingerprint"`
+
+	// Name optionally renames the resource (defaults to the device name).
+	Name string `json:"name,omitempty"`
+
+	// Namespace optionally places the resource (defaults to vendor tags).
+	Namespace string `json:"namespace,omitempty"`
+
+	// Reason is recorded on the proposal for later reference.
+	Reason string `json:"reason,omitempty"`
+}
+
+// adoptionID derives a stable proposal ID from the vendor identity, so
+// repeated scans update the same proposal instead of creating duplicates.
+func adoptionID(vendorType, vendorID string) string {
+	return "adopt-" + vendorType + "-" + vendorID
+}
+
+// HandleDiscoveryScan runs discovery against all (or one) vendors.
+func (c *Controller) HandleDiscoveryScan(w http.ResponseWriter, r *http.Request) {
+	vendorFilter := r.URL.Query().Get("vendor")
+	if vendorFilter != "" {
+		if _, exists := c.config().Providers[vendorFilter]; !exists {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "unsupported vendor: " + vendorFilter})
+			return
+		}
+	}
+
+	// WHY 60 SECONDS: Listing a large inventory is slower than a single read
+	ctx, cancel := context.WithTimeout(vendorContext(r), 60*time.Second)
+	defer cancel()
+
+	// Step 1: Scan (see runDiscovery)
+	result := c.runDiscovery(ctx, vendorFilter)
+
+	// Step 2: Return the proposals created or refreshed
+	c.mu.RLock()
+	sortProposals(result.Proposals)
+	body, _ := json.Marshal(result) // Encode under the lock: proposals are shared
+	c.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(body, '\n'))
+}
+
+// runDiscovery asks each discoverable provider (or only vendorFilter) for
+// its inventory and reconciles it against ResourceDB and existing
+// proposals.
+// WHY PAGED FIRST: A paged sync that is interrupted resumes from its
+// cursor on the next scan (see inventory.go)
+func (c *Controller) runDiscovery(ctx context.Context, vendorFilter string) ScanResult {
+	result := ScanResult{Proposals: []*models.AdoptionProposal{}}
+	for name, p := range c.config().Providers {
+		if vendorFilter != "" && name != vendorFilter {
+			continue
+		}
+		var err error
+		if paged, ok := p.(provider.PagedDiscoverer); ok {
+			err = c.syncInventory(ctx, name, paged, &result)
+		} else if discoverer, ok := p.(provider.Discoverer); ok {
+			err = c.scanInventory(ctx, name, discoverer, &result)
+		} else {
+			continue // Vendor API can't enumerate devices
+		}
+		if err != nil {
+			logger.Warnf("Discovery failed for %s: %v", name, err)
+			if result.Errors == nil {
+				result.Errors = make(map[string]string)
+			}
+			result.Errors[name] = err.Error()
+		}
+	}
+	return result
+}
+
+// scanInventory lists a vendor's whole inventory in one call and
+// reconciles it.
+func (c *Controller) scanInventory(ctx context.Context, vendor string, discoverer provider.Discoverer, result *ScanResult) error {
+	// WHY OUTSIDE THE LOCK: Vendor calls are slow; never hold c.mu during I/O
+	release, err := c.acquireVendor(ctx, vendor)
+	if err != nil {
+		return err
+	}
+	devices, err := discoverer.Discover(ctx)
+	release()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	seen := make(map[string]bool)
+	c.reconcileDevicesLocked(vendor, devices, seen, result)
+	c.dropUnseenLocked(vendor, seen)
+	return nil
+}
+
+// reconcileDevicesLocked compares discovered devices of vendor against
+// ResourceDB and existing proposals: unmanaged devices get (or refresh) a
+// proposal. Adoption IDs of the devices are added to seen. Must be called
+// with c.mu held.
+func (c *Controller) reconcileDevicesLocked(vendor string, devices []models.DiscoveredDevice, seen map[string]bool, result *ScanResult) {
+	now := c.Clock.Now()
+	managed := make(map[string]bool)
+	for _, res := range c.ResourceDB {
+		if res.Status.VendorID != "" {
+			managed[adoptionID(res.Spec.VendorType, res.Status.VendorID)] = true
+		}
+	}
+
+	for _, device := range devices {
+		result.Scanned++
+		id := adoptionID(vendor, device.VendorID)
+		seen[id] = true
+		if managed[id] {
+			result.Managed++
+			continue
+		}
+
+		fingerprint := models.SpecFingerprint(device.Spec)
+		existing, exists := c.Adoptions[id]
+		switch {
+		case exists && existing.State == models.AdoptionRejected:
+			// WHY SKIP: Operator already decided this isn't ours
+			existing.LastSeenAt = now
+			continue
+		case exists && existing.State == models.AdoptionPending:
+			// Device still unmanaged; surface any drift since last scan
+			if existing.Fingerprint != fingerprint {
+				existing.Changes = models.DiffSpecs(existing.Device.Spec, device.Spec)
+				existing.Fingerprint = fingerprint
+			}
+			existing.Device = device
+			existing.LastSeenAt = now
+			result.Proposals = append(result.Proposals, existing)
+		default:
+			// New device, or a previously adopted one whose resource was
+			// since deleted from Forge (unmanaged again)
+			proposal := &models.AdoptionProposal{
+				ID:           id,
+				State:        models.AdoptionPending,
+				Device:       device,
+				Fingerprint:  fingerprint,
+				DiscoveredAt: now,
+				LastSeenAt:   now,
+			}
+			c.Adoptions[id] = proposal
+			result.Proposals = append(result.Proposals, proposal)
+			logger.Infof("Discovered unmanaged %s device %s (%s)", vendor, device.VendorID, device.Name)
+		}
+	}
+}
+
+// dropUnseenLocked drops pending proposals of vendor whose device wasn't
+// seen in a complete pass over its inventory (the device no longer
+// exists). Must be called with c.mu held.
+func (c *Controller) dropUnseenLocked(vendor string, seen map[string]bool) {
+	for id, proposal := range c.Adoptions {
+		if proposal.Device.VendorType == vendor && proposal.State == models.AdoptionPending && !seen[id] {
+			delete(c.Adoptions, id)
+		}
+	}
+}
+
+// HandleListAdoptions lists proposals, optionally filtered by ?state=.
+func (c *Controller) HandleListAdoptions(w http.ResponseWriter, r *http.Request) {
+	state := r.URL.Query().Get("state")
+
+	c.mu.RLock()
+	proposals := make([]*models.AdoptionProposal, 0, len(c.Adoptions))
+	for _, proposal := range c.Adoptions {
+		if state == "" || proposal.State == state {
+			proposals = append(proposals, proposal)
+		}
+	}
+	sortProposals(proposals)
+	body, _ := json.Marshal(map[string]interface{}{"items": proposals})
+	c.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(body, '\n'))
+}
+
+// HandleGetAdoption returns a single proposal, including the reverse-mapped
+// spec and the fingerprint needed to approve it.
+func (c *Controller) HandleGetAdoption(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	c.mu.RLock()
+	proposal, exists := c.Adoptions[id]
+	var body []byte
+	if exists {
+		body, _ = json.Marshal(proposal)
+	}
+	c.mu.RUnlock()
+
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "adoption proposal not found"})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(body, '\n'))
+}
+
+// HandleApproveAdoption turns a pending proposal into a managed resource.
+func (c *Controller) HandleApproveAdoption(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var req ApproveAdoptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+	if req.Fingerprint == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "fingerprint is required (see GET /adoptions/" + id + ")"})
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	proposal, exists := c.Adoptions[id]
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "adoption proposal not found"})
+		return
+	}
+	if proposal.State != models.AdoptionPending {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "proposal is already " + proposal.State})
+		return
+	}
+	if proposal.Fingerprint != req.Fingerprint {
+		// WHY 409 WITH THE PROPOSAL: The device changed after the approver
+		// looked at it; show them what they would actually be adopting
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":    "fingerprint mismatch: the device changed since it was reviewed",
+			"proposal": proposal,
+		})
+		return
+	}
+
+	// WHY CHECK AGAIN: Someone may have created/adopted the same vendor
+	// device between the scan and this approval
+	for _, res := range c.ResourceDB {
+		if res.Spec.VendorType == proposal.Device.VendorType && res.Status.VendorID == proposal.Device.VendorID {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]string{"error": "device is already managed by " + res.ID})
+			return
+		}
+	}
+
+	namespace := proposal.Device.Namespace
+	if req.Namespace != "" {
+		namespace = req.Namespace
+	}
+	if capErr := c.checkCapacityLocked(namespace); capErr != nil {
+		writeCapacityError(w, capErr)
+		return
+	}
+
+	now := c.Clock.Now()
+	resource := &models.ForgeResource{
+		ID:          c.IDs.NewID("res"),
+		Type:        proposal.Device.Type,
+		Name:        proposal.Device.Name,
+		Namespace:   proposal.Device.Namespace,
+		Annotations: proposal.Device.Annotations,
+		Spec:        proposal.Device.Spec,
+		Status:      proposal.Device.Status,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if req.Name != "" {
+		resource.Name = req.Name
+	}
+	if req.Namespace != "" {
+		resource.Namespace = req.Namespace
+	}
+	resource.Status.Message = "Adopted from discovered vendor device"
+	// WHY A TRANSACTION: The resource and the approved proposal naming it
+	// are stored together (see tx.go)
+	// WHY NO UNIQUENESS CHECK: The device already exists; it still holds
+	// its values unless another resource got there first
+	c.withTxLocked(func(tx *storeTx) error {
+		tx.Put(resource, "adopted")
+		tx.OnCommit(func() {
+			c.recordEvent(resource, models.EventNormal, models.ReasonAdopted,
+				fmt.Sprintf("Adopted existing %s device %s", proposal.Device.VendorType, proposal.Device.VendorID),
+				resource.Status.Phase, resource.Status.HealthStatus)
+			proposal.State = models.AdoptionApproved
+			proposal.DecidedAt = now
+			proposal.Reason = req.Reason
+			proposal.ResourceID = resource.ID
+		})
+		return nil
+	})
+	logger.Infof("Adopted %s device %s as %s", proposal.Device.VendorType, proposal.Device.VendorID, resource.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", c.externalURL(r, versionedPath("/resources/"+resource.ID)))
+	redact := c.redactionFor(r)
+	redact.announce(w)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"proposal": proposal,
+		"resource": redact.resource(resource.DeepCopy()),
+	})
+}
+
+// HandleRejectAdoption marks a proposal as rejected so future scans
+// leave the device alone.
+func (c *Controller) HandleRejectAdoption(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	// WHY IGNORE EOF: The body is optional for rejections
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+			return
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	proposal, exists := c.Adoptions[id]
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "adoption proposal not found"})
+		return
+	}
+	if proposal.State != models.AdoptionPending {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "proposal is already " + proposal.State})
+		return
+	}
+
+	proposal.State = models.AdoptionRejected
+	proposal.DecidedAt = c.Clock.Now()
+	proposal.Reason = req.Reason
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(proposal)
+}
+
+// sortProposals orders proposals oldest-first, then by ID.
+// WHY: Map iteration is random; stable output makes review easier
+func sortProposals(proposals []*models.AdoptionProposal) {
+	sort.Slice(proposals, func(i, j int) bool {
+		if !proposals[i].DiscoveredAt.Equal(proposals[j].DiscoveredAt) {
+			return proposals[i].DiscoveredAt.Before(proposals[j].DiscoveredAt)
+		}
+		return proposals[i].ID < proposals[j].ID
+	})
+}
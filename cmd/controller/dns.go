@@ -0,0 +1,410 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/client"
+	"github.com/Zhichengu1/mock-control-plane/pkg/dns"
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// DNS REGISTRATION (DNS_BACKEND)
+// =============================================================================
+// With DNS_BACKEND set, every device with an IP gets an address record:
+//
+//   <name>.<namespace>.<DNS_ZONE>   A (or AAAA)   <control IP>
+//
+// e.g. cam-1.studio-a.devices.example.com. The IP is the device's control
+// endpoint as the vendor reports it (status.endpoints), so the record is
+// created once provisioning reports one, changed when it changes, and
+// removed when the resource is deleted. Backends (see pkg/dns):
+//
+//   route53  DNS_ROUTE53_HOSTED_ZONE_ID; secrets DNS_AWS_ACCESS_KEY_ID,
+//            DNS_AWS_SECRET_ACCESS_KEY (and DNS_AWS_SESSION_TOKEN)
+//   etcd     DNS_ETCD_URL, DNS_ETCD_PREFIX (default /skydns) for CoreDNS
+//   rfc2136  DNS_RFC2136_SERVER; DNS_TSIG_KEY_NAME, DNS_TSIG_ALGORITHM and
+//            the secret DNS_TSIG_SECRET to sign updates
+//
+// DNS_TTL (default 60) sets the records' TTL. Secrets come from
+// SECRETS_DIR or the environment, as for vendors (see signing.go).
+//
+// Registration runs in the background: recordRevision notes what each
+// resource's record should be, and a worker brings the backend in line.
+// A failed call is retried every DNS_RETRY_INTERVAL (default 30s); the
+// resource gets a DNSRegistered or DNSFailed event, and GET /admin/dns
+// shows the records and what is still pending.
+//
+// WHY NOT FAIL THE CREATE: The device works without its name; DNS being
+// down must not stop a show from being set up.
+//
+// On start every resource's record is upserted again (the registrations
+// aren't stored). Records of resources deleted while the controller was
+// down are left behind.
+// =============================================================================
+
+// dnsRegistrar keeps one record per resource in a DNS backend.
+type dnsRegistrar struct {
+	backend dns.Backend
+	zone    string
+	ttl     int
+	retry   time.Duration
+
+	mu sync.Mutex
+
+	// registered is what the backend has, by resource ID
+	registered map[string]dns.Record
+
+	// pending is what the backend should have where it differs (nil:
+	// remove the record), by resource ID
+	pending map[string]*dnsPending
+
+	// conflicts names resources whose record name another resource holds
+	conflicts map[string]string
+
+	wake chan struct{}
+}
+
+// dnsPending is a change the backend hasn't taken yet.
+type dnsPending struct {
+	want     *dns.Record
+	attempts int
+	lastErr  string
+}
+
+// loadDNSRegistrar builds the registrar from DNS_BACKEND and friends.
+// Returns nil if DNS_BACKEND isn't set.
+func loadDNSRegistrar(secrets client.Secrets) (*dnsRegistrar, error) {
+	backendType := os.Getenv("DNS_BACKEND")
+	if backendType == "" {
+		return nil, nil
+	}
+	cfg := dns.Config{
+		Type:          backendType,
+		Zone:          os.Getenv("DNS_ZONE"),
+		HostedZoneID:  os.Getenv("DNS_ROUTE53_HOSTED_ZONE_ID"),
+		Endpoint:      os.Getenv("DNS_ROUTE53_ENDPOINT"),
+		EtcdURL:       os.Getenv("DNS_ETCD_URL"),
+		EtcdPrefix:    os.Getenv("DNS_ETCD_PREFIX"),
+		Server:        os.Getenv("DNS_RFC2136_SERVER"),
+		TSIGKeyName:   os.Getenv("DNS_TSIG_KEY_NAME"),
+		TSIGAlgorithm: os.Getenv("DNS_TSIG_ALGORITHM"),
+	}
+	cfg.TSIGSecret, _ = secrets.Secret("DNS_TSIG_SECRET")
+	if backendType == "route53" {
+		sigv4 := client.SigV4Signer{Region: "us-east-1", Service: "route53"}
+		sigv4.AccessKeyID, _ = secrets.Secret("DNS_AWS_ACCESS_KEY_ID")
+		sigv4.SecretAccessKey, _ = secrets.Secret("DNS_AWS_SECRET_ACCESS_KEY")
+		sigv4.SessionToken, _ = secrets.Secret("DNS_AWS_SESSION_TOKEN")
+		if sigv4.AccessKeyID != "" && sigv4.SecretAccessKey != "" {
+			cfg.Signer = sigv4
+		}
+	}
+	backend, err := cfg.Build()
+	if err != nil {
+		return nil, err
+	}
+	ttl := envInt("DNS_TTL", 60)
+	if ttl <= 0 {
+		return nil, fmt.Errorf("DNS_TTL must be positive")
+	}
+	return &dnsRegistrar{
+		backend:    backend,
+		zone:       cfg.Zone,
+		ttl:        ttl,
+		retry:      envDuration("DNS_RETRY_INTERVAL", 30*time.Second),
+		registered: make(map[string]dns.Record),
+		pending:    make(map[string]*dnsPending),
+		conflicts:  make(map[string]string),
+		wake:       make(chan struct{}, 1),
+	}, nil
+}
+
+// recordFor returns the record res should have, or nil if none (no IP).
+func (d *dnsRegistrar) recordFor(res *models.ForgeResource) *dns.Record {
+	address := ""
+	for _, endpoint := range res.Status.Endpoints {
+		if endpoint.Type != models.EndpointTypeIP {
+			continue
+		}
+		// WHY CONTROL FIRST: That is the device itself; other IPs are
+		// where it sends its output
+		if address == "" || endpoint.Role == models.EndpointRoleControl {
+			address = endpoint.Address
+		}
+		if endpoint.Role == models.EndpointRoleControl {
+			break
+		}
+	}
+	rec, ok := dns.NewRecord(dns.Hostname(res.Name, res.Namespace, d.zone), address, d.ttl)
+	if !ok {
+		return nil
+	}
+	return &rec
+}
+
+// registerDNSLocked notes the record res should now have (none once
+// deleted). Called by recordRevision; must be called with c.mu held.
+func (c *Controller) registerDNSLocked(res *models.ForgeResource, deleted bool) {
+	d := c.DNS
+	if d == nil {
+		return
+	}
+	var want *dns.Record
+	if !deleted {
+		want = d.recordFor(res)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// WHY CHECK NAMES: "cam_1" and "cam-1" both become cam-1; the first
+	// one keeps it
+	if want != nil {
+		if holder := d.holderLocked(want.Name, res.ID); holder != "" {
+			if d.conflicts[res.ID] != holder {
+				d.conflicts[res.ID] = holder
+				c.recordEvent(res, models.EventWarning, models.ReasonDNSFailed,
+					fmt.Sprintf("Not registering %s: resource %s already has it", want.Name, holder), "", "")
+			}
+			want = nil
+		} else {
+			delete(d.conflicts, res.ID)
+		}
+	} else if deleted {
+		delete(d.conflicts, res.ID)
+	}
+
+	current, has := d.registered[res.ID]
+	if p, queued := d.pending[res.ID]; queued {
+		if sameRecord(p.want, want) {
+			return
+		}
+	} else if (want == nil && !has) || (want != nil && has && *want == current) {
+		return
+	}
+	d.pending[res.ID] = &dnsPending{want: want}
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// holderLocked returns the resource other than id that has (or is about
+// to get) name. Caller must hold d.mu.
+func (d *dnsRegistrar) holderLocked(name, id string) string {
+	for holder, p := range d.pending {
+		if holder != id && p.want != nil && p.want.Name == name {
+			return holder
+		}
+	}
+	for holder, rec := range d.registered {
+		if holder != id && rec.Name == name {
+			if p, queued := d.pending[holder]; !queued || p.want != nil {
+				return holder
+			}
+		}
+	}
+	return ""
+}
+
+// sameRecord reports whether a and b are both nil or equal.
+func sameRecord(a, b *dns.Record) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// startDNS registers every resource's record and runs the worker until
+// ctx is done.
+func (c *Controller) startDNS(ctx context.Context) {
+	if c.DNS == nil {
+		return
+	}
+	c.mu.Lock()
+	for _, id := range sortedKeys(c.ResourceDB) {
+		c.registerDNSLocked(c.ResourceDB[id], false)
+	}
+	c.mu.Unlock()
+	go func() {
+		for {
+			c.syncDNS(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-c.DNS.wake:
+			case <-c.Clock.After(c.DNS.retry):
+			}
+		}
+	}()
+}
+
+// syncDNS applies the pending changes. A change that fails stays pending
+// for the next round.
+func (c *Controller) syncDNS(ctx context.Context) {
+	d := c.DNS
+	d.mu.Lock()
+	ids := make([]string, 0, len(d.pending))
+	for id := range d.pending {
+		ids = append(ids, id)
+	}
+	d.mu.Unlock()
+	sort.Strings(ids)
+
+	freed := false
+	for _, id := range ids {
+		// Step 1: Snapshot the change
+		d.mu.Lock()
+		p, queued := d.pending[id]
+		if !queued {
+			d.mu.Unlock()
+			continue
+		}
+		want := p.want
+		current, has := d.registered[id]
+		d.mu.Unlock()
+
+		// Step 2: Remove the old record if the name or type moves, then
+		// upsert the new one
+		var err error
+		if has && (want == nil || want.Name != current.Name || want.Type != current.Type) {
+			err = d.backend.Delete(ctx, current)
+		}
+		if err == nil && want != nil {
+			err = d.backend.Upsert(ctx, *want)
+		}
+
+		// Step 3: Record the outcome; a newer change stays pending
+		d.mu.Lock()
+		if err == nil {
+			if want == nil {
+				freed = freed || has
+				delete(d.registered, id)
+			} else {
+				d.registered[id] = *want
+			}
+		} else if want != nil && has && (want.Name != current.Name || want.Type != current.Type) {
+			// The delete may have gone through; upsert it again next round
+			delete(d.registered, id)
+		}
+		attempts := p.attempts + 1
+		if latest := d.pending[id]; latest == p {
+			if err == nil {
+				delete(d.pending, id)
+			} else {
+				p.attempts, p.lastErr = attempts, err.Error()
+			}
+		}
+		d.mu.Unlock()
+		c.noteDNSOutcome(id, want, err, attempts)
+	}
+
+	// Step 4: A removed name can go to a resource that was refused it
+	if freed {
+		c.retryDNSConflicts()
+	}
+}
+
+// retryDNSConflicts registers again the resources refused a name that
+// another resource held.
+func (c *Controller) retryDNSConflicts() {
+	d := c.DNS
+	d.mu.Lock()
+	ids := sortedKeys(d.conflicts)
+	d.mu.Unlock()
+	if len(ids) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, id := range ids {
+		if res, exists := c.ResourceDB[id]; exists {
+			c.registerDNSLocked(res, false)
+		}
+	}
+}
+
+// noteDNSOutcome logs a change and records an event on its resource (for
+// a failure, only the first attempt's).
+func (c *Controller) noteDNSOutcome(id string, want *dns.Record, err error, attempts int) {
+	if err != nil {
+		logger.Warnf("%s: DNS %s (attempt %d): %v", id, c.DNS.backend.Name(), attempts, err)
+	} else if want == nil {
+		logger.Infof("%s: DNS record removed (%s)", id, c.DNS.backend.Name())
+	} else {
+		logger.Infof("%s: DNS %s %s %s (%s)", id, want.Name, want.Type, want.Address, c.DNS.backend.Name())
+	}
+	if want == nil || (err != nil && attempts > 1) {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	res, exists := c.ResourceDB[id]
+	if !exists {
+		return
+	}
+	if err != nil {
+		c.recordEvent(res, models.EventWarning, models.ReasonDNSFailed,
+			fmt.Sprintf("Registering %s failed (retrying every %s): %v", want.Name, c.DNS.retry, err), "", "")
+		return
+	}
+	c.recordEvent(res, models.EventNormal, models.ReasonDNSRegistered,
+		fmt.Sprintf("Registered %s %s %s in %s", want.Name, want.Type, want.Address, c.DNS.backend.Name()), "", "")
+}
+
+// DNSRecordStatus is a resource's record in GET /admin/dns.
+type DNSRecordStatus struct {
+	ResourceID string `json:"resource_id"`
+	dns.Record
+
+	// Pending is set while the backend doesn't have this record yet
+	// (Remove: the record is to be removed)
+	Pending   bool   `json:"pending,omitempty"`
+	Remove    bool   `json:"remove,omitempty"`
+	Attempts  int    `json:"attempts,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// HandleGetDNS handles GET /admin/dns
+func (c *Controller) HandleGetDNS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	d := c.DNS
+	if d == nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{"enabled": false})
+		return
+	}
+	d.mu.Lock()
+	records := make([]DNSRecordStatus, 0, len(d.registered)+len(d.pending))
+	for id, rec := range d.registered {
+		if _, queued := d.pending[id]; !queued {
+			records = append(records, DNSRecordStatus{ResourceID: id, Record: rec})
+		}
+	}
+	for id, p := range d.pending {
+		status := DNSRecordStatus{ResourceID: id, Pending: true, Attempts: p.attempts, LastError: p.lastErr}
+		if p.want != nil {
+			status.Record = *p.want
+		} else {
+			status.Record, status.Remove = d.registered[id], true
+		}
+		records = append(records, status)
+	}
+	d.mu.Unlock()
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Name+records[i].ResourceID < records[j].Name+records[j].ResourceID
+	})
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"enabled": true,
+		"backend": d.backend.Name(),
+		"zone":    d.zone,
+		"ttl":     d.ttl,
+		"records": records,
+	})
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/Zhichengu1/mock-control-plane/pkg/provider"
+)
+
+// =============================================================================
+// DUPLICATE VENDOR DEVICE NAMES
+// =============================================================================
+// Vendors don't enforce unique device names, so a retried create, or a
+// device someone made by hand in the vendor console, quietly ends up with
+// two "cam-1"s, and operators pick the wrong one on air. Before creating,
+// the controller lists the vendor's devices (provider.Discoverer) and, if
+// one already has the requested name, applies a strategy:
+//
+//   error    409 Conflict naming the existing device (the default)
+//   adopt    take over the existing device and apply the requested spec
+//            to it, instead of creating a second one
+//   rename   create with the first free name of "cam-1-2", "cam-1-3", ...
+//   allow    don't check (the old behavior)
+//
+// The strategy comes from ?onDuplicate= on the create, else from the
+// namespace policy DUPLICATE_NAME_POLICY ("dev=rename,*=error").
+//
+// A device already managed by another Forge resource is never adopted.
+// One managed in another namespace isn't a duplicate at all: names are
+// per namespace (see namespaces.go), so two teams can each have a "cam-1".
+// Vendors without discovery can't be checked; their creates go ahead.
+// =============================================================================
+
+// Duplicate name strategies.
+const (
+	duplicateError  = "error"
+	duplicateAdopt  = "adopt"
+	duplicateRename = "rename"
+	duplicateAllow  = "allow"
+)
+
+// duplicateStrategies lists the accepted strategies.
+var duplicateStrategies = []string{duplicateError, duplicateAdopt, duplicateRename, duplicateAllow}
+
+// maxRenameAttempts bounds the suffixes tried by the rename strategy.
+const maxRenameAttempts = 100
+
+// DuplicateNamePolicy picks the strategy per namespace.
+type DuplicateNamePolicy struct {
+	// Default applies to namespaces without an entry
+	Default string
+
+	// Namespaces maps namespace → strategy
+	Namespaces map[string]string
+}
+
+// loadDuplicateNamePolicy reads DUPLICATE_NAME_POLICY: a strategy, or
+// "namespace=strategy" entries with "*" for the default.
+func loadDuplicateNamePolicy() DuplicateNamePolicy {
+	policy := DuplicateNamePolicy{Default: duplicateError, Namespaces: make(map[string]string)}
+	v := os.Getenv("DUPLICATE_NAME_POLICY")
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		namespace, strategy, ok := strings.Cut(entry, "=")
+		if !ok {
+			namespace, strategy = "*", entry
+		}
+		if !validDuplicateStrategy(strategy) {
+			logger.Warnf("Ignoring DUPLICATE_NAME_POLICY entry %q: strategy must be one of %s",
+				entry, strings.Join(duplicateStrategies, ", "))
+			continue
+		}
+		if namespace == "*" {
+			policy.Default = strategy
+		} else {
+			policy.Namespaces[namespace] = strategy
+		}
+	}
+	return policy
+}
+
+// strategyFor returns the strategy for creates in namespace.
+func (p DuplicateNamePolicy) strategyFor(namespace string) string {
+	if strategy, ok := p.Namespaces[namespaceKey(namespace)]; ok {
+		return strategy
+	}
+	return p.Default
+}
+
+func validDuplicateStrategy(strategy string) bool {
+	for _, s := range duplicateStrategies {
+		if s == strategy {
+			return true
+		}
+	}
+	return false
+}
+
+// duplicateNameError reports a name already used by a vendor device.
+type duplicateNameError struct {
+	Device    models.DiscoveredDevice
+	ManagedBy string // Forge resource managing the device ("" if unmanaged)
+}
+
+func (e *duplicateNameError) Error() string {
+	msg := fmt.Sprintf("%s device %s is already named %q", e.Device.VendorType, e.Device.VendorID, e.Device.Name)
+	if e.ManagedBy != "" {
+		return msg + " (managed by " + e.ManagedBy + ")"
+	}
+	return msg + " (not managed by Forge; create with ?onDuplicate=adopt to take it over)"
+}
+
+// writeDuplicateError writes a checkDuplicateName error.
+func writeDuplicateError(w http.ResponseWriter, err error) {
+	var dup *duplicateNameError
+	if !errors.As(err, &dup) {
+		writeProviderError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":      err.Error(),
+		"vendor_id":  dup.Device.VendorID,
+		"managed_by": dup.ManagedBy,
+		"strategies": duplicateStrategies,
+	})
+}
+
+// checkDuplicateName looks for a vendor device named like resource and
+// applies strategy. For rename it changes resource.Name; for adopt it
+// returns the device to take over. Errors are *duplicateNameError or the
+// provider's. The caller holds a vendor slot.
+func (c *Controller) checkDuplicateName(ctx context.Context, p provider.VendorProvider, resource *models.ForgeResource, strategy string) (*models.DiscoveredDevice, error) {
+	discoverer, ok := p.(provider.Discoverer)
+	if strategy == duplicateAllow || !ok {
+		return nil, nil
+	}
+	devices, err := discoverer.Discover(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("checking for duplicate names: %w", err)
+	}
+	byName := make(map[string]*models.DiscoveredDevice, len(devices))
+	for i := range devices {
+		byName[devices[i].Name] = &devices[i]
+	}
+	existing := byName[resource.Name]
+	if existing == nil {
+		return nil, nil
+	}
+
+	c.mu.RLock()
+	managedBy, otherNamespace := "", false
+	for _, res := range c.ResourceDB {
+		if res.Spec.VendorType == existing.VendorType && res.Status.VendorID == existing.VendorID {
+			managedBy = res.ID
+			otherNamespace = namespaceKey(res.Namespace) != namespaceKey(resource.Namespace)
+			break
+		}
+	}
+	c.mu.RUnlock()
+	if otherNamespace {
+		return nil, nil
+	}
+
+	switch strategy {
+	case duplicateRename:
+		for n := 2; n <= maxRenameAttempts; n++ {
+			candidate := fmt.Sprintf("%s-%d", resource.Name, n)
+			if byName[candidate] == nil {
+				logger.Infof("Duplicate name %q on %s: creating as %q", resource.Name, existing.VendorType, candidate)
+				resource.Name = candidate
+				return nil, nil
+			}
+		}
+		return nil, fmt.Errorf("no free name for %q after %d attempts: %w", resource.Name, maxRenameAttempts,
+			&duplicateNameError{Device: *existing, ManagedBy: managedBy})
+	case duplicateAdopt:
+		if managedBy == "" {
+			return existing, nil
+		}
+	}
+	return nil, &duplicateNameError{Device: *existing, ManagedBy: managedBy}
+}
+
+// createByAdopting finishes a create with the adopt strategy: it applies
+// the requested spec to device and stores resource as managing it. The
+// caller holds a vendor slot (release frees it) and a capacity reservation.
+func (c *Controller) createByAdopting(ctx context.Context, p provider.VendorProvider, resource *models.ForgeResource, device *models.DiscoveredDevice, release func(), stage txStage) error {
+	resource.Status.VendorID = device.VendorID
+	status, err := p.Update(ctx, resource)
+	release()
+	if err != nil {
+		c.cancelReservation(resource.Namespace)
+		logger.Errorf("Failed to adopt %s device %s: %v", device.VendorType, device.VendorID, err)
+		return err
+	}
+	resource.Status = *status
+	c.config().HealthPolicy.Apply(resource)
+	c.applyMaintenanceCondition(resource)
+
+	c.withTx(func(tx *storeTx) error {
+		dropped := c.dropStaleRefs(tx, resource)
+		tx.Put(resource, "adopted")
+		tx.OnCommit(func() {
+			c.commitReservationLocked(resource.Namespace)
+			c.recordEvent(resource, models.EventNormal, models.ReasonAdopted,
+				fmt.Sprintf("Adopted existing %s device %s with the same name instead of creating another", device.VendorType, device.VendorID),
+				resource.Status.Phase, resource.Status.HealthStatus)
+			c.recordDroppedRefsLocked(resource, dropped)
+		})
+		if stage != nil {
+			stage(tx, resource)
+		}
+		return nil
+	})
+	logger.Infof("Create of %q adopted existing %s device %s as %s", resource.Name, device.VendorType, device.VendorID, resource.ID)
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/client"
+)
+
+// =============================================================================
+// EGRESS POLICY (EGRESS_POLICY_FILE)
+// =============================================================================
+// The controller may only reach approved vendor endpoints. EGRESS_POLICY_FILE
+// lists, per provider (PROVIDERS name, or "*" for all), the host names and
+// address ranges it may call:
+//
+//   {"sony": {"hosts": ["sony-api.internal", "*.sony.example.com"],
+//             "cidrs": ["10.20.0.0/16"]}}
+//
+// Enforcement lives in pkg/client (see client.EgressPolicy): requests and
+// redirects to anything else fail with "egress denied" (a 502 to the
+// caller), and every denial is logged and written to the audit trail.
+// GET /admin/egress shows the policy and the most recent denials.
+//
+// Without the file nothing is checked.
+// =============================================================================
+
+// loadEgressPolicy reads EGRESS_POLICY_FILE and checks every provider is
+// one of providers. Returns nil if the file isn't set.
+func loadEgressPolicy(path string, providers map[string]bool) (client.EgressPolicy, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var policy client.EgressPolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if policy == nil {
+		return nil, fmt.Errorf("%s: want an object of provider → {hosts, cidrs}", path)
+	}
+
+	// WHY CHECK NOW: A rule under a misspelled provider leaves the real one
+	// without a rule, denying all of its calls
+	for name := range policy {
+		if name == "*" || providers[name] {
+			continue
+		}
+		registered := make([]string, 0, len(providers))
+		for provider := range providers {
+			registered = append(registered, provider)
+		}
+		sort.Strings(registered)
+		return nil, fmt.Errorf("%s: unknown provider %q (registered: %s)", path, name, strings.Join(registered, ", "))
+	}
+	return policy, nil
+}
+
+// HandleGetEgress handles GET /admin/egress
+func (c *Controller) HandleGetEgress(w http.ResponseWriter, r *http.Request) {
+	policy := client.CurrentEgressPolicy()
+	denied, recent := client.EgressDenials()
+	if recent == nil {
+		recent = []client.EgressDenial{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"enabled":        policy != nil,
+		"policy":         policy,
+		"denied":         denied,
+		"recent_denials": recent,
+	})
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/Zhichengu1/mock-control-plane/pkg/notify"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// RESOURCE EVENTS AND NOTIFICATIONS
+// =============================================================================
+// Every notable change to a resource is recorded as an event (see
+// pkg/models/event.go). Events are kept per resource (bounded by
+// EVENTS_MAX_PER_RESOURCE) and, when NOTIFY_CONFIG is set, routed to
+// notification channels (see pkg/notify).
+//
+// Endpoints:
+//   GET /resources/{id}/events   → recent events, oldest first
+//   GET /admin/notifications          → configured channels, routes, delivery stats
+//   GET /admin/notifications/outbox   → deliveries not yet accepted (retrying, dead)
+// =============================================================================
+
+// recentEventsInNotification is how many events a notification includes.
+const recentEventsInNotification = 5
+
+// recordEvent appends an event to res's event list and dispatches
+// notifications. phase/health are the values the event moved the resource
+// into ("" if unchanged). Caller must hold c.mu (write lock).
+func (c *Controller) recordEvent(res *models.ForgeResource, eventType, reason, message, phase, health string) {
+	event := models.Event{
+		ID:         c.IDs.NewID("evt"),
+		ResourceID: res.ID,
+		Time:       c.Clock.Now(),
+		Type:       eventType,
+		Reason:     reason,
+		Message:    message,
+		Phase:      phase,
+		Health:     health,
+	}
+
+	events := append(c.Events[res.ID], event)
+	// WHY CAP: Status flapping could otherwise grow this without bound
+	if c.MaxEventsPerResource > 0 && len(events) > c.MaxEventsPerResource {
+		events = events[len(events)-c.MaxEventsPerResource:]
+	}
+	c.Events[res.ID] = events
+
+	// WHY SUPPRESS: Alerts about a vendor in declared maintenance are noise
+	// (the event itself is still recorded)
+	if c.Notifier != nil && !c.suppressAlert(res, reason) {
+		recent := events
+		if len(recent) > recentEventsInNotification {
+			recent = recent[len(recent)-recentEventsInNotification:]
+		}
+		// WHY COPIES: Dispatch delivers in the background, after c.mu is released
+		// WHY HERE (under c.mu): The outbox write happens before anyone can
+		// see the mutation, so a crash can't lose its notification
+		if err := c.Notifier.Dispatch(event, *res.DeepCopy(), append([]models.Event(nil), recent...)); err != nil {
+			logger.Errorf("Failed to write notification for %s (%s) to the outbox: %v", res.ID, reason, err)
+		}
+	}
+}
+
+// recordStatusEvents records PhaseChanged / HealthChanged events for a
+// status transition. Caller must hold c.mu (write lock).
+func (c *Controller) recordStatusEvents(res *models.ForgeResource, old models.ResourceStatus) {
+	if res.Status.Phase != old.Phase {
+		eventType := models.EventNormal
+		if res.Status.Phase == "Failed" || res.Status.Phase == "Unknown" {
+			eventType = models.EventWarning
+		}
+		c.recordEvent(res, eventType, models.ReasonPhaseChanged,
+			fmt.Sprintf("Phase changed from %s to %s: %s", old.Phase, res.Status.Phase, res.Status.Message),
+			res.Status.Phase, "")
+	}
+	if res.Status.HealthStatus != old.HealthStatus && res.Status.HealthStatus != "" {
+		eventType := models.EventNormal
+		if res.Status.HealthStatus == "degraded" || res.Status.HealthStatus == "unhealthy" {
+			eventType = models.EventWarning
+		}
+		message := fmt.Sprintf("Health changed from %s to %s", valueOrNone(old.HealthStatus), res.Status.HealthStatus)
+		if res.Status.HealthCheckMessage != "" {
+			message += ": " + res.Status.HealthCheckMessage
+		}
+		c.recordEvent(res, eventType, models.ReasonHealthChanged, message, "", res.Status.HealthStatus)
+	}
+	for _, condition := range res.Status.Conditions {
+		previous := old.Condition(condition.Type)
+		if previous != nil && previous.Status == condition.Status {
+			continue
+		}
+		// WHY SKIP NEW TRUE: A condition that starts out fine isn't news
+		if previous == nil && condition.Status == models.ConditionTrue {
+			continue
+		}
+		eventType := models.EventNormal
+		if condition.Status != models.ConditionTrue {
+			eventType = models.EventWarning
+		}
+		c.recordEvent(res, eventType, models.ReasonConditionChanged,
+			fmt.Sprintf("%s is %s: %s", condition.Type, condition.Status, condition.Message), "", "")
+	}
+}
+
+func valueOrNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// HandleListEvents handles GET /resources/{id}/events
+// Events outlive the resource, like revision history.
+func (c *Controller) HandleListEvents(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	c.mu.RLock()
+	events, exists := c.Events[id]
+	items := append([]models.Event{}, events...)
+	c.mu.RUnlock()
+
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "no events for resource"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+}
+
+// HandleGetNotifications handles GET /admin/notifications
+// Secrets (webhook URLs, routing keys, passwords) are never returned.
+func (c *Controller) HandleGetNotifications(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if c.Notifier == nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{"enabled": false})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"enabled":  true,
+		"channels": c.Notifier.Channels(),
+		"routes":   c.Notifier.Routes(),
+		"outbox":   c.Notifier.Outbox().Stats(),
+	})
+}
+
+// HandleGetOutbox handles GET /admin/notifications/outbox
+// Lists deliveries not yet accepted by their channel, oldest first;
+// ?state=pending|in_flight|dead filters.
+func (c *Controller) HandleGetOutbox(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if c.Notifier == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "notifications are not configured"})
+		return
+	}
+	state := r.URL.Query().Get("state")
+	items := []notify.Delivery{}
+	for _, d := range c.Notifier.Outbox().Deliveries() {
+		if state == "" || d.State == state {
+			items = append(items, d)
+		}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// SPARSE FIELDSETS (?fields=)
+// =============================================================================
+// Monitoring pollers read hundreds of resources a minute but only look at
+// a few fields. ?fields= on GET /resources and GET /resources/{id} returns
+// just those:
+//
+//   GET /resources?fields=id,status.phase,status.vendor_id
+//   → {"items": [{"id": "res-1", "status": {"phase": "Running", "vendor_id": "sony-7"}}], ...}
+//
+// Paths use the JSON field names, dot-separated; camelCase spellings
+// (status.vendorId) are accepted too. A path ending in an object returns
+// the whole object, and one inside spec.config or another map picks that
+// key. An unknown field is refused with 400 so a typo doesn't look like
+// missing data.
+//
+// WHY REFLECTION, NOT JSON: Building the response from the struct skips
+// the fields nobody asked for, so spec.config and the conditions aren't
+// serialized only to be thrown away.
+// =============================================================================
+
+// fieldSet is a parsed ?fields= value: each node maps a JSON field name to
+// its selected children. A nil child selects the whole field.
+type fieldSet map[string]fieldSet
+
+var resourceType = reflect.TypeOf(models.ForgeResource{})
+
+// parseFieldSet parses ?fields= for a ForgeResource. It returns nil when
+// the parameter is absent.
+func parseFieldSet(query map[string][]string) (fieldSet, error) {
+	values := query["fields"]
+	if len(values) == 0 {
+		return nil, nil
+	}
+	set := fieldSet{}
+	for _, value := range values {
+		for _, path := range strings.Split(value, ",") {
+			path = strings.TrimSpace(path)
+			if path == "" {
+				continue
+			}
+			segments, err := checkFieldPath(resourceType, strings.Split(path, "."))
+			if err != nil {
+				return nil, errors.New("fields: " + path + ": " + err.Error())
+			}
+			set.add(segments)
+		}
+	}
+	if len(set) == 0 {
+		return nil, errors.New("fields must name at least one field")
+	}
+	return set, nil
+}
+
+// add selects the field at segments. Selecting a whole field wins over
+// selecting parts of it.
+func (s fieldSet) add(segments []string) {
+	child, seen := s[segments[0]]
+	if len(segments) == 1 {
+		s[segments[0]] = nil
+		return
+	}
+	if seen && child == nil {
+		return
+	}
+	if child == nil {
+		child = fieldSet{}
+		s[segments[0]] = child
+	}
+	child.add(segments[1:])
+}
+
+// snakeCase turns a camelCase segment into the JSON name ("vendorId" →
+// "vendor_id", "vendorID" → "vendor_id"). Snake case passes through
+// unchanged.
+func snakeCase(segment string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range segment {
+		if unicode.IsUpper(r) {
+			if !upper {
+				b.WriteByte('_')
+			}
+			upper = true
+			r = unicode.ToLower(r)
+		} else {
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// checkFieldPath checks segments name a field of t and returns them with
+// struct field names in snake case. Map keys (spec.config) are taken as
+// written; slices are selected whole.
+func checkFieldPath(t reflect.Type, segments []string) ([]string, error) {
+	normalized := make([]string, len(segments))
+	for i, segment := range segments {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			segment = snakeCase(segment)
+			field, ok := jsonField(t, segment)
+			if !ok {
+				return nil, errors.New("unknown field " + segment + " (known: " + strings.Join(jsonFieldNames(t), ", ") + ")")
+			}
+			t = field.Type
+		case reflect.Map:
+			t = t.Elem()
+		case reflect.Interface:
+			// WHY: Free-form values (spec.config) are only known at run time;
+			// a path that isn't there selects nothing
+			copy(normalized[i:], segments[i:])
+			return normalized, nil
+		default:
+			return nil, errors.New(segment + " is not inside an object")
+		}
+		normalized[i] = segment
+	}
+	return normalized, nil
+}
+
+// jsonField finds the struct field encoded under name.
+func jsonField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if tagName, _ := jsonTag(field); tagName == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// jsonFieldNames lists the JSON names of t's fields, sorted.
+func jsonFieldNames(t reflect.Type) []string {
+	names := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if name, _ := jsonTag(t.Field(i)); name != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// jsonTag returns the name a field is encoded under ("" = not encoded)
+// and whether it has omitempty.
+func jsonTag(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, options, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, strings.Contains(","+options+",", ",omitempty,")
+}
+
+// project returns the fields of resource selected by set, ready to encode.
+func (s fieldSet) project(resource *models.ForgeResource) map[string]interface{} {
+	return s.projectValue(reflect.ValueOf(resource).Elem()).(map[string]interface{})
+}
+
+// projectValue builds the selected part of v. Fields with omitempty are
+// left out when empty, as encoding/json would.
+func (s fieldSet) projectValue(v reflect.Value) interface{} {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	out := make(map[string]interface{}, len(s))
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			name, omitEmpty := jsonTag(v.Type().Field(i))
+			child, selected := s[name]
+			if name == "" || !selected {
+				continue
+			}
+			field := v.Field(i)
+			if omitEmpty && isEmptyJSON(field) {
+				continue
+			}
+			if child == nil {
+				out[name] = field.Interface()
+			} else if projected := child.projectValue(field); projected != nil {
+				out[name] = projected
+			}
+		}
+	case reflect.Map:
+		for name, child := range s {
+			value := v.MapIndex(reflect.ValueOf(name))
+			if !value.IsValid() {
+				continue
+			}
+			if child == nil {
+				out[name] = value.Interface()
+			} else if projected := child.projectValue(value); projected != nil {
+				out[name] = projected
+			}
+		}
+	default:
+		// WHY: A value that isn't an object has no fields to pick; the
+		// caller leaves it out
+		return nil
+	}
+	return out
+}
+
+// isEmptyJSON reports whether omitempty drops v.
+func isEmptyJSON(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Struct:
+		// WHY: encoding/json never omits structs (time.Time included)
+		return false
+	}
+	return v.IsZero()
+}
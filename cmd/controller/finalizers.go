@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// FINALIZERS
+// =============================================================================
+// Some teardown isn't ours: the archive copies a recorder's files off the
+// device, billing closes the camera's meter. Such systems put a finalizer
+// on the resource (on create, or with PATCH):
+//
+//   {"finalizers": ["archive.example.com/recordings", "billing.example.com"]}
+//
+// A DELETE then turns the resource Terminating but waits with the vendor
+// delete until every finalizer is removed. Each system watches for the
+// Terminating phase, does its part while the device still exists, and
+// removes its own finalizer:
+//
+//   DELETE /resources/{id}/finalizers/archive.example.com/recordings
+//
+// (or PATCH the list). When the last one goes, the deletion continues as
+// in deletion.go. Until then nothing has reached the vendor, so POST
+// /resources/{id}/restore still cancels it (see softdelete.go).
+//
+// Finalizers can't be added to a resource being deleted, and batch
+// deletes refuse resources that have any (unless DELETE_GRACE_PERIOD
+// schedules them, see batch.go; then they wait like any other).
+//
+// WHY BEFORE THE VENDOR DELETE: What the systems need (recordings on the
+// device, its last counters) is gone once the vendor tears it down.
+// =============================================================================
+
+// awaitFinalizersLocked parks the deletion of stored (task taskID) while
+// it has finalizers; it reports whether it did. Must be called with c.mu
+// held.
+func (c *Controller) awaitFinalizersLocked(ctx context.Context, stored *models.ForgeResource, taskID string, previous models.ResourceStatus) bool {
+	if len(stored.Finalizers) == 0 {
+		return false
+	}
+	c.finalizing[stored.ID] = &scheduledDeletion{taskID: taskID, previous: previous, ctx: ctx}
+	c.noteFinalizersLocked(stored, taskID)
+	c.recordEvent(stored, models.EventNormal, models.ReasonFinalizing,
+		fmt.Sprintf("Waiting for finalizers %s before deleting (task %s)", strings.Join(stored.Finalizers, ", "), taskID),
+		phaseTerminating, "")
+	return true
+}
+
+// noteFinalizersLocked shows the finalizers a deletion waits for on the
+// resource and its task. Must be called with c.mu held.
+func (c *Controller) noteFinalizersLocked(stored *models.ForgeResource, taskID string) {
+	waiting := "Waiting for finalizers: " + strings.Join(stored.Finalizers, ", ")
+	stored.Status.Message = waiting + " (task " + taskID + ")"
+	if task, exists := c.Tasks[taskID]; exists && !task.Done() {
+		task.Message = waiting
+		task.UpdatedAt = c.Clock.Now()
+	}
+}
+
+// continueFinalizedLocked is called after stored's finalizers changed: it
+// starts the parked deletion once none are left. The caller records the
+// revision. Must be called with c.mu held.
+func (c *Controller) continueFinalizedLocked(stored *models.ForgeResource) {
+	parked, ok := c.finalizing[stored.ID]
+	if !ok {
+		return
+	}
+	if len(stored.Finalizers) > 0 {
+		c.noteFinalizersLocked(stored, parked.taskID)
+		return
+	}
+	delete(c.finalizing, stored.ID)
+	stored.Status.Message = "Deleting from vendor (task " + parked.taskID + ")"
+	c.recordEvent(stored, models.EventNormal, models.ReasonDeleting,
+		fmt.Sprintf("Finalizers done; deleting %s device %s (task %s)", stored.Spec.VendorType, stored.Status.VendorID, parked.taskID),
+		phaseTerminating, "")
+	res := stored.DeepCopy()
+	// WHY A GOROUTINE: runDeletion takes c.mu, which the caller holds
+	go func() {
+		c.updateTask(parked.taskID, 0, "Waiting for the vendor to accept the delete")
+		c.runDeletion(parked.ctx, parked.taskID, res, parked.previous)
+	}()
+}
+
+// addsFinalizers reports whether next names a finalizer current doesn't.
+func addsFinalizers(current, next []string) bool {
+	for _, name := range next {
+		if !containsString(current, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// errFinalizersWhileTerminating refuses new finalizers on a resource
+// being deleted.
+var errFinalizersWhileTerminating = errors.New("finalizers can't be added while the resource is being deleted")
+
+// HandleRemoveFinalizer handles DELETE /resources/{id}/finalizers/{name}
+//
+// WHY A ROUTE OF ITS OWN: Each system removes only its own finalizer; a
+// PATCH of the whole list races with the others doing the same.
+func (c *Controller) HandleRemoveFinalizer(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	name := vars["name"]
+	removedBy := "anonymous"
+	if principal, ok := principalFrom(r.Context()); ok && principal.Name != "" {
+		removedBy = principal.Name
+	}
+
+	c.mu.Lock()
+	stored, exists := c.ResourceDB[id]
+	if !exists {
+		c.mu.Unlock()
+		writeOperationError(w, errResourceNotFound)
+		return
+	}
+	if !containsString(stored.Finalizers, name) {
+		c.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("resource has no finalizer %q", name)})
+		return
+	}
+	remaining := make([]string, 0, len(stored.Finalizers)-1)
+	for _, finalizer := range stored.Finalizers {
+		if finalizer != name {
+			remaining = append(remaining, finalizer)
+		}
+	}
+	if len(remaining) == 0 {
+		remaining = nil
+	}
+	stored.Finalizers = remaining
+	stored.UpdatedAt = c.Clock.Now()
+	c.continueFinalizedLocked(stored)
+	c.recordRevision(stored, "finalizer-removed", false)
+	c.recordEvent(stored, models.EventNormal, models.ReasonMetadataUpdated,
+		fmt.Sprintf("Removed finalizer %s by %s", name, removedBy), "", "")
+	res := stored.DeepCopy()
+	c.mu.Unlock()
+	logger.Infof("%s: finalizer %s removed by %s", id, name, removedBy)
+
+	redact := c.redactionFor(r)
+	redact.announce(w)
+	redact.resource(res)
+	w.Header().Set("Content-Type", "application/json")
+	setResourceETag(w, res)
+	json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/fanout"
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// BATCH HEALTH CHECK
+// =============================================================================
+// Right before an event goes live, the whole rig has to be verified: every
+// camera, encoder and recorder up, locked and within limits. Rather than
+// one GET per resource:
+//
+//   POST /resources:healthCheck
+//   {"selector": {"namespace": "prod", "type": "camera"}, "parallelism": 8}
+//   {"ids": ["res-1", "res-2"]}
+//
+// reads every selected resource from its vendor concurrently (the same
+// refresh as GET /resources/{id}, so the stored status, events and health
+// policy are updated too) and answers with one compact line per resource,
+// worst first. "healthy" is true only if every resource is.
+// =============================================================================
+
+// Health check bounds.
+const (
+	defaultHealthCheckParallelism = 8
+	healthCheckReadTimeout        = 10 * time.Second
+)
+
+// Health check outcomes beyond the health statuses.
+const (
+	healthUnreachable = "unreachable" // vendor read failed; stored status shown
+	healthNotFound    = "not_found"
+)
+
+// healthCheckRank orders outcomes worst first.
+var healthCheckRank = map[string]int{
+	healthNotFound: 0, healthUnreachable: 1, "unhealthy": 2, "unknown": 3, "degraded": 4, "healthy": 5,
+}
+
+// HealthCheckRequest is the body accepted by POST /resources:healthCheck.
+type HealthCheckRequest struct {
+	// IDs are checked in addition to the selector's IDs.
+	IDs []string `json:"ids,omitempty"`
+
+	// Selector picks resources by namespace, type or vendor (see rollouts).
+	Selector RolloutSelector `json:"selector"`
+
+	// Parallelism bounds vendor reads in flight (default 8, max 32).
+	Parallelism int `json:"parallelism,omitempty"`
+}
+
+// HealthCheckItem is the summary of one resource.
+type HealthCheckItem struct {
+	ID         string `json:"id"`
+	Name       string `json:"name,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+	VendorType string `json:"vendor_type,omitempty"`
+	Phase      string `json:"phase,omitempty"`
+
+	// Health is the resource's health status, "unreachable" or "not_found"
+	Health string `json:"health"`
+
+	// Message explains a health other than healthy
+	Message string `json:"message,omitempty"`
+
+	// Failing lists the conditions that are not True
+	Failing []string `json:"failing,omitempty"`
+
+	Error     string `json:"error,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+}
+
+// HealthCheckReport is the response of POST /resources:healthCheck.
+type HealthCheckReport struct {
+	// Healthy is true if every checked resource is healthy
+	Healthy bool `json:"healthy"`
+
+	// Counts maps health → number of resources
+	Counts     map[string]int    `json:"counts"`
+	Items      []HealthCheckItem `json:"items"`
+	DurationMS int64             `json:"duration_ms"`
+}
+
+// HandleBatchHealthCheck handles POST /resources:healthCheck
+func (c *Controller) HandleBatchHealthCheck(w http.ResponseWriter, r *http.Request) {
+	var req HealthCheckRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+	if len(req.IDs) == 0 && req.Selector.empty() {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "ids or selector is required"})
+		return
+	}
+	parallelism := req.Parallelism
+	if parallelism <= 0 {
+		parallelism = defaultHealthCheckParallelism
+	}
+	if parallelism > maxBatchParallelism {
+		parallelism = maxBatchParallelism
+	}
+
+	// Step 1: Resolve the targets (explicit IDs that don't exist are reported)
+	wanted := make(map[string]bool)
+	for _, id := range req.IDs {
+		wanted[id] = true
+	}
+	c.mu.RLock()
+	var targets []string
+	if !req.Selector.empty() {
+		for id, res := range c.ResourceDB {
+			if req.Selector.matches(res) {
+				targets = append(targets, id)
+				delete(wanted, id)
+			}
+		}
+	}
+	items := []HealthCheckItem{}
+	for id := range wanted {
+		if _, exists := c.ResourceDB[id]; exists {
+			targets = append(targets, id)
+		} else {
+			items = append(items, HealthCheckItem{ID: id, Health: healthNotFound, Error: "resource not found"})
+		}
+	}
+	c.mu.RUnlock()
+
+	// Step 2: Read them concurrently
+	started := c.Clock.Now()
+	branches, _ := fanout.Map(vendorContext(r), targets, fanout.Options{Limit: parallelism}, func(ctx context.Context, id string) (HealthCheckItem, error) {
+		return c.checkResourceHealth(ctx, id), nil
+	})
+	for i, branch := range branches {
+		if branch.Err != nil {
+			branch.Value = HealthCheckItem{ID: targets[i], Health: healthUnreachable, Error: branch.Err.Error()}
+		}
+		items = append(items, branch.Value)
+	}
+
+	// Step 3: Summarize, worst first
+	report := HealthCheckReport{Healthy: len(items) > 0, Counts: make(map[string]int), Items: items}
+	for _, item := range items {
+		report.Counts[item.Health]++
+		if item.Health != "healthy" {
+			report.Healthy = false
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if healthCheckRank[a.Health] != healthCheckRank[b.Health] {
+			return healthCheckRank[a.Health] < healthCheckRank[b.Health]
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	})
+	report.DurationMS = c.Clock.Since(started).Milliseconds()
+	logger.Infof("Health check of %d resources: %v (healthy: %t)", len(items), report.Counts, report.Healthy)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// checkResourceHealth refreshes one resource from its vendor and
+// summarizes its health.
+func (c *Controller) checkResourceHealth(parent context.Context, id string) HealthCheckItem {
+	started := c.Clock.Now()
+	c.mu.RLock()
+	stored, exists := c.ResourceDB[id]
+	var vendorID, phase string
+	if exists {
+		vendorID, phase = stored.Status.VendorID, stored.Status.Phase
+	}
+	c.mu.RUnlock()
+	if !exists {
+		return HealthCheckItem{ID: id, Health: healthNotFound, Error: "resource not found"}
+	}
+
+	var readErr error
+	switch {
+	case vendorID == "":
+		readErr = errNoVendorDevice
+	case phase != phaseTerminating:
+		ctx, cancel := context.WithTimeout(parent, healthCheckReadTimeout)
+		_, readErr = c.refreshStatus(ctx, id)
+		cancel()
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if stored, exists = c.ResourceDB[id]; !exists {
+		return HealthCheckItem{ID: id, Health: healthNotFound, Error: errResourceGone.Error()}
+	}
+	status := stored.Status
+	item := HealthCheckItem{
+		ID:         id,
+		Name:       stored.Name,
+		Namespace:  stored.Namespace,
+		VendorType: stored.Spec.VendorType,
+		Phase:      status.Phase,
+		Health:     status.HealthStatus,
+		LatencyMS:  c.Clock.Since(started).Milliseconds(),
+	}
+	if item.Health == "" {
+		item.Health = "unknown"
+	}
+	if item.Health != "healthy" {
+		item.Message = status.HealthCheckMessage
+		if item.Message == "" {
+			item.Message = status.Message
+		}
+	}
+	for _, condition := range status.Conditions {
+		if condition.Status != models.ConditionTrue {
+			item.Failing = append(item.Failing, condition.Type)
+		}
+	}
+	if readErr != nil {
+		item.Error = readErr.Error()
+		if !errors.Is(readErr, errNoVendorDevice) {
+			item.Health = healthUnreachable
+		}
+	}
+	return item
+}
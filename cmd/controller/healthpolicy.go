@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/health"
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// HandleGetHealthPolicy handles GET /admin/health-policy
+// Shows the health thresholds in effect. With ?resource_id= it shows the
+// thresholds that apply to that resource and how its latest metrics
+// compare with them.
+func (c *Controller) HandleGetHealthPolicy(w http.ResponseWriter, r *http.Request) {
+	cfg := c.config()
+	w.Header().Set("Content-Type", "application/json")
+	resourceID := r.URL.Query().Get("resource_id")
+	if resourceID == "" {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"default":  cfg.HealthPolicy.Default,
+			"policies": cfg.HealthPolicy.Policies,
+			"metrics":  health.Metrics(),
+		})
+		return
+	}
+
+	c.mu.RLock()
+	res, exists := c.ResourceDB[resourceID]
+	var snapshot *models.ForgeResource
+	if exists {
+		snapshot = res.DeepCopy()
+	}
+	c.mu.RUnlock()
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Resource not found"})
+		return
+	}
+
+	name, thresholds := cfg.HealthPolicy.ThresholdsFor(snapshot)
+	signals := health.Evaluate(thresholds, snapshot.Status.Metrics)
+	if signals == nil {
+		signals = []health.Signal{}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"resource_id":   snapshot.ID,
+		"policy":        name,
+		"thresholds":    thresholds,
+		"health_status": snapshot.Status.HealthStatus,
+		"signals":       signals,
+	})
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// REVISION HISTORY AND TIME-TRAVEL QUERIES
+// =============================================================================
+// The controller keeps an append-only list of snapshots per resource ID.
+// History outlives the resource itself: after a DELETE, the revisions
+// (ending in a tombstone) remain queryable for incident analysis.
+//
+// Endpoints:
+//   GET /resources/{id}?asOf=2026-01-30T10:00:00Z → resource as it was then
+//   GET /resources/{id}/revisions                 → list of revisions
+//   POST /resources/{id}/rollback?revision=N      → restore revision N's spec
+//                                                   (see rollback.go)
+// =============================================================================
+
+// recordRevision appends a snapshot of res to its history.
+// Caller must hold c.mu (write lock).
+func (c *Controller) recordRevision(res *models.ForgeResource, reason string, deleted bool) {
+	revisions := c.History[res.ID]
+	// WHY NOT ONLY THE HISTORY: Compaction may have dropped all of it;
+	// the resource's version keeps the numbers going up
+	next := res.ResourceVersion + 1
+	if len(revisions) > 0 && revisions[len(revisions)-1].Revision >= next {
+		next = revisions[len(revisions)-1].Revision + 1
+	}
+	res.ResourceVersion = next
+
+	var prev *models.ForgeResource
+	if len(revisions) > 0 && !revisions[len(revisions)-1].Deleted {
+		last := revisions[len(revisions)-1].Resource
+		prev = &last
+	}
+	revision := models.ResourceRevision{
+		Revision:  next,
+		Timestamp: c.Clock.Now(),
+		Reason:    reason,
+		Deleted:   deleted,
+		Resource:  *res.DeepCopy(),
+	}
+	revisions = append(revisions, revision)
+	event := c.publishWatch(revision, prev)
+	c.appendStoreEvent(event, revision)
+	c.search.update(res, deleted)
+	c.registerDNSLocked(res, deleted)
+	if !deleted {
+		c.snapshotAfterChangeLocked(res, prev)
+	}
+
+	// WHY CAP: Memory is finite; keep the most recent N revisions per resource
+	if c.MaxRevisions > 0 && len(revisions) > c.MaxRevisions {
+		revisions = revisions[len(revisions)-c.MaxRevisions:]
+	}
+	c.History[res.ID] = revisions
+}
+
+// statusChanged reports whether two statuses differ in anything other than
+// bookkeeping timestamps.
+// WHY: Every GET refreshes LastHealthCheck; recording a revision for that
+// alone would bury the interesting changes (phase, health, metrics).
+func statusChanged(old, new models.ResourceStatus) bool {
+	old.LastHealthCheck, new.LastHealthCheck = time.Time{}, time.Time{}
+	old.LastSuccessfulOperation, new.LastSuccessfulOperation = time.Time{}, time.Time{}
+	old.Uptime, new.Uptime = 0, 0
+	// WHY IGNORE METRICS: Readings move on every poll; what matters is
+	// the health and conditions they roll up into
+	old.Metrics, new.Metrics = nil, nil
+	return !reflect.DeepEqual(old, new)
+}
+
+// parseAsOf accepts RFC 3339 timestamps or Unix seconds.
+func parseAsOf(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	secs, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(secs, 0), nil
+}
+
+// serveResourceAsOf answers GET /resources/{id}?asOf=... from history
+// without contacting the vendor. fields (nil = all) selects what is returned.
+func (c *Controller) serveResourceAsOf(w http.ResponseWriter, r *http.Request, resourceID, asOfParam string, fields fieldSet) {
+	asOf, err := parseAsOf(asOfParam)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "asOf must be an RFC 3339 timestamp or Unix seconds"})
+		return
+	}
+
+	// Find the last revision recorded at or before asOf
+	c.mu.RLock()
+	var found *models.ResourceRevision
+	for i, rev := range c.History[resourceID] {
+		if rev.Timestamp.After(asOf) {
+			break // Revisions are appended in time order
+		}
+		found = &c.History[resourceID][i]
+	}
+	var snapshot models.ResourceRevision
+	if found != nil {
+		snapshot = *found
+	}
+	c.mu.RUnlock()
+
+	// Older than the history kept in memory: ask the event log
+	if found == nil && c.eventLog != nil {
+		if found, err = c.storeRevisionAt(resourceID, asOf); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		if found != nil {
+			snapshot = *found
+		}
+	}
+
+	if found == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "no history for resource at " + asOf.Format(time.RFC3339)})
+		return
+	}
+	if snapshot.Deleted {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "resource was deleted at " + snapshot.Timestamp.Format(time.RFC3339)})
+		return
+	}
+
+	// WHY HEADERS: The body stays a plain ForgeResource (same shape as a
+	// normal GET) while clients can still see which revision they got
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Forge-Revision", strconv.FormatInt(snapshot.Revision, 10))
+	w.Header().Set("X-Forge-Revision-Time", snapshot.Timestamp.Format(time.RFC3339Nano))
+	if redact := c.redactionFor(r); redact != nil {
+		// WHY COPY: The revision shares its maps with the stored history
+		redact.announce(w)
+		snapshot.Resource = *redact.resource(snapshot.Resource.DeepCopy())
+	}
+	if fields != nil {
+		json.NewEncoder(w).Encode(fields.project(&snapshot.Resource))
+		return
+	}
+	json.NewEncoder(w).Encode(snapshot.Resource)
+}
+
+// HandleListRevisions returns every recorded revision of a resource,
+// oldest first. Works for deleted resources too.
+func (c *Controller) HandleListRevisions(w http.ResponseWriter, r *http.Request) {
+	resourceID := mux.Vars(r)["id"]
+
+	c.mu.RLock()
+	revisions := append([]models.ResourceRevision(nil), c.History[resourceID]...)
+	c.mu.RUnlock()
+
+	if len(revisions) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "no history for resource"})
+		return
+	}
+
+	if redact := c.redactionFor(r); redact != nil {
+		redact.announce(w)
+		for i := range revisions {
+			revisions[i].Resource = *redact.resource(revisions[i].Resource.DeepCopy())
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": revisions})
+}
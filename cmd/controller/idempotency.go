@@ -0,0 +1,176 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// IDEMPOTENT CREATES (Idempotency-Key)
+// =============================================================================
+// A client whose create timed out can't tell whether the device exists;
+// retrying blindly would provision a second one (or, now that names are
+// unique, fail with 409). With an Idempotency-Key header the retry is
+// safe:
+//
+//   POST /resources   Idempotency-Key: 5f1c...   → 201, res-123
+//   POST /resources   Idempotency-Key: 5f1c...   → 201, res-123 again
+//                                                  (Idempotent-Replayed: true)
+//
+// The replay answers with the resource as it is now and the original
+// status code; no vendor call is made. Keys are per principal, or per
+// client IP without API keys (clients can't see each other's), printable
+// ASCII of at most 128 characters, and remembered for
+// IDEMPOTENCY_KEY_TTL (default 24h).
+//
+//   - The same key with a different body is refused with 422: it's a
+//     client bug, not a retry.
+//   - A retry while the first request is still running gets 409 with
+//     Retry-After.
+//   - A create that fails before anything is stored (validation, quota,
+//     conflicts) forgets the key, so the corrected request can reuse it.
+//   - If the resource has been deleted since, the retry gets 410.
+//
+// Without a key, a retry with the same name is refused with 409 naming
+// the resource the first request created (see uniqueness.go).
+// =============================================================================
+
+// HeaderIdempotencyKey names the client's key for a create.
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+// idempotencyRecord is a create made with an Idempotency-Key.
+type idempotencyRecord struct {
+	fingerprint string
+	createdAt   time.Time
+
+	// resourceID and status are set once the create is done ("" while it
+	// is running)
+	resourceID string
+	status     int
+}
+
+// idempotencyState remembers keys by principal and key.
+type idempotencyState struct {
+	mu   sync.Mutex
+	ttl  time.Duration
+	keys map[string]*idempotencyRecord
+}
+
+// newIdempotencyState reads IDEMPOTENCY_KEY_TTL.
+func newIdempotencyState() *idempotencyState {
+	return &idempotencyState{
+		ttl:  envDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour),
+		keys: make(map[string]*idempotencyRecord),
+	}
+}
+
+// createFingerprint identifies what a create asked for: the decoded body
+// (so formatting doesn't matter), the route's namespace and the duplicate
+// strategy.
+func createFingerprint(r *http.Request, resource *models.ForgeResource, duplicateStrategy string) string {
+	body, _ := json.Marshal(resource)
+	sum := sha256.Sum256([]byte(routeNamespace(r) + "\x00" + duplicateStrategy + "\x00" + string(body)))
+	return hex.EncodeToString(sum[:])
+}
+
+// idempotentCreate is a create with an Idempotency-Key, between beginCreate
+// and finish.
+type idempotentCreate struct {
+	c   *Controller
+	key string
+}
+
+// finish remembers the created resource, or forgets the key if nothing
+// was stored (resourceID "").
+func (ic *idempotentCreate) finish(resourceID string, status int) {
+	if ic == nil {
+		return
+	}
+	state := ic.c.idempotency
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if resourceID == "" {
+		delete(state.keys, ic.key)
+		return
+	}
+	if record, ok := state.keys[ic.key]; ok {
+		record.resourceID, record.status = resourceID, status
+	}
+}
+
+// beginCreate claims r's Idempotency-Key for the create. It returns
+// handled=true if it answered the request itself: a replay of the
+// resource the key created, or an error. Without a key it returns
+// (nil, false).
+func (c *Controller) beginCreate(w http.ResponseWriter, r *http.Request, resource *models.ForgeResource, duplicateStrategy string) (*idempotentCreate, bool) {
+	key := r.Header.Get(HeaderIdempotencyKey)
+	if key == "" {
+		return nil, false
+	}
+	if !validRequestID(key) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": HeaderIdempotencyKey + " must be 1-128 printable ASCII characters"})
+		return nil, true
+	}
+	// WHY callerKey: Without API keys every caller is anonymous; the
+	// client IP keeps their keys apart
+	scoped := callerKey(r) + "\x00" + key
+	fingerprint := createFingerprint(r, resource, duplicateStrategy)
+
+	// Step 1: Claim the key, or find the create that claimed it
+	state := c.idempotency
+	now := c.Clock.Now()
+	state.mu.Lock()
+	for k, record := range state.keys {
+		if now.Sub(record.createdAt) > state.ttl {
+			delete(state.keys, k)
+		}
+	}
+	record, seen := state.keys[scoped]
+	if !seen {
+		state.keys[scoped] = &idempotencyRecord{fingerprint: fingerprint, createdAt: now}
+		state.mu.Unlock()
+		return &idempotentCreate{c: c, key: scoped}, false
+	}
+	previous := *record
+	state.mu.Unlock()
+
+	// Step 2: A retry must ask for the same thing
+	switch {
+	case previous.fingerprint != fingerprint:
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("%s %q was used for a different request", HeaderIdempotencyKey, key)})
+		return nil, true
+	case previous.resourceID == "":
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("a request with %s %q is still in progress", HeaderIdempotencyKey, key)})
+		return nil, true
+	}
+
+	// Step 3: Answer with the resource as it is now
+	c.mu.RLock()
+	stored, exists := c.ResourceDB[previous.resourceID]
+	var snapshot *models.ForgeResource
+	if exists {
+		snapshot = stored.DeepCopy()
+	}
+	c.mu.RUnlock()
+	if !exists {
+		w.WriteHeader(http.StatusGone)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("resource %s created with %s %q has been deleted", previous.resourceID, HeaderIdempotencyKey, key),
+			"resource_id": previous.resourceID})
+		return nil, true
+	}
+	logger.Infof("%s: replayed create for %s %q", snapshot.ID, HeaderIdempotencyKey, key)
+	w.Header().Set("Idempotent-Replayed", "true")
+	c.writeCreated(w, r, snapshot, previous.status)
+	return nil, true
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/Zhichengu1/mock-control-plane/pkg/provider"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// IDLE RESOURCE DETECTION
+// =============================================================================
+// Cloud channels and encoders bill by the hour whether anyone watches or
+// not; one left running after a show costs more than the show. The idle
+// analyzer reads every Running resource from its vendor each
+// IDLE_CHECK_INTERVAL and tracks how long it has had no viewers AND no
+// output bitrate:
+//
+//   idle for IDLE_AFTER → Warning event "Idle", and a stop recommendation
+//                         in GET /recommendations
+//   namespace listed in IDLE_AUTO_STOP ("*" = all) → stopped automatically
+//
+// WHY BOTH ZERO: A contribution feed pushing to an ingest has bitrate but
+// no viewers; it is working, not idle.
+//
+// Stopping keeps the configuration (POST /resources/{id}:start brings the
+// resource back); nothing is ever deleted.
+// =============================================================================
+
+// IdlePolicy configures idle detection.
+type IdlePolicy struct {
+	// After is how long a resource must be idle before it is flagged
+	After time.Duration
+
+	// CheckInterval is how often Running resources are read (0 disables)
+	CheckInterval time.Duration
+
+	// AutoStop lists namespaces whose idle resources are stopped
+	// automatically; "*" matches every namespace
+	AutoStop []string
+}
+
+// idleState tracks one resource seen idle.
+type idleState struct {
+	since   time.Time
+	flagged bool // Idle event recorded
+}
+
+// loadIdlePolicy reads IDLE_AFTER, IDLE_CHECK_INTERVAL and IDLE_AUTO_STOP.
+func loadIdlePolicy() IdlePolicy {
+	policy := IdlePolicy{
+		// WHY 30 MINUTES: Longer than a break between segments
+		After:         envDuration("IDLE_AFTER", 30*time.Minute),
+		CheckInterval: envDuration("IDLE_CHECK_INTERVAL", time.Minute),
+	}
+	for _, ns := range strings.Split(os.Getenv("IDLE_AUTO_STOP"), ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			policy.AutoStop = append(policy.AutoStop, ns)
+		}
+	}
+	return policy
+}
+
+// autoStops reports whether idle resources in namespace are stopped
+// without a human.
+func (p IdlePolicy) autoStops(namespace string) bool {
+	for _, ns := range p.AutoStop {
+		if ns == "*" || ns == namespaceKey(namespace) {
+			return true
+		}
+	}
+	return false
+}
+
+// startIdleAnalyzer runs the idle analyzer in the background.
+func (c *Controller) startIdleAnalyzer(ctx context.Context) {
+	policy := c.IdlePolicy
+	if policy.CheckInterval <= 0 {
+		logger.Infof("Idle analyzer disabled (IDLE_CHECK_INTERVAL=0)")
+		return
+	}
+	logger.Infof("Idle analyzer enabled: flag after %s, check every %s, auto-stop in %v",
+		policy.After, policy.CheckInterval, policy.AutoStop)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-c.Clock.After(policy.CheckInterval):
+			}
+			// WHY SKIP: Under memory pressure background work waits
+			if c.ReconcilerPaused() {
+				continue
+			}
+			c.analyzeIdle(ctx)
+		}
+	}()
+}
+
+// analyzeIdle reads every Running resource and updates idle tracking,
+// flagging and (per policy) stopping resources idle for too long.
+func (c *Controller) analyzeIdle(ctx context.Context) {
+	defer c.profileLoop("idle", time.Now())
+	// Step 1: Forget resources that are gone or no longer Running
+	inMaintenance := c.vendorsInMaintenance()
+	c.mu.Lock()
+	var ids []string
+	for id := range c.idle {
+		if res, exists := c.ResourceDB[id]; !exists || res.Status.Phase != "Running" {
+			delete(c.idle, id)
+		}
+	}
+	for _, res := range c.ResourceDB {
+		// WHY SKIP MAINTENANCE: Reads fail or mislead while the vendor works
+		if res.Status.Phase == "Running" && res.Status.VendorID != "" && !inMaintenance[res.Spec.VendorType] {
+			ids = append(ids, res.ID)
+		}
+	}
+	c.mu.Unlock()
+	sort.Strings(ids)
+
+	// Step 2: Read each one from its vendor
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return
+		}
+		status, err := c.refreshStatus(ctx, id)
+		if err != nil {
+			logger.Debugf("Idle analyzer: skipping %s: %v", id, err)
+			continue
+		}
+		idle := status.Phase == "Running" && status.ConnectionCount == 0 && status.CurrentBitrate == 0
+
+		c.mu.Lock()
+		res, exists := c.ResourceDB[id]
+		if !exists || !idle {
+			delete(c.idle, id)
+			c.mu.Unlock()
+			continue
+		}
+		now := c.Clock.Now()
+		state, tracked := c.idle[id]
+		if !tracked {
+			state = &idleState{since: now}
+			c.idle[id] = state
+		}
+		idleFor := now.Sub(state.since)
+		due := idleFor >= c.IdlePolicy.After
+		if due && !state.flagged {
+			state.flagged = true
+			c.recordEvent(res, models.EventWarning, models.ReasonIdle,
+				fmt.Sprintf("No viewers and no output for %s; consider stopping it", idleFor.Round(time.Second)), "", "")
+		}
+		autoStop := due && c.IdlePolicy.autoStops(res.Namespace)
+		c.mu.Unlock()
+
+		if autoStop {
+			detail := fmt.Sprintf(" after %s idle (IDLE_AUTO_STOP policy)", idleFor.Round(time.Second))
+			if _, err := c.setPower(ctx, id, true, detail); err != nil {
+				logger.Warnf("Idle analyzer: failed to stop %s: %v", id, err)
+			} else {
+				logger.Infof("Idle analyzer: stopped %s%s", id, detail)
+			}
+		}
+	}
+}
+
+// setPower stops (stop=true) or starts resource id through the vendor's
+// PowerController. detail is appended to the event message.
+func (c *Controller) setPower(parent context.Context, id string, stop bool, detail string) (*models.ForgeResource, error) {
+	cfg := c.config()
+	c.mu.RLock()
+	stored, exists := c.ResourceDB[id]
+	var vendorType, vendorID, phase string
+	var lockErr error
+	if exists {
+		vendorType, vendorID, phase = stored.Spec.VendorType, stored.Status.VendorID, stored.Status.Phase
+		lockErr = c.lockConflictLocked(parent, id)
+		if lockErr == nil {
+			lockErr = c.preconditionLocked(parent, stored)
+		}
+	}
+	c.mu.RUnlock()
+	if !exists {
+		return nil, errResourceNotFound
+	}
+	if lockErr != nil {
+		return nil, lockErr
+	}
+	if vendorID == "" {
+		return nil, errNoVendorDevice
+	}
+	if phase == phaseTerminating {
+		return nil, errResourceTerminating
+	}
+	selectedProvider, exists := cfg.Providers[vendorType]
+	if !exists {
+		return nil, fmt.Errorf("provider %s not configured", vendorType)
+	}
+	power, supported := selectedProvider.(provider.PowerController)
+	if !supported {
+		return nil, fmt.Errorf("stop/start: %w (%s)", errUnsupported, vendorType)
+	}
+
+	ctx, cancel := context.WithTimeout(parent, 30*time.Second)
+	defer cancel()
+	release, err := c.acquireVendor(ctx, vendorType)
+	if err != nil {
+		return nil, err
+	}
+	var status *models.ResourceStatus
+	reason, message := models.ReasonStarted, "Started"
+	if stop {
+		status, err = power.Stop(ctx, vendorID)
+		reason, message = models.ReasonStopped, "Stopped"
+	} else {
+		status, err = power.Start(ctx, vendorID)
+	}
+	release()
+	c.forgetVendorRead(vendorType, vendorID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if stored, exists = c.ResourceDB[id]; !exists {
+		return nil, errResourceGone
+	}
+	oldStatus := stored.Status
+	stored.Status = *status
+	cfg.HealthPolicy.Apply(stored)
+	c.applyMaintenanceCondition(stored)
+	stored.UpdatedAt = c.Clock.Now()
+	delete(c.idle, id)
+	c.recordRevision(stored, strings.ToLower(message), false)
+	c.recordEvent(stored, models.EventNormal, reason, message+detail, "", "")
+	c.recordStatusEvents(stored, oldStatus)
+	return stored.DeepCopy(), nil
+}
+
+// HandleStopResource handles POST /resources/{id}:stop
+func (c *Controller) HandleStopResource(w http.ResponseWriter, r *http.Request) {
+	c.handlePower(w, r, true)
+}
+
+// HandleStartResource handles POST /resources/{id}:start
+func (c *Controller) HandleStartResource(w http.ResponseWriter, r *http.Request) {
+	c.handlePower(w, r, false)
+}
+
+func (c *Controller) handlePower(w http.ResponseWriter, r *http.Request, stop bool) {
+	detail := ""
+	if principal, ok := principalFrom(r.Context()); ok {
+		detail = " by " + principal.Name
+	}
+	operation := "start"
+	if stop {
+		operation = "stop"
+	}
+	if c.rejectIfLocked(w, r, mux.Vars(r)["id"]) || c.rejectIfPreconditionFailed(w, r, mux.Vars(r)["id"]) {
+		return
+	}
+	// WHY ONLY STOPS: Stopping a whole show is as bad as deleting it (see
+	// massdelete.go); starting isn't
+	if stop && c.guardMassDelete(w, r, massOpStop, []string{mux.Vars(r)["id"]}) {
+		return
+	}
+	if c.deferForMaintenance(w, r, mux.Vars(r)["id"], operation, detail, nil) {
+		return
+	}
+	res, err := c.setPower(vendorContext(r), mux.Vars(r)["id"], stop, detail)
+	if err != nil {
+		if stop {
+			c.refundMassDelete(r, []string{mux.Vars(r)["id"]})
+		}
+		writeOperationError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	setResourceETag(w, res)
+	redact := c.redactionFor(r)
+	redact.announce(w)
+	json.NewEncoder(w).Encode(redact.resource(res))
+}
+
+// idleRecommendations builds the current recommendations, oldest idle
+// first.
+func (c *Controller) idleRecommendations() []models.Recommendation {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	now := c.Clock.Now()
+	items := []models.Recommendation{}
+	for id, state := range c.idle {
+		res, exists := c.ResourceDB[id]
+		idleFor := now.Sub(state.since)
+		if !exists || idleFor < c.IdlePolicy.After {
+			continue
+		}
+		items = append(items, models.Recommendation{
+			ID:         "idle-" + id,
+			Kind:       models.RecommendationIdle,
+			Action:     models.ActionStop,
+			Reason:     fmt.Sprintf("Running with no viewers and no output for %s", idleFor.Round(time.Second)),
+			ResourceID: id,
+			Name:       res.Name,
+			Namespace:  res.Namespace,
+			VendorType: res.Spec.VendorType,
+			IdleSince:  state.since,
+			AutoApply:  c.IdlePolicy.autoStops(res.Namespace),
+		})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].IdleSince.Equal(items[j].IdleSince) {
+			return items[i].IdleSince.Before(items[j].IdleSince)
+		}
+		return items[i].ID < items[j].ID
+	})
+	return items
+}
+
+// HandleListRecommendations handles GET /recommendations
+func (c *Controller) HandleListRecommendations(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": c.idleRecommendations()})
+}
+
+// HandleApplyRecommendation handles POST /recommendations/{id}/apply
+func (c *Controller) HandleApplyRecommendation(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var found *models.Recommendation
+	for _, rec := range c.idleRecommendations() {
+		if rec.ID == id {
+			found = &rec
+			break
+		}
+	}
+	if found == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "recommendation not found (the resource may no longer be idle)"})
+		return
+	}
+
+	detail := " (recommendation " + found.ID + ")"
+	if principal, ok := principalFrom(r.Context()); ok {
+		detail = " by " + principal.Name + detail
+	}
+	// WHY THE SAME CHECKS AS POST :stop: Applying every recommendation in a
+	// loop stops a show just the same
+	if c.rejectIfLocked(w, r, found.ResourceID) || c.rejectIfPreconditionFailed(w, r, found.ResourceID) {
+		return
+	}
+	if c.guardMassDelete(w, r, massOpStop, []string{found.ResourceID}) {
+		return
+	}
+	if c.deferForMaintenance(w, r, found.ResourceID, "stop", detail, nil) {
+		return
+	}
+	res, err := c.setPower(vendorContext(r), found.ResourceID, true, detail)
+	if err != nil {
+		c.refundMassDelete(r, []string{found.ResourceID})
+		writeOperationError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	setResourceETag(w, res)
+	redact := c.redactionFor(r)
+	redact.announce(w)
+	json.NewEncoder(w).Encode(redact.resource(res))
+}
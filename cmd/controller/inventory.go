@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/Zhichengu1/mock-control-plane/pkg/provider"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// PAGED INVENTORY SYNC
+// =============================================================================
+// Vendors with thousands of devices list them in pages (provider.
+// PagedDiscoverer). Listing them all inside one discovery scan means a
+// timeout or vendor hiccup on page 40 throws away pages 1-39, and the
+// next scan starts over and fails in the same place.
+//
+// Instead, each page is reconciled as soon as it arrives and the cursor of
+// the next page is saved per provider. A scan that stops part-way leaves
+// the sync "interrupted"; the next POST /discovery/scan resumes from the
+// saved cursor. Pending proposals for devices that vanished are only
+// dropped after a complete pass, since a partial one hasn't seen them all.
+//
+// INVENTORY_STATE_FILE keeps cursors across restarts (written after every
+// page); without it they only survive within the process.
+//
+//   GET    /admin/inventory            sync progress per provider
+//   DELETE /admin/inventory/{vendor}   discard the cursor; next scan starts over
+// =============================================================================
+
+// Inventory sync states.
+const (
+	syncRunning     = "running"
+	syncInterrupted = "interrupted"
+	syncComplete    = "complete"
+)
+
+// errSyncRunning refuses a second sync of the same vendor.
+var errSyncRunning = errors.New("inventory sync already running")
+
+// InventorySync is the progress of one provider's paged inventory sync.
+type InventorySync struct {
+	Vendor string `json:"vendor"`
+	State  string `json:"state"`
+
+	// Cursor is where the next page starts ("" = the beginning)
+	Cursor string `json:"cursor,omitempty"`
+
+	// Pages and Devices count what this pass has reconciled so far;
+	// Total is the vendor's device count, if it reports one
+	Pages   int `json:"pages"`
+	Devices int `json:"devices"`
+	Total   int `json:"total,omitempty"`
+	Percent int `json:"percent,omitempty"`
+
+	// Resumes counts how often this pass continued from a saved cursor
+	Resumes int `json:"resumes"`
+
+	LastError   string    `json:"last_error,omitempty"`
+	StartedAt   time.Time `json:"started_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	CompletedAt time.Time `json:"completed_at,omitempty"`
+
+	// Seen holds the adoption IDs reconciled in this pass, for dropping
+	// stale proposals once it completes (persisted, not shown)
+	Seen map[string]bool `json:"seen,omitempty"`
+}
+
+// inventoryState holds sync progress by vendor.
+type inventoryState struct {
+	mu      sync.Mutex
+	path    string // INVENTORY_STATE_FILE ("" = memory only)
+	syncs   map[string]*InventorySync
+	running map[string]bool
+}
+
+// inventoryFile is the layout of INVENTORY_STATE_FILE.
+type inventoryFile struct {
+	Syncs map[string]*InventorySync `json:"syncs"`
+}
+
+// newInventoryState loads saved sync progress from path, if any.
+func newInventoryState(path string) *inventoryState {
+	state := &inventoryState{path: path, syncs: make(map[string]*InventorySync), running: make(map[string]bool)}
+	if path == "" {
+		return state
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state
+	}
+	var file inventoryFile
+	if err == nil {
+		err = json.Unmarshal(data, &file)
+	}
+	if err != nil {
+		logger.Warnf("Ignoring INVENTORY_STATE_FILE %s: %v", path, err)
+		return state
+	}
+	for vendor, s := range file.Syncs {
+		// WHY: A sync that was running when the process stopped was
+		// interrupted; the next scan resumes it
+		if s.State == syncRunning {
+			s.State = syncInterrupted
+		}
+		state.syncs[vendor] = s
+	}
+	logger.Infof("Loaded inventory sync state for %d providers from %s", len(state.syncs), path)
+	return state
+}
+
+// saveLocked writes all syncs to the state file. Must be called with mu held.
+// WHY RENAME: A crash mid-write must not leave a truncated file behind
+func (s *inventoryState) saveLocked() {
+	if s.path == "" {
+		return
+	}
+	data, err := json.Marshal(inventoryFile{Syncs: s.syncs})
+	if err == nil {
+		tmp := s.path + ".tmp"
+		if err = os.WriteFile(tmp, data, 0o600); err == nil {
+			err = os.Rename(tmp, s.path)
+		}
+	}
+	if err != nil {
+		logger.Warnf("Failed to save inventory sync state: %v", err)
+	}
+}
+
+// syncInventory reconciles vendor's inventory page by page, resuming from
+// a saved cursor if the last pass was interrupted.
+func (c *Controller) syncInventory(ctx context.Context, vendor string, p provider.PagedDiscoverer, result *ScanResult) error {
+	state := c.inventory
+	state.mu.Lock()
+	if state.running[vendor] {
+		state.mu.Unlock()
+		return errSyncRunning
+	}
+	now := c.Clock.Now()
+	progress := state.syncs[vendor]
+	if progress == nil || progress.State == syncComplete {
+		progress = &InventorySync{Vendor: vendor, StartedAt: now, Seen: make(map[string]bool)}
+		state.syncs[vendor] = progress
+	} else {
+		progress.Resumes++
+		result.Resumed = append(result.Resumed, vendor)
+		logger.Infof("Resuming %s inventory sync at page %d", vendor, progress.Pages+1)
+	}
+	if progress.Seen == nil {
+		progress.Seen = make(map[string]bool)
+	}
+	progress.State = syncRunning
+	progress.LastError = ""
+	progress.UpdatedAt = now
+	cursor := progress.Cursor
+	state.running[vendor] = true
+	state.saveLocked()
+	state.mu.Unlock()
+
+	defer func() {
+		state.mu.Lock()
+		delete(state.running, vendor)
+		state.mu.Unlock()
+	}()
+
+	restarted := false
+	for {
+		// Step 1: Fetch the next page
+		release, err := c.acquireVendor(ctx, vendor)
+		var page *models.DiscoveryPage
+		if err == nil {
+			page, err = p.DiscoverPage(ctx, cursor, c.InventoryPageSize)
+			release()
+		}
+		if errors.Is(err, provider.ErrInvalidRequest) && cursor != "" && !restarted {
+			// WHY START OVER: The vendor no longer accepts the saved
+			// cursor (expired); resuming is impossible, but a fresh pass isn't
+			logger.Warnf("%s rejected the saved inventory cursor; starting over: %v", vendor, err)
+			restarted = true
+			state.mu.Lock()
+			resumes := progress.Resumes
+			*progress = InventorySync{Vendor: vendor, State: syncRunning, Resumes: resumes,
+				StartedAt: c.Clock.Now(), UpdatedAt: c.Clock.Now(), Seen: make(map[string]bool)}
+			state.mu.Unlock()
+			cursor = ""
+			continue
+		}
+		if err != nil {
+			state.mu.Lock()
+			progress.State = syncInterrupted
+			progress.LastError = err.Error()
+			progress.UpdatedAt = c.Clock.Now()
+			pages := progress.Pages
+			state.saveLocked()
+			state.mu.Unlock()
+			return fmt.Errorf("inventory sync interrupted after %d pages; the next scan resumes it: %w", pages, err)
+		}
+
+		// Step 2: Reconcile it right away
+		seen := make(map[string]bool, len(page.Devices))
+		c.mu.Lock()
+		c.reconcileDevicesLocked(vendor, page.Devices, seen, result)
+		c.mu.Unlock()
+
+		// Step 3: Save the cursor of the next page
+		state.mu.Lock()
+		for id := range seen {
+			progress.Seen[id] = true
+		}
+		progress.Pages++
+		progress.Devices += len(page.Devices)
+		progress.Total = page.Total
+		progress.Cursor = page.NextCursor
+		progress.UpdatedAt = c.Clock.Now()
+		done := page.NextCursor == ""
+		var seenAll map[string]bool
+		if done {
+			progress.State = syncComplete
+			progress.CompletedAt = progress.UpdatedAt
+			seenAll, progress.Seen = progress.Seen, nil
+		}
+		state.saveLocked()
+		state.mu.Unlock()
+
+		if done {
+			// Step 4: A complete pass has seen every device; drop the rest
+			c.mu.Lock()
+			c.dropUnseenLocked(vendor, seenAll)
+			c.mu.Unlock()
+			return nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// HandleListInventorySyncs handles GET /admin/inventory
+func (c *Controller) HandleListInventorySyncs(w http.ResponseWriter, r *http.Request) {
+	c.inventory.mu.Lock()
+	items := make([]InventorySync, 0, len(c.inventory.syncs))
+	for _, s := range c.inventory.syncs {
+		item := *s
+		item.Seen = nil
+		if item.Total > 0 {
+			item.Percent = item.Devices * 100 / item.Total
+			if item.Percent > 100 {
+				item.Percent = 100
+			}
+		}
+		items = append(items, item)
+	}
+	c.inventory.mu.Unlock()
+
+	sort.Slice(items, func(i, j int) bool { return items[i].Vendor < items[j].Vendor })
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"page_size": c.InventoryPageSize,
+		"persisted": c.inventory.path != "",
+		"items":     items,
+	})
+}
+
+// HandleResetInventorySync handles DELETE /admin/inventory/{vendor}
+// Discards the saved cursor so the next scan starts from the first page.
+func (c *Controller) HandleResetInventorySync(w http.ResponseWriter, r *http.Request) {
+	vendor := mux.Vars(r)["vendor"]
+	c.inventory.mu.Lock()
+	defer c.inventory.mu.Unlock()
+	if c.inventory.running[vendor] {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": errSyncRunning.Error()})
+		return
+	}
+	if _, exists := c.inventory.syncs[vendor]; !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "no inventory sync for " + vendor})
+		return
+	}
+	delete(c.inventory.syncs, vendor)
+	c.inventory.saveLocked()
+	logger.Infof("Inventory sync of %s reset", vendor)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// JSON PATCH (RFC 6902)
+// =============================================================================
+// A merge patch can't remove one element of a list or tell "set to null"
+// from "remove". A JSON Patch names each edit exactly:
+//
+//   PATCH /resources/{id}
+//   Content-Type: application/json-patch+json
+//   [
+//     {"op": "replace", "path": "/spec/bitrate", "value": 8000000},
+//     {"op": "add",     "path": "/spec/config/gop", "value": 2},
+//     {"op": "remove",  "path": "/metadata/runbook_url"},
+//     {"op": "add",     "path": "/labels/site", "value": "stadium-a"}
+//   ]
+//
+// Paths are JSON Pointers (RFC 6901) into the resource as GET shows it,
+// limited to /spec (any depth, spec.config included),
+// /metadata/notes, /metadata/runbook_url, /labels and /annotations (or
+// one key, /labels/<key>; a key with a slash is escaped as ~1) and
+// /finalizers (or one entry, /finalizers/<index>, /finalizers/- to
+// append) and /owner_ref. add, remove and replace are
+// supported; replacing a spec field that is unset (and so missing from
+// GET) works like add. Operations apply in order to a copy; if any fails
+// (a missing path, a parent that isn't an object or array, an index out
+// of range, an unknown spec field), nothing is applied and the error
+// names the operation.
+// =============================================================================
+
+// jsonPatchOps are the supported operations.
+var jsonPatchOps = []string{"add", "remove", "replace"}
+
+// jsonPatchOp is one RFC 6902 operation.
+type jsonPatchOp struct {
+	Op   string `json:"op"`
+	Path string `json:"path"`
+
+	// Value is nil if absent (json "null" is a value)
+	Value json.RawMessage `json:"value"`
+}
+
+// decodeJSONValue decodes data keeping numbers exact (a bitrate must not
+// round-trip through float64).
+func decodeJSONValue(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	err := decoder.Decode(&value)
+	return value, err
+}
+
+// parsePointer splits a JSON Pointer into unescaped tokens.
+func parsePointer(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, errors.New("path must start with /")
+	}
+	tokens := strings.Split(path[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// applyJSONPatch applies a JSON Patch body to current.
+func applyJSONPatch(body []byte, current *models.ForgeResource) (patchResult, error) {
+	result := patchResult{spec: current.Spec, metadata: current.Metadata, labels: current.Labels, annotations: current.Annotations, finalizers: current.Finalizers, ownerRef: current.OwnerRef}
+	var ops []jsonPatchOp
+	if err := json.Unmarshal(body, &ops); err != nil {
+		return result, fmt.Errorf("invalid JSON Patch (want an array of operations): %w", err)
+	}
+	if len(ops) == 0 {
+		return result, errors.New("nothing to patch: the JSON Patch is empty")
+	}
+
+	// Step 1: The patchable part of the resource, as GET shows it
+	specJSON, err := json.Marshal(current.Spec)
+	if err != nil {
+		return result, err
+	}
+	specDoc, err := decodeJSONValue(specJSON)
+	if err != nil {
+		return result, err
+	}
+	metaDoc := map[string]interface{}{}
+	if current.Metadata.Notes != "" {
+		metaDoc["notes"] = current.Metadata.Notes
+	}
+	if current.Metadata.RunbookURL != "" {
+		metaDoc["runbook_url"] = current.Metadata.RunbookURL
+	}
+	finalizersDoc := make([]interface{}, 0, len(current.Finalizers))
+	for _, name := range current.Finalizers {
+		finalizersDoc = append(finalizersDoc, name)
+	}
+	doc := map[string]interface{}{"spec": specDoc, "metadata": metaDoc,
+		"labels": stringMapDoc(current.Labels), "annotations": stringMapDoc(current.Annotations),
+		"finalizers": finalizersDoc}
+	if current.OwnerRef != "" {
+		doc["owner_ref"] = current.OwnerRef
+	}
+
+	// Step 2: Apply the operations in order
+	for i, op := range ops {
+		if err := applyJSONPatchOp(doc, op); err != nil {
+			return result, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+
+	// Step 3: Read the spec and metadata back
+	merged, err := json.Marshal(doc["spec"])
+	if err != nil {
+		return result, err
+	}
+	var spec models.ResourceSpec
+	decoder := json.NewDecoder(bytes.NewReader(merged))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&spec); err != nil {
+		return result, fmt.Errorf("patched spec is invalid: %w", err)
+	}
+	meta := current.Metadata
+	targets := map[string]*string{"notes": &meta.Notes, "runbook_url": &meta.RunbookURL}
+	for _, field := range metadataFields {
+		value, set := metaDoc[field]
+		text, isString := value.(string)
+		if set && !isString {
+			return result, fmt.Errorf("metadata.%s must be a string", field)
+		}
+		if *targets[field] != text {
+			*targets[field] = text
+			result.metaChanged = append(result.metaChanged, field)
+		}
+	}
+
+	labels, labelsChanged, err := readStringMap(doc, "labels", current.Labels)
+	if err != nil {
+		return result, err
+	}
+	annotations, annotationsChanged, err := readStringMap(doc, "annotations", current.Annotations)
+	if err != nil {
+		return result, err
+	}
+	result.labelsChanged, result.annotationsChanged = labelsChanged, annotationsChanged
+	if result.finalizers, err = readFinalizers(doc); err != nil {
+		return result, err
+	}
+	result.finalizersChanged = !reflect.DeepEqual(result.finalizers, current.Finalizers)
+	if value, set := doc["owner_ref"]; set && value != nil {
+		ownerRef, isString := value.(string)
+		if !isString {
+			return result, errors.New("owner_ref must be a resource ID")
+		}
+		result.ownerRef = ownerRef
+	} else {
+		result.ownerRef = ""
+	}
+	result.ownerRefChanged = result.ownerRef != current.OwnerRef
+
+	before, after := specFields(current.Spec), specFields(spec)
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			before[key] = ""
+		}
+	}
+	for key, value := range before {
+		if after[key] != value {
+			result.specChanged = append(result.specChanged, key)
+		}
+	}
+	sort.Strings(result.specChanged)
+	result.spec, result.metadata, result.labels, result.annotations = spec, meta, labels, annotations
+	return result, nil
+}
+
+// stringMapDoc returns labels or annotations as a patchable document.
+func stringMapDoc(m map[string]string) map[string]interface{} {
+	doc := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		doc[key] = value
+	}
+	return doc
+}
+
+// readStringMap reads the labels or annotations (field) back from doc.
+// Returns the map (nil if empty) and the keys that differ from current.
+func readStringMap(doc map[string]interface{}, field string, current map[string]string) (map[string]string, []string, error) {
+	value, isObject := doc[field].(map[string]interface{})
+	if !isObject {
+		return nil, nil, fmt.Errorf("%s must be an object", field)
+	}
+	m := make(map[string]string, len(value))
+	var changed []string
+	for key, v := range value {
+		text, isString := v.(string)
+		if !isString {
+			return nil, nil, fmt.Errorf("%s.%s must be a string", field, key)
+		}
+		m[key] = text
+		if old, set := current[key]; !set || old != text {
+			changed = append(changed, key)
+		}
+	}
+	for key := range current {
+		if _, kept := m[key]; !kept {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	if len(m) == 0 {
+		m = nil
+	}
+	return m, changed, nil
+}
+
+// readFinalizers reads the finalizers back from doc (nil if none).
+func readFinalizers(doc map[string]interface{}) ([]string, error) {
+	value, isArray := doc["finalizers"].([]interface{})
+	if !isArray {
+		return nil, errors.New("finalizers must be an array")
+	}
+	var finalizers []string
+	for i, v := range value {
+		text, isString := v.(string)
+		if !isString {
+			return nil, fmt.Errorf("finalizers/%d must be a string", i)
+		}
+		finalizers = append(finalizers, text)
+	}
+	return finalizers, nil
+}
+
+// applyJSONPatchOp applies one operation to doc ({"spec", "metadata"}).
+func applyJSONPatchOp(doc map[string]interface{}, op jsonPatchOp) error {
+	supported := false
+	for _, name := range jsonPatchOps {
+		supported = supported || op.Op == name
+	}
+	if !supported {
+		return fmt.Errorf("unsupported op (supported: %s)", strings.Join(jsonPatchOps, ", "))
+	}
+	tokens, err := parsePointer(op.Path)
+	if err != nil {
+		return err
+	}
+
+	// Only /spec/..., /metadata/<field>, /labels[/<key>],
+	// /annotations[/<key>], /finalizers[/<index>] and /owner_ref can be
+	// patched
+	switch {
+	case len(tokens) == 0:
+		return errors.New("the whole resource can't be patched; use paths under /spec or /metadata")
+	case tokens[0] == "spec":
+		if len(tokens) == 1 && op.Op != "replace" {
+			return errors.New("/spec can only be replaced")
+		}
+	case tokens[0] == "labels" || tokens[0] == "annotations":
+		if len(tokens) > 2 {
+			return fmt.Errorf("%s values are strings; use /%s/<key>", tokens[0], tokens[0])
+		}
+		if len(tokens) == 1 && op.Op != "replace" {
+			return fmt.Errorf("/%s can only be replaced", tokens[0])
+		}
+	case tokens[0] == "finalizers":
+		if len(tokens) > 2 {
+			return errors.New("finalizers are strings; use /finalizers/<index>")
+		}
+		if len(tokens) == 1 && op.Op != "replace" {
+			return errors.New("/finalizers can only be replaced")
+		}
+	case tokens[0] == "owner_ref":
+		if len(tokens) > 1 {
+			return errors.New("owner_ref is a string; use /owner_ref")
+		}
+	case tokens[0] == "metadata":
+		if len(tokens) != 2 || !containsString(metadataFields, tokens[1]) {
+			return fmt.Errorf("only /metadata/%s can be patched", strings.Join(metadataFields, " and /metadata/"))
+		}
+	default:
+		return fmt.Errorf("only %s can be patched", "/"+strings.Join(patchableFields, " and /"))
+	}
+
+	var value interface{}
+	if op.Op != "remove" {
+		if op.Value == nil {
+			return errors.New("value is required")
+		}
+		if value, err = decodeJSONValue(op.Value); err != nil {
+			return err
+		}
+	}
+	operation := op.Op
+	if operation == "replace" && len(tokens) == 2 && tokens[0] == "spec" && specFieldNames()[tokens[1]] {
+		spec, _ := doc["spec"].(map[string]interface{})
+		if _, set := spec[tokens[1]]; !set {
+			operation = "add"
+		}
+	}
+	_, err = patchNode(doc, tokens, operation, value)
+	return err
+}
+
+// specFieldNames returns the JSON names of ResourceSpec's fields.
+func specFieldNames() map[string]bool {
+	names := make(map[string]bool)
+	t := reflect.TypeOf(models.ResourceSpec{})
+	for i := 0; i < t.NumField(); i++ {
+		if name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// patchNode applies op at tokens below node and returns the (possibly
+// new) node; arrays are replaced rather than changed in place.
+func patchNode(node interface{}, tokens []string, op string, value interface{}) (interface{}, error) {
+	key, last := tokens[0], len(tokens) == 1
+	switch n := node.(type) {
+	case map[string]interface{}:
+		child, exists := n[key]
+		if !exists && (op != "add" || !last) {
+			return nil, fmt.Errorf("%q does not exist", key)
+		}
+		if !last {
+			updated, err := patchNode(child, tokens[1:], op, value)
+			if err != nil {
+				return nil, err
+			}
+			n[key] = updated
+			return n, nil
+		}
+		if op == "remove" {
+			delete(n, key)
+		} else {
+			n[key] = value
+		}
+		return n, nil
+
+	case []interface{}:
+		if key == "-" && last && op == "add" {
+			return append(n, value), nil
+		}
+		index, err := strconv.Atoi(key)
+		limit := len(n)
+		if last && op == "add" {
+			limit++ // add may insert at the end
+		}
+		if err != nil || index < 0 || index >= limit || (len(key) > 1 && key[0] == '0') {
+			return nil, fmt.Errorf("array index %q is out of range (length %d)", key, len(n))
+		}
+		if !last {
+			updated, err := patchNode(n[index], tokens[1:], op, value)
+			if err != nil {
+				return nil, err
+			}
+			n[index] = updated
+			return n, nil
+		}
+		switch op {
+		case "add":
+			n = append(n[:index], append([]interface{}{value}, n[index:]...)...)
+		case "remove":
+			n = append(n[:index], n[index+1:]...)
+		default:
+			n[index] = value
+		}
+		return n, nil
+
+	case nil:
+		return nil, fmt.Errorf("parent of %q is null; add it as an object first", key)
+	default:
+		return nil, fmt.Errorf("parent of %q is not an object or array", key)
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
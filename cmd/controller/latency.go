@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/audit"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// LATENCY BUDGETS AND PROFILING
+// =============================================================================
+// Each route may have a latency budget: how long a client should ever wait
+// for it, however slow the vendor is. LATENCY_BUDGETS sets them as
+// "METHOD /route=duration" entries, routes written the way they are
+// registered, without the base path or version:
+//
+//   LATENCY_BUDGETS="GET /resources/{id}=2s,GET /resources=500ms"
+//
+// GET /resources/{id} (and its /namespaces/{ns} form) defaults to 2s; an
+// entry with 0 removes a budget.
+//
+// ENFORCEMENT: The request context carries the budget as its deadline.
+// Routes with a cache to fall back on answer from it when the deadline
+// comes first, with "Forge-Degraded: <reason>" on the response:
+//
+//   GET /resources/{id}   the stored status with status.partial=true,
+//                         marked "vendor-timeout" (the vendor read keeps
+//                         going and refreshes the cache) or "vendor-error";
+//                         a read that fails queues the resource for the
+//                         reconciler (see queueRefresh)
+//
+// Routes without a fallback are only measured: writes are never cut short
+// by a budget, since abandoning a vendor call halfway leaves its outcome
+// unknown.
+//
+// GET /admin/latency reports, per route, requests, budget violations,
+// fallbacks and latency percentiles over recent requests, and the same
+// for each pass of the background loops (reconciler, idle analyzer,
+// compactor, maintenance, queued replay). /admin/pprof/{profile} serves
+// the Go runtime profiles (admin only).
+// =============================================================================
+
+// HeaderDegraded names why a response was served from the cache.
+const HeaderDegraded = "Forge-Degraded"
+
+// Fallback reasons.
+const (
+	degradedVendorTimeout = "vendor-timeout"
+	degradedVendorError   = "vendor-error"
+)
+
+// latencySamples is how many recent durations each route keeps for its
+// percentiles.
+const latencySamples = 512
+
+// defaultLatencyBudgets apply unless LATENCY_BUDGETS overrides them.
+var defaultLatencyBudgets = map[string]time.Duration{
+	"GET /resources/{id}":                 2 * time.Second,
+	"GET /namespaces/{ns}/resources/{id}": 2 * time.Second,
+}
+
+// latencyStats accumulates the timings of one route or loop.
+type latencyStats struct {
+	count      int
+	overBudget int
+	fallbacks  map[string]int
+	max        time.Duration
+	lastOver   time.Time
+
+	// recent is a ring of the last latencySamples durations
+	recent []time.Duration
+	next   int
+}
+
+// observe records one duration.
+func (s *latencyStats) observe(d time.Duration) {
+	s.count++
+	if d > s.max {
+		s.max = d
+	}
+	if len(s.recent) < latencySamples {
+		s.recent = append(s.recent, d)
+		return
+	}
+	s.recent[s.next] = d
+	s.next = (s.next + 1) % latencySamples
+}
+
+// LatencyReport is one entry of GET /admin/latency.
+type LatencyReport struct {
+	Route      string         `json:"route"`
+	BudgetMS   float64        `json:"budget_ms,omitempty"`
+	Count      int            `json:"count"`
+	OverBudget int            `json:"over_budget"`
+	LastOverAt *time.Time     `json:"last_over_budget_at,omitempty"`
+	Fallbacks  map[string]int `json:"fallbacks,omitempty"`
+	P50MS      float64        `json:"p50_ms"`
+	P95MS      float64        `json:"p95_ms"`
+	P99MS      float64        `json:"p99_ms"`
+	MaxMS      float64        `json:"max_ms"`
+}
+
+// latencyMillis converts d to milliseconds with two decimals.
+func latencyMillis(d time.Duration) float64 {
+	return math.Round(float64(d.Microseconds())/10) / 100
+}
+
+// report summarizes s for route.
+func (s *latencyStats) report(route string, budget time.Duration) LatencyReport {
+	report := LatencyReport{
+		Route:      route,
+		BudgetMS:   latencyMillis(budget),
+		Count:      s.count,
+		OverBudget: s.overBudget,
+		MaxMS:      latencyMillis(s.max),
+	}
+	if !s.lastOver.IsZero() {
+		at := s.lastOver
+		report.LastOverAt = &at
+	}
+	if len(s.fallbacks) > 0 {
+		report.Fallbacks = make(map[string]int, len(s.fallbacks))
+		for reason, n := range s.fallbacks {
+			report.Fallbacks[reason] = n
+		}
+	}
+	if n := len(s.recent); n > 0 {
+		sorted := append([]time.Duration(nil), s.recent...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		percentile := func(p float64) float64 {
+			return latencyMillis(sorted[int(math.Ceil(p*float64(n)))-1])
+		}
+		report.P50MS, report.P95MS, report.P99MS = percentile(0.50), percentile(0.95), percentile(0.99)
+	}
+	return report
+}
+
+// latencyState holds the budgets and the timings of routes and loops.
+type latencyState struct {
+	budgets map[string]time.Duration
+
+	mu     sync.Mutex
+	routes map[string]*latencyStats
+	loops  map[string]*latencyStats
+}
+
+// newLatencyState loads LATENCY_BUDGETS over the defaults.
+func newLatencyState() *latencyState {
+	state := &latencyState{
+		budgets: make(map[string]time.Duration),
+		routes:  make(map[string]*latencyStats),
+		loops:   make(map[string]*latencyStats),
+	}
+	for route, budget := range defaultLatencyBudgets {
+		state.budgets[route] = budget
+	}
+	for _, entry := range strings.Split(os.Getenv("LATENCY_BUDGETS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		i := strings.LastIndex(entry, "=")
+		method, path, ok := strings.Cut(strings.TrimSpace(entry[:max(i, 0)]), " ")
+		budget, err := time.ParseDuration(strings.TrimSpace(entry[i+1:]))
+		if i < 0 || !ok || !strings.HasPrefix(path, "/") || err != nil || budget < 0 {
+			logger.Warnf("Ignoring LATENCY_BUDGETS entry %q: want \"METHOD /route=duration\"", entry)
+			continue
+		}
+		route := strings.ToUpper(method) + " " + strings.TrimSpace(path)
+		if budget == 0 {
+			delete(state.budgets, route)
+			continue
+		}
+		state.budgets[route] = budget
+	}
+	return state
+}
+
+// stats returns the timings of name in set, creating them. Caller must
+// hold x.mu.
+func (x *latencyState) stats(set map[string]*latencyStats, name string) *latencyStats {
+	s, ok := set[name]
+	if !ok {
+		s = &latencyStats{}
+		set[name] = s
+	}
+	return s
+}
+
+// routeKeyContextKey carries the route key of a measured request.
+type routeKeyContextKey struct{}
+
+// routeKey names the route r matched: "GET /resources/{id}" for both
+// /v1/resources/{id} and the unversioned alias.
+func (c *Controller) routeKey(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return ""
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return ""
+	}
+	template = strings.TrimPrefix(template, c.BasePath)
+	if rest := strings.TrimPrefix(template, "/"+currentAPIVersion); strings.HasPrefix(rest, "/") {
+		template = rest
+	}
+	return r.Method + " " + template
+}
+
+// LatencyMiddleware sets each request's deadline to its route's budget and
+// records how long the route took.
+func (c *Controller) LatencyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := c.routeKey(r)
+		// WHY NOT WATCHES: A watch is open for as long as the client
+		// wants; its duration says nothing about the controller
+		if key == "" || strings.HasSuffix(key, "/watch") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		budget := c.latency.budgets[key]
+		ctx := context.WithValue(r.Context(), routeKeyContextKey{}, key)
+		if budget > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, budget)
+			defer cancel()
+		}
+		started := time.Now()
+		next.ServeHTTP(w, r.WithContext(ctx))
+		elapsed := time.Since(started)
+
+		c.latency.mu.Lock()
+		stats := c.latency.stats(c.latency.routes, key)
+		stats.observe(elapsed)
+		over := budget > 0 && elapsed > budget
+		if over {
+			stats.overBudget++
+			stats.lastOver = c.Clock.Now()
+		}
+		c.latency.mu.Unlock()
+		if over {
+			logger.Warnf("%s took %s, over its %s budget (request %s)", key, elapsed.Round(time.Millisecond), budget, audit.RequestID(r.Context()))
+		}
+	})
+}
+
+// fallbackMargin is kept from the budget to answer from the cache.
+const fallbackMargin = 50 * time.Millisecond
+
+// fallbackContext returns a context that ends when r should stop waiting
+// for the vendor and answer from the cache: fallbackMargin before its
+// budget runs out, or when the client goes away.
+func fallbackContext(r *http.Request) (context.Context, context.CancelFunc) {
+	if deadline, ok := r.Context().Deadline(); ok {
+		return context.WithDeadline(r.Context(), deadline.Add(-fallbackMargin))
+	}
+	return context.WithCancel(r.Context())
+}
+
+// markDegraded records that r was answered from the cache, and says so on
+// the response.
+func (c *Controller) markDegraded(w http.ResponseWriter, r *http.Request, reason string) {
+	w.Header().Set(HeaderDegraded, reason)
+	key, _ := r.Context().Value(routeKeyContextKey{}).(string)
+	if key == "" {
+		return
+	}
+	c.latency.mu.Lock()
+	defer c.latency.mu.Unlock()
+	stats := c.latency.stats(c.latency.routes, key)
+	if stats.fallbacks == nil {
+		stats.fallbacks = make(map[string]int)
+	}
+	stats.fallbacks[reason]++
+}
+
+// profileLoop records how long one pass of background loop name took;
+// call it as defer c.profileLoop(name, time.Now()).
+func (c *Controller) profileLoop(name string, started time.Time) {
+	elapsed := time.Since(started)
+	c.latency.mu.Lock()
+	defer c.latency.mu.Unlock()
+	c.latency.stats(c.latency.loops, name).observe(elapsed)
+}
+
+// HandleGetLatency handles GET /admin/latency
+func (c *Controller) HandleGetLatency(w http.ResponseWriter, r *http.Request) {
+	c.latency.mu.Lock()
+	routes := make([]LatencyReport, 0, len(c.latency.routes)+len(c.latency.budgets))
+	for key, stats := range c.latency.routes {
+		routes = append(routes, stats.report(key, c.latency.budgets[key]))
+	}
+	// Budgeted routes that haven't been called yet are listed too
+	for key, budget := range c.latency.budgets {
+		if _, seen := c.latency.routes[key]; !seen {
+			routes = append(routes, (&latencyStats{}).report(key, budget))
+		}
+	}
+	loops := make([]LatencyReport, 0, len(c.latency.loops))
+	for name, stats := range c.latency.loops {
+		loops = append(loops, stats.report(name, 0))
+	}
+	c.latency.mu.Unlock()
+
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Route < routes[j].Route })
+	sort.Slice(loops, func(i, j int) bool { return loops[i].Route < loops[j].Route })
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"routes": routes,
+		"loops":  loops,
+	})
+}
+
+// HandleProfile handles GET /admin/pprof/{profile}
+// "profile" is a CPU profile (?seconds=, default 30), "trace" an execution
+// trace; any other name is a runtime profile (heap, goroutine, allocs,
+// block, mutex, threadcreate).
+func (c *Controller) HandleProfile(w http.ResponseWriter, r *http.Request) {
+	switch name := mux.Vars(r)["profile"]; name {
+	case "profile":
+		pprof.Profile(w, r)
+	case "trace":
+		pprof.Trace(w, r)
+	default:
+		pprof.Handler(name).ServeHTTP(w, r)
+	}
+}
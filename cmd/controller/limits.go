@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/memguard"
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/Zhichengu1/mock-control-plane/pkg/validation"
+)
+
+// =============================================================================
+// CAPACITY LIMITS AND MEMORY GUARDRAILS
+// =============================================================================
+// All state lives in memory, so unbounded growth eventually kills the
+// process. Two independent safety nets:
+//
+// 1. Resource caps (checked on create/adopt), plus per-resource size
+//    limits (SPEC_MAX_*, checked on create; see pkg/validation):
+//    MAX_RESOURCES                → 507 Insufficient Storage when reached
+//    MAX_RESOURCES_PER_NAMESPACE  → 429 Too Many Requests when reached
+//
+// 2. Memory watermarks (memguard, sampled every MEMORY_CHECK_INTERVAL):
+//    soft → pause background reconciliation and shed caches
+//           (history, events and audit trail are trimmed)
+//    hard → additionally refuse new resources with 507
+//
+// WHY 507 vs 429: A full controller is a server-side capacity problem
+// (507 Insufficient Storage); a full namespace is a per-tenant quota the
+// caller exceeded (429), the same way rate limits are reported.
+// =============================================================================
+
+// maxResourceBodyBytes bounds a create request body. SpecLimits bound the
+// individual fields; this stops a huge body before it is even decoded.
+const maxResourceBodyBytes = 1 << 20 // 1 MiB
+
+// loadSpecLimits reads resource size limits from SPEC_MAX_* variables,
+// defaulting to validation.DefaultSizeLimits (0 disables a limit).
+func loadSpecLimits() validation.SizeLimits {
+	d := validation.DefaultSizeLimits
+	return validation.SizeLimits{
+		MaxConfigKeys:       envInt("SPEC_MAX_CONFIG_KEYS", d.MaxConfigKeys),
+		MaxConfigKeyLength:  envInt("SPEC_MAX_CONFIG_KEY_LENGTH", d.MaxConfigKeyLength),
+		MaxConfigValueBytes: envInt("SPEC_MAX_CONFIG_VALUE_BYTES", d.MaxConfigValueBytes),
+		MaxConfigBytes:      envInt("SPEC_MAX_CONFIG_BYTES", d.MaxConfigBytes),
+		MaxConfigDepth:      envInt("SPEC_MAX_CONFIG_DEPTH", d.MaxConfigDepth),
+		MaxNameLength:       envInt("SPEC_MAX_NAME_LENGTH", d.MaxNameLength),
+		MaxNamespaceLength:  envInt("SPEC_MAX_NAMESPACE_LENGTH", d.MaxNamespaceLength),
+		MaxTypeLength:       envInt("SPEC_MAX_TYPE_LENGTH", d.MaxTypeLength),
+		MaxDependencies:     envInt("SPEC_MAX_DEPENDENCIES", d.MaxDependencies),
+		MaxNotesLength:      envInt("SPEC_MAX_NOTES_LENGTH", d.MaxNotesLength),
+		MaxLabels:           envInt("SPEC_MAX_LABELS", d.MaxLabels),
+		MaxAnnotationBytes:  envInt("SPEC_MAX_ANNOTATION_BYTES", d.MaxAnnotationBytes),
+	}
+}
+
+// defaultNamespace is used for capacity accounting when a resource has
+// no namespace.
+const defaultNamespace = "default"
+
+// Cache sizes kept when shedding under memory pressure.
+const (
+	shedKeepRevisions    = 5
+	shedKeepEvents       = 10
+	shedKeepAuditEntries = 1000
+)
+
+// CapacityError is returned when a new resource would exceed a limit.
+type CapacityError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *CapacityError) Error() string { return e.Message }
+
+// writeCapacityError writes e as a JSON error response.
+func writeCapacityError(w http.ResponseWriter, e *CapacityError) {
+	w.WriteHeader(e.StatusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": e.Message})
+}
+
+// namespaceKey maps an empty namespace to the default namespace.
+func namespaceKey(namespace string) string {
+	if namespace == "" {
+		return defaultNamespace
+	}
+	return namespace
+}
+
+// checkCapacityLocked reports whether one more resource fits in namespace.
+// Reservations for creates still talking to the vendor count as used.
+// Caller must hold c.mu.
+func (c *Controller) checkCapacityLocked(namespace string) *CapacityError {
+	return c.checkPlannedCapacityLocked(namespace, 0, 0)
+}
+
+// checkPlannedCapacityLocked is checkCapacityLocked with planned more
+// resources counted as used, plannedInNamespace of them in namespace (see
+// simulate.go). Caller must hold c.mu.
+func (c *Controller) checkPlannedCapacityLocked(namespace string, planned, plannedInNamespace int) *CapacityError {
+	cfg := c.config()
+	if c.MemoryGuard != nil && c.MemoryGuard.Level() == memguard.Hard {
+		return &CapacityError{
+			StatusCode: http.StatusInsufficientStorage,
+			Message:    "controller is under memory pressure; new resources are refused until usage drops",
+		}
+	}
+
+	if cfg.MaxResources > 0 && len(c.ResourceDB)+c.pendingTotal+planned >= cfg.MaxResources {
+		return &CapacityError{
+			StatusCode: http.StatusInsufficientStorage,
+			Message:    fmt.Sprintf("resource limit reached: the controller manages at most %d resources (MAX_RESOURCES)", cfg.MaxResources),
+		}
+	}
+
+	if cfg.MaxResourcesPerNamespace > 0 {
+		ns := namespaceKey(namespace)
+		count := c.pendingByNamespace[ns] + plannedInNamespace
+		for _, res := range c.ResourceDB {
+			if namespaceKey(res.Namespace) == ns {
+				count++
+			}
+		}
+		if count >= cfg.MaxResourcesPerNamespace {
+			return &CapacityError{
+				StatusCode: http.StatusTooManyRequests,
+				Message:    fmt.Sprintf("namespace %q has reached its limit of %d resources (MAX_RESOURCES_PER_NAMESPACE)", ns, cfg.MaxResourcesPerNamespace),
+			}
+		}
+	}
+	return nil
+}
+
+// reserveCapacity claims room for one resource in namespace before the
+// (slow) vendor call. The caller must later either store the resource and
+// call commitReservationLocked, or call cancelReservation.
+//
+// WHY RESERVE: Checking and inserting happen seconds apart (the vendor
+// call sits between them). Without a reservation, N concurrent creates
+// could all pass the check and overshoot the cap.
+func (c *Controller) reserveCapacity(namespace string) *CapacityError {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.checkCapacityLocked(namespace); err != nil {
+		return err
+	}
+	c.pendingTotal++
+	c.pendingByNamespace[namespaceKey(namespace)]++
+	return nil
+}
+
+// commitReservationLocked releases a reservation whose resource has just
+// been stored in ResourceDB. Caller must hold c.mu.
+func (c *Controller) commitReservationLocked(namespace string) {
+	ns := namespaceKey(namespace)
+	c.pendingTotal--
+	c.pendingByNamespace[ns]--
+	if c.pendingByNamespace[ns] <= 0 {
+		delete(c.pendingByNamespace, ns)
+	}
+}
+
+// cancelReservation releases a reservation without storing a resource.
+func (c *Controller) cancelReservation(namespace string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.commitReservationLocked(namespace)
+}
+
+// ReconcilerPaused reports whether background reconciliation should
+// skip its work because the controller is under memory pressure.
+func (c *Controller) ReconcilerPaused() bool {
+	return c.reconcilerPaused.Load()
+}
+
+// startMemoryGuard wires the guard's level changes to the controller and
+// starts sampling in the background.
+func (c *Controller) startMemoryGuard(ctx context.Context, interval time.Duration) {
+	if c.MemoryGuard == nil {
+		logger.Infof("Memory guard disabled (no MEMORY_LIMIT_MB and no cgroup limit detected)")
+		return
+	}
+	c.MemoryGuard.OnChange(c.onMemoryPressure)
+	status := c.MemoryGuard.Status()
+	logger.Infof("Memory guard enabled: limit %d MB, soft %.0f%%, hard %.0f%%",
+		status.LimitBytes>>20, status.SoftWatermark*100, status.HardWatermark*100)
+	go c.MemoryGuard.Run(ctx, interval)
+}
+
+// onMemoryPressure reacts to memory level changes.
+func (c *Controller) onMemoryPressure(from, to memguard.Level) {
+	switch {
+	case to >= memguard.Soft && from == memguard.Normal:
+		logger.Warnf("Memory pressure %s: pausing reconciler and shedding caches", to)
+		c.reconcilerPaused.Store(true)
+		c.shedCaches()
+	case to == memguard.Hard:
+		logger.Errorf("Memory pressure hard: refusing new resources")
+		c.shedCaches()
+	case to == memguard.Normal:
+		logger.Infof("Memory pressure cleared: resuming reconciler")
+		c.reconcilerPaused.Store(false)
+	default:
+		logger.Warnf("Memory pressure %s (was %s)", to, from)
+	}
+}
+
+// shedCaches drops data the controller can live without: old revisions
+// and events (the newest few per resource are kept) and old audit entries.
+func (c *Controller) shedCaches() {
+	c.mu.Lock()
+	droppedRevisions := 0
+	for id, revisions := range c.History {
+		if len(revisions) > shedKeepRevisions {
+			droppedRevisions += len(revisions) - shedKeepRevisions
+			// WHY COPY: Re-slicing would keep the old backing array alive
+			kept := make([]models.ResourceRevision, shedKeepRevisions)
+			copy(kept, revisions[len(revisions)-shedKeepRevisions:])
+			c.History[id] = kept
+		}
+	}
+	droppedEvents := 0
+	for id, events := range c.Events {
+		if len(events) > shedKeepEvents {
+			droppedEvents += len(events) - shedKeepEvents
+			kept := make([]models.Event, shedKeepEvents)
+			copy(kept, events[len(events)-shedKeepEvents:])
+			c.Events[id] = kept
+		}
+	}
+	c.mu.Unlock()
+
+	droppedAudit := c.Audit.Trim(shedKeepAuditEntries)
+
+	// WHY FreeOSMemory: Returns the freed memory to the OS right away
+	// instead of waiting for the scavenger
+	debug.FreeOSMemory()
+	logger.Warnf("Shed %d history revisions, %d events and %d audit entries", droppedRevisions, droppedEvents, droppedAudit)
+}
+
+// HandleGetLimits handles GET /admin/limits
+// Shows the configured caps, current usage, and memory guard state.
+func (c *Controller) HandleGetLimits(w http.ResponseWriter, r *http.Request) {
+	cfg := c.config()
+	c.mu.RLock()
+	perNamespace := make(map[string]int)
+	for _, res := range c.ResourceDB {
+		perNamespace[namespaceKey(res.Namespace)]++
+	}
+	response := map[string]interface{}{
+		"resources":                   len(c.ResourceDB),
+		"pending_creates":             c.pendingTotal,
+		"resources_by_namespace":      perNamespace,
+		"max_resources":               cfg.MaxResources,
+		"max_resources_per_namespace": cfg.MaxResourcesPerNamespace,
+		"reconciler_paused":           c.ReconcilerPaused(),
+		"spec_limits":                 cfg.SpecLimits,
+	}
+	c.mu.RUnlock()
+
+	if c.MemoryGuard != nil {
+		response["memory"] = c.MemoryGuard.Status()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
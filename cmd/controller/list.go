@@ -0,0 +1,348 @@
+package main
+
+import (
+	"container/heap"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// STABLE LIST OUTPUT
+// =============================================================================
+// Go randomizes map iteration, so anything built by ranging over a map
+// comes out in a different order on every call. Diff-based tooling
+// (GitOps drift checks, "what changed since yesterday" scripts) then sees
+// changes that aren't there. Every list response is sorted:
+//
+//   GET /resources   created_at, then ID (see ?sort= or ?sortBy=, and ?order=)
+//   revisions/events oldest first (recording order)
+//   adoptions        discovered_at, then ID
+//   rollouts         newest first, then ID
+//   recordings       started_at, then session ID
+//
+// JSON field order is stable too: struct fields are encoded in declaration
+// order and encoding/json writes map keys (spec.config) sorted, so the
+// same resource always serializes to the same bytes.
+// =============================================================================
+
+// resourceSortKeys are the ?sort= values GET /resources supports. Each
+// compares on its key only; ties fall back to the resource ID.
+var resourceSortKeys = map[string]func(a, b *models.ForgeResource) int{
+	"created_at": func(a, b *models.ForgeResource) int { return a.CreatedAt.Compare(b.CreatedAt) },
+	"updated_at": func(a, b *models.ForgeResource) int { return a.UpdatedAt.Compare(b.UpdatedAt) },
+	"name":       func(a, b *models.ForgeResource) int { return strings.Compare(a.Name, b.Name) },
+	"namespace":  func(a, b *models.ForgeResource) int { return strings.Compare(a.Namespace, b.Namespace) },
+	"phase":      func(a, b *models.ForgeResource) int { return strings.Compare(a.Status.Phase, b.Status.Phase) },
+}
+
+// resourceLess reports whether a sorts before b: by key, then ID. desc
+// reverses the whole order (ties included), so asc and desc are exact
+// mirrors.
+func resourceLess(a, b *models.ForgeResource, key string, desc bool) bool {
+	if desc {
+		a, b = b, a
+	}
+	if n := resourceSortKeys[key](a, b); n != 0 {
+		return n < 0
+	}
+	return a.ID < b.ID
+}
+
+// =============================================================================
+// PAGINATION
+// =============================================================================
+// Dashboards page through GET /resources with ?limit= and either ?offset=
+// or ?cursor=. The response says how many resources matched and, if more
+// remain, the cursor of the next page:
+//
+//   {"items": [...], "total": 1250, "next_cursor": "eyJzIjoi..."}
+//
+// WHY CURSORS: An offset shifts when resources are created or deleted
+// between pages, so a dashboard skips or repeats some. The cursor holds
+// the sort position of the last item returned (its sort field and ID),
+// so the next page starts right after it whatever changed in between.
+// Without ?limit= everything is returned, as before.
+//
+// The cursor is opaque to clients: it is only valid for the sort, order
+// and filters it was issued for (a filter changed mid-iteration would
+// silently skip resources), and is refused with 400 otherwise. The next
+// page's URL is in the Link header too (rel="next", RFC 8288), so generic
+// HTTP clients can follow it without knowing the parameters.
+// =============================================================================
+
+// maxListLimit caps ?limit=.
+const maxListLimit = 1000
+
+// listCursor is the decoded form of a next_cursor: where the last page
+// ended, and the sort it was issued for.
+type listCursor struct {
+	Sort      string    `json:"s"`
+	Order     string    `json:"o"`
+	Filter    string    `json:"f,omitempty"`
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"c,omitempty"`
+	UpdatedAt time.Time `json:"u,omitempty"`
+	Name      string    `json:"n,omitempty"`
+	Namespace string    `json:"ns,omitempty"`
+	Phase     string    `json:"p,omitempty"`
+}
+
+// filterDigest identifies a list's filters in its cursors.
+func filterDigest(filter watchFilter) string {
+	sum := sha256.Sum256([]byte(filter.key()))
+	return base64.RawURLEncoding.EncodeToString(sum[:8])
+}
+
+// encodeListCursor returns the cursor of the page after last.
+func encodeListCursor(key, order string, filter watchFilter, last *models.ForgeResource) string {
+	data, _ := json.Marshal(listCursor{
+		Sort: key, Order: order, Filter: filterDigest(filter), ID: last.ID,
+		CreatedAt: last.CreatedAt, UpdatedAt: last.UpdatedAt,
+		Name: last.Name, Namespace: last.Namespace, Phase: last.Status.Phase,
+	})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+var errInvalidCursor = errors.New("cursor is invalid; start again without it")
+
+// decodeListCursor parses a cursor issued for the given sort, order and
+// filter.
+func decodeListCursor(value, key, order string, filter watchFilter) (*models.ForgeResource, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	var cursor listCursor
+	if err == nil {
+		err = json.Unmarshal(data, &cursor)
+	}
+	if err != nil || cursor.ID == "" {
+		return nil, errInvalidCursor
+	}
+	if cursor.Sort != key || cursor.Order != order {
+		return nil, errors.New("cursor was issued for sort=" + cursor.Sort + "&order=" + cursor.Order + "; keep the same sort and order while paging")
+	}
+	if cursor.Filter != filterDigest(filter) {
+		return nil, errors.New("cursor was issued for different filters; keep the same filters while paging")
+	}
+	// WHY A RESOURCE: The boundary compares with the same function as
+	// the items, so paging can never disagree with sorting
+	boundary := &models.ForgeResource{
+		ID: cursor.ID, CreatedAt: cursor.CreatedAt, UpdatedAt: cursor.UpdatedAt,
+		Name: cursor.Name, Namespace: cursor.Namespace,
+	}
+	boundary.Status.Phase = cursor.Phase
+	return boundary, nil
+}
+
+// parsePageParams reads ?limit= and ?offset=. limit 0 means no limit.
+func parsePageParams(query map[string][]string) (limit, offset int, err error) {
+	get := func(name string) string {
+		if v := query[name]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	if v := get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxListLimit {
+			return 0, 0, errors.New("limit must be between 1 and " + strconv.Itoa(maxListLimit))
+		}
+	}
+	if v := get("offset"); v != "" {
+		offset, err = strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return 0, 0, errors.New("offset must be a non-negative integer")
+		}
+	}
+	return limit, offset, nil
+}
+
+// listResources returns copies of one page of the stored resources
+// matching filter, in the given sort order, starting after boundary (if
+// set) or skipping offset, with at most limit items (0 = all). total
+// counts every match; more reports whether any follow the page.
+//
+// WHY IN THE STORE: Matches are sorted as pointers under the read lock and
+// only the page is copied, so a page of 50 out of 10,000 cameras copies 50
+// resources, not 10,000. With a limit, only the first offset+limit matches
+// are kept in order (a bounded heap) instead of sorting them all.
+func (c *Controller) listResources(filter watchFilter, key string, desc bool, boundary *models.ForgeResource, offset, limit int) (page []*models.ForgeResource, total int, more bool) {
+	less := func(a, b *models.ForgeResource) bool { return resourceLess(a, b, key, desc) }
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	matches := make([]*models.ForgeResource, 0)
+	after := 0
+	for _, res := range c.ResourceDB {
+		if !filter.matches(res) {
+			continue
+		}
+		total++
+		// WHY COUNT, NOT KEEP: Everything up to the cursor was on earlier
+		// pages
+		if boundary != nil && !less(boundary, res) {
+			continue
+		}
+		after++
+		matches = append(matches, res)
+	}
+
+	// keep is how many sorted matches the page needs; one more tells
+	// whether another page follows
+	keep := len(matches)
+	if limit > 0 && offset+limit+1 < keep {
+		keep = offset + limit + 1
+	}
+	matches = smallest(matches, keep, less)
+
+	start := min(offset, len(matches))
+	end := len(matches)
+	if limit > 0 && start+limit < end {
+		end = start + limit
+	}
+	page = make([]*models.ForgeResource, 0, end-start)
+	for _, res := range matches[start:end] {
+		page = append(page, res.DeepCopy())
+	}
+	return page, total, end < after
+}
+
+// smallest returns the k smallest items by less, sorted. Sorting them all
+// is O(n log n); a bounded heap is O(n log k), which matters for a small
+// page of a large inventory.
+func smallest(items []*models.ForgeResource, k int, less func(a, b *models.ForgeResource) bool) []*models.ForgeResource {
+	if k >= len(items) {
+		sort.Slice(items, func(i, j int) bool { return less(items[i], items[j]) })
+		return items
+	}
+	// WHY A MAX-HEAP: Its root is the largest of the k kept so far, the
+	// one a smaller item replaces
+	h := &resourceHeap{items: make([]*models.ForgeResource, 0, k), less: less}
+	for _, res := range items {
+		if h.Len() < k {
+			heap.Push(h, res)
+		} else if k > 0 && less(res, h.items[0]) {
+			h.items[0] = res
+			heap.Fix(h, 0)
+		}
+	}
+	sort.Slice(h.items, func(i, j int) bool { return less(h.items[i], h.items[j]) })
+	return h.items
+}
+
+// resourceHeap is a max-heap of resources for smallest.
+type resourceHeap struct {
+	items []*models.ForgeResource
+	less  func(a, b *models.ForgeResource) bool
+}
+
+func (h *resourceHeap) Len() int           { return len(h.items) }
+func (h *resourceHeap) Less(i, j int) bool { return h.less(h.items[j], h.items[i]) }
+func (h *resourceHeap) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *resourceHeap) Push(x any)         { h.items = append(h.items, x.(*models.ForgeResource)) }
+func (h *resourceHeap) Pop() any {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
+
+// HandleListResources handles GET /resources
+// Query parameters (all optional): sort or sortBy (created_at, updated_at,
+// name, namespace, phase, or camelCase as createdAt; default created_at), order (asc or desc; default asc),
+// filters as for GET /resources/watch (namespace, where "default" includes
+// unset namespaces; vendor_type, phase and type, comma-separated lists;
+// name_prefix; labelSelector), show_deleted (true lists resources whose
+// deletion is scheduled, see softdelete.go), limit, and offset or cursor
+// (see PAGINATION). Filters combine with AND. fields returns only the named fields of each item
+// (see fields.go).
+//
+// WHY NO VENDOR READS: A list is answered from the store; GET
+// /resources/{id} refreshes a single resource's status from the vendor.
+func (c *Controller) HandleListResources(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	// WHY BOTH: ?sortBy=createdAt is the spelling most REST clients use;
+	// it means the same as ?sort=created_at
+	key := query.Get("sort")
+	if sortBy := snakeCase(query.Get("sortBy")); sortBy != "" {
+		if key != "" && key != sortBy {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "use either sort or sortBy, not both"})
+			return
+		}
+		key = sortBy
+	}
+	if key == "" {
+		key = "created_at"
+	}
+	if _, ok := resourceSortKeys[key]; !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "sort (or sortBy) must be one of: " + strings.Join(sortedKeys(resourceSortKeys), ", ")})
+		return
+	}
+	order := query.Get("order")
+	if order != "" && order != "asc" && order != "desc" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "order must be asc or desc"})
+		return
+	}
+	if order == "" {
+		order = "asc"
+	}
+	filter, err := parseWatchFilter(r)
+	filter.hideScheduled = query.Get("show_deleted") != "true"
+	var limit, offset int
+	if err == nil {
+		limit, offset, err = parsePageParams(query)
+	}
+	var fields fieldSet
+	if err == nil {
+		fields, err = parseFieldSet(query)
+	}
+	var boundary *models.ForgeResource
+	if err == nil && query.Get("cursor") != "" {
+		if query.Has("offset") {
+			err = errors.New("use either offset or cursor, not both")
+		} else {
+			boundary, err = decodeListCursor(query.Get("cursor"), key, order, filter)
+		}
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	page, total, more := c.listResources(filter, key, order == "desc", boundary, offset, limit)
+	response := map[string]interface{}{"items": page, "total": total}
+	if more {
+		cursor := encodeListCursor(key, order, filter, page[len(page)-1])
+		response["next_cursor"] = cursor
+		next := r.URL.Query()
+		next.Del("offset")
+		next.Set("cursor", cursor)
+		w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", c.externalURL(r, strings.TrimPrefix(r.URL.Path, c.BasePath)+"?"+next.Encode())))
+	}
+	redact := c.redactionFor(r)
+	redact.announce(w)
+	for _, item := range page {
+		redact.resource(item)
+	}
+	if fields != nil {
+		projected := make([]map[string]interface{}, 0, len(page))
+		for _, item := range page {
+			projected = append(projected, fields.project(item))
+		}
+		response["items"] = projected
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/clock"
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// RESOURCE LOCKS
+// =============================================================================
+// During manual maintenance an operator must be sure nobody else changes
+// the device under their hands: no rollout, no PUT from a script, no
+// delete from a teardown job. They take a lease on the resource:
+//
+//   POST /resources/{id}:lock     {"duration": "45m", "reason": "re-cabling SDI 2"}
+//   POST /resources/{id}:unlock
+//   GET  /locks
+//
+// While the lock is held, updates (PUT, PATCH, stop/start) and deletes
+// (single, batch and by filter) from anyone but the owner are refused
+// with 423 Locked, naming the owner and the expiry. Background work runs
+// as nobody, so rollouts, deferred maintenance mutations and idle
+// auto-stop are refused too, and the reconciler leaves the resource alone.
+//
+// Locks are time-boxed (default 30m, at most LOCK_MAX_DURATION, default
+// 8h) so a forgotten lock can't block a device forever. Locking again
+// renews the lease. An admin can break someone else's lock. Taking,
+// releasing and expiring a lock are recorded as events.
+//
+// WHY OPERATOR ROLE: A lock is only as good as knowing who holds it, so
+// it needs an authenticated principal (FORGE_API_KEYS).
+// =============================================================================
+
+// defaultLockDuration applies when the lock request has no duration.
+const defaultLockDuration = 30 * time.Minute
+
+// resourceLock is a held lock and the timer that expires it.
+type resourceLock struct {
+	models.ResourceLock
+	timer clock.Timer
+}
+
+// resourceLockedError is returned for a change to a resource locked by
+// someone else.
+type resourceLockedError struct {
+	lock models.ResourceLock
+}
+
+func (e *resourceLockedError) Error() string {
+	msg := fmt.Sprintf("resource is locked by %s until %s", e.lock.Owner, e.lock.ExpiresAt.UTC().Format(time.RFC3339))
+	if e.lock.Reason != "" {
+		msg += " (" + e.lock.Reason + ")"
+	}
+	return msg
+}
+
+// writeLockedError writes a 423 naming the lock.
+func writeLockedError(w http.ResponseWriter, e *resourceLockedError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusLocked)
+	json.NewEncoder(w).Encode(map[string]interface{}{"error": e.Error(), "lock": e.lock})
+}
+
+// lockConflictLocked returns a *resourceLockedError if id is locked by
+// someone other than the principal in ctx. Caller must hold c.mu.
+func (c *Controller) lockConflictLocked(ctx context.Context, id string) error {
+	lock, held := c.locks[id]
+	if !held || !c.Clock.Now().Before(lock.ExpiresAt) {
+		return nil
+	}
+	if principal, ok := principalFrom(ctx); ok && principal.Name == lock.Owner {
+		return nil
+	}
+	return &resourceLockedError{lock: lock.ResourceLock}
+}
+
+// checkLock is lockConflictLocked for callers not holding c.mu.
+func (c *Controller) checkLock(ctx context.Context, id string) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.lockConflictLocked(ctx, id)
+}
+
+// rejectIfLocked writes 423 and returns true if r may not change id.
+func (c *Controller) rejectIfLocked(w http.ResponseWriter, r *http.Request, id string) bool {
+	var locked *resourceLockedError
+	if err := c.checkLock(r.Context(), id); errors.As(err, &locked) {
+		writeLockedError(w, locked)
+		return true
+	}
+	return false
+}
+
+// LockRequest is the (optional) body of POST /resources/{id}:lock.
+type LockRequest struct {
+	// Duration is a Go duration like "45m" (default 30m)
+	Duration string `json:"duration,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// HandleLockResource handles POST /resources/{id}:lock
+func (c *Controller) HandleLockResource(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	principal, _ := principalFrom(r.Context())
+
+	// Step 1: Parse the lease
+	var req LockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+	duration := defaultLockDuration
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "duration must be a positive Go duration like \"45m\""})
+			return
+		}
+		duration = d
+	}
+	if duration > c.MaxLockDuration {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "duration may not exceed " + c.MaxLockDuration.String()})
+		return
+	}
+
+	// Step 2: Take or renew the lock
+	c.mu.Lock()
+	res, exists := c.ResourceDB[id]
+	if !exists {
+		c.mu.Unlock()
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Resource not found"})
+		return
+	}
+	var locked *resourceLockedError
+	if errors.As(c.lockConflictLocked(r.Context(), id), &locked) {
+		c.mu.Unlock()
+		writeLockedError(w, locked)
+		return
+	}
+	now := c.Clock.Now()
+	lock, held := c.locks[id]
+	renewed := held && now.Before(lock.ExpiresAt)
+	if held {
+		lock.timer.Stop()
+	}
+	if !renewed {
+		lock = &resourceLock{ResourceLock: models.ResourceLock{ResourceID: id, Owner: principal.Name, AcquiredAt: now}}
+		c.locks[id] = lock
+	}
+	if req.Reason != "" || !renewed {
+		lock.Reason = req.Reason
+	}
+	lock.ExpiresAt = now.Add(duration)
+	expiresAt := lock.ExpiresAt
+	lock.timer = c.Clock.AfterFunc(duration, func() { c.expireLock(id, expiresAt) })
+	message := fmt.Sprintf("Locked by %s for %s", principal.Name, duration)
+	if renewed {
+		message = fmt.Sprintf("Lock renewed by %s for %s", principal.Name, duration)
+	}
+	if lock.Reason != "" {
+		message += ": " + lock.Reason
+	}
+	c.recordEvent(res, models.EventNormal, models.ReasonLocked, message, "", "")
+	snapshot := lock.ResourceLock
+	c.mu.Unlock()
+	logger.Infof("%s: %s", id, message)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snapshot)
+}
+
+// HandleUnlockResource handles POST /resources/{id}:unlock
+// Only the owner, or an admin breaking the lock, may release it.
+func (c *Controller) HandleUnlockResource(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	principal, _ := principalFrom(r.Context())
+
+	c.mu.Lock()
+	res, exists := c.ResourceDB[id]
+	lock, held := c.locks[id]
+	if !exists || !held || !c.Clock.Now().Before(lock.ExpiresAt) {
+		c.mu.Unlock()
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "resource is not locked"})
+		return
+	}
+	if lock.Owner != principal.Name && !principal.HasRole(RoleAdmin) {
+		c.mu.Unlock()
+		writeLockedError(w, &resourceLockedError{lock: lock.ResourceLock})
+		return
+	}
+	lock.timer.Stop()
+	delete(c.locks, id)
+	message := "Unlocked by " + principal.Name
+	if lock.Owner != principal.Name {
+		message += " (lock held by " + lock.Owner + " was broken)"
+	}
+	c.recordEvent(res, models.EventNormal, models.ReasonUnlocked, message, "", "")
+	c.mu.Unlock()
+	logger.Infof("%s: %s", id, message)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// expireLock drops the lock on id if it still expires at expiresAt (it
+// wasn't renewed or released in the meantime).
+func (c *Controller) expireLock(id string, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lock, held := c.locks[id]
+	if !held || !lock.ExpiresAt.Equal(expiresAt) {
+		return
+	}
+	delete(c.locks, id)
+	if res, exists := c.ResourceDB[id]; exists {
+		c.recordEvent(res, models.EventNormal, models.ReasonLockExpired,
+			fmt.Sprintf("Lock held by %s expired", lock.Owner), "", "")
+		logger.Infof("%s: lock held by %s expired", id, lock.Owner)
+	}
+}
+
+// HandleListLocks handles GET /locks
+func (c *Controller) HandleListLocks(w http.ResponseWriter, r *http.Request) {
+	c.mu.RLock()
+	now := c.Clock.Now()
+	items := []models.ResourceLock{}
+	for id, lock := range c.locks {
+		// WHY CHECK THE RESOURCE: A deleted resource's lock lingers until
+		// it expires
+		if _, exists := c.ResourceDB[id]; exists && now.Before(lock.ExpiresAt) {
+			items = append(items, lock.ResourceLock)
+		}
+	}
+	c.mu.RUnlock()
+	sort.Slice(items, func(i, j int) bool { return items[i].ResourceID < items[j].ResourceID })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+}
@@ -21,42 +21,446 @@ package main
 import (
 	"context"       
 	"encoding/json" 
+	"errors"
 	"fmt"           
+	"io"
 	"log"           
 	"net/http"     
 	"os"            
+	"os/signal"
+	"strconv"
+	"strings"
 	"sync"          
+	"sync/atomic"
+	"syscall"
 	"time"          
+	"github.com/Zhichengu1/mock-control-plane/pkg/audit"    // Audit trail of vendor calls
+	"github.com/Zhichengu1/mock-control-plane/pkg/client"   // Vendor HTTP client (retry backoff clock)
+	"github.com/Zhichengu1/mock-control-plane/pkg/clock"    // Injectable time and ID sources
+	"github.com/Zhichengu1/mock-control-plane/pkg/eventstore" // Append-only event log (STORE_BACKEND=eventlog)
+	"github.com/Zhichengu1/mock-control-plane/pkg/dns"      // DNS registration backends
+	"github.com/Zhichengu1/mock-control-plane/pkg/fairqueue" // Weighted fair work queue for the reconciler
+	"github.com/Zhichengu1/mock-control-plane/pkg/health"   // Metric thresholds rolled up into health status
+	"github.com/Zhichengu1/mock-control-plane/pkg/logging"  // Leveled logging with runtime overrides
+	"github.com/Zhichengu1/mock-control-plane/pkg/memguard" // Memory watermarks
 	"github.com/Zhichengu1/mock-control-plane/pkg/models"   // Our data structures
+	"github.com/Zhichengu1/mock-control-plane/pkg/notify"   // Slack/PagerDuty/email/webhook notifications
 	"github.com/Zhichengu1/mock-control-plane/pkg/provider" // Vendor translators
+	"github.com/Zhichengu1/mock-control-plane/pkg/ratelimit" // Back-pressure: client quotas and vendor concurrency
+	"github.com/Zhichengu1/mock-control-plane/pkg/singleflight" // Coalescing of identical vendor calls
+	"github.com/Zhichengu1/mock-control-plane/pkg/validation" // Cross-field spec rules
+	"github.com/Zhichengu1/mock-control-plane/pkg/version"  // Build info for GET /version
+	"github.com/Zhichengu1/mock-control-plane/pkg/webhook"  // Signed vendor callbacks
 	"github.com/gorilla/mux"                                // Router - better than default, supports URL params like /resources/{id}
 )
 
+// logger is the controller's component logger (see PUT /admin/loglevel)
+var logger = logging.For(logging.ComponentController)
+
 type Controller struct {
-	Providers  map[string]provider.VendorProvider // "sony" → SonyProvider, "aws" → AWSProvider
-	ResourceDB map[string]*models.ForgeResource   // "res-123" → resource data
-	mu         sync.RWMutex                       // Protects ResourceDB from concurrent access
+	ResourceDB map[string]*models.ForgeResource // "res-123" → resource data
+	mu         sync.RWMutex                     // Protects ResourceDB (and Adoptions) from concurrent access
+
+	// settings are the providers, keys, quotas and policies a config
+	// reload can change; read them with c.config() (see reload.go)
+	settings atomic.Pointer[runtimeConfig]
+
+	// History holds revision snapshots per resource ID (see history.go)
+	// WHY SEPARATE MAP: History must survive deletion of the resource
+	History map[string][]models.ResourceRevision
+
+	// MaxRevisions caps snapshots kept per resource (0 = unlimited)
+	MaxRevisions int
+
+	// watch publishes every revision to GET /resources/watch streams;
+	// WatchBookmarkInterval is how often idle streams get a bookmark
+	// (see watch.go)
+	watch                 *watchHub
+	WatchBookmarkInterval time.Duration
+
+	// search indexes names, vendor IDs, addresses and config values for
+	// GET /resources/search; kept up to date by recordRevision (see
+	// search.go)
+	search *searchIndex
+
+	// Adoptions holds discovered, unmanaged vendor devices awaiting approval
+	// "adopt-sony-sony-dev-1" → proposal (see discovery.go)
+	Adoptions map[string]*models.AdoptionProposal
+
+	// InventoryPageSize is the page size for paged inventory syncs;
+	// inventory holds their cursors (see inventory.go)
+	InventoryPageSize int
+	inventory         *inventoryState
+
+	// configSnapshots holds device configuration snapshots per resource ID
+	// (protected by mu); ConfigBackup configures them (see configbackup.go)
+	configSnapshots map[string][]models.ConfigSnapshot
+	ConfigBackup    ConfigBackupPolicy
+
+	// Rollouts holds staged spec changes by rollout ID (see rollout.go)
+	// Guarded by mu
+	Rollouts map[string]*Rollout
+
+	// IdlePolicy configures idle detection; idle tracks resources seen
+	// idle by ID, guarded by mu (see idle.go)
+	IdlePolicy IdlePolicy
+	idle       map[string]*idleState
+
+	// Compaction is the retention policy for revisions, events and the
+	// audit trail; compaction tracks what each run reclaimed (see
+	// compaction.go)
+	Compaction CompactionPolicy
+	compaction *compactionState
+
+	// Tasks tracks long-running operations by task ID, guarded by mu;
+	// Deletion configures async deletes (see tasks.go, deletion.go)
+	Tasks    map[string]*models.Task
+	Deletion DeletePolicy
+
+	// Reconcile configures the status reconciler; reconcileQueue is its
+	// fair work queue (see reconciler.go)
+	Reconcile      ReconcilePolicy
+	reconcileQueue *fairqueue.Queue
+
+	// LogOverrideTTL is how long a runtime log level change lasts when the
+	// request doesn't specify a duration
+	LogOverrideTTL time.Duration
+
+	// LogTail keeps recent log lines and DiagnosticsSigningKey signs
+	// diagnostic bundles (see diagnostics.go)
+	LogTail               *logging.Tail
+	DiagnosticsSigningKey []byte
+
+	// maintenance holds vendor maintenance windows and the mutations they
+	// deferred (see maintenance.go)
+	maintenance *maintenanceState
+
+	// queued holds writes refused by an open circuit until they are
+	// replayed (see queued.go)
+	queued *queuedState
+
+	// idempotency remembers creates made with an Idempotency-Key (see
+	// idempotency.go)
+	idempotency *idempotencyState
+
+	// latency holds route budgets and the timings of routes and
+	// background loops (see latency.go)
+	latency *latencyState
+
+	// unique indexes the constrained spec values per namespace (protected
+	// by mu; see uniqueness.go)
+	unique *uniqueIndex
+
+	// updating counts spec updates at the vendor per resource ID
+	// (protected by mu; see preconditions.go)
+	updating map[string]int
+
+	// scheduledDeletions holds deletions waiting out DELETE_GRACE_PERIOD by
+	// resource ID (protected by mu; see softdelete.go)
+	scheduledDeletions map[string]*scheduledDeletion
+
+	// finalizing holds deletions waiting for the resource's finalizers by
+	// resource ID (protected by mu; see finalizers.go)
+	finalizing map[string]*scheduledDeletion
+
+	// creating holds the IDs of creates not stored yet, whose unique
+	// claims are in flight (protected by mu; see storecheck.go)
+	creating map[string]bool
+
+	// VendorSlots caps concurrent calls to each vendor API
+	// "sony" → semaphore with VENDOR_MAX_CONCURRENCY slots
+	VendorSlots map[string]*ratelimit.Semaphore
+
+	// Audit records outbound vendor calls, linked to API request IDs (see audit.go)
+	Audit *audit.Log
+
+	// pendingTotal / pendingByNamespace count creates that passed the
+	// capacity check but are still waiting on the vendor (protected by mu)
+	pendingTotal       int
+	pendingByNamespace map[string]int
+
+	// MemoryGuard reports memory pressure (nil = disabled)
+	MemoryGuard *memguard.Guard
+
+	// reconcilerPaused is set while memory is above the soft watermark
+	reconcilerPaused atomic.Bool
+
+	// Events holds recent events per resource ID (see events.go)
+	Events map[string][]models.Event
+
+	// MaxEventsPerResource caps events kept per resource (0 = unlimited)
+	MaxEventsPerResource int
+
+	// Validators check specs by resource type before any vendor call
+	// (see pkg/validation/validator.go)
+	Validators *validation.Registry
+
+	// ConfigKeys are the spec.config keys each vendor understands (see
+	// configkeys.go)
+	ConfigKeys *validation.ConfigKeyRegistry
+
+	// DestinationProbeFrom ("controller" or "" for the vendor if it can)
+	// and DestinationProbeTimeout configure spec.probe_destination
+	// (see destination.go)
+	DestinationProbeFrom    string
+	DestinationProbeTimeout time.Duration
+
+	// Notifier routes events to notification channels (nil = disabled)
+	Notifier *notify.Router
+
+	// DNS registers devices' addresses in DNS (nil = disabled; see dns.go)
+	DNS *dnsRegistrar
+
+	// Preview mints stream preview URLs (nil = disabled; see preview.go)
+	Preview *previewGateway
+
+	// callbacks verifies signed vendor callbacks (see callbacks.go)
+	callbacks *webhook.Verifier
+
+	// locks holds resource locks by resource ID (protected by mu; see locks.go)
+	// MaxLockDuration caps how long one lease lasts
+	locks           map[string]*resourceLock
+	MaxLockDuration time.Duration
+
+	// schedules holds the scheduled jobs and their history (see schedules.go)
+	schedules *scheduleState
+
+	// shares holds share links by link ID (protected by mu; see sharelinks.go)
+	// ShareSigningKey signs their tokens; MaxShareTTL caps their lifetime
+	shares          map[string]*models.ShareLink
+	ShareSigningKey []byte
+	MaxShareTTL     time.Duration
+
+	// Clock is the controller's time source (see testmode.go)
+	// WHY INJECTED: FORGE_TEST_MODE swaps in a fake clock so tests can
+	// move time forward instead of sleeping
+	Clock clock.Clock
+
+	// IDs generates resource and event IDs; RequestIDs generates request IDs
+	IDs        clock.IDGenerator
+	RequestIDs clock.IDGenerator
+
+	// BasePath is the URL prefix all routes are served under ("" = root)
+	// TrustedProxies may set X-Forwarded-* headers (see proxy.go)
+	BasePath       string
+	TrustedProxies *trustedProxies
+
+	// StoreBackend is "memory" or "eventlog"; eventLog is the log for the
+	// latter, and storeFailed is set once it fails (the store is then
+	// read-only; see store.go)
+	StoreBackend string
+	eventLog     *eventstore.Log
+	storeFailed  atomic.Pointer[storeFailure]
+
+	// txEvents collects the events of the transaction being committed
+	// (nil outside one; protected by mu; see tx.go)
+	txEvents []eventstore.Event
+
+	// vendorReads, healthChecks and metricReads merge identical vendor
+	// calls in flight
+	// (see coalescing.go)
+	vendorReads  *singleflight.Group[*models.ResourceStatus]
+	healthChecks *singleflight.Group[struct{}]
+	metricReads  *singleflight.Group[*models.ResourceMetrics]
+
+	// UnversionedSunset is when the unversioned route aliases stop being
+	// served (announced in the Sunset header; see versioning.go)
+	UnversionedSunset time.Time
+
+	// massDelete counts what each caller deleted or stopped recently
+	// (see massdelete.go)
+	massDelete *massDeleteState
+
+	// reload tracks the settings a config reload compares against (see
+	// reload.go)
+	reload *reloadState
+}
+
+// newProvider creates the provider registered as name from its settings
+// in the environment, or nil for an unknown vendor.
+// WHY A FUNCTION: A config reload builds providers again (see reload.go)
+func newProvider(name string, clk clock.Clock) provider.VendorProvider {
+	switch name {
+	case "sony":
+		sonyBaseURL := os.Getenv("SONY_API_URL")
+		sonyAPIKey := os.Getenv("SONY_API_KEY")
+
+		if sonyBaseURL == "" {
+			sonyBaseURL = "http://localhost:9000" // Our mock Sony server
+		}
+		if sonyAPIKey == "" {
+			sonyAPIKey = "test-api-key" // Fake key for testing
+		}
+		sonyProvider := provider.NewSonyProvider(sonyBaseURL, sonyAPIKey)
+		sonyProvider.Name = name
+		sonyProvider.Clock = clk
+		return sonyProvider
+	case "mock":
+		mockProvider := provider.NewMockProvider(mockOptions())
+		mockProvider.Clock = clk
+		return mockProvider
+	}
+	return nil
+}
+
+// mockOptions reads the mock vendor's MOCK_PROVIDER_* settings.
+func mockOptions() provider.MockOptions {
+	return provider.MockOptions{
+		Latency:       envDuration("MOCK_PROVIDER_LATENCY", 50*time.Millisecond),
+		Jitter:        envDuration("MOCK_PROVIDER_JITTER", 50*time.Millisecond),
+		FailureRate:   envFloat("MOCK_PROVIDER_FAILURE_RATE", 0),
+		ProvisionTime: envDuration("MOCK_PROVIDER_PROVISION_TIME", 0),
+		TeardownTime:  envDuration("MOCK_PROVIDER_TEARDOWN_TIME", 0),
+		Seed:          int64(envInt("MOCK_PROVIDER_SEED", 0)),
+	}
 }
 
 func NewController() *Controller {
-	sonyBaseURL := os.Getenv("SONY_API_URL")
-	sonyAPIKey := os.Getenv("SONY_API_KEY")
+	// WHY DEFAULT 15 MINUTES: Long enough to reproduce an issue,
+	// short enough that a forgotten debug override doesn't flood the logs
+	logOverrideTTL := envDuration("LOG_LEVEL_OVERRIDE_TTL", 15*time.Minute)
+
+	// Back-pressure configuration
+	// WHY 600/min DEFAULT: ~10 req/s per client is plenty for dashboards and
+	// scripts, while stopping a runaway loop from starving everyone else
+	// Set RATE_LIMIT_REQUESTS=0 to disable client rate limiting
+	var rateLimiter *ratelimit.Limiter
+	rateLimitRequests := envInt("RATE_LIMIT_REQUESTS", 600)
+	rateLimitWindow := envDuration("RATE_LIMIT_WINDOW", time.Minute)
+	if rateLimitRequests > 0 {
+		rateLimiter = ratelimit.NewLimiter(rateLimitRequests, rateLimitWindow)
+	}
+	// WHY 10: Conservative default; vendor APIs often throttle above this
+	vendorConcurrency := envInt("VENDOR_MAX_CONCURRENCY", 10)
 
-	if sonyBaseURL == "" {
-		sonyBaseURL = "http://localhost:9000" // Our mock Sony server
+	// Memory guardrails
+	// WHY CGROUP FALLBACK: In Kubernetes the container limit is what the OOM
+	// killer enforces, so that's the number to stay under
+	var memoryGuard *memguard.Guard
+	memoryLimit := uint64(envInt("MEMORY_LIMIT_MB", 0)) << 20
+	if memoryLimit == 0 {
+		memoryLimit = memguard.DetectLimit()
 	}
-	if sonyAPIKey == "" {
-		sonyAPIKey = "test-api-key" // Fake key for testing
+	if memoryLimit > 0 {
+		memoryGuard = memguard.New(memoryLimit,
+			envFloat("MEMORY_SOFT_WATERMARK", 0.80),
+			envFloat("MEMORY_HARD_WATERMARK", 0.90))
 	}
 
-	return &Controller{
-		Providers: map[string]provider.VendorProvider{
-			"sony": provider.NewSonyProvider(sonyBaseURL, sonyAPIKey),
-		},
+	basePath, proxies := loadProxyConfig()
+
+	// Time and ID sources: real by default, deterministic in test mode
+	clk, ids, requestIDs := newTimeSources()
+	if rateLimiter != nil {
+		rateLimiter.SetClock(clk)
+	}
+	// Vendors to register: PROVIDERS="sony,mock" (default "sony")
+	// WHY "mock": An in-process vendor for demos and load tests without
+	// running cmd/vendor-api (see pkg/provider/mock_provider.go)
+	providerNames := os.Getenv("PROVIDERS")
+	if providerNames == "" {
+		providerNames = "sony"
+	}
+	providers := make(map[string]provider.VendorProvider)
+	for _, name := range strings.Split(providerNames, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if p := newProvider(name, clk); p != nil {
+			providers[name] = p
+		} else {
+			logger.Warnf("Ignoring unknown provider %q in PROVIDERS (known: sony, mock)", name)
+		}
+	}
+	vendorNames := make([]string, 0, len(providers))
+	for name := range providers {
+		vendorNames = append(vendorNames, name)
+	}
+
+	reconcilePolicy := loadReconcilePolicy()
+
+	c := &Controller{
+		VendorSlots: newVendorSlots(vendorNames, vendorConcurrency),
 		// Initialize empty database
 		// WHY make(): In Go, maps must be initialized before use
-		ResourceDB: make(map[string]*models.ForgeResource),
+		ResourceDB:     make(map[string]*models.ForgeResource),
+		Adoptions:      make(map[string]*models.AdoptionProposal),
+		InventoryPageSize: envInt("INVENTORY_PAGE_SIZE", 100),
+		inventory:         newInventoryState(os.Getenv("INVENTORY_STATE_FILE")),
+		Rollouts:       make(map[string]*Rollout),
+		Tasks:          make(map[string]*models.Task),
+		Deletion:       loadDeletePolicy(),
+		IdlePolicy:     loadIdlePolicy(),
+		Compaction:     loadCompactionPolicy(),
+		compaction:     newCompactionState(),
+		Reconcile:      reconcilePolicy,
+		reconcileQueue: newReconcileQueue(reconcilePolicy),
+		idle:           make(map[string]*idleState),
+		History:        make(map[string][]models.ResourceRevision),
+		configSnapshots: make(map[string][]models.ConfigSnapshot),
+		ConfigBackup:    loadConfigBackupPolicy(),
+		MaxRevisions:   envInt("HISTORY_MAX_REVISIONS", 100),
+		// WHY 1000: A few minutes of changes on a busy fleet, enough to
+		// ride out a reconnect without relisting
+		watch:                 newWatchHub(envInt("WATCH_BUFFER", 1000)),
+		search:                newSearchIndex(),
+		WatchBookmarkInterval: envDuration("WATCH_BOOKMARK_INTERVAL", 15*time.Second),
+		LogOverrideTTL: logOverrideTTL,
+		DiagnosticsSigningKey: []byte(os.Getenv("DIAGNOSTICS_SIGNING_KEY")),
+		maintenance:           newMaintenanceState(),
+		schedules:             newScheduleState(os.Getenv("SCHEDULES_STATE_FILE")),
+		massDelete:            newMassDeleteState(),
+		callbacks:             webhook.NewVerifier(callbackSecrets{secretsFromEnv()}, envDuration("CALLBACK_WINDOW", webhook.DefaultWindow), clk),
+		queued:                &queuedState{},
+		latency:               newLatencyState(),
+		idempotency:           newIdempotencyState(),
+		unique:                newUniqueIndex(),
+		updating:              make(map[string]int),
+		creating:              make(map[string]bool),
+		scheduledDeletions:    make(map[string]*scheduledDeletion),
+		finalizing:            make(map[string]*scheduledDeletion),
+		locks:                 make(map[string]*resourceLock),
+		MaxLockDuration:       envDuration("LOCK_MAX_DURATION", 8*time.Hour),
+		shares:                make(map[string]*models.ShareLink),
+		ShareSigningKey:       shareSigningKey(),
+		MaxShareTTL:           envDuration("SHARE_LINK_MAX_TTL", 24*time.Hour),
+		// WHY 10000: Several days of vendor calls for a typical studio,
+		// roughly a few MB of memory
+		Audit: audit.NewLog(envInt("AUDIT_MAX_ENTRIES", 10000)),
+		pendingByNamespace:       make(map[string]int),
+		Events:                   make(map[string][]models.Event),
+		MaxEventsPerResource:     envInt("EVENTS_MAX_PER_RESOURCE", 50),
+		Validators:               validation.DefaultRegistry(),
+		ConfigKeys:               validation.DefaultConfigKeys(),
+		DestinationProbeFrom:     os.Getenv("DESTINATION_PROBE_FROM"),
+		DestinationProbeTimeout:  envDuration("DESTINATION_PROBE_TIMEOUT", 3*time.Second),
+		MemoryGuard:              memoryGuard,
+		Clock:                    clk,
+		IDs:                      ids,
+		RequestIDs:               requestIDs,
+		BasePath:                 basePath,
+		UnversionedSunset:        loadUnversionedSunset(),
+		TrustedProxies:           proxies,
 	}
+	c.settings.Store(&runtimeConfig{
+		Providers:   providers,
+		RateLimiter: rateLimiter,
+		// WHY 10000: Well below the memory a resource + its history needs
+		// on a small pod; raise it deliberately, not by accident
+		MaxResources:             envInt("MAX_RESOURCES", 10000),
+		MaxResourcesPerNamespace: envInt("MAX_RESOURCES_PER_NAMESPACE", 0),
+		SpecLimits:               loadSpecLimits(),
+		ConfigKeyPolicy:          loadConfigKeyPolicy(),
+		DuplicateNames:           loadDuplicateNamePolicy(),
+		Routing:                  VendorRouting{Namespaces: make(map[string]*NamespaceRoutes)},
+		HealthPolicy:             health.DefaultConfig(),
+		CircuitOpen:              loadCircuitOpenPolicy(),
+		MassDelete:               loadMassDeletePolicy(),
+	})
+	c.vendorReads = newVendorReads(c)
+	c.healthChecks = &singleflight.Group[struct{}]{}
+	c.metricReads = &singleflight.Group[*models.ResourceMetrics]{}
+	return c
 }
 
 func (c *Controller) HandleCreateResource(w http.ResponseWriter, r *http.Request) {
@@ -65,43 +469,186 @@ func (c *Controller) HandleCreateResource(w http.ResponseWriter, r *http.Request
 	// Step 1: Decode the JSON request body
 	// WHY: Convert raw JSON bytes into a Go struct we can work with
 	// WHY NewDecoder: Streams directly from request body, efficient for large payloads
-	if err := json.NewDecoder(r.Body).Decode(&resource); err != nil {
+	// WHY MaxBytesReader: Refuse oversized bodies before buffering them
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxResourceBodyBytes)).Decode(&resource); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit)})
+			return
+		}
 		// WHY 400 Bad Request: Client sent invalid data, not our fault
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
 		return // WHY return: Stop processing, don't continue with bad data
 	}
 
+	// WHY HERE: A bad strategy should fail before anything is reserved
+	duplicateStrategy := r.URL.Query().Get("onDuplicate")
+	if duplicateStrategy != "" && !validDuplicateStrategy(duplicateStrategy) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "onDuplicate must be one of: " + strings.Join(duplicateStrategies, ", ")})
+		return
+	}
+	if rejectBadCircuitOpenParam(w, r) {
+		return
+	}
+
+	// WHY AFTER PARSING: A retry is recognized by what it asks for (see
+	// idempotency.go)
+	idempotent, handled := c.beginCreate(w, r, &resource, duplicateStrategy)
+	if handled {
+		return
+	}
+
+	// Steps 2-9: Validate, create on the vendor and store (see createResource)
+	if err := c.createResource(vendorContext(r), r, &resource, duplicateStrategy); err != nil {
+		idempotent.finish("", 0)
+		writeCreateError(w, err)
+		return
+	}
+
+	// Step 10: Return the created resource as JSON with HTTP 201
+	// WHY 201 Created: REST convention - resource was successfully created
+	// WHY 202 WHEN QUEUED: Stored, but not on the vendor yet (see queued.go)
+	status := http.StatusCreated
+	if resource.Status.Phase == phaseQueued {
+		status = http.StatusAccepted
+	}
+	idempotent.finish(resource.ID, status)
+	c.writeCreated(w, r, &resource, status)
+}
+
+// writeCreated writes the response to a create of resource.
+// WHY Content-Type: Tells client to parse response as JSON
+// WHY Location: Points at the new resource, as seen through any proxy
+// WHY COPY: resource may be the stored record, and the redactor edits
+// what it is given
+func (c *Controller) writeCreated(w http.ResponseWriter, r *http.Request, resource *models.ForgeResource, status int) {
+	c.mu.RLock()
+	resource = resource.DeepCopy()
+	c.mu.RUnlock()
+	w.Header().Set("Content-Type", "application/json")
+	location := "/resources/" + resource.ID
+	if ns := routeNamespace(r); ns != "" {
+		location = "/namespaces/" + ns + location
+	}
+	w.Header().Set("Location", c.externalURL(r, versionedPath(location)))
+	setResourceETag(w, resource)
+	redact := c.redactionFor(r)
+	redact.announce(w)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(redact.resource(resource))
+}
+
+// createResource validates resource, creates it on its vendor and stores
+// it (steps 2-9 of a create). A vendor failure is not an error: the
+// resource is stored as Failed so it can be inspected. Errors are for
+// writeCreateError. duplicateStrategy "" means the namespace default.
+func (c *Controller) createResource(parent context.Context, r *http.Request, resource *models.ForgeResource, duplicateStrategy string) error {
+	return c.createResourceWith(parent, r, resource, duplicateStrategy, nil)
+}
+
+// createResourceWith is createResource with stage (nil = none) run in the
+// transaction that stores the resource.
+func (c *Controller) createResourceWith(parent context.Context, r *http.Request, resource *models.ForgeResource, duplicateStrategy string, stage txStage) error {
+	cfg := c.config()
 	// Step 2: Validate required fields
 	// WHY VALIDATE: Catch errors early before we do expensive vendor API calls
 	// WHY THESE FIELDS: Minimum info needed to create any resource
 	if resource.Name == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "name is required"})
-		return
+		return &createError{http.StatusBadRequest, "name is required"}
 	}
 	if resource.Type == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "type is required"})
-		return
+		return &createError{http.StatusBadRequest, "type is required"}
 	}
-	if resource.Spec.VendorType == "" {
+	// WHY HERE: Everything below (routing, policies, quotas, uniqueness)
+	// is per namespace
+	if ns := routeNamespace(r); ns != "" {
+		if resource.Namespace != "" && namespaceKey(resource.Namespace) != namespaceKey(ns) {
+			return &createError{http.StatusBadRequest, fmt.Sprintf("namespace %q doesn't match the route's %q", resource.Namespace, ns)}
+		}
+		resource.Namespace = ns
+	}
+	// WHY ROUTE: A spec may leave the vendor to its namespace's rules (see routing.go)
+	if !c.routeVendor(resource) {
 		// WHY vendor_type required: We need to know WHICH provider to use
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "vendor_type is required"})
-		return
+		return &createError{http.StatusBadRequest, "vendor_type is required (no routing rule matches this resource)"}
+	}
+	if duplicateStrategy == "" {
+		duplicateStrategy = cfg.DuplicateNames.strategyFor(resource.Namespace)
+	}
+
+	// Step 2a: Merge the environment's overlay (see profiles.go)
+	// WHY BEFORE VALIDATION: The merged spec is what the vendor gets
+	spec, err := cfg.Profiles.apply(resource.Spec)
+	if err != nil {
+		return &createError{http.StatusBadRequest, err.Error()}
+	}
+	resource.Spec = spec
+
+	// Step 2b: Validate sizes, cross-field rules (e.g. recording needs a
+	// path) and the rules of the resource's type (e.g. camera bitrates)
+	// WHY ALL AT ONCE: The client gets every problem with a field path in
+	// one round trip instead of fixing them one by one
+	// WHY NORMALIZE FIRST: "10.0.1.50 " and "10.0.1.50" must be the same
+	// address to the rules, the overlap check and the vendor
+	resource.Spec = validation.NormalizeNetwork(resource.Spec)
+	violations := append(validation.CheckSize(resource, cfg.SpecLimits), c.Validators.Validate(resource.Type, resource.Spec)...)
+	violations = append(violations, validation.ValidateMetadata(resource.Metadata)...)
+	violations = append(violations, validation.ValidateLabels(resource.Labels)...)
+	violations = append(violations, validation.ValidateAnnotations(resource.Annotations)...)
+	violations = append(violations, validation.ValidateFinalizers(resource.Finalizers)...)
+	configRejected, configWarnings := c.checkConfigKeys(resource.Namespace, nil, resource.Spec)
+	violations = append(violations, configRejected...)
+	if len(violations) == 0 {
+		violations = c.networkViolations("", resource.Spec)
+	}
+	if len(violations) > 0 {
+		return violations
+	}
+
+	// Step 2c: Dependencies and the owner must already exist
+	// WHY HERE: A dangling reference would make delete ordering meaningless
+	if msg := c.checkDependencies(resource); msg != "" {
+		return &createError{http.StatusBadRequest, msg}
+	}
+	if msg := c.checkOwner("", resource); msg != "" {
+		return &createError{http.StatusBadRequest, msg}
 	}
 
 	// Step 3: Generate a unique ID for this resource
 	// WHY WE GENERATE IT: Client doesn't control IDs, prevents duplicates/conflicts
 	// WHY NOT UUID: Nanosecond timestamp is simpler, good enough for this project
-	resource.ID = generateResourceID()
+	resource.ID = c.IDs.NewID("res")
+	// WHY: The version is the controller's to count (see preconditions.go)
+	resource.ResourceVersion = 0
+
+	// Step 3a: Claim the values that must be unique in the namespace (see uniqueness.go)
+	// WHY BEFORE THE VENDOR CALL: A concurrent create with the same
+	// ip_address must fail here, not after both devices exist
+	c.mu.Lock()
+	c.creating[resource.ID] = true
+	c.mu.Unlock()
+	defer func() {
+		// WHY: The claims stay only with a stored resource
+		c.mu.Lock()
+		delete(c.creating, resource.ID)
+		if _, stored := c.ResourceDB[resource.ID]; !stored {
+			c.releaseUniqueLocked(resource.ID)
+		}
+		c.mu.Unlock()
+	}()
+	if err := c.claimUnique(resource.ID, resource.Namespace, resource.Name, resource.Spec); err != nil {
+		return err
+	}
 
 	// Step 4: Set timestamps
 	// WHY: Track when resource was created for auditing/debugging
 	// WHY BOTH SAME: At creation time, created and updated are identical
-	resource.CreatedAt = time.Now()
-	resource.UpdatedAt = time.Now()
+	resource.CreatedAt = c.Clock.Now()
+	resource.UpdatedAt = resource.CreatedAt
+	c.stampMetadata(r, &resource.Metadata, resource.CreatedAt)
 
 	// Step 5: Initialize the resource status to "Pending"
 	// WHY "Pending": Resource exists but vendor hasn't confirmed yet
@@ -112,50 +659,128 @@ func (c *Controller) HandleCreateResource(w http.ResponseWriter, r *http.Request
 	// Step 6: Select the provider based on resource.Spec.VendorType
 	// WHY MAP LOOKUP: O(1) lookup, easy to add new vendors
 	// This is the key abstraction - controller doesn't know vendor details
-	selectedProvider, exists := c.Providers[resource.Spec.VendorType]
+	selectedProvider, exists := cfg.Providers[resource.Spec.VendorType]
 	if !exists {
 		// WHY 400: Client asked for a vendor we don't support
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "unsupported vendor: " + resource.Spec.VendorType})
-		return
+		return &createError{http.StatusBadRequest, "unsupported vendor: " + resource.Spec.VendorType}
+	}
+	preflighter, canPreflight := selectedProvider.(provider.Preflighter)
+	if resource.Spec.Preflight && !canPreflight {
+		return &createError{http.StatusNotImplemented, "vendor " + resource.Spec.VendorType + " does not support preflight checks"}
+	}
+
+	// Step 6a: Probe the stream destination (spec.probe_destination, see destination.go)
+	// WHY BEFORE RESERVING: Nothing to give back if the destination is dead
+	if resource.Spec.ProbeDestination {
+		if err := c.probeDestination(parent, selectedProvider, resource); err != nil {
+			return err
+		}
+	}
+
+	// Step 6b: Reserve capacity before touching the vendor
+	// WHY BEFORE THE VENDOR CALL: Refusing after the device exists would
+	// leave an orphan on the vendor side
+	if capErr := c.reserveCapacity(resource.Namespace); capErr != nil {
+		return capErr
 	}
 
 	// Step 7: Create a context with timeout for the vendor API call
 	// WHY CONTEXT: Provides cancellation and timeout capabilities
 	// WHY 30 SECONDS: Generous timeout for slow vendor APIs
 	// WHY defer cancel(): Prevents goroutine/memory leaks if we return early
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(parent, 30*time.Second)
 	defer cancel()
 
 	// Step 8: Call provider.Create() with the context and resource
 	// WHY PROVIDER: Provider handles all vendor-specific translation and HTTP calls
 	// Controller doesn't know HOW to talk to Sony - provider does
-	status, err := selectedProvider.Create(ctx, &resource)
+	// WHY SLOT BEFORE CALL: Caps concurrent calls into the vendor API
+	release, err := c.acquireVendor(ctx, resource.Spec.VendorType)
+	if err != nil {
+		// WHY 503 (not Failed resource): Nothing was sent to the vendor,
+		// so the client can simply retry later
+		c.cancelReservation(resource.Namespace)
+		return err
+	}
+	// Step 8a: Vendor-side duplicate names - refuse, rename or adopt (see duplicates.go)
+	requestedName := resource.Name
+	adopt, err := c.checkDuplicateName(ctx, selectedProvider, resource, duplicateStrategy)
+	if err != nil {
+		release()
+		if isCircuitOpen(err) && cfg.CircuitOpen.queues(r, resource.Namespace) {
+			return c.queueCreate(r, resource, duplicateStrategy, err, stage)
+		}
+		c.cancelReservation(resource.Namespace)
+		return err
+	}
+	// WHY CLAIM AGAIN: rename picked a name free on the vendor, which may
+	// still be taken in the namespace
+	if resource.Name != requestedName {
+		if err := c.claimUnique(resource.ID, resource.Namespace, resource.Name, resource.Spec); err != nil {
+			release()
+			c.cancelReservation(resource.Namespace)
+			return err
+		}
+	}
+	if adopt != nil {
+		return c.createByAdopting(ctx, selectedProvider, resource, adopt, release, stage)
+	}
+	// Step 8b: Preflight (spec.preflight) - abort before anything is provisioned
+	// WHY IN THE SAME SLOT: Check and create are one logical vendor operation
+	if resource.Spec.Preflight {
+		result, err := preflighter.Preflight(ctx, resource)
+		if err != nil || !result.Passed() {
+			release()
+			c.cancelReservation(resource.Namespace)
+			return &preflightError{result: result, err: err}
+		}
+	}
+	status, err := selectedProvider.Create(ctx, resource)
+	release()
+	// Step 8c: Vendor's circuit is open - queue the create if the policy says so (see queued.go)
+	if isCircuitOpen(err) && cfg.CircuitOpen.queues(r, resource.Namespace) {
+		return c.queueCreate(r, resource, duplicateStrategy, err, stage)
+	}
 	if err != nil {
 		// WHY NOT RETURN ERROR: We still want to save the failed resource
 		// so users can query it and see what went wrong
 		resource.Status.Phase = "Failed"
 		resource.Status.Message = "Vendor API error: " + err.Error()
-		log.Printf("Failed to create resource with vendor: %v", err)
+		logger.Errorf("Failed to create resource with vendor: %v", err)
 	} else {
 		// WHY COPY STATUS: Provider returns the observed state from vendor
 		// This includes VendorID which we need for future Read/Update/Delete
 		resource.Status = *status
 	}
+	cfg.HealthPolicy.Apply(resource)
+	c.applyMaintenanceCondition(resource)
 
 	// Step 9: Store the resource in the in-memory database
-	// WHY LOCK: Multiple requests might try to write at the same time
-	// Without lock, we could corrupt the map (race condition)
-	c.mu.Lock()
-	c.ResourceDB[resource.ID] = &resource
-	c.mu.Unlock() // WHY UNLOCK IMMEDIATELY: Don't hold lock during JSON encoding
-
-	// Step 10: Return the created resource as JSON with HTTP 201
-	// WHY 201 Created: REST convention - resource was successfully created
-	// WHY Content-Type: Tells client to parse response as JSON
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(resource)
+	// WHY A TRANSACTION: The resource, its claims and its references to
+	// other resources are stored together (see tx.go)
+	return c.withTx(func(tx *storeTx) error {
+		dropped := c.dropStaleRefs(tx, resource)
+		tx.Put(resource, "created")
+		tx.OnCommit(func() {
+			c.commitReservationLocked(resource.Namespace)
+			if resource.Status.Phase == "Failed" {
+				c.recordEvent(resource, models.EventWarning, models.ReasonCreateFailed, resource.Status.Message, "Failed", "")
+			} else {
+				message := fmt.Sprintf("Created %s device %s", resource.Spec.VendorType, resource.Status.VendorID)
+				if resource.Name != requestedName {
+					message += fmt.Sprintf(" as %q (%q is taken on the vendor)", resource.Name, requestedName)
+				}
+				c.recordEvent(resource, models.EventNormal, models.ReasonCreated, message,
+					resource.Status.Phase, resource.Status.HealthStatus)
+			}
+			c.recordDroppedRefsLocked(resource, dropped)
+			c.warnConfigKeysLocked(resource, configWarnings)
+		})
+		if stage != nil {
+			stage(tx, resource)
+		}
+		return nil
+	})
 }
 
 
@@ -170,11 +795,34 @@ func (c *Controller) HandleGetResource(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// WHY BEFORE THE VENDOR READ: A typo in ?fields= shouldn't cost one
+	fields, err := parseFieldSet(r.URL.Query())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	// Time-travel query: answer from revision history instead of the vendor
+	// WHY: Post-incident analysis needs the state AS IT WAS, not as it is now
+	if asOf := r.URL.Query().Get("asOf"); asOf != "" {
+		c.serveResourceAsOf(w, r, resourceID, asOf, fields)
+		return
+	}
+
 	// Step 2: Look up the resource from the in-memory database
 	// WHY RLock (not Lock): Read lock allows multiple simultaneous readers
 	// Only blocks if someone is writing. Better performance for read-heavy workloads.
+	// WHY READ THE FIELDS HERE: Other GETs and the reconciler replace
+	// resource.Status under c.mu while the vendor read below runs
 	c.mu.RLock()
 	resource, exists := c.ResourceDB[resourceID]
+	var vendorType, vendorID, phase string
+	if exists {
+		// Step 3: Get the vendor type from the stored resource
+		vendorType = resource.Spec.VendorType
+		vendorID, phase = resource.Status.VendorID, resource.Status.Phase
+	}
 	c.mu.RUnlock() // WHY UNLOCK BEFORE CHECK: Don't hold lock while doing other work
 
 	if !exists {
@@ -184,11 +832,8 @@ func (c *Controller) HandleGetResource(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Step 3: Get the vendor type from the stored resource
-	vendorType := resource.Spec.VendorType
-
 	// Step 4: Select the appropriate provider
-	selectedProvider, exists := c.Providers[vendorType]
+	selectedProvider, exists := c.config().Providers[vendorType]
 	if !exists {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": "provider not configured"})
@@ -196,34 +841,104 @@ func (c *Controller) HandleGetResource(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Step 5: Create a context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancel()
+	// WHY NOT THE BUDGET: The read keeps going after the response is sent
+	// so the cache is fresh for the next GET (see latency.go)
+	ctx, cancel := context.WithTimeout(vendorContext(r), 15*time.Second)
 
 	// Step 6: Call provider.Read() to get current status from vendor
 	// WHY CHECK VendorID: If empty, resource was never created in vendor system
 	// (maybe creation failed). Can't read something that doesn't exist.
-	if resource.Status.VendorID != "" {
-		status, err := selectedProvider.Read(ctx, resource.Status.VendorID)
-		if err != nil {
-			// WHY NOT FAIL: Vendor being down shouldn't break our API
-			// GRACEFUL DEGRADATION: Return stale cache data instead of error
-			log.Printf("Failed to read from vendor: %v", err)
-		} else {
-			// Update the resource with fresh status from vendor
-			// WHY UPDATE: Vendor status may have changed (device went offline, etc.)
-			resource.Status = *status
-			resource.UpdatedAt = time.Now()
-			// Update in database so next read doesn't need vendor call
-			c.mu.Lock()
-			c.ResourceDB[resourceID] = resource
-			c.mu.Unlock()
+	// WHY NOT WHILE TERMINATING: The deletion worker owns the status until
+	// the record is removed (see deletion.go)
+	// WHY NOT WHILE QUEUED: The replayer owns it until the queued update
+	// reaches the vendor (see queued.go)
+	partial := false
+	if vendorID != "" && phase != phaseTerminating && phase != phaseQueued {
+		read := make(chan error, 1)
+		go func() {
+			defer cancel()
+			status, err := c.readWithSlot(ctx, selectedProvider, vendorType, vendorID)
+			if err == nil {
+				c.storeReadStatus(resource, status)
+			}
+			read <- err
+		}()
+
+		// WHY SELECT: The request's deadline is the route's latency budget;
+		// a vendor slower than that must not make the GET slow
+		fallback, stopWaiting := fallbackContext(r)
+		defer stopWaiting()
+		select {
+		case err := <-read:
+			if err != nil {
+				// WHY NOT FAIL: Vendor being down shouldn't break our API
+				// GRACEFUL DEGRADATION: Return stale cache data instead of error
+				logger.Warnf("Failed to read from vendor: %v", err)
+				c.markDegraded(w, r, degradedVendorError)
+				partial = true
+				c.queueRefresh(resource)
+			}
+		case <-fallback.Done():
+			logger.Warnf("Vendor read of %s still running at the latency budget; serving the cached status", resourceID)
+			c.markDegraded(w, r, degradedVendorTimeout)
+			partial = true
+			// WHY WATCH THE READ: If it fails too, the cache is still
+			// stale; the reconciler tries again
+			go func() {
+				if err := <-read; err != nil {
+					logger.Warnf("Vendor read of %s failed after the latency budget: %v", resourceID, err)
+					c.queueRefresh(resource)
+				}
+			}()
 		}
+	} else {
+		cancel()
 	}
 
 	// Step 7: Return the resource as JSON with HTTP 200
 	// WHY 200 OK: Resource found and returned (even if using cached data)
+	// WHY COPY: Other requests may be updating the record meanwhile
+	c.mu.RLock()
+	snapshot := resource.DeepCopy()
+	c.mu.RUnlock()
+	snapshot.Status.Partial = partial
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resource)
+	setResourceETag(w, snapshot)
+	redact := c.redactionFor(r)
+	redact.announce(w)
+	redact.resource(snapshot)
+	if fields != nil {
+		json.NewEncoder(w).Encode(fields.project(snapshot))
+		return
+	}
+	json.NewEncoder(w).Encode(snapshot)
+}
+
+
+// storeReadStatus applies a status read from the vendor to resource.
+func (c *Controller) storeReadStatus(resource *models.ForgeResource, status *models.ResourceStatus) {
+	// Update the resource with fresh status from vendor
+	// WHY UPDATE: Vendor status may have changed (device went offline, etc.)
+	// WHY UNDER THE LOCK: Concurrent GETs of the same resource
+	// (coalesced reads finish together) update the same record
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// WHY CHECK: The read may finish after the response, once the
+	// resource is gone
+	if c.ResourceDB[resource.ID] != resource {
+		return
+	}
+	oldStatus := resource.Status
+	resource.Status = *status
+	resource.UpdatedAt = c.Clock.Now()
+	// Update in database so next read doesn't need vendor call
+	c.config().HealthPolicy.Apply(resource)
+	c.applyMaintenanceCondition(resource)
+	if statusChanged(oldStatus, resource.Status) {
+		// WHY ONLY ON CHANGE: Keeps history focused on real transitions
+		c.recordRevision(resource, "status-refresh", false)
+		c.recordStatusEvents(resource, oldStatus)
+	}
 }
 
 
@@ -251,76 +966,287 @@ func (c *Controller) HandleDeleteResource(w http.ResponseWriter, r *http.Request
 		json.NewEncoder(w).Encode(map[string]string{"error": "resource not found"})
 		return
 	}
+	// WHY 423: Someone is working on the device by hand (see locks.go)
+	// WHY 412: The client deletes the version it saw, or nothing (see
+	// preconditions.go)
+	if c.rejectIfLocked(w, r, resourceID) || c.rejectIfPreconditionFailed(w, r, resourceID) {
+		return
+	}
+	propagation := r.URL.Query().Get("propagation")
+	if propagation != "" && propagation != propagationForeground && propagation != propagationOrphan {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "propagation must be foreground or orphan"})
+		return
+	}
+	cascade := propagation != propagationOrphan
 
-	// Step 3: Select the provider
-	selectedProvider, exists := c.Providers[resource.Spec.VendorType]
-	if !exists {
+	// Step 2b: Refuse while other resources depend on this one
+	// WHY: Tearing down a camera under a running encoder breaks the encoder;
+	// POST /resources:batchDelete deletes dependents first
+	// WHY NOT OWNED ONES: The cascade deletes them first (see owners.go)
+	c.mu.RLock()
+	var dependents []string
+	for _, dependent := range c.dependentsLocked(resourceID) {
+		if !cascade || !c.ownsLocked(resourceID, dependent) {
+			dependents = append(dependents, dependent)
+		}
+	}
+	owns := len(c.ownedLocked(resourceID)) > 0
+	c.mu.RUnlock()
+	if len(dependents) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":      "resource has dependents; delete them first or use POST /resources:batchDelete with include_dependents",
+			"dependents": dependents,
+		})
+		return
+	}
+
+	// Step 3: Check the provider before accepting the delete
+	// WHY NOW: The worker can't report a misconfiguration back to the caller
+	if _, exists := c.config().Providers[resource.Spec.VendorType]; !exists {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": "provider not configured"})
 		return
 	}
 
-	// Step 4: Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	// Step 3a: Hold deletes past the caller's limit (see massdelete.go)
+	targets := []string{resourceID}
+	if cascade {
+		targets = c.deleteTargets(targets, false)
+	}
+	if c.guardMassDelete(w, r, massOpDelete, targets) {
+		return
+	}
 
-	// Step 5: Call provider.Delete() with the vendor ID
+	// Step 3b: ?propagation=orphan keeps the owned resources (see owners.go)
+	principal, _ := principalFrom(r.Context())
+	if owns && !cascade {
+		c.withTx(func(tx *storeTx) error {
+			c.orphanOwned(tx, resourceID, principal.Name)
+			return nil
+		})
+		owns = false
+	}
+
+	// Step 4: Nothing was created in the vendor system? Delete right away
+	// (unless finalizers or owned resources hold it; see finalizers.go)
 	// WHY CHECK VendorID: If empty, nothing exists in vendor system to delete
-	if resource.Status.VendorID != "" {
-		err := selectedProvider.Delete(ctx, resource.Status.VendorID)
+	if resource.Status.VendorID == "" && len(resource.Finalizers) == 0 && !owns {
+		// WHY TOMBSTONE: History outlives the resource for incident analysis
+		err := c.withTx(func(tx *storeTx) error {
+			stored, exists := c.ResourceDB[resourceID]
+			if !exists {
+				return nil
+			}
+			if err := c.preconditionLocked(vendorContext(r), stored); err != nil {
+				return err
+			}
+			c.removeResource(tx, stored, "deleted", "Deleted from controller (no vendor device)")
+			return nil
+		})
 		if err != nil {
-			// WHY 500: Vendor delete failed - could be network, auth, etc.
-			// WHY RETURN (not continue): Don't delete locally if vendor failed
-			// This maintains consistency - resource still exists in vendor
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{"error": "failed to delete from vendor: " + err.Error()})
+			c.refundMassDelete(r, targets)
+			writeOperationError(w, err)
 			return
 		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// Step 5: Mark the resource Terminating and start the deletion worker
+	// (after DELETE_GRACE_PERIOD, if set; see softdelete.go)
+	// WHY ASYNC: Vendor teardown can take minutes; the worker waits for it
+	// and then removes the record (see deletion.go)
+	task, err := c.beginDeletion(vendorContext(r), resourceID, principal.Name)
+	if err != nil {
+		c.refundMassDelete(r, targets)
+		writeOperationError(w, err)
+		return
+	}
+
+	// Step 6: Return HTTP 202 Accepted with the task tracking the deletion
+	// WHY 202 (not 204): The resource still exists until the vendor is done
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", c.externalURL(r, versionedPath("/tasks/"+task.ID)))
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(task)
+}
+
+// refreshStatus reads the resource's status from the vendor and stores it,
+// recording events for phase/health changes.
+func (c *Controller) refreshStatus(parent context.Context, id string) (*models.ResourceStatus, error) {
+	cfg := c.config()
+	c.mu.RLock()
+	stored, exists := c.ResourceDB[id]
+	var vendorType, vendorID string
+	if exists {
+		vendorType, vendorID = stored.Spec.VendorType, stored.Status.VendorID
+	}
+	c.mu.RUnlock()
+	if !exists {
+		return nil, errResourceGone
+	}
+	selectedProvider, exists := cfg.Providers[vendorType]
+	if !exists {
+		return nil, fmt.Errorf("provider %s not configured", vendorType)
+	}
+
+	ctx, cancel := context.WithTimeout(parent, 30*time.Second)
+	defer cancel()
+	status, err := c.readWithSlot(ctx, selectedProvider, vendorType, vendorID)
+	if err != nil {
+		return nil, err
 	}
 
-	// Step 6: Remove from in-memory database
-	// WHY AFTER VENDOR: Only delete locally after vendor confirms deletion
 	c.mu.Lock()
-	delete(c.ResourceDB, resourceID) // Built-in Go function to remove map entry
-	c.mu.Unlock()
+	defer c.mu.Unlock()
+	if stored, exists = c.ResourceDB[id]; !exists {
+		return nil, errResourceGone
+	}
+	if stored.Status.Phase == phaseTerminating {
+		// WHY KEEP: The deletion worker owns the status (see deletion.go)
+		current := stored.Status
+		return &current, nil
+	}
+	oldStatus := stored.Status
+	stored.Status = *status
+	cfg.HealthPolicy.Apply(stored)
+	c.applyMaintenanceCondition(stored)
+	if statusChanged(oldStatus, stored.Status) {
+		stored.UpdatedAt = c.Clock.Now()
+		c.recordRevision(stored, "status-refresh", false)
+		c.recordStatusEvents(stored, oldStatus)
+	}
+	current := stored.Status
+	return &current, nil
+}
 
-	// Step 7: Return HTTP 204 No Content (successful deletion)
-	// WHY 204 (not 200): REST convention - success but no body to return
-	// The resource no longer exists, so there's nothing to return
-	w.WriteHeader(http.StatusNoContent)
+// createError rejects a create request before anything is reserved.
+type createError struct {
+	status  int
+	message string
 }
 
-// generateResourceID creates a unique resource identifier.
-//
-// WHY TIME-BASED:
-// - Simple and doesn't require external dependencies
-// - Nanoseconds gives uniqueness for reasonable request rates
-// - Prefix "res-" makes IDs human-readable and identifiable
-//
-// LIMITATION: Could produce duplicates under extreme concurrency.
-// For production, consider using UUID: github.com/google/uuid
-func generateResourceID() string {
-	return fmt.Sprintf("res-%d", time.Now().UnixNano())
+func (e *createError) Error() string { return e.message }
+
+// writeCreateError maps an error from createResource to an HTTP response.
+func writeCreateError(w http.ResponseWriter, err error) {
+	var invalid *createError
+	var capErr *CapacityError
+	var dup *duplicateNameError
+	var preflight *preflightError
+	var unreachable *destinationUnreachableError
+	switch {
+	case errors.As(err, &invalid):
+		w.WriteHeader(invalid.status)
+		json.NewEncoder(w).Encode(map[string]string{"error": invalid.message})
+	case errors.As(err, &capErr):
+		writeCapacityError(w, capErr)
+	case errors.As(err, &dup):
+		writeDuplicateError(w, err)
+	case errors.As(err, &preflight):
+		writePreflightFailure(w, preflight.result, preflight.err)
+	case errors.As(err, &unreachable):
+		writeDestinationUnreachable(w, unreachable)
+	default:
+		writeOperationError(w, err)
+	}
 }
 
+// writeProviderError maps a provider error to an HTTP response.
+// WHY errors.Is: Providers wrap vendor 404/409 in sentinel errors
+// (pkg/provider/errors.go) so we don't parse vendor error strings.
+func writeProviderError(w http.ResponseWriter, err error) {
+	var circuitErr *client.CircuitOpenError
+	switch {
+	case errors.Is(err, provider.ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, provider.ErrConflict):
+		w.WriteHeader(http.StatusConflict)
+	case errors.Is(err, provider.ErrInvalidRequest):
+		w.WriteHeader(http.StatusBadRequest)
+	case errors.As(err, &circuitErr):
+		// WHY 503: The vendor host is known to be down; retrying later helps
+		w.Header().Set("Retry-After", strconv.Itoa(int(circuitErr.RetryAfter/time.Second)+1))
+		w.WriteHeader(http.StatusServiceUnavailable)
+	default:
+		// WHY 502: The vendor (upstream) failed, not this controller
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// envInt reads an integer environment variable, falling back to def when
+// unset or invalid.
+func envInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		logger.Warnf("Ignoring invalid %s %q", key, v)
+		return def
+	}
+	return n
+}
+
+// envDuration reads a Go duration environment variable ("30s", "5m"),
+// falling back to def when unset or invalid.
+func envDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		logger.Warnf("Ignoring invalid %s %q", key, v)
+		return def
+	}
+	return d
+}
+
+// envFloat reads a floating-point environment variable, falling back to def
+// when it is unset or invalid.
+func envFloat(key string, def float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f <= 0 {
+		logger.Warnf("Ignoring invalid %s %q", key, v)
+		return def
+	}
+	return f
+}
 
 func (c *Controller) HandleHealthCheck(w http.ResponseWriter, r *http.Request) {
 	// WHY SHORT TIMEOUT: Health checks should be fast
 	// If vendor takes > 5 seconds, something is wrong
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(vendorContext(r), 5*time.Second)
 	defer cancel()
 
 	healthy := true
 	// Check each registered provider
-	for name, selectedProvider := range c.Providers {
-		if err := selectedProvider.HealthCheck(ctx); err != nil {
+	for name := range c.config().Providers {
+		if err := c.checkProvider(ctx, name); err != nil {
 			// WHY LOG: Operators need to know which provider failed
-			log.Printf("Provider %s unhealthy: %v", name, err)
+			logger.Warnf("Provider %s unhealthy: %v", name, err)
 			healthy = false
 			// WHY NOT BREAK: Check all providers, report all failures
 		}
 	}
 
+	// WHY THE STORE: A read-only store refuses every change; traffic is
+	// better sent to a replica that can take it
+	if failure := c.storeFailed.Load(); failure != nil {
+		logger.Warnf("Store is read-only: %s", failure.Error)
+		healthy = false
+	}
+
 	if healthy {
 		// WHY 200: Service is ready to handle requests
 		w.WriteHeader(http.StatusOK)
@@ -333,12 +1259,354 @@ func (c *Controller) HandleHealthCheck(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// =============================================================================
+// ROUTES
+// =============================================================================
+// registerRoutes adds every API route to api. It is called once per
+// mount point: /v1 and the deprecated unversioned aliases.
+func (c *Controller) registerRoutes(api *mux.Router) {
+	api.HandleFunc("/resources", c.HandleCreateResource).Methods("POST") // create 
+	api.HandleFunc("/resources", c.HandleListResources).Methods("GET")
+	api.HandleFunc("/resources", c.HandleDeleteResources).Methods("DELETE")
+	// WHY BEFORE {id}: Otherwise "watch" would be taken for a resource ID
+	api.HandleFunc("/resources/watch", c.HandleWatchResources).Methods("GET")
+	api.HandleFunc("/resources/search", c.HandleSearchResources).Methods("GET")
+	api.HandleFunc("/resources/{id}", c.HandleGetResource).Methods("GET") // read
+	api.HandleFunc("/resources/{id}", c.HandlePatchResource).Methods("PATCH")
+	api.HandleFunc("/resources/{id}", c.HandleUpdateResource).Methods("PUT")
+	api.HandleFunc("/resources/{id}/revisions", c.HandleListRevisions).Methods("GET")
+	api.HandleFunc("/resources/{id}/rollback", c.HandleRollbackResource).Methods("POST")
+	api.HandleFunc("/resources/{id}/restore", c.HandleRestoreResource).Methods("POST")
+	api.HandleFunc("/resources/{id}/clone", c.HandleCloneResource).Methods("POST")
+	api.HandleFunc("/resources/{id}/finalizers/{name:.+}", c.HandleRemoveFinalizer).Methods("DELETE")
+	api.HandleFunc("/resources/{id}/events", c.HandleListEvents).Methods("GET")
+	api.HandleFunc("/resources/{id}/metrics", c.HandleGetResourceMetrics).Methods("GET")
+	api.HandleFunc("/resources/{id}:convert", c.HandleConvertResource).Methods("POST")
+	api.HandleFunc("/resources:batch", c.HandleBatchCreate).Methods("POST")
+	api.HandleFunc("/resources:apply", c.HandleApplyResource).Methods("POST")
+	api.HandleFunc("/apply", c.HandleApplyManifest).Methods("POST")
+	api.HandleFunc("/resources:simulate", c.HandleSimulateCreate).Methods("POST")
+	api.HandleFunc("/resources:batchDelete", c.HandleBatchDelete).Methods("POST")
+	api.HandleFunc("/resources:healthCheck", c.HandleBatchHealthCheck).Methods("POST")
+	api.HandleFunc("/resources/{id}:stop", c.HandleStopResource).Methods("POST")
+	api.HandleFunc("/resources/{id}:start", c.HandleStartResource).Methods("POST")
+	api.HandleFunc("/resources/{id}/actions", c.HandleListActions).Methods("GET")
+	api.HandleFunc("/resources/{id}/actions", c.HandleRunAction).Methods("POST")
+	api.HandleFunc("/resources/{id}:lock", c.requireRole(RoleOperator, c.HandleLockResource)).Methods("POST")
+	api.HandleFunc("/resources/{id}:unlock", c.requireRole(RoleOperator, c.HandleUnlockResource)).Methods("POST")
+	api.HandleFunc("/locks", c.HandleListLocks).Methods("GET")
+	api.HandleFunc("/resources/{id}:share", c.requireRole(RoleOperator, c.HandleShareResource)).Methods("POST")
+	api.HandleFunc("/resources/{id}:preview", c.HandlePreviewResource).Methods("POST")
+	api.HandleFunc("/resources/{id}/shares", c.HandleListShares).Methods("GET")
+	api.HandleFunc("/resources/{id}/shares/{sid}", c.requireRole(RoleOperator, c.HandleRevokeShare)).Methods("DELETE")
+	// WHY NO ROLE: The signed token is the credential (see sharelinks.go)
+	api.HandleFunc("/share/{token}", c.HandleGetShared).Methods("GET")
+	api.Handle("/callbacks/{vendor}", c.callbacks.Middleware(http.HandlerFunc(c.HandleVendorCallback))).Methods("POST")
+
+	// Recording sessions
+	api.HandleFunc("/resources/{id}/recordings", c.HandleStartRecording).Methods("POST")
+	api.HandleFunc("/resources/{id}/recordings", c.HandleListRecordings).Methods("GET")
+	api.HandleFunc("/resources/{id}/recordings/cleanup", c.HandleCleanupRecordings).Methods("POST")
+	api.HandleFunc("/resources/{id}/recordings/{sid}/stop", c.HandleStopRecording).Methods("POST")
+	api.HandleFunc("/resources/{id}/recordings/{sid}", c.HandleDeleteRecording).Methods("DELETE")
+	api.HandleFunc("/resources/{id}/ptz", c.HandleGetPTZ).Methods("GET")
+	api.HandleFunc("/resources/{id}/ptz", c.HandleMovePTZ).Methods("POST")
+	api.HandleFunc("/resources/{id}/presets", c.HandleListPresets).Methods("GET")
+	api.HandleFunc("/resources/{id}/presets", c.HandleSavePreset).Methods("POST")
+	api.HandleFunc("/resources/{id}/presets/{number}/recall", c.HandleRecallPreset).Methods("POST")
+	api.HandleFunc("/resources/{id}:backupConfig", c.HandleBackupConfig).Methods("POST")
+	api.HandleFunc("/resources/{id}:restoreConfig", c.HandleRestoreConfig).Methods("POST")
+	api.HandleFunc("/resources/{id}/configSnapshots", c.HandleListConfigSnapshots).Methods("GET")
+	api.HandleFunc("/resources/{id}/configSnapshots/{n}", c.HandleGetConfigSnapshot).Methods("GET")
+	api.HandleFunc("/resources/{id}", c.HandleDeleteResource).Methods("DELETE") // dete
+
+	// Discovery and adoption of unmanaged vendor devices
+	api.HandleFunc("/discovery/scan", c.HandleDiscoveryScan).Methods("POST")
+	api.HandleFunc("/adoptions", c.HandleListAdoptions).Methods("GET")
+	api.HandleFunc("/adoptions/{id}", c.HandleGetAdoption).Methods("GET")
+	api.HandleFunc("/adoptions/{id}/approve", c.HandleApproveAdoption).Methods("POST")
+	api.HandleFunc("/adoptions/{id}/reject", c.HandleRejectAdoption).Methods("POST")
+
+	// Canary rollouts of spec changes across a group
+	api.HandleFunc("/profiles", c.HandleListProfiles).Methods("GET")
+	api.HandleFunc("/capabilities", c.HandleListCapabilities).Methods("GET")
+	api.HandleFunc("/capabilities/{vendor}", c.HandleGetCapabilities).Methods("GET")
+	api.HandleFunc("/admin/routing", c.HandleGetRouting).Methods("GET")
+	api.HandleFunc("/admin/redaction", c.HandleGetRedaction).Methods("GET")
+	api.HandleFunc("/admin/egress", c.HandleGetEgress).Methods("GET")
+	api.HandleFunc("/admin/dns", c.HandleGetDNS).Methods("GET")
+	api.HandleFunc("/admin/store", c.HandleGetStore).Methods("GET")
+	api.HandleFunc("/admin/store/events", c.HandleListStoreEvents).Methods("GET")
+	api.HandleFunc("/admin/store/state", c.HandleGetStoreState).Methods("GET")
+	api.HandleFunc("/admin/store:verify", c.requireRole(RoleAdmin, c.HandleVerifyStore)).Methods("POST")
+	api.HandleFunc("/admin/reload", c.HandleListReloads).Methods("GET")
+	api.HandleFunc("/admin/reload", c.requireRole(RoleAdmin, c.HandleReload)).Methods("POST")
+	api.HandleFunc("/admin/store:reindex", c.requireRole(RoleAdmin, c.HandleReindexStore)).Methods("POST")
+	api.HandleFunc("/rollouts", c.HandleCreateRollout).Methods("POST")
+	api.HandleFunc("/rollouts", c.HandleListRollouts).Methods("GET")
+	api.HandleFunc("/rollouts/{id}", c.HandleGetRollout).Methods("GET")
+	api.HandleFunc("/rollouts/{id}/abort", c.HandleAbortRollout).Methods("POST")
+	api.HandleFunc("/tasks", c.HandleListTasks).Methods("GET")
+	api.HandleFunc("/tasks/{id}", c.HandleGetTask).Methods("GET")
+
+	// Cost-saving recommendations (idle resources)
+	api.HandleFunc("/recommendations", c.HandleListRecommendations).Methods("GET")
+	api.HandleFunc("/recommendations/{id}/apply", c.HandleApplyRecommendation).Methods("POST")
+
+	// Vendor health and what depends on each vendor
+	api.HandleFunc("/providers/health", c.HandleProvidersHealth).Methods("GET")
+
+	// Raw vendor access for debugging (admin only, audited)
+	api.HandleFunc("/providers/{name}/passthrough", c.requireRole(RoleAdmin, c.HandlePassthrough)).Methods("POST")
+	api.HandleFunc("/providers/{name}:diagnose", c.requireRole(RoleOperator, c.HandleDiagnoseProvider)).Methods("POST")
+
+	// Admin endpoints
+	api.HandleFunc("/audit", c.HandleListAudit).Methods("GET")
+	api.HandleFunc("/audit/checkpoints", c.HandleListAuditCheckpoints).Methods("GET")
+	api.HandleFunc("/admin/audit:verify", c.requireRole(RoleAdmin, c.HandleVerifyAudit)).Methods("POST")
+	api.HandleFunc("/admin/audit:checkpoint", c.requireRole(RoleAdmin, c.HandleAuditCheckpoint)).Methods("POST")
+	api.HandleFunc("/admin/limits", c.HandleGetLimits).Methods("GET")
+	api.HandleFunc("/admin/compact", c.HandleGetCompaction).Methods("GET")
+	api.HandleFunc("/admin/compact", c.requireRole(RoleAdmin, c.HandleCompact)).Methods("POST")
+	api.HandleFunc("/admin/reconciler", c.HandleGetReconciler).Methods("GET")
+	api.HandleFunc("/admin/coalescing", c.HandleGetCoalescing).Methods("GET")
+	api.HandleFunc("/admin/latency", c.HandleGetLatency).Methods("GET")
+	api.HandleFunc("/admin/pprof/{profile}", c.requireRole(RoleAdmin, c.HandleProfile)).Methods("GET")
+	api.HandleFunc("/admin/queued", c.HandleListQueued).Methods("GET")
+	api.HandleFunc("/admin/queued/{id}", c.requireRole(RoleOperator, c.HandleCancelQueued)).Methods("DELETE")
+	api.HandleFunc("/admin/health-policy", c.HandleGetHealthPolicy).Methods("GET")
+	api.HandleFunc("/admin/diagnostics", c.requireRole(RoleAdmin, c.HandleDiagnostics)).Methods("GET")
+	api.HandleFunc("/admin/maintenance", c.HandleListMaintenance).Methods("GET")
+	api.HandleFunc("/admin/maintenance", c.requireRole(RoleAdmin, c.HandleCreateMaintenance)).Methods("POST")
+	api.HandleFunc("/admin/maintenance/{id}", c.requireRole(RoleAdmin, c.HandleDeleteMaintenance)).Methods("DELETE")
+
+	// Scheduled jobs (see schedules.go)
+	api.HandleFunc("/schedules", c.HandleListSchedules).Methods("GET")
+	api.HandleFunc("/schedules", c.requireRole(RoleAdmin, c.HandleCreateSchedule)).Methods("POST")
+	api.HandleFunc("/schedules/{id}", c.HandleGetSchedule).Methods("GET")
+	api.HandleFunc("/schedules/{id}", c.requireRole(RoleAdmin, c.HandleUpdateSchedule)).Methods("PUT")
+	api.HandleFunc("/schedules/{id}", c.requireRole(RoleAdmin, c.HandleDeleteSchedule)).Methods("DELETE")
+	api.HandleFunc("/schedules/{id}:run", c.requireRole(RoleAdmin, c.HandleRunSchedule)).Methods("POST")
+	api.HandleFunc("/schedules/{id}/runs", c.HandleListScheduleRuns).Methods("GET")
+	api.HandleFunc("/admin/inventory", c.HandleListInventorySyncs).Methods("GET")
+	api.HandleFunc("/admin/inventory/{vendor}", c.requireRole(RoleAdmin, c.HandleResetInventorySync)).Methods("DELETE")
+	api.HandleFunc("/admin/notifications", c.HandleGetNotifications).Methods("GET")
+	api.HandleFunc("/admin/notifications/outbox", c.HandleGetOutbox).Methods("GET")
+	api.HandleFunc("/admin/loglevel", c.HandleGetLogLevel).Methods("GET")
+	api.HandleFunc("/admin/loglevel", c.requireRole(RoleAdmin, c.HandleSetLogLevel)).Methods("PUT")
+	api.HandleFunc("/admin/loglevel", c.requireRole(RoleAdmin, c.HandleResetLogLevel)).Methods("DELETE")
+	api.HandleFunc("/admin/clock", c.HandleGetClock).Methods("GET")
+	api.HandleFunc("/admin/clock", c.HandleAdvanceClock).Methods("POST")
+	c.registerNamespaceRoutes(api)
+}
+
 // =============================================================================
 // MAIN - APPLICATION ENTRY POINT
 // =============================================================================
 func main() {
+	// Keep recent log lines for diagnostic bundles
+	logTail := logging.NewTail(envInt("DIAGNOSTICS_LOG_LINES", 2000))
+	log.SetOutput(io.MultiWriter(os.Stderr, logTail))
+
+	// CONFIG_ENV_FILE settings override the environment (see reload.go);
+	// a file that can't be read is fatal like the other config files
+	reloadState, err := loadConfigEnv()
+	if err != nil {
+		log.Fatalf("invalid CONFIG_ENV_FILE: %v", err)
+	}
+
+	// Configure the baseline log level before anything logs
+	// WHY ENV: Operators set the steady-state level per environment;
+	// temporary changes go through PUT /admin/loglevel instead
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		level, err := logging.ParseLevel(v)
+		if err != nil {
+			log.Fatalf("invalid LOG_LEVEL: %v", err)
+		}
+		logging.SetBaseline(level)
+	}
+
 	// Initialize controller with all providers configured
 	controller := NewController()
+	controller.LogTail = logTail
+	controller.installCallRecorder()
+	controller.configureAuditSigning()
+	client.SetClock(controller.Clock)
+	// Per-host circuit breaker for outbound vendor calls
+	// Set CLIENT_BREAKER_FAILURES=0 to disable it
+	client.SetBreakerConfig(client.BreakerConfig{
+		FailureThreshold: envInt("CLIENT_BREAKER_FAILURES", client.DefaultBreakerConfig.FailureThreshold),
+		OpenFor:          envDuration("CLIENT_BREAKER_OPEN_FOR", client.DefaultBreakerConfig.OpenFor),
+		ConnResetAfter:   envInt("CLIENT_CONN_RESET_AFTER", client.DefaultBreakerConfig.ConnResetAfter),
+	})
+
+	// Notification routing is optional; a broken config is fatal so it
+	// isn't silently ignored
+	if path := os.Getenv("NOTIFY_CONFIG"); path != "" {
+		router, err := notify.LoadConfig(path)
+		if err != nil {
+			log.Fatalf("invalid NOTIFY_CONFIG: %v", err)
+		}
+		// WHY FATAL TOO: Starting without the outbox would drop the
+		// notifications a crash left in it
+		outbox, err := notify.OpenOutbox(os.Getenv("NOTIFY_OUTBOX_FILE"), notify.OutboxConfig{
+			MaxAttempts: envInt("NOTIFY_MAX_ATTEMPTS", notify.DefaultOutboxConfig.MaxAttempts),
+			RetryBase:   envDuration("NOTIFY_RETRY_BASE", notify.DefaultOutboxConfig.RetryBase),
+			RetryMax:    envDuration("NOTIFY_RETRY_MAX", notify.DefaultOutboxConfig.RetryMax),
+			Concurrency: envInt("NOTIFY_CONCURRENCY", notify.DefaultOutboxConfig.Concurrency),
+		})
+		if err != nil {
+			log.Fatalf("invalid NOTIFY_OUTBOX_FILE: %v", err)
+		}
+		router.SetOutbox(outbox)
+		controller.Notifier = router
+		logger.Infof("Notifications enabled: %d channels, %d routes", len(router.Channels()), len(router.Routes()))
+		if replayed := outbox.Stats().Replayed; replayed > 0 {
+			logger.Infof("Replaying %d undelivered notifications from %s", replayed, os.Getenv("NOTIFY_OUTBOX_FILE"))
+		}
+	}
+	// Same for health policies: a typo in a metric name must not silently
+	// leave devices "healthy"
+	if path := os.Getenv("HEALTH_POLICY_CONFIG"); path != "" {
+		policy, err := health.LoadConfig(path)
+		if err != nil {
+			log.Fatalf("invalid HEALTH_POLICY_CONFIG: %v", err)
+		}
+		controller.setConfig(func(cfg *runtimeConfig) { cfg.HealthPolicy = policy })
+		logger.Infof("Health policy loaded: %d default thresholds, %d policies", len(policy.Default), len(policy.Policies))
+	}
+	// Environment profiles too: a bad overlay would fail every create in
+	// its environment
+	if path := os.Getenv("SPEC_PROFILES_FILE"); path != "" {
+		profiles, err := loadSpecProfiles(path)
+		if err != nil {
+			log.Fatalf("invalid SPEC_PROFILES_FILE: %v", err)
+		}
+		controller.setConfig(func(cfg *runtimeConfig) { cfg.Profiles = profiles })
+		logger.Infof("Environment profiles loaded: %s", strings.Join(profiles.names(), ", "))
+	}
+	// And for vendor routing: a rule naming an unregistered vendor would
+	// fail every create it routes
+	if path := os.Getenv("VENDOR_ROUTING_FILE"); path != "" {
+		registered := make(map[string]bool)
+		for name := range controller.config().Providers {
+			registered[name] = true
+		}
+		routing, err := loadVendorRouting(path, registered)
+		if err != nil {
+			log.Fatalf("invalid VENDOR_ROUTING_FILE: %v", err)
+		}
+		controller.setConfig(func(cfg *runtimeConfig) { cfg.Routing = routing })
+		logger.Infof("Vendor routing loaded for %d namespaces", len(routing.Namespaces))
+	}
+	// API keys are optional; a malformed list is fatal so a typo can't
+	// silently lock admins out (or leave a key unusable)
+	if spec := os.Getenv("FORGE_API_KEYS"); spec != "" {
+		keys, err := parseAPIKeys(spec)
+		if err != nil {
+			log.Fatalf("invalid FORGE_API_KEYS: %v", err)
+		}
+		controller.setConfig(func(cfg *runtimeConfig) { cfg.APIKeys = keys })
+		logger.Infof("API keys configured for %d principals", len(keys))
+	}
+	redaction, err := loadRedactionPolicy(os.Getenv("REDACTION_POLICY_FILE"))
+	if err != nil {
+		log.Fatalf("invalid REDACTION_POLICY_FILE: %v", err)
+	}
+	controller.setConfig(func(cfg *runtimeConfig) { cfg.Redaction = redaction })
+	// Egress allowlists: a broken file must not leave the controller
+	// talking to anything (see egress.go)
+	if path := os.Getenv("EGRESS_POLICY_FILE"); path != "" {
+		registered := make(map[string]bool)
+		for name := range controller.config().Providers {
+			registered[name] = true
+		}
+		if os.Getenv("DNS_BACKEND") != "" {
+			registered[dns.EgressName] = true
+		}
+		policy, err := loadEgressPolicy(path, registered)
+		if err == nil {
+			err = client.SetEgressPolicy(policy)
+		}
+		if err != nil {
+			log.Fatalf("invalid EGRESS_POLICY_FILE: %v", err)
+		}
+		logger.Infof("Egress policy loaded for %s", strings.Join(policy.Providers(), ", "))
+	}
+	// Vendor credentials (<VENDOR>_AUTH, see signing.go); missing ones are
+	// fatal so they don't surface as a 401 on every call
+	if err := controller.configureSigners(secretsFromEnv()); err != nil {
+		log.Fatalf("invalid vendor credentials: %v", err)
+	}
+	// DNS registration (see dns.go); a bad backend config is fatal so
+	// devices don't silently go unregistered
+	registrar, err := loadDNSRegistrar(secretsFromEnv())
+	if err != nil {
+		log.Fatalf("invalid DNS configuration: %v", err)
+	}
+	if registrar != nil {
+		controller.DNS = registrar
+		logger.Infof("DNS registration: %s in zone %s", registrar.backend.Name(), registrar.zone)
+	}
+	// Stream previews (see preview.go); a gateway without its key is fatal
+	// rather than a 503 on every preview
+	preview, err := loadPreviewGateway(secretsFromEnv())
+	if err != nil {
+		log.Fatalf("invalid preview gateway: %v", err)
+	}
+	if preview != nil {
+		controller.Preview = preview
+		logger.Infof("Stream previews via %s", preview.baseURL)
+	}
+	// The store backend (see store.go); a log that can't be read is fatal
+	// so the controller never starts empty over existing state
+	replayed, err := controller.openStore(os.Getenv("STORE_BACKEND"), os.Getenv("STORE_EVENT_LOG"))
+	if err != nil {
+		log.Fatalf("invalid store: %v", err)
+	}
+	if controller.eventLog != nil {
+		defer controller.eventLog.Close()
+		logger.Infof("Event-sourced store %s: replayed %d events, %d resources", os.Getenv("STORE_EVENT_LOG"), replayed, len(controller.ResourceDB))
+		if resumed := controller.resumeScheduledDeletions(); resumed > 0 {
+			logger.Infof("Resumed %d deletions waiting for their grace period or finalizers", resumed)
+		}
+	}
+	// Schedules (see schedules.go); a bad SCHEDULE_* expression is fatal
+	// so a maintenance job doesn't silently stop running
+	if err := controller.initSchedules(); err != nil {
+		log.Fatalf("invalid schedule: %v", err)
+	}
+	controller.initReload(reloadState)
+	// WHY A SIGNAL CONTEXT: SIGTERM stops the background loops and the
+	// server, then notifications drain (see shutdown below)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	controller.startMemoryGuard(ctx, envDuration("MEMORY_CHECK_INTERVAL", 5*time.Second))
+	controller.startIdleAnalyzer(ctx)
+	controller.startCompactor(ctx)
+	controller.startReconciler(ctx)
+	controller.startMaintenance(ctx)
+	controller.startQueuedReplay(ctx)
+	controller.startDNS(ctx)
+	controller.startScheduler(ctx)
+	controller.startStoreSync(ctx, envDuration("STORE_SYNC_INTERVAL", time.Second))
+	// SIGHUP reloads the configuration (see reload.go)
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	go func() {
+		for range hangups {
+			controller.reloadConfig("SIGHUP", false)
+		}
+	}()
+	dispatcherDone := make(chan struct{})
+	if controller.Notifier != nil {
+		go func() {
+			controller.Notifier.Run(ctx)
+			close(dispatcherDone)
+		}()
+	} else {
+		close(dispatcherDone)
+	}
 
 	// Set up HTTP router
 	// WHY GORILLA MUX: Better than default http.ServeMux
@@ -346,18 +1614,68 @@ func main() {
 	// - Supports HTTP method filtering (.Methods("GET"))
 	// - More features for REST APIs
 	r := mux.NewRouter()
+	r.Use(controller.ForwardedMiddleware)
+	r.Use(controller.RequestIDMiddleware)
+	// WHY EARLY: Auth and rate limit errors are answered in YAML too
+	r.Use(controller.YAMLMiddleware)
+	r.Use(controller.AuthMiddleware)
+	r.Use(controller.RateLimitMiddleware)
+	r.Use(controller.StoreMiddleware)
+	r.Use(controller.LatencyMiddleware)
 
+	// Probes keep working without knowing the prefix or the version
+	r.HandleFunc("/health", controller.HandleHealthCheck).Methods("GET")
 
-	r.HandleFunc("/resources", controller.HandleCreateResource).Methods("POST") // create 
-	r.HandleFunc("/resources/{id}", controller.HandleGetResource).Methods("GET") // read
-	r.HandleFunc("/resources/{id}", controller.HandleDeleteResource).Methods("DELETE") // dete
-	r.HandleFunc("/health", controller.HandleHealthCheck).Methods("GET") // health check
+	// WHY A SUBROUTER: BASE_PATH (e.g. /api/forge) lets the controller
+	// sit behind a shared ingress gateway; see proxy.go
+	api := r
+	if controller.BasePath != "" {
+		api = r.PathPrefix(controller.BasePath).Subrouter()
+		api.HandleFunc("/health", controller.HandleHealthCheck).Methods("GET")
+	}
 
+	// WHY /v1: Breaking changes ship as a new version while old clients
+	// keep working; unversioned paths are deprecated aliases of the
+	// current version (see versioning.go)
+	api.HandleFunc("/versions", controller.HandleListVersions).Methods("GET")
+	api.HandleFunc("/version", controller.HandleGetVersion).Methods("GET")
+	versioned := api.PathPrefix("/" + currentAPIVersion).Subrouter()
+	versioned.Use(controller.versionMiddleware(false))
+	versioned.HandleFunc("/health", controller.HandleHealthCheck).Methods("GET")
+	controller.registerRoutes(versioned)
+	legacy := api.NewRoute().Subrouter()
+	legacy.Use(controller.versionMiddleware(true))
+	controller.registerRoutes(legacy)
 
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080" 
 	}
-	log.Printf("Controller listening on :%s", port)
-	log.Fatal(http.ListenAndServe(":"+port, r))
+	build := version.Get(supportedAPIVersions, currentAPIVersion)
+	logger.Infof("Controller %s (commit %s) listening on :%s%s", build.Version, build.Commit, port, controller.BasePath)
+	server := &http.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+	<-ctx.Done()
+	stop()
+
+	// Shutdown order: stop taking requests (in-flight ones finish, and
+	// may still record events), then deliver what the outbox holds
+	// WHY BOUNDED: An orchestrator kills the process after its own grace
+	// period anyway; whatever isn't delivered stays in NOTIFY_OUTBOX_FILE
+	timeout := envDuration("SHUTDOWN_TIMEOUT", 20*time.Second)
+	logger.Infof("Shutting down (up to %s)", timeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Warnf("HTTP shutdown: %v", err)
+	}
+	<-dispatcherDone
+	if controller.Notifier != nil {
+		controller.Notifier.Drain(shutdownCtx)
+	}
+	logger.Infof("Controller stopped")
 }
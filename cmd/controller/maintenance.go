@@ -0,0 +1,534 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// VENDOR MAINTENANCE WINDOWS
+// =============================================================================
+// Vendors announce maintenance ("Sony cloud, Tuesday 02:00-04:00 UTC").
+// Declaring the window tells the controller to expect trouble instead of
+// paging everyone about it:
+//
+//   MAINTENANCE_WINDOWS="sony@2026-10-20T02:00:00Z/2026-10-20T04:00:00Z"
+//   POST /admin/maintenance {"vendor": "sony", "start": ..., "end": ...}
+//
+// While a window is active, for that vendor:
+//
+//   alerts        PhaseChanged / HealthChanged / ConditionChanged events
+//                 are still recorded but not sent to notification channels
+//   reconciling   the status reconciler and idle analyzer skip it
+//   mutations     stop/start and recommendation applies are queued (202)
+//                 and applied in order once the window ends; ?urgent=true
+//                 goes through anyway. Rollouts wait before each update.
+//   status        resources carry a MaintenanceWindow condition
+//
+// Creates, deletes and live operations (PTZ, recordings) are not queued:
+// someone asking for them now needs them now, and a failure is visible.
+//
+// WHY A SEPARATE LOCK: recordEvent checks for maintenance with c.mu held,
+// so the windows have their own mutex (always taken after c.mu).
+// =============================================================================
+
+// Maintenance window states (computed from the clock).
+const (
+	maintenanceScheduled = "scheduled"
+	maintenanceActive    = "active"
+	maintenanceEnded     = "ended"
+)
+
+// Deferred mutation states.
+const (
+	deferredQueued  = "queued"
+	deferredApplied = "applied"
+	deferredFailed  = "failed"
+)
+
+// maintenanceRetention is how long ended windows and finished deferred
+// mutations stay listed.
+const maintenanceRetention = 24 * time.Hour
+
+// alertReasons are the event reasons suppressed during maintenance.
+var alertReasons = map[string]bool{
+	models.ReasonPhaseChanged:     true,
+	models.ReasonHealthChanged:    true,
+	models.ReasonConditionChanged: true,
+}
+
+// MaintenanceWindow is a declared vendor maintenance period.
+type MaintenanceWindow struct {
+	ID     string    `json:"id"`
+	Vendor string    `json:"vendor"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Reason string    `json:"reason,omitempty"`
+
+	// Source is "config" (MAINTENANCE_WINDOWS) or "api"
+	Source    string `json:"source"`
+	CreatedBy string `json:"created_by,omitempty"`
+
+	// State is maintenanceScheduled, maintenanceActive or maintenanceEnded
+	State string `json:"state"`
+
+	// SuppressedAlerts counts notifications not sent during the window
+	SuppressedAlerts int `json:"suppressed_alerts"`
+}
+
+// activeAt reports whether the window covers t.
+func (mw *MaintenanceWindow) activeAt(t time.Time) bool {
+	return !t.Before(mw.Start) && t.Before(mw.End)
+}
+
+// stateAt returns the window's state at t.
+func (mw *MaintenanceWindow) stateAt(t time.Time) string {
+	switch {
+	case t.Before(mw.Start):
+		return maintenanceScheduled
+	case t.Before(mw.End):
+		return maintenanceActive
+	}
+	return maintenanceEnded
+}
+
+// DeferredMutation is an operation queued until its vendor's maintenance
+// window ends.
+type DeferredMutation struct {
+	ID         string `json:"id"`
+	ResourceID string `json:"resource_id"`
+	Vendor     string `json:"vendor"`
+
+	// Operation is "stop", "start", "update", "rollback" or a device action
+	// ("restart", see actions.go)
+	Operation string `json:"operation"`
+
+	// WindowID is the maintenance window that deferred it
+	WindowID    string    `json:"window_id"`
+	RequestedBy string    `json:"requested_by,omitempty"`
+	QueuedAt    time.Time `json:"queued_at"`
+
+	State     string    `json:"state"`
+	AppliedAt time.Time `json:"applied_at,omitempty"`
+	Error     string    `json:"error,omitempty"`
+
+	// detail is appended to the event message when it is applied
+	detail string
+
+	// spec is the spec an "update" applies
+	spec *models.ResourceSpec
+}
+
+// maintenanceState holds windows and deferred mutations.
+type maintenanceState struct {
+	mu       sync.Mutex
+	windows  map[string]*MaintenanceWindow
+	deferred []*DeferredMutation
+}
+
+// newMaintenanceState creates the state with windows from MAINTENANCE_WINDOWS.
+func newMaintenanceState() *maintenanceState {
+	state := &maintenanceState{windows: make(map[string]*MaintenanceWindow)}
+	v := os.Getenv("MAINTENANCE_WINDOWS")
+	if v == "" {
+		return state
+	}
+	for i, entry := range strings.Split(v, ",") {
+		mw, err := parseMaintenanceWindow(strings.TrimSpace(entry))
+		if err != nil {
+			logger.Warnf("Ignoring MAINTENANCE_WINDOWS entry %q: %v", entry, err)
+			continue
+		}
+		mw.ID = fmt.Sprintf("mw-config-%d", i+1)
+		state.windows[mw.ID] = mw
+	}
+	return state
+}
+
+// parseMaintenanceWindow parses "vendor@start/end" (RFC 3339 times).
+func parseMaintenanceWindow(entry string) (*MaintenanceWindow, error) {
+	vendor, interval, ok := strings.Cut(entry, "@")
+	startValue, endValue, ok2 := strings.Cut(interval, "/")
+	if !ok || !ok2 || vendor == "" {
+		return nil, fmt.Errorf("want vendor@start/end")
+	}
+	start, err := time.Parse(time.RFC3339, startValue)
+	if err != nil {
+		return nil, fmt.Errorf("start: %v", err)
+	}
+	end, err := time.Parse(time.RFC3339, endValue)
+	if err != nil {
+		return nil, fmt.Errorf("end: %v", err)
+	}
+	if !end.After(start) {
+		return nil, fmt.Errorf("end must be after start")
+	}
+	return &MaintenanceWindow{Vendor: vendor, Start: start, End: end, Source: "config"}, nil
+}
+
+// activeMaintenance returns a copy of vendor's active window, or nil.
+func (c *Controller) activeMaintenance(vendor string) *MaintenanceWindow {
+	c.maintenance.mu.Lock()
+	defer c.maintenance.mu.Unlock()
+	if mw := c.activeMaintenanceLocked(vendor); mw != nil {
+		active := *mw
+		return &active
+	}
+	return nil
+}
+
+// activeMaintenanceLocked returns vendor's active window (the one ending
+// last if they overlap). Caller must hold c.maintenance.mu.
+func (c *Controller) activeMaintenanceLocked(vendor string) *MaintenanceWindow {
+	now := c.Clock.Now()
+	var found *MaintenanceWindow
+	for _, mw := range c.maintenance.windows {
+		if mw.Vendor == vendor && mw.activeAt(now) && (found == nil || mw.End.After(found.End)) {
+			found = mw
+		}
+	}
+	return found
+}
+
+// vendorsInMaintenance returns the vendors with an active window.
+func (c *Controller) vendorsInMaintenance() map[string]bool {
+	c.maintenance.mu.Lock()
+	defer c.maintenance.mu.Unlock()
+	now := c.Clock.Now()
+	vendors := make(map[string]bool)
+	for _, mw := range c.maintenance.windows {
+		if mw.activeAt(now) {
+			vendors[mw.Vendor] = true
+		}
+	}
+	return vendors
+}
+
+// suppressAlert reports whether an event's notification should be held
+// back because res's vendor is in maintenance, and counts it.
+func (c *Controller) suppressAlert(res *models.ForgeResource, reason string) bool {
+	if !alertReasons[reason] {
+		return false
+	}
+	c.maintenance.mu.Lock()
+	defer c.maintenance.mu.Unlock()
+	mw := c.activeMaintenanceLocked(res.Spec.VendorType)
+	if mw == nil {
+		return false
+	}
+	mw.SuppressedAlerts++
+	return true
+}
+
+// applyMaintenanceCondition sets or clears res's MaintenanceWindow
+// condition. Returns true if it changed. Caller must hold c.mu.
+//
+// WHY ON EVERY STATUS WRITE: Vendor reads replace the status wholesale and
+// know nothing about maintenance.
+func (c *Controller) applyMaintenanceCondition(res *models.ForgeResource) bool {
+	mw := c.activeMaintenance(res.Spec.VendorType)
+	existing := res.Status.Condition(models.ConditionMaintenanceWindow)
+	if mw == nil {
+		if existing == nil {
+			return false
+		}
+		conditions := res.Status.Conditions[:0:0]
+		for _, condition := range res.Status.Conditions {
+			if condition.Type != models.ConditionMaintenanceWindow {
+				conditions = append(conditions, condition)
+			}
+		}
+		res.Status.Conditions = conditions
+		return true
+	}
+
+	message := fmt.Sprintf("%s maintenance until %s", mw.Vendor, mw.End.UTC().Format(time.RFC3339))
+	if mw.Reason != "" {
+		message += ": " + mw.Reason
+	}
+	condition := models.Condition{Type: models.ConditionMaintenanceWindow, Status: models.ConditionTrue,
+		Reason: "VendorMaintenance", Message: message}
+	if existing != nil {
+		if *existing == condition {
+			return false
+		}
+		*existing = condition
+		return true
+	}
+	res.Status.Conditions = append(res.Status.Conditions, condition)
+	return true
+}
+
+// startMaintenance keeps maintenance conditions current and applies
+// deferred mutations once their window ends.
+func (c *Controller) startMaintenance(ctx context.Context) {
+	interval := envDuration("MAINTENANCE_CHECK_INTERVAL", 30*time.Second)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-c.Clock.After(interval):
+			}
+			c.syncMaintenance(ctx)
+		}
+	}()
+}
+
+// syncMaintenance annotates resources, releases deferred mutations for
+// vendors out of maintenance and forgets old windows.
+func (c *Controller) syncMaintenance(ctx context.Context) {
+	defer c.profileLoop("maintenance", time.Now())
+	// Step 1: Conditions
+	c.mu.Lock()
+	changed := 0
+	for _, res := range c.ResourceDB {
+		if c.applyMaintenanceCondition(res) {
+			res.UpdatedAt = c.Clock.Now()
+			c.recordRevision(res, "maintenance", false)
+			changed++
+		}
+	}
+	c.mu.Unlock()
+	if changed > 0 {
+		logger.Infof("Maintenance: updated the %s condition on %d resources", models.ConditionMaintenanceWindow, changed)
+	}
+
+	// Step 2: Deferred mutations whose vendor is out of maintenance, in order
+	inMaintenance := c.vendorsInMaintenance()
+	c.maintenance.mu.Lock()
+	var ready []*DeferredMutation
+	for _, m := range c.maintenance.deferred {
+		if m.State == deferredQueued && !inMaintenance[m.Vendor] {
+			ready = append(ready, m)
+		}
+	}
+	c.maintenance.mu.Unlock()
+	for _, m := range ready {
+		var err error
+		switch m.Operation {
+		case "update":
+			_, err = c.updateResourceSpec(ctx, m.ResourceID, *m.spec, "updated", m.detail)
+		case "rollback":
+			_, err = c.updateResourceSpec(ctx, m.ResourceID, *m.spec, "rolled-back", m.detail)
+		case "stop", "start":
+			_, err = c.setPower(ctx, m.ResourceID, m.Operation == "stop", m.detail)
+		default:
+			_, err = c.runAction(ctx, m.ResourceID, m.Operation, m.detail)
+		}
+		c.maintenance.mu.Lock()
+		m.AppliedAt = c.Clock.Now()
+		if err != nil {
+			m.State, m.Error = deferredFailed, err.Error()
+		} else {
+			m.State = deferredApplied
+		}
+		c.maintenance.mu.Unlock()
+		if err != nil {
+			logger.Warnf("Maintenance: deferred %s of %s failed: %v", m.Operation, m.ResourceID, err)
+		} else {
+			logger.Infof("Maintenance: applied deferred %s of %s", m.Operation, m.ResourceID)
+		}
+	}
+
+	// Step 3: Forget what's long over
+	cutoff := c.Clock.Now().Add(-maintenanceRetention)
+	c.maintenance.mu.Lock()
+	for id, mw := range c.maintenance.windows {
+		if mw.End.Before(cutoff) {
+			delete(c.maintenance.windows, id)
+		}
+	}
+	kept := c.maintenance.deferred[:0]
+	for _, m := range c.maintenance.deferred {
+		if m.State == deferredQueued || m.AppliedAt.After(cutoff) {
+			kept = append(kept, m)
+		}
+	}
+	c.maintenance.deferred = kept
+	c.maintenance.mu.Unlock()
+}
+
+// deferForMaintenance queues a stop/start (or an update to spec) of
+// resource id when its vendor is in maintenance and the request isn't
+// ?urgent=true, answering 202. Returns false (nothing written) if the
+// operation should run now.
+func (c *Controller) deferForMaintenance(w http.ResponseWriter, r *http.Request, id, operation, detail string, spec *models.ResourceSpec) bool {
+	if r.URL.Query().Get("urgent") == "true" {
+		return false
+	}
+	c.mu.RLock()
+	stored, exists := c.ResourceDB[id]
+	var vendor string
+	if exists {
+		vendor = stored.Spec.VendorType
+	}
+	c.mu.RUnlock()
+	if !exists {
+		return false
+	}
+
+	c.maintenance.mu.Lock()
+	mw := c.activeMaintenanceLocked(vendor)
+	if mw == nil {
+		c.maintenance.mu.Unlock()
+		return false
+	}
+	m := &DeferredMutation{
+		ID:         c.IDs.NewID("dm"),
+		ResourceID: id,
+		Vendor:     vendor,
+		Operation:  operation,
+		WindowID:   mw.ID,
+		QueuedAt:   c.Clock.Now(),
+		State:      deferredQueued,
+		detail:     detail + " (deferred by maintenance " + mw.ID + ")",
+		spec:       spec,
+	}
+	if principal, ok := principalFrom(r.Context()); ok {
+		m.RequestedBy = principal.Name
+	}
+	c.maintenance.deferred = append(c.maintenance.deferred, m)
+	response := *m
+	end := mw.End
+	c.maintenance.mu.Unlock()
+
+	logger.Infof("Maintenance: queued %s of %s until %s", operation, id, end.UTC().Format(time.RFC3339))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(response)
+	return true
+}
+
+// waitOutMaintenance blocks while vendor is in maintenance (rollouts use
+// it before each update). Returns ctx's error if it ends first.
+func (c *Controller) waitOutMaintenance(ctx context.Context, vendor string) error {
+	for {
+		mw := c.activeMaintenance(vendor)
+		if mw == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.Clock.After(mw.End.Sub(c.Clock.Now())):
+		}
+	}
+}
+
+// MaintenanceRequest is the body accepted by POST /admin/maintenance.
+type MaintenanceRequest struct {
+	Vendor string    `json:"vendor"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// HandleListMaintenance handles GET /admin/maintenance
+// Lists windows (soonest first) and deferred mutations (oldest first).
+func (c *Controller) HandleListMaintenance(w http.ResponseWriter, r *http.Request) {
+	now := c.Clock.Now()
+	c.maintenance.mu.Lock()
+	windows := make([]MaintenanceWindow, 0, len(c.maintenance.windows))
+	for _, mw := range c.maintenance.windows {
+		item := *mw
+		item.State = mw.stateAt(now)
+		windows = append(windows, item)
+	}
+	deferred := make([]DeferredMutation, 0, len(c.maintenance.deferred))
+	for _, m := range c.maintenance.deferred {
+		deferred = append(deferred, *m)
+	}
+	c.maintenance.mu.Unlock()
+
+	sort.Slice(windows, func(i, j int) bool {
+		if !windows[i].Start.Equal(windows[j].Start) {
+			return windows[i].Start.Before(windows[j].Start)
+		}
+		return windows[i].ID < windows[j].ID
+	})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": windows, "deferred": deferred})
+}
+
+// HandleCreateMaintenance handles POST /admin/maintenance
+func (c *Controller) HandleCreateMaintenance(w http.ResponseWriter, r *http.Request) {
+	cfg := c.config()
+	var req MaintenanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+
+	// Step 1: Validate
+	var msg string
+	switch {
+	case req.Vendor == "":
+		msg = "vendor is required"
+	case cfg.Providers[req.Vendor] == nil:
+		msg = "unknown vendor " + req.Vendor + " (configured: " + strings.Join(sortedKeys(cfg.Providers), ", ") + ")"
+	case req.Start.IsZero() || req.End.IsZero():
+		msg = "start and end are required (RFC 3339)"
+	case !req.End.After(req.Start):
+		msg = "end must be after start"
+	case !req.End.After(c.Clock.Now()):
+		msg = "end is in the past"
+	}
+	if msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": msg})
+		return
+	}
+
+	// Step 2: Store
+	mw := &MaintenanceWindow{
+		ID:     c.IDs.NewID("mw"),
+		Vendor: req.Vendor,
+		Start:  req.Start,
+		End:    req.End,
+		Reason: req.Reason,
+		Source: "api",
+	}
+	if principal, ok := principalFrom(r.Context()); ok {
+		mw.CreatedBy = principal.Name
+	}
+	c.maintenance.mu.Lock()
+	c.maintenance.windows[mw.ID] = mw
+	response := *mw
+	c.maintenance.mu.Unlock()
+	response.State = response.stateAt(c.Clock.Now())
+
+	logger.Infof("Maintenance window %s declared for %s: %s to %s", mw.ID, mw.Vendor,
+		mw.Start.UTC().Format(time.RFC3339), mw.End.UTC().Format(time.RFC3339))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleDeleteMaintenance handles DELETE /admin/maintenance/{id}
+// Cancels a window; deferred mutations are applied on the next check.
+func (c *Controller) HandleDeleteMaintenance(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	c.maintenance.mu.Lock()
+	_, exists := c.maintenance.windows[id]
+	delete(c.maintenance.windows, id)
+	c.maintenance.mu.Unlock()
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "maintenance window not found"})
+		return
+	}
+	logger.Infof("Maintenance window %s cancelled", id)
+	w.WriteHeader(http.StatusNoContent)
+}
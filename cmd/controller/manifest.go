@@ -0,0 +1,428 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/fanout"
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/Zhichengu1/mock-control-plane/pkg/validation"
+)
+
+// =============================================================================
+// MULTI-DOCUMENT APPLY (POST /apply)
+// =============================================================================
+// An event's whole device configuration lives in one manifest: a JSON
+// array of resources, or a YAML stream with one resource per document:
+//
+//   curl -X POST /v1/apply -H 'Content-Type: application/yaml' --data-binary @event-42.yaml
+//
+//   name: cam-1
+//   namespace: event-42
+//   type: camera
+//   spec: {vendor_type: sony, resolution: 1080p}
+//   ---
+//   name: enc-1
+//   namespace: event-42
+//   type: encoder
+//   depends_on: [cam-1]
+//   spec: {vendor_type: sony, bitrate: 8000000}
+//
+// Each document is applied exactly like POST /resources:apply (created,
+// updated or unchanged, looked up by namespace and name).
+//
+// DEPENDENCY ORDER: depends_on and owner_ref take IDs, which a manifest
+// can't know for resources it creates. So an entry naming another
+// document of the manifest (same namespace) refers to it, and is replaced
+// by its ID once that document is applied; other entries are IDs as
+// usual. The documents are applied in waves:
+//
+//   wave 1: documents that refer to no other document (in parallel)
+//   wave 2: documents whose references were all applied in wave 1
+//   ...
+//
+// A document whose reference failed (or ended up in phase Failed) is
+// skipped, since it would be created against a broken or missing
+// dependency; documents in a reference cycle are skipped too. Everything
+// else goes ahead (continue-on-error). ?parallelism= bounds applies in
+// flight within a wave (default 4, max 32).
+//
+// The response reports every document in manifest order:
+//
+//   {"items": [{"index": 0, "name": "cam-1", "status": "created", "wave": 1, "id": "res-..."},
+//              {"index": 1, "name": "enc-1", "status": "skipped", "error": "depends on cam-1 (failed)"}],
+//    "created": 1, "skipped": 1, "waves": 1, "duration_ms": 910}
+//
+// An update deferred by a maintenance window or queued behind a vendor
+// outage is "accepted", with the deferred or queued call as its response.
+//
+// WHY NOT ATOMIC: Vendor calls can't be rolled back; the report says
+// what happened, and applying the same manifest again is harmless.
+// =============================================================================
+
+// Manifest apply item outcomes (besides appliedCreated, appliedUpdated,
+// appliedUnchanged, batchFailed and batchSkipped).
+const appliedAccepted = "accepted"
+
+// ManifestApplyItem is the outcome for one document of the manifest.
+type ManifestApplyItem struct {
+	// Index is the document's position in the manifest
+	Index     int    `json:"index"`
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Status    string `json:"status"`
+
+	// Wave is the 1-based wave the document was applied in (0: skipped)
+	Wave       int    `json:"wave,omitempty"`
+	HTTPStatus int    `json:"http_status,omitempty"`
+	ID         string `json:"id,omitempty"`
+	Error      string `json:"error,omitempty"`
+
+	// Violations are set when the document failed validation
+	Violations validation.Violations `json:"violations,omitempty"`
+
+	// Resource is the resource as applied; Response is the deferred or
+	// queued call of an accepted update
+	Resource *models.ForgeResource `json:"resource,omitempty"`
+	Response json.RawMessage       `json:"response,omitempty"`
+}
+
+// ManifestApplyReport is the response of POST /apply.
+type ManifestApplyReport struct {
+	Items      []ManifestApplyItem `json:"items"`
+	Created    int                 `json:"created"`
+	Updated    int                 `json:"updated"`
+	Unchanged  int                 `json:"unchanged"`
+	Accepted   int                 `json:"accepted"`
+	Failed     int                 `json:"failed"`
+	Skipped    int                 `json:"skipped"`
+	Waves      int                 `json:"waves"`
+	DurationMS int64               `json:"duration_ms"`
+}
+
+// manifestDocument is one document with its references to others.
+type manifestDocument struct {
+	resource models.ForgeResource
+
+	// namespace is the one it is applied in (the route's if it has none)
+	namespace string
+
+	// refs are the indexes of the documents it refers to
+	refs []int
+}
+
+// HandleApplyManifest handles POST /apply
+func (c *Controller) HandleApplyManifest(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Step 1: Decode the manifest (a YAML stream arrives as an array, see
+	// yaml.go)
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBatchCreateBodyBytes))
+	if err != nil {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	var resources []models.ForgeResource
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &resources)
+	} else {
+		// A single document is a manifest of one
+		var single models.ForgeResource
+		if err = json.Unmarshal(body, &single); err == nil {
+			resources = []models.ForgeResource{single}
+		}
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+	if len(resources) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "the manifest has no resources"})
+		return
+	}
+	if len(resources) > maxBatchCreateItems {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("at most %d resources per manifest", maxBatchCreateItems)})
+		return
+	}
+	parallelism := defaultBatchParallelism
+	if r.URL.Query().Has("parallelism") {
+		if parallelism, err = strconv.Atoi(r.URL.Query().Get("parallelism")); err != nil || parallelism < 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "parallelism must be a positive integer"})
+			return
+		}
+	}
+	if parallelism > maxBatchParallelism {
+		parallelism = maxBatchParallelism
+	}
+
+	// Step 2: Resolve references between documents
+	docs, err := manifestDocuments(resources, routeNamespace(r))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	// Step 3: Apply in waves
+	started := c.Clock.Now()
+	report := c.applyManifest(r, docs, parallelism)
+	report.DurationMS = c.Clock.Since(started).Milliseconds()
+	logger.Infof("Manifest apply: %d created, %d updated, %d unchanged, %d accepted, %d failed, %d skipped in %d waves",
+		report.Created, report.Updated, report.Unchanged, report.Accepted, report.Failed, report.Skipped, report.Waves)
+	// WHY ONLY ANNOUNCE: Each document's resource was redacted by the
+	// apply that answered it
+	c.redactionFor(r).announce(w)
+	json.NewEncoder(w).Encode(report)
+}
+
+// manifestDocuments pairs each resource with the documents its depends_on
+// and owner_ref name. Two documents with the same namespace and name are
+// an error: references to them would be ambiguous.
+func manifestDocuments(resources []models.ForgeResource, routeNS string) ([]*manifestDocument, error) {
+	docs := make([]*manifestDocument, len(resources))
+	byName := make(map[string]int, len(resources))
+	for i, res := range resources {
+		namespace := res.Namespace
+		if namespace == "" {
+			namespace = routeNS
+		}
+		docs[i] = &manifestDocument{resource: res, namespace: namespace}
+		if res.Name == "" {
+			continue
+		}
+		key := namespaceKey(namespace) + "/" + res.Name
+		if first, dup := byName[key]; dup {
+			return nil, fmt.Errorf("documents %d and %d are both %q in namespace %q", first, i, res.Name, namespaceKey(namespace))
+		}
+		byName[key] = i
+	}
+	for i, doc := range docs {
+		refs := append(append([]string(nil), doc.resource.DependsOn...), doc.resource.OwnerRef)
+		seen := make(map[int]bool)
+		for _, ref := range refs {
+			target, ok := byName[namespaceKey(doc.namespace)+"/"+ref]
+			if ref == "" || !ok || seen[target] {
+				continue
+			}
+			if target == i {
+				return nil, fmt.Errorf("document %d (%q) refers to itself", i, doc.resource.Name)
+			}
+			seen[target] = true
+			doc.refs = append(doc.refs, target)
+		}
+	}
+	return docs, nil
+}
+
+// applyManifest applies docs in dependency waves.
+func (c *Controller) applyManifest(r *http.Request, docs []*manifestDocument, parallelism int) *ManifestApplyReport {
+	results := make([]*ManifestApplyItem, len(docs))
+	pending := make(map[int]bool, len(docs))
+	for i := range docs {
+		pending[i] = true
+	}
+
+	// Step 1: A document is ready once everything it refers to is applied;
+	// it's skipped once one of them failed
+	wave := 0
+	for len(pending) > 0 {
+		var ready []int
+		progressed := false
+		for i := range docs {
+			if !pending[i] {
+				continue
+			}
+			blocker, waiting := -1, false
+			for _, ref := range docs[i].refs {
+				switch {
+				case pending[ref]:
+					waiting = true
+				case results[ref].Status == batchFailed || results[ref].Status == batchSkipped:
+					blocker = ref
+				}
+				if blocker >= 0 {
+					break
+				}
+			}
+			switch {
+			case blocker >= 0:
+				results[i] = docs[i].item(i, batchSkipped)
+				results[i].Error = fmt.Sprintf("depends on %s (%s)", docs[blocker].resource.Name, results[blocker].Status)
+				delete(pending, i)
+				progressed = true
+			case !waiting:
+				ready = append(ready, i)
+			}
+		}
+
+		if len(ready) == 0 {
+			if progressed {
+				continue
+			}
+			// Only a reference cycle can leave everything waiting
+			for i := range docs {
+				if pending[i] {
+					results[i] = docs[i].item(i, batchSkipped)
+					results[i].Error = "dependency cycle"
+				}
+			}
+			break
+		}
+
+		// Step 2: References become the IDs of the documents applied
+		wave++
+		for _, i := range ready {
+			docs[i].resolve(docs, results)
+		}
+		branches, _ := fanout.Map(r.Context(), ready, fanout.Options{Limit: parallelism}, func(_ context.Context, i int) (*ManifestApplyItem, error) {
+			return c.applyDocument(r, docs[i], i), nil
+		})
+		for n, branch := range branches {
+			i := ready[n]
+			item := branch.Value
+			if branch.Err != nil {
+				item = docs[i].item(i, batchFailed)
+				item.Error = branch.Err.Error()
+			}
+			item.Wave = wave
+			results[i] = item
+			delete(pending, i)
+		}
+	}
+
+	// Step 3: Report in manifest order
+	report := &ManifestApplyReport{Items: make([]ManifestApplyItem, len(results)), Waves: wave}
+	for i, item := range results {
+		report.Items[i] = *item
+		switch item.Status {
+		case appliedCreated:
+			report.Created++
+		case appliedUpdated:
+			report.Updated++
+		case appliedUnchanged:
+			report.Unchanged++
+		case appliedAccepted:
+			report.Accepted++
+		case batchFailed:
+			report.Failed++
+		case batchSkipped:
+			report.Skipped++
+		}
+	}
+	return report
+}
+
+// item starts the report item of document index.
+func (d *manifestDocument) item(index int, status string) *ManifestApplyItem {
+	return &ManifestApplyItem{Index: index, Name: d.resource.Name, Namespace: d.namespace, Status: status}
+}
+
+// resolve replaces the references to other documents by their IDs.
+func (d *manifestDocument) resolve(docs []*manifestDocument, results []*ManifestApplyItem) {
+	for _, ref := range d.refs {
+		name, id := docs[ref].resource.Name, results[ref].ID
+		for n, dep := range d.resource.DependsOn {
+			if dep == name {
+				d.resource.DependsOn[n] = id
+			}
+		}
+		if d.resource.OwnerRef == name {
+			d.resource.OwnerRef = id
+		}
+	}
+}
+
+// applyDocument applies one document through POST /resources:apply and
+// turns its response into a report item.
+// WHY THE HANDLER: An apply may answer from several places (created,
+// deferred, queued, refused by a lock); going through it keeps every
+// document exactly as if it had been applied alone
+func (c *Controller) applyDocument(r *http.Request, doc *manifestDocument, index int) *ManifestApplyItem {
+	item := doc.item(index, batchFailed)
+	payload, err := json.Marshal(doc.resource)
+	if err != nil {
+		item.Error = err.Error()
+		return item
+	}
+	sub := r.Clone(r.Context())
+	sub.Body = io.NopCloser(bytes.NewReader(payload))
+	sub.ContentLength = int64(len(payload))
+	sub.Header.Set("Content-Type", "application/json")
+	// WHY: Preconditions name one resource's version; they can't hold for
+	// every document
+	sub.Header.Del("If-Match")
+	sub.Header.Del("If-None-Match")
+	recorder := &bufferedResponse{header: make(http.Header)}
+	c.HandleApplyResource(recorder, sub)
+
+	item.HTTPStatus = recorder.status
+	outcome := recorder.header.Get(HeaderApplied)
+	switch {
+	case recorder.status >= 300:
+		var failure struct {
+			Error      string                `json:"error"`
+			Violations validation.Violations `json:"violations"`
+		}
+		if json.Unmarshal(recorder.body.Bytes(), &failure) != nil || failure.Error == "" {
+			failure.Error = strings.TrimSpace(recorder.body.String())
+		}
+		item.Error, item.Violations = failure.Error, failure.Violations
+	case outcome == "":
+		item.Status = appliedAccepted
+		item.Response = json.RawMessage(bytes.TrimSpace(recorder.body.Bytes()))
+	default:
+		item.Status = outcome
+		var res models.ForgeResource
+		if json.Unmarshal(recorder.body.Bytes(), &res) == nil && res.ID != "" {
+			item.Resource = &res
+			if res.Status.Phase == "Failed" {
+				item.Status, item.Error = batchFailed, res.Status.Message
+			}
+		}
+	}
+
+	// The ID dependents need, also for accepted updates (whose response
+	// is the call, not the resource)
+	c.mu.RLock()
+	for _, res := range c.ResourceDB {
+		if res.Name == doc.resource.Name && namespaceKey(res.Namespace) == namespaceKey(doc.namespace) {
+			item.ID = res.ID
+			break
+		}
+	}
+	c.mu.RUnlock()
+	return item
+}
+
+// bufferedResponse holds one document's response.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header { return b.header }
+
+func (b *bufferedResponse) WriteHeader(code int) {
+	if b.status == 0 {
+		b.status = code
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// MASS DELETE GUARD
+// =============================================================================
+// A bulk script with a wrong filter ("namespace=prod" instead of
+// "namespace=prod-test") tears down a live show in seconds. The guard
+// counts the deletes and stops each caller (API key principal, else
+// client IP) made in the last MASS_DELETE_WINDOW, including what a delete
+// takes along (owned resources, include_dependents). A request that would
+// go over a limit isn't carried out:
+//
+//   MASS_DELETE_MAX           more than this many resources (0 = no limit)
+//   MASS_DELETE_MAX_PERCENT   more than this share of a namespace (0 = no
+//                             limit; only from 3 resources on)
+//
+// MASS_DELETE_ACTION says what happens then:
+//
+//   confirm  428 with a confirm_token; repeating the same request with
+//            "Forge-Confirm: <token>" carries it out (default)
+//   block    429 with Retry-After, until the window has room again
+//
+// The token is single-use, expires after MASS_DELETE_CONFIRM_TTL and
+// only confirms the same operation by the same caller on exactly the
+// same resources. A filter that matches something else on the replay
+// gets a new challenge.
+//
+// WHY NOT PER REQUEST: A script deleting one resource per request is the
+// usual way to do damage, so the window spans requests.
+// WHY 3: Deleting the last resource of a namespace is 100% of it; the
+// share only means something for larger deletes.
+// =============================================================================
+
+// Mass delete actions.
+const (
+	massDeleteConfirm = "confirm"
+	massDeleteBlock   = "block"
+)
+
+// Guarded operations.
+const (
+	massOpDelete = "delete"
+	massOpStop   = "stop"
+)
+
+// minPercentGuardCount is the fewest resources the percent limit applies to.
+const minPercentGuardCount = 3
+
+// confirmHeader carries a confirm token on the repeated request.
+const confirmHeader = "Forge-Confirm"
+
+// MassDeletePolicy limits how many resources one caller deletes or stops
+// within Window.
+type MassDeletePolicy struct {
+	MaxResources int           `json:"max_resources"`
+	MaxPercent   float64       `json:"max_percent"`
+	Window       time.Duration `json:"-"`
+	Action       string        `json:"action"`
+	ConfirmTTL   time.Duration `json:"-"`
+}
+
+// enabled reports whether any limit is set.
+func (p MassDeletePolicy) enabled() bool {
+	return p.MaxResources > 0 || p.MaxPercent > 0
+}
+
+// loadMassDeletePolicy reads the MASS_DELETE_* variables.
+func loadMassDeletePolicy() MassDeletePolicy {
+	policy := MassDeletePolicy{
+		MaxResources: envInt("MASS_DELETE_MAX", 0),
+		MaxPercent:   envFloat("MASS_DELETE_MAX_PERCENT", 0),
+		Window:       envDuration("MASS_DELETE_WINDOW", 5*time.Minute),
+		Action:       massDeleteConfirm,
+		ConfirmTTL:   envDuration("MASS_DELETE_CONFIRM_TTL", 5*time.Minute),
+	}
+	switch action := os.Getenv("MASS_DELETE_ACTION"); action {
+	case "", massDeleteConfirm:
+	case massDeleteBlock:
+		policy.Action = action
+	default:
+		logger.Warnf("Ignoring MASS_DELETE_ACTION %q: action must be %s or %s", action, massDeleteConfirm, massDeleteBlock)
+	}
+	return policy
+}
+
+// massDeleteEntry is one guarded resource in a caller's window.
+type massDeleteEntry struct {
+	at        time.Time
+	id        string
+	namespace string
+}
+
+// massDeleteChallenge is an outstanding confirm token.
+type massDeleteChallenge struct {
+	caller      string
+	fingerprint string
+	expires     time.Time
+}
+
+// massDeleteState holds the callers' windows and the confirm tokens.
+type massDeleteState struct {
+	mu         sync.Mutex
+	recent     map[string][]massDeleteEntry
+	challenges map[string]*massDeleteChallenge
+}
+
+func newMassDeleteState() *massDeleteState {
+	return &massDeleteState{
+		recent:     make(map[string][]massDeleteEntry),
+		challenges: make(map[string]*massDeleteChallenge),
+	}
+}
+
+// MassDeleteChallenge is the body of a 428 (or 429) from the guard.
+type MassDeleteChallenge struct {
+	Error string `json:"error"`
+
+	// ConfirmToken goes in the Forge-Confirm header of the repeated
+	// request (confirm action only)
+	ConfirmToken string     `json:"confirm_token,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+
+	Operation string   `json:"operation"`
+	Count     int      `json:"count"`
+	Resources []string `json:"resources"`
+	Window    string   `json:"window"`
+}
+
+// callerKey identifies who a guarded request counts against.
+func callerKey(r *http.Request) string {
+	if principal, ok := principalFrom(r.Context()); ok {
+		return "principal:" + principal.Name
+	}
+	return "ip:" + clientKey(r)
+}
+
+// guardMassDelete checks a delete or stop of ids by the request's caller
+// against the policy. false means go ahead (the resources now count
+// against the caller; refundMassDelete takes back the ones that then
+// fail); true that it wrote a challenge or refusal.
+func (c *Controller) guardMassDelete(w http.ResponseWriter, r *http.Request, operation string, ids []string) bool {
+	policy := c.config().MassDelete
+	if !policy.enabled() || len(ids) == 0 {
+		return false
+	}
+	caller := callerKey(r)
+	now := c.Clock.Now()
+
+	state := c.massDelete
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	for token, challenge := range state.challenges {
+		if !now.Before(challenge.expires) {
+			delete(state.challenges, token)
+		}
+	}
+	// WHY EVERY CALLER: One who never comes back would stay forever
+	for key, entries := range state.recent {
+		kept := entries[:0]
+		for _, entry := range entries {
+			if now.Sub(entry.at) < policy.Window {
+				kept = append(kept, entry)
+			}
+		}
+		if len(kept) == 0 {
+			delete(state.recent, key)
+		} else {
+			state.recent[key] = kept
+		}
+	}
+	window := state.recent[caller]
+
+	// Step 1: Look up the namespaces involved and their sizes
+	counted := make(map[string]string)
+	for _, entry := range window {
+		counted[entry.id] = entry.namespace
+	}
+	namespaces := make(map[string]string, len(ids))
+	sizes := make(map[string]int)
+	c.mu.RLock()
+	// WHY ONLY EXISTING ONES: The handler reports the rest as not found
+	present := ids[:0:0]
+	for _, id := range ids {
+		if res, exists := c.ResourceDB[id]; exists {
+			namespaces[id] = namespaceKey(res.Namespace)
+			sizes[namespaces[id]] = 0
+			present = append(present, id)
+		}
+	}
+	ids = present
+	for _, res := range c.ResourceDB {
+		if _, involved := sizes[namespaceKey(res.Namespace)]; involved {
+			sizes[namespaceKey(res.Namespace)]++
+		}
+	}
+	for id, namespace := range counted {
+		// WHY: Resources already gone were part of the namespace too
+		if _, involved := sizes[namespace]; involved && c.ResourceDB[id] == nil {
+			sizes[namespace]++
+		}
+	}
+	c.mu.RUnlock()
+
+	if len(ids) == 0 {
+		return false
+	}
+
+	// Step 2: Count distinct resources, in the window and this request
+	for _, id := range ids {
+		counted[id] = namespaces[id]
+	}
+	reason := ""
+	if policy.MaxResources > 0 && len(counted) > policy.MaxResources {
+		reason = fmt.Sprintf("%d resources within %s exceeds the limit of %d", len(counted), policy.Window, policy.MaxResources)
+	}
+	if reason == "" && policy.MaxPercent > 0 {
+		perNamespace := make(map[string]int)
+		for _, namespace := range counted {
+			perNamespace[namespace]++
+		}
+		for _, namespace := range sortedKeys(sizes) {
+			count, size := perNamespace[namespace], sizes[namespace]
+			if percent := float64(count) * 100 / float64(size); count >= minPercentGuardCount && percent > policy.MaxPercent {
+				reason = fmt.Sprintf("%d of %d resources in namespace %s (%.0f%%) within %s exceeds the limit of %g%%",
+					count, size, namespace, percent, policy.Window, policy.MaxPercent)
+				break
+			}
+		}
+	}
+
+	// Step 3: Over a limit: refuse, or challenge unless confirmed
+	if reason != "" {
+		sorted := append([]string(nil), ids...)
+		sort.Strings(sorted)
+		sum := sha256.Sum256([]byte(operation + "\n" + strings.Join(sorted, ",")))
+		fingerprint := hex.EncodeToString(sum[:])
+		token := r.Header.Get(confirmHeader)
+		challenge, found := state.challenges[token]
+		if found && policy.Action == massDeleteConfirm && challenge.caller == caller && challenge.fingerprint == fingerprint {
+			delete(state.challenges, token)
+			logger.Warnf("Mass %s confirmed by %s: %s", operation, caller, reason)
+		} else {
+			body := MassDeleteChallenge{
+				Operation: operation,
+				Count:     len(ids),
+				Resources: sorted,
+				Window:    policy.Window.String(),
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if policy.Action == massDeleteBlock {
+				retryAfter := policy.Window
+				if len(window) > 0 {
+					retryAfter = policy.Window - now.Sub(window[0].at)
+				}
+				body.Error = fmt.Sprintf("mass %s guard: %s; try again later", operation, reason)
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				w.WriteHeader(http.StatusTooManyRequests)
+			} else {
+				token := newConfirmToken()
+				expires := now.Add(policy.ConfirmTTL)
+				state.challenges[token] = &massDeleteChallenge{caller: caller, fingerprint: fingerprint, expires: expires}
+				body.Error = fmt.Sprintf("mass %s guard: %s; repeat the request with the %s header to proceed", operation, reason, confirmHeader)
+				body.ConfirmToken, body.ExpiresAt = token, &expires
+				w.WriteHeader(http.StatusPreconditionRequired)
+			}
+			logger.Warnf("Mass %s by %s held: %s", operation, caller, reason)
+			json.NewEncoder(w).Encode(body)
+			return true
+		}
+	}
+
+	// Step 4: Count them against the caller
+	// WHY BEFORE THE OPERATION: Concurrent requests from the same caller
+	// would each pass the check otherwise
+	for _, id := range ids {
+		window = append(window, massDeleteEntry{at: now, id: id, namespace: namespaces[id]})
+	}
+	state.recent[caller] = window
+	return false
+}
+
+// refundMassDelete takes ids the request's caller was charged for by
+// guardMassDelete back out of its window, for operations that failed
+// (locked, precondition, finalizers, vendor errors): a caller shouldn't be
+// held for deletes that never happened.
+func (c *Controller) refundMassDelete(r *http.Request, ids []string) {
+	if !c.config().MassDelete.enabled() || len(ids) == 0 {
+		return
+	}
+	caller := callerKey(r)
+	state := c.massDelete
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	window := state.recent[caller]
+	for _, id := range ids {
+		// WHY THE LAST ONE: That's the charge this request added
+		for i := len(window) - 1; i >= 0; i-- {
+			if window[i].id == id {
+				window = append(window[:i], window[i+1:]...)
+				break
+			}
+		}
+	}
+	if len(window) == 0 {
+		delete(state.recent, caller)
+	} else {
+		state.recent[caller] = window
+	}
+}
+
+// newConfirmToken returns an unguessable confirm token.
+func newConfirmToken() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return "confirm-" + hex.EncodeToString(buf)
+}
+
+// deleteTargets returns ids and what deleting them takes along: owned
+// resources and, with includeDependents, dependents (as batchDelete does).
+func (c *Controller) deleteTargets(ids []string, includeDependents bool) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	seen := make(map[string]bool)
+	var targets []string
+	queue := append([]string(nil), ids...)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if _, exists := c.ResourceDB[id]; seen[id] || !exists {
+			continue
+		}
+		seen[id] = true
+		targets = append(targets, id)
+		if includeDependents {
+			queue = append(queue, c.dependentsLocked(id)...)
+		}
+		queue = append(queue, c.ownedLocked(id)...)
+	}
+	return targets
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// RESOURCE METADATA (NOTES, RUNBOOKS AND LABELS)
+// =============================================================================
+// metadata.notes and metadata.runbook_url give on-call engineers context
+// next to device state: "spare body, swap with cam-4", a link to the
+// procedure. They are Forge-only, so PATCH (see patch.go) changes them
+// without a vendor Update; a note can be added while the vendor is down or
+// in maintenance.
+//
+//   PATCH /resources/{id}
+//   {"metadata": {"notes": "...", "runbook_url": null}}
+//
+// Labels (see pkg/labels) and annotations are patched the same way, key
+// by key:
+//
+//   {"labels": {"site": "stadium-a", "retired": null},
+//    "annotations": {"ticket": "OPS-4411"}}
+//
+// Annotations are not for selecting: they carry what integrators need to
+// find again (ticket IDs, run-sheet references) and are passed to the
+// vendor with the device where it supports metadata (Sony). A PATCH of
+// annotations alone doesn't call the vendor; the vendor's copy is
+// refreshed on the next create or update.
+//
+// Every change is a revision and a MetadataUpdated event naming who made it.
+// =============================================================================
+
+// metadataFields are the metadata fields PATCH can set.
+var metadataFields = []string{"notes", "runbook_url"}
+
+// stampMetadata records who set meta and when (on create), or clears the
+// stamp if meta is empty. Client-sent stamps are never kept.
+func (c *Controller) stampMetadata(r *http.Request, meta *models.ResourceMetadata, now time.Time) {
+	meta.UpdatedBy, meta.UpdatedAt = "", nil
+	if meta.IsZero() {
+		return
+	}
+	meta.UpdatedAt = &now
+	if principal, ok := principalFrom(r.Context()); ok {
+		meta.UpdatedBy = principal.Name
+	}
+}
+
+// parseMetadataPatch applies the metadata member of a merge patch to a
+// copy of meta. Returns the patched metadata and the fields it changes.
+func parseMetadataPatch(raw json.RawMessage, meta models.ResourceMetadata) (models.ResourceMetadata, []string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil || fields == nil {
+		return meta, nil, errors.New("metadata must be an object")
+	}
+	targets := map[string]*string{"notes": &meta.Notes, "runbook_url": &meta.RunbookURL}
+	var changed []string
+	for key, value := range fields {
+		target, ok := targets[key]
+		if !ok {
+			return meta, nil, fmt.Errorf("metadata.%s cannot be patched (patchable: %s)", key, strings.Join(metadataFields, ", "))
+		}
+		next := ""
+		if !bytes.Equal(bytes.TrimSpace(value), []byte("null")) {
+			if err := json.Unmarshal(value, &next); err != nil {
+				return meta, nil, fmt.Errorf("metadata.%s must be a string or null", key)
+			}
+		}
+		if next != *target {
+			*target = next
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return meta, changed, nil
+}
+
+// parseStringMapPatch applies the labels or annotations (field) member of
+// a merge patch to a copy of current: a string sets a key, null removes
+// it. Returns the patched map and the keys it changes. Keys and values are
+// validated by the caller (validation.ValidateLabels, ValidateAnnotations).
+func parseStringMapPatch(field string, raw json.RawMessage, current map[string]string) (map[string]string, []string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil || fields == nil {
+		return current, nil, fmt.Errorf("%s must be an object", field)
+	}
+	patched := make(map[string]string, len(current)+len(fields))
+	for key, value := range current {
+		patched[key] = value
+	}
+	var changed []string
+	for key, value := range fields {
+		old, set := patched[key]
+		if bytes.Equal(bytes.TrimSpace(value), []byte("null")) {
+			if set {
+				delete(patched, key)
+				changed = append(changed, key)
+			}
+			continue
+		}
+		var next string
+		if err := json.Unmarshal(value, &next); err != nil {
+			return current, nil, fmt.Errorf("%s.%s must be a string or null", field, key)
+		}
+		if !set || next != old {
+			patched[key] = next
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	if len(patched) == 0 {
+		patched = nil
+	}
+	return patched, changed, nil
+}
+
+// storeMetadata saves the patched metadata, labels, annotations,
+// finalizers and owner on resource id,
+// with a revision and a MetadataUpdated event naming the changed fields.
+// Returns false if the resource no longer exists.
+func (c *Controller) storeMetadata(r *http.Request, id string, patched patchResult) bool {
+	err := c.withTx(func(tx *storeTx) error {
+		res, exists := tx.Get(id)
+		if !exists {
+			return errResourceGone
+		}
+		c.stageMetadata(tx, r, res, patched)
+		tx.Put(res, "metadata-updated")
+		return nil
+	})
+	return err == nil
+}
+
+// stageMetadata applies the patched metadata, labels, annotations,
+// finalizers and owner to res (staged in tx) and stages the
+// MetadataUpdated event; the caller stores res.
+func (c *Controller) stageMetadata(tx *storeTx, r *http.Request, res *models.ForgeResource, patched patchResult) {
+	now := c.Clock.Now()
+	changed := append([]string(nil), patched.metaChanged...)
+	if len(patched.metaChanged) > 0 {
+		meta := patched.metadata
+		c.stampMetadata(r, &meta, now)
+		res.Metadata = meta
+	}
+	for _, key := range patched.labelsChanged {
+		changed = append(changed, "labels."+key)
+	}
+	if len(patched.labelsChanged) > 0 {
+		res.Labels = patched.labels
+	}
+	for _, key := range patched.annotationsChanged {
+		changed = append(changed, "annotations."+key)
+	}
+	if len(patched.annotationsChanged) > 0 {
+		res.Annotations = patched.annotations
+	}
+	if patched.finalizersChanged {
+		changed = append(changed, "finalizers")
+		res.Finalizers = patched.finalizers
+		tx.OnCommit(func() { c.continueFinalizedLocked(res) })
+	}
+	var dropped []string
+	if patched.ownerRefChanged {
+		changed = append(changed, "owner_ref")
+		res.OwnerRef = patched.ownerRef
+		// WHY AGAIN: checkOwner ran before the lock was taken
+		dropped = c.dropStaleRefs(tx, res)
+	}
+	res.UpdatedAt = now
+	message := "Updated " + strings.Join(changed, " and ")
+	if principal, ok := principalFrom(r.Context()); ok {
+		message += " by " + principal.Name
+	}
+	tx.OnCommit(func() {
+		c.recordEvent(res, models.EventNormal, models.ReasonMetadataUpdated, message, "", "")
+		c.recordDroppedRefsLocked(res, dropped)
+		logger.Infof("%s: %s", res.ID, message)
+	})
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/Zhichengu1/mock-control-plane/pkg/provider"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// LIVE METRICS (GET /resources/{id}/metrics)
+// =============================================================================
+// Dashboards and metric scrapers poll bitrate, dropped frames and device
+// temperature every few seconds. Polling GET /resources/{id} for that
+// rewrites the stored resource (and its revision history) on every call.
+// GET /resources/{id}/metrics reads the vendor and returns only the
+// numbers; nothing is stored:
+//
+//   {"resource_id": "res-42", "vendor_type": "sony", "vendor_id": "sony-dev-170664",
+//    "collected_at": "...", "current_bitrate": 8000000, "dropped_frames": 12,
+//    "uptime_seconds": 3600,
+//    "metrics": {"cpu_percent": 35, "temperature_celsius": 45, ...},
+//    "health": {"cpu_usage_percent": 35, "temperature_celsius": 45, "fan_speed_rpm": 2400, ...}}
+//
+// Vendors with a provider.MetricsReporter (Sony) include their full
+// health report; the others are read with provider.Read, merged with
+// concurrent GETs of the device (see coalescing.go). Concurrent metric
+// reads of one device are merged too.
+//
+// WHY NO CACHED FALLBACK: A scraper would record old readings as new;
+// when the vendor fails the request fails (502).
+// =============================================================================
+
+// readMetrics reads the live metrics of the device behind res.
+func (c *Controller) readMetrics(ctx context.Context, res *models.ForgeResource) (*models.ResourceMetrics, error) {
+	vendorType, vendorID := res.Spec.VendorType, res.Status.VendorID
+	selectedProvider := c.config().Providers[vendorType]
+	reporter, ok := selectedProvider.(provider.MetricsReporter)
+	if !ok {
+		status, err := c.readWithSlot(ctx, selectedProvider, vendorType, vendorID)
+		if err != nil {
+			return nil, err
+		}
+		return &models.ResourceMetrics{
+			VendorID:       vendorID,
+			CollectedAt:    c.Clock.Now(),
+			CurrentBitrate: status.CurrentBitrate,
+			DroppedFrames:  status.DroppedFrames,
+			UptimeSeconds:  int64(status.Uptime / time.Second),
+			Metrics:        status.Metrics,
+		}, nil
+	}
+
+	metrics, err, _ := c.metricReads.Do(vendorReadKey(vendorType, vendorID), func() (*models.ResourceMetrics, error) {
+		release, err := c.acquireVendor(ctx, vendorType)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return reporter.Metrics(ctx, vendorID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// WHY COPY: Merged callers share the result
+	copied := *metrics
+	return &copied, nil
+}
+
+// HandleGetResourceMetrics handles GET /resources/{id}/metrics
+func (c *Controller) HandleGetResourceMetrics(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	c.mu.RLock()
+	stored, exists := c.ResourceDB[id]
+	var res *models.ForgeResource
+	if exists {
+		res = stored.DeepCopy()
+	}
+	c.mu.RUnlock()
+	switch {
+	case !exists:
+		writeOperationError(w, errResourceNotFound)
+		return
+	case res.Status.VendorID == "":
+		writeOperationError(w, errNoVendorDevice)
+		return
+	case res.Status.Phase == phaseTerminating:
+		writeOperationError(w, errResourceTerminating)
+		return
+	}
+	if _, configured := c.config().Providers[res.Spec.VendorType]; !configured {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "provider not configured"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(vendorContext(r), 15*time.Second)
+	defer cancel()
+	metrics, err := c.readMetrics(ctx, res)
+	if err != nil {
+		writeOperationError(w, err)
+		return
+	}
+	metrics.ResourceID, metrics.VendorType = res.ID, res.Spec.VendorType
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(metrics)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/labels"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// NAMESPACE-SCOPED ROUTES
+// =============================================================================
+// Teams sharing a controller each work in their own namespace. The
+// resource routes are also served under /namespaces/{ns}:
+//
+//   POST   /namespaces/{ns}/resources          create in ns
+//   GET    /namespaces/{ns}/resources          list ns only
+//   DELETE /namespaces/{ns}/resources          bulk delete in ns only
+//   GET    /namespaces/{ns}/resources/{id}     ... and the other {id} routes
+//
+// A scoped route never sees another namespace: lists, watches and bulk
+// deletes are filtered to ns, and a resource ID from another namespace is
+// 404, exactly as if it didn't exist. Names are unique per namespace (see
+// uniqueness.go), so two teams can each have a "cam-1".
+//
+// The unscoped /resources routes stay, for operators working across
+// namespaces. The default namespace is /namespaces/default.
+// =============================================================================
+
+// registerNamespaceRoutes adds the /namespaces/{ns} routes to api.
+func (c *Controller) registerNamespaceRoutes(api *mux.Router) {
+	api.HandleFunc("/namespaces", c.HandleListNamespaces).Methods("GET")
+
+	ns := api.PathPrefix("/namespaces/{ns}").Subrouter()
+	ns.Use(c.namespaceScope)
+	ns.HandleFunc("/resources", c.HandleCreateResource).Methods("POST")
+	ns.HandleFunc("/resources", c.HandleListResources).Methods("GET")
+	ns.HandleFunc("/resources", c.HandleDeleteResources).Methods("DELETE")
+	ns.HandleFunc("/resources/watch", c.HandleWatchResources).Methods("GET")
+	ns.HandleFunc("/resources/search", c.HandleSearchResources).Methods("GET")
+	ns.HandleFunc("/resources:batch", c.HandleBatchCreate).Methods("POST")
+	ns.HandleFunc("/resources:apply", c.HandleApplyResource).Methods("POST")
+	ns.HandleFunc("/apply", c.HandleApplyManifest).Methods("POST")
+	ns.HandleFunc("/resources/{id}", c.HandleGetResource).Methods("GET")
+	ns.HandleFunc("/resources/{id}", c.HandlePatchResource).Methods("PATCH")
+	ns.HandleFunc("/resources/{id}", c.HandleUpdateResource).Methods("PUT")
+	ns.HandleFunc("/resources/{id}", c.HandleDeleteResource).Methods("DELETE")
+	ns.HandleFunc("/resources/{id}/revisions", c.HandleListRevisions).Methods("GET")
+	ns.HandleFunc("/resources/{id}/rollback", c.HandleRollbackResource).Methods("POST")
+	ns.HandleFunc("/resources/{id}/restore", c.HandleRestoreResource).Methods("POST")
+	ns.HandleFunc("/resources/{id}/clone", c.HandleCloneResource).Methods("POST")
+	ns.HandleFunc("/resources/{id}/finalizers/{name:.+}", c.HandleRemoveFinalizer).Methods("DELETE")
+	ns.HandleFunc("/resources/{id}/events", c.HandleListEvents).Methods("GET")
+	ns.HandleFunc("/resources/{id}/metrics", c.HandleGetResourceMetrics).Methods("GET")
+	ns.HandleFunc("/resources/{id}:stop", c.HandleStopResource).Methods("POST")
+	ns.HandleFunc("/resources/{id}:start", c.HandleStartResource).Methods("POST")
+	ns.HandleFunc("/resources/{id}:preview", c.HandlePreviewResource).Methods("POST")
+	ns.HandleFunc("/resources/{id}/actions", c.HandleListActions).Methods("GET")
+	ns.HandleFunc("/resources/{id}/actions", c.HandleRunAction).Methods("POST")
+}
+
+// namespaceScope confines a /namespaces/{ns} request to ns.
+func (c *Controller) namespaceScope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		ns := vars["ns"]
+		w.Header().Set("Content-Type", "application/json")
+
+		// Step 1: The namespace must be a valid name
+		if ns == "" || labels.ValidateValue(ns) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("invalid namespace %q", ns)})
+			return
+		}
+
+		// Step 2: Filter lists, watches and bulk deletes to ns
+		// WHY REWRITE THE QUERY: The unscoped handlers already filter on
+		// ?namespace=; a different one in the query is a mistake, not a
+		// way out of the namespace
+		query := r.URL.Query()
+		if query.Has("namespace") && namespaceKey(query.Get("namespace")) != namespaceKey(ns) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("namespace %q doesn't match the route's %q", query.Get("namespace"), ns)})
+			return
+		}
+		query.Set("namespace", ns)
+		r.URL.RawQuery = query.Encode()
+
+		// Step 3: A resource in another namespace doesn't exist here
+		if id := vars["id"]; id != "" {
+			c.mu.RLock()
+			res, exists := c.ResourceDB[id]
+			inScope := exists && namespaceKey(res.Namespace) == namespaceKey(ns)
+			c.mu.RUnlock()
+			if !inScope {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]string{"error": "resource not found"})
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// routeNamespace returns the {ns} of a /namespaces/{ns} route ("" for the
+// unscoped routes).
+func routeNamespace(r *http.Request) string {
+	return mux.Vars(r)["ns"]
+}
+
+// NamespaceSummary is one entry of GET /namespaces.
+type NamespaceSummary struct {
+	Name      string         `json:"name"`
+	Resources int            `json:"resources"`
+	Phases    map[string]int `json:"phases"`
+}
+
+// HandleListNamespaces handles GET /namespaces
+// Lists namespaces that have resources, by name.
+func (c *Controller) HandleListNamespaces(w http.ResponseWriter, r *http.Request) {
+	c.mu.RLock()
+	byName := make(map[string]*NamespaceSummary)
+	for _, res := range c.ResourceDB {
+		name := namespaceKey(res.Namespace)
+		summary, ok := byName[name]
+		if !ok {
+			summary = &NamespaceSummary{Name: name, Phases: make(map[string]int)}
+			byName[name] = summary
+		}
+		summary.Resources++
+		summary.Phases[res.Status.Phase]++
+	}
+	c.mu.RUnlock()
+
+	items := make([]NamespaceSummary, 0, len(byName))
+	for _, summary := range byName {
+		items = append(items, *summary)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// OWNER REFERENCES AND CASCADING DELETE
+// =============================================================================
+// A production owns its cameras, encoders and recorders: when the show is
+// over, deleting the production should tear all of them down. Owned
+// resources name their owner (on create, or with PATCH):
+//
+//   {"name": "cam-1", "type": "camera", "owner_ref": "res-production", ...}
+//
+// The owner must exist in the same namespace, and ownership can't form a
+// cycle. DELETE /resources/{owner} then cascades:
+//
+//   1. the owner turns Terminating and gets the cascadeFinalizer, so its
+//      own vendor delete waits (see finalizers.go)
+//   2. every resource it owns is deleted as if DELETEd on its own (which
+//      cascades further down, and waits for its own finalizers)
+//   3. once the last owned resource is gone, the finalizer is removed and
+//      the owner's deletion continues
+//
+// With DELETE_GRACE_PERIOD, the cascade starts when the owner's grace
+// period ends, so restoring the owner in time leaves its resources alone.
+// DELETE /resources/{owner}?propagation=orphan keeps the owned resources
+// instead: their owner_ref is cleared and only the owner is deleted.
+//
+// Batch deletes (POST /resources:batchDelete, DELETE /resources) always
+// include the owned resources, in the waves before their owner.
+// =============================================================================
+
+// cascadeFinalizer holds an owner's deletion until the resources it owns
+// are gone.
+const cascadeFinalizer = "forge/owned-resources"
+
+// Deletion propagation policies (?propagation=).
+const (
+	propagationForeground = "foreground"
+	propagationOrphan     = "orphan"
+)
+
+// ownedLocked returns the IDs of the resources id owns directly, sorted.
+// Caller must hold c.mu.
+func (c *Controller) ownedLocked(id string) []string {
+	var owned []string
+	for _, res := range c.ResourceDB {
+		if res.OwnerRef == id {
+			owned = append(owned, res.ID)
+		}
+	}
+	sort.Strings(owned)
+	return owned
+}
+
+// ownsLocked reports whether owner owns id, directly or through other
+// owned resources. Caller must hold c.mu.
+func (c *Controller) ownsLocked(owner, id string) bool {
+	// WHY A LIMIT: A cycle left in the store must not hang the walk
+	for hops := 0; hops <= len(c.ResourceDB); hops++ {
+		res, exists := c.ResourceDB[id]
+		if !exists || res.OwnerRef == "" {
+			return false
+		}
+		if res.OwnerRef == owner {
+			return true
+		}
+		id = res.OwnerRef
+	}
+	return false
+}
+
+// checkOwner verifies that res (stored under id, "" for a new resource)
+// may be owned by res.OwnerRef. Returns an error message, or "" if valid.
+func (c *Controller) checkOwner(id string, res *models.ForgeResource) string {
+	if res.OwnerRef == "" {
+		return ""
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	owner, exists := c.ResourceDB[res.OwnerRef]
+	switch {
+	case !exists:
+		return "owner_ref: resource " + res.OwnerRef + " not found"
+	case namespaceKey(owner.Namespace) != namespaceKey(res.Namespace):
+		return "owner_ref: resource " + res.OwnerRef + " is in another namespace"
+	case owner.Status.Phase == phaseTerminating:
+		return "owner_ref: resource " + res.OwnerRef + " is being deleted"
+	case id != "" && (res.OwnerRef == id || c.ownsLocked(id, res.OwnerRef)):
+		return "owner_ref: resource " + res.OwnerRef + " is owned by this resource"
+	}
+	return ""
+}
+
+// cascadeLocked starts the cascade of stored's deletion: if it owns
+// resources, it gets the cascadeFinalizer (so it's parked by
+// awaitFinalizersLocked) and the owned resources are returned for
+// deleteOwned. Must be called with c.mu held.
+func (c *Controller) cascadeLocked(stored *models.ForgeResource, taskID string) []string {
+	owned := c.ownedLocked(stored.ID)
+	if len(owned) == 0 {
+		return nil
+	}
+	if !containsString(stored.Finalizers, cascadeFinalizer) {
+		stored.Finalizers = append(append([]string(nil), stored.Finalizers...), cascadeFinalizer)
+	}
+	c.recordEvent(stored, models.EventNormal, models.ReasonCascading,
+		fmt.Sprintf("Deleting %d owned resources first: %s (task %s)", len(owned), strings.Join(owned, ", "), taskID),
+		phaseTerminating, "")
+	return owned
+}
+
+// cascadeKey marks the context of a deletion started by a cascade.
+type cascadeKey struct{}
+
+// cascaded reports whether ctx is that of a cascaded deletion.
+// WHY: The owner already waited out its grace period; its resources
+// don't wait again
+func cascaded(ctx context.Context) bool {
+	return ctx.Value(cascadeKey{}) != nil
+}
+
+// deleteOwned begins the deletion of each owned resource of owner.
+// WHY NOT FAIL THE OWNER: One owned resource that can't be deleted yet
+// (locked, say) only holds the owner; DELETE it once it can be.
+func (c *Controller) deleteOwned(ctx context.Context, owner string, owned []string, requestedBy string) {
+	// WHY DROP THE PRECONDITION: If-Match named the owner's version
+	ctx = context.WithValue(context.WithValue(ctx, preconditionKey{}, precondition{}), cascadeKey{}, owner)
+	for _, id := range owned {
+		if _, err := c.beginDeletion(ctx, id, requestedBy); err != nil {
+			logger.Warnf("%s: cascading delete of owned resource %s: %v", owner, id, err)
+			c.mu.Lock()
+			if stored, exists := c.ResourceDB[owner]; exists {
+				c.recordEvent(stored, models.EventWarning, models.ReasonCascading,
+					fmt.Sprintf("Couldn't delete owned resource %s: %v; DELETE it to continue", id, err), "", "")
+			}
+			c.mu.Unlock()
+		}
+	}
+}
+
+// removeResource stages the removal of stored (with its tombstone for
+// reason and a Deleted event saying message) in tx, releasing its owner.
+// WHY ONE TRANSACTION: An owner must never wait for a resource that is
+// gone, nor lose its finalizer while the resource is still there
+func (c *Controller) removeResource(tx *storeTx, stored *models.ForgeResource, reason, message string) {
+	tx.Delete(stored.ID, reason)
+	tx.OnCommit(func() {
+		c.recordEvent(stored, models.EventNormal, models.ReasonDeleted, message, "", "")
+	})
+	if stored.OwnerRef != "" {
+		c.settleCascade(tx, stored.OwnerRef)
+	}
+}
+
+// settleCascade stages the removal of owner's cascadeFinalizer in tx
+// once it owns nothing any more; its deletion then continues.
+func (c *Controller) settleCascade(tx *storeTx, ownerID string) {
+	owner, exists := tx.Get(ownerID)
+	if !exists || !containsString(owner.Finalizers, cascadeFinalizer) || len(tx.Owned(ownerID)) > 0 {
+		return
+	}
+	owner.Finalizers = withoutString(owner.Finalizers, cascadeFinalizer)
+	owner.UpdatedAt = c.Clock.Now()
+	tx.Put(owner, "cascade-done")
+	tx.OnCommit(func() { c.continueFinalizedLocked(owner) })
+}
+
+// orphanOwned stages clearing the owner_ref of the resources owner owns
+// (?propagation=orphan) in tx.
+func (c *Controller) orphanOwned(tx *storeTx, owner, orphanedBy string) {
+	message := "Owner " + owner + " deleted without its owned resources"
+	if orphanedBy != "" {
+		message += " by " + orphanedBy
+	}
+	for _, id := range tx.Owned(owner) {
+		res, _ := tx.Get(id)
+		res.OwnerRef = ""
+		res.UpdatedAt = c.Clock.Now()
+		tx.Put(res, "orphaned")
+		tx.OnCommit(func() {
+			c.recordEvent(res, models.EventNormal, models.ReasonOrphaned, message, "", "")
+		})
+	}
+}
+
+// dropStaleRefs drops the references of res, about to be stored by tx,
+// that stopped holding since checkOwner and checkDependencies approved
+// them: an owner that is gone, being deleted or now owned by res,
+// dependencies that are gone or being deleted. Returns what it dropped.
+// WHY: A create holds no lock during its vendor call. The owner's cascade
+// has already collected what it owns; a resource pointing at it would be
+// left behind with a dangling owner_ref
+func (c *Controller) dropStaleRefs(tx *storeTx, res *models.ForgeResource) []string {
+	var dropped []string
+	if res.OwnerRef != "" {
+		owner, exists := tx.Get(res.OwnerRef)
+		if !exists || owner.Status.Phase == phaseTerminating || res.OwnerRef == res.ID || c.ownsLocked(res.ID, res.OwnerRef) {
+			dropped = append(dropped, "owner_ref "+res.OwnerRef)
+			res.OwnerRef = ""
+		}
+	}
+	var deps []string
+	for _, id := range res.DependsOn {
+		if dep, exists := tx.Get(id); exists && dep.Status.Phase != phaseTerminating {
+			deps = append(deps, id)
+		} else {
+			dropped = append(dropped, "depends_on "+id)
+		}
+	}
+	if len(deps) < len(res.DependsOn) {
+		res.DependsOn = deps
+	}
+	return dropped
+}
+
+// recordDroppedRefsLocked reports the references dropStaleRefs dropped
+// from res. Caller must hold c.mu.
+func (c *Controller) recordDroppedRefsLocked(res *models.ForgeResource, dropped []string) {
+	if len(dropped) == 0 {
+		return
+	}
+	c.recordEvent(res, models.EventWarning, models.ReasonOrphaned,
+		fmt.Sprintf("Stored without %s: deleted while this change was in progress", strings.Join(dropped, ", ")), "", "")
+}
+
+// withoutString returns list without s (nil if nothing is left).
+func withoutString(list []string, s string) []string {
+	var rest []string
+	for _, item := range list {
+		if item != s {
+			rest = append(rest, item)
+		}
+	}
+	return rest
+}
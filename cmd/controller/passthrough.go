@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/audit"
+	"github.com/Zhichengu1/mock-control-plane/pkg/client"
+	"github.com/Zhichengu1/mock-control-plane/pkg/provider"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// VENDOR PASSTHROUGH (ADMIN ONLY)
+// =============================================================================
+// POST /providers/{name}/passthrough forwards a raw request to the vendor
+// using the provider's credentials and returns the vendor's response
+// as-is. Engineers can diagnose vendor-side issues without exporting the
+// vendor API keys to their laptops.
+//
+// Example:
+//
+//	curl -H "Authorization: Bearer $KEY" -X POST \
+//	  localhost:8080/providers/sony/passthrough \
+//	  -d '{"method": "GET", "path": "/devices/sony-dev-1"}'
+//
+// Every call is audited twice under the same request ID: a "passthrough"
+// entry naming the admin, and the usual "vendor-call" entry.
+// =============================================================================
+
+// Passthrough size limits.
+// WHY LIMITS: Responses are buffered in memory; a runaway vendor listing
+// shouldn't take the controller down.
+const (
+	maxPassthroughRequestBytes  = 1 << 20  // 1 MiB
+	maxPassthroughResponseBytes = 10 << 20 // 10 MiB
+)
+
+// passthroughMethods are the methods that may be forwarded.
+var passthroughMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true,
+	http.MethodPut: true, http.MethodPatch: true, http.MethodDelete: true,
+}
+
+// PassthroughRequest is the body accepted by POST /providers/{name}/passthrough.
+type PassthroughRequest struct {
+	// Method is the HTTP method to send (default GET).
+	Method string `json:"method"`
+
+	// Path is relative to the vendor base URL and may include a query
+	// string, e.g. "/devices?status=error".
+	Path string `json:"path"`
+
+	// Body is sent as-is (JSON) when present.
+	Body json.RawMessage `json:"body,omitempty"`
+}
+
+// HandlePassthrough handles POST /providers/{name}/passthrough
+func (c *Controller) HandlePassthrough(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	var req PassthroughRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPassthroughRequestBytes)).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+	req.Method = strings.ToUpper(req.Method)
+	if req.Method == "" {
+		req.Method = http.MethodGet
+	}
+	if !passthroughMethods[req.Method] {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "unsupported method " + req.Method})
+		return
+	}
+	if req.Path == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "path is required"})
+		return
+	}
+
+	p, exists := c.config().Providers[name]
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "unknown provider: " + name})
+		return
+	}
+	passthrough, supported := p.(provider.Passthrough)
+	if !supported {
+		w.WriteHeader(http.StatusNotImplemented)
+		json.NewEncoder(w).Encode(map[string]string{"error": "provider " + name + " does not support passthrough"})
+		return
+	}
+
+	principal, _ := principalFrom(r.Context())
+	entry := audit.Entry{
+		Time:          c.Clock.Now(),
+		Kind:          audit.KindPassthrough,
+		RequestID:     audit.RequestID(r.Context()),
+		Actor:         principal.Name,
+		Method:        req.Method,
+		URL:           name + ":" + redactPath(req.Path),
+		PayloadSHA256: payloadSHA256(req.Body),
+		PayloadBytes:  len(req.Body),
+	}
+
+	ctx, cancel := context.WithTimeout(vendorContext(r), 30*time.Second)
+	defer cancel()
+	release, err := c.acquireVendor(ctx, name)
+	if err != nil {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	resp, err := passthrough.Passthrough(ctx, req.Method, req.Path, req.Body)
+	if err != nil {
+		release()
+		entry.Error = err.Error()
+		entry.DurationMS = c.Clock.Since(entry.Time).Milliseconds()
+		c.Audit.Record(entry)
+		writeProviderError(w, err)
+		return
+	}
+	body, readErr := io.ReadAll(io.LimitReader(resp.Body, maxPassthroughResponseBytes+1))
+	resp.Body.Close()
+	release()
+
+	entry.StatusCode = resp.StatusCode
+	entry.DurationMS = c.Clock.Since(entry.Time).Milliseconds()
+	if readErr != nil {
+		entry.Error = readErr.Error()
+	}
+	c.Audit.Record(entry)
+	logger.Infof("Passthrough by %s: %s %s %s → %d", principal.Name, name, req.Method, redactPath(req.Path), resp.StatusCode)
+
+	if readErr != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(map[string]string{"error": "reading vendor response: " + readErr.Error()})
+		return
+	}
+	if len(body) > maxPassthroughResponseBytes {
+		body = body[:maxPassthroughResponseBytes]
+		w.Header().Set("X-Forge-Truncated", "true")
+	}
+
+	// Return the vendor response untouched, marked so it isn't mistaken
+	// for a controller response
+	if ct := resp.Header.Get("Content-Type"); ct != "" {
+		w.Header().Set("Content-Type", ct)
+	}
+	w.Header().Set("X-Forge-Passthrough", name)
+	w.WriteHeader(resp.StatusCode)
+	w.Write(body)
+}
+
+// redactPath redacts secret-looking query parameters in a relative path.
+func redactPath(path string) string {
+	u, err := url.Parse(path)
+	if err != nil {
+		return "(unparseable path)"
+	}
+	return client.RedactURL(u)
+}
+
+// payloadSHA256 returns the hex SHA-256 of body, or "" if empty.
+func payloadSHA256(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
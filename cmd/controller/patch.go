@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/Zhichengu1/mock-control-plane/pkg/validation"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// RESOURCE PATCHES (JSON MERGE PATCH AND JSON PATCH)
+// =============================================================================
+// PUT replaces the whole spec, so a client that only wants to raise the
+// bitrate has to read the resource first and send everything back (and
+// races anyone else doing the same). PATCH takes a JSON merge patch
+// (RFC 7396, application/merge-patch+json) instead:
+//
+//   PATCH /resources/{id}
+//   {"spec": {"bitrate": 8000000, "config": {"gop": null}}}
+//
+// Listed fields are set, null removes one, missing fields are kept; nested
+// objects (spec.config) are merged the same way. The merged spec goes
+// through updateResourceSpec like a PUT: validated, pushed to the vendor,
+// stored only if it accepts. Metadata, labels, annotations, finalizers and
+// owner_ref in the same patch are Forge-only and are stored without a
+// vendor call (see metadata.go, finalizers.go, owners.go):
+//
+//   {"labels": {"env": "prod", "retired": null}}
+//
+// For surgical edits (one key deep in spec.config, an exact removal) PATCH
+// also takes a JSON Patch (RFC 6902, application/json-patch+json); see
+// jsonpatch.go. Both kinds end in the same patchResult and are applied
+// the same way.
+// =============================================================================
+
+// Patch media types. Merge patches are also accepted as plain JSON.
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	jsonPatchContentType  = "application/json-patch+json"
+)
+
+// patchableFields are the top-level members a patch can carry.
+var patchableFields = []string{"annotations", "finalizers", "labels", "metadata", "owner_ref", "spec"}
+
+// specFields encodes each top-level field of spec as GET shows it.
+func specFields(spec models.ResourceSpec) map[string]string {
+	data, _ := json.Marshal(spec)
+	var fields map[string]json.RawMessage
+	json.Unmarshal(data, &fields)
+	encoded := make(map[string]string, len(fields))
+	for key, value := range fields {
+		encoded[key] = string(value)
+	}
+	return encoded
+}
+
+// applySpecPatch applies the spec member of a merge patch to spec (see
+// patchSpec). Returns the patched spec and the patched fields whose value
+// changes; setting a field to its current value is not a change.
+func applySpecPatch(raw json.RawMessage, spec models.ResourceSpec) (models.ResourceSpec, []string, error) {
+	var patch map[string]interface{}
+	if err := json.Unmarshal(raw, &patch); err != nil || patch == nil {
+		return spec, nil, errors.New("spec must be an object")
+	}
+	patched, err := patchSpec(spec, patch)
+	if err != nil {
+		return spec, nil, err
+	}
+	before, after := specFields(spec), specFields(patched)
+	var changed []string
+	for key := range patch {
+		if before[key] != after[key] {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return patched, changed, nil
+}
+
+// patchResult is a patch applied to a copy of a resource.
+type patchResult struct {
+	spec        models.ResourceSpec
+	metadata    models.ResourceMetadata
+	labels      map[string]string
+	annotations map[string]string
+	finalizers  []string
+	ownerRef    string
+
+	// specChanged, metaChanged, labelsChanged and annotationsChanged name
+	// the fields (label and annotation keys) whose value changes
+	specChanged        []string
+	metaChanged        []string
+	labelsChanged      []string
+	annotationsChanged []string
+	finalizersChanged  bool
+	ownerRefChanged    bool
+}
+
+// forgeOnly reports whether the patch changes metadata, labels,
+// annotations, finalizers or the owner.
+func (p patchResult) forgeOnly() bool {
+	return len(p.metaChanged) > 0 || len(p.labelsChanged) > 0 || len(p.annotationsChanged) > 0 || p.finalizersChanged || p.ownerRefChanged
+}
+
+// applyMergePatch applies a JSON merge patch body to current.
+func applyMergePatch(body []byte, current *models.ForgeResource) (patchResult, error) {
+	result := patchResult{spec: current.Spec, metadata: current.Metadata, labels: current.Labels, annotations: current.Annotations, finalizers: current.Finalizers, ownerRef: current.OwnerRef}
+	var patch map[string]json.RawMessage
+	if err := json.Unmarshal(body, &patch); err != nil {
+		return result, fmt.Errorf("invalid JSON: %w", err)
+	}
+	var unsupported []string
+	for key := range patch {
+		if !containsString(patchableFields, key) {
+			unsupported = append(unsupported, key)
+		}
+	}
+	if len(unsupported) > 0 {
+		sort.Strings(unsupported)
+		return result, fmt.Errorf("cannot patch %s: only %s can be patched",
+			strings.Join(unsupported, ", "), strings.Join(patchableFields, ", "))
+	}
+	if len(patch) == 0 {
+		return result, errors.New("nothing to patch: set " + strings.Join(patchableFields, ", "))
+	}
+
+	var err error
+	if raw, ok := patch["metadata"]; ok {
+		if result.metadata, result.metaChanged, err = parseMetadataPatch(raw, current.Metadata); err != nil {
+			return result, err
+		}
+	}
+	if raw, ok := patch["labels"]; ok {
+		if result.labels, result.labelsChanged, err = parseStringMapPatch("labels", raw, current.Labels); err != nil {
+			return result, err
+		}
+	}
+	if raw, ok := patch["annotations"]; ok {
+		if result.annotations, result.annotationsChanged, err = parseStringMapPatch("annotations", raw, current.Annotations); err != nil {
+			return result, err
+		}
+	}
+	if raw, ok := patch["finalizers"]; ok {
+		// WHY THE WHOLE LIST: Merge patches replace arrays (RFC 7396)
+		var finalizers []string
+		if err := json.Unmarshal(raw, &finalizers); err != nil {
+			return result, errors.New("finalizers must be an array of strings or null")
+		}
+		if len(finalizers) == 0 {
+			finalizers = nil
+		}
+		result.finalizers = finalizers
+		result.finalizersChanged = !reflect.DeepEqual(finalizers, current.Finalizers)
+	}
+	if raw, ok := patch["owner_ref"]; ok {
+		var ownerRef *string
+		if err := json.Unmarshal(raw, &ownerRef); err != nil {
+			return result, errors.New("owner_ref must be a resource ID or null")
+		}
+		result.ownerRef = ""
+		if ownerRef != nil {
+			result.ownerRef = *ownerRef
+		}
+		result.ownerRefChanged = result.ownerRef != current.OwnerRef
+	}
+	if raw, ok := patch["spec"]; ok {
+		if result.spec, result.specChanged, err = applySpecPatch(raw, current.Spec); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// HandlePatchResource handles PATCH /resources/{id}
+func (c *Controller) HandlePatchResource(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	w.Header().Set("Content-Type", "application/json")
+	if rejectBadCircuitOpenParam(w, r) {
+		return
+	}
+
+	// Step 1: Read the patch
+	var body bytes.Buffer
+	if _, err := body.ReadFrom(http.MaxBytesReader(w, r.Body, maxResourceBodyBytes)); err != nil {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	c.mu.RLock()
+	stored, exists := c.ResourceDB[id]
+	var current models.ForgeResource
+	if exists {
+		current = *stored.DeepCopy()
+	}
+	c.mu.RUnlock()
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Resource not found"})
+		return
+	}
+	if c.rejectIfLocked(w, r, id) || c.rejectIfPreconditionFailed(w, r, id) {
+		return
+	}
+
+	// Step 2: Apply it to a copy and validate the metadata
+	// WHY ANY OTHER TYPE IS A MERGE PATCH: Other handlers don't check the
+	// content type either (curl -d sends a form type)
+	var patched patchResult
+	var err error
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == jsonPatchContentType {
+		patched, err = applyJSONPatch(body.Bytes(), &current)
+	} else {
+		patched, err = applyMergePatch(body.Bytes(), &current)
+	}
+	// WHY ONLY ON A MOVE: Re-merging on every patch would undo a
+	// deliberate one-off change to a field the overlay sets
+	if err == nil && containsString(patched.specChanged, "environment") {
+		patched.spec, err = c.config().Profiles.apply(patched.spec)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	res, ok := c.savePatch(w, r, id, current, patched, "patched")
+	if !ok {
+		return
+	}
+	setResourceETag(w, res)
+	redact := c.redactionFor(r)
+	redact.announce(w)
+	json.NewEncoder(w).Encode(redact.resource(res))
+}
+
+// savePatch validates patched (a patch of current, resource id) and stores
+// it: Forge-only fields right away, the spec through updateResourceSpec
+// (reason names the revision). It returns the stored resource, or false
+// if it wrote the response itself (an error, a deferred or queued update).
+func (c *Controller) savePatch(w http.ResponseWriter, r *http.Request, id string, current models.ForgeResource, patched patchResult, reason string) (*models.ForgeResource, bool) {
+	candidate := current
+	candidate.Metadata, candidate.Labels, candidate.Annotations = patched.metadata, patched.labels, patched.annotations
+	candidate.Finalizers, candidate.OwnerRef = patched.finalizers, patched.ownerRef
+	if patched.ownerRefChanged {
+		if msg := c.checkOwner(id, &candidate); msg != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": msg})
+			return nil, false
+		}
+	}
+	if current.Status.Phase == phaseTerminating && addsFinalizers(current.Finalizers, candidate.Finalizers) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": errFinalizersWhileTerminating.Error()})
+		return nil, false
+	}
+	if patched.forgeOnly() {
+		violations := append(validation.CheckSize(&candidate, c.config().SpecLimits), validation.ValidateMetadata(candidate.Metadata)...)
+		violations = append(violations, validation.ValidateLabels(candidate.Labels)...)
+		violations = append(violations, validation.ValidateAnnotations(candidate.Annotations)...)
+		violations = append(violations, validation.ValidateFinalizers(candidate.Finalizers)...)
+		if len(violations) > 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "metadata validation failed", "violations": violations})
+			return nil, false
+		}
+	}
+	// WHY NO-OP WITHOUT CHANGES: Saving the same note twice shouldn't
+	// add history
+	storeMetadata := func() bool {
+		return !patched.forgeOnly() || c.storeMetadata(r, id, patched)
+	}
+
+	// Step 3: Metadata, labels, annotations, finalizers and owner only:
+	// store them (no vendor call)
+	if len(patched.specChanged) == 0 {
+		if !storeMetadata() {
+			writeOperationError(w, errResourceGone)
+			return nil, false
+		}
+		c.mu.RLock()
+		res := c.ResourceDB[id].DeepCopy()
+		c.mu.RUnlock()
+		return res, true
+	}
+
+	// Step 4: Spec changes go to the vendor like a PUT. Metadata (labels,
+	// annotations) is stored
+	// once the spec is accepted, or right away if the spec waits for a
+	// maintenance window or is queued (notes don't wait)
+	detail := " (" + strings.Join(patched.specChanged, ", ") + ")"
+	if principal, ok := principalFrom(r.Context()); ok {
+		detail += " by " + principal.Name
+	}
+	if r.URL.Query().Get("urgent") != "true" && c.activeMaintenance(current.Spec.VendorType) != nil {
+		storeMetadata()
+	}
+	if c.deferForMaintenance(w, r, id, "update", detail, &patched.spec) {
+		return nil, false
+	}
+	if c.queueBehindPending(w, r, id, detail, &patched.spec) {
+		storeMetadata()
+		return nil, false
+	}
+	// WHY A STAGE: The rest of the patch is stored with the spec, as one
+	// revision (see tx.go)
+	var stage txStage
+	if patched.forgeOnly() {
+		stage = func(tx *storeTx, res *models.ForgeResource) { c.stageMetadata(tx, r, res, patched) }
+	}
+	res, err := c.updateResourceSpecWith(vendorContext(r), id, patched.spec, reason, detail, stage)
+	if err != nil {
+		if c.queueAfterCircuitOpen(w, r, id, detail, &patched.spec, err) {
+			storeMetadata()
+			return nil, false
+		}
+		writeOperationError(w, err)
+		return nil, false
+	}
+	logger.Infof("%s: spec %s%s", id, reason, detail)
+	return res, true
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// PREFLIGHT ON CREATE
+// =============================================================================
+// With "spec.preflight": true, HandleCreateResource asks the vendor to
+// check the create first (quota, IP reachability, model availability).
+// If any check fails, nothing is provisioned or stored:
+//
+//   422 {"error": "preflight failed: model-availability: model \"FX7\" is not
+//        available in this region", "checks": [...every check...]}
+//
+// WHY 422: The request is well-formed but can't be satisfied as is; the
+// client has to change it (or free quota) before retrying.
+// If the checks can't run at all, the create is aborted too (provider
+// error mapping, e.g. 502), rather than provisioning blind.
+// =============================================================================
+
+// preflightError aborts a create whose preflight failed (result) or
+// could not run (err).
+type preflightError struct {
+	result *models.PreflightResult
+	err    error
+}
+
+func (e *preflightError) Error() string {
+	if e.err != nil {
+		return "preflight could not run: " + e.err.Error()
+	}
+	var reasons []string
+	for _, check := range e.result.Failed() {
+		reasons = append(reasons, check.Name+": "+check.Message)
+	}
+	return "preflight failed: " + strings.Join(reasons, "; ")
+}
+
+func (e *preflightError) Unwrap() error { return e.err }
+
+// writePreflightFailure reports a failed or unrunnable preflight.
+func writePreflightFailure(w http.ResponseWriter, result *models.PreflightResult, err error) {
+	if err != nil {
+		logger.Warnf("Create aborted: preflight could not run: %v", err)
+		writeProviderError(w, fmt.Errorf("preflight could not run: %w", err))
+		return
+	}
+
+	failed := result.Failed()
+	reasons := make([]string, 0, len(failed))
+	for _, check := range failed {
+		reasons = append(reasons, check.Name+": "+check.Message)
+	}
+	logger.Infof("Create aborted: preflight failed (%s)", strings.Join(reasons, "; "))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":  "preflight failed: " + strings.Join(reasons, "; "),
+		"checks": result.Checks,
+	})
+}
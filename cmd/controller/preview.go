@@ -0,0 +1,259 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/client"
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// STREAM PREVIEW (POST /resources/{id}:preview)
+// =============================================================================
+// "Is camera 3 actually sending picture?" shouldn't need VLC and the
+// stream key. The controller hands the UI a short-lived URL on a preview
+// gateway (a media server that pulls the feed and repackages it for
+// browsers):
+//
+//   POST /resources/{id}:preview   {"format": "webrtc", "ttl": "2m"}
+//   → {"url": "https://preview.example.com/webrtc/<token>", "format": "webrtc",
+//      "expires_at": ..., "source": {"from": "endpoint", "protocol": "srt"}}
+//
+//   hls     <gateway>/hls/<token>/index.m3u8 (default; plays everywhere)
+//   webrtc  <gateway>/webrtc/<token> (a WHEP endpoint; sub-second delay)
+//
+// The feed is the resource's first output URL endpoint (where it can be
+// pulled from), else spec.stream_url (the destination it pushes to,
+// which ingest servers serve too). Only a Running resource has one (409
+// otherwise); one without either is 422.
+//
+// The token seals the source URL, resource ID, format and expiry with
+// AES-GCM under PREVIEW_GATEWAY_KEY, which the gateway shares: the
+// gateway can open it, the browser can't read the source (which may hold
+// a stream key) or stretch the expiry. TTL defaults to 5m, at most
+// PREVIEW_MAX_TTL (default 1h). Without PREVIEW_GATEWAY_URL the endpoint
+// answers 503.
+// =============================================================================
+
+// Preview formats.
+const (
+	previewHLS    = "hls"
+	previewWebRTC = "webrtc"
+)
+
+// defaultPreviewTTL is how long a preview URL lasts unless asked otherwise.
+// WHY SHORT: Long enough to look, short enough that a URL pasted into a
+// chat doesn't stay a live feed
+const defaultPreviewTTL = 5 * time.Minute
+
+// previewGateway mints preview URLs for one gateway.
+type previewGateway struct {
+	baseURL string
+	aead    cipher.AEAD
+	maxTTL  time.Duration
+}
+
+// loadPreviewGateway reads PREVIEW_GATEWAY_URL and PREVIEW_GATEWAY_KEY.
+// Returns nil if no gateway is configured.
+func loadPreviewGateway(secrets client.Secrets) (*previewGateway, error) {
+	base := os.Getenv("PREVIEW_GATEWAY_URL")
+	if base == "" {
+		return nil, nil
+	}
+	if u, err := url.Parse(base); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("PREVIEW_GATEWAY_URL %q: want an http(s) URL", base)
+	}
+	key, ok := secrets.Secret("PREVIEW_GATEWAY_KEY")
+	if !ok || key == "" {
+		return nil, errors.New("PREVIEW_GATEWAY_URL needs PREVIEW_GATEWAY_KEY, the key the gateway opens tokens with")
+	}
+	// WHY HASHED: Any passphrase becomes an AES-256 key
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &previewGateway{
+		baseURL: strings.TrimRight(base, "/"),
+		aead:    aead,
+		maxTTL:  envDuration("PREVIEW_MAX_TTL", time.Hour),
+	}, nil
+}
+
+// previewClaims is what a preview token carries to the gateway.
+type previewClaims struct {
+	ResourceID string `json:"rid"`
+	Source     string `json:"src"`
+	Format     string `json:"fmt"`
+	Expires    int64  `json:"exp"`
+}
+
+// seal encrypts claims as base64url(nonce || ciphertext).
+func (g *previewGateway) seal(claims previewClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, g.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(g.aead.Seal(nonce, nonce, payload, nil)), nil
+}
+
+// previewURL returns the gateway URL that plays token in format.
+func (g *previewGateway) previewURL(format, token string) string {
+	if format == previewWebRTC {
+		return g.baseURL + "/webrtc/" + token
+	}
+	return g.baseURL + "/hls/" + token + "/index.m3u8"
+}
+
+// PreviewRequest is the (optional) body of POST /resources/{id}:preview.
+type PreviewRequest struct {
+	// Format is "hls" (default) or "webrtc"
+	Format string `json:"format,omitempty"`
+
+	// TTL is a Go duration like "2m" (default 5m)
+	TTL string `json:"ttl,omitempty"`
+}
+
+// PreviewSource says where a preview's feed comes from, without the URL.
+type PreviewSource struct {
+	// From is "endpoint" (an output endpoint) or "stream_url"
+	From     string `json:"from"`
+	Protocol string `json:"protocol,omitempty"`
+}
+
+// PreviewResponse is the response of POST /resources/{id}:preview.
+type PreviewResponse struct {
+	URL       string        `json:"url"`
+	Format    string        `json:"format"`
+	ExpiresAt time.Time     `json:"expires_at"`
+	Source    PreviewSource `json:"source"`
+}
+
+// previewSource picks the feed of res: its first output URL endpoint,
+// else spec.stream_url.
+func previewSource(res *models.ForgeResource) (string, PreviewSource, bool) {
+	for _, endpoint := range res.Status.Endpoints {
+		if endpoint.Role == models.EndpointRoleOutput && endpoint.Type == models.EndpointTypeURL {
+			return endpoint.Address, PreviewSource{From: "endpoint", Protocol: streamProtocol(endpoint.Address, endpoint.Protocol)}, true
+		}
+	}
+	if res.Spec.StreamURL != "" {
+		return res.Spec.StreamURL, PreviewSource{From: "stream_url", Protocol: streamProtocol(res.Spec.StreamURL, "")}, true
+	}
+	return "", PreviewSource{}, false
+}
+
+// streamProtocol returns protocol, or the scheme of address.
+func streamProtocol(address, protocol string) string {
+	if protocol != "" {
+		return protocol
+	}
+	if u, err := url.Parse(address); err == nil {
+		return u.Scheme
+	}
+	return ""
+}
+
+// HandlePreviewResource handles POST /resources/{id}:preview
+func (c *Controller) HandlePreviewResource(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	w.Header().Set("Content-Type", "application/json")
+	if c.Preview == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"error": "no preview gateway configured (PREVIEW_GATEWAY_URL)"})
+		return
+	}
+
+	// Step 1: Parse the format and lifetime
+	var req PreviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+	if req.Format == "" {
+		req.Format = previewHLS
+	}
+	if req.Format != previewHLS && req.Format != previewWebRTC {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "format must be hls or webrtc"})
+		return
+	}
+	// WHY min: A PREVIEW_MAX_TTL below the default caps the default too
+	ttl := min(defaultPreviewTTL, c.Preview.maxTTL)
+	if req.TTL != "" {
+		d, err := time.ParseDuration(req.TTL)
+		if err != nil || d <= 0 || d > c.Preview.maxTTL {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "ttl must be a positive Go duration up to " + c.Preview.maxTTL.String()})
+			return
+		}
+		ttl = d
+	}
+
+	// Step 2: Find the feed
+	c.mu.RLock()
+	stored, exists := c.ResourceDB[id]
+	var res *models.ForgeResource
+	if exists {
+		res = stored.DeepCopy()
+	}
+	c.mu.RUnlock()
+	if !exists {
+		writeOperationError(w, errResourceNotFound)
+		return
+	}
+	if res.Status.Phase != "Running" {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("resource is %s; only a Running resource has a stream to preview", res.Status.Phase)})
+		return
+	}
+	source, described, ok := previewSource(res)
+	if !ok {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"error": "resource has no stream to preview (no output endpoint or spec.stream_url)"})
+		return
+	}
+
+	// Step 3: Seal it into a gateway URL
+	expiresAt := c.Clock.Now().Add(ttl).Truncate(time.Second)
+	token, err := c.Preview.seal(previewClaims{ResourceID: id, Source: source, Format: req.Format, Expires: expiresAt.Unix()})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "sealing preview token: " + err.Error()})
+		return
+	}
+	caller := "anonymous"
+	if principal, ok := principalFrom(r.Context()); ok {
+		caller = principal.Name
+	}
+	logger.Infof("%s: %s preview for %s until %s", id, req.Format, caller, expiresAt.Format(time.RFC3339))
+	json.NewEncoder(w).Encode(PreviewResponse{
+		URL:       c.Preview.previewURL(req.Format, token),
+		Format:    req.Format,
+		ExpiresAt: expiresAt,
+		Source:    described,
+	})
+}
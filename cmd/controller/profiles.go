@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// ENVIRONMENT PROFILES
+// =============================================================================
+// The same rig is set up in dev, staging and prod, with a few deliberate
+// differences: lower bitrates and no recording in dev, other stream
+// destinations per environment. Copying the whole spec per environment
+// lets the copies drift apart. Instead, clients send one base spec and
+// pick an environment:
+//
+//   POST /resources
+//   {"name": "cam1", "spec": {"vendor_type": "sony", "bitrate": 12000000,
+//                             "recording_enabled": true, "environment": "dev"}}
+//
+// and the controller merges that environment's overlay (a JSON merge
+// patch on the spec, RFC 7396) over it. SPEC_PROFILES_FILE holds the
+// overlays:
+//
+//   {
+//     "dev":  {"bitrate": 3000000, "recording_enabled": false,
+//              "stream_url": "rtmp://dev-ingest/live"},
+//     "prod": {"stream_url": "srt://prod-ingest:9000"}
+//   }
+//
+// The overlay wins over the base spec (nested config is merged key by
+// key; null removes a field). It is merged on create and PUT, and on a
+// PATCH that changes spec.environment. A PATCH of other fields edits the
+// merged spec as stored, so a deliberate one-off change sticks.
+// NOTE: Only the merged spec is stored. A PATCH to another environment
+// merges its overlay over the stored spec, keeping values only the old
+// overlay set; PUT the base spec to switch cleanly.
+// An unknown environment is rejected with 400.
+// =============================================================================
+
+// SpecProfiles maps environment → overlay (a merge patch on the spec).
+type SpecProfiles map[string]map[string]interface{}
+
+// unprofiledFields can't be set by an overlay.
+// WHY: The vendor and the environment itself are chosen by the client
+var unprofiledFields = []string{"vendor_type", "environment"}
+
+// loadSpecProfiles reads SPEC_PROFILES_FILE. Returns nil if it isn't set.
+func loadSpecProfiles(path string) (SpecProfiles, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var profiles SpecProfiles
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	// WHY CHECK NOW: A typo in an overlay should stop the deploy, not
+	// fail every create in that environment
+	known := specFieldNames()
+	for environment, overlay := range profiles {
+		if environment == "" || overlay == nil {
+			return nil, fmt.Errorf("%s: profile %q must be a named object", path, environment)
+		}
+		for field := range overlay {
+			if !known[field] || containsString(unprofiledFields, field) {
+				return nil, fmt.Errorf("%s: profile %q can't set %q", path, environment, field)
+			}
+		}
+		if _, err := patchSpec(models.ResourceSpec{}, overlay); err != nil {
+			return nil, fmt.Errorf("%s: profile %q: %w", path, environment, err)
+		}
+	}
+	return profiles, nil
+}
+
+// names returns the configured environments, sorted.
+func (p SpecProfiles) names() []string {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// apply merges the overlay of spec.Environment over spec. A spec without
+// an environment is returned unchanged.
+func (p SpecProfiles) apply(spec models.ResourceSpec) (models.ResourceSpec, error) {
+	if spec.Environment == "" {
+		return spec, nil
+	}
+	overlay, ok := p[spec.Environment]
+	if !ok {
+		if len(p) == 0 {
+			return spec, fmt.Errorf("unknown environment %q (no profiles are configured)", spec.Environment)
+		}
+		return spec, fmt.Errorf("unknown environment %q (configured: %s)", spec.Environment, strings.Join(p.names(), ", "))
+	}
+	merged, err := patchSpec(spec, overlay)
+	if err != nil {
+		return spec, err
+	}
+	merged.VendorType, merged.Environment = spec.VendorType, spec.Environment
+	return merged, nil
+}
+
+// HandleListProfiles handles GET /profiles
+func (c *Controller) HandleListProfiles(w http.ResponseWriter, r *http.Request) {
+	cfg := c.config()
+	type profile struct {
+		Environment string                 `json:"environment"`
+		Overlay     map[string]interface{} `json:"overlay"`
+	}
+	items := []profile{}
+	for _, name := range cfg.Profiles.names() {
+		items = append(items, profile{Environment: name, Overlay: cfg.Profiles[name]})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+}
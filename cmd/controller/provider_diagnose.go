@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/provider"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// PROVIDER CONNECTION DIAGNOSIS
+// =============================================================================
+// When GET /providers/health says a vendor is down, the next question is
+// why. POST /providers/{name}:diagnose walks the connection (DNS, TCP,
+// TLS, credentials, latency; see provider.ConnectionDiagnoser) and returns
+// a report naming the step that fails:
+//
+//   curl -X POST localhost:8080/providers/sony:diagnose -d '{"samples": 10}'
+//
+// The report is returned with 200 whatever the vendor's state; its status
+// is the worst check (pass, warn, fail).
+//
+// WHY NO VENDOR SLOT: Diagnosing matters most when the vendor is slow and
+// every slot is taken; the few probe requests don't wait for one.
+// =============================================================================
+
+// Latency samples per diagnosis.
+const (
+	defaultDiagnoseSamples = 5
+	maxDiagnoseSamples     = 20
+)
+
+// DiagnoseRequest is the (optional) body of POST /providers/{name}:diagnose.
+type DiagnoseRequest struct {
+	// Samples is the number of latency probes (default 5, max 20)
+	Samples int `json:"samples,omitempty"`
+}
+
+// HandleDiagnoseProvider handles POST /providers/{name}:diagnose
+func (c *Controller) HandleDiagnoseProvider(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	// Step 1: Parse the options
+	var req DiagnoseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+	if req.Samples == 0 {
+		req.Samples = defaultDiagnoseSamples
+	}
+	if req.Samples < 1 || req.Samples > maxDiagnoseSamples {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("samples must be between 1 and %d", maxDiagnoseSamples)})
+		return
+	}
+
+	// Step 2: Find a provider that can diagnose itself
+	p, exists := c.config().Providers[name]
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "unknown provider: " + name})
+		return
+	}
+	diagnoser, supported := p.(provider.ConnectionDiagnoser)
+	if !supported {
+		w.WriteHeader(http.StatusNotImplemented)
+		json.NewEncoder(w).Encode(map[string]string{"error": "provider " + name + " does not support connection diagnosis"})
+		return
+	}
+
+	// Step 3: Run the checks
+	ctx, cancel := context.WithTimeout(vendorContext(r), 30*time.Second)
+	defer cancel()
+	diagnosis, err := diagnoser.DiagnoseConnection(ctx, req.Samples)
+	if err != nil {
+		writeProviderError(w, err)
+		return
+	}
+	diagnosis.Provider = name
+	logger.Infof("Diagnosed provider %s: %s", name, diagnosis.Status)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(diagnosis)
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/audit"
+	"github.com/Zhichengu1/mock-control-plane/pkg/client"
+	"github.com/Zhichengu1/mock-control-plane/pkg/fanout"
+	"github.com/Zhichengu1/mock-control-plane/pkg/provider"
+)
+
+// =============================================================================
+// PROVIDER HEALTH AND BLAST RADIUS
+// =============================================================================
+// GET /health answers "should this pod get traffic?". GET /providers/health
+// answers the operator's question when a vendor region degrades: how bad
+// is it, and what does it take down with it? For every provider:
+//
+//   check      a live HealthCheck against the vendor API
+//   hosts      circuit breaker state per vendor API host (pkg/client)
+//   calls      error rate of recent vendor calls, from the audit log
+//   slots      vendor concurrency slots in use
+//   resources  the resources on this vendor, by phase and namespace,
+//              plus every resource that depends on them (transitively)
+//
+// Status is "unhealthy" if the check fails or a circuit is open,
+// "degraded" if a circuit is probing or the error rate is high, and
+// "healthy" otherwise.
+//
+// WHY ALWAYS 200: This is a report, not a probe; a load balancer polling
+// it must not pull the controller because a vendor is down.
+// =============================================================================
+
+// Provider health statuses.
+const (
+	providerHealthy   = "healthy"
+	providerDegraded  = "degraded"
+	providerUnhealthy = "unhealthy"
+)
+
+// Error rate thresholds for "degraded".
+// WHY A MINIMUM: One failed call out of two is noise, not an outage.
+const (
+	providerDegradedErrorRate = 0.1
+	providerMinCallsForRate   = 5
+)
+
+// defaultProviderHealthWindow is how far back call error rates look.
+const defaultProviderHealthWindow = 5 * time.Minute
+
+// ProviderHealth is one provider's entry in GET /providers/health.
+type ProviderHealth struct {
+	Name    string   `json:"name"`
+	Status  string   `json:"status"`
+	Reasons []string `json:"reasons,omitempty"`
+
+	// CheckError is the HealthCheck error ("" if it passed)
+	CheckError string `json:"check_error,omitempty"`
+	CheckMS    int64  `json:"check_ms"`
+
+	Hosts     []client.HostState `json:"hosts"`
+	Calls     ProviderCallStats  `json:"calls"`
+	Slots     map[string]int     `json:"slots,omitempty"`
+	Resources ProviderBlast      `json:"resources"`
+
+	// Maintenance is the vendor's active maintenance window, if any
+	Maintenance *MaintenanceWindow `json:"maintenance,omitempty"`
+}
+
+// ProviderCallStats summarizes recent vendor calls to a provider's hosts.
+type ProviderCallStats struct {
+	Total     int     `json:"total"`
+	Failed    int     `json:"failed"`
+	ErrorRate float64 `json:"error_rate"`
+	AvgMS     int64   `json:"avg_ms"`
+	LastError string  `json:"last_error,omitempty"`
+}
+
+// ProviderBlast lists what depends on a provider.
+type ProviderBlast struct {
+	// Total resources hosted on the vendor
+	Total       int            `json:"total"`
+	ByPhase     map[string]int `json:"by_phase"`
+	ByNamespace map[string]int `json:"by_namespace"`
+	IDs         []string       `json:"ids"`
+
+	// Dependents are resources elsewhere that depend on the vendor's
+	// resources, directly or through other resources
+	Dependents []string `json:"dependents"`
+}
+
+// HandleProvidersHealth handles GET /providers/health
+// Query parameters: window (duration for call error rates, default 5m).
+func (c *Controller) HandleProvidersHealth(w http.ResponseWriter, r *http.Request) {
+	window := defaultProviderHealthWindow
+	if v := r.URL.Query().Get("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "window must be a positive duration like \"5m\""})
+			return
+		}
+		window = d
+	}
+
+	ctx, cancel := context.WithTimeout(vendorContext(r), 5*time.Second)
+	defer cancel()
+	overall, items := c.providersHealth(ctx, window)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": overall,
+		"window": window.String(),
+		"items":  items,
+	})
+}
+
+// providersHealth checks every provider and returns the overall status and
+// one entry per provider, with call stats over window.
+func (c *Controller) providersHealth(ctx context.Context, window time.Duration) (string, []ProviderHealth) {
+	cfg := c.config()
+	// Step 1: Live checks, concurrently so one slow vendor doesn't add up
+	names := sortedKeys(cfg.Providers)
+	branches, _ := fanout.Map(ctx, names, fanout.Options{}, func(ctx context.Context, name string) (ProviderHealth, error) {
+		item := ProviderHealth{Name: name, Status: providerHealthy, Hosts: []client.HostState{}}
+		started := time.Now()
+		if err := c.checkProvider(ctx, name); err != nil {
+			item.CheckError = err.Error()
+		}
+		item.CheckMS = time.Since(started).Milliseconds()
+		return item, nil
+	})
+	items := make([]ProviderHealth, len(names))
+	for i, branch := range branches {
+		items[i] = branch.Value
+		if branch.Err != nil {
+			items[i] = ProviderHealth{Name: names[i], Status: providerHealthy, Hosts: []client.HostState{}, CheckError: branch.Err.Error()}
+		}
+	}
+
+	// Step 2: Attribute hosts, calls and resources
+	hostStates := client.HostStates()
+	calls := c.Audit.Query(audit.Filter{Kind: audit.KindVendorCall, Since: c.Clock.Now().Add(-window)})
+	blast := c.providerBlastRadius()
+
+	overall := providerHealthy
+	for i := range items {
+		item := &items[i]
+		hosts := make(map[string]bool)
+		if lister, ok := cfg.Providers[item.Name].(provider.HostLister); ok {
+			for _, host := range lister.APIHosts() {
+				hosts[host] = true
+			}
+		}
+		for _, state := range hostStates {
+			if hosts[state.Host] {
+				item.Hosts = append(item.Hosts, state)
+			}
+		}
+		item.Calls = providerCallStats(calls, hosts)
+		if slots, ok := c.VendorSlots[item.Name]; ok {
+			item.Slots = map[string]int{"in_use": slots.InUse(), "capacity": slots.Capacity()}
+		}
+		item.Resources = blast[item.Name]
+
+		item.Status, item.Reasons = providerStatus(item)
+		if mw := c.activeMaintenance(item.Name); mw != nil {
+			mw.State = maintenanceActive
+			item.Maintenance = mw
+			item.Reasons = append(item.Reasons, "in maintenance until "+mw.End.UTC().Format(time.RFC3339))
+		}
+		overall = worseProviderStatus(overall, item.Status)
+	}
+	return overall, items
+}
+
+// providerStatus derives a provider's status and the reasons for it.
+func providerStatus(item *ProviderHealth) (string, []string) {
+	status := providerHealthy
+	var reasons []string
+	if item.CheckError != "" {
+		status = providerUnhealthy
+		reasons = append(reasons, "health check failed")
+	}
+	for _, host := range item.Hosts {
+		switch host.State {
+		case client.CircuitOpen:
+			status = providerUnhealthy
+			reasons = append(reasons, "circuit open for "+host.Host)
+		case client.CircuitHalfOpen:
+			status = worseProviderStatus(status, providerDegraded)
+			reasons = append(reasons, "circuit half-open for "+host.Host)
+		}
+	}
+	if item.Calls.Total >= providerMinCallsForRate && item.Calls.ErrorRate >= providerDegradedErrorRate {
+		status = worseProviderStatus(status, providerDegraded)
+		reasons = append(reasons, "high error rate on recent calls")
+	}
+	return status, reasons
+}
+
+// worseProviderStatus returns the more severe of two statuses.
+func worseProviderStatus(a, b string) string {
+	rank := map[string]int{providerHealthy: 0, providerDegraded: 1, providerUnhealthy: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+// providerCallStats summarizes the vendor calls whose URL host is in hosts.
+// A call failed if it got no response or a 5xx.
+func providerCallStats(calls []audit.Entry, hosts map[string]bool) ProviderCallStats {
+	var stats ProviderCallStats
+	var totalMS int64
+	for _, call := range calls {
+		u, err := url.Parse(call.URL)
+		if err != nil || !hosts[u.Host] {
+			continue
+		}
+		stats.Total++
+		totalMS += call.DurationMS
+		switch {
+		case call.Error != "":
+			stats.Failed++
+			stats.LastError = call.Error
+		case call.StatusCode >= 500:
+			stats.Failed++
+			stats.LastError = http.StatusText(call.StatusCode)
+		}
+	}
+	if stats.Total > 0 {
+		stats.ErrorRate = float64(stats.Failed) / float64(stats.Total)
+		stats.AvgMS = totalMS / int64(stats.Total)
+	}
+	return stats
+}
+
+// providerBlastRadius groups resources by vendor and finds everything
+// that depends on each vendor's resources.
+func (c *Controller) providerBlastRadius() map[string]ProviderBlast {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	// dependents["res-a"] = resources listing res-a in depends_on
+	dependents := make(map[string][]string)
+	for _, res := range c.ResourceDB {
+		for _, dep := range res.DependsOn {
+			dependents[dep] = append(dependents[dep], res.ID)
+		}
+	}
+
+	result := make(map[string]ProviderBlast)
+	for name := range c.config().Providers {
+		result[name] = ProviderBlast{ByPhase: map[string]int{}, ByNamespace: map[string]int{}, IDs: []string{}, Dependents: []string{}}
+	}
+	for _, res := range c.ResourceDB {
+		blast, ok := result[res.Spec.VendorType]
+		if !ok {
+			continue
+		}
+		blast.Total++
+		blast.ByPhase[res.Status.Phase]++
+		blast.ByNamespace[namespaceKey(res.Namespace)]++
+		blast.IDs = append(blast.IDs, res.ID)
+		result[res.Spec.VendorType] = blast
+	}
+
+	for name, blast := range result {
+		// Walk the dependency graph outward from the vendor's resources
+		seen := make(map[string]bool, len(blast.IDs))
+		queue := append([]string(nil), blast.IDs...)
+		for _, id := range blast.IDs {
+			seen[id] = true
+		}
+		for len(queue) > 0 {
+			id := queue[0]
+			queue = queue[1:]
+			for _, dependent := range dependents[id] {
+				if !seen[dependent] {
+					seen[dependent] = true
+					blast.Dependents = append(blast.Dependents, dependent)
+					queue = append(queue, dependent)
+				}
+			}
+		}
+		sort.Strings(blast.IDs)
+		sort.Strings(blast.Dependents)
+		result[name] = blast
+	}
+	return result
+}
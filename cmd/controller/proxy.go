@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// =============================================================================
+// BASE PATH AND REVERSE PROXIES
+// =============================================================================
+// Behind a shared ingress gateway the controller is usually reachable at
+// something like https://gateway.example.com/api/forge/v1/resources.
+//
+//   BASE_PATH=/api/forge/v1   → serve every route under this prefix
+//                               (GET /health also stays at the root for probes)
+//   TRUSTED_PROXIES=10.0.0.0/8,192.168.1.5   → honor X-Forwarded-* / Forwarded
+//                               from these addresses ("*" trusts everyone)
+//
+// From a trusted proxy we take the original scheme, host, path prefix
+// (X-Forwarded-Prefix, for gateways that strip it) and client IP. They are
+// used for links in responses (Location headers) and as the client
+// identity for rate limiting.
+//
+// WHY ONLY FROM TRUSTED PROXIES: Anyone can send X-Forwarded-For; trusting
+// it from arbitrary clients would let them dodge rate limits.
+// =============================================================================
+
+// normalizeBasePath turns "api/forge/v1/" into "/api/forge/v1" ("/" → "").
+func normalizeBasePath(p string) string {
+	p = strings.Trim(strings.TrimSpace(p), "/")
+	if p == "" {
+		return ""
+	}
+	return "/" + p
+}
+
+// trustedProxies matches the remote addresses allowed to set forwarding
+// headers.
+type trustedProxies struct {
+	all  bool
+	nets []*net.IPNet
+}
+
+// parseTrustedProxies parses a comma-separated list of IPs and CIDRs.
+// Invalid entries are logged and ignored.
+func parseTrustedProxies(spec string) *trustedProxies {
+	proxies := &trustedProxies{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		switch {
+		case entry == "":
+			continue
+		case entry == "*":
+			proxies.all = true
+			continue
+		case !strings.Contains(entry, "/"):
+			if strings.Contains(entry, ":") {
+				entry += "/128"
+			} else {
+				entry += "/32"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			logger.Warnf("Ignoring invalid TRUSTED_PROXIES entry %q", entry)
+			continue
+		}
+		proxies.nets = append(proxies.nets, ipNet)
+	}
+	return proxies
+}
+
+// contains reports whether ip (a bare IP string) is a trusted proxy.
+func (t *trustedProxies) contains(ip string) bool {
+	if t == nil {
+		return false
+	}
+	if t.all {
+		return true
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, ipNet := range t.nets {
+		if ipNet.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestOrigin is how the original client reached us.
+type requestOrigin struct {
+	Scheme   string // "https"
+	Host     string // "gateway.example.com"
+	Prefix   string // path prefix stripped by the proxy, e.g. "/forge"
+	ClientIP string // original client address
+}
+
+type originKey struct{}
+
+// originFrom returns the origin stored by ForwardedMiddleware.
+func originFrom(ctx context.Context) (requestOrigin, bool) {
+	origin, ok := ctx.Value(originKey{}).(requestOrigin)
+	return origin, ok
+}
+
+// ForwardedMiddleware records the request's original scheme, host, prefix
+// and client IP, taking forwarding headers into account when the direct
+// peer is a trusted proxy.
+func (c *Controller) ForwardedMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := requestOrigin{Scheme: "http", Host: r.Host, ClientIP: remoteIP(r)}
+		if r.TLS != nil {
+			origin.Scheme = "https"
+		}
+		if c.TrustedProxies.contains(origin.ClientIP) {
+			c.applyForwardedHeaders(r, &origin)
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), originKey{}, origin)))
+	})
+}
+
+// applyForwardedHeaders overrides origin from the RFC 7239 Forwarded
+// header or the de-facto X-Forwarded-* headers.
+func (c *Controller) applyForwardedHeaders(r *http.Request, origin *requestOrigin) {
+	var forwardedFor []string
+	if fwd := r.Header.Get("Forwarded"); fwd != "" {
+		// Use the first (client-side) element: for=...;proto=...;host=...
+		for _, pair := range strings.Split(strings.Split(fwd, ",")[0], ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok {
+				continue
+			}
+			value = strings.Trim(value, `"`)
+			switch strings.ToLower(key) {
+			case "proto":
+				origin.Scheme = value
+			case "host":
+				origin.Host = value
+			}
+		}
+		for _, element := range strings.Split(fwd, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(key, "for") {
+					addr := stripPort(strings.Trim(value, `"`))
+					forwardedFor = append(forwardedFor, strings.Trim(addr, "[]"))
+				}
+			}
+		}
+	} else {
+		if proto := firstHeaderValue(r, "X-Forwarded-Proto"); proto != "" {
+			origin.Scheme = proto
+		}
+		if host := firstHeaderValue(r, "X-Forwarded-Host"); host != "" {
+			origin.Host = host
+		}
+		for _, ip := range strings.Split(r.Header.Get("X-Forwarded-For"), ",") {
+			if ip = strings.TrimSpace(ip); ip != "" {
+				forwardedFor = append(forwardedFor, stripPort(ip))
+			}
+		}
+	}
+	origin.Prefix = normalizeBasePath(firstHeaderValue(r, "X-Forwarded-Prefix"))
+
+	// WHY RIGHT TO LEFT: Each proxy appends the address it received from;
+	// the client is the first address not belonging to a trusted proxy.
+	// Entries further left could have been forged by the client.
+	for i := len(forwardedFor) - 1; i >= 0; i-- {
+		origin.ClientIP = forwardedFor[i]
+		if !c.TrustedProxies.contains(forwardedFor[i]) {
+			break
+		}
+	}
+}
+
+// externalURL builds the absolute URL clients use to reach path (a route
+// path like "/resources/res-1") on this controller.
+func (c *Controller) externalURL(r *http.Request, path string) string {
+	origin, ok := originFrom(r.Context())
+	if !ok {
+		origin = requestOrigin{Scheme: "http", Host: r.Host}
+	}
+	return origin.Scheme + "://" + origin.Host + origin.Prefix + c.BasePath + path
+}
+
+// firstHeaderValue returns the first comma-separated value of a header.
+func firstHeaderValue(r *http.Request, name string) string {
+	return strings.TrimSpace(strings.Split(r.Header.Get(name), ",")[0])
+}
+
+// remoteIP returns the IP of the direct peer.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// stripPort removes a trailing ":port" from an IPv4 address or host.
+func stripPort(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// loadProxyConfig reads BASE_PATH and TRUSTED_PROXIES.
+func loadProxyConfig() (string, *trustedProxies) {
+	return normalizeBasePath(os.Getenv("BASE_PATH")), parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+}
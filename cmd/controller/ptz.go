@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/Zhichengu1/mock-control-plane/pkg/provider"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// PTZ CONTROL AND CAMERA PRESETS
+// =============================================================================
+// Camera operators move PTZ heads and recall saved shots during a show:
+//
+//   GET  /resources/{id}/ptz                      → current position
+//   POST /resources/{id}/ptz                      → move / home / stop
+//   GET  /resources/{id}/presets                  → saved presets
+//   POST /resources/{id}/presets                  → save current position
+//   POST /resources/{id}/presets/{number}/recall  → move to a preset
+//
+// Example:
+//
+//	curl -X POST localhost:8080/resources/res-1/ptz \
+//	  -d '{"action": "absolute", "pan": 30, "tilt": -5, "zoom": 0.5}'
+//
+// Positions live in the camera; the controller only records events for
+// preset changes (moves are too frequent to be worth an event each).
+// =============================================================================
+
+// cameraTarget resolves the resource and its CameraController for a PTZ or
+// preset request, writing an error response and returning ok=false on failure.
+func (c *Controller) cameraTarget(w http.ResponseWriter, id string) (res models.ForgeResource, camera provider.CameraController, ok bool) {
+	c.mu.RLock()
+	stored, exists := c.ResourceDB[id]
+	if exists {
+		res = *stored.DeepCopy()
+	}
+	c.mu.RUnlock()
+
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "resource not found"})
+		return res, nil, false
+	}
+	if res.Status.VendorID == "" {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "resource has no vendor device (phase " + res.Status.Phase + ")"})
+		return res, nil, false
+	}
+	p, exists := c.config().Providers[res.Spec.VendorType]
+	if !exists {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "provider not configured"})
+		return res, nil, false
+	}
+	camera, supported := p.(provider.CameraController)
+	if !supported {
+		w.WriteHeader(http.StatusNotImplemented)
+		json.NewEncoder(w).Encode(map[string]string{"error": "vendor " + res.Spec.VendorType + " does not support PTZ control"})
+		return res, nil, false
+	}
+	return res, camera, true
+}
+
+// cameraCall runs fn against the camera holding a vendor slot. It writes
+// the error response itself and returns false on failure.
+func (c *Controller) cameraCall(w http.ResponseWriter, r *http.Request, res models.ForgeResource, fn func(ctx context.Context) error) bool {
+	ctx, cancel := context.WithTimeout(vendorContext(r), 30*time.Second)
+	defer cancel()
+	release, err := c.acquireVendor(ctx, res.Spec.VendorType)
+	if err != nil {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return false
+	}
+	err = fn(ctx)
+	release()
+	if err != nil {
+		writeProviderError(w, err)
+		return false
+	}
+	return true
+}
+
+// HandleGetPTZ handles GET /resources/{id}/ptz
+func (c *Controller) HandleGetPTZ(w http.ResponseWriter, r *http.Request) {
+	res, camera, ok := c.cameraTarget(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	var position *models.PTZPosition
+	if !c.cameraCall(w, r, res, func(ctx context.Context) (err error) {
+		position, err = camera.GetPTZ(ctx, res.Status.VendorID)
+		return err
+	}) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(position)
+}
+
+// HandleMovePTZ handles POST /resources/{id}/ptz
+func (c *Controller) HandleMovePTZ(w http.ResponseWriter, r *http.Request) {
+	var cmd models.PTZCommand
+	if err := json.NewDecoder(r.Body).Decode(&cmd); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+	if err := cmd.Validate(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	res, camera, ok := c.cameraTarget(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	var position *models.PTZPosition
+	if !c.cameraCall(w, r, res, func(ctx context.Context) (err error) {
+		position, err = camera.MovePTZ(ctx, res.Status.VendorID, cmd)
+		return err
+	}) {
+		return
+	}
+	logger.Debugf("PTZ %s on %s → pan %.1f tilt %.1f zoom %.2f", cmd.Action, res.ID, position.Pan, position.Tilt, position.Zoom)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(position)
+}
+
+// HandleListPresets handles GET /resources/{id}/presets
+func (c *Controller) HandleListPresets(w http.ResponseWriter, r *http.Request) {
+	res, camera, ok := c.cameraTarget(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	var presets []models.CameraPreset
+	if !c.cameraCall(w, r, res, func(ctx context.Context) (err error) {
+		presets, err = camera.ListPresets(ctx, res.Status.VendorID)
+		return err
+	}) {
+		return
+	}
+	if presets == nil {
+		presets = []models.CameraPreset{}
+	}
+	sort.Slice(presets, func(i, j int) bool { return presets[i].Number < presets[j].Number })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": presets})
+}
+
+// HandleSavePreset handles POST /resources/{id}/presets
+func (c *Controller) HandleSavePreset(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var req models.PresetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+	if req.Number < 1 || req.Number > models.MaxPresets {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("number must be between 1 and %d", models.MaxPresets)})
+		return
+	}
+
+	res, camera, ok := c.cameraTarget(w, id)
+	if !ok {
+		return
+	}
+
+	var preset *models.CameraPreset
+	if !c.cameraCall(w, r, res, func(ctx context.Context) (err error) {
+		preset, err = camera.SavePreset(ctx, res.Status.VendorID, req)
+		return err
+	}) {
+		return
+	}
+
+	c.recordResourceEvent(id, models.ReasonPresetSaved,
+		fmt.Sprintf("Preset %d %q saved at pan %.1f tilt %.1f zoom %.2f", preset.Number, preset.Name,
+			preset.Position.Pan, preset.Position.Tilt, preset.Position.Zoom))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(preset)
+}
+
+// HandleRecallPreset handles POST /resources/{id}/presets/{number}/recall
+func (c *Controller) HandleRecallPreset(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	number, err := strconv.Atoi(vars["number"])
+	if err != nil || number < 1 || number > models.MaxPresets {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("preset number must be between 1 and %d", models.MaxPresets)})
+		return
+	}
+
+	res, camera, ok := c.cameraTarget(w, id)
+	if !ok {
+		return
+	}
+
+	var position *models.PTZPosition
+	if !c.cameraCall(w, r, res, func(ctx context.Context) (err error) {
+		position, err = camera.RecallPreset(ctx, res.Status.VendorID, number)
+		return err
+	}) {
+		return
+	}
+
+	c.recordResourceEvent(id, models.ReasonPresetRecalled, fmt.Sprintf("Preset %d recalled", number))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(position)
+}
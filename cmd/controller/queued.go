@@ -0,0 +1,594 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/client"
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// QUEUED VENDOR CALLS (CIRCUIT OPEN)
+// =============================================================================
+// While a vendor host's circuit breaker is open (pkg/client/breaker.go),
+// creates and updates fail fast with 503 and every client has to write its
+// own retry loop. With the queue policy they are accepted instead:
+//
+//   create    the resource is stored in phase Queued (202) and created on
+//             the vendor once the circuit lets calls through again
+//   update    the resource goes to phase Queued (202) and the new spec is
+//             pushed later; its current spec stays until then
+//
+// The replayer retries queued calls every QUEUED_REPLAY_INTERVAL, oldest
+// first. A call refused by the breaker again costs nothing (it never
+// leaves the controller) and keeps the rest of that vendor's calls
+// waiting, so a resource's updates are applied in the order they came.
+// An update to a resource with calls waiting is queued behind them.
+//
+// The policy comes from ?onCircuitOpen=queue|reject on the request, else
+// from CIRCUIT_OPEN_POLICY ("prod=queue,*=reject"; reject by default).
+// Calls still waiting after QUEUED_CALL_MAX_AGE give up: a create ends
+// Failed, an update is dropped and the resource gets its phase back.
+// Deleting the resource cancels its calls.
+//
+// WHY A SEPARATE LOCK: Calls are queued with c.mu held, so the queue has
+// its own mutex (always taken after c.mu).
+// =============================================================================
+
+// phaseQueued marks a resource whose create or update waits for its vendor.
+const phaseQueued = "Queued"
+
+// Circuit open policies.
+const (
+	circuitReject = "reject"
+	circuitQueue  = "queue"
+)
+
+// Queued call states besides those shared with deferred mutations:
+// waited longer than the maximum age, or given up on by an operator or a
+// delete.
+const (
+	queuedExpired   = "expired"
+	queuedCancelled = "cancelled"
+)
+
+// CircuitOpenPolicy decides whether writes refused by an open circuit
+// are queued, per namespace.
+type CircuitOpenPolicy struct {
+	// Default applies to namespaces without an entry
+	Default string
+
+	// Namespaces maps namespace → policy
+	Namespaces map[string]string
+
+	// MaxAge is how long a call may wait before it gives up
+	MaxAge time.Duration
+
+	// ReplayInterval is how often queued calls are retried
+	ReplayInterval time.Duration
+}
+
+// loadCircuitOpenPolicy reads CIRCUIT_OPEN_POLICY: a policy, or
+// "namespace=policy" entries with "*" for the default.
+func loadCircuitOpenPolicy() CircuitOpenPolicy {
+	policy := CircuitOpenPolicy{
+		Default:        circuitReject,
+		Namespaces:     make(map[string]string),
+		MaxAge:         envDuration("QUEUED_CALL_MAX_AGE", time.Hour),
+		ReplayInterval: envDuration("QUEUED_REPLAY_INTERVAL", 10*time.Second),
+	}
+	for _, entry := range strings.Split(os.Getenv("CIRCUIT_OPEN_POLICY"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		namespace, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			namespace, value = "*", entry
+		}
+		if value != circuitReject && value != circuitQueue {
+			logger.Warnf("Ignoring CIRCUIT_OPEN_POLICY entry %q: policy must be %s or %s", entry, circuitQueue, circuitReject)
+			continue
+		}
+		if namespace == "*" {
+			policy.Default = value
+		} else {
+			policy.Namespaces[namespace] = value
+		}
+	}
+	return policy
+}
+
+// queues reports whether a write to namespace refused by an open circuit
+// is queued; ?onCircuitOpen= on r overrides the policy.
+func (p CircuitOpenPolicy) queues(r *http.Request, namespace string) bool {
+	if r != nil {
+		if value := r.URL.Query().Get("onCircuitOpen"); value != "" {
+			return value == circuitQueue
+		}
+	}
+	if value, ok := p.Namespaces[namespaceKey(namespace)]; ok {
+		return value == circuitQueue
+	}
+	return p.Default == circuitQueue
+}
+
+// rejectBadCircuitOpenParam writes 400 for an unknown ?onCircuitOpen=.
+func rejectBadCircuitOpenParam(w http.ResponseWriter, r *http.Request) bool {
+	value := r.URL.Query().Get("onCircuitOpen")
+	if value == "" || value == circuitQueue || value == circuitReject {
+		return false
+	}
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": "onCircuitOpen must be " + circuitQueue + " or " + circuitReject})
+	return true
+}
+
+// isCircuitOpen reports whether err is a call refused by an open circuit.
+func isCircuitOpen(err error) bool {
+	return errors.Is(err, client.ErrCircuitOpen)
+}
+
+// QueuedCall is a create or update waiting for its vendor's circuit to close.
+type QueuedCall struct {
+	ID         string `json:"id"`
+	ResourceID string `json:"resource_id"`
+	Vendor     string `json:"vendor"`
+
+	// Operation is "create" or "update"
+	Operation   string    `json:"operation"`
+	RequestedBy string    `json:"requested_by,omitempty"`
+	QueuedAt    time.Time `json:"queued_at"`
+
+	// Attempts counts replays refused by the breaker
+	Attempts    int       `json:"attempts"`
+	LastAttempt time.Time `json:"last_attempt,omitempty"`
+
+	State      string    `json:"state"`
+	FinishedAt time.Time `json:"finished_at,omitempty"`
+	Error      string    `json:"error,omitempty"`
+
+	// detail is appended to the event message when it is applied
+	detail string
+
+	// spec is the spec an "update" applies; strategy is a create's
+	// duplicate name strategy
+	spec     *models.ResourceSpec
+	strategy string
+
+	// previousPhase and previousMessage are restored if an update gives up
+	previousPhase   string
+	previousMessage string
+}
+
+// queuedState holds queued calls, oldest first.
+type queuedState struct {
+	mu    sync.Mutex
+	calls []*QueuedCall
+}
+
+// newQueuedCall fills in what every queued call records for res (the
+// stored record). Caller must hold c.mu.
+func (c *Controller) newQueuedCall(r *http.Request, res *models.ForgeResource, operation string) *QueuedCall {
+	q := &QueuedCall{
+		ID:         c.IDs.NewID("qc"),
+		ResourceID: res.ID,
+		Vendor:     res.Spec.VendorType,
+		Operation:  operation,
+		QueuedAt:   c.Clock.Now(),
+		State:      deferredQueued,
+	}
+	if r != nil {
+		if principal, ok := principalFrom(r.Context()); ok {
+			q.RequestedBy = principal.Name
+		}
+	}
+	return q
+}
+
+// queueCreate stores resource in phase Queued after its vendor refused
+// the create with cause. It keeps the capacity reservation the create
+// made; the caller has released its vendor slot.
+func (c *Controller) queueCreate(r *http.Request, resource *models.ForgeResource, duplicateStrategy string, cause error, stage txStage) error {
+	resource.Status.Phase = phaseQueued
+	resource.Status.Message = "Vendor unavailable (" + cause.Error() + "); create queued"
+	c.applyMaintenanceCondition(resource)
+
+	var q *QueuedCall
+	c.withTx(func(tx *storeTx) error {
+		dropped := c.dropStaleRefs(tx, resource)
+		tx.Put(resource, "queued")
+		tx.OnCommit(func() {
+			c.commitReservationLocked(resource.Namespace)
+			q = c.newQueuedCall(r, resource, "create")
+			q.strategy = duplicateStrategy
+			c.recordEvent(resource, models.EventWarning, models.ReasonQueued,
+				fmt.Sprintf("Create queued as %s until %s accepts calls: %v", q.ID, resource.Spec.VendorType, cause), phaseQueued, "")
+			c.recordDroppedRefsLocked(resource, dropped)
+			c.queued.mu.Lock()
+			c.queued.calls = append(c.queued.calls, q)
+			c.queued.mu.Unlock()
+		})
+		if stage != nil {
+			stage(tx, resource)
+		}
+		return nil
+	})
+	logger.Infof("Circuit open: queued create of %s (%s)", resource.ID, q.ID)
+	return nil
+}
+
+// hasQueuedCalls reports whether resource id has calls waiting.
+func (c *Controller) hasQueuedCalls(id string) bool {
+	c.queued.mu.Lock()
+	defer c.queued.mu.Unlock()
+	for _, q := range c.queued.calls {
+		if q.ResourceID == id && q.State == deferredQueued {
+			return true
+		}
+	}
+	return false
+}
+
+// queueBehindPending queues an update of resource id (answering 202) if
+// earlier calls for it are still waiting, so it can't overtake them.
+func (c *Controller) queueBehindPending(w http.ResponseWriter, r *http.Request, id, detail string, spec *models.ResourceSpec) bool {
+	if !c.hasQueuedCalls(id) {
+		return false
+	}
+	return c.queueUpdate(w, r, id, detail, spec, "earlier calls are still queued")
+}
+
+// queueAfterCircuitOpen queues an update of resource id (answering 202) if
+// err is its vendor's circuit refusing it and the policy says to queue.
+func (c *Controller) queueAfterCircuitOpen(w http.ResponseWriter, r *http.Request, id, detail string, spec *models.ResourceSpec, err error) bool {
+	if !isCircuitOpen(err) {
+		return false
+	}
+	c.mu.RLock()
+	stored, exists := c.ResourceDB[id]
+	queue := exists && c.config().CircuitOpen.queues(r, stored.Namespace)
+	c.mu.RUnlock()
+	if !queue {
+		return false
+	}
+	return c.queueUpdate(w, r, id, detail, spec, err.Error())
+}
+
+// queueUpdate puts resource id in phase Queued with an update to spec
+// waiting, and answers 202 with the queued call.
+func (c *Controller) queueUpdate(w http.ResponseWriter, r *http.Request, id, detail string, spec *models.ResourceSpec, cause string) bool {
+	c.mu.Lock()
+	stored, exists := c.ResourceDB[id]
+	if !exists {
+		c.mu.Unlock()
+		return false
+	}
+	q := c.newQueuedCall(r, stored, "update")
+	q.spec = spec
+	q.detail = detail + " (queued " + q.ID + ")"
+	if stored.Status.Phase == phaseQueued {
+		q.previousPhase, q.previousMessage = c.queuedPreviousPhase(id)
+	} else {
+		q.previousPhase, q.previousMessage = stored.Status.Phase, stored.Status.Message
+		stored.Status.Phase = phaseQueued
+		stored.Status.Message = "Update queued: " + cause
+		stored.UpdatedAt = c.Clock.Now()
+		c.recordRevision(stored, "queued", false)
+	}
+	c.recordEvent(stored, models.EventWarning, models.ReasonQueued,
+		fmt.Sprintf("Update queued as %s: %s", q.ID, cause), phaseQueued, "")
+	c.queued.mu.Lock()
+	c.queued.calls = append(c.queued.calls, q)
+	response := *q
+	c.queued.mu.Unlock()
+	c.mu.Unlock()
+
+	logger.Infof("Queued update of %s (%s): %s", id, q.ID, cause)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(response)
+	return true
+}
+
+// queuedPreviousPhase returns the phase resource id had before its waiting
+// calls were queued ("" if it was created queued).
+func (c *Controller) queuedPreviousPhase(id string) (string, string) {
+	c.queued.mu.Lock()
+	defer c.queued.mu.Unlock()
+	for _, q := range c.queued.calls {
+		if q.ResourceID == id && q.State == deferredQueued {
+			return q.previousPhase, q.previousMessage
+		}
+	}
+	return "", ""
+}
+
+// startQueuedReplay retries queued calls every ReplayInterval.
+func (c *Controller) startQueuedReplay(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-c.Clock.After(c.config().CircuitOpen.ReplayInterval):
+			}
+			c.replayQueued(ctx)
+		}
+	}()
+}
+
+// replayQueued retries waiting calls in order, gives up on expired ones
+// and forgets finished ones.
+func (c *Controller) replayQueued(ctx context.Context) {
+	cfg := c.config()
+	defer c.profileLoop("queued-replay", time.Now())
+	// Step 1: Snapshot the waiting calls, oldest first
+	c.queued.mu.Lock()
+	var waiting []*QueuedCall
+	for _, q := range c.queued.calls {
+		if q.State == deferredQueued {
+			waiting = append(waiting, q)
+		}
+	}
+	c.queued.mu.Unlock()
+
+	// Step 2: Replay; a vendor whose circuit is still open keeps the rest
+	// of its calls waiting
+	blocked := make(map[string]bool)
+	for _, q := range waiting {
+		if c.Clock.Since(q.QueuedAt) > cfg.CircuitOpen.MaxAge {
+			c.finishQueued(q, queuedExpired, fmt.Errorf("still queued after %s", cfg.CircuitOpen.MaxAge))
+			continue
+		}
+		// WHY SKIP TERMINATING: A delete in progress may still be refused;
+		// its calls are cancelled once the record is removed
+		if blocked[q.Vendor] || c.queuedState(q) != deferredQueued || c.resourcePhase(q.ResourceID) == phaseTerminating {
+			continue
+		}
+		var err error
+		if q.Operation == "create" {
+			err = c.replayCreate(ctx, q)
+		} else {
+			_, err = c.updateResourceSpec(ctx, q.ResourceID, *q.spec, "updated", q.detail)
+		}
+		if isCircuitOpen(err) {
+			blocked[q.Vendor] = true
+			c.queued.mu.Lock()
+			q.Attempts++
+			q.LastAttempt = c.Clock.Now()
+			q.Error = err.Error()
+			c.queued.mu.Unlock()
+			continue
+		}
+		if err != nil {
+			c.finishQueued(q, deferredFailed, err)
+			logger.Warnf("Queued %s of %s failed: %v", q.Operation, q.ResourceID, err)
+			continue
+		}
+		c.finishQueued(q, deferredApplied, nil)
+		logger.Infof("Replayed queued %s of %s", q.Operation, q.ResourceID)
+	}
+
+	// Step 3: Forget what's long over
+	cutoff := c.Clock.Now().Add(-maintenanceRetention)
+	c.queued.mu.Lock()
+	kept := c.queued.calls[:0]
+	for _, q := range c.queued.calls {
+		if q.State == deferredQueued || q.FinishedAt.After(cutoff) {
+			kept = append(kept, q)
+		}
+	}
+	c.queued.calls = kept
+	c.queued.mu.Unlock()
+}
+
+// queuedState returns q's state (it may have been cancelled meanwhile).
+func (c *Controller) queuedState(q *QueuedCall) string {
+	c.queued.mu.Lock()
+	defer c.queued.mu.Unlock()
+	return q.State
+}
+
+// resourcePhase returns resource id's phase ("" if it doesn't exist).
+func (c *Controller) resourcePhase(id string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if res, exists := c.ResourceDB[id]; exists {
+		return res.Status.Phase
+	}
+	return ""
+}
+
+// replayCreate creates a Queued resource on its vendor, checking for
+// duplicate names again first (the vendor may have changed meanwhile).
+func (c *Controller) replayCreate(parent context.Context, q *QueuedCall) error {
+	cfg := c.config()
+	c.mu.RLock()
+	stored, exists := c.ResourceDB[q.ResourceID]
+	var res models.ForgeResource
+	if exists {
+		res = *stored.DeepCopy()
+	}
+	c.mu.RUnlock()
+	if !exists {
+		return errResourceNotFound
+	}
+	selectedProvider, exists := cfg.Providers[res.Spec.VendorType]
+	if !exists {
+		return fmt.Errorf("provider %s not configured", res.Spec.VendorType)
+	}
+
+	ctx, cancel := context.WithTimeout(parent, 30*time.Second)
+	defer cancel()
+	release, err := c.acquireVendor(ctx, res.Spec.VendorType)
+	if err != nil {
+		return err
+	}
+	adopt, err := c.checkDuplicateName(ctx, selectedProvider, &res, q.strategy)
+	if err != nil {
+		release()
+		return err
+	}
+	var status *models.ResourceStatus
+	if adopt != nil {
+		res.Status.VendorID = adopt.VendorID
+		status, err = selectedProvider.Update(ctx, &res)
+	} else {
+		status, err = selectedProvider.Create(ctx, &res)
+	}
+	release()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stored, exists = c.ResourceDB[q.ResourceID]
+	if !exists {
+		// WHY LOG LOUDLY: The device now exists on the vendor with nothing
+		// managing it; discovery will offer it for adoption
+		logger.Warnf("Queued create %s: %s device %s was created after %s was deleted", q.ID, res.Spec.VendorType, status.VendorID, q.ResourceID)
+		return errResourceGone
+	}
+	oldStatus := stored.Status
+	stored.Name = res.Name
+	c.resetUniqueLocked(stored.ID, stored.Namespace, stored.Name, stored.Spec)
+	stored.Status = *status
+	cfg.HealthPolicy.Apply(stored)
+	c.applyMaintenanceCondition(stored)
+	stored.UpdatedAt = c.Clock.Now()
+	c.recordRevision(stored, "created", false)
+	message := fmt.Sprintf("Created %s device %s (queued %s)", stored.Spec.VendorType, stored.Status.VendorID, q.ID)
+	if adopt != nil {
+		message = fmt.Sprintf("Adopted existing %s device %s with the same name (queued %s)", stored.Spec.VendorType, stored.Status.VendorID, q.ID)
+	}
+	c.recordEvent(stored, models.EventNormal, models.ReasonCreated, message, stored.Status.Phase, stored.Status.HealthStatus)
+	c.recordStatusEvents(stored, oldStatus)
+	return nil
+}
+
+// finishQueued records q's outcome. A create that gave up leaves its
+// resource Failed; an update that gave up restores the resource's phase
+// once nothing else is queued for it.
+func (c *Controller) finishQueued(q *QueuedCall, state string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queued.mu.Lock()
+	q.State = state
+	q.FinishedAt = c.Clock.Now()
+	q.Error = ""
+	if err != nil {
+		q.Error = err.Error()
+	}
+	pending := false
+	for _, other := range c.queued.calls {
+		if other.ResourceID == q.ResourceID && other.State == deferredQueued {
+			pending = true
+		}
+	}
+	c.queued.mu.Unlock()
+
+	stored, exists := c.ResourceDB[q.ResourceID]
+	if state == deferredApplied || !exists || stored.Status.Phase != phaseQueued {
+		return
+	}
+	switch {
+	case q.Operation == "create":
+		stored.Status.Phase = "Failed"
+		stored.Status.Message = "Queued create gave up: " + err.Error()
+		c.recordEvent(stored, models.EventWarning, models.ReasonCreateFailed, stored.Status.Message, "Failed", "")
+	case pending || q.previousPhase == "":
+		return
+	default:
+		stored.Status.Phase, stored.Status.Message = q.previousPhase, q.previousMessage
+		// WHY NOT ON FAILED: updateResourceSpec recorded UpdateFailed already
+		if state != deferredFailed {
+			c.recordEvent(stored, models.EventWarning, models.ReasonUpdateFailed,
+				fmt.Sprintf("Queued update %s %s: %v", q.ID, state, err), stored.Status.Phase, "")
+		}
+	}
+	stored.UpdatedAt = c.Clock.Now()
+	c.recordRevision(stored, "queue-"+state, false)
+}
+
+// cancelQueuedCallsLocked cancels resource id's waiting calls (it is
+// being deleted). Caller must hold c.mu.
+func (c *Controller) cancelQueuedCallsLocked(id string) {
+	c.queued.mu.Lock()
+	defer c.queued.mu.Unlock()
+	for _, q := range c.queued.calls {
+		if q.ResourceID == id && q.State == deferredQueued {
+			q.State, q.Error = queuedCancelled, "resource deleted"
+			q.FinishedAt = c.Clock.Now()
+		}
+	}
+}
+
+// HandleListQueued handles GET /admin/queued
+// Lists queued calls, oldest first, with the breaker state of each host.
+func (c *Controller) HandleListQueued(w http.ResponseWriter, r *http.Request) {
+	cfg := c.config()
+	c.queued.mu.Lock()
+	items := make([]QueuedCall, 0, len(c.queued.calls))
+	waiting := 0
+	for _, q := range c.queued.calls {
+		items = append(items, *q)
+		if q.State == deferredQueued {
+			waiting++
+		}
+	}
+	c.queued.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"items":    items,
+		"waiting":  waiting,
+		"policy":   cfg.CircuitOpen.Default,
+		"max_age":  cfg.CircuitOpen.MaxAge.String(),
+		"circuits": client.HostStates(),
+	})
+}
+
+// HandleCancelQueued handles DELETE /admin/queued/{id}
+// Gives up on a waiting call: a create ends Failed, an update is dropped.
+func (c *Controller) HandleCancelQueued(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	c.queued.mu.Lock()
+	var found *QueuedCall
+	for _, q := range c.queued.calls {
+		if q.ID == id {
+			found = q
+		}
+	}
+	state := ""
+	if found != nil {
+		state = found.State
+	}
+	c.queued.mu.Unlock()
+
+	switch {
+	case found == nil:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "queued call not found"})
+		return
+	case state != deferredQueued:
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "queued call is already " + state})
+		return
+	}
+	detail := "cancelled"
+	if principal, ok := principalFrom(r.Context()); ok {
+		detail += " by " + principal.Name
+	}
+	c.finishQueued(found, queuedCancelled, errors.New(detail))
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/ratelimit"
+)
+
+// =============================================================================
+// BACK-PRESSURE SIGNALING
+// =============================================================================
+// Every response carries headers describing how close the caller is to
+// being throttled, so SDKs and scripts can slow down BEFORE they hit 429:
+//
+//   X-RateLimit-Limit:     requests allowed per window for this client
+//   X-RateLimit-Remaining: requests left in the current window
+//   X-RateLimit-Reset:     seconds until the window resets
+//
+// The same information plus the vendor-facing concurrency limits is also
+// emitted in the structured-field format of the IETF RateLimit header
+// fields draft:
+//
+//   RateLimit-Policy: "client";q=600;w=60, "vendor-sony";q=10
+//   RateLimit:        "client";r=599;t=42, "vendor-sony";r=8
+//
+// WHY VENDOR POLICIES: Vendor APIs have their own limits. The controller
+// caps concurrent calls per vendor (semaphores); advertising free slots
+// lets batch clients back off when a vendor is saturated even though
+// their own request quota is fine.
+// =============================================================================
+
+// RateLimitMiddleware enforces the per-client request limit and sets the
+// back-pressure headers on every response.
+func (c *Controller) RateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var policies, states []string
+
+		// WHY EXEMPT /health: Kubernetes probes must never be throttled, or a
+		// busy client could get the pod marked unhealthy
+		// WHY c.config(): A reload stores a new config with a new limiter
+		// (see reload.go); this request counts against the one it loaded
+		if limiter := c.config().RateLimiter; limiter != nil && !c.isHealthPath(r.URL.Path) {
+			decision := limiter.Allow(clientKey(r))
+			resetSeconds := int(math.Ceil(decision.Reset.Seconds()))
+
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(decision.Limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+			w.Header().Set("X-RateLimit-Reset", strconv.Itoa(resetSeconds))
+			policies = append(policies, fmt.Sprintf(`"client";q=%d;w=%d`, decision.Limit, int(limiter.Period().Seconds())))
+			states = append(states, fmt.Sprintf(`"client";r=%d;t=%d`, decision.Remaining, resetSeconds))
+
+			if !decision.Allowed {
+				c.setVendorRateLimitHeaders(w, policies, states)
+				// WHY Retry-After: Standard header most HTTP clients honor
+				w.Header().Set("Retry-After", strconv.Itoa(resetSeconds))
+				w.WriteHeader(http.StatusTooManyRequests)
+				json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("rate limit exceeded, retry in %ds", resetSeconds)})
+				return
+			}
+		}
+
+		c.setVendorRateLimitHeaders(w, policies, states)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// setVendorRateLimitHeaders appends one policy per vendor semaphore and
+// writes the RateLimit-Policy / RateLimit headers.
+func (c *Controller) setVendorRateLimitHeaders(w http.ResponseWriter, policies, states []string) {
+	// WHY SORT: Stable header order keeps responses diffable
+	vendors := make([]string, 0, len(c.VendorSlots))
+	for vendor := range c.VendorSlots {
+		vendors = append(vendors, vendor)
+	}
+	sort.Strings(vendors)
+
+	for _, vendor := range vendors {
+		slots := c.VendorSlots[vendor]
+		policies = append(policies, fmt.Sprintf(`"vendor-%s";q=%d`, vendor, slots.Capacity()))
+		states = append(states, fmt.Sprintf(`"vendor-%s";r=%d`, vendor, slots.Available()))
+	}
+	if len(policies) > 0 {
+		w.Header().Set("RateLimit-Policy", strings.Join(policies, ", "))
+		w.Header().Set("RateLimit", strings.Join(states, ", "))
+	}
+}
+
+// acquireVendor waits for a concurrency slot for vendor's API.
+// The returned release func must be called when the vendor call finishes.
+//
+// WHY WAIT (not fail fast): Short bursts should queue behind in-flight
+// calls; the caller's context timeout bounds how long we wait.
+func (c *Controller) acquireVendor(ctx context.Context, vendor string) (func(), error) {
+	slots, ok := c.VendorSlots[vendor]
+	if !ok {
+		return func() {}, nil // No limit configured for this vendor
+	}
+	if err := slots.Acquire(ctx); err != nil {
+		return nil, &vendorBusyError{vendor: vendor, capacity: slots.Capacity(), err: err}
+	}
+	return slots.Release, nil
+}
+
+// vendorBusyError is returned by acquireVendor when no slot freed up in
+// time. Handlers answer it with 503 and Retry-After.
+type vendorBusyError struct {
+	vendor   string
+	capacity int
+	err      error
+}
+
+func (e *vendorBusyError) Error() string {
+	return fmt.Sprintf("vendor %s concurrency limit (%d) reached: %v", e.vendor, e.capacity, e.err)
+}
+
+func (e *vendorBusyError) Unwrap() error { return e.err }
+
+// newVendorSlots creates one semaphore per configured provider.
+func newVendorSlots(vendors []string, capacity int) map[string]*ratelimit.Semaphore {
+	slots := make(map[string]*ratelimit.Semaphore, len(vendors))
+	for _, vendor := range vendors {
+		slots[vendor] = ratelimit.NewSemaphore(capacity)
+	}
+	return slots
+}
+
+// clientKey identifies the caller for rate limiting purposes.
+// WHY IP: API keys are optional, so the client address is the only
+// identity every request has. Behind a trusted proxy it is the original
+// client's address (see proxy.go), not the proxy's.
+func clientKey(r *http.Request) string {
+	if origin, ok := originFrom(r.Context()); ok {
+		return origin.ClientIP
+	}
+	return remoteIP(r)
+}
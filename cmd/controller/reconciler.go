@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/fairqueue"
+	"github.com/Zhichengu1/mock-control-plane/pkg/logging"
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// STATUS RECONCILER
+// =============================================================================
+// The reconciler keeps stored status in step with the vendors without
+// waiting for a client to GET each resource. Every RECONCILE_INTERVAL it
+// queues every provisioned resource; RECONCILE_WORKERS workers take them
+// off the queue and refresh their status (see refreshStatus).
+//
+// WHY A FAIR QUEUE: With a plain FIFO, a vendor with 10,000 resources
+// would occupy every worker for the whole pass while a vendor with 20
+// waited behind it. pkg/fairqueue serves vendors, and namespaces within a
+// vendor, in weighted turns:
+//
+//   RECONCILE_VENDOR_WEIGHTS      "sony=2,mock=1" (default weight 1)
+//   RECONCILE_NAMESPACE_WEIGHTS   "production=4"  (default weight 1)
+//   RECONCILE_VENDOR_CONCURRENCY  max refreshes in flight per vendor
+//
+// A resource still queued from the last pass isn't queued twice, so a
+// backlog shows up as age, not unbounded depth. GET /admin/reconciler
+// reports depth and age of the oldest item per queue.
+//
+// Passes are skipped while the reconciler is paused (memory pressure, see
+// limits.go), and vendors in a maintenance window are left out (see
+// maintenance.go); queued work still drains.
+// =============================================================================
+
+var reconcileLogger = logging.For(logging.ComponentReconciler)
+
+// ReconcilePolicy configures the status reconciler.
+type ReconcilePolicy struct {
+	// Interval is how often every resource is queued (0 disables)
+	Interval time.Duration
+
+	// Workers is how many refreshes run at once
+	Workers int
+
+	// VendorConcurrency caps refreshes in flight per vendor
+	VendorConcurrency int
+
+	VendorWeights    map[string]float64
+	NamespaceWeights map[string]float64
+}
+
+// loadReconcilePolicy reads the reconciler configuration from the environment.
+func loadReconcilePolicy() ReconcilePolicy {
+	return ReconcilePolicy{
+		Interval: envDuration("RECONCILE_INTERVAL", time.Minute),
+		Workers:  envInt("RECONCILE_WORKERS", 4),
+		// WHY 2: Leaves vendor slots (VENDOR_MAX_CONCURRENCY) free for
+		// API traffic while a pass is running
+		VendorConcurrency: envInt("RECONCILE_VENDOR_CONCURRENCY", 2),
+		VendorWeights:     parseWeights("RECONCILE_VENDOR_WEIGHTS"),
+		NamespaceWeights:  parseWeights("RECONCILE_NAMESPACE_WEIGHTS"),
+	}
+}
+
+// parseWeights reads "name=weight,..." from the environment, skipping
+// invalid entries.
+func parseWeights(key string) map[string]float64 {
+	weights := make(map[string]float64)
+	v := os.Getenv(key)
+	if v == "" {
+		return weights
+	}
+	for _, entry := range strings.Split(v, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		weight, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if !ok || name == "" || err != nil || weight <= 0 {
+			logger.Warnf("Ignoring invalid %s entry %q", key, entry)
+			continue
+		}
+		weights[strings.TrimSpace(name)] = weight
+	}
+	return weights
+}
+
+// newReconcileQueue builds the reconciler's work queue from policy.
+func newReconcileQueue(policy ReconcilePolicy) *fairqueue.Queue {
+	return fairqueue.New(fairqueue.Options{
+		MaxPerVendor:     policy.VendorConcurrency,
+		VendorWeights:    policy.VendorWeights,
+		NamespaceWeights: policy.NamespaceWeights,
+	})
+}
+
+// startReconciler runs the status reconciler in the background.
+func (c *Controller) startReconciler(ctx context.Context) {
+	policy := c.Reconcile
+	if policy.Interval <= 0 || policy.Workers <= 0 {
+		reconcileLogger.Infof("Reconciler disabled (RECONCILE_INTERVAL=0)")
+		return
+	}
+	c.reconcileQueue.SetClock(c.Clock)
+	reconcileLogger.Infof("Reconciler enabled: every %s, %d workers, %d per vendor",
+		policy.Interval, policy.Workers, policy.VendorConcurrency)
+
+	for i := 0; i < policy.Workers; i++ {
+		go c.reconcileWorker(ctx)
+	}
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				c.reconcileQueue.ShutDown()
+				return
+			case <-c.Clock.After(policy.Interval):
+			}
+			// WHY SKIP: Under memory pressure background work waits
+			if c.ReconcilerPaused() {
+				continue
+			}
+			c.enqueueReconcile()
+		}
+	}()
+}
+
+// enqueueReconcile queues every resource reconcileItems returns.
+func (c *Controller) enqueueReconcile() {
+	defer c.profileLoop("reconciler.enqueue", time.Now())
+	items := c.reconcileItems()
+	added := 0
+	for _, item := range items {
+		if c.reconcileQueue.Add(item) {
+			added++
+		}
+	}
+	reconcileLogger.Debugf("Queued %d of %d resources for reconciliation", added, len(items))
+}
+
+// reconcileItems returns every provisioned resource (except those being
+// deleted, whose status the deletion worker owns, and locked ones, which
+// an operator is working on by hand) as a work item.
+func (c *Controller) reconcileItems() []fairqueue.Item {
+	inMaintenance := c.vendorsInMaintenance()
+	c.mu.RLock()
+	items := make([]fairqueue.Item, 0, len(c.ResourceDB))
+	for _, res := range c.ResourceDB {
+		if res.Status.VendorID == "" || res.Status.Phase == phaseTerminating || res.Status.Phase == phaseQueued || inMaintenance[res.Spec.VendorType] ||
+			c.lockConflictLocked(context.Background(), res.ID) != nil {
+			continue
+		}
+		items = append(items, fairqueue.Item{
+			Key:       res.ID,
+			Vendor:    res.Spec.VendorType,
+			Namespace: namespaceKey(res.Namespace),
+		})
+	}
+	c.mu.RUnlock()
+	return items
+}
+
+// queueRefresh queues res for the reconciler workers ahead of the next
+// pass, after a GET had to answer from the stored status. Nothing is
+// queued if the reconciler is disabled; the next GET reads the vendor
+// again anyway.
+func (c *Controller) queueRefresh(res *models.ForgeResource) {
+	if c.Reconcile.Interval <= 0 || c.Reconcile.Workers <= 0 {
+		return
+	}
+	c.mu.RLock()
+	item := fairqueue.Item{
+		Key:       res.ID,
+		Vendor:    res.Spec.VendorType,
+		Namespace: namespaceKey(res.Namespace),
+	}
+	c.mu.RUnlock()
+	if c.reconcileQueue.Add(item) {
+		reconcileLogger.Debugf("Queued %s for a refresh after a partial GET", res.ID)
+	}
+}
+
+// reconcileWorker refreshes queued resources until ctx is done.
+func (c *Controller) reconcileWorker(ctx context.Context) {
+	for {
+		item, err := c.reconcileQueue.Get(ctx)
+		if err != nil {
+			return
+		}
+		started := time.Now()
+		if _, err := c.refreshStatus(ctx, item.Key); err != nil && !errors.Is(err, errResourceGone) {
+			reconcileLogger.Warnf("Reconcile %s (%s): %v", item.Key, item.Vendor, err)
+		}
+		c.profileLoop("reconciler.refresh", started)
+		c.reconcileQueue.Done(item)
+	}
+}
+
+// HandleGetReconciler handles GET /admin/reconciler
+// Shows the reconciler's configuration and per-vendor, per-namespace queues.
+func (c *Controller) HandleGetReconciler(w http.ResponseWriter, r *http.Request) {
+	policy := c.Reconcile
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"enabled":            policy.Interval > 0 && policy.Workers > 0,
+		"paused":             c.ReconcilerPaused(),
+		"interval":           policy.Interval.String(),
+		"workers":            policy.Workers,
+		"vendor_concurrency": policy.VendorConcurrency,
+		"queued":             c.reconcileQueue.Len(),
+		"queues":             c.reconcileQueue.Stats(),
+	})
+}
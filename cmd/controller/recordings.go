@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/Zhichengu1/mock-control-plane/pkg/provider"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// RECORDING SESSIONS
+// =============================================================================
+// spec.recording_enabled only says "this device records". Operators also
+// need to start and stop named takes during a show and clean up old files:
+//
+//   POST   /resources/{id}/recordings              → start a session
+//   POST   /resources/{id}/recordings/{sid}/stop   → stop it
+//   GET    /resources/{id}/recordings              → sessions with durations/paths
+//   DELETE /resources/{id}/recordings/{sid}        → delete one recording
+//   POST   /resources/{id}/recordings/cleanup      → delete recordings past retention
+//
+// Sessions live in the vendor system (the source of truth for files);
+// the controller only records events about them.
+// =============================================================================
+
+// recordingTarget resolves the resource and its Recorder for a recording
+// request, writing an error response and returning ok=false on failure.
+func (c *Controller) recordingTarget(w http.ResponseWriter, id string) (res models.ForgeResource, recorder provider.Recorder, ok bool) {
+	c.mu.RLock()
+	stored, exists := c.ResourceDB[id]
+	if exists {
+		res = *stored.DeepCopy()
+	}
+	c.mu.RUnlock()
+
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "resource not found"})
+		return res, nil, false
+	}
+	if res.Status.VendorID == "" {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "resource has no vendor device (phase " + res.Status.Phase + ")"})
+		return res, nil, false
+	}
+	p, exists := c.config().Providers[res.Spec.VendorType]
+	if !exists {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "provider not configured"})
+		return res, nil, false
+	}
+	recorder, supported := p.(provider.Recorder)
+	if !supported {
+		// WHY 501: The request is fine; this vendor just can't do it
+		w.WriteHeader(http.StatusNotImplemented)
+		json.NewEncoder(w).Encode(map[string]string{"error": "vendor " + res.Spec.VendorType + " does not support recording sessions"})
+		return res, nil, false
+	}
+	return res, recorder, true
+}
+
+// recordResourceEvent records a Normal event on the stored resource, if it
+// still exists.
+func (c *Controller) recordResourceEvent(id, reason, message string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if res, exists := c.ResourceDB[id]; exists {
+		c.recordEvent(res, models.EventNormal, reason, message, "", "")
+	}
+}
+
+// HandleStartRecording handles POST /resources/{id}/recordings
+func (c *Controller) HandleStartRecording(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var req models.RecordingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+
+	res, recorder, ok := c.recordingTarget(w, id)
+	if !ok {
+		return
+	}
+
+	// Defaults come from the resource spec so a bare {"name": "take-1"} works
+	if req.StoragePath == "" {
+		req.StoragePath = res.Spec.RecordingPath
+	}
+	if req.StoragePath == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "storage_path is required (the resource has no spec.recording_path)"})
+		return
+	}
+	if req.Format == "" {
+		req.Format, _ = res.Spec.Config["recording_format"].(string)
+	}
+	if req.Quality == "" {
+		req.Quality, _ = res.Spec.Config["recording_quality"].(string)
+	}
+	if req.RetentionDays == 0 {
+		req.RetentionDays = res.Spec.RetentionDays
+	}
+
+	ctx, cancel := context.WithTimeout(vendorContext(r), 30*time.Second)
+	defer cancel()
+	release, err := c.acquireVendor(ctx, res.Spec.VendorType)
+	if err != nil {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	session, err := recorder.StartRecording(ctx, res.Status.VendorID, req)
+	release()
+	if err != nil {
+		writeProviderError(w, err)
+		return
+	}
+	session.ResourceID = id
+
+	c.recordResourceEvent(id, models.ReasonRecordingStarted,
+		fmt.Sprintf("Recording %q (%s) started → %s", session.Name, session.ID, session.StoragePath))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(session)
+}
+
+// HandleStopRecording handles POST /resources/{id}/recordings/{sid}/stop
+func (c *Controller) HandleStopRecording(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, sessionID := vars["id"], vars["sid"]
+
+	res, recorder, ok := c.recordingTarget(w, id)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(vendorContext(r), 30*time.Second)
+	defer cancel()
+	release, err := c.acquireVendor(ctx, res.Spec.VendorType)
+	if err != nil {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	session, err := recorder.StopRecording(ctx, res.Status.VendorID, sessionID)
+	release()
+	if err != nil {
+		writeProviderError(w, err)
+		return
+	}
+	session.ResourceID = id
+
+	c.recordResourceEvent(id, models.ReasonRecordingStopped,
+		fmt.Sprintf("Recording %q (%s) stopped after %s", session.Name, session.ID,
+			(time.Duration(session.DurationSeconds)*time.Second).String()))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+// HandleListRecordings handles GET /resources/{id}/recordings
+// Optional ?state=recording|stopped filter.
+func (c *Controller) HandleListRecordings(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	stateFilter := r.URL.Query().Get("state")
+
+	res, recorder, ok := c.recordingTarget(w, id)
+	if !ok {
+		return
+	}
+
+	sessions, err := c.listRecordings(vendorContext(r), res, recorder)
+	if err != nil {
+		writeProviderError(w, err)
+		return
+	}
+
+	items := []models.RecordingSession{}
+	for _, session := range sessions {
+		if stateFilter == "" || session.State == stateFilter {
+			items = append(items, session)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+}
+
+// listRecordings fetches sessions from the vendor, sorted oldest first.
+func (c *Controller) listRecordings(parent context.Context, res models.ForgeResource, recorder provider.Recorder) ([]models.RecordingSession, error) {
+	ctx, cancel := context.WithTimeout(parent, 15*time.Second)
+	defer cancel()
+	release, err := c.acquireVendor(ctx, res.Spec.VendorType)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := recorder.ListRecordings(ctx, res.Status.VendorID)
+	release()
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].ResourceID = res.ID
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].StartedAt.Equal(sessions[j].StartedAt) {
+			return sessions[i].StartedAt.Before(sessions[j].StartedAt)
+		}
+		return sessions[i].ID < sessions[j].ID
+	})
+	return sessions, nil
+}
+
+// HandleDeleteRecording handles DELETE /resources/{id}/recordings/{sid}
+func (c *Controller) HandleDeleteRecording(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, sessionID := vars["id"], vars["sid"]
+
+	res, recorder, ok := c.recordingTarget(w, id)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(vendorContext(r), 30*time.Second)
+	defer cancel()
+	release, err := c.acquireVendor(ctx, res.Spec.VendorType)
+	if err != nil {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	err = recorder.DeleteRecording(ctx, res.Status.VendorID, sessionID)
+	release()
+	if err != nil {
+		writeProviderError(w, err)
+		return
+	}
+
+	c.recordResourceEvent(id, models.ReasonRecordingDeleted, fmt.Sprintf("Recording %s deleted", sessionID))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RecordingCleanupResult is the response of the retention cleanup endpoint.
+type RecordingCleanupResult struct {
+	DryRun   bool                      `json:"dry_run"`
+	Deleted  []models.RecordingSession `json:"deleted"`
+	Retained int                       `json:"retained"`
+	Errors   []string                  `json:"errors,omitempty"`
+}
+
+// HandleCleanupRecordings handles POST /resources/{id}/recordings/cleanup
+// Deletes stopped recordings whose retention period has passed. A session
+// without its own retention uses spec.retention_days; 0 keeps it forever.
+// ?dry_run=true reports what would be deleted without deleting.
+func (c *Controller) HandleCleanupRecordings(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+
+	res, recorder, ok := c.recordingTarget(w, id)
+	if !ok {
+		return
+	}
+
+	sessions, err := c.listRecordings(vendorContext(r), res, recorder)
+	if err != nil {
+		writeProviderError(w, err)
+		return
+	}
+
+	result := RecordingCleanupResult{DryRun: dryRun, Deleted: []models.RecordingSession{}}
+	now := c.Clock.Now()
+	for _, session := range sessions {
+		if session.RetentionDays == 0 {
+			session.RetentionDays = res.Spec.RetentionDays
+		}
+		expiresAt := session.ExpiresAt()
+		if expiresAt.IsZero() || expiresAt.After(now) {
+			result.Retained++
+			continue
+		}
+		if dryRun {
+			result.Deleted = append(result.Deleted, session)
+			continue
+		}
+
+		// WHY ONE AT A TIME: Cleanup isn't latency-sensitive; don't hog vendor slots
+		ctx, cancel := context.WithTimeout(vendorContext(r), 30*time.Second)
+		release, err := c.acquireVendor(ctx, res.Spec.VendorType)
+		if err == nil {
+			err = recorder.DeleteRecording(ctx, res.Status.VendorID, session.ID)
+			release()
+		}
+		cancel()
+		if err != nil {
+			result.Errors = append(result.Errors, session.ID+": "+err.Error())
+			continue
+		}
+		result.Deleted = append(result.Deleted, session)
+	}
+
+	if !dryRun && len(result.Deleted) > 0 {
+		c.recordResourceEvent(id, models.ReasonRecordingDeleted,
+			fmt.Sprintf("Retention cleanup deleted %d recording(s)", len(result.Deleted)))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
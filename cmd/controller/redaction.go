@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// RESPONSE REDACTION BY ROLE
+// =============================================================================
+// A wall display with a viewer key needs to know a camera is Running, not
+// where it sits on the production network. Resource responses (get, list,
+// ?asOf=, revisions, watch events, and the resource returned by creates,
+// updates, patches and actions) drop the fields the caller's role may not
+// see before they are encoded:
+//
+//   viewer, anonymous   spec.config.ip_address, spec.config.subnet,
+//                       spec.config.vlan_id, spec.config.mtu,
+//                       spec.config.port, spec.config.network_interface,
+//                       spec.config.tally_address, spec.config.srt_passphrase,
+//                       spec.stream_url, status.endpoints.address
+//   operator, admin     nothing
+//
+// REDACTION_POLICY_FILE replaces the defaults with a JSON object of role →
+// paths ("anonymous" is a caller without a key; it falls back to
+// "viewer"):
+//
+//   {"viewer": ["spec.config.ip_address", "spec.config.*"], "operator": ["spec.stream_url"]}
+//
+// Paths use the ?fields= spelling (see fields.go). "*" stands for every key
+// of a map, and a path through a list (status.endpoints.address) applies
+// to each entry. Responses that were redacted say which paths in
+// X-Forge-Redacted.
+//
+// WHY NOT WITHOUT KEYS: With FORGE_API_KEYS unset every caller is
+// anonymous; redacting then would hide the network config from everyone.
+// =============================================================================
+
+// roleAnonymous is the policy key for callers without an API key.
+const roleAnonymous = "anonymous"
+
+// RedactionPolicy maps a role to the resource field paths it may not see.
+type RedactionPolicy map[string][]string
+
+// defaultViewerRedactions is what viewers and anonymous callers don't see
+// unless REDACTION_POLICY_FILE says otherwise.
+var defaultViewerRedactions = []string{
+	"spec.config.ip_address",
+	"spec.config.subnet",
+	"spec.config.vlan_id",
+	"spec.config.mtu",
+	"spec.config.port",
+	"spec.config.network_interface",
+	"spec.config.tally_address",
+	"spec.config.srt_passphrase",
+	"spec.stream_url",
+	"status.endpoints.address",
+}
+
+// defaultRedactionPolicy returns the built-in policy.
+func defaultRedactionPolicy() RedactionPolicy {
+	return RedactionPolicy{RoleViewer: defaultViewerRedactions}
+}
+
+// loadRedactionPolicy reads REDACTION_POLICY_FILE ("" = the defaults).
+func loadRedactionPolicy(path string) (RedactionPolicy, error) {
+	if path == "" {
+		return defaultRedactionPolicy(), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var policy RedactionPolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for role, paths := range policy {
+		if _, ok := roleRank[role]; !ok && role != roleAnonymous {
+			return nil, fmt.Errorf("%s: unknown role %q (want viewer, operator, admin or anonymous)", path, role)
+		}
+		for _, p := range paths {
+			if _, err := checkRedactionPath(resourceType, strings.Split(p, ".")); err != nil {
+				return nil, fmt.Errorf("%s: %s: %s: %w", path, role, p, err)
+			}
+		}
+	}
+	return policy, nil
+}
+
+// checkRedactionPath checks segments name a field of t, like
+// checkFieldPath, and additionally steps through lists and accepts "*" for
+// map keys.
+func checkRedactionPath(t reflect.Type, segments []string) ([]string, error) {
+	normalized := make([]string, 0, len(segments))
+	for i := 0; i < len(segments); i++ {
+		for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			segment := snakeCase(segments[i])
+			field, ok := jsonField(t, segment)
+			if !ok {
+				return nil, errors.New("unknown field " + segment + " (known: " + strings.Join(jsonFieldNames(t), ", ") + ")")
+			}
+			normalized = append(normalized, segment)
+			t = field.Type
+		case reflect.Map:
+			normalized = append(normalized, segments[i])
+			t = t.Elem()
+		case reflect.Interface:
+			return append(normalized, segments[i:]...), nil
+		default:
+			return nil, errors.New(segments[i] + " is not inside an object")
+		}
+	}
+	return normalized, nil
+}
+
+// redactor removes fields from resources on the way out. A nil redactor
+// removes nothing.
+type redactor struct {
+	paths [][]string
+	names []string
+}
+
+// redactionFor returns the redactor for the caller of r.
+func (c *Controller) redactionFor(r *http.Request) *redactor {
+	cfg := c.config()
+	if len(cfg.APIKeys) == 0 {
+		return nil
+	}
+	role := roleAnonymous
+	if principal, ok := principalFrom(r.Context()); ok {
+		role = principal.Role
+	}
+	paths, ok := cfg.Redaction[role]
+	if !ok && role == roleAnonymous {
+		paths = cfg.Redaction[RoleViewer]
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	red := &redactor{names: append([]string(nil), paths...)}
+	sort.Strings(red.names)
+	for _, p := range paths {
+		// WHY IGNORE THE ERROR: Paths were checked when the policy loaded
+		segments, _ := checkRedactionPath(resourceType, strings.Split(p, "."))
+		red.paths = append(red.paths, segments)
+	}
+	return red
+}
+
+// announce sets X-Forge-Redacted when anything will be removed.
+func (red *redactor) announce(w http.ResponseWriter) {
+	if red != nil {
+		w.Header().Set("X-Forge-Redacted", strings.Join(red.names, ", "))
+	}
+}
+
+// resource removes the redacted fields from resource in place and returns
+// it. Callers pass a copy, never a stored record.
+func (red *redactor) resource(resource *models.ForgeResource) *models.ForgeResource {
+	if red == nil || resource == nil {
+		return resource
+	}
+	for _, segments := range red.paths {
+		redactValue(reflect.ValueOf(resource).Elem(), segments)
+	}
+	return resource
+}
+
+// event returns event with its resource redacted.
+// WHY COPY: One event is fanned out to every watcher, whatever its role
+func (red *redactor) event(event models.WatchEvent) models.WatchEvent {
+	if red != nil && event.Resource != nil {
+		event.Resource = red.resource(event.Resource.DeepCopy())
+	}
+	return event
+}
+
+// redactValue zeroes the field at segments below v (struct fields) or
+// deletes it (map keys).
+func redactValue(v reflect.Value, segments []string) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	last := len(segments) == 1
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if name, _ := jsonTag(v.Type().Field(i)); name != segments[0] {
+				continue
+			}
+			field := v.Field(i)
+			if last {
+				field.Set(reflect.Zero(field.Type()))
+			} else {
+				redactValue(field, segments[1:])
+			}
+			return
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			return
+		}
+		keys := []reflect.Value{reflect.ValueOf(segments[0])}
+		if segments[0] == "*" {
+			keys = v.MapKeys()
+		}
+		for _, key := range keys {
+			if last {
+				v.SetMapIndex(key, reflect.Value{})
+			} else if value := v.MapIndex(key); value.IsValid() {
+				// WHY ONLY THROUGH REFERENCES: Map values aren't addressable;
+				// nested maps (spec.config) are changed through their own
+				// reference
+				redactValue(value, segments[1:])
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			redactValue(v.Index(i), segments)
+		}
+	}
+}
+
+// HandleGetRedaction returns the redaction policy and what the caller's
+// role doesn't see.
+func (c *Controller) HandleGetRedaction(w http.ResponseWriter, r *http.Request) {
+	cfg := c.config()
+	response := map[string]interface{}{
+		"enabled": len(cfg.APIKeys) > 0,
+		"policy":  cfg.Redaction,
+	}
+	if red := c.redactionFor(r); red != nil {
+		response["redacted_for_you"] = red.names
+	} else {
+		response["redacted_for_you"] = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
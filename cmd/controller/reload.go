@@ -0,0 +1,703 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/client"
+	"github.com/Zhichengu1/mock-control-plane/pkg/dns"
+	"github.com/Zhichengu1/mock-control-plane/pkg/health"
+	"github.com/Zhichengu1/mock-control-plane/pkg/provider"
+	"github.com/Zhichengu1/mock-control-plane/pkg/ratelimit"
+	"github.com/Zhichengu1/mock-control-plane/pkg/validation"
+)
+
+// =============================================================================
+// CONFIG RELOAD (SIGHUP, POST /admin/reload)
+// =============================================================================
+// Routine config edits (a rotated vendor key, a raised quota, a new API
+// key) shouldn't need a restart, which drops every watch and replays the
+// store. A reload re-reads these settings, and the files they name:
+//
+//   providers   <VENDOR>_* settings and secrets (SONY_API_URL, SONY_AUTH,
+//               MOCK_PROVIDER_LATENCY, ...), SECRETS_DIR
+//   quotas      MAX_RESOURCES, MAX_RESOURCES_PER_NAMESPACE,
+//               RATE_LIMIT_REQUESTS, RATE_LIMIT_WINDOW
+//   validation  SPEC_MAX_*, CONFIG_KEY_POLICY, DUPLICATE_NAME_POLICY,
+//               SPEC_PROFILES_FILE
+//   auth        FORGE_API_KEYS, REDACTION_POLICY_FILE
+//   policies    HEALTH_POLICY_CONFIG, VENDOR_ROUTING_FILE,
+//               EGRESS_POLICY_FILE, CIRCUIT_OPEN_POLICY, MASS_DELETE_*
+//
+// A process can't see changes to its own environment, so settings that
+// change go in CONFIG_ENV_FILE (KEY=VALUE lines, # comments), which is
+// read at startup and on every reload and overrides the environment.
+//
+//   kill -HUP <pid>
+//   POST /admin/reload               (admin; ?dry_run=true only reports)
+//   GET  /admin/reload               the last reloads
+//
+// The report lists every setting that changed, old and new (secrets
+// only as "changed"; files by their SHA-256).
+//
+// WHY ALL OR NOTHING: Everything is loaded and checked first, with the
+// checks that are fatal at startup; one bad setting fails the reload and
+// nothing changes. Then the new config is swapped in at once.
+//
+// WHY ONE POINTER: The reloadable settings live in a runtimeConfig that is
+// never modified; a reload builds a new one and stores it atomically. A
+// request loads it once (c.config()) and sees one consistent config to
+// the end, without taking a lock; an operation in flight finishes with
+// the settings it started with. Mock options change in place, keeping
+// the mock devices; a changed rate limit starts counting afresh.
+//
+// Other settings (PROVIDERS, PORT, STORE_*, NOTIFY_CONFIG, DNS_*, ...) are
+// only read at startup: a change to one in CONFIG_ENV_FILE is reported as
+// restart_required and not applied.
+// =============================================================================
+
+// runtimeConfig is the settings a reload can change. Never modified once
+// stored: load it with c.config(), change it with c.setConfig.
+type runtimeConfig struct {
+	// Providers are the vendor translators by name
+	// "sony" → SonyProvider, "aws" → AWSProvider
+	Providers map[string]provider.VendorProvider
+
+	// APIKeys maps hashed API keys to principals (see auth.go)
+	// Empty = no keys configured; role-restricted endpoints are refused
+	APIKeys map[string]Principal
+
+	// Redaction lists the resource fields each role doesn't see (see
+	// redaction.go); only applied when APIKeys is set
+	Redaction RedactionPolicy
+
+	// RateLimiter enforces per-client request quotas (nil = unlimited)
+	RateLimiter *ratelimit.Limiter
+
+	// Capacity limits (see limits.go); 0 = unlimited
+	MaxResources             int
+	MaxResourcesPerNamespace int
+
+	// SpecLimits bounds the size of created resources (see pkg/validation)
+	SpecLimits validation.SizeLimits
+
+	// ConfigKeyPolicy is what unknown or mistyped spec.config keys do
+	// (see configkeys.go)
+	ConfigKeyPolicy ConfigKeyPolicy
+
+	// DuplicateNames picks what a create does when the vendor already has
+	// a device with the same name (see duplicates.go)
+	DuplicateNames DuplicateNamePolicy
+
+	// Profiles are the environment overlays merged into specs
+	// (SPEC_PROFILES_FILE, see profiles.go)
+	Profiles SpecProfiles
+
+	// Routing picks the vendor of creates without a vendor_type
+	// (VENDOR_ROUTING_FILE, see routing.go)
+	Routing VendorRouting
+
+	// HealthPolicy rolls vendor metrics up into HealthStatus
+	// (HEALTH_POLICY_CONFIG, else built-in thresholds)
+	HealthPolicy *health.Config
+
+	// CircuitOpen decides whether writes refused by an open circuit are
+	// queued (see queued.go)
+	CircuitOpen CircuitOpenPolicy
+
+	// MassDelete limits how many resources one caller deletes or stops
+	// at a time (see massdelete.go)
+	MassDelete MassDeletePolicy
+}
+
+// config returns the settings in effect. Load it once per request.
+func (c *Controller) config() *runtimeConfig {
+	return c.settings.Load()
+}
+
+// setConfig stores a copy of the settings with change applied.
+// WHY NO LOCK: Only startup and reloads (serialized by reloadState.mu)
+// change the settings
+func (c *Controller) setConfig(change func(cfg *runtimeConfig)) {
+	next := *c.config()
+	change(&next)
+	c.settings.Store(&next)
+}
+
+// Reload sections.
+const (
+	sectionProviders  = "providers"
+	sectionQuotas     = "quotas"
+	sectionValidation = "validation"
+	sectionAuth       = "auth"
+	sectionPolicies   = "policies"
+)
+
+// reloadSettings maps the settings a reload applies to their section.
+// WHY EXPLICIT: A setting read only at startup must not look reloadable
+var reloadSettings = map[string]string{
+	"SECRETS_DIR":                 sectionProviders,
+	"MAX_RESOURCES":               sectionQuotas,
+	"MAX_RESOURCES_PER_NAMESPACE": sectionQuotas,
+	"RATE_LIMIT_REQUESTS":         sectionQuotas,
+	"RATE_LIMIT_WINDOW":           sectionQuotas,
+	"CONFIG_KEY_POLICY":           sectionValidation,
+	"DUPLICATE_NAME_POLICY":       sectionValidation,
+	"SPEC_PROFILES_FILE":          sectionValidation,
+	"FORGE_API_KEYS":              sectionAuth,
+	"REDACTION_POLICY_FILE":       sectionAuth,
+	"HEALTH_POLICY_CONFIG":        sectionPolicies,
+	"VENDOR_ROUTING_FILE":         sectionPolicies,
+	"EGRESS_POLICY_FILE":          sectionPolicies,
+	"CIRCUIT_OPEN_POLICY":         sectionPolicies,
+	"MASS_DELETE_MAX":             sectionPolicies,
+	"MASS_DELETE_MAX_PERCENT":     sectionPolicies,
+	"MASS_DELETE_WINDOW":          sectionPolicies,
+	"MASS_DELETE_ACTION":          sectionPolicies,
+	"MASS_DELETE_CONFIRM_TTL":     sectionPolicies,
+}
+
+// reloadFileSettings name files whose contents count as part of the value.
+var reloadFileSettings = map[string]bool{
+	"SPEC_PROFILES_FILE":    true,
+	"REDACTION_POLICY_FILE": true,
+	"HEALTH_POLICY_CONFIG":  true,
+	"VENDOR_ROUTING_FILE":   true,
+	"EGRESS_POLICY_FILE":    true,
+}
+
+// secretPrefix marks a fingerprint of a file in SECRETS_DIR.
+const secretPrefix = "secret:"
+
+// maxReloadHistory is how many reports GET /admin/reload keeps.
+const maxReloadHistory = 20
+
+// ConfigChange is one changed setting in a ReloadReport.
+type ConfigChange struct {
+	Section string `json:"section,omitempty"`
+	Setting string `json:"setting"`
+
+	// Change is "added", "removed" or "changed"
+	Change string `json:"change"`
+
+	// Old and New are left out for secrets
+	Old string `json:"old,omitempty"`
+	New string `json:"new,omitempty"`
+}
+
+// ReloadReport is the outcome of a reload.
+type ReloadReport struct {
+	Time    time.Time `json:"time"`
+	Trigger string    `json:"trigger"`
+	DryRun  bool      `json:"dry_run,omitempty"`
+
+	// Applied is true if the changes are in effect
+	Applied bool           `json:"applied"`
+	Changes []ConfigChange `json:"changes"`
+
+	// RestartRequired are changes to settings only read at startup
+	RestartRequired []ConfigChange `json:"restart_required,omitempty"`
+
+	// Errors are why nothing was applied
+	Errors []string `json:"errors,omitempty"`
+}
+
+// reloadState tracks what the last reload applied.
+type reloadState struct {
+	// mu serializes reloads and guards the fields below
+	mu sync.Mutex
+
+	// base is the process environment; fileKeys the settings
+	// CONFIG_ENV_FILE set over it
+	base     map[string]string
+	fileKeys map[string]bool
+
+	// applied fingerprints the reloadable settings in effect
+	applied map[string]string
+
+	history []ReloadReport
+}
+
+// loadConfigEnv reads CONFIG_ENV_FILE and sets its settings in the
+// environment, so everything read at startup sees them. Returns the
+// state for later reloads.
+func loadConfigEnv() (*reloadState, error) {
+	state := &reloadState{base: make(map[string]string), fileKeys: make(map[string]bool)}
+	for _, entry := range os.Environ() {
+		if key, value, ok := strings.Cut(entry, "="); ok {
+			state.base[key] = value
+		}
+	}
+	settings, err := readEnvFile(state.base["CONFIG_ENV_FILE"])
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range settings {
+		os.Setenv(key, value)
+		state.fileKeys[key] = true
+	}
+	return state, nil
+}
+
+// readEnvFile parses KEY=VALUE lines ("export " and quotes allowed,
+// # comments). Returns nil if path is "".
+func readEnvFile(path string) (map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	settings := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(text, "export "), "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("%s:%d: want KEY=VALUE", path, line)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		// WHY SKIPPED: The file can't move itself
+		if key != "CONFIG_ENV_FILE" {
+			settings[key] = value
+		}
+	}
+	return settings, scanner.Err()
+}
+
+// initReload records the settings in effect at startup.
+func (c *Controller) initReload(state *reloadState) {
+	state.applied = c.reloadFingerprints(state.effective(state.fileSettings()))
+	c.reload = state
+}
+
+// fileSettings returns the settings the file set last time.
+func (s *reloadState) fileSettings() map[string]string {
+	settings := make(map[string]string, len(s.fileKeys))
+	for key := range s.fileKeys {
+		settings[key] = os.Getenv(key)
+	}
+	return settings
+}
+
+// effective returns the environment with file over it.
+func (s *reloadState) effective(file map[string]string) map[string]string {
+	env := make(map[string]string, len(s.base)+len(file))
+	for key, value := range s.base {
+		env[key] = value
+	}
+	for key, value := range file {
+		env[key] = value
+	}
+	return env
+}
+
+// reloadSection returns the section of a reloadable setting.
+func (c *Controller) reloadSection(key string) (string, bool) {
+	if section, ok := reloadSettings[key]; ok {
+		return section, true
+	}
+	if strings.HasPrefix(key, "SPEC_MAX_") {
+		return sectionValidation, true
+	}
+	if c.settingVendor(strings.TrimPrefix(key, secretPrefix)) != "" {
+		return sectionProviders, true
+	}
+	return "", false
+}
+
+// settingVendor returns the provider a <VENDOR>_* setting belongs to, or "".
+func (c *Controller) settingVendor(key string) string {
+	for name := range c.config().Providers {
+		if strings.HasPrefix(key, strings.ToUpper(name)+"_") {
+			return name
+		}
+	}
+	return ""
+}
+
+// reloadFingerprints returns the value of every reloadable setting in
+// env: files with their contents' hash, secrets in SECRETS_DIR by hash.
+func (c *Controller) reloadFingerprints(env map[string]string) map[string]string {
+	fingerprints := make(map[string]string)
+	for key, value := range env {
+		if _, ok := c.reloadSection(key); !ok {
+			continue
+		}
+		if reloadFileSettings[key] && value != "" {
+			value += " (" + fileFingerprint(value) + ")"
+		}
+		fingerprints[key] = value
+	}
+	if dir := env["SECRETS_DIR"]; dir != "" {
+		entries, _ := os.ReadDir(dir)
+		for _, entry := range entries {
+			if !entry.IsDir() && c.settingVendor(entry.Name()) != "" {
+				fingerprints[secretPrefix+entry.Name()] = fileFingerprint(filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+	return fingerprints
+}
+
+// fileFingerprint identifies the contents of path.
+func fileFingerprint(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "unreadable"
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:6])
+}
+
+// isSecretSetting reports whether key's values must not be shown.
+func isSecretSetting(key string) bool {
+	if strings.HasPrefix(key, secretPrefix) {
+		return true
+	}
+	for _, word := range []string{"KEY", "SECRET", "PASSWORD", "TOKEN", "PASSPHRASE"} {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// configChanges compares two sets of settings, sorted by section and name.
+func configChanges(old, new map[string]string, section func(string) string) []ConfigChange {
+	keys := make(map[string]bool, len(old)+len(new))
+	for key := range old {
+		keys[key] = true
+	}
+	for key := range new {
+		keys[key] = true
+	}
+	changes := []ConfigChange{}
+	for key := range keys {
+		before, had := old[key]
+		after, has := new[key]
+		change := ConfigChange{Section: section(key), Setting: key, Old: before, New: after}
+		switch {
+		case had && has && before == after:
+			continue
+		case !had:
+			change.Change = "added"
+		case !has:
+			change.Change = "removed"
+		default:
+			change.Change = "changed"
+		}
+		if isSecretSetting(key) {
+			change.Old, change.New = "", ""
+		}
+		changes = append(changes, change)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Section != changes[j].Section {
+			return changes[i].Section < changes[j].Section
+		}
+		return changes[i].Setting < changes[j].Setting
+	})
+	return changes
+}
+
+// reloadConfig re-reads the configuration and applies what changed
+// (only reports it if dryRun).
+func (c *Controller) reloadConfig(trigger string, dryRun bool) ReloadReport {
+	state := c.reload
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	report := ReloadReport{Time: c.Clock.Now(), Trigger: trigger, DryRun: dryRun, Changes: []ConfigChange{}}
+	defer func() {
+		if !dryRun {
+			state.history = append(state.history, report)
+			if len(state.history) > maxReloadHistory {
+				state.history = state.history[len(state.history)-maxReloadHistory:]
+			}
+		}
+	}()
+
+	// Step 1: Read the file and see what changed
+	file, err := readEnvFile(state.base["CONFIG_ENV_FILE"])
+	if err != nil {
+		report.Errors = []string{"CONFIG_ENV_FILE: " + err.Error()}
+		logger.Warnf("Config reload (%s) failed: %s", trigger, report.Errors[0])
+		return report
+	}
+	env := state.effective(file)
+	fingerprints := c.reloadFingerprints(env)
+	sectionOf := func(key string) string { section, _ := c.reloadSection(key); return section }
+	report.Changes = configChanges(state.applied, fingerprints, sectionOf)
+
+	// WHY ONLY FILE KEYS: The rest of the environment can't have changed;
+	// startup-only settings keep the values they started with
+	current, next := make(map[string]string), make(map[string]string)
+	for _, keys := range []map[string]string{file, state.fileSettings()} {
+		for key := range keys {
+			if _, reloadable := c.reloadSection(key); reloadable {
+				continue
+			}
+			if value, ok := os.LookupEnv(key); ok {
+				current[key] = value
+			}
+			if value, ok := env[key]; ok {
+				next[key] = value
+			}
+		}
+	}
+	report.RestartRequired = configChanges(current, next, func(string) string { return "" })
+	if len(report.RestartRequired) == 0 {
+		report.RestartRequired = nil
+	}
+	if len(report.Changes) == 0 {
+		return report
+	}
+
+	// Step 2: Put the new values in the environment the loaders read,
+	// remembering the old ones in case the reload fails
+	previous := make(map[string]*string)
+	setEnv := func(key string, value string, set bool) {
+		if _, saved := previous[key]; !saved {
+			if old, ok := os.LookupEnv(key); ok {
+				previous[key] = &old
+			} else {
+				previous[key] = nil
+			}
+		}
+		if set {
+			os.Setenv(key, value)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+	for _, change := range report.Changes {
+		if strings.HasPrefix(change.Setting, secretPrefix) {
+			continue
+		}
+		value, set := env[change.Setting]
+		setEnv(change.Setting, value, set)
+	}
+	restoreEnv := func() {
+		for key, value := range previous {
+			if value != nil {
+				os.Setenv(key, *value)
+			} else {
+				os.Unsetenv(key)
+			}
+		}
+	}
+
+	// Step 3: Load everything that changed
+	apply, errs := c.loadReloadedConfig(report.Changes)
+	if len(errs) > 0 || dryRun {
+		restoreEnv()
+		report.Errors = errs
+		if len(errs) > 0 {
+			logger.Warnf("Config reload (%s) failed, nothing changed: %s", trigger, strings.Join(errs, "; "))
+		}
+		return report
+	}
+
+	// Step 4: Swap it in at once
+	c.setConfig(func(cfg *runtimeConfig) {
+		for _, fn := range apply {
+			fn(cfg)
+		}
+	})
+	state.applied = fingerprints
+	// WHY KEEP STARTUP-ONLY KEYS: Until a restart they still differ
+	for key := range state.fileKeys {
+		if _, reloadable := c.reloadSection(key); reloadable {
+			delete(state.fileKeys, key)
+		}
+	}
+	for key := range file {
+		state.fileKeys[key] = true
+	}
+	report.Applied = true
+	settings := make([]string, len(report.Changes))
+	for i, change := range report.Changes {
+		settings[i] = change.Setting
+	}
+	logger.Infof("Config reloaded (%s): %s", trigger, strings.Join(settings, ", "))
+	return report
+}
+
+// loadReloadedConfig loads the sections of changes from the environment
+// and returns functions that install them in the next config, or why
+// they can't be.
+func (c *Controller) loadReloadedConfig(changes []ConfigChange) ([]func(*runtimeConfig), []string) {
+	current := c.config()
+	sections := make(map[string]bool)
+	vendors := make(map[string]bool)
+	changed := make(map[string]bool)
+	for _, change := range changes {
+		sections[change.Section] = true
+		changed[change.Setting] = true
+		if vendor := c.settingVendor(strings.TrimPrefix(change.Setting, secretPrefix)); vendor != "" {
+			vendors[vendor] = true
+		}
+	}
+	// WHY ALL VENDORS: Their secrets moved with SECRETS_DIR
+	if changed["SECRETS_DIR"] {
+		for name := range current.Providers {
+			vendors[name] = true
+		}
+	}
+	var apply []func(*runtimeConfig)
+	var errs []string
+	fail := func(setting string, err error) { errs = append(errs, setting+": "+err.Error()) }
+
+	if len(vendors) > 0 {
+		providers := make(map[string]provider.VendorProvider, len(current.Providers))
+		for name, p := range current.Providers {
+			providers[name] = p
+		}
+		for _, name := range sortedKeys(vendors) {
+			// WHY IN PLACE: A new mock provider would forget its devices
+			if mock, ok := current.Providers[name].(*provider.MockProvider); ok {
+				options := mockOptions()
+				apply = append(apply, func(*runtimeConfig) { mock.SetOptions(options) })
+				continue
+			}
+			p := newProvider(name, c.Clock)
+			if err := configureSigner(name, p, secretsFromEnv()); err != nil {
+				fail(name, err)
+				continue
+			}
+			providers[name] = p
+		}
+		apply = append(apply, func(cfg *runtimeConfig) { cfg.Providers = providers })
+	}
+
+	if sections[sectionQuotas] {
+		maxResources, maxPerNamespace := envInt("MAX_RESOURCES", 10000), envInt("MAX_RESOURCES_PER_NAMESPACE", 0)
+		apply = append(apply, func(cfg *runtimeConfig) {
+			cfg.MaxResources, cfg.MaxResourcesPerNamespace = maxResources, maxPerNamespace
+		})
+		if changed["RATE_LIMIT_REQUESTS"] || changed["RATE_LIMIT_WINDOW"] {
+			var limiter *ratelimit.Limiter
+			if requests := envInt("RATE_LIMIT_REQUESTS", 600); requests > 0 {
+				limiter = ratelimit.NewLimiter(requests, envDuration("RATE_LIMIT_WINDOW", time.Minute))
+				limiter.SetClock(c.Clock)
+			}
+			apply = append(apply, func(cfg *runtimeConfig) { cfg.RateLimiter = limiter })
+		}
+	}
+
+	if sections[sectionValidation] {
+		limits, keyPolicy, duplicates := loadSpecLimits(), loadConfigKeyPolicy(), loadDuplicateNamePolicy()
+		profiles := SpecProfiles{}
+		if path := os.Getenv("SPEC_PROFILES_FILE"); path != "" {
+			var err error
+			if profiles, err = loadSpecProfiles(path); err != nil {
+				fail("SPEC_PROFILES_FILE", err)
+			}
+		}
+		apply = append(apply, func(cfg *runtimeConfig) {
+			cfg.SpecLimits, cfg.ConfigKeyPolicy, cfg.DuplicateNames, cfg.Profiles = limits, keyPolicy, duplicates, profiles
+		})
+	}
+
+	if sections[sectionAuth] {
+		keys, err := parseAPIKeys(os.Getenv("FORGE_API_KEYS"))
+		switch {
+		case err != nil:
+			fail("FORGE_API_KEYS", err)
+		case len(keys) == 0 && len(current.APIKeys) > 0:
+			// WHY REFUSED: An emptied variable would silently turn
+			// authentication off; that takes a deliberate restart
+			fail("FORGE_API_KEYS", fmt.Errorf("removing every API key needs a restart"))
+		}
+		redaction, err := loadRedactionPolicy(os.Getenv("REDACTION_POLICY_FILE"))
+		if err != nil {
+			fail("REDACTION_POLICY_FILE", err)
+		}
+		if len(keys) == 0 {
+			keys = nil
+		}
+		apply = append(apply, func(cfg *runtimeConfig) { cfg.APIKeys, cfg.Redaction = keys, redaction })
+	}
+
+	if sections[sectionPolicies] {
+		healthPolicy := health.DefaultConfig()
+		if path := os.Getenv("HEALTH_POLICY_CONFIG"); path != "" {
+			var err error
+			if healthPolicy, err = health.LoadConfig(path); err != nil {
+				fail("HEALTH_POLICY_CONFIG", err)
+			}
+		}
+		registered := make(map[string]bool)
+		for name := range current.Providers {
+			registered[name] = true
+		}
+		routing := VendorRouting{Namespaces: make(map[string]*NamespaceRoutes)}
+		if path := os.Getenv("VENDOR_ROUTING_FILE"); path != "" {
+			var err error
+			if routing, err = loadVendorRouting(path, registered); err != nil {
+				fail("VENDOR_ROUTING_FILE", err)
+			}
+		}
+		if os.Getenv("DNS_BACKEND") != "" {
+			registered[dns.EgressName] = true
+		}
+		egress, err := loadEgressPolicy(os.Getenv("EGRESS_POLICY_FILE"), registered)
+		if err == nil {
+			err = client.CheckEgressPolicy(egress)
+		}
+		if err != nil {
+			fail("EGRESS_POLICY_FILE", err)
+		}
+		circuitOpen, massDelete := loadCircuitOpenPolicy(), loadMassDeletePolicy()
+		apply = append(apply, func(cfg *runtimeConfig) {
+			cfg.HealthPolicy, cfg.Routing, cfg.CircuitOpen, cfg.MassDelete = healthPolicy, routing, circuitOpen, massDelete
+			// Checked above, so it can't fail
+			client.SetEgressPolicy(egress)
+		})
+	}
+	return apply, errs
+}
+
+// HandleReload handles POST /admin/reload
+func (c *Controller) HandleReload(w http.ResponseWriter, r *http.Request) {
+	trigger := "api"
+	if principal, ok := principalFrom(r.Context()); ok {
+		trigger = "api:" + principal.Name
+	}
+	report := c.reloadConfig(trigger, r.URL.Query().Get("dry_run") == "true")
+	w.Header().Set("Content-Type", "application/json")
+	if len(report.Errors) > 0 {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// HandleListReloads handles GET /admin/reload
+func (c *Controller) HandleListReloads(w http.ResponseWriter, r *http.Request) {
+	c.reload.mu.Lock()
+	history := append([]ReloadReport{}, c.reload.history...)
+	c.reload.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"config_env_file": c.reload.base["CONFIG_ENV_FILE"],
+		"reloads":         history,
+	})
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// ROLLBACK TO A PREVIOUS REVISION
+// =============================================================================
+// When a spec change goes wrong, the known-good spec is already in the
+// revision history. POST /resources/{id}/rollback?revision=N takes the
+// spec of revision N and applies it like PUT /resources/{id} would:
+// validated, pushed to the vendor with provider.Update, and stored as a
+// new revision (reason "rolled-back"). History is never rewritten, so the
+// bad change and the rollback both stay visible.
+//
+// Only the spec goes back; labels, annotations, metadata and status are
+// left as they are. Locks, preconditions (If-Match), maintenance windows
+// and queued updates apply as for any update. Revisions older than the
+// history kept in memory are looked up in the event log when there is one
+// (STORE_BACKEND=eventlog).
+// =============================================================================
+
+// findRevision returns revision number of resource id.
+func (c *Controller) findRevision(id string, number int64) (*models.ResourceRevision, error) {
+	c.mu.RLock()
+	for _, revision := range c.History[id] {
+		if revision.Revision == number {
+			c.mu.RUnlock()
+			return &revision, nil
+		}
+	}
+	c.mu.RUnlock()
+
+	if c.eventLog == nil {
+		return nil, nil
+	}
+	// WHY NEWEST FIRST: Revisions only go up, so the one asked for is
+	// usually among the last few
+	seqs := c.eventLog.ResourceSeqs(id)
+	for i := len(seqs) - 1; i >= 0; i-- {
+		e, err := c.eventLog.Read(seqs[i])
+		if err != nil {
+			return nil, err
+		}
+		if e.Revision == number {
+			return &e.ResourceRevision, nil
+		}
+		if e.Revision < number {
+			break
+		}
+	}
+	return nil, nil
+}
+
+// HandleRollbackResource handles POST /resources/{id}/rollback?revision=N
+func (c *Controller) HandleRollbackResource(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	w.Header().Set("Content-Type", "application/json")
+	if rejectBadCircuitOpenParam(w, r) {
+		return
+	}
+	number, err := strconv.ParseInt(r.URL.Query().Get("revision"), 10, 64)
+	if err != nil || number < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "revision must be a positive revision number (see GET /resources/" + id + "/revisions)"})
+		return
+	}
+
+	// Step 1: Find the revision and compare with the current spec
+	c.mu.RLock()
+	stored, exists := c.ResourceDB[id]
+	var current models.ForgeResource
+	if exists {
+		current = *stored.DeepCopy()
+	}
+	c.mu.RUnlock()
+	if !exists {
+		writeOperationError(w, errResourceNotFound)
+		return
+	}
+	revision, err := c.findRevision(id, number)
+	switch {
+	case err != nil:
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	case revision == nil:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("no revision %d of resource %s", number, id)})
+		return
+	case revision.Deleted:
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("revision %d is the deletion tombstone; pick an earlier revision", number)})
+		return
+	case number >= current.ResourceVersion:
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("revision %d is the current revision", number)})
+		return
+	}
+	spec := revision.Resource.Spec
+	if reflect.DeepEqual(spec, current.Spec) {
+		// Nothing to push: the spec is already what the revision had
+		setResourceETag(w, &current)
+		redact := c.redactionFor(r)
+		redact.announce(w)
+		json.NewEncoder(w).Encode(redact.resource(&current))
+		return
+	}
+
+	// Step 2: Apply it like an update
+	detail := fmt.Sprintf(" (rollback to revision %d)", number)
+	if principal, ok := principalFrom(r.Context()); ok {
+		detail += " by " + principal.Name
+	}
+	if c.rejectIfLocked(w, r, id) || c.rejectIfPreconditionFailed(w, r, id) || c.deferForMaintenance(w, r, id, "rollback", detail, &spec) ||
+		c.queueBehindPending(w, r, id, detail, &spec) {
+		return
+	}
+	res, err := c.updateResourceSpec(vendorContext(r), id, spec, "rolled-back", detail)
+	if err != nil {
+		if c.queueAfterCircuitOpen(w, r, id, detail, &spec, err) {
+			return
+		}
+		writeOperationError(w, err)
+		return
+	}
+	logger.Infof("%s: spec rolled back to revision %d%s", id, number, detail)
+	setResourceETag(w, res)
+	redact := c.redactionFor(r)
+	redact.announce(w)
+	json.NewEncoder(w).Encode(redact.resource(res))
+}
@@ -0,0 +1,726 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/fanout"
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/Zhichengu1/mock-control-plane/pkg/validation"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// CANARY ROLLOUTS
+// =============================================================================
+// Changing one encoder setting on every camera at once means one bad value
+// takes out the whole show. A rollout applies a spec change to a group in
+// stages:
+//
+//   POST /rollouts
+//   {
+//     "selector": {"namespace": "prod", "type": "camera"},
+//     "patch": {"bitrate": 12000000},        ← JSON merge patch on spec
+//     "canary_percent": 10,
+//     "bake_time": "5m", "check_interval": "15s"
+//   }
+//
+//   Canary    → update 10% of the group (at least one resource)
+//   Baking    → every check_interval, read the canaries from the vendor;
+//               all must stay Running/healthy for bake_time
+//   Promoting → update the rest
+//   Completed
+//
+// If a canary fails to update or turns unhealthy, or promotion fails,
+// every resource already changed is restored to its previous spec
+// (RolledBack). With "auto_rollback": false the rollout stops as Failed
+// instead, leaving the changed resources for inspection;
+// POST /rollouts/{id}/abort rolls them back.
+//
+// Waiting uses the controller clock, so FORGE_TEST_MODE can fast-forward
+// through the bake time.
+// =============================================================================
+
+// Rollout phases.
+const (
+	rolloutCanary      = "Canary"
+	rolloutBaking      = "Baking"
+	rolloutPromoting   = "Promoting"
+	rolloutCompleted   = "Completed"
+	rolloutRollingBack = "RollingBack"
+	rolloutRolledBack  = "RolledBack"
+	rolloutFailed      = "Failed"
+	rolloutAborted     = "Aborted"
+)
+
+// Rollout target states.
+const (
+	targetPending        = "pending"
+	targetUpdated        = "updated"
+	targetFailed         = "failed"
+	targetRolledBack     = "rolled_back"
+	targetRollbackFailed = "rollback_failed"
+)
+
+// Rollout stages.
+const (
+	stageCanary = "canary"
+	stageMain   = "main"
+)
+
+// Rollout defaults and bounds.
+const (
+	defaultCanaryPercent   = 10
+	defaultBakeTime        = 5 * time.Minute
+	defaultCheckInterval   = 15 * time.Second
+	minCheckInterval       = time.Second
+	defaultRolloutParallel = 4
+)
+
+// RolloutSelector picks the group a rollout applies to. All set fields
+// must match; at least one must be set.
+type RolloutSelector struct {
+	IDs        []string `json:"ids,omitempty"`
+	Namespace  string   `json:"namespace,omitempty"`
+	Type       string   `json:"type,omitempty"`
+	VendorType string   `json:"vendor_type,omitempty"`
+}
+
+// empty reports whether no field is set.
+func (s RolloutSelector) empty() bool {
+	return len(s.IDs) == 0 && s.Namespace == "" && s.Type == "" && s.VendorType == ""
+}
+
+// matches reports whether res belongs to the group.
+func (s RolloutSelector) matches(res *models.ForgeResource) bool {
+	if len(s.IDs) > 0 {
+		found := false
+		for _, id := range s.IDs {
+			if id == res.ID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return (s.Namespace == "" || s.Namespace == res.Namespace) &&
+		(s.Type == "" || s.Type == res.Type) &&
+		(s.VendorType == "" || s.VendorType == res.Spec.VendorType)
+}
+
+// RolloutRequest is the body accepted by POST /rollouts.
+type RolloutRequest struct {
+	Selector RolloutSelector `json:"selector"`
+
+	// Patch is a JSON merge patch (RFC 7396) applied to each resource's spec.
+	Patch map[string]interface{} `json:"patch"`
+
+	// CanaryPercent of the group is updated first (default 10, at least one resource).
+	CanaryPercent int `json:"canary_percent,omitempty"`
+
+	// BakeTime is how long the canaries must stay healthy ("5m").
+	BakeTime string `json:"bake_time,omitempty"`
+
+	// CheckInterval is how often canary health is checked ("15s").
+	CheckInterval string `json:"check_interval,omitempty"`
+
+	// AutoRollback restores changed resources on failure (default true).
+	AutoRollback *bool `json:"auto_rollback,omitempty"`
+
+	// Parallelism bounds concurrent updates within a stage (default 4).
+	Parallelism int `json:"parallelism,omitempty"`
+}
+
+// RolloutTarget is one resource of a rollout.
+type RolloutTarget struct {
+	ResourceID string `json:"resource_id"`
+	Name       string `json:"name"`
+	Stage      string `json:"stage"`
+	State      string `json:"state"`
+	Error      string `json:"error,omitempty"`
+
+	// previous is the spec restored on rollback
+	previous models.ResourceSpec
+	// updated is the spec after the patch
+	updated models.ResourceSpec
+}
+
+// Rollout is a staged spec change across a group.
+type Rollout struct {
+	ID            string                 `json:"id"`
+	Phase         string                 `json:"phase"`
+	Message       string                 `json:"message,omitempty"`
+	Selector      RolloutSelector        `json:"selector"`
+	Patch         map[string]interface{} `json:"patch"`
+	CanaryPercent int                    `json:"canary_percent"`
+	BakeTime      string                 `json:"bake_time"`
+	CheckInterval string                 `json:"check_interval"`
+	AutoRollback  bool                   `json:"auto_rollback"`
+	Parallelism   int                    `json:"parallelism"`
+	CreatedBy     string                 `json:"created_by,omitempty"`
+	Targets       []RolloutTarget        `json:"targets"`
+	CreatedAt     time.Time              `json:"created_at"`
+	UpdatedAt     time.Time              `json:"updated_at"`
+	// BakeUntil is when the canaries will have baked long enough
+	BakeUntil   time.Time `json:"bake_until,omitempty"`
+	CompletedAt time.Time `json:"completed_at,omitempty"`
+
+	bakeTime      time.Duration
+	checkInterval time.Duration
+	cancel        context.CancelFunc
+	aborted       bool
+}
+
+// active reports whether the rollout is still running.
+func (ro *Rollout) active() bool {
+	switch ro.Phase {
+	case rolloutCompleted, rolloutRolledBack, rolloutFailed, rolloutAborted:
+		return false
+	}
+	return true
+}
+
+// snapshot copies the exported state for responses. Caller must hold c.mu.
+func (ro *Rollout) snapshot() Rollout {
+	out := *ro
+	out.Targets = append([]RolloutTarget(nil), ro.Targets...)
+	out.cancel = nil
+	return out
+}
+
+// HandleCreateRollout handles POST /rollouts
+func (c *Controller) HandleCreateRollout(w http.ResponseWriter, r *http.Request) {
+	var req RolloutRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxResourceBodyBytes)).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+
+	// Step 1: Validate the request
+	ro, msg := c.newRollout(req)
+	if msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": msg})
+		return
+	}
+	if principal, ok := principalFrom(r.Context()); ok {
+		ro.CreatedBy = principal.Name
+	}
+
+	// Step 2: Resolve the group and patch every spec up front, so an
+	// invalid result is reported before anything changes
+	c.mu.Lock()
+	var violations []map[string]interface{}
+	for _, res := range c.ResourceDB {
+		if !req.Selector.matches(res) {
+			continue
+		}
+		if other := c.activeRolloutForLocked(res.ID); other != "" {
+			c.mu.Unlock()
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]string{"error": "resource " + res.ID + " is already part of active rollout " + other})
+			return
+		}
+		updated, err := patchSpec(res.Spec, req.Patch)
+		if err == nil {
+			candidate := *res.DeepCopy()
+			candidate.Spec = updated
+			if v := append(validation.CheckSize(&candidate, c.config().SpecLimits), c.Validators.Validate(res.Type, updated)...); len(v) > 0 {
+				err = v
+			} else if updated.VendorType != res.Spec.VendorType {
+				err = errors.New("the patch can't change vendor_type")
+			}
+		}
+		if err != nil {
+			violations = append(violations, map[string]interface{}{"resource_id": res.ID, "error": err.Error()})
+			continue
+		}
+		ro.Targets = append(ro.Targets, RolloutTarget{
+			ResourceID: res.ID, Name: res.Name, State: targetPending,
+			previous: res.Spec, updated: updated,
+		})
+	}
+	if len(violations) > 0 || len(ro.Targets) == 0 {
+		c.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		if len(violations) > 0 {
+			sort.Slice(violations, func(i, j int) bool {
+				return violations[i]["resource_id"].(string) < violations[j]["resource_id"].(string)
+			})
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "patch is invalid for some resources", "resources": violations})
+		} else {
+			json.NewEncoder(w).Encode(map[string]string{"error": "selector matches no resources"})
+		}
+		return
+	}
+
+	// Step 3: Pick the canaries (in ID order, so the choice is predictable)
+	sort.Slice(ro.Targets, func(i, j int) bool { return ro.Targets[i].ResourceID < ro.Targets[j].ResourceID })
+	canaries := (len(ro.Targets)*ro.CanaryPercent + 99) / 100
+	if canaries < 1 {
+		canaries = 1
+	}
+	for i := range ro.Targets {
+		ro.Targets[i].Stage = stageMain
+		if i < canaries {
+			ro.Targets[i].Stage = stageCanary
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ro.cancel = cancel
+	c.Rollouts[ro.ID] = ro
+	response := ro.snapshot()
+	c.mu.Unlock()
+
+	logger.Infof("Rollout %s started: %d resources (%d canaries), bake %s", ro.ID, len(ro.Targets), canaries, ro.BakeTime)
+	go c.runRollout(ctx, ro)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", c.externalURL(r, versionedPath("/rollouts/"+ro.ID)))
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(response)
+}
+
+// newRollout validates req and applies defaults. Returns an error message,
+// or "" if valid.
+func (c *Controller) newRollout(req RolloutRequest) (*Rollout, string) {
+	if req.Selector.empty() {
+		return nil, "selector must set at least one of ids, namespace, type, vendor_type"
+	}
+	if len(req.Patch) == 0 {
+		return nil, "patch is required"
+	}
+	if req.CanaryPercent == 0 {
+		req.CanaryPercent = defaultCanaryPercent
+	}
+	if req.CanaryPercent < 1 || req.CanaryPercent > 100 {
+		return nil, "canary_percent must be between 1 and 100"
+	}
+	bakeTime, checkInterval := defaultBakeTime, defaultCheckInterval
+	var err error
+	if req.BakeTime != "" {
+		if bakeTime, err = time.ParseDuration(req.BakeTime); err != nil || bakeTime < 0 {
+			return nil, "bake_time must be a duration like \"5m\""
+		}
+	}
+	if req.CheckInterval != "" {
+		if checkInterval, err = time.ParseDuration(req.CheckInterval); err != nil || checkInterval < minCheckInterval {
+			return nil, "check_interval must be a duration of at least 1s"
+		}
+	}
+	if req.Parallelism <= 0 {
+		req.Parallelism = defaultRolloutParallel
+	}
+	if req.Parallelism > maxBatchParallelism {
+		req.Parallelism = maxBatchParallelism
+	}
+	now := c.Clock.Now()
+	return &Rollout{
+		ID:            c.IDs.NewID("ro"),
+		Phase:         rolloutCanary,
+		Selector:      req.Selector,
+		Patch:         req.Patch,
+		CanaryPercent: req.CanaryPercent,
+		BakeTime:      bakeTime.String(),
+		CheckInterval: checkInterval.String(),
+		AutoRollback:  req.AutoRollback == nil || *req.AutoRollback,
+		Parallelism:   req.Parallelism,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		bakeTime:      bakeTime,
+		checkInterval: checkInterval,
+	}, ""
+}
+
+// activeRolloutForLocked returns the ID of a rollout that still owns
+// resource id, or "". Caller must hold c.mu.
+// WHY FAILED ROLLOUTS TOO: A Failed rollout can still be aborted, which
+// restores the specs it saved; a newer change would be overwritten.
+func (c *Controller) activeRolloutForLocked(id string) string {
+	for _, ro := range c.Rollouts {
+		for _, target := range ro.Targets {
+			if target.ResourceID != id {
+				continue
+			}
+			if ro.active() || (ro.Phase == rolloutFailed && target.State != targetPending && target.State != targetRolledBack) {
+				return ro.ID
+			}
+		}
+	}
+	return ""
+}
+
+// runRollout drives a rollout through its phases.
+func (c *Controller) runRollout(ctx context.Context, ro *Rollout) {
+	defer ro.cancel()
+
+	// Stage 1: canaries
+	if failure := c.applyStage(ctx, ro, stageCanary); failure != "" {
+		c.failRollout(ro, "canary update failed: "+failure)
+		return
+	}
+
+	// Stage 2: bake
+	if c.countStage(ro, stageMain) > 0 {
+		c.setRolloutPhase(ro, rolloutBaking, "waiting for canaries to stay healthy for "+ro.BakeTime)
+		if failure := c.bakeCanaries(ctx, ro); failure != "" {
+			c.failRollout(ro, failure)
+			return
+		}
+
+		// Stage 3: everyone else
+		c.setRolloutPhase(ro, rolloutPromoting, "canaries healthy; updating the rest of the group")
+		if failure := c.applyStage(ctx, ro, stageMain); failure != "" {
+			c.failRollout(ro, "promotion failed: "+failure)
+			return
+		}
+	}
+
+	c.mu.Lock()
+	if ro.aborted {
+		// Aborted after the last update finished
+		c.mu.Unlock()
+		c.failRollout(ro, "aborted")
+		return
+	}
+	ro.Phase = rolloutCompleted
+	ro.Message = fmt.Sprintf("updated %d resources", len(ro.Targets))
+	ro.UpdatedAt = c.Clock.Now()
+	ro.CompletedAt = ro.UpdatedAt
+	c.mu.Unlock()
+	logger.Infof("Rollout %s completed", ro.ID)
+}
+
+// applyStage updates the stage's targets with bounded parallelism.
+// Returns a description of the first failure, or "".
+func (c *Controller) applyStage(ctx context.Context, ro *Rollout, stage string) string {
+	c.mu.RLock()
+	var indexes []int
+	for i, target := range ro.Targets {
+		if target.Stage == stage && target.State == targetPending {
+			indexes = append(indexes, i)
+		}
+	}
+	c.mu.RUnlock()
+
+	// WHY NOT FATAL: One target failing doesn't stop the others in its
+	// stage; the rollout decides what a failed stage means
+	branches, _ := fanout.Map(ctx, indexes, fanout.Options{Limit: ro.Parallelism}, func(ctx context.Context, i int) (struct{}, error) {
+		c.mu.RLock()
+		target := ro.Targets[i]
+		c.mu.RUnlock()
+		err := c.waitOutMaintenance(ctx, target.updated.VendorType)
+		if err == nil {
+			_, err = c.updateResourceSpec(ctx, target.ResourceID, target.updated, "rollout", " (rollout "+ro.ID+", "+stage+")")
+		}
+
+		c.mu.Lock()
+		ro.UpdatedAt = c.Clock.Now()
+		if err != nil {
+			ro.Targets[i].State = targetFailed
+			ro.Targets[i].Error = err.Error()
+		} else {
+			ro.Targets[i].State = targetUpdated
+		}
+		c.mu.Unlock()
+		if err != nil {
+			err = errors.New(target.ResourceID + ": " + err.Error())
+		}
+		return struct{}{}, err
+	})
+	var failures []string
+	for _, branch := range branches {
+		if branch.Err != nil && !errors.Is(branch.Err, fanout.ErrNotStarted) {
+			failures = append(failures, branch.Err.Error())
+		}
+	}
+
+	if ctx.Err() != nil {
+		return "aborted"
+	}
+	if len(failures) > 0 {
+		sort.Strings(failures)
+		return failures[0]
+	}
+	return ""
+}
+
+// bakeCanaries checks canary health every check interval until the bake
+// time has passed. Returns a description of the first problem, or "".
+func (c *Controller) bakeCanaries(ctx context.Context, ro *Rollout) string {
+	deadline := c.Clock.Now().Add(ro.bakeTime)
+	c.mu.Lock()
+	ro.BakeUntil = deadline
+	c.mu.Unlock()
+
+	for {
+		if problem := c.checkCanaries(ctx, ro); problem != "" {
+			return problem
+		}
+		remaining := deadline.Sub(c.Clock.Now())
+		if remaining <= 0 {
+			return ""
+		}
+		wait := ro.checkInterval
+		if remaining < wait {
+			wait = remaining
+		}
+		select {
+		case <-ctx.Done():
+			return "aborted"
+		case <-c.Clock.After(wait):
+		}
+	}
+}
+
+// checkCanaries reads every updated canary from the vendor and returns a
+// description of the first unhealthy one, or "".
+func (c *Controller) checkCanaries(ctx context.Context, ro *Rollout) string {
+	c.mu.RLock()
+	var ids []string
+	for _, target := range ro.Targets {
+		if target.Stage == stageCanary && target.State == targetUpdated {
+			ids = append(ids, target.ResourceID)
+		}
+	}
+	c.mu.RUnlock()
+
+	for _, id := range ids {
+		status, err := c.refreshStatus(ctx, id)
+		if ctx.Err() != nil {
+			return "aborted"
+		}
+		switch {
+		case err != nil:
+			return fmt.Sprintf("canary %s health check failed: %v", id, err)
+		case !status.IsHealthy():
+			return fmt.Sprintf("canary %s is %s/%s: %s", id, status.Phase, status.HealthStatus, status.Message)
+		}
+	}
+	return ""
+}
+
+// failRollout ends a rollout after a failure or abort, rolling back the
+// changed resources when configured (always, for an abort).
+func (c *Controller) failRollout(ro *Rollout, reason string) {
+	c.mu.Lock()
+	aborted := ro.aborted
+	if aborted {
+		reason = "aborted"
+	}
+	if !ro.AutoRollback && !aborted {
+		ro.Phase = rolloutFailed
+		ro.Message = reason + " (auto_rollback disabled; changed resources were left as they are)"
+		ro.UpdatedAt = c.Clock.Now()
+		ro.CompletedAt = ro.UpdatedAt
+		c.mu.Unlock()
+		logger.Warnf("Rollout %s failed: %s", ro.ID, reason)
+		return
+	}
+	c.mu.Unlock()
+
+	logger.Warnf("Rollout %s: %s; rolling back", ro.ID, reason)
+	c.rollBack(ro, reason)
+}
+
+// rollBack restores the previous spec of every changed target.
+// WHY A FRESH CONTEXT: The rollout's context may have been cancelled by
+// an abort; the rollback itself must still run.
+func (c *Controller) rollBack(ro *Rollout, reason string) {
+	c.setRolloutPhase(ro, rolloutRollingBack, reason)
+
+	c.mu.RLock()
+	var indexes []int
+	for i, target := range ro.Targets {
+		// WHY FAILED TOO: A failed update may have been half-applied by the vendor
+		// rollback_failed: an abort of a Failed rollout retries the rollback
+		if target.State == targetUpdated || target.State == targetFailed || target.State == targetRollbackFailed {
+			indexes = append(indexes, i)
+		}
+	}
+	c.mu.RUnlock()
+
+	failed := 0
+	for _, i := range indexes {
+		c.mu.RLock()
+		target := ro.Targets[i]
+		c.mu.RUnlock()
+		_, err := c.updateResourceSpec(context.Background(), target.ResourceID, target.previous,
+			"rollout-rollback", " (rollback of rollout "+ro.ID+")")
+
+		c.mu.Lock()
+		if err != nil && !errors.Is(err, errResourceNotFound) {
+			ro.Targets[i].State = targetRollbackFailed
+			ro.Targets[i].Error = "rollback: " + err.Error()
+			failed++
+		} else {
+			ro.Targets[i].State = targetRolledBack
+		}
+		c.mu.Unlock()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case failed > 0:
+		ro.Phase = rolloutFailed
+		ro.Message = fmt.Sprintf("%s; rollback failed for %d resources", reason, failed)
+	case ro.aborted:
+		ro.Phase = rolloutAborted
+		ro.Message = fmt.Sprintf("aborted; %d resources restored", len(indexes))
+	default:
+		ro.Phase = rolloutRolledBack
+		ro.Message = fmt.Sprintf("%s; %d resources restored", reason, len(indexes))
+	}
+	ro.UpdatedAt = c.Clock.Now()
+	ro.CompletedAt = ro.UpdatedAt
+}
+
+func (c *Controller) setRolloutPhase(ro *Rollout, phase, message string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ro.Phase = phase
+	ro.Message = message
+	ro.UpdatedAt = c.Clock.Now()
+}
+
+func (c *Controller) countStage(ro *Rollout, stage string) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	n := 0
+	for _, target := range ro.Targets {
+		if target.Stage == stage {
+			n++
+		}
+	}
+	return n
+}
+
+// HandleListRollouts handles GET /rollouts
+func (c *Controller) HandleListRollouts(w http.ResponseWriter, r *http.Request) {
+	c.mu.RLock()
+	items := make([]Rollout, 0, len(c.Rollouts))
+	for _, ro := range c.Rollouts {
+		items = append(items, ro.snapshot())
+	}
+	c.mu.RUnlock()
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].CreatedAt.Equal(items[j].CreatedAt) {
+			return items[i].CreatedAt.After(items[j].CreatedAt)
+		}
+		return items[i].ID > items[j].ID
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+}
+
+// HandleGetRollout handles GET /rollouts/{id}
+func (c *Controller) HandleGetRollout(w http.ResponseWriter, r *http.Request) {
+	c.mu.RLock()
+	ro, exists := c.Rollouts[mux.Vars(r)["id"]]
+	var response Rollout
+	if exists {
+		response = ro.snapshot()
+	}
+	c.mu.RUnlock()
+
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "rollout not found"})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleAbortRollout handles POST /rollouts/{id}/abort
+// Stops a running rollout, or one that failed without auto-rollback, and
+// restores every changed resource.
+func (c *Controller) HandleAbortRollout(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	ro, exists := c.Rollouts[mux.Vars(r)["id"]]
+	if !exists {
+		c.mu.Unlock()
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "rollout not found"})
+		return
+	}
+	if ro.aborted || (!ro.active() && ro.Phase != rolloutFailed) || ro.Phase == rolloutRollingBack {
+		phase := ro.Phase
+		c.mu.Unlock()
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "rollout is " + phase + "; nothing to abort"})
+		return
+	}
+	ro.aborted = true
+	running := ro.active()
+	c.mu.Unlock()
+
+	if running {
+		// The runner notices the cancellation and rolls back
+		ro.cancel()
+	} else {
+		go c.rollBack(ro, "aborted")
+	}
+	logger.Infof("Rollout %s aborted", ro.ID)
+
+	c.mu.RLock()
+	response := ro.snapshot()
+	c.mu.RUnlock()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(response)
+}
+
+// patchSpec applies a JSON merge patch (RFC 7396) to spec.
+func patchSpec(spec models.ResourceSpec, patch map[string]interface{}) (models.ResourceSpec, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return spec, err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return spec, err
+	}
+	merged, err := json.Marshal(mergePatch(doc, patch))
+	if err != nil {
+		return spec, err
+	}
+	var out models.ResourceSpec
+	if err := json.Unmarshal(merged, &out); err != nil {
+		return spec, fmt.Errorf("patched spec is invalid: %w", err)
+	}
+	return out, nil
+}
+
+// mergePatch applies patch to target per RFC 7396: objects merge
+// recursively, null deletes a key, anything else replaces.
+func mergePatch(target, patch map[string]interface{}) map[string]interface{} {
+	if target == nil {
+		target = make(map[string]interface{})
+	}
+	for key, value := range patch {
+		if value == nil {
+			delete(target, key)
+			continue
+		}
+		if patchObj, ok := value.(map[string]interface{}); ok {
+			targetObj, _ := target[key].(map[string]interface{})
+			target[key] = mergePatch(targetObj, patchObj)
+			continue
+		}
+		target[key] = value
+	}
+	return target
+}
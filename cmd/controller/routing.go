@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/labels"
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// VENDOR ROUTING
+// =============================================================================
+// Most teams always use the same vendor for the same kind of device, so
+// they shouldn't have to repeat vendor_type in every spec. A create
+// without spec.vendor_type is routed by its namespace's rules, which
+// VENDOR_ROUTING_FILE declares:
+//
+//   {
+//     "namespaces": {
+//       "sydney": {"default_vendor": "sony",
+//                  "rules": [{"match": {"type": "camera"}, "vendor": "sony"},
+//                            {"match": {"type": "cloud-channel"}, "vendor": "mock"},
+//                            {"match": {"labels": "site=lab"}, "vendor": "mock"}]},
+//       "*":      {"default_vendor": "sony"}
+//     }
+//   }
+//
+// The namespace's rules are tried in order (a rule matches on the resource
+// type and/or a label selector), then its default_vendor, then the same
+// for "*". An explicit vendor_type always wins. Vendors are PROVIDERS
+// names; a file naming one that isn't registered stops the controller.
+// Without a route, vendor_type is required as before.
+//
+// GET /admin/routing shows the rules; ?namespace=&type=&labels= shows
+// where such a resource would go.
+// =============================================================================
+
+// routeAnyNamespace holds the rules for namespaces without their own.
+const routeAnyNamespace = "*"
+
+// RouteMatch selects resources; empty fields match everything.
+type RouteMatch struct {
+	Type string `json:"type,omitempty"`
+
+	// Labels is a label selector (see pkg/labels)
+	Labels string `json:"labels,omitempty"`
+
+	selector labels.Selector
+}
+
+// RouteRule sends matching resources to Vendor.
+type RouteRule struct {
+	Match  RouteMatch `json:"match"`
+	Vendor string     `json:"vendor"`
+}
+
+// NamespaceRoutes are one namespace's rules.
+type NamespaceRoutes struct {
+	DefaultVendor string      `json:"default_vendor,omitempty"`
+	Rules         []RouteRule `json:"rules,omitempty"`
+}
+
+// VendorRouting maps namespace ("*" for the rest) → its routes.
+type VendorRouting struct {
+	Namespaces map[string]*NamespaceRoutes `json:"namespaces"`
+}
+
+// loadVendorRouting reads VENDOR_ROUTING_FILE and checks every vendor is
+// one of vendors. Returns an empty routing if the file isn't set.
+func loadVendorRouting(path string, vendors map[string]bool) (VendorRouting, error) {
+	routing := VendorRouting{Namespaces: make(map[string]*NamespaceRoutes)}
+	if path == "" {
+		return routing, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return routing, err
+	}
+	if err := json.Unmarshal(data, &routing); err != nil {
+		return routing, fmt.Errorf("%s: %w", path, err)
+	}
+
+	// WHY CHECK NOW: A typo in a vendor name would fail every create it
+	// routes
+	known := func(vendor string) error {
+		if vendors[vendor] {
+			return nil
+		}
+		registered := make([]string, 0, len(vendors))
+		for name := range vendors {
+			registered = append(registered, name)
+		}
+		sort.Strings(registered)
+		return fmt.Errorf("unknown vendor %q (registered: %s)", vendor, strings.Join(registered, ", "))
+	}
+	for namespace, routes := range routing.Namespaces {
+		if routes == nil {
+			return routing, fmt.Errorf("%s: namespace %q must be an object", path, namespace)
+		}
+		if namespace != routeAnyNamespace && labels.ValidateValue(namespace) != nil {
+			return routing, fmt.Errorf("%s: invalid namespace %q", path, namespace)
+		}
+		if routes.DefaultVendor != "" {
+			if err := known(routes.DefaultVendor); err != nil {
+				return routing, fmt.Errorf("%s: namespace %q: default_vendor: %w", path, namespace, err)
+			}
+		}
+		for i := range routes.Rules {
+			rule := &routes.Rules[i]
+			if err := known(rule.Vendor); err != nil {
+				return routing, fmt.Errorf("%s: namespace %q: rule %d: %w", path, namespace, i, err)
+			}
+			if rule.Match.selector, err = labels.Parse(rule.Match.Labels); err != nil {
+				return routing, fmt.Errorf("%s: namespace %q: rule %d: %w", path, namespace, i, err)
+			}
+		}
+	}
+	return routing, nil
+}
+
+// route returns the vendor for a resource of resourceType with
+// resourceLabels in namespace, and why ("" if nothing routes it).
+func (v VendorRouting) route(namespace, resourceType string, resourceLabels map[string]string) (vendor, reason string) {
+	for _, name := range []string{namespaceKey(namespace), routeAnyNamespace} {
+		routes, ok := v.Namespaces[name]
+		if !ok {
+			continue
+		}
+		for i, rule := range routes.Rules {
+			if (rule.Match.Type == "" || rule.Match.Type == resourceType) && rule.Match.selector.Matches(resourceLabels) {
+				return rule.Vendor, fmt.Sprintf("namespace %q rule %d", name, i)
+			}
+		}
+		if routes.DefaultVendor != "" {
+			return routes.DefaultVendor, fmt.Sprintf("namespace %q default_vendor", name)
+		}
+	}
+	return "", ""
+}
+
+// routeVendor fills in resource's vendor_type from the routing rules if it
+// has none. Returns false if no rule routes it.
+func (c *Controller) routeVendor(resource *models.ForgeResource) bool {
+	if resource.Spec.VendorType != "" {
+		return true
+	}
+	vendor, reason := c.config().Routing.route(resource.Namespace, resource.Type, resource.Labels)
+	if vendor == "" {
+		return false
+	}
+	resource.Spec.VendorType = vendor
+	logger.Debugf("Routed %s %q to %s (%s)", resource.Type, resource.Name, vendor, reason)
+	return true
+}
+
+// HandleGetRouting handles GET /admin/routing
+// With ?type= (and optionally ?namespace=, ?labels=), also says where such
+// a resource would be routed.
+func (c *Controller) HandleGetRouting(w http.ResponseWriter, r *http.Request) {
+	cfg := c.config()
+	w.Header().Set("Content-Type", "application/json")
+	response := map[string]interface{}{"namespaces": cfg.Routing.Namespaces}
+	query := r.URL.Query()
+	if query.Has("type") {
+		selected := make(map[string]string)
+		for _, pair := range strings.Split(query.Get("labels"), ",") {
+			if key, value, ok := strings.Cut(strings.TrimSpace(pair), "="); ok {
+				selected[key] = value
+			}
+		}
+		vendor, reason := cfg.Routing.route(query.Get("namespace"), query.Get("type"), selected)
+		response["route"] = map[string]string{"vendor": vendor, "reason": reason}
+	}
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,885 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/cron"
+	"github.com/Zhichengu1/mock-control-plane/pkg/fanout"
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// SCHEDULER (/schedules)
+// =============================================================================
+// Jobs that run on a timetable rather than on a request:
+//
+//   drift-sync         reads every provisioned resource from its vendor
+//                      and reports the ones whose phase or health changed
+//                      (one reconciler pass, whether or not it is enabled)
+//   orphan-scan        a discovery scan: devices on the vendor that no
+//                      resource manages get adoption proposals (see
+//                      discovery.go)
+//   retention-cleanup  compaction of revisions, events and the audit
+//                      trail (see compaction.go), whatever the window
+//   provision          creates a resource once, at a given time
+//
+// "vendor" limits drift-sync and orphan-scan to one provider.
+//
+// A schedule has a cron expression (see pkg/cron), evaluated in its
+// timezone (UTC by default), or for provision a single "at" time:
+//
+//   POST /schedules
+//   {"job": "provision", "at": "2026-02-08T21:00:00Z",
+//    "resource": {"name": "cam-halftime", "type": "camera", "spec": {...}}}
+//
+//   {"job": "orphan-scan", "cron": "0 6 * * mon-fri", "timezone":
+//    "America/New_York", "vendor": "sony"}
+//
+// The three maintenance jobs are built in, with the expressions from
+// SCHEDULE_DRIFT_SYNC ("0 3 * * *"), SCHEDULE_ORPHAN_SCAN ("0 4 * * 0")
+// and SCHEDULE_RETENTION_CLEANUP ("30 2 * * *"); "off" drops one. The API
+// can pause, resume and run them but not change or delete them.
+//
+// Every run is kept in the schedule's history (the last SCHEDULE_HISTORY,
+// default 50): trigger, start, end, outcome and what the job reported.
+// A schedule whose last run is still going skips its turn (recorded as
+// skipped) instead of piling up runs. Provisioning failures (validation,
+// a name in use) show up there too: the resource is only checked when it
+// is created.
+//
+// PERSISTENCE: SCHEDULES_STATE_FILE keeps the schedules, their next run
+// and their history across restarts (written after every change). A run
+// that was due while the controller was down runs once at startup, if it
+// is less than SCHEDULE_CATCH_UP (default 1h) late; older ones are
+// recorded as skipped. Without the file, schedules made through the API
+// only live as long as the process.
+// =============================================================================
+
+// Schedulable jobs.
+const (
+	jobDriftSync        = "drift-sync"
+	jobOrphanScan       = "orphan-scan"
+	jobRetentionCleanup = "retention-cleanup"
+	jobProvision        = "provision"
+)
+
+// scheduleJobs lists the jobs in the order they are documented.
+var scheduleJobs = []string{jobDriftSync, jobOrphanScan, jobRetentionCleanup, jobProvision}
+
+// builtinSchedules are the maintenance jobs every controller has, with
+// the variable that sets their expression and its default.
+var builtinSchedules = []struct {
+	job, env, cron string
+}{
+	{jobDriftSync, "SCHEDULE_DRIFT_SYNC", "0 3 * * *"},
+	{jobOrphanScan, "SCHEDULE_ORPHAN_SCAN", "0 4 * * 0"},
+	{jobRetentionCleanup, "SCHEDULE_RETENTION_CLEANUP", "30 2 * * *"},
+}
+
+// Schedule states (computed).
+const (
+	scheduleActive    = "active"
+	schedulePaused    = "paused"
+	scheduleRunning   = "running"
+	scheduleCompleted = "completed"
+)
+
+// Run triggers and outcomes.
+const (
+	triggerSchedule = "schedule"
+	triggerManual   = "manual"
+	triggerMissed   = "missed"
+
+	runRunning   = "running"
+	runSucceeded = "succeeded"
+	runFailed    = "failed"
+	runSkipped   = "skipped"
+)
+
+// maxSchedulerSleep is the longest the scheduler sleeps between checks.
+// WHY: The test mode clock can jump; a minute is the cron resolution anyway
+const maxSchedulerSleep = time.Minute
+
+// Schedule runs a job on a cron expression or once.
+type Schedule struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+	Job  string `json:"job"`
+
+	// Cron is a cron expression in Timezone (default UTC); At is a single
+	// run instead
+	Cron     string     `json:"cron,omitempty"`
+	Timezone string     `json:"timezone,omitempty"`
+	At       *time.Time `json:"at,omitempty"`
+
+	// Vendor limits drift-sync and orphan-scan to one provider
+	Vendor string `json:"vendor,omitempty"`
+
+	// Resource is what a provision job creates, as for POST /resources
+	Resource json.RawMessage `json:"resource,omitempty"`
+
+	Paused bool `json:"paused"`
+
+	// Builtin marks the maintenance jobs configured by SCHEDULE_*
+	Builtin   bool      `json:"builtin"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// NextRun is when the job runs next (nil once a single run is done)
+	NextRun *time.Time `json:"next_run,omitempty"`
+
+	// Done marks a single-run schedule that has run
+	Done bool `json:"done,omitempty"`
+
+	// State and LastRun are filled in for responses
+	State   string       `json:"state,omitempty"`
+	LastRun *ScheduleRun `json:"last_run,omitempty"`
+}
+
+// ScheduleRun is one run of a schedule.
+type ScheduleRun struct {
+	ID         string `json:"id"`
+	ScheduleID string `json:"schedule_id"`
+	Job        string `json:"job"`
+
+	// Trigger is "schedule", "manual" (POST /schedules/{id}:run) or
+	// "missed" (due while the controller was down)
+	Trigger string `json:"trigger"`
+
+	// Status is running, succeeded, failed or skipped
+	Status     string     `json:"status"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	// Result is what the job reported; Error why it failed or was skipped
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// ScheduleRequest is the body of POST and PUT /schedules.
+type ScheduleRequest struct {
+	Name     string          `json:"name,omitempty"`
+	Job      string          `json:"job"`
+	Cron     string          `json:"cron,omitempty"`
+	Timezone string          `json:"timezone,omitempty"`
+	At       *time.Time      `json:"at,omitempty"`
+	Vendor   string          `json:"vendor,omitempty"`
+	Resource json.RawMessage `json:"resource,omitempty"`
+	Paused   bool            `json:"paused,omitempty"`
+}
+
+// DriftSyncResult is what a drift-sync run reports.
+type DriftSyncResult struct {
+	Checked int `json:"checked"`
+
+	// Changed lists resources whose phase or health changed
+	Changed []string `json:"changed"`
+
+	// Failed maps resource ID → why it couldn't be read
+	Failed map[string]string `json:"failed,omitempty"`
+}
+
+// OrphanScanResult is what an orphan-scan run reports.
+type OrphanScanResult struct {
+	Scanned int `json:"scanned"`
+	Managed int `json:"managed"`
+
+	// Unmanaged lists the adoption proposals for devices no resource manages
+	Unmanaged []string `json:"unmanaged"`
+
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// scheduleState holds the schedules and their history.
+type scheduleState struct {
+	mu         sync.Mutex
+	path       string // SCHEDULES_STATE_FILE ("" = memory only)
+	schedules  map[string]*Schedule
+	history    map[string][]*ScheduleRun // by schedule ID, oldest first
+	running    map[string]bool
+	maxHistory int
+
+	// missed holds the schedules whose run came due while the controller
+	// was down; catchUp is how late such a run may still start
+	missed  map[string]bool
+	catchUp time.Duration
+
+	// wake interrupts the scheduler's sleep after a change
+	wake chan struct{}
+
+	// ctx is the scheduler's, for runs started through the API
+	ctx context.Context
+}
+
+// schedulesFile is the layout of SCHEDULES_STATE_FILE.
+type schedulesFile struct {
+	Schedules map[string]*Schedule      `json:"schedules"`
+	History   map[string][]*ScheduleRun `json:"history,omitempty"`
+}
+
+// newScheduleState loads saved schedules from path, if any.
+func newScheduleState(path string) *scheduleState {
+	state := &scheduleState{
+		path:       path,
+		schedules:  make(map[string]*Schedule),
+		history:    make(map[string][]*ScheduleRun),
+		running:    make(map[string]bool),
+		missed:     make(map[string]bool),
+		maxHistory: envInt("SCHEDULE_HISTORY", 50),
+		catchUp:    envDuration("SCHEDULE_CATCH_UP", time.Hour),
+		wake:       make(chan struct{}, 1),
+		ctx:        context.Background(),
+	}
+	if path == "" {
+		return state
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state
+	}
+	var file schedulesFile
+	if err == nil {
+		err = json.Unmarshal(data, &file)
+	}
+	if err != nil {
+		logger.Warnf("Ignoring SCHEDULES_STATE_FILE %s: %v", path, err)
+		return state
+	}
+	for id, s := range file.Schedules {
+		state.schedules[id] = s
+	}
+	for id, runs := range file.History {
+		for _, run := range runs {
+			// WHY: A run that was going when the process stopped never finished
+			if run.Status == runRunning {
+				run.Status, run.Error = runFailed, "interrupted by a restart"
+			}
+		}
+		state.history[id] = runs
+	}
+	logger.Infof("Loaded %d schedules from %s", len(state.schedules), path)
+	return state
+}
+
+// saveLocked writes the schedules and history to the state file. Must be
+// called with mu held.
+// WHY RENAME: A crash mid-write must not leave a truncated file behind
+func (s *scheduleState) saveLocked() {
+	if s.path == "" {
+		return
+	}
+	data, err := json.Marshal(schedulesFile{Schedules: s.schedules, History: s.history})
+	if err == nil {
+		tmp := s.path + ".tmp"
+		if err = os.WriteFile(tmp, data, 0o600); err == nil {
+			err = os.Rename(tmp, s.path)
+		}
+	}
+	if err != nil {
+		logger.Warnf("Failed to save schedules: %v", err)
+	}
+}
+
+// poke wakes the scheduler to look at the schedules again.
+func (s *scheduleState) poke() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// recordLocked appends run to its schedule's history, dropping the
+// oldest past maxHistory. Must be called with mu held.
+func (s *scheduleState) recordLocked(run *ScheduleRun) {
+	runs := append(s.history[run.ScheduleID], run)
+	if s.maxHistory > 0 && len(runs) > s.maxHistory {
+		runs = runs[len(runs)-s.maxHistory:]
+	}
+	s.history[run.ScheduleID] = runs
+}
+
+// nextRun returns when sched runs next after now (nil for none).
+func nextRun(sched *Schedule, now time.Time) *time.Time {
+	if sched.At != nil {
+		if sched.Done {
+			return nil
+		}
+		at := *sched.At
+		return &at
+	}
+	expr, err := cron.Parse(sched.Cron)
+	if err != nil {
+		return nil
+	}
+	loc := time.UTC
+	if sched.Timezone != "" {
+		if loc, err = time.LoadLocation(sched.Timezone); err != nil {
+			return nil
+		}
+	}
+	next := expr.Next(now.In(loc))
+	if next.IsZero() {
+		return nil
+	}
+	next = next.UTC()
+	return &next
+}
+
+// view returns a copy of sched for a response. Must be called with mu held.
+func (s *scheduleState) view(sched *Schedule) Schedule {
+	out := *sched
+	switch {
+	case s.running[sched.ID]:
+		out.State = scheduleRunning
+	case sched.Done:
+		out.State = scheduleCompleted
+	case sched.Paused:
+		out.State = schedulePaused
+	default:
+		out.State = scheduleActive
+	}
+	if runs := s.history[sched.ID]; len(runs) > 0 {
+		last := *runs[len(runs)-1]
+		out.LastRun = &last
+	}
+	return out
+}
+
+// initSchedules registers the built-in maintenance jobs (their
+// expressions come from the environment every start) and works out the
+// next run of every schedule; missed runs become due now.
+func (c *Controller) initSchedules() error {
+	state := c.schedules
+	now := c.Clock.Now()
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	for _, builtin := range builtinSchedules {
+		expr := os.Getenv(builtin.env)
+		if expr == "" {
+			expr = builtin.cron
+		}
+		if expr == "off" {
+			delete(state.schedules, builtin.job)
+			delete(state.history, builtin.job)
+			continue
+		}
+		if _, err := cron.Parse(expr); err != nil {
+			return fmt.Errorf("%s: %w", builtin.env, err)
+		}
+		sched, exists := state.schedules[builtin.job]
+		if !exists {
+			sched = &Schedule{ID: builtin.job, Job: builtin.job, Builtin: true, CreatedAt: now}
+			state.schedules[sched.ID] = sched
+		}
+		if sched.Cron != expr {
+			sched.Cron, sched.NextRun = expr, nil
+		}
+	}
+	for _, sched := range state.schedules {
+		if sched.NextRun == nil {
+			sched.NextRun = nextRun(sched, now)
+		} else if sched.NextRun.Before(now) {
+			state.missed[sched.ID] = true
+		}
+	}
+	state.saveLocked()
+	return nil
+}
+
+// startScheduler runs due schedules in the background.
+func (c *Controller) startScheduler(ctx context.Context) {
+	c.schedules.mu.Lock()
+	count := len(c.schedules.schedules)
+	c.schedules.ctx = ctx
+	c.schedules.mu.Unlock()
+	logger.Infof("Scheduler started with %d schedules", count)
+	go func() {
+		for {
+			c.runDueSchedules(ctx)
+			wait := maxSchedulerSleep
+			c.schedules.mu.Lock()
+			now := c.Clock.Now()
+			for _, sched := range c.schedules.schedules {
+				if sched.NextRun != nil && !sched.Paused && sched.NextRun.Sub(now) < wait {
+					wait = sched.NextRun.Sub(now)
+				}
+			}
+			c.schedules.mu.Unlock()
+			select {
+			case <-ctx.Done():
+				return
+			case <-c.Clock.After(wait):
+			case <-c.schedules.wake:
+			}
+		}
+	}()
+}
+
+// runDueSchedules starts every schedule whose next run has come.
+func (c *Controller) runDueSchedules(ctx context.Context) {
+	state := c.schedules
+	now := c.Clock.Now()
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	changed := false
+	for _, id := range sortedKeys(state.schedules) {
+		sched := state.schedules[id]
+		if sched.Paused || sched.NextRun == nil || sched.NextRun.After(now) {
+			continue
+		}
+		due := *sched.NextRun
+		if sched.At != nil {
+			sched.Done = true
+		}
+		sched.NextRun = nextRun(sched, now)
+		changed = true
+
+		// Step 1: Decide whether this turn runs
+		trigger, skip := triggerSchedule, ""
+		if state.missed[id] {
+			delete(state.missed, id)
+			trigger = triggerMissed
+			if late := now.Sub(due); late > state.catchUp {
+				skip = fmt.Sprintf("missed by %s (more than SCHEDULE_CATCH_UP)", late.Round(time.Second))
+			}
+		}
+		if state.running[id] {
+			skip = "the previous run is still going"
+		}
+		if skip != "" {
+			finished := now
+			state.recordLocked(&ScheduleRun{
+				ID: c.IDs.NewID("run"), ScheduleID: id, Job: sched.Job, Trigger: trigger,
+				Status: runSkipped, StartedAt: now, FinishedAt: &finished, Error: skip,
+			})
+			logger.Warnf("Schedule %s (%s) skipped: %s", id, sched.Job, skip)
+			continue
+		}
+
+		// Step 2: Start it
+		c.startRunLocked(ctx, sched, trigger)
+	}
+	if changed {
+		state.saveLocked()
+	}
+}
+
+// startRunLocked records a run of sched and starts it in the background.
+// Must be called with c.schedules.mu held.
+func (c *Controller) startRunLocked(ctx context.Context, sched *Schedule, trigger string) *ScheduleRun {
+	state := c.schedules
+	run := &ScheduleRun{
+		ID:         c.IDs.NewID("run"),
+		ScheduleID: sched.ID,
+		Job:        sched.Job,
+		Trigger:    trigger,
+		Status:     runRunning,
+		StartedAt:  c.Clock.Now(),
+	}
+	state.recordLocked(run)
+	state.running[sched.ID] = true
+	state.saveLocked()
+	job := *sched
+	logger.Infof("Schedule %s: %s started (%s)", sched.ID, sched.Job, trigger)
+	go func() {
+		result, err := c.runJob(ctx, &job)
+		finished := c.Clock.Now()
+
+		state.mu.Lock()
+		defer state.mu.Unlock()
+		delete(state.running, job.ID)
+		run.FinishedAt = &finished
+		run.Status = runSucceeded
+		if result != nil {
+			run.Result, _ = json.Marshal(result)
+		}
+		if err != nil {
+			run.Status, run.Error = runFailed, err.Error()
+			logger.Warnf("Schedule %s: %s failed: %v", job.ID, job.Job, err)
+		} else {
+			logger.Infof("Schedule %s: %s finished in %s", job.ID, job.Job, finished.Sub(run.StartedAt).Round(time.Millisecond))
+		}
+		state.saveLocked()
+	}()
+	return run
+}
+
+// runJob runs the job of sched.
+func (c *Controller) runJob(ctx context.Context, sched *Schedule) (interface{}, error) {
+	switch sched.Job {
+	case jobDriftSync:
+		// WHY SKIP: Under memory pressure background work waits (see limits.go)
+		if c.ReconcilerPaused() {
+			return nil, errors.New("paused under memory pressure")
+		}
+		return c.driftSync(ctx, sched.Vendor), nil
+	case jobOrphanScan:
+		// WHY 60 SECONDS: As for POST /discovery/scan
+		scanCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+		defer cancel()
+		scan := c.runDiscovery(scanCtx, sched.Vendor)
+		result := OrphanScanResult{Scanned: scan.Scanned, Managed: scan.Managed, Unmanaged: []string{}, Errors: scan.Errors}
+		for _, proposal := range scan.Proposals {
+			result.Unmanaged = append(result.Unmanaged, proposal.ID)
+		}
+		sort.Strings(result.Unmanaged)
+		if len(scan.Errors) > 0 {
+			return result, fmt.Errorf("%d providers couldn't be scanned", len(scan.Errors))
+		}
+		return result, nil
+	case jobRetentionCleanup:
+		results := make(map[string]CompactionStats, len(compactTypes))
+		for _, t := range compactTypes {
+			results[t] = c.compact(t, "schedule")
+		}
+		return results, nil
+	case jobProvision:
+		return c.provisionScheduled(ctx, sched)
+	}
+	return nil, fmt.Errorf("unknown job %q", sched.Job)
+}
+
+// driftSync reads every provisioned resource (of vendor, if set) from its
+// vendor, as many at once as the reconciler has workers.
+func (c *Controller) driftSync(ctx context.Context, vendor string) DriftSyncResult {
+	var ids []string
+	before := make(map[string]models.ResourceStatus)
+	items := c.reconcileItems()
+	c.mu.RLock()
+	for _, item := range items {
+		if vendor != "" && item.Vendor != vendor {
+			continue
+		}
+		if res, exists := c.ResourceDB[item.Key]; exists {
+			ids = append(ids, item.Key)
+			before[item.Key] = res.Status
+		}
+	}
+	c.mu.RUnlock()
+	sort.Strings(ids)
+
+	result := DriftSyncResult{Checked: len(ids), Changed: []string{}}
+	branches, _ := fanout.Map(ctx, ids, fanout.Options{Limit: max(c.Reconcile.Workers, 1)}, func(ctx context.Context, id string) (*models.ResourceStatus, error) {
+		return c.refreshStatus(ctx, id)
+	})
+	for i, branch := range branches {
+		id := ids[i]
+		switch {
+		case errors.Is(branch.Err, errResourceGone):
+			// Deleted meanwhile
+		case branch.Err != nil:
+			if result.Failed == nil {
+				result.Failed = make(map[string]string)
+			}
+			result.Failed[id] = branch.Err.Error()
+		case branch.Value.Phase != before[id].Phase || branch.Value.HealthStatus != before[id].HealthStatus:
+			result.Changed = append(result.Changed, id)
+		}
+	}
+	return result
+}
+
+// provisionScheduled creates the resource of a provision schedule, as
+// POST /resources would on behalf of whoever made the schedule.
+func (c *Controller) provisionScheduled(ctx context.Context, sched *Schedule) (interface{}, error) {
+	var resource *models.ForgeResource
+	if err := json.Unmarshal(sched.Resource, &resource); err != nil || resource == nil {
+		return nil, fmt.Errorf("invalid resource: %v", err)
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, "/resources", nil)
+	if err != nil {
+		return nil, err
+	}
+	// WHY ADMIN: Only admins create schedules
+	if sched.CreatedBy != "" {
+		r = r.WithContext(context.WithValue(ctx, principalKey{}, Principal{Name: sched.CreatedBy, Role: RoleAdmin}))
+	}
+	if err := c.createResource(vendorContext(r), r, resource, ""); err != nil {
+		return nil, err
+	}
+	return map[string]string{"resource_id": resource.ID, "name": resource.Name, "phase": resource.Status.Phase}, nil
+}
+
+// provisionable reports whether raw is a resource with a name and type;
+// the rest is checked when it is created.
+func provisionable(raw json.RawMessage) bool {
+	var resource models.ForgeResource
+	return json.Unmarshal(raw, &resource) == nil && resource.Name != "" && resource.Type != ""
+}
+
+// validateScheduleRequest checks req and returns the schedule it
+// describes (without ID, creator and run times).
+func (c *Controller) validateScheduleRequest(req *ScheduleRequest) (*Schedule, error) {
+	cfg := c.config()
+	known := false
+	for _, job := range scheduleJobs {
+		known = known || req.Job == job
+	}
+	switch {
+	case !known:
+		return nil, fmt.Errorf("job must be one of: %s", strings.Join(scheduleJobs, ", "))
+	case (req.Cron == "") == (req.At == nil):
+		return nil, errors.New("give either cron or at")
+	case req.Job == jobProvision && req.At == nil:
+		return nil, errors.New("provision runs once: give at, not cron")
+	case req.Job == jobProvision && !provisionable(req.Resource):
+		return nil, errors.New("provision needs a resource with name and type")
+	case req.Job != jobProvision && req.Resource != nil:
+		return nil, errors.New("only provision takes a resource")
+	case req.Vendor != "" && req.Job != jobDriftSync && req.Job != jobOrphanScan:
+		return nil, errors.New("only drift-sync and orphan-scan take a vendor")
+	case req.Vendor != "" && cfg.Providers[req.Vendor] == nil:
+		return nil, fmt.Errorf("unknown vendor %s (configured: %s)", req.Vendor, strings.Join(sortedKeys(cfg.Providers), ", "))
+	case req.At != nil && req.Timezone != "":
+		return nil, errors.New("timezone only applies to cron (at carries its own offset)")
+	case req.At != nil && !req.At.After(c.Clock.Now()):
+		return nil, errors.New("at is in the past")
+	}
+	if req.Cron != "" {
+		if _, err := cron.Parse(req.Cron); err != nil {
+			return nil, fmt.Errorf("cron: %w", err)
+		}
+	}
+	if req.Timezone != "" {
+		if _, err := time.LoadLocation(req.Timezone); err != nil {
+			return nil, fmt.Errorf("unknown timezone %q", req.Timezone)
+		}
+	}
+	sched := &Schedule{
+		Name:     req.Name,
+		Job:      req.Job,
+		Cron:     req.Cron,
+		Timezone: req.Timezone,
+		Vendor:   req.Vendor,
+		Paused:   req.Paused,
+	}
+	if req.At != nil {
+		at := req.At.UTC()
+		sched.At = &at
+	}
+	if req.Resource != nil {
+		sched.Resource = append(json.RawMessage(nil), req.Resource...)
+	}
+	return sched, nil
+}
+
+// HandleListSchedules handles GET /schedules
+func (c *Controller) HandleListSchedules(w http.ResponseWriter, r *http.Request) {
+	state := c.schedules
+	state.mu.Lock()
+	items := make([]Schedule, 0, len(state.schedules))
+	for _, id := range sortedKeys(state.schedules) {
+		items = append(items, state.view(state.schedules[id]))
+	}
+	state.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+}
+
+// HandleGetSchedule handles GET /schedules/{id}
+func (c *Controller) HandleGetSchedule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	state := c.schedules
+	state.mu.Lock()
+	sched, exists := state.schedules[mux.Vars(r)["id"]]
+	var view Schedule
+	if exists {
+		view = state.view(sched)
+	}
+	state.mu.Unlock()
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "schedule not found"})
+		return
+	}
+	json.NewEncoder(w).Encode(view)
+}
+
+// HandleCreateSchedule handles POST /schedules
+func (c *Controller) HandleCreateSchedule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var req ScheduleRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxResourceBodyBytes)).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+
+	// Step 1: Validate
+	sched, err := c.validateScheduleRequest(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	// Step 2: Store and wake the scheduler
+	sched.ID = c.IDs.NewID("sch")
+	sched.CreatedAt = c.Clock.Now()
+	if principal, ok := principalFrom(r.Context()); ok {
+		sched.CreatedBy = principal.Name
+	}
+	sched.NextRun = nextRun(sched, sched.CreatedAt)
+	state := c.schedules
+	state.mu.Lock()
+	state.schedules[sched.ID] = sched
+	state.saveLocked()
+	view := state.view(sched)
+	state.mu.Unlock()
+	state.poke()
+
+	logger.Infof("Schedule %s created: %s", sched.ID, sched.Job)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(view)
+}
+
+// HandleUpdateSchedule handles PUT /schedules/{id}
+// Replaces the schedule's settings; the job can't change. Built-in
+// schedules only take paused.
+func (c *Controller) HandleUpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id := mux.Vars(r)["id"]
+	var req ScheduleRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxResourceBodyBytes)).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+
+	state := c.schedules
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	sched, exists := state.schedules[id]
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "schedule not found"})
+		return
+	}
+	if req.Job == "" {
+		req.Job = sched.Job
+	}
+	if req.Job != sched.Job {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "job can't change; create a new schedule"})
+		return
+	}
+
+	// Step 1: Built-ins: only pause or resume
+	if sched.Builtin {
+		if req.Cron != "" || req.At != nil || req.Timezone != "" || req.Vendor != "" || req.Resource != nil || req.Name != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "built-in schedules only take paused; their cron comes from the environment"})
+			return
+		}
+		sched.Paused = req.Paused
+	} else {
+		// Step 2: Others: replace the settings, keep identity and history
+		updated, err := c.validateScheduleRequest(&req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		updated.ID, updated.CreatedBy, updated.CreatedAt = sched.ID, sched.CreatedBy, sched.CreatedAt
+		state.schedules[id] = updated
+		sched = updated
+	}
+	sched.NextRun = nextRun(sched, c.Clock.Now())
+	delete(state.missed, id)
+	state.saveLocked()
+	state.poke()
+	logger.Infof("Schedule %s updated", id)
+	json.NewEncoder(w).Encode(state.view(sched))
+}
+
+// HandleDeleteSchedule handles DELETE /schedules/{id}
+// A run in progress finishes; its history goes with the schedule.
+func (c *Controller) HandleDeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id := mux.Vars(r)["id"]
+	state := c.schedules
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	sched, exists := state.schedules[id]
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "schedule not found"})
+		return
+	}
+	if sched.Builtin {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "built-in schedules can't be deleted; pause it, or set its SCHEDULE_* variable to off"})
+		return
+	}
+	delete(state.schedules, id)
+	delete(state.history, id)
+	state.saveLocked()
+	logger.Infof("Schedule %s deleted", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleRunSchedule handles POST /schedules/{id}:run
+// Runs the job now, in the background; the schedule's timetable is
+// unchanged (a provision schedule runs once either way).
+func (c *Controller) HandleRunSchedule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id := mux.Vars(r)["id"]
+	state := c.schedules
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	sched, exists := state.schedules[id]
+	switch {
+	case !exists:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "schedule not found"})
+		return
+	case state.running[id]:
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "schedule is already running"})
+		return
+	case sched.Done:
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "schedule has already run"})
+		return
+	}
+	if sched.At != nil {
+		sched.Done, sched.NextRun = true, nil
+	}
+	// WHY THE SCHEDULER'S CONTEXT: The run outlives this request
+	run := c.startRunLocked(state.ctx, sched, triggerManual)
+	response := *run
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleListScheduleRuns handles GET /schedules/{id}/runs
+// Newest first.
+func (c *Controller) HandleListScheduleRuns(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id := mux.Vars(r)["id"]
+	state := c.schedules
+	state.mu.Lock()
+	_, exists := state.schedules[id]
+	runs := state.history[id]
+	items := make([]ScheduleRun, 0, len(runs))
+	for i := len(runs) - 1; i >= 0; i-- {
+		items = append(items, *runs[i])
+	}
+	state.mu.Unlock()
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "schedule not found"})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// SEARCH (GET /resources/search?q=)
+// =============================================================================
+// The vendor's alarm says "sony-dev-170664 overheating"; the operator
+// needs the Forge resource that owns it. GET /resources/search finds
+// resources by what they are known as, not just by name:
+//
+//   GET /resources/search?q=sony-dev-1706
+//   → {"items": [{"id": "res-42", "name": "cam-3", ...,
+//                 "matches": [{"field": "status.vendor_id", "value": "sony-dev-170664"}]}],
+//      "total": 1}
+//
+// Searched: id, name, status.vendor_id, spec.stream_url, the endpoint
+// addresses and every spec.config value. Each word of q must match the
+// start of a value, or of one of its parts ("170664" finds
+// "sony-dev-170664"); case is ignored. The list filters (namespace,
+// vendor, phase, type, name_prefix, labelSelector) narrow the results,
+// and limit/offset page them.
+//
+// WHY AN INDEX: Scanning every resource's config per keystroke doesn't
+// scale to a fleet. Terms are kept sorted so a prefix is a binary search;
+// the index is updated with the revision history (recordRevision), which
+// every change to a resource already goes through.
+//
+// WHY RESPECT REDACTION: A viewer who can't see IP addresses mustn't be
+// able to find a camera by one either (see redaction.go).
+// =============================================================================
+
+// searchField is one indexed value of a resource.
+type searchField struct {
+	Field string `json:"field"`
+	Value string `json:"value"`
+}
+
+// searchIndex maps terms to the resources holding them. Protected by c.mu.
+type searchIndex struct {
+	// postings: term → resource IDs
+	postings map[string]map[string]bool
+
+	// terms lists the keys of postings, sorted
+	terms []string
+
+	// docs: resource ID → its indexed fields
+	docs map[string][]searchField
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		postings: make(map[string]map[string]bool),
+		docs:     make(map[string][]searchField),
+	}
+}
+
+// update re-indexes res, or removes it when deleted. Callers hold c.mu.
+func (idx *searchIndex) update(res *models.ForgeResource, deleted bool) {
+	for _, field := range idx.docs[res.ID] {
+		for _, term := range searchTerms(field.Value) {
+			idx.removeTerm(term, res.ID)
+		}
+	}
+	delete(idx.docs, res.ID)
+	if deleted {
+		return
+	}
+	fields := searchFields(res)
+	for _, field := range fields {
+		for _, term := range searchTerms(field.Value) {
+			idx.addTerm(term, res.ID)
+		}
+	}
+	idx.docs[res.ID] = fields
+}
+
+func (idx *searchIndex) addTerm(term, id string) {
+	ids, ok := idx.postings[term]
+	if !ok {
+		ids = make(map[string]bool)
+		idx.postings[term] = ids
+		i := sort.SearchStrings(idx.terms, term)
+		idx.terms = append(idx.terms, "")
+		copy(idx.terms[i+1:], idx.terms[i:])
+		idx.terms[i] = term
+	}
+	ids[id] = true
+}
+
+func (idx *searchIndex) removeTerm(term, id string) {
+	ids, ok := idx.postings[term]
+	if !ok {
+		return
+	}
+	delete(ids, id)
+	if len(ids) > 0 {
+		return
+	}
+	delete(idx.postings, term)
+	if i := sort.SearchStrings(idx.terms, term); i < len(idx.terms) && idx.terms[i] == term {
+		idx.terms = append(idx.terms[:i], idx.terms[i+1:]...)
+	}
+}
+
+// candidates returns the IDs holding a term that starts with prefix.
+func (idx *searchIndex) candidates(prefix string) map[string]bool {
+	found := make(map[string]bool)
+	for i := sort.SearchStrings(idx.terms, prefix); i < len(idx.terms) && strings.HasPrefix(idx.terms[i], prefix); i++ {
+		for id := range idx.postings[idx.terms[i]] {
+			found[id] = true
+		}
+	}
+	return found
+}
+
+// searchFields lists the values of res that search looks at.
+func searchFields(res *models.ForgeResource) []searchField {
+	fields := []searchField{{"id", res.ID}, {"name", res.Name}}
+	if res.Status.VendorID != "" {
+		fields = append(fields, searchField{"status.vendor_id", res.Status.VendorID})
+	}
+	if res.Spec.StreamURL != "" {
+		fields = append(fields, searchField{"spec.stream_url", res.Spec.StreamURL})
+	}
+	for _, endpoint := range res.Status.Endpoints {
+		if endpoint.Address != "" {
+			fields = append(fields, searchField{"status.endpoints.address", endpoint.Address})
+		}
+	}
+	return appendConfigFields(fields, "spec.config", res.Spec.Config)
+}
+
+// appendConfigFields adds the scalar values below value, keyed by path.
+func appendConfigFields(fields []searchField, path string, value interface{}) []searchField {
+	switch v := value.(type) {
+	case nil:
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fields = appendConfigFields(fields, path+"."+key, v[key])
+		}
+	case []interface{}:
+		for _, item := range v {
+			fields = appendConfigFields(fields, path, item)
+		}
+	default:
+		fields = append(fields, searchField{path, fmt.Sprint(v)})
+	}
+	return fields
+}
+
+// searchTerms returns the terms a value is found by: the whole value and
+// its parts between punctuation, lower-cased. Dots stay inside parts so
+// IP addresses match by prefix ("10.0.0").
+func searchTerms(value string) []string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return nil
+	}
+	terms := []string{value}
+	seen := map[string]bool{value: true}
+	parts := strings.FieldsFunc(value, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.'
+	})
+	for _, part := range parts {
+		if !seen[part] {
+			seen[part] = true
+			terms = append(terms, part)
+		}
+	}
+	return terms
+}
+
+// fieldMatches reports whether a query word matches value.
+func fieldMatches(word, value string) bool {
+	for _, term := range searchTerms(value) {
+		if strings.HasPrefix(term, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// hides reports whether the redactor removes field (or an object it is
+// in) from responses.
+func (red *redactor) hides(field string) bool {
+	if red == nil {
+		return false
+	}
+	segments := strings.Split(field, ".")
+	for _, path := range red.paths {
+		if len(path) > len(segments) {
+			continue
+		}
+		covered := true
+		for i, segment := range path {
+			if segment != "*" && segment != segments[i] {
+				covered = false
+				break
+			}
+		}
+		if covered {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchResult is one item of GET /resources/search.
+type SearchResult struct {
+	ID         string        `json:"id"`
+	Name       string        `json:"name"`
+	Namespace  string        `json:"namespace,omitempty"`
+	Type       string        `json:"type"`
+	VendorType string        `json:"vendor_type"`
+	Phase      string        `json:"phase"`
+	Matches    []searchField `json:"matches"`
+}
+
+// HandleSearchResources handles GET /resources/search?q=
+func (c *Controller) HandleSearchResources(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Parse the query, filters and page
+	query := r.URL.Query()
+	words := strings.Fields(strings.ToLower(query.Get("q")))
+	filter, err := parseWatchFilter(r)
+	var limit, offset int
+	if err == nil {
+		limit, offset, err = parsePageParams(query)
+	}
+	if err == nil && len(words) == 0 {
+		err = fmt.Errorf("q is required")
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	redact := c.redactionFor(r)
+
+	// Step 2: Intersect the candidates for each word, then check which
+	// fields matched (a word may only match a field the caller can't see)
+	results := []SearchResult{}
+	c.mu.RLock()
+	var ids map[string]bool
+	for _, word := range words {
+		found := c.search.candidates(word)
+		if ids != nil {
+			for id := range ids {
+				if !found[id] {
+					delete(ids, id)
+				}
+			}
+		} else {
+			ids = found
+		}
+	}
+	for id := range ids {
+		res, exists := c.ResourceDB[id]
+		if !exists || !filter.matches(res) {
+			continue
+		}
+		var matches []searchField
+		matchedAll := true
+		for _, word := range words {
+			matched := false
+			for _, field := range c.search.docs[id] {
+				if !redact.hides(field.Field) && fieldMatches(word, field.Value) {
+					matched = true
+					matches = appendMatch(matches, field)
+				}
+			}
+			matchedAll = matchedAll && matched
+		}
+		if !matchedAll {
+			continue
+		}
+		results = append(results, SearchResult{
+			ID:         res.ID,
+			Name:       res.Name,
+			Namespace:  res.Namespace,
+			Type:       res.Type,
+			VendorType: res.Spec.VendorType,
+			Phase:      res.Status.Phase,
+			Matches:    matches,
+		})
+	}
+	c.mu.RUnlock()
+
+	// Step 3: Exact matches first, then by name
+	sort.Slice(results, func(i, j int) bool {
+		ei, ej := exactMatch(results[i], words), exactMatch(results[j], words)
+		if ei != ej {
+			return ei
+		}
+		if results[i].Name != results[j].Name {
+			return results[i].Name < results[j].Name
+		}
+		return results[i].ID < results[j].ID
+	})
+	total := len(results)
+	if offset > len(results) {
+		offset = len(results)
+	}
+	results = results[offset:]
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	redact.announce(w)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": results, "total": total})
+}
+
+// appendMatch adds field to matches once.
+func appendMatch(matches []searchField, field searchField) []searchField {
+	for _, m := range matches {
+		if m == field {
+			return matches
+		}
+	}
+	return append(matches, field)
+}
+
+// exactMatch reports whether some matched value equals one of the words.
+func exactMatch(result SearchResult, words []string) bool {
+	for _, m := range result.Matches {
+		for _, word := range words {
+			if strings.ToLower(m.Value) == word {
+				return true
+			}
+		}
+	}
+	return false
+}
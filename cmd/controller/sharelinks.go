@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// SHARE LINKS
+// =============================================================================
+// A freelancer or a vendor's field technician checking a camera during an
+// event shouldn't need an API key for it. An operator mints a link:
+//
+//   POST   /resources/{id}:share          {"ttl": "8h", "label": "Dave, truck 2"}
+//          → 201 {"id": "shr-42", "url": ".../v1/share/<token>", "expires_at": ...}
+//   GET    /resources/{id}/shares         the links that are still valid
+//   DELETE /resources/{id}/shares/{sid}   revoke one
+//
+// Anyone holding the URL can GET it, without an API key, until it expires
+// (default 8h, at most SHARE_LINK_MAX_TTL, default 24h) or is revoked. It
+// shows the resource's name, type, phase, health, conditions and metrics,
+// read fresh from the vendor when it answers within shareReadTimeout. It
+// never shows the spec, endpoints, labels or annotations, which may hold
+// addresses and stream keys.
+//
+// The token is signed (HMAC-SHA256 with SHARE_LINK_SIGNING_KEY), so it
+// can't be forged or stretched. Without a key, a random one is made at
+// startup. Links are kept in memory like resources: a restart ends them.
+// A bad token is 404; an expired or revoked one is 410. Minting and
+// revoking are recorded as events.
+// =============================================================================
+
+// Share link lifetimes.
+const (
+	defaultShareTTL  = 8 * time.Hour
+	shareReadTimeout = 2 * time.Second
+)
+
+// shareSigningKey returns SHARE_LINK_SIGNING_KEY, or a random key.
+func shareSigningKey() []byte {
+	if key := os.Getenv("SHARE_LINK_SIGNING_KEY"); key != "" {
+		return []byte(key)
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("share links: no randomness for a signing key: %v", err))
+	}
+	return key
+}
+
+// shareToken signs link as "<payload>.<signature>", both base64url. The
+// payload is "<resource ID>|<link ID>|<expiry in unix seconds>".
+func (c *Controller) shareToken(link *models.ShareLink) string {
+	payload := fmt.Sprintf("%s|%s|%d", link.ResourceID, link.ID, link.ExpiresAt.Unix())
+	mac := hmac.New(sha256.New, c.ShareSigningKey)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// parseShareToken checks token's signature and returns what it names.
+func (c *Controller) parseShareToken(token string) (resourceID, linkID string, expiresAt time.Time, ok bool) {
+	encoded, signature, found := strings.Cut(token, ".")
+	if !found {
+		return "", "", time.Time{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", "", time.Time{}, false
+	}
+	sum, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return "", "", time.Time{}, false
+	}
+	mac := hmac.New(sha256.New, c.ShareSigningKey)
+	mac.Write(payload)
+	if !hmac.Equal(sum, mac.Sum(nil)) {
+		return "", "", time.Time{}, false
+	}
+	parts := strings.Split(string(payload), "|")
+	if len(parts) != 3 {
+		return "", "", time.Time{}, false
+	}
+	expiry, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return "", "", time.Time{}, false
+	}
+	return parts[0], parts[1], time.Unix(expiry, 0), true
+}
+
+// pruneSharesLocked forgets expired links and links to deleted resources.
+// Caller must hold c.mu.
+func (c *Controller) pruneSharesLocked() {
+	now := c.Clock.Now()
+	for id, link := range c.shares {
+		if _, exists := c.ResourceDB[link.ResourceID]; !exists || !now.Before(link.ExpiresAt) {
+			delete(c.shares, id)
+		}
+	}
+}
+
+// ShareRequest is the (optional) body of POST /resources/{id}:share.
+type ShareRequest struct {
+	// TTL is a Go duration like "8h" (default 8h)
+	TTL   string `json:"ttl,omitempty"`
+	Label string `json:"label,omitempty"`
+}
+
+// HandleShareResource handles POST /resources/{id}:share
+func (c *Controller) HandleShareResource(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	principal, _ := principalFrom(r.Context())
+
+	// Step 1: Parse the lifetime
+	var req ShareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+	// WHY min: A SHARE_LINK_MAX_TTL below the default caps the default too
+	ttl := min(defaultShareTTL, c.MaxShareTTL)
+	if req.TTL != "" {
+		d, err := time.ParseDuration(req.TTL)
+		if err != nil || d <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "ttl must be a positive Go duration like \"8h\""})
+			return
+		}
+		ttl = d
+	}
+	if ttl > c.MaxShareTTL {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "ttl may not exceed " + c.MaxShareTTL.String()})
+		return
+	}
+
+	// Step 2: Remember the link
+	c.mu.Lock()
+	res, exists := c.ResourceDB[id]
+	if !exists {
+		c.mu.Unlock()
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Resource not found"})
+		return
+	}
+	c.pruneSharesLocked()
+	now := c.Clock.Now()
+	link := &models.ShareLink{
+		ID:         c.IDs.NewID("shr"),
+		ResourceID: id,
+		Label:      req.Label,
+		CreatedBy:  principal.Name,
+		CreatedAt:  now,
+		// WHY WHOLE SECONDS: The token carries the expiry in unix seconds
+		ExpiresAt: now.Add(ttl).Truncate(time.Second),
+	}
+	c.shares[link.ID] = link
+	message := fmt.Sprintf("Share link %s created by %s, valid for %s", link.ID, principal.Name, ttl)
+	if link.Label != "" {
+		message += ": " + link.Label
+	}
+	c.recordEvent(res, models.EventNormal, models.ReasonShared, message, "", "")
+	snapshot := *link
+	c.mu.Unlock()
+	logger.Infof("%s: %s", id, message)
+
+	// Step 3: Hand out the URL (only now; it isn't listed later)
+	snapshot.URL = c.externalURL(r, versionedPath("/share/"+c.shareToken(&snapshot)))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(snapshot)
+}
+
+// HandleListShares handles GET /resources/{id}/shares
+// Lists the resource's valid links, oldest first, without their URLs.
+func (c *Controller) HandleListShares(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	c.mu.Lock()
+	if _, exists := c.ResourceDB[id]; !exists {
+		c.mu.Unlock()
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Resource not found"})
+		return
+	}
+	c.pruneSharesLocked()
+	items := make([]models.ShareLink, 0)
+	for _, link := range c.shares {
+		if link.ResourceID == id {
+			items = append(items, *link)
+		}
+	}
+	c.mu.Unlock()
+
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].CreatedAt.Equal(items[j].CreatedAt) {
+			return items[i].CreatedAt.Before(items[j].CreatedAt)
+		}
+		return items[i].ID < items[j].ID
+	})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+}
+
+// HandleRevokeShare handles DELETE /resources/{id}/shares/{sid}
+func (c *Controller) HandleRevokeShare(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, sid := vars["id"], vars["sid"]
+	principal, _ := principalFrom(r.Context())
+
+	c.mu.Lock()
+	res, exists := c.ResourceDB[id]
+	link, shared := c.shares[sid]
+	if !exists || !shared || link.ResourceID != id {
+		c.mu.Unlock()
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "share link not found"})
+		return
+	}
+	delete(c.shares, sid)
+	message := fmt.Sprintf("Share link %s revoked by %s", sid, principal.Name)
+	c.recordEvent(res, models.EventNormal, models.ReasonShareRevoked, message, "", "")
+	c.mu.Unlock()
+	logger.Infof("%s: %s", id, message)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SharedStatus is what a share link shows: no spec, endpoints, labels or
+// annotations.
+type SharedStatus struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Namespace string `json:"namespace,omitempty"`
+
+	Phase           string             `json:"phase"`
+	Message         string             `json:"message,omitempty"`
+	HealthStatus    string             `json:"health_status,omitempty"`
+	LastHealthCheck time.Time          `json:"last_health_check,omitempty"`
+	Conditions      []models.Condition `json:"conditions,omitempty"`
+	Metrics         map[string]float64 `json:"metrics,omitempty"`
+	CurrentBitrate  int64              `json:"current_bitrate,omitempty"`
+	DroppedFrames   int64              `json:"dropped_frames,omitempty"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// ExpiresAt is when the link stops working
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// HandleGetShared handles GET /share/{token}
+// Needs no API key: the signed token is the credential.
+func (c *Controller) HandleGetShared(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	// WHY NO-STORE: The status is live and the URL is a credential
+	w.Header().Set("Cache-Control", "no-store")
+
+	// Step 1: The token must be ours
+	// WHY 404 (not 401): Don't confirm to a guesser that tokens exist
+	resourceID, linkID, expiresAt, ok := c.parseShareToken(mux.Vars(r)["token"])
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "share link not found"})
+		return
+	}
+	if !c.Clock.Now().Before(expiresAt) {
+		w.WriteHeader(http.StatusGone)
+		json.NewEncoder(w).Encode(map[string]string{"error": "share link has expired"})
+		return
+	}
+
+	// Step 2: ... and still valid
+	c.mu.RLock()
+	link, shared := c.shares[linkID]
+	res, exists := c.ResourceDB[resourceID]
+	var phase, vendorID string
+	if exists {
+		phase, vendorID = res.Status.Phase, res.Status.VendorID
+	}
+	c.mu.RUnlock()
+	switch {
+	case !exists:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "resource not found"})
+		return
+	case !shared || link.ResourceID != resourceID:
+		w.WriteHeader(http.StatusGone)
+		json.NewEncoder(w).Encode(map[string]string{"error": "share link has been revoked"})
+		return
+	}
+
+	// Step 3: Read the status fresh if the vendor answers quickly
+	// WHY NOT WHILE TERMINATING OR QUEUED: See HandleGetResource
+	if vendorID != "" && phase != phaseTerminating && phase != phaseQueued {
+		ctx, cancel := context.WithTimeout(context.Background(), shareReadTimeout)
+		_, err := c.refreshStatus(ctx, resourceID)
+		cancel()
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			c.markDegraded(w, r, degradedVendorTimeout)
+		case err != nil && !errors.Is(err, errResourceGone):
+			logger.Warnf("Share link %s: failed to read %s from vendor: %v", linkID, resourceID, err)
+			c.markDegraded(w, r, degradedVendorError)
+		}
+	}
+
+	// Step 4: Show the status only
+	c.mu.RLock()
+	if res, exists = c.ResourceDB[resourceID]; !exists {
+		c.mu.RUnlock()
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "resource not found"})
+		return
+	}
+	view := SharedStatus{
+		ID:              res.ID,
+		Name:            res.Name,
+		Type:            res.Type,
+		Namespace:       res.Namespace,
+		Phase:           res.Status.Phase,
+		Message:         res.Status.Message,
+		HealthStatus:    res.Status.HealthStatus,
+		LastHealthCheck: res.Status.LastHealthCheck,
+		Conditions:      append([]models.Condition(nil), res.Status.Conditions...),
+		CurrentBitrate:  res.Status.CurrentBitrate,
+		DroppedFrames:   res.Status.DroppedFrames,
+		UpdatedAt:       res.UpdatedAt,
+		ExpiresAt:       link.ExpiresAt,
+	}
+	if len(res.Status.Metrics) > 0 {
+		view.Metrics = make(map[string]float64, len(res.Status.Metrics))
+		for name, value := range res.Status.Metrics {
+			view.Metrics[name] = value
+		}
+	}
+	c.mu.RUnlock()
+	json.NewEncoder(w).Encode(view)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/client"
+	"github.com/Zhichengu1/mock-control-plane/pkg/provider"
+)
+
+// =============================================================================
+// VENDOR REQUEST SIGNING
+// =============================================================================
+// Each HTTP provider picks how its requests are authenticated with
+// <VENDOR>_AUTH (see client.Signer):
+//
+//   bearer  (default) <VENDOR>_API_KEY as a Bearer token
+//   hmac    <VENDOR>_HMAC_KEY_ID + secret <VENDOR>_HMAC_SECRET
+//   sigv4   <VENDOR>_AWS_REGION, <VENDOR>_AWS_SERVICE + secrets
+//           <VENDOR>_AWS_ACCESS_KEY_ID, <VENDOR>_AWS_SECRET_ACCESS_KEY
+//           and optionally <VENDOR>_AWS_SESSION_TOKEN
+//   oauth2  <VENDOR>_OAUTH_TOKEN_URL, <VENDOR>_OAUTH_CLIENT_ID,
+//           <VENDOR>_OAUTH_SCOPES (comma-separated) + secret
+//           <VENDOR>_OAUTH_CLIENT_SECRET
+//   session <VENDOR>_SESSION_LOGIN_URL, <VENDOR>_SESSION_USERNAME + secret
+//           <VENDOR>_SESSION_PASSWORD; optionally <VENDOR>_SESSION_HEADER
+//           (default Authorization: Bearer) and <VENDOR>_SESSION_TTL when
+//           the login response has no expires_in
+//
+// oauth2 and session tokens are refreshed before they expire, and a
+// request the vendor answers with 401 is repeated once with a new token.
+//
+// Secrets are read from files in SECRETS_DIR if it is set (one file per
+// secret, named as above), otherwise from the environment. Missing
+// credentials are fatal at startup rather than a 401 on every call.
+// =============================================================================
+
+// Signing schemes accepted in <VENDOR>_AUTH.
+var signingSchemes = []string{"bearer", "hmac", "sigv4", "oauth2", "session"}
+
+// secretsFromEnv returns the configured secrets source.
+func secretsFromEnv() client.Secrets {
+	if dir := os.Getenv("SECRETS_DIR"); dir != "" {
+		return client.DirSecrets{Dir: dir}
+	}
+	return client.EnvSecrets{}
+}
+
+// vendorSigner builds the Signer selected by <prefix>_AUTH. Returns nil
+// for bearer without a <prefix>_API_KEY secret (the provider's default
+// key applies).
+func vendorSigner(prefix string, secrets client.Secrets) (client.Signer, string, error) {
+	scheme := strings.ToLower(strings.TrimSpace(os.Getenv(prefix + "_AUTH")))
+	if scheme == "" {
+		scheme = "bearer"
+	}
+
+	var missing []string
+	secret := func(name string) string {
+		value, ok := secrets.Secret(prefix + "_" + name)
+		if !ok {
+			missing = append(missing, prefix+"_"+name)
+		}
+		return value
+	}
+	setting := func(name string) string {
+		value := os.Getenv(prefix + "_" + name)
+		if value == "" {
+			missing = append(missing, prefix+"_"+name)
+		}
+		return value
+	}
+
+	var signer client.Signer
+	switch scheme {
+	case "bearer":
+		if key, ok := secrets.Secret(prefix + "_API_KEY"); ok {
+			signer = client.BearerSigner{Token: key}
+		}
+	case "hmac":
+		signer = client.HMACSigner{KeyID: setting("HMAC_KEY_ID"), Secret: []byte(secret("HMAC_SECRET"))}
+	case "sigv4":
+		sigv4 := client.SigV4Signer{
+			Region:          setting("AWS_REGION"),
+			Service:         setting("AWS_SERVICE"),
+			AccessKeyID:     secret("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: secret("AWS_SECRET_ACCESS_KEY"),
+		}
+		sigv4.SessionToken, _ = secrets.Secret(prefix + "_AWS_SESSION_TOKEN")
+		signer = sigv4
+	case "oauth2":
+		oauth := &client.OAuth2Signer{
+			TokenURL:     setting("OAUTH_TOKEN_URL"),
+			ClientID:     setting("OAUTH_CLIENT_ID"),
+			ClientSecret: secret("OAUTH_CLIENT_SECRET"),
+		}
+		for _, scope := range strings.Split(os.Getenv(prefix+"_OAUTH_SCOPES"), ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				oauth.Scopes = append(oauth.Scopes, scope)
+			}
+		}
+		signer = oauth
+	case "session":
+		session := &client.SessionSigner{
+			LoginURL: setting("SESSION_LOGIN_URL"),
+			Username: setting("SESSION_USERNAME"),
+			Password: secret("SESSION_PASSWORD"),
+			Header:   os.Getenv(prefix + "_SESSION_HEADER"),
+		}
+		if v := os.Getenv(prefix + "_SESSION_TTL"); v != "" {
+			ttl, err := time.ParseDuration(v)
+			if err != nil || ttl <= 0 {
+				return nil, "", fmt.Errorf("%s_SESSION_TTL: invalid duration %q", prefix, v)
+			}
+			session.TTL = ttl
+		}
+		signer = session
+	default:
+		return nil, "", fmt.Errorf("%s_AUTH: unknown scheme %q (want %s)", prefix, scheme, strings.Join(signingSchemes, ", "))
+	}
+	if len(missing) > 0 {
+		return nil, "", fmt.Errorf("%s_AUTH=%s needs %s", prefix, scheme, strings.Join(missing, ", "))
+	}
+	return signer, scheme, nil
+}
+
+// configureSigners sets the request signer of every HTTP provider.
+func (c *Controller) configureSigners(secrets client.Secrets) error {
+	for name, p := range c.config().Providers {
+		if err := configureSigner(name, p, secrets); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configureSigner sets the request signer of p, registered as name, if
+// it is an HTTP provider.
+func configureSigner(name string, p provider.VendorProvider, secrets client.Secrets) error {
+	sony, ok := p.(*provider.SonyProvider)
+	if !ok {
+		return nil
+	}
+	signer, scheme, err := vendorSigner(strings.ToUpper(name), secrets)
+	if err != nil {
+		return err
+	}
+	if signer != nil {
+		sony.Signer = signer
+	}
+	logger.Infof("%s: requests authenticated with %s", name, scheme)
+	return nil
+}
@@ -0,0 +1,457 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/Zhichengu1/mock-control-plane/pkg/provider"
+	"github.com/Zhichengu1/mock-control-plane/pkg/validation"
+)
+
+// =============================================================================
+// CAPACITY SIMULATION
+// =============================================================================
+// Event planners want to know whether a show plan fits before the truck
+// rolls in. POST /resources:simulate takes the same body as
+// POST /resources:batch and reports which resources would be admitted,
+// without provisioning or storing anything:
+//
+//   POST /resources:simulate
+//   {"items": [{"name": "cam1", "type": "camera", "spec": {...}}, ...]}
+//
+//   {"items": [{"index": 0, "name": "cam1", "admitted": true, "checks": [...]},
+//              {"index": 7, "name": "cam8", "admitted": false, "checks": [
+//                {"check": "vendor-capacity", "status": "fail",
+//                 "message": "sony account quota of 50 devices reached (43 in use, 7 planned)"}]}],
+//    "admitted": 7, "rejected": 1, "capacity": {...}}
+//
+// Checks, per item:
+//   spec             required fields, profiles, size and type rules
+//   config-keys      spec.config keys the vendor doesn't know (a warning
+//                    unless CONFIG_KEY_POLICY rejects them; see configkeys.go)
+//   vendor           the vendor (or a routing rule) exists
+//   dependencies     depends_on names existing resources
+//   uniqueness       name, ip_address, stream_url free in the namespace
+//   quota            MAX_RESOURCES, MAX_RESOURCES_PER_NAMESPACE
+//   ip-pool          an ip_address is free on its VLAN and on the vendor;
+//                    without one, the vendor's DHCP pool has a lease left
+//   vendor-capacity  the vendor account's device quota
+//
+// WHY IN ORDER: The plan is admitted item by item as if it were created
+// in request order; admitted items use up quota, addresses and leases for
+// the ones after them, rejected ones don't. Vendor capacity is asked once
+// per vendor (providers without it skip the vendor checks).
+//
+// The answer is a snapshot: creates running at the same time, or devices
+// added on the vendor side, can change it.
+// =============================================================================
+
+// Simulation bounds.
+// WHY LARGER THAN A BATCH: A whole show is planned at once, and nothing
+// is provisioned
+const maxSimulationItems = 1000
+
+// Simulation check outcomes.
+const (
+	checkPass    = "pass"
+	checkFail    = "fail"
+	checkSkipped = "skipped"
+
+	// checkWarn reports a finding that doesn't reject the item
+	checkWarn = "warn"
+)
+
+// SimulationCheck is the outcome of one admission check.
+type SimulationCheck struct {
+	Check   string `json:"check"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// SimulationItem is the verdict for one resource of the plan.
+type SimulationItem struct {
+	Index      int               `json:"index"`
+	Name       string            `json:"name,omitempty"`
+	Namespace  string            `json:"namespace"`
+	VendorType string            `json:"vendor_type,omitempty"`
+	Admitted   bool              `json:"admitted"`
+	Checks     []SimulationCheck `json:"checks"`
+
+	// Violations are set when the spec or ip-pool check failed
+	Violations validation.Violations `json:"violations,omitempty"`
+}
+
+// CapacityUsage is one limit: what is used now and what the admitted
+// items would add (Limit 0 = unlimited).
+type CapacityUsage struct {
+	Used    int `json:"used"`
+	Planned int `json:"planned"`
+	Limit   int `json:"limit,omitempty"`
+}
+
+// VendorUsage is a vendor account's usage with the admitted items added.
+type VendorUsage struct {
+	Devices       int                 `json:"devices"`
+	Planned       int                 `json:"planned"`
+	DeviceQuota   int                 `json:"device_quota,omitempty"`
+	AddressPool   *models.AddressPool `json:"address_pool,omitempty"`
+	PlannedLeases int                 `json:"planned_leases"`
+
+	// Error is set when the vendor's capacity couldn't be read
+	Error string `json:"error,omitempty"`
+}
+
+// SimulationReport is the response of POST /resources:simulate.
+type SimulationReport struct {
+	Items    []SimulationItem `json:"items"`
+	Admitted int              `json:"admitted"`
+	Rejected int              `json:"rejected"`
+
+	Capacity struct {
+		Resources  CapacityUsage            `json:"resources"`
+		Namespaces map[string]CapacityUsage `json:"namespaces"`
+		Vendors    map[string]*VendorUsage  `json:"vendors"`
+	} `json:"capacity"`
+}
+
+// plannedItem is a plan entry with its prepared resource.
+type plannedItem struct {
+	resource *models.ForgeResource
+	item     *SimulationItem
+	rejected bool
+}
+
+func (p *plannedItem) add(check, status, message string) {
+	p.item.Checks = append(p.item.Checks, SimulationCheck{Check: check, Status: status, Message: message})
+	if status == checkFail {
+		p.rejected = true
+	}
+}
+
+// HandleSimulateCreate handles POST /resources:simulate
+func (c *Controller) HandleSimulateCreate(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Decode the array or object form (as for :batch)
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBatchCreateBodyBytes))
+	if err != nil {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	var req BatchCreateRequest
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &req.Items)
+	} else {
+		err = json.Unmarshal(body, &req)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+	if len(req.Items) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "items is required"})
+		return
+	}
+	if len(req.Items) > maxSimulationItems {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("at most %d resources per simulation", maxSimulationItems)})
+		return
+	}
+
+	// Step 2: Per-item checks that don't depend on the rest of the plan
+	report := &SimulationReport{Items: make([]SimulationItem, len(req.Items))}
+	plan := make([]*plannedItem, len(req.Items))
+	vendors := make(map[string]bool)
+	for i := range req.Items {
+		report.Items[i] = SimulationItem{Index: i, Name: req.Items[i].Name}
+		p := &plannedItem{resource: &req.Items[i], item: &report.Items[i]}
+		c.prepareSimulated(r, p)
+		plan[i] = p
+		if !p.rejected {
+			vendors[p.resource.Spec.VendorType] = true
+		}
+	}
+
+	// Step 3: Ask each vendor in the plan for its capacity, once
+	// WHY NOT UNDER c.mu: Vendor I/O
+	report.Capacity.Vendors = make(map[string]*VendorUsage)
+	capacities := make(map[string]*models.VendorCapacity)
+	for vendor := range vendors {
+		usage := &VendorUsage{}
+		report.Capacity.Vendors[vendor] = usage
+		capacity, err := c.vendorCapacity(vendorContext(r), vendor)
+		if err != nil {
+			usage.Error = err.Error()
+			continue
+		}
+		if capacity == nil {
+			continue
+		}
+		capacities[vendor] = capacity
+		usage.Devices, usage.DeviceQuota, usage.AddressPool = capacity.Devices, capacity.DeviceQuota, capacity.AddressPool
+	}
+
+	// Step 4: Admit the plan in order against one snapshot
+	c.mu.RLock()
+	c.admitSimulated(plan, capacities, report)
+	c.mu.RUnlock()
+
+	for _, item := range report.Items {
+		if item.Admitted {
+			report.Admitted++
+		} else {
+			report.Rejected++
+		}
+	}
+	logger.Infof("Simulated %d creates: %d admitted, %d rejected", len(report.Items), report.Admitted, report.Rejected)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// prepareSimulated runs the checks createResource runs before it claims
+// anything, on p's resource.
+func (c *Controller) prepareSimulated(r *http.Request, p *plannedItem) {
+	cfg := c.config()
+	resource := p.resource
+	if ns := routeNamespace(r); ns != "" {
+		if resource.Namespace != "" && namespaceKey(resource.Namespace) != namespaceKey(ns) {
+			p.add("spec", checkFail, fmt.Sprintf("namespace %q doesn't match the route's %q", resource.Namespace, ns))
+		}
+		resource.Namespace = ns
+	}
+	p.item.Namespace = namespaceKey(resource.Namespace)
+	if p.rejected {
+		return
+	}
+	if resource.Name == "" || resource.Type == "" {
+		p.add("spec", checkFail, "name and type are required")
+		return
+	}
+	if !c.routeVendor(resource) {
+		p.add("vendor", checkFail, "vendor_type is required (no routing rule matches this resource)")
+		return
+	}
+	p.item.VendorType = resource.Spec.VendorType
+
+	spec, err := cfg.Profiles.apply(resource.Spec)
+	if err != nil {
+		p.add("spec", checkFail, err.Error())
+		return
+	}
+	resource.Spec = validation.NormalizeNetwork(spec)
+	violations := append(validation.CheckSize(resource, cfg.SpecLimits), c.Validators.Validate(resource.Type, resource.Spec)...)
+	violations = append(violations, validation.ValidateMetadata(resource.Metadata)...)
+	violations = append(violations, validation.ValidateLabels(resource.Labels)...)
+	violations = append(violations, validation.ValidateAnnotations(resource.Annotations)...)
+	violations = append(violations, validation.ValidateFinalizers(resource.Finalizers)...)
+	configRejected, configWarnings := c.checkConfigKeys(resource.Namespace, nil, resource.Spec)
+	violations = append(violations, configRejected...)
+	if len(violations) > 0 {
+		p.item.Violations = violations
+		p.add("spec", checkFail, violations.Error())
+		return
+	}
+	p.add("spec", checkPass, "")
+	if len(configWarnings) > 0 {
+		p.add("config-keys", checkWarn, configWarnings.Error())
+	}
+
+	if _, exists := cfg.Providers[resource.Spec.VendorType]; !exists {
+		p.add("vendor", checkFail, "unsupported vendor: "+resource.Spec.VendorType)
+		return
+	}
+	p.add("vendor", checkPass, resource.Spec.VendorType)
+
+	if len(resource.DependsOn) > 0 {
+		if msg := c.checkDependencies(resource); msg != "" {
+			p.add("dependencies", checkFail, msg)
+			return
+		}
+		p.add("dependencies", checkPass, "")
+	}
+	if resource.OwnerRef != "" {
+		if msg := c.checkOwner("", resource); msg != "" {
+			p.add("owner", checkFail, msg)
+			return
+		}
+		p.add("owner", checkPass, resource.OwnerRef)
+	}
+}
+
+// vendorCapacity asks vendor for its account capacity. Returns nil (and
+// no error) for providers that can't report it.
+func (c *Controller) vendorCapacity(parent context.Context, vendor string) (*models.VendorCapacity, error) {
+	reporter, ok := c.config().Providers[vendor].(provider.CapacityReporter)
+	if !ok {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(parent, 10*time.Second)
+	defer cancel()
+	release, err := c.acquireVendor(ctx, vendor)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return reporter.Capacity(ctx)
+}
+
+// admitSimulated runs the plan-wide checks in order and fills in the
+// capacity summary. Caller must hold c.mu (read lock).
+func (c *Controller) admitSimulated(plan []*plannedItem, capacities map[string]*models.VendorCapacity, report *SimulationReport) {
+	cfg := c.config()
+	// Everything managed now, and what the admitted items add to it
+	var endpoints []validation.NetworkEndpoint
+	for _, res := range c.ResourceDB {
+		if endpoint, ok := validation.EndpointOf(res.ID, res.Name, res.Spec); ok {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	vendorAddresses := make(map[string]map[string]bool)
+	for vendor, capacity := range capacities {
+		vendorAddresses[vendor] = make(map[string]bool, len(capacity.AddressesInUse))
+		for _, addr := range capacity.AddressesInUse {
+			vendorAddresses[vendor][addr] = true
+		}
+	}
+	claimed := make(map[string]string) // unique index key → plan item name
+	planned, plannedByNamespace := 0, make(map[string]int)
+	plannedByVendor, leasesByVendor := make(map[string]int), make(map[string]int)
+
+	for _, p := range plan {
+		if p.rejected {
+			continue
+		}
+		res := p.resource
+		vendor := res.Spec.VendorType
+		ns := namespaceKey(res.Namespace)
+		id := fmt.Sprintf("plan[%d]", p.item.Index)
+
+		// Uniqueness: held now, or by an earlier item of the plan
+		uniqueness := ""
+		for _, claim := range c.unique.claimsOf(res.Namespace, res.Name, res.Spec) {
+			path := claim.field
+			if field, ok := uniqueFields[claim.field]; ok {
+				path = field.path
+			}
+			if owner, taken := c.unique.owners[claim.key]; taken {
+				holder := owner
+				if held, exists := c.ResourceDB[owner]; exists {
+					holder += " (" + held.Name + ")"
+				}
+				uniqueness = fmt.Sprintf("%s %q is already used in namespace %q by %s", path, claim.value, ns, holder)
+				break
+			}
+			if other, taken := claimed[claim.key]; taken {
+				uniqueness = fmt.Sprintf("%s %q is also used by %s earlier in the plan", path, claim.value, other)
+				break
+			}
+		}
+		if uniqueness != "" {
+			p.add("uniqueness", checkFail, uniqueness)
+		} else {
+			p.add("uniqueness", checkPass, "")
+		}
+
+		// Quota
+		if capErr := c.checkPlannedCapacityLocked(res.Namespace, planned, plannedByNamespace[ns]); capErr != nil {
+			p.add("quota", checkFail, capErr.Message)
+		} else {
+			p.add("quota", checkPass, "")
+		}
+
+		// IP pool: a fixed address must be free, else the vendor leases one
+		capacity := capacities[vendor]
+		endpoint, fixed := validation.EndpointOf(id, res.Name, res.Spec)
+		leases := 0
+		switch {
+		case fixed:
+			if violations := validation.CheckNetworkOverlap(endpoint, endpoints); len(violations) > 0 {
+				p.item.Violations = append(p.item.Violations, violations...)
+				messages := make([]string, len(violations))
+				for i, v := range violations {
+					messages[i] = v.Message
+				}
+				p.add("ip-pool", checkFail, strings.Join(messages, "; "))
+			} else if addr := endpoint.IP.String(); vendorAddresses[vendor][addr] {
+				p.add("ip-pool", checkFail, fmt.Sprintf("%s is already leased or configured on a %s device", addr, vendor))
+			} else {
+				p.add("ip-pool", checkPass, addr+" is free")
+			}
+		case capacity == nil || capacity.AddressPool == nil:
+			p.add("ip-pool", checkSkipped, vendor+" doesn't report its address pool")
+		default:
+			pool := capacity.AddressPool
+			if free := pool.Free() - leasesByVendor[vendor]; free <= 0 {
+				p.add("ip-pool", checkFail, fmt.Sprintf("%s DHCP pool %s is exhausted (%d leased, %d planned)", vendor, pool.Range, pool.Leased, leasesByVendor[vendor]))
+			} else {
+				p.add("ip-pool", checkPass, fmt.Sprintf("%d of %d leases free in %s", free, pool.Size, pool.Range))
+				leases = 1
+			}
+		}
+
+		// Vendor capacity
+		switch {
+		case capacity == nil:
+			message := vendor + " doesn't report its capacity"
+			if usage := report.Capacity.Vendors[vendor]; usage != nil && usage.Error != "" {
+				message = usage.Error
+			}
+			p.add("vendor-capacity", checkSkipped, message)
+		case capacity.DeviceQuota > 0 && capacity.Devices+plannedByVendor[vendor] >= capacity.DeviceQuota:
+			p.add("vendor-capacity", checkFail, fmt.Sprintf("%s account quota of %d devices reached (%d in use, %d planned)",
+				vendor, capacity.DeviceQuota, capacity.Devices, plannedByVendor[vendor]))
+		default:
+			p.add("vendor-capacity", checkPass, "")
+		}
+
+		if p.rejected {
+			continue
+		}
+		// Admitted: it uses up room for the items after it
+		p.item.Admitted = true
+		planned++
+		plannedByNamespace[ns]++
+		plannedByVendor[vendor]++
+		leasesByVendor[vendor] += leases
+		for _, claim := range c.unique.claimsOf(res.Namespace, res.Name, res.Spec) {
+			claimed[claim.key] = id + " (" + res.Name + ")"
+		}
+		if fixed {
+			endpoint.Name = res.Name
+			endpoints = append(endpoints, endpoint)
+			if vendorAddresses[vendor] != nil {
+				vendorAddresses[vendor][endpoint.IP.String()] = true
+			}
+		}
+	}
+
+	// Capacity summary
+	report.Capacity.Resources = CapacityUsage{Used: len(c.ResourceDB) + c.pendingTotal, Planned: planned, Limit: cfg.MaxResources}
+	report.Capacity.Namespaces = make(map[string]CapacityUsage)
+	for _, p := range plan {
+		ns := p.item.Namespace
+		if _, done := report.Capacity.Namespaces[ns]; done {
+			continue
+		}
+		used := c.pendingByNamespace[ns]
+		for _, res := range c.ResourceDB {
+			if namespaceKey(res.Namespace) == ns {
+				used++
+			}
+		}
+		report.Capacity.Namespaces[ns] = CapacityUsage{Used: used, Planned: plannedByNamespace[ns], Limit: cfg.MaxResourcesPerNamespace}
+	}
+	for vendor, usage := range report.Capacity.Vendors {
+		usage.Planned = plannedByVendor[vendor]
+		usage.PlannedLeases = leasesByVendor[vendor]
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/clock"
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// SOFT DELETE (DELETE_GRACE_PERIOD, POST /resources/{id}/restore)
+// =============================================================================
+// A DELETE aimed at the wrong camera takes it off air; by the time anyone
+// notices, the vendor has torn the device down. With DELETE_GRACE_PERIOD
+// set (e.g. "10m"), DELETE /resources/{id} only schedules the deletion:
+//
+//   1. the resource turns Terminating with status.delete_at set, and drops
+//      out of GET /resources (?show_deleted=true lists it); it answers 202
+//      with the delete task, as before
+//   2. until delete_at, POST /resources/{id}/restore cancels it: the
+//      status goes back to what it was and the task ends "cancelled"
+//      (so does a deletion still waiting for finalizers, see finalizers.go)
+//   3. at delete_at the vendor delete runs as described in deletion.go
+//
+// Nothing reaches the vendor during the grace period, and the resource
+// keeps its name and unique values so a restore can't collide. Resources
+// without a vendor device are deleted right away. Batch deletes (POST
+// /resources:batchDelete, DELETE /resources) schedule every resource the
+// same way, devices or not: a wrong filter is the fat finger this is for.
+// A scheduled resource whose dependents are still being deleted when its
+// period is over waits for them (see batch.go).
+//
+// WHY SURVIVE RESTARTS: With the event log (see store.go) the resource
+// comes back Terminating with delete_at set; the deletion is scheduled
+// again, so a restart neither loses it nor strands the resource.
+// =============================================================================
+
+// scheduledDeletion is a deletion waiting out its grace period (or for
+// finalizers, see finalizers.go).
+type scheduledDeletion struct {
+	taskID string
+
+	// previous is the status a restore puts back
+	previous models.ResourceStatus
+
+	// ctx carries the request ID and principal of the DELETE
+	ctx   context.Context
+	timer clock.Timer
+}
+
+// scheduleDeletionLocked marks stored Terminating and schedules its vendor
+// delete at deleteAt. Must be called with c.mu held.
+func (c *Controller) scheduleDeletionLocked(ctx context.Context, stored *models.ForgeResource, requestedBy string, previous models.ResourceStatus, deleteAt time.Time) *models.Task {
+	when := deleteAt.UTC().Format(time.RFC3339)
+	task := c.startTaskLocked(models.TaskDelete, stored, requestedBy, "Deletion scheduled for "+when+"; POST /resources/"+stored.ID+"/restore cancels it")
+	stored.Status.Phase = phaseTerminating
+	stored.Status.Message = fmt.Sprintf("Scheduled for deletion at %s (task %s); restore to cancel", when, task.ID)
+	stored.Status.DeleteAt = &deleteAt
+	stored.UpdatedAt = c.Clock.Now()
+	c.recordRevision(stored, "delete-scheduled", false)
+	message := fmt.Sprintf("Deletion of %s device %s scheduled for %s (task %s)", stored.Spec.VendorType, stored.Status.VendorID, when, task.ID)
+	if requestedBy != "" {
+		message += " by " + requestedBy
+	}
+	c.recordEvent(stored, models.EventNormal, models.ReasonDeleteScheduled, message, phaseTerminating, "")
+	c.armDeletionLocked(ctx, stored.ID, task.ID, previous, deleteAt)
+	return task
+}
+
+// armDeletionLocked starts the grace period timer of a scheduled deletion.
+// Must be called with c.mu held.
+func (c *Controller) armDeletionLocked(ctx context.Context, id, taskID string, previous models.ResourceStatus, deleteAt time.Time) {
+	wait := deleteAt.Sub(c.Clock.Now())
+	if wait < 0 {
+		wait = 0
+	}
+	c.scheduledDeletions[id] = &scheduledDeletion{
+		taskID:   taskID,
+		previous: previous,
+		ctx:      ctx,
+		timer:    c.Clock.AfterFunc(wait, func() { c.runScheduledDeletion(id, taskID) }),
+	}
+}
+
+// runScheduledDeletion starts the vendor delete once the grace period is
+// over, unless the deletion was restored in the meantime.
+func (c *Controller) runScheduledDeletion(id, taskID string) {
+	c.mu.Lock()
+	scheduled, ok := c.scheduledDeletions[id]
+	if !ok || scheduled.taskID != taskID {
+		c.mu.Unlock()
+		return
+	}
+	stored, exists := c.ResourceDB[id]
+	if !exists {
+		// Deleted meanwhile by someone else
+		delete(c.scheduledDeletions, id)
+		c.finishTaskLocked(taskID, nil, "Deleted")
+		c.mu.Unlock()
+		return
+	}
+
+	// WHY WAIT FOR DEPENDENTS: A batch delete schedules them together with
+	// what they depend on; their vendor deletes go first, as in its waves
+	dependents := c.dependentsLocked(id)
+	for _, dependent := range dependents {
+		if c.ResourceDB[dependent].Status.Phase != phaseTerminating {
+			// Restored, failed or never deleted: this one stays too
+			delete(c.scheduledDeletions, id)
+			c.mu.Unlock()
+			c.failDeletion(taskID, id, fmt.Errorf("still required by %s", dependent), &scheduled.previous)
+			return
+		}
+	}
+	if len(dependents) > 0 {
+		c.armDeletionLocked(scheduled.ctx, id, taskID, scheduled.previous, c.Clock.Now().Add(c.Deletion.PollInterval))
+		c.mu.Unlock()
+		return
+	}
+	delete(c.scheduledDeletions, id)
+	stored.Status.DeleteAt = nil
+	stored.Status.Message = "Deleting from vendor (task " + taskID + ")"
+	stored.UpdatedAt = c.Clock.Now()
+	owned := c.cascadeLocked(stored, taskID)
+	finalizing := c.awaitFinalizersLocked(scheduled.ctx, stored, taskID, scheduled.previous)
+	c.recordRevision(stored, "terminating", false)
+	if finalizing {
+		c.mu.Unlock()
+		c.deleteOwned(scheduled.ctx, id, owned, "")
+		return
+	}
+	c.recordEvent(stored, models.EventNormal, models.ReasonDeleting,
+		fmt.Sprintf("Grace period over; deleting %s device %s (task %s)", stored.Spec.VendorType, stored.Status.VendorID, taskID),
+		phaseTerminating, "")
+	res := stored.DeepCopy()
+	c.mu.Unlock()
+
+	c.updateTask(taskID, 0, "Waiting for the vendor to accept the delete")
+	c.runDeletion(scheduled.ctx, taskID, res, scheduled.previous)
+}
+
+// resumeScheduledDeletions schedules again the deletions of resources the
+// store brought back in their grace period, and parks again those waiting
+// for finalizers (their vendor delete can't have started: finalizers
+// can't be added while Terminating). It returns how many.
+func (c *Controller) resumeScheduledDeletions() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	resumed := 0
+	for _, id := range sortedKeys(c.ResourceDB) {
+		res := c.ResourceDB[id]
+		switch {
+		case res.Status.Phase != phaseTerminating:
+			continue
+		case res.Status.DeleteAt != nil:
+			deleteAt := *res.Status.DeleteAt
+			task := c.startTaskLocked(models.TaskDelete, res, "", "Deletion scheduled for "+deleteAt.UTC().Format(time.RFC3339)+" (resumed after restart)")
+			c.armDeletionLocked(context.Background(), id, task.ID, c.statusBeforeDeletionLocked(res), deleteAt)
+		case len(res.Finalizers) > 0:
+			task := c.startTaskLocked(models.TaskDelete, res, "", "Waiting for finalizers (resumed after restart)")
+			c.finalizing[id] = &scheduledDeletion{taskID: task.ID, previous: c.statusBeforeDeletionLocked(res), ctx: context.Background()}
+			c.noteFinalizersLocked(res, task.ID)
+			// WHY: The last owned resource may be gone already
+			c.withTxLocked(func(tx *storeTx) error {
+				c.settleCascade(tx, id)
+				return nil
+			})
+		default:
+			continue
+		}
+		resumed++
+	}
+	return resumed
+}
+
+// statusBeforeDeletionLocked finds res's status from before its deletion
+// was scheduled, in its revision history. If compaction dropped it, the
+// status is Unknown until the reconciler refreshes it. Must be called
+// with c.mu held.
+func (c *Controller) statusBeforeDeletionLocked(res *models.ForgeResource) models.ResourceStatus {
+	revisions := c.History[res.ID]
+	for i := len(revisions) - 1; i >= 0; i-- {
+		if status := revisions[i].Resource.Status; status.Phase != phaseTerminating {
+			return status
+		}
+	}
+	status := res.Status
+	status.Phase = "Unknown"
+	status.Message = "Restored; waiting for a status refresh from the vendor"
+	status.DeleteAt = nil
+	return status
+}
+
+// HandleRestoreResource handles POST /resources/{id}/restore
+func (c *Controller) HandleRestoreResource(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if c.rejectIfLocked(w, r, id) || c.rejectIfPreconditionFailed(w, r, id) {
+		return
+	}
+	restoredBy := "anonymous"
+	if principal, ok := principalFrom(r.Context()); ok && principal.Name != "" {
+		restoredBy = principal.Name
+	}
+
+	c.mu.Lock()
+	stored, exists := c.ResourceDB[id]
+	if !exists {
+		c.mu.Unlock()
+		writeOperationError(w, errResourceNotFound)
+		return
+	}
+	scheduled, ok := c.scheduledDeletions[id]
+	if ok {
+		// WHY STOP UNDER THE LOCK: A timer firing now finds the deletion
+		// gone (runScheduledDeletion checks under the same lock)
+		scheduled.timer.Stop()
+		delete(c.scheduledDeletions, id)
+	} else if scheduled, ok = c.finalizing[id]; ok {
+		delete(c.finalizing, id)
+		// WHY: Nothing waits for the owned resources any more; those
+		// already deleted stay deleted
+		stored.Finalizers = withoutString(stored.Finalizers, cascadeFinalizer)
+	} else {
+		message := "resource is not scheduled for deletion"
+		if stored.Status.Phase == phaseTerminating {
+			message = "the vendor delete has already started; the resource can't be restored"
+		}
+		c.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": message})
+		return
+	}
+	stored.Status = scheduled.previous
+	stored.UpdatedAt = c.Clock.Now()
+	c.recordRevision(stored, "restored", false)
+	c.recordEvent(stored, models.EventNormal, models.ReasonRestored,
+		fmt.Sprintf("Deletion cancelled by %s (task %s)", restoredBy, scheduled.taskID),
+		stored.Status.Phase, stored.Status.HealthStatus)
+	c.cancelTaskLocked(scheduled.taskID, "Restored by "+restoredBy)
+	res := stored.DeepCopy()
+	c.mu.Unlock()
+	logger.Infof("%s: deletion cancelled by %s (task %s)", id, restoredBy, scheduled.taskID)
+
+	redact := c.redactionFor(r)
+	redact.announce(w)
+	redact.resource(res)
+	w.Header().Set("Content-Type", "application/json")
+	setResourceETag(w, res)
+	json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,523 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/eventstore"
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// STORE BACKENDS
+// =============================================================================
+// STORE_BACKEND selects where resources live:
+//
+//   memory     (default) the in-memory map only; a restart starts empty
+//   eventlog   event-sourced: every change is also appended to
+//              STORE_EVENT_LOG (see pkg/eventstore), and the in-memory
+//              state is rebuilt from it on start
+//
+// With the event log the resources are a projection of the events.
+// recordRevision, which every change to a resource goes through, appends
+// the change as one event numbered like its watch event (a transaction's
+// changes together; see tx.go), so:
+//
+//   - a restart replays the log: resources, revision history (up to
+//     HISTORY_MAX_REVISIONS), name uniqueness, the search index and the
+//     watch buffer come back as of the last event in the file
+//   - a watch can resume from any event in the log, not only the buffered
+//     ones (Last-Event-ID keeps working across restarts)
+//   - ?asOf= falls back to the log when history was capped or compacted
+//   - GET /admin/store/state?asOf= rebuilds the whole store as it was
+//   - GET /admin/store/events is the raw change feed, for audit
+//
+// Compaction trims the in-memory history only; the log is never rewritten.
+// The admin reads (events, state, ?asOf=, rollback) use the log's index
+// and read only the events they return.
+//
+// DURABILITY: recordRevision runs under c.mu, so it only writes the event;
+// the fsync happens outside the lock. StoreMiddleware syncs the log before
+// a write request's response starts, so a change the API confirmed is on
+// disk (concurrent requests share one fsync). Changes nobody is waiting
+// on (reconciler, deletion workers, schedules) are synced every
+// STORE_SYNC_INTERVAL (default 1s); a crash can lose up to that much of
+// them, and a restart then replays the log as it was last synced.
+//
+// READ-ONLY: If an append or a sync fails, the log no longer matches
+// memory, and logging later changes on top would make a restart replay a
+// state that never existed. The store turns read-only instead: nothing
+// more is appended, write requests (other than /admin) are refused with
+// 503, and /health and GET /admin/store say why. Memory still has the
+// change that failed; a restart after fixing the disk goes back to the
+// log. Background work keeps running in memory only.
+//
+// WHAT A RESTART DOESN'T RESUME: Work in flight (deletions, queued vendor
+// calls, rollouts) isn't in the log; such resources come back in the
+// phase they were in and the reconciler picks them up. Deletions still in
+// their grace period are scheduled again (see softdelete.go).
+// =============================================================================
+
+// Store backends.
+const (
+	storeMemory   = "memory"
+	storeEventLog = "eventlog"
+)
+
+// openStore sets up the backend. It returns how many events were
+// replayed.
+func (c *Controller) openStore(backend, path string) (int, error) {
+	switch backend {
+	case "", storeMemory:
+		c.StoreBackend = storeMemory
+		return 0, nil
+	case storeEventLog:
+		if path == "" {
+			return 0, fmt.Errorf("STORE_EVENT_LOG is required with STORE_BACKEND=%s", storeEventLog)
+		}
+		log, err := eventstore.Open(path)
+		if err != nil {
+			return 0, err
+		}
+		if stats := log.Stats(); stats.Dropped > 0 {
+			logger.Warnf("Event log %s ended in an unfinished event; dropped it", path)
+		}
+		if stats := log.Stats(); stats.DroppedTx > 0 {
+			logger.Warnf("Event log %s ended in an unfinished transaction; dropped its %d events", path, stats.DroppedTx)
+		}
+		c.StoreBackend, c.eventLog = storeEventLog, log
+		return c.restoreFromEventLog()
+	default:
+		return 0, fmt.Errorf("unknown STORE_BACKEND %q (want %s or %s)", backend, storeMemory, storeEventLog)
+	}
+}
+
+// restoreFromEventLog rebuilds the store from the event log.
+func (c *Controller) restoreFromEventLog() (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var buffered []watchEntry
+	var lastSeq int64
+	replayed := 0
+	err := c.eventLog.Replay(func(e eventstore.Event) error {
+		res := e.Resource.DeepCopy()
+		var prev *models.ForgeResource
+		if stored, exists := c.ResourceDB[res.ID]; exists {
+			prev = stored.DeepCopy()
+		}
+
+		revisions := append(c.History[res.ID], e.ResourceRevision)
+		if c.MaxRevisions > 0 && len(revisions) > c.MaxRevisions {
+			revisions = revisions[len(revisions)-c.MaxRevisions:]
+		}
+		c.History[res.ID] = revisions
+		if e.Deleted {
+			delete(c.ResourceDB, res.ID)
+			c.releaseUniqueLocked(res.ID)
+		} else {
+			c.ResourceDB[res.ID] = res
+			c.resetUniqueLocked(res.ID, res.Namespace, res.Name, res.Spec)
+		}
+		c.search.update(res, e.Deleted)
+
+		buffered = append(buffered, watchEntry{event: watchEventFrom(e), prev: prev})
+		if len(buffered) > c.watch.size {
+			buffered = buffered[len(buffered)-c.watch.size:]
+		}
+		lastSeq = e.Seq
+		replayed++
+		return nil
+	})
+	if err != nil {
+		return replayed, err
+	}
+	c.watch.restore(lastSeq, buffered)
+	return replayed, nil
+}
+
+// watchEventFrom renders a stored event as the watch event it was.
+func watchEventFrom(e eventstore.Event) models.WatchEvent {
+	res := e.Resource
+	return models.WatchEvent{
+		Seq:       e.Seq,
+		Type:      e.Type,
+		Reason:    e.Reason,
+		Revision:  e.Revision,
+		Timestamp: e.Timestamp,
+		Resource:  &res,
+	}
+}
+
+// appendStoreEvent appends a published change to the event log, if there
+// is one and it is still writable. Caller must hold c.mu (write lock).
+// WHY NOT FAIL THE CHANGE: The vendor has already done it; the in-memory
+// state stays right and the store turns read-only (see above)
+func (c *Controller) appendStoreEvent(event models.WatchEvent, revision models.ResourceRevision) {
+	if c.eventLog == nil || c.storeFailed.Load() != nil {
+		return
+	}
+	// WHY: A transaction appends its events together (see tx.go)
+	if c.txEvents != nil {
+		c.txEvents = append(c.txEvents, eventstore.Event{Seq: event.Seq, Type: event.Type, ResourceRevision: revision})
+		return
+	}
+	err := c.eventLog.Append(eventstore.Event{Seq: event.Seq, Type: event.Type, ResourceRevision: revision})
+	if err != nil {
+		c.failStore(fmt.Errorf("appending event %d (%s %s): %w", event.Seq, revision.Reason, revision.Resource.ID, err))
+	}
+}
+
+// storeFailure is why the store turned read-only.
+type storeFailure struct {
+	Error string    `json:"error"`
+	At    time.Time `json:"at"`
+
+	// LastSeq is the last event in the log; later changes are in memory only
+	LastSeq int64 `json:"last_logged_seq"`
+}
+
+// failStore turns the store read-only (the first failure is kept).
+func (c *Controller) failStore(err error) {
+	failure := &storeFailure{Error: err.Error(), At: c.Clock.Now(), LastSeq: c.eventLog.Stats().LastSeq}
+	if c.storeFailed.CompareAndSwap(nil, failure) {
+		logger.Errorf("Event log: %v; the store is read-only until a restart", err)
+	}
+}
+
+// syncStore makes the logged changes durable, turning the store
+// read-only if that fails.
+func (c *Controller) syncStore() {
+	if c.eventLog == nil || c.storeFailed.Load() != nil {
+		return
+	}
+	if err := c.eventLog.Sync(); err != nil {
+		c.failStore(fmt.Errorf("syncing: %w", err))
+	}
+}
+
+// startStoreSync syncs changes made in the background every interval.
+func (c *Controller) startStoreSync(ctx context.Context, interval time.Duration) {
+	if c.eventLog == nil || interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.syncStore()
+			}
+		}
+	}()
+}
+
+// storeSyncWriter syncs the event log before a response starts.
+type storeSyncWriter struct {
+	http.ResponseWriter
+	c      *Controller
+	synced bool
+}
+
+func (s *storeSyncWriter) sync() {
+	if !s.synced {
+		s.synced = true
+		s.c.syncStore()
+	}
+}
+
+func (s *storeSyncWriter) WriteHeader(code int) {
+	s.sync()
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *storeSyncWriter) Write(p []byte) (int, error) {
+	s.sync()
+	return s.ResponseWriter.Write(p)
+}
+
+// StoreMiddleware refuses writes while the store is read-only, and syncs
+// the event log before answering a write.
+func (c *Controller) StoreMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c.eventLog == nil || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		// WHY NOT /admin: Reloads, log levels and store checks don't
+		// change resources, and are how an operator looks into it
+		if failure := c.storeFailed.Load(); failure != nil && !strings.Contains(r.URL.Path, "/admin/") {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(map[string]string{"error": "the store is read-only since the event log failed (" + failure.Error + "); restart once it is fixed"})
+			return
+		}
+		sw := &storeSyncWriter{ResponseWriter: w, c: c}
+		next.ServeHTTP(sw, r)
+		// A handler that wrote nothing still gets the 200 after this
+		sw.sync()
+	})
+}
+
+// storeEventsAfter reads the logged events after since as watch entries,
+// each with the resource as it was before. It returns the last sequence
+// number read.
+func (c *Controller) storeEventsAfter(since int64) ([]watchEntry, int64, error) {
+	last := make(map[string]*models.ForgeResource)
+	var entries []watchEntry
+	latest, err := c.eventLog.ReadFrom(since, func(e eventstore.Event) error {
+		id := e.Resource.ID
+		prev, seen := last[id]
+		if !seen {
+			// The resource's event before the range, if any
+			before, err := c.storeResourceAt(id, since)
+			if err != nil {
+				return err
+			}
+			prev = before
+		}
+		entries = append(entries, watchEntry{event: watchEventFrom(e), prev: prev})
+		if e.Deleted {
+			last[id] = nil
+		} else {
+			res := e.Resource
+			last[id] = &res
+		}
+		return nil
+	})
+	return entries, latest, err
+}
+
+// storeResourceAt returns resource id as of event upTo (nil if it didn't
+// exist then).
+func (c *Controller) storeResourceAt(id string, upTo int64) (*models.ForgeResource, error) {
+	seq := c.eventLog.LastFor(id, upTo)
+	if seq == 0 {
+		return nil, nil
+	}
+	e, err := c.eventLog.Read(seq)
+	if err != nil || e.Deleted {
+		return nil, err
+	}
+	return &e.Resource, nil
+}
+
+// subscribeFromLog subscribes a watch resuming after since, reading the
+// events that have left the buffer from the event log.
+func (c *Controller) subscribeFromLog(filter watchFilter, since int64) (*watchSubscriber, []models.WatchEvent, int64, error) {
+	// WHY READ FIRST: Scanning the file under the hub's lock would stall
+	// every write; whatever is appended meanwhile is still buffered
+	older, readUpTo, err := c.storeEventsAfter(since)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	sub, newer, latest, err := c.watch.subscribe(filter, readUpTo, true)
+	if err != nil {
+		return nil, nil, latest, err
+	}
+	var replay []models.WatchEvent
+	for _, entry := range older {
+		if view, ok := filter.view(entry); ok {
+			replay = append(replay, view)
+		}
+	}
+	return sub, append(replay, newer...), latest, nil
+}
+
+// storeRevisionAt finds resource id's last logged revision at or before
+// asOf (nil if there is none).
+func (c *Controller) storeRevisionAt(id string, asOf time.Time) (*models.ResourceRevision, error) {
+	seq := c.eventLog.LastFor(id, c.eventLog.SeqAt(asOf))
+	if seq == 0 {
+		return nil, nil
+	}
+	e, err := c.eventLog.Read(seq)
+	if err != nil {
+		return nil, err
+	}
+	return &e.ResourceRevision, nil
+}
+
+// errStopReplay ends a replay early.
+var errStopReplay = errors.New("stop replay")
+
+// HandleGetStore handles GET /admin/store
+func (c *Controller) HandleGetStore(w http.ResponseWriter, r *http.Request) {
+	c.mu.RLock()
+	response := map[string]interface{}{
+		"backend":   c.StoreBackend,
+		"resources": len(c.ResourceDB),
+	}
+	c.mu.RUnlock()
+	if c.eventLog != nil {
+		response["event_log"] = c.eventLog.Stats()
+		response["unsynced_bytes"] = c.eventLog.Unsynced()
+		response["read_only"] = false
+		if failure := c.storeFailed.Load(); failure != nil {
+			response["read_only"] = true
+			response["failure"] = failure
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// requireEventLog answers 501 unless the event log backend is in use.
+func (c *Controller) requireEventLog(w http.ResponseWriter) bool {
+	if c.eventLog != nil {
+		return true
+	}
+	w.Header().Set("Content-Type", "application/json")
+	writeOperationError(w, fmt.Errorf("the event log: %w (set STORE_BACKEND=%s)", errUnsupported, storeEventLog))
+	return false
+}
+
+// HandleListStoreEvents handles GET /admin/store/events
+// Query parameters: since (sequence number, default 0), resource_id,
+// limit (default 100, max 1000).
+func (c *Controller) HandleListStoreEvents(w http.ResponseWriter, r *http.Request) {
+	if !c.requireEventLog(w) {
+		return
+	}
+	query := r.URL.Query()
+	since, err := strconv.ParseInt(query.Get("since"), 10, 64)
+	if query.Get("since") == "" {
+		since, err = 0, nil
+	}
+	limit := 100
+	if err == nil && query.Get("limit") != "" {
+		limit, err = strconv.Atoi(query.Get("limit"))
+		if err == nil && (limit < 1 || limit > 1000) {
+			err = fmt.Errorf("out of range")
+		}
+	}
+	if err != nil || since < 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "since must be a non-negative sequence number and limit between 1 and 1000"})
+		return
+	}
+	resourceID := query.Get("resource_id")
+
+	events := []eventstore.Event{}
+	more := false
+	collect := func(e eventstore.Event) error {
+		if len(events) == limit {
+			more = true
+			return errStopReplay
+		}
+		events = append(events, e)
+		return nil
+	}
+	if resourceID != "" {
+		// WHY THE INDEX: Only this resource's events are read
+		for _, seq := range c.eventLog.ResourceSeqs(resourceID) {
+			if seq <= since {
+				continue
+			}
+			e, readErr := c.eventLog.Read(seq)
+			if readErr == nil {
+				readErr = collect(e)
+			}
+			if err = readErr; err != nil {
+				break
+			}
+		}
+	} else {
+		_, err = c.eventLog.ReadFrom(since, collect)
+	}
+	if err != nil && !errors.Is(err, errStopReplay) {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	redact := c.redactionFor(r)
+	for i := range events {
+		if redact != nil {
+			events[i].Resource = *redact.resource(events[i].Resource.DeepCopy())
+		}
+	}
+	response := map[string]interface{}{"items": events, "more": more}
+	if more {
+		response["next_since"] = events[len(events)-1].Seq
+	}
+	redact.announce(w)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleGetStoreState handles GET /admin/store/state?asOf= (or ?seq=)
+// It returns every resource that existed at that point of the event log,
+// reading each one's last event up to there.
+func (c *Controller) HandleGetStoreState(w http.ResponseWriter, r *http.Request) {
+	if !c.requireEventLog(w) {
+		return
+	}
+	query := r.URL.Query()
+	var asOf time.Time
+	var upTo int64
+	var err error
+	switch {
+	case query.Get("asOf") != "":
+		asOf, err = parseAsOf(query.Get("asOf"))
+	case query.Get("seq") != "":
+		upTo, err = strconv.ParseInt(query.Get("seq"), 10, 64)
+	default:
+		err = fmt.Errorf("missing")
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "asOf (RFC 3339 or Unix seconds) or seq (event sequence number) is required"})
+		return
+	}
+
+	if !asOf.IsZero() {
+		upTo = c.eventLog.SeqAt(asOf)
+	}
+	upTo = min(upTo, c.eventLog.Stats().LastSeq)
+	var at time.Time
+	var items []*models.ForgeResource
+	if upTo > 0 {
+		e, err := c.eventLog.Read(upTo)
+		if err == nil {
+			at = e.Timestamp
+		}
+		for _, id := range c.eventLog.ResourceIDs() {
+			var res *models.ForgeResource
+			if err == nil {
+				res, err = c.storeResourceAt(id, upTo)
+			}
+			if res != nil {
+				items = append(items, res)
+			}
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+	}
+
+	redact := c.redactionFor(r)
+	for i, res := range items {
+		items[i] = redact.resource(res)
+	}
+	if items == nil {
+		items = []*models.ForgeResource{}
+	}
+	redact.announce(w)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"seq":        upTo,
+		"event_time": at,
+		"items":      items,
+		"total":      len(items),
+	})
+}
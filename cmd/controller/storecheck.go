@@ -0,0 +1,478 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/eventstore"
+)
+
+// =============================================================================
+// STORE INTEGRITY (POST /admin/store:verify, POST /admin/store:reindex)
+// =============================================================================
+// The resources, their revision history and the indexes built from them
+// (uniqueness, search) are kept in step by the code that changes them. A
+// bug there, or an event log written by an older build, leaves them
+// disagreeing in ways nothing notices until a create is refused for a
+// name nobody holds. POST /admin/store:verify scans the store and reports
+// what it finds:
+//
+//   {"ok": false, "errors": 1, "warnings": 0,
+//    "checked": {"resources": 40, "revisions": 212, "unique_keys": 96, ...},
+//    "findings": [{"check": "unique-index", "severity": "error",
+//                  "resource_id": "res-7", "message": "holds name \"cam-3\" in
+//                  namespace \"prod\" but no longer has it",
+//                  "repair": "reindex"}]}
+//
+// Checks:
+//
+//   resource-id          a resource stored under another ID than its own
+//   duplicate-vendor-id  two resources managing the same vendor device
+//   dangling-dependency  depends_on naming a resource that doesn't exist
+//   dangling-owner       (warning) owner_ref naming a resource that
+//                        doesn't exist
+//   orphan-revisions     history of a resource that is gone without a
+//                        deletion revision
+//   revision-order       revision numbers not increasing, or the latest
+//                        not matching the resource's resource_version
+//   duplicate-unique     two resources with the same constrained value
+//   unique-index         claims missing, held by the wrong resource, or
+//                        held by nothing
+//   search-index         resources indexed with stale values, or terms
+//                        the index doesn't agree with
+//   event-log            (eventlog backend) the log's projection differs
+//                        from the resources in memory
+//   dangling-share       (warning) a share link to a deleted resource
+//
+// Findings with "repair": "reindex" are fixed by POST /admin/store:reindex,
+// which rebuilds the indexes from the resources (?index=unique,search to
+// pick; both by default) and reports what the rebuild fixed. The others
+// need a person: the store can't tell which of two resources should own
+// a camera.
+//
+// WHY ONLINE: Both run under the store lock like any write, in time
+// linear in the store; claims of creates and updates still waiting on the
+// vendor are kept, so nothing in flight loses its reservation.
+// =============================================================================
+
+// Store checks, as reported in findings.
+const (
+	checkResourceID         = "resource-id"
+	checkDuplicateVendorID  = "duplicate-vendor-id"
+	checkDanglingDependency = "dangling-dependency"
+	checkDanglingOwner      = "dangling-owner"
+	checkOrphanRevisions    = "orphan-revisions"
+	checkRevisionOrder      = "revision-order"
+	checkDuplicateUnique    = "duplicate-unique"
+	checkUniqueIndex        = "unique-index"
+	checkSearchIndex        = "search-index"
+	checkEventLog           = "event-log"
+	checkDanglingShare      = "dangling-share"
+)
+
+// Indexes :reindex rebuilds.
+const (
+	indexUnique = "unique"
+	indexSearch = "search"
+)
+
+var storeIndexes = []string{indexSearch, indexUnique}
+
+// storeFinding is one problem found in the store.
+type storeFinding struct {
+	Check      string `json:"check"`
+	Severity   string `json:"severity"` // "error" or "warning"
+	ResourceID string `json:"resource_id,omitempty"`
+	Message    string `json:"message"`
+
+	// Repair is "reindex" for findings :reindex fixes
+	Repair string `json:"repair,omitempty"`
+}
+
+// storeChecked counts what a verify looked at.
+type storeChecked struct {
+	Resources   int   `json:"resources"`
+	Revisions   int   `json:"revisions"`
+	UniqueKeys  int   `json:"unique_keys"`
+	SearchTerms int   `json:"search_terms"`
+	LogEvents   int64 `json:"log_events,omitempty"`
+}
+
+// storeReport is the result of a verify.
+type storeReport struct {
+	OK         bool           `json:"ok"`
+	Errors     int            `json:"errors"`
+	Warnings   int            `json:"warnings"`
+	Checked    storeChecked   `json:"checked"`
+	Findings   []storeFinding `json:"findings"`
+	DurationMS int64          `json:"duration_ms"`
+}
+
+// storeFindings collects findings.
+type storeFindings []storeFinding
+
+func (f *storeFindings) add(check, severity, id, repair, format string, args ...interface{}) {
+	*f = append(*f, storeFinding{Check: check, Severity: severity, ResourceID: id, Repair: repair, Message: fmt.Sprintf(format, args...)})
+}
+
+// report sorts the findings into a storeReport.
+func (f storeFindings) report(checked storeChecked, started time.Time) storeReport {
+	findings := []storeFinding(f)
+	if findings == nil {
+		findings = []storeFinding{}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Severity != findings[j].Severity {
+			return findings[i].Severity == "error"
+		}
+		if findings[i].Check != findings[j].Check {
+			return findings[i].Check < findings[j].Check
+		}
+		return findings[i].ResourceID < findings[j].ResourceID
+	})
+	report := storeReport{Checked: checked, Findings: findings, DurationMS: time.Since(started).Milliseconds()}
+	for _, finding := range findings {
+		if finding.Severity == "error" {
+			report.Errors++
+		} else {
+			report.Warnings++
+		}
+	}
+	report.OK = report.Errors == 0
+	return report
+}
+
+// sortedResourceIDs lists the stored resource IDs, oldest resource first
+// (the order that decides who keeps a contested unique value).
+// Caller must hold c.mu.
+func (c *Controller) sortedResourceIDs() []string {
+	ids := make([]string, 0, len(c.ResourceDB))
+	for id := range c.ResourceDB {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := c.ResourceDB[ids[i]], c.ResourceDB[ids[j]]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// verifyResourcesLocked checks the resources and their history.
+// Caller must hold c.mu.
+func (c *Controller) verifyResourcesLocked(findings *storeFindings, checked *storeChecked) {
+	checked.Resources = len(c.ResourceDB)
+	devices := make(map[string]string) // vendor type + vendor ID → resource ID
+	for _, id := range c.sortedResourceIDs() {
+		res := c.ResourceDB[id]
+		if res.ID != id {
+			findings.add(checkResourceID, "error", id, "", "stored under %s but its id is %q", id, res.ID)
+		}
+		if res.Status.VendorID != "" {
+			device := res.Spec.VendorType + "\x00" + res.Status.VendorID
+			if other, taken := devices[device]; taken {
+				findings.add(checkDuplicateVendorID, "error", id, "", "manages %s device %s, as does %s", res.Spec.VendorType, res.Status.VendorID, other)
+			} else {
+				devices[device] = id
+			}
+		}
+		for _, dep := range res.DependsOn {
+			if _, exists := c.ResourceDB[dep]; !exists {
+				findings.add(checkDanglingDependency, "error", id, "", "depends on %s, which doesn't exist", dep)
+			}
+		}
+		if _, exists := c.ResourceDB[res.OwnerRef]; res.OwnerRef != "" && !exists {
+			findings.add(checkDanglingOwner, "warning", id, "", "owned by %s, which doesn't exist", res.OwnerRef)
+		}
+	}
+
+	for id, revisions := range c.History {
+		checked.Revisions += len(revisions)
+		if len(revisions) == 0 {
+			continue
+		}
+		for i := 1; i < len(revisions); i++ {
+			if revisions[i].Revision <= revisions[i-1].Revision {
+				findings.add(checkRevisionOrder, "error", id, "", "revision %d follows revision %d", revisions[i].Revision, revisions[i-1].Revision)
+				break
+			}
+		}
+		last := revisions[len(revisions)-1]
+		res, exists := c.ResourceDB[id]
+		switch {
+		case !exists && !last.Deleted:
+			findings.add(checkOrphanRevisions, "error", id, "", "has %d revisions (latest %d, %q) but no resource and no deletion revision", len(revisions), last.Revision, last.Reason)
+		case exists && last.Deleted:
+			findings.add(checkOrphanRevisions, "error", id, "", "is stored but its latest revision (%d) is a deletion", last.Revision)
+		case exists && last.Revision != res.ResourceVersion:
+			findings.add(checkRevisionOrder, "error", id, "", "resource_version is %d but the latest revision is %d", res.ResourceVersion, last.Revision)
+		}
+	}
+
+	for _, link := range c.shares {
+		if _, exists := c.ResourceDB[link.ResourceID]; !exists {
+			findings.add(checkDanglingShare, "warning", link.ResourceID, "", "share link %s points to a resource that doesn't exist (it expires %s)", link.ID, link.ExpiresAt.UTC().Format(time.RFC3339))
+		}
+	}
+}
+
+// rebuildUniqueLocked builds the uniqueness index from the stored
+// resources, keeping the claims of creates and updates in flight. It
+// reports values two resources share. Caller must hold c.mu.
+func (c *Controller) rebuildUniqueLocked(findings *storeFindings) *uniqueIndex {
+	fresh := &uniqueIndex{fields: c.unique.fields, owners: make(map[string]string), held: make(map[string][]string)}
+	for _, id := range c.sortedResourceIDs() {
+		res := c.ResourceDB[id]
+		for _, claim := range fresh.claimsOf(res.Namespace, res.Name, res.Spec) {
+			if owner, taken := fresh.owners[claim.key]; taken && owner != id && findings != nil {
+				findings.add(checkDuplicateUnique, "error", id, "", "%s %q in namespace %q is also used by %s", uniqueFieldPath(claim.field), claim.value, namespaceKey(res.Namespace), owner)
+			}
+		}
+		fresh.add(id, fresh.claimsOf(res.Namespace, res.Name, res.Spec))
+	}
+	// WHY KEEP: An update holds its new values and a create its values
+	// until the vendor answers; dropping them would let a competitor in
+	for id, keys := range c.unique.held {
+		if !c.creating[id] && c.updating[id] == 0 {
+			continue
+		}
+		for _, key := range keys {
+			if _, taken := fresh.owners[key]; !taken {
+				fresh.owners[key] = id
+				fresh.held[id] = append(fresh.held[id], key)
+			}
+		}
+	}
+	return fresh
+}
+
+// uniqueFieldPath names an index field as the API does.
+func uniqueFieldPath(field string) string {
+	if f, ok := uniqueFields[field]; ok {
+		return f.path
+	}
+	return field
+}
+
+// describeUniqueKey renders an index key for a message.
+func describeUniqueKey(key string) string {
+	parts := strings.SplitN(key, "\x00", 3)
+	if len(parts) != 3 {
+		return fmt.Sprintf("%q", key)
+	}
+	return fmt.Sprintf("%s %q in namespace %q", uniqueFieldPath(parts[1]), parts[2], parts[0])
+}
+
+// verifyUniqueLocked compares the uniqueness index with one rebuilt from
+// the resources. Caller must hold c.mu.
+func (c *Controller) verifyUniqueLocked(findings *storeFindings, checked *storeChecked) {
+	checked.UniqueKeys = len(c.unique.owners)
+	fresh := c.rebuildUniqueLocked(findings)
+	for key, owner := range fresh.owners {
+		if actual, ok := c.unique.owners[key]; !ok {
+			findings.add(checkUniqueIndex, "error", owner, "reindex", "doesn't hold its %s", describeUniqueKey(key))
+		} else if actual != owner {
+			findings.add(checkUniqueIndex, "error", owner, "reindex", "its %s is held by %s", describeUniqueKey(key), actual)
+		}
+	}
+	for key, owner := range c.unique.owners {
+		if _, ok := fresh.owners[key]; !ok {
+			findings.add(checkUniqueIndex, "error", owner, "reindex", "holds %s but no longer has it", describeUniqueKey(key))
+		}
+	}
+}
+
+// verifySearchLocked compares the search index with one rebuilt from the
+// resources. Caller must hold c.mu.
+func (c *Controller) verifySearchLocked(findings *storeFindings, checked *storeChecked) {
+	checked.SearchTerms = len(c.search.terms)
+	fresh := newSearchIndex()
+	for _, res := range c.ResourceDB {
+		fresh.update(res, false)
+	}
+	for id, fields := range fresh.docs {
+		if indexed, ok := c.search.docs[id]; !ok {
+			findings.add(checkSearchIndex, "error", id, "reindex", "isn't indexed")
+		} else if !reflect.DeepEqual(indexed, fields) {
+			findings.add(checkSearchIndex, "error", id, "reindex", "is indexed with stale values")
+		}
+	}
+	for id := range c.search.docs {
+		if _, ok := fresh.docs[id]; !ok {
+			findings.add(checkSearchIndex, "error", id, "reindex", "is indexed but doesn't exist")
+		}
+	}
+	if !reflect.DeepEqual(c.search.postings, fresh.postings) || !reflect.DeepEqual(c.search.terms, fresh.terms) {
+		findings.add(checkSearchIndex, "error", "", "reindex", "the terms (%d) don't match the indexed values (%d terms)", len(c.search.terms), len(fresh.terms))
+	}
+}
+
+// verifyEventLog compares the event log, replayed up to upTo, with the
+// latest revision of each resource (versions; 0 for a deleted one).
+func (c *Controller) verifyEventLog(upTo int64, versions map[string]int64, findings *storeFindings, checked *storeChecked) error {
+	logged := make(map[string]int64)
+	err := c.eventLog.Replay(func(e eventstore.Event) error {
+		if e.Seq > upTo {
+			return errStopReplay
+		}
+		checked.LogEvents++
+		if e.Deleted {
+			logged[e.Resource.ID] = 0
+		} else {
+			logged[e.Resource.ID] = e.Revision
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStopReplay) {
+		return err
+	}
+	for id, version := range versions {
+		got, ok := logged[id]
+		switch {
+		case !ok && version > 0:
+			findings.add(checkEventLog, "error", id, "", "is stored but not in the event log (a restart would lose it)")
+		case ok && got != version && version == 0:
+			findings.add(checkEventLog, "error", id, "", "is deleted but the event log has it at revision %d", got)
+		case ok && got != version && got == 0:
+			findings.add(checkEventLog, "error", id, "", "is at revision %d but the event log has it deleted", version)
+		case ok && got != version:
+			findings.add(checkEventLog, "error", id, "", "is at revision %d but the event log has revision %d", version, got)
+		}
+	}
+	for id, got := range logged {
+		if _, ok := versions[id]; !ok && got > 0 {
+			findings.add(checkEventLog, "error", id, "", "is in the event log (revision %d) but not stored", got)
+		}
+	}
+	return nil
+}
+
+// verifyStore runs every check. With the event log it returns after
+// releasing c.mu to replay the log.
+func (c *Controller) verifyStore() (storeReport, error) {
+	started := time.Now()
+	var findings storeFindings
+	var checked storeChecked
+
+	c.mu.RLock()
+	c.verifyResourcesLocked(&findings, &checked)
+	c.verifyUniqueLocked(&findings, &checked)
+	c.verifySearchLocked(&findings, &checked)
+	var upTo int64
+	var versions map[string]int64
+	if c.eventLog != nil {
+		// WHY UNDER THE LOCK: Events are appended under c.mu, so the log up
+		// to this point matches the store as it is now
+		upTo = c.eventLog.Stats().LastSeq
+		versions = make(map[string]int64, len(c.History))
+		for id, revisions := range c.History {
+			if len(revisions) > 0 && revisions[len(revisions)-1].Deleted {
+				versions[id] = 0
+			}
+		}
+		for id, res := range c.ResourceDB {
+			versions[id] = res.ResourceVersion
+		}
+	}
+	c.mu.RUnlock()
+
+	if versions != nil {
+		if err := c.verifyEventLog(upTo, versions, &findings, &checked); err != nil {
+			return storeReport{}, err
+		}
+	}
+	return findings.report(checked, started), nil
+}
+
+// HandleVerifyStore handles POST /admin/store:verify
+func (c *Controller) HandleVerifyStore(w http.ResponseWriter, r *http.Request) {
+	report, err := c.verifyStore()
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "failed to read the event log: " + err.Error()})
+		return
+	}
+	if !report.OK {
+		logger.Warnf("Store verify found %d errors, %d warnings", report.Errors, report.Warnings)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// reindexResult reports the rebuild of one index.
+type reindexResult struct {
+	// Before and After count the index entries (unique keys or search terms)
+	Before int `json:"before"`
+	After  int `json:"after"`
+
+	// Fixed lists the findings the rebuild resolved
+	Fixed []storeFinding `json:"fixed"`
+}
+
+// HandleReindexStore handles POST /admin/store:reindex
+// Query parameters: index (comma-separated: search, unique; default both).
+func (c *Controller) HandleReindexStore(w http.ResponseWriter, r *http.Request) {
+	indexes := storeIndexes
+	if v := r.URL.Query().Get("index"); v != "" {
+		indexes = nil
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			if name != indexSearch && name != indexUnique {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "index must be one of: " + strings.Join(storeIndexes, ", ")})
+				return
+			}
+			indexes = append(indexes, name)
+		}
+	}
+
+	started := time.Now()
+	results := make(map[string]reindexResult, len(indexes))
+	c.mu.Lock()
+	for _, name := range indexes {
+		var before storeFindings
+		var checked storeChecked
+		var result reindexResult
+		switch name {
+		case indexUnique:
+			c.verifyUniqueLocked(&before, &checked)
+			result.Before = len(c.unique.owners)
+			c.unique = c.rebuildUniqueLocked(nil)
+			result.After = len(c.unique.owners)
+		case indexSearch:
+			c.verifySearchLocked(&before, &checked)
+			result.Before = len(c.search.terms)
+			fresh := newSearchIndex()
+			for _, res := range c.ResourceDB {
+				fresh.update(res, false)
+			}
+			c.search = fresh
+			result.After = len(c.search.terms)
+		}
+		result.Fixed = []storeFinding{}
+		for _, finding := range before {
+			if finding.Repair == "reindex" {
+				result.Fixed = append(result.Fixed, finding)
+			}
+		}
+		results[name] = result
+	}
+	c.mu.Unlock()
+	logger.Infof("Rebuilt store indexes %s in %s", strings.Join(indexes, ", "), time.Since(started).Round(time.Millisecond))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"items":       results,
+		"duration_ms": time.Since(started).Milliseconds(),
+	})
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// TASKS
+// =============================================================================
+// Operations that outlive their request (async deletes, see deletion.go)
+// answer 202 Accepted with a task, and report progress on it:
+//
+//   GET /tasks                 all tasks, newest first
+//                              ?resource_id= ?kind= ?state= filter
+//   GET /tasks/{id}            one task
+//
+// Finished tasks are kept for taskRetention so clients that poll slowly
+// still see the outcome.
+// =============================================================================
+
+// taskRetention is how long finished tasks stay visible.
+const taskRetention = 24 * time.Hour
+
+// startTaskLocked registers a running task of kind for res. Must be called with
+// c.mu held.
+func (c *Controller) startTaskLocked(kind string, res *models.ForgeResource, requestedBy, message string) *models.Task {
+	now := c.Clock.Now()
+	c.pruneTasksLocked(now)
+	task := &models.Task{
+		ID:          c.IDs.NewID("task"),
+		Kind:        kind,
+		ResourceID:  res.ID,
+		Name:        res.Name,
+		Namespace:   res.Namespace,
+		VendorType:  res.Spec.VendorType,
+		State:       models.TaskRunning,
+		Message:     message,
+		RequestedBy: requestedBy,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	c.Tasks[task.ID] = task
+	return task
+}
+
+// runningTaskLocked returns the running task of kind for resource id, if
+// any. Must be called with c.mu held.
+func (c *Controller) runningTaskLocked(kind, id string) *models.Task {
+	for _, task := range c.Tasks {
+		if task.Kind == kind && task.ResourceID == id && !task.Done() {
+			return task
+		}
+	}
+	return nil
+}
+
+// updateTask reports progress (0-100) on task id.
+func (c *Controller) updateTask(id string, progress int, message string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	task, exists := c.Tasks[id]
+	if !exists || task.Done() {
+		return
+	}
+	// WHY NEVER BACKWARDS: Vendors estimate coarsely; a bar that jumps
+	// back confuses more than it informs
+	if progress > task.Progress {
+		task.Progress = progress
+	}
+	task.Message = message
+	task.UpdatedAt = c.Clock.Now()
+}
+
+// finishTaskLocked marks task id succeeded (err == nil) or failed. Must be
+// called with c.mu held.
+func (c *Controller) finishTaskLocked(id string, err error, message string) {
+	task, exists := c.Tasks[id]
+	if !exists {
+		return
+	}
+	task.State = models.TaskSucceeded
+	if err != nil {
+		task.State = models.TaskFailed
+		task.Error = err.Error()
+	} else {
+		task.Progress = 100
+	}
+	task.Message = message
+	now := c.Clock.Now()
+	task.UpdatedAt, task.CompletedAt = now, &now
+}
+
+// cancelTaskLocked marks task id cancelled. Must be called with c.mu held.
+func (c *Controller) cancelTaskLocked(id, message string) {
+	task, exists := c.Tasks[id]
+	if !exists {
+		return
+	}
+	task.State = models.TaskCancelled
+	task.Message = message
+	now := c.Clock.Now()
+	task.UpdatedAt, task.CompletedAt = now, &now
+}
+
+// pruneTasksLocked drops tasks finished more than taskRetention ago. Must
+// be called with c.mu held.
+func (c *Controller) pruneTasksLocked(now time.Time) {
+	for id, task := range c.Tasks {
+		if task.Done() && task.CompletedAt != nil && now.Sub(*task.CompletedAt) > taskRetention {
+			delete(c.Tasks, id)
+		}
+	}
+}
+
+// HandleListTasks handles GET /tasks
+func (c *Controller) HandleListTasks(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	resourceID, kind, state := query.Get("resource_id"), query.Get("kind"), query.Get("state")
+
+	c.mu.RLock()
+	items := make([]models.Task, 0, len(c.Tasks))
+	for _, task := range c.Tasks {
+		if (resourceID != "" && task.ResourceID != resourceID) ||
+			(kind != "" && task.Kind != kind) ||
+			(state != "" && task.State != state) {
+			continue
+		}
+		items = append(items, *task)
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].CreatedAt.Equal(items[j].CreatedAt) {
+			return items[i].CreatedAt.After(items[j].CreatedAt)
+		}
+		return items[i].ID > items[j].ID
+	})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+}
+
+// HandleGetTask handles GET /tasks/{id}
+func (c *Controller) HandleGetTask(w http.ResponseWriter, r *http.Request) {
+	c.mu.RLock()
+	task, exists := c.Tasks[mux.Vars(r)["id"]]
+	var response models.Task
+	if exists {
+		response = *task
+	}
+	c.mu.RUnlock()
+
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "task not found"})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
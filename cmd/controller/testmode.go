@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/clock"
+)
+
+// =============================================================================
+// TEST MODE - DETERMINISTIC CLOCK AND IDS
+// =============================================================================
+// The controller reads time and generates IDs through c.Clock, c.IDs and
+// c.RequestIDs (see pkg/clock). Normally these are the real clock,
+// time-based resource IDs ("res-1706640000000000000") and random request
+// IDs.
+//
+// With FORGE_TEST_MODE=true, integration tests get:
+//   - a fake clock starting at FORGE_TEST_START_TIME (RFC3339, default
+//     2026-01-01T00:00:00Z) that only moves via POST /admin/clock
+//   - sequential IDs: res-1, res-2, evt-1, req-1, ...
+//
+// Timestamps, history, retention cleanup, rate limit windows and vendor
+// retry backoff all follow the fake clock, so a test can step through a
+// retention period or a backoff schedule without sleeping:
+//
+//	POST /admin/clock {"advance": "72h"}
+//
+// Endpoints:
+//   GET  /admin/clock   → current time and whether it is controllable
+//   POST /admin/clock   → advance or set the fake clock (test mode only)
+// =============================================================================
+
+// defaultTestStartTime is where the fake clock starts unless configured.
+var defaultTestStartTime = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// newTimeSources returns the clock, resource/event ID generator and
+// request ID generator for the controller, based on FORGE_TEST_MODE.
+func newTimeSources() (clock.Clock, clock.IDGenerator, clock.IDGenerator) {
+	testMode, _ := strconv.ParseBool(os.Getenv("FORGE_TEST_MODE"))
+	if !testMode {
+		system := clock.Real{}
+		return system, clock.NewTimeIDs(system), clock.RandomIDs{}
+	}
+
+	start := defaultTestStartTime
+	if v := os.Getenv("FORGE_TEST_START_TIME"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			logger.Warnf("Ignoring invalid FORGE_TEST_START_TIME %q", v)
+		} else {
+			start = t
+		}
+	}
+	logger.Warnf("TEST MODE: fake clock at %s, sequential IDs", start.Format(time.RFC3339))
+
+	// WHY ONE GENERATOR: Sequence numbers are counted per prefix anyway
+	ids := clock.NewSequenceIDs()
+	return clock.NewFake(start), ids, ids
+}
+
+// ClockRequest is the body accepted by POST /admin/clock.
+// Exactly one of Advance or Set must be given.
+//
+// Example:
+//
+//	{"advance": "90s"}
+//	{"set": "2026-01-31T00:00:00Z"}
+type ClockRequest struct {
+	// Advance moves the clock forward by a Go duration.
+	Advance string `json:"advance,omitempty"`
+
+	// Set jumps the clock to an RFC3339 time (forward only).
+	Set string `json:"set,omitempty"`
+}
+
+// ClockStatus is returned by the clock endpoints.
+type ClockStatus struct {
+	Now time.Time `json:"now"`
+
+	// Fake is true in test mode, when the clock can be moved.
+	Fake bool `json:"fake"`
+
+	// PendingTimers is how many waits (e.g. retry backoffs) are blocked on
+	// the fake clock. Tests poll it to know when to advance.
+	PendingTimers int `json:"pending_timers,omitempty"`
+}
+
+func (c *Controller) clockStatus() ClockStatus {
+	status := ClockStatus{Now: c.Clock.Now()}
+	if fake, ok := c.Clock.(*clock.Fake); ok {
+		status.Fake = true
+		status.PendingTimers = fake.Pending()
+	}
+	return status
+}
+
+// HandleGetClock handles GET /admin/clock
+func (c *Controller) HandleGetClock(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.clockStatus())
+}
+
+// HandleAdvanceClock handles POST /admin/clock
+func (c *Controller) HandleAdvanceClock(w http.ResponseWriter, r *http.Request) {
+	fake, ok := c.Clock.(*clock.Fake)
+	if !ok {
+		// WHY 409: Moving the real clock is never valid; the controller
+		// has to be started in test mode
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "clock is not controllable; start the controller with FORGE_TEST_MODE=true"})
+		return
+	}
+
+	var req ClockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+	if (req.Advance == "") == (req.Set == "") {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "exactly one of advance or set is required"})
+		return
+	}
+
+	if req.Advance != "" {
+		d, err := time.ParseDuration(req.Advance)
+		if err != nil || d < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "advance must be a non-negative duration like \"30s\""})
+			return
+		}
+		fake.Advance(d)
+	} else {
+		t, err := time.Parse(time.RFC3339, req.Set)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "set must be an RFC3339 time: " + err.Error()})
+			return
+		}
+		if t.Before(fake.Now()) {
+			// WHY FORWARD ONLY: Going back would break revision ordering
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "the clock can only move forward"})
+			return
+		}
+		fake.Set(t)
+	}
+
+	logger.Infof("Test clock now %s", fake.Now().Format(time.RFC3339Nano))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.clockStatus())
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/eventstore"
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// STORE TRANSACTIONS
+// =============================================================================
+// Some changes touch several records that must agree: removing a
+// resource releases its owner's cascade finalizer, an owner deleted with
+// ?propagation=orphan clears the owner_ref of everything it owned, a
+// create stores the resource with its unique claims and its owner_ref
+// (checked again by dropStaleRefs), an approved adoption names the
+// resource it created. Half of such a change corrupts the relationship
+// (an owner waiting forever for a resource that is gone, a resource
+// pointing at a deleted owner). withTx runs them as one transaction:
+//
+//   err := c.withTx(func(tx *storeTx) error {
+//       owner, ok := tx.Get(ownerID)         // a copy; edit it freely
+//       ...
+//       tx.Put(owner, "cascade-done")        // staged, not stored yet
+//       tx.Delete(id, "deleted")
+//       tx.OnCommit(func() { ... })          // events, workers
+//       return nil                           // or an error: nothing happens
+//   })
+//
+// Functions that store a resource for their callers (createResourceWith,
+// updateResourceSpecWith) take a txStage, so the caller's writes (a
+// clone's event, a PATCH's labels) join their transaction.
+//
+// IN MEMORY: fn only stages writes. An error from fn discards them;
+// otherwise the commit applies all of them in the same critical section
+// (c.mu), where no step can fail, so readers see all or none of it.
+//
+// IN THE EVENT LOG: The commit's events are appended with one AppendTx:
+// all of them or none survive a failed write or a crash (see
+// pkg/eventstore). If the append fails the store turns read-only (see
+// store.go); memory keeps the whole transaction, the log none of it.
+//
+// WHY NOT ROLL BACK AN APPLIED COMMIT: Watch subscribers have already
+// been sent its events; and what was committed is consistent, only not
+// durable. A SQL backend would run the same staged writes in one database
+// transaction and apply them to memory once it commits.
+// =============================================================================
+
+// storeTx stages the writes of one transaction.
+type storeTx struct {
+	c *Controller
+
+	// staged holds each resource the transaction read or wrote (nil =
+	// deleted); writes lists the written ones, in order, with the reason
+	// of their last write; hooks run on commit
+	staged map[string]*models.ForgeResource
+	writes []txWrite
+	hooks  []func()
+}
+
+// txWrite is a resource the transaction writes.
+type txWrite struct {
+	id     string
+	reason string
+}
+
+// write notes that resource id is written, for reason.
+// WHY ONE ENTRY PER RESOURCE: A resource written twice gets one revision,
+// of how it ends up
+func (tx *storeTx) write(id, reason string) {
+	for i := range tx.writes {
+		if tx.writes[i].id == id {
+			tx.writes[i].reason = reason
+			return
+		}
+	}
+	tx.writes = append(tx.writes, txWrite{id: id, reason: reason})
+}
+
+// txStage stages more writes in the transaction that stores res, for
+// callers of a function that stores it (createResourceWith,
+// updateResourceSpecWith).
+type txStage func(tx *storeTx, res *models.ForgeResource)
+
+// withTx runs fn as one transaction and commits what it staged, unless it
+// returns an error.
+func (c *Controller) withTx(fn func(tx *storeTx) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.withTxLocked(fn)
+}
+
+// withTxLocked is withTx for a caller that holds c.mu (write lock).
+func (c *Controller) withTxLocked(fn func(tx *storeTx) error) error {
+	tx := &storeTx{c: c, staged: make(map[string]*models.ForgeResource)}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	tx.commitLocked()
+	return nil
+}
+
+// Get returns resource id as the transaction sees it: a copy, with its
+// staged writes.
+func (tx *storeTx) Get(id string) (*models.ForgeResource, bool) {
+	if res, staged := tx.staged[id]; staged {
+		return res, res != nil
+	}
+	stored, exists := tx.c.ResourceDB[id]
+	if !exists {
+		return nil, false
+	}
+	res := stored.DeepCopy()
+	tx.staged[id] = res
+	return res, true
+}
+
+// Put stages res (from Get, or a new resource) to be stored, recording a
+// revision for reason.
+func (tx *storeTx) Put(res *models.ForgeResource, reason string) {
+	tx.staged[res.ID] = res
+	tx.write(res.ID, reason)
+}
+
+// Delete stages the removal of resource id, recording its tombstone for
+// reason.
+func (tx *storeTx) Delete(id, reason string) {
+	if _, exists := tx.Get(id); exists {
+		tx.write(id, reason)
+	}
+	tx.staged[id] = nil
+}
+
+// OnCommit runs fn when the transaction commits, once its writes are in
+// ResourceDB and before their revisions are recorded (so fn may still
+// touch the stored resources). fn must not fail.
+func (tx *storeTx) OnCommit(fn func()) {
+	tx.hooks = append(tx.hooks, fn)
+}
+
+// Owned returns the resources owner owns directly, as the transaction
+// sees them, sorted.
+func (tx *storeTx) Owned(owner string) []string {
+	var owned []string
+	for id, stored := range tx.c.ResourceDB {
+		res, staged := tx.staged[id]
+		if !staged {
+			res = stored
+		}
+		if res != nil && res.OwnerRef == owner {
+			owned = append(owned, id)
+		}
+	}
+	sort.Strings(owned)
+	return owned
+}
+
+// commitLocked applies the staged writes. Caller must hold c.mu.
+func (tx *storeTx) commitLocked() {
+	c := tx.c
+	// Step 1: Swap in the new state
+	previous := make(map[string]*models.ForgeResource, len(tx.writes))
+	for _, write := range tx.writes {
+		previous[write.id] = c.ResourceDB[write.id]
+		if res := tx.staged[write.id]; res != nil {
+			c.ResourceDB[write.id] = res
+			c.resetUniqueLocked(res.ID, res.Namespace, res.Name, res.Spec)
+		} else {
+			delete(c.ResourceDB, write.id)
+			delete(c.idle, write.id)
+			c.cancelQueuedCallsLocked(write.id)
+			c.releaseUniqueLocked(write.id)
+		}
+	}
+	for _, hook := range tx.hooks {
+		hook()
+	}
+
+	// Step 2: Record each write, collecting the events for one append
+	c.txEvents = []eventstore.Event{}
+	for _, write := range tx.writes {
+		switch res := tx.staged[write.id]; {
+		case res != nil:
+			c.recordRevision(res, write.reason, false)
+		case previous[write.id] != nil:
+			c.recordRevision(previous[write.id], write.reason, true)
+		}
+	}
+	events := c.txEvents
+	c.txEvents = nil
+	if c.eventLog == nil || c.storeFailed.Load() != nil || len(events) == 0 {
+		return
+	}
+	if err := c.eventLog.AppendTx(events); err != nil {
+		c.failStore(fmt.Errorf("appending events %d-%d (transaction): %w", events[0].Seq, events[len(events)-1].Seq, err))
+	}
+}
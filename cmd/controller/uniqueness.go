@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// UNIQUENESS CONSTRAINTS
+// =============================================================================
+// Two cameras in one namespace configured with the same ip_address, or
+// pushing to the same stream destination, fight over it on air; the
+// vendor accepts both. Constrained fields are unique per namespace:
+//
+//   name         the resource name (always)
+//   ip_address   spec.config.ip_address (normalized, see pkg/validation)
+//   stream_url   spec.stream_url (scheme and host case-insensitive,
+//                trailing slash ignored)
+//
+// UNIQUE_SPEC_FIELDS picks the constrained spec fields
+// ("ip_address,stream_url" by default, "none" to turn those checks off).
+//
+// WHY AN INDEX: A create claims its values before the vendor call, the
+// way it reserves capacity, so two concurrent creates can't both pass the
+// check. The index maps namespace + field + value to the resource holding
+// it; a create that doesn't end up stored releases its claims, an update
+// holds both old and new values until the vendor answers, and a delete
+// releases them. Protected by c.mu.
+// =============================================================================
+
+// uniqueField extracts a constrained value from a spec ("" = not set).
+type uniqueField struct {
+	path  string
+	value func(spec models.ResourceSpec) string
+}
+
+// uniqueFields are the fields that can be constrained, by name.
+var uniqueFields = map[string]uniqueField{
+	"ip_address": {path: "spec.config.ip_address", value: func(spec models.ResourceSpec) string {
+		addr, _ := spec.Config["ip_address"].(string)
+		return strings.TrimSpace(addr)
+	}},
+	"stream_url": {path: "spec.stream_url", value: func(spec models.ResourceSpec) string {
+		return canonicalStreamURL(spec.StreamURL)
+	}},
+}
+
+// canonicalStreamURL lower-cases the scheme and host and drops a trailing
+// slash, so "SRT://Ingest:9000/" and "srt://ingest:9000" are the same
+// destination.
+func canonicalStreamURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return strings.TrimSuffix(raw, "/")
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	return u.String()
+}
+
+// uniqueIndex records which resource holds each constrained value.
+type uniqueIndex struct {
+	// fields are the constrained field names, sorted
+	fields []string
+
+	// owners maps an index key to the resource ID holding it; held lists
+	// each resource's keys
+	owners map[string]string
+	held   map[string][]string
+}
+
+// newUniqueIndex builds the index for UNIQUE_SPEC_FIELDS.
+func newUniqueIndex() *uniqueIndex {
+	index := &uniqueIndex{owners: make(map[string]string), held: make(map[string][]string)}
+	v, set := os.LookupEnv("UNIQUE_SPEC_FIELDS")
+	if !set {
+		v = "ip_address,stream_url"
+	}
+	for _, name := range strings.Split(v, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || name == "none" {
+			continue
+		}
+		if _, ok := uniqueFields[name]; !ok {
+			logger.Warnf("Ignoring UNIQUE_SPEC_FIELDS entry %q: unknown field", name)
+			continue
+		}
+		index.fields = append(index.fields, name)
+	}
+	sort.Strings(index.fields)
+	return index
+}
+
+// uniqueClaim is one constrained value of a spec.
+type uniqueClaim struct {
+	field string
+	value string
+	key   string
+}
+
+// nameField is the index field for resource names.
+const nameField = "name"
+
+// claimsOf returns the constrained values a resource named name with spec
+// claims in namespace.
+func (x *uniqueIndex) claimsOf(namespace, resourceName string, spec models.ResourceSpec) []uniqueClaim {
+	claims := []uniqueClaim{{field: nameField, value: resourceName,
+		key: namespaceKey(namespace) + "\x00" + nameField + "\x00" + resourceName}}
+	for _, name := range x.fields {
+		value := uniqueFields[name].value(spec)
+		if value == "" {
+			continue
+		}
+		claims = append(claims, uniqueClaim{field: name, value: value,
+			key: namespaceKey(namespace) + "\x00" + name + "\x00" + value})
+	}
+	return claims
+}
+
+// add records id as holding claims not already held by someone else.
+func (x *uniqueIndex) add(id string, claims []uniqueClaim) {
+	for _, claim := range claims {
+		if _, taken := x.owners[claim.key]; taken {
+			continue
+		}
+		x.owners[claim.key] = id
+		x.held[id] = append(x.held[id], claim.key)
+	}
+}
+
+// release drops everything id holds.
+func (x *uniqueIndex) release(id string) {
+	for _, key := range x.held[id] {
+		if x.owners[key] == id {
+			delete(x.owners, key)
+		}
+	}
+	delete(x.held, id)
+}
+
+// uniquenessError reports a constrained value another resource holds.
+type uniquenessError struct {
+	Path      string
+	Value     string
+	Namespace string
+
+	// Resource and Name identify the competing resource (Name is empty
+	// while its create is in progress)
+	Resource string
+	Name     string
+}
+
+func (e *uniquenessError) Error() string {
+	holder := e.Resource
+	if e.Name != "" {
+		holder += " (" + e.Name + ")"
+	} else {
+		holder += " (being created)"
+	}
+	return fmt.Sprintf("%s %q is already used in namespace %q by %s", e.Path, e.Value, namespaceKey(e.Namespace), holder)
+}
+
+// writeUniquenessError writes 409 naming the competing resource.
+func writeUniquenessError(w http.ResponseWriter, err *uniquenessError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":                 err.Error(),
+		"field":                 err.Path,
+		"value":                 err.Value,
+		"conflicting_id":        err.Resource,
+		"conflicting_name":      err.Name,
+		"conflicting_namespace": namespaceKey(err.Namespace),
+	})
+}
+
+// claimUnique claims name and spec's constrained values in namespace for
+// resource id, which keeps what it already holds. Returns a
+// *uniquenessError (and claims nothing) if another resource holds one.
+func (c *Controller) claimUnique(id, namespace, name string, spec models.ResourceSpec) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	claims := c.unique.claimsOf(namespace, name, spec)
+	for _, claim := range claims {
+		owner, taken := c.unique.owners[claim.key]
+		if !taken || owner == id {
+			continue
+		}
+		conflict := &uniquenessError{Path: claim.field, Value: claim.value, Namespace: namespace, Resource: owner}
+		if field, ok := uniqueFields[claim.field]; ok {
+			conflict.Path = field.path
+		}
+		if res, exists := c.ResourceDB[owner]; exists {
+			conflict.Name = res.Name
+		}
+		return conflict
+	}
+	c.unique.add(id, claims)
+	return nil
+}
+
+// resetUniqueLocked makes resource id hold exactly name and spec's
+// constrained values (after an update, whether the vendor took it or not).
+// Caller must hold c.mu.
+func (c *Controller) resetUniqueLocked(id, namespace, name string, spec models.ResourceSpec) {
+	c.unique.release(id)
+	c.unique.add(id, c.unique.claimsOf(namespace, name, spec))
+}
+
+// releaseUniqueLocked drops resource id's claims (it was deleted, or its
+// create didn't go through). Caller must hold c.mu.
+func (c *Controller) releaseUniqueLocked(id string) {
+	c.unique.release(id)
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/Zhichengu1/mock-control-plane/pkg/validation"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// SPEC UPDATES
+// =============================================================================
+// updateResourceSpec is the one path that changes a resource's spec: it
+// validates the new spec, pushes it to the vendor with provider.Update,
+// and only then stores it (with a revision and an event). Features that
+// change specs (rollouts, ...) go through it so they all behave the same.
+// =============================================================================
+
+// Update errors not caused by the vendor.
+var (
+	errResourceNotFound = errors.New("resource not found")
+	errNoVendorDevice   = errors.New("resource has no vendor device")
+	errResourceGone     = errors.New("resource was deleted during the update")
+	errUnsupported      = errors.New("not supported by the vendor")
+)
+
+// writeOperationError maps an error from updateResourceSpec (or another
+// operation on a stored resource) to an HTTP response.
+func writeOperationError(w http.ResponseWriter, err error) {
+	var violations validation.Violations
+	var busy *vendorBusyError
+	var locked *resourceLockedError
+	var unique *uniquenessError
+	var stale *preconditionError
+	switch {
+	case errors.As(err, &locked):
+		writeLockedError(w, locked)
+		return
+	case errors.As(err, &unique):
+		writeUniquenessError(w, unique)
+		return
+	case errors.As(err, &stale):
+		writePreconditionError(w, stale)
+		return
+	case errors.Is(err, errResourceNotFound), errors.Is(err, errResourceGone):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, errNoVendorDevice), errors.Is(err, errResourceTerminating), errors.Is(err, errUpdateInProgress):
+		w.WriteHeader(http.StatusConflict)
+	case errors.Is(err, errUnsupported):
+		w.WriteHeader(http.StatusNotImplemented)
+	case errors.As(err, &violations):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "spec validation failed", "violations": violations})
+		return
+	case errors.As(err, &busy):
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	default:
+		writeProviderError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// networkViolations checks spec's ip_address, VLAN and subnet against
+// every other managed resource (see validation.CheckNetworkOverlap). id is
+// the resource being updated ("" for a create).
+func (c *Controller) networkViolations(id string, spec models.ResourceSpec) validation.Violations {
+	candidate, ok := validation.EndpointOf(id, "", spec)
+	if !ok {
+		return nil
+	}
+	c.mu.RLock()
+	var others []validation.NetworkEndpoint
+	for _, res := range c.ResourceDB {
+		// WHY TERMINATING TOO: The device keeps its address until the
+		// vendor teardown is done
+		if endpoint, ok := validation.EndpointOf(res.ID, res.Name, res.Spec); ok {
+			others = append(others, endpoint)
+		}
+	}
+	c.mu.RUnlock()
+	return validation.CheckNetworkOverlap(candidate, others)
+}
+
+// updateResourceSpec applies spec to resource id. reason is recorded on
+// the revision; detail is appended to the event message.
+// Errors are errResourceNotFound, errNoVendorDevice, errResourceTerminating,
+// errResourceGone, *resourceLockedError, *preconditionError,
+// errUpdateInProgress, validation.Violations (invalid spec) or the
+// provider's error.
+func (c *Controller) updateResourceSpec(parent context.Context, id string, spec models.ResourceSpec, reason, detail string) (*models.ForgeResource, error) {
+	return c.updateResourceSpecWith(parent, id, spec, reason, detail, nil)
+}
+
+// updateResourceSpecWith is updateResourceSpec with stage (nil = none) run
+// in the transaction that stores the new spec.
+func (c *Controller) updateResourceSpecWith(parent context.Context, id string, spec models.ResourceSpec, reason, detail string, stage txStage) (*models.ForgeResource, error) {
+	cfg := c.config()
+	// Step 1: Snapshot the resource
+	// WHY Lock: A conditional update checks the version and counts itself
+	// in c.updating in one step (see preconditions.go)
+	c.mu.Lock()
+	stored, exists := c.ResourceDB[id]
+	var res models.ForgeResource
+	var lockErr error
+	if exists {
+		res = *stored.DeepCopy()
+		lockErr = c.lockConflictLocked(parent, id)
+		if lockErr == nil {
+			lockErr = c.preconditionLocked(parent, stored)
+		}
+		if lockErr == nil {
+			c.updating[id]++
+		}
+	}
+	c.mu.Unlock()
+	if !exists {
+		return nil, errResourceNotFound
+	}
+	if lockErr != nil {
+		return nil, lockErr
+	}
+	defer c.doneUpdating(id)
+	if res.Status.VendorID == "" {
+		return nil, fmt.Errorf("%w (phase %s)", errNoVendorDevice, res.Status.Phase)
+	}
+	if res.Status.Phase == phaseTerminating {
+		return nil, errResourceTerminating
+	}
+	if spec.VendorType != res.Spec.VendorType {
+		return nil, validation.Violations{{Field: "spec.vendor_type", Rule: "immutable",
+			Message: "vendor_type can't be changed; convert the resource and recreate it instead"}}
+	}
+
+	// Step 2: Validate the new spec like a create would
+	spec = validation.NormalizeNetwork(spec)
+	configRejected, configWarnings := c.checkConfigKeys(res.Namespace, res.Spec.Config, spec)
+	res.Spec = spec
+	violations := append(validation.CheckSize(&res, cfg.SpecLimits), c.Validators.Validate(res.Type, spec)...)
+	violations = append(violations, configRejected...)
+	if len(violations) == 0 {
+		violations = c.networkViolations(id, spec)
+	}
+	if len(violations) > 0 {
+		return nil, violations
+	}
+
+	selectedProvider, exists := cfg.Providers[spec.VendorType]
+	if !exists {
+		return nil, fmt.Errorf("provider %s not configured", spec.VendorType)
+	}
+
+	// Step 2b: Claim the new unique values; the old ones stay claimed
+	// until the vendor answers (see uniqueness.go)
+	if err := c.claimUnique(id, res.Namespace, res.Name, spec); err != nil {
+		return nil, err
+	}
+
+	// Step 3: Push to the vendor
+	ctx, cancel := context.WithTimeout(parent, 30*time.Second)
+	defer cancel()
+	release, err := c.acquireVendor(ctx, spec.VendorType)
+	if err != nil {
+		c.mu.Lock()
+		if stored, exists := c.ResourceDB[id]; exists {
+			c.resetUniqueLocked(id, stored.Namespace, stored.Name, stored.Spec)
+		}
+		c.mu.Unlock()
+		return nil, err
+	}
+	status, err := selectedProvider.Update(ctx, &res)
+	release()
+	c.forgetVendorRead(spec.VendorType, res.Status.VendorID)
+
+	// Step 4: Store the outcome
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stored, exists = c.ResourceDB[id]
+	if !exists {
+		return nil, errResourceGone
+	}
+	if err != nil {
+		c.resetUniqueLocked(id, stored.Namespace, stored.Name, stored.Spec)
+		// WHY NOT WHEN THE CIRCUIT IS OPEN: Nothing reached the vendor; the
+		// caller gets the error or queues the update (see queued.go), and
+		// replays refused again would flood the events
+		if !isCircuitOpen(err) {
+			c.recordEvent(stored, models.EventWarning, models.ReasonUpdateFailed,
+				fmt.Sprintf("Update failed%s: %v", detail, err), "", "")
+		}
+		return nil, err
+	}
+	// WHY A TRANSACTION: The spec, its unique claims and what stage adds
+	// (a PATCH's metadata) are stored together (see tx.go)
+	var updated *models.ForgeResource
+	c.withTxLocked(func(tx *storeTx) error {
+		updated, _ = tx.Get(id)
+		oldStatus := updated.Status
+		updated.Spec = spec
+		updated.Status = *status
+		cfg.HealthPolicy.Apply(updated)
+		c.applyMaintenanceCondition(updated)
+		updated.UpdatedAt = c.Clock.Now()
+		tx.Put(updated, reason)
+		tx.OnCommit(func() {
+			c.recordEvent(updated, models.EventNormal, models.ReasonUpdated, "Spec updated"+detail, "", "")
+			c.warnConfigKeysLocked(updated, configWarnings)
+			c.recordStatusEvents(updated, oldStatus)
+		})
+		if stage != nil {
+			stage(tx, updated)
+		}
+		return nil
+	})
+	return updated.DeepCopy(), nil
+}
+
+// HandleUpdateResource handles PUT /resources/{id}
+// Replaces the spec; name, type and namespace can't change.
+func (c *Controller) HandleUpdateResource(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	w.Header().Set("Content-Type", "application/json")
+	if rejectBadCircuitOpenParam(w, r) {
+		return
+	}
+
+	// Step 1: Decode the new resource
+	var body models.ForgeResource
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxResourceBodyBytes)).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+
+	if body.Spec.VendorType == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "spec (with vendor_type) is required"})
+		return
+	}
+	spec, err := c.config().Profiles.apply(body.Spec)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	body.Spec = spec
+	// WHY: A resource read with GET and sent back only replaces the
+	// version it was read at (see preconditions.go)
+	r = r.WithContext(withResourceVersion(r.Context(), body.ResourceVersion))
+
+	// Step 2: Only the spec is updated; everything else must match
+	c.mu.RLock()
+	stored, exists := c.ResourceDB[id]
+	var current models.ForgeResource
+	if exists {
+		current = *stored.DeepCopy()
+	}
+	c.mu.RUnlock()
+	if !exists {
+		writeOperationError(w, errResourceNotFound)
+		return
+	}
+	var violations validation.Violations
+	immutable := func(field, got, want string) {
+		if got != "" && got != want {
+			violations = append(violations, validation.Violation{Field: field, Rule: "immutable",
+				Message: fmt.Sprintf("%s can't be changed (is %q)", field, want)})
+		}
+	}
+	immutable("id", body.ID, current.ID)
+	immutable("name", body.Name, current.Name)
+	immutable("type", body.Type, current.Type)
+	immutable("namespace", body.Namespace, current.Namespace)
+	if len(violations) > 0 {
+		writeOperationError(w, violations)
+		return
+	}
+	if !body.Metadata.IsZero() && (body.Metadata.Notes != current.Metadata.Notes || body.Metadata.RunbookURL != current.Metadata.RunbookURL) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "metadata is changed with PATCH /resources/{id}"})
+		return
+	}
+
+	// Step 3: Apply it now, or once the vendor's maintenance window ends
+	detail := ""
+	if principal, ok := principalFrom(r.Context()); ok {
+		detail = " by " + principal.Name
+	}
+	if c.rejectIfLocked(w, r, id) || c.rejectIfPreconditionFailed(w, r, id) || c.deferForMaintenance(w, r, id, "update", detail, &body.Spec) ||
+		c.queueBehindPending(w, r, id, detail, &body.Spec) {
+		return
+	}
+	res, err := c.updateResourceSpec(vendorContext(r), id, body.Spec, "updated", detail)
+	if err != nil {
+		// WHY QUEUE: The vendor's circuit is open and the policy says to
+		// replay the update later (see queued.go)
+		if c.queueAfterCircuitOpen(w, r, id, detail, &body.Spec, err) {
+			return
+		}
+		writeOperationError(w, err)
+		return
+	}
+	logger.Infof("%s: spec updated%s", id, detail)
+	setResourceETag(w, res)
+	redact := c.redactionFor(r)
+	redact.announce(w)
+	json.NewEncoder(w).Encode(redact.resource(res))
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/version"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// API VERSIONING
+// =============================================================================
+// Every route is served under a version prefix:
+//
+//   /v1/resources, /v1/resources/{id}, ...   current
+//   /resources, /resources/{id}, ...         deprecated aliases of /v1
+//
+// Deprecated aliases behave exactly like the current version but say so
+// (RFC 9745 / RFC 8594):
+//
+//   Deprecation: @1792108800                       (when they were deprecated)
+//   Sunset: Fri, 16 Apr 2027 00:00:00 GMT          (API_UNVERSIONED_SUNSET)
+//   Link: <http://host/v1/resources>; rel="successor-version"
+//
+// VERSION NEGOTIATION: A client may name the version it speaks with
+// "Forge-Version: v1" or "Accept: application/vnd.forge.v1+json". An
+// unversioned path is served in that version; a version the controller
+// doesn't serve (or one contradicting the path) is refused with 406 and
+// the list of supported versions. Every response carries Forge-Version.
+//
+// /health, GET /versions and GET /version are not versioned.
+//
+// BUILD INFO: GET /version reports the controller's semantic version, git
+// commit, build date and the API versions above, so the SDK and CLI can
+// check compatibility before anything else (see pkg/version).
+// =============================================================================
+
+// currentAPIVersion is the version unversioned paths alias.
+const currentAPIVersion = "v1"
+
+// supportedAPIVersions lists every version served, oldest first.
+var supportedAPIVersions = []string{"v1"}
+
+// HeaderAPIVersion names the version of a request or response.
+const HeaderAPIVersion = "Forge-Version"
+
+// unversionedDeprecatedAt is when the unversioned aliases were deprecated.
+var unversionedDeprecatedAt = time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC)
+
+// defaultUnversionedSunset gives clients six months to move to /v1.
+var defaultUnversionedSunset = unversionedDeprecatedAt.AddDate(0, 6, 0)
+
+// loadUnversionedSunset reads API_UNVERSIONED_SUNSET (2027-04-16 or RFC3339).
+func loadUnversionedSunset() time.Time {
+	v := os.Getenv("API_UNVERSIONED_SUNSET")
+	if v == "" {
+		return defaultUnversionedSunset
+	}
+	for _, layout := range []string{time.DateOnly, time.RFC3339} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t
+		}
+	}
+	logger.Warnf("Ignoring invalid API_UNVERSIONED_SUNSET %q", v)
+	return defaultUnversionedSunset
+}
+
+// versionedPath returns path ("/resources/res-1") under the current
+// version, for Location headers and links.
+func versionedPath(path string) string {
+	return "/" + currentAPIVersion + path
+}
+
+// isHealthPath reports whether path is a health probe.
+func (c *Controller) isHealthPath(path string) bool {
+	path = strings.TrimPrefix(path, c.BasePath)
+	return path == "/health" || path == versionedPath("/health")
+}
+
+// requestedVersion returns the version named by the request's
+// Forge-Version or Accept header, or "" if none.
+func requestedVersion(r *http.Request) string {
+	if v := strings.TrimSpace(r.Header.Get(HeaderAPIVersion)); v != "" {
+		return v
+	}
+	for _, mediaType := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType = strings.TrimSpace(strings.Split(mediaType, ";")[0])
+		if v, ok := strings.CutPrefix(mediaType, "application/vnd.forge."); ok {
+			return strings.TrimSuffix(v, "+json")
+		}
+	}
+	return ""
+}
+
+func supportedAPIVersion(version string) bool {
+	for _, v := range supportedAPIVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// versionMiddleware negotiates the API version. unversioned marks the
+// deprecated alias mount.
+func (c *Controller) versionMiddleware(unversioned bool) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requested := requestedVersion(r)
+			if requested != "" && (!supportedAPIVersion(requested) || (!unversioned && requested != currentAPIVersion)) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusNotAcceptable)
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"error":     fmt.Sprintf("API version %q is not served at %s", requested, r.URL.Path),
+					"supported": supportedAPIVersions,
+				})
+				return
+			}
+
+			w.Header().Set(HeaderAPIVersion, currentAPIVersion)
+			if unversioned {
+				successor := c.externalURL(r, versionedPath(strings.TrimPrefix(r.URL.Path, c.BasePath)))
+				w.Header().Set("Deprecation", fmt.Sprintf("@%d", unversionedDeprecatedAt.Unix()))
+				w.Header().Set("Sunset", c.UnversionedSunset.UTC().Format(http.TimeFormat))
+				w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// HandleListVersions handles GET /versions
+func (c *Controller) HandleListVersions(w http.ResponseWriter, r *http.Request) {
+	versions := make([]map[string]string, 0, len(supportedAPIVersions))
+	for _, v := range supportedAPIVersions {
+		status := "supported"
+		if v == currentAPIVersion {
+			status = "current"
+		}
+		versions = append(versions, map[string]string{"version": v, "status": status, "url": c.externalURL(r, "/"+v)})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"current":  currentAPIVersion,
+		"versions": versions,
+		"unversioned": map[string]interface{}{
+			"status":        "deprecated",
+			"serves":        currentAPIVersion,
+			"deprecated_at": unversionedDeprecatedAt,
+			"sunset":        c.UnversionedSunset,
+		},
+	})
+}
+
+// HandleGetVersion handles GET /version
+func (c *Controller) HandleGetVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(version.Get(supportedAPIVersions, currentAPIVersion))
+}
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/labels"
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// RESOURCE WATCH (SERVER-SENT EVENTS)
+// =============================================================================
+// GET /resources/watch streams resource changes as Server-Sent Events:
+//
+//   id: 42
+//   event: MODIFIED
+//   data: {"seq":42,"type":"MODIFIED","reason":"status-refresh",...}
+//
+// Every revision recorded for any resource (see history.go) is published
+// to the watch hub with a controller-wide sequence number. The hub keeps
+// the last WATCH_BUFFER events so a client can resume after a disconnect:
+// EventSource sends Last-Event-ID automatically; other clients pass
+// ?since=<seq>. If the events it missed have already left the buffer the
+// watch answers 410 Gone and the client must list again, unless the event
+// log backend is in use: then they are read from the log (see store.go).
+//
+// WHY FILTER ON THE SERVER: A dashboard for one show's namespace must not
+// receive (and throw away) every status refresh in the fleet. Filters
+// (namespace, vendor, phase, type, name prefix, label selector) are applied
+// before an event is queued
+// for a subscriber. A resource that stops matching (e.g. leaves
+// ?phase=Running) is sent once as DELETED, so the client's view never
+// keeps a resource that no longer belongs in it.
+//
+// BOOKMARKS: Every WATCH_BOOKMARK_INTERVAL the stream carries a BOOKMARK
+// event with the latest sequence number, even when everything in between
+// was filtered out. Resuming from a bookmark skips nothing that matched.
+// =============================================================================
+
+// watchQueueSize is how many events may wait for a slow subscriber before
+// it is disconnected (it resumes from its last event ID).
+const watchQueueSize = 256
+
+// watchEntry is a published change plus the resource as it was before
+// (nil if it didn't exist), used to detect resources leaving a filter.
+type watchEntry struct {
+	event models.WatchEvent
+	prev  *models.ForgeResource
+}
+
+// watchFilter selects the resources a subscriber receives. Empty sets
+// match everything.
+type watchFilter struct {
+	namespace    string
+	hasNamespace bool
+	vendors      map[string]bool
+	phases       map[string]bool
+	types        map[string]bool
+	namePrefix   string
+	selector     labels.Selector
+
+	// hideScheduled leaves out resources whose deletion is scheduled
+	// (GET /resources without ?show_deleted=true; see softdelete.go)
+	hideScheduled bool
+}
+
+// empty reports whether the filter matches every resource.
+func (f watchFilter) empty() bool {
+	return !f.hasNamespace && len(f.vendors) == 0 && len(f.phases) == 0 && len(f.types) == 0 && f.namePrefix == "" && f.selector.Empty() && !f.hideScheduled
+}
+
+// matches reports whether res passes the filter.
+func (f watchFilter) matches(res *models.ForgeResource) bool {
+	if res == nil {
+		return false
+	}
+	if f.hasNamespace && namespaceKey(res.Namespace) != namespaceKey(f.namespace) {
+		return false
+	}
+	if len(f.vendors) > 0 && !f.vendors[res.Spec.VendorType] {
+		return false
+	}
+	if len(f.phases) > 0 && !f.phases[res.Status.Phase] {
+		return false
+	}
+	if len(f.types) > 0 && !f.types[res.Type] {
+		return false
+	}
+	if !strings.HasPrefix(res.Name, f.namePrefix) {
+		return false
+	}
+	if f.hideScheduled && res.Status.DeleteAt != nil {
+		return false
+	}
+	return f.selector.Matches(res.Labels)
+}
+
+// key renders the filter canonically (sets sorted, namespace
+// normalized), so two requests with the same filters in any order agree.
+func (f watchFilter) key() string {
+	namespace := ""
+	if f.hasNamespace {
+		namespace = "ns=" + namespaceKey(f.namespace)
+	}
+	parts := []string{
+		namespace,
+		"vendor=" + strings.Join(sortedKeys(f.vendors), ","),
+		"phase=" + strings.Join(sortedKeys(f.phases), ","),
+		"type=" + strings.Join(sortedKeys(f.types), ","),
+		"prefix=" + f.namePrefix,
+		"labels=" + f.selector.String(),
+	}
+	if f.hideScheduled {
+		parts = append(parts, "scheduled=hidden")
+	}
+	return strings.Join(parts, ";")
+}
+
+// view returns the event as seen through the filter, or false if the
+// subscriber shouldn't get it.
+func (f watchFilter) view(entry watchEntry) (models.WatchEvent, bool) {
+	event := entry.event
+	now, before := f.matches(event.Resource), f.matches(entry.prev)
+	switch {
+	case now && event.Type == models.WatchModified && !before:
+		// Entered the filter
+		event.Type = models.WatchAdded
+	case !now && before && event.Type != models.WatchDeleted:
+		// Left the filter: gone from this subscriber's view
+		event.Type = models.WatchDeleted
+	case !now && !before:
+		return event, false
+	}
+	return event, true
+}
+
+// watchSubscriber is one open watch stream.
+type watchSubscriber struct {
+	filter watchFilter
+	events chan models.WatchEvent // closed when the subscriber falls behind
+}
+
+// watchHub fans resource changes out to watch streams.
+type watchHub struct {
+	mu     sync.Mutex
+	seq    int64
+	buffer []watchEntry // the most recent events, oldest first
+	size   int
+	subs   map[*watchSubscriber]bool
+}
+
+func newWatchHub(size int) *watchHub {
+	if size < 1 {
+		size = 1
+	}
+	return &watchHub{size: size, subs: make(map[*watchSubscriber]bool)}
+}
+
+// publish assigns the next sequence number to event, delivers it and
+// returns it. Called with c.mu held (from recordRevision), so events are
+// published in the order changes are stored; it never blocks on a
+// subscriber.
+func (h *watchHub) publish(event models.WatchEvent, prev *models.ForgeResource) models.WatchEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.seq++
+	event.Seq = h.seq
+	entry := watchEntry{event: event, prev: prev}
+	h.buffer = append(h.buffer, entry)
+	if len(h.buffer) > h.size {
+		h.buffer = h.buffer[len(h.buffer)-h.size:]
+	}
+
+	for sub := range h.subs {
+		view, ok := sub.filter.view(entry)
+		if !ok {
+			continue
+		}
+		select {
+		case sub.events <- view:
+		default:
+			// WHY DISCONNECT: Blocking here would stall every write in the
+			// controller behind one slow client
+			close(sub.events)
+			delete(h.subs, sub)
+		}
+	}
+	return event
+}
+
+// restore sets the sequence number and buffer after a restart from the
+// event log (see store.go), before anyone subscribes.
+func (h *watchHub) restore(seq int64, buffer []watchEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.seq, h.buffer = seq, buffer
+}
+
+// errWatchExpired means the requested resume point has left the buffer.
+type errWatchExpired struct {
+	since, oldest, latest int64
+}
+
+func (e *errWatchExpired) Error() string {
+	if e.since > e.latest {
+		return fmt.Sprintf("event %d is newer than the latest event %d (the controller restarted); list resources again and watch from the latest event", e.since, e.latest)
+	}
+	return fmt.Sprintf("events after %d are no longer buffered (oldest is %d); list resources again and watch from the latest event", e.since, e.oldest)
+}
+
+// subscribe registers a subscriber. If resume is set, the buffered events
+// after since that pass the filter are returned for replay. Replay and
+// registration happen under one lock, so no event is missed or repeated.
+func (h *watchHub) subscribe(filter watchFilter, since int64, resume bool) (*watchSubscriber, []models.WatchEvent, int64, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var replay []models.WatchEvent
+	if resume {
+		oldest := h.seq + 1
+		if len(h.buffer) > 0 {
+			oldest = h.buffer[0].event.Seq
+		}
+		if since > h.seq || since < oldest-1 {
+			return nil, nil, h.seq, &errWatchExpired{since: since, oldest: oldest, latest: h.seq}
+		}
+		for _, entry := range h.buffer {
+			if entry.event.Seq <= since {
+				continue
+			}
+			if view, ok := filter.view(entry); ok {
+				replay = append(replay, view)
+			}
+		}
+	}
+	sub := &watchSubscriber{filter: filter, events: make(chan models.WatchEvent, watchQueueSize)}
+	h.subs[sub] = true
+	return sub, replay, h.seq, nil
+}
+
+// unsubscribe removes a subscriber (a no-op if it was disconnected).
+func (h *watchHub) unsubscribe(sub *watchSubscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subs, sub)
+}
+
+// bookmark returns the latest sequence number if sub has nothing queued.
+// WHY ONLY WHEN EMPTY: A bookmark past an event still waiting in the
+// queue would make a client resuming from it skip that event.
+func (h *watchHub) bookmark(sub *watchSubscriber) (int64, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(sub.events) > 0 {
+		return 0, false
+	}
+	return h.seq, true
+}
+
+// publishWatch publishes a recorded revision and returns the event. Must
+// be called with c.mu held.
+func (c *Controller) publishWatch(revision models.ResourceRevision, prev *models.ForgeResource) models.WatchEvent {
+	eventType := models.WatchModified
+	switch {
+	case revision.Deleted:
+		eventType = models.WatchDeleted
+	case prev == nil:
+		eventType = models.WatchAdded
+	}
+	return c.watch.publish(models.WatchEvent{
+		Type:      eventType,
+		Reason:    revision.Reason,
+		Revision:  revision.Revision,
+		Timestamp: revision.Timestamp,
+		Resource:  &revision.Resource,
+	}, prev)
+}
+
+// parseWatchFilter reads the filter query parameters. Each of vendor
+// (or vendor_type), phase and type takes a comma-separated list.
+func parseWatchFilter(r *http.Request) (watchFilter, error) {
+	query := r.URL.Query()
+	// WHY REJECT ?labels: Silently ignoring a misspelled selector would
+	// widen the match (and DELETE /resources deletes what matches)
+	if query.Has("labels") {
+		return watchFilter{}, fmt.Errorf("unknown parameter labels; use labelSelector")
+	}
+	selector, err := labels.Parse(query.Get("labelSelector"))
+	if err != nil {
+		return watchFilter{}, err
+	}
+	list := func(names ...string) map[string]bool {
+		set := make(map[string]bool)
+		for _, name := range names {
+			for _, v := range strings.Split(query.Get(name), ",") {
+				if v = strings.TrimSpace(v); v != "" {
+					set[v] = true
+				}
+			}
+		}
+		return set
+	}
+	return watchFilter{
+		namespace:    query.Get("namespace"),
+		hasNamespace: query.Has("namespace"),
+		vendors:      list("vendor", "vendor_type"),
+		phases:       list("phase"),
+		types:        list("type"),
+		namePrefix:   query.Get("name_prefix"),
+		selector:     selector,
+	}, nil
+}
+
+// HandleWatchResources handles GET /resources/watch
+// Query parameters (all optional): namespace, vendor, phase, type,
+// name_prefix, labelSelector (filters; vendor, phase and type take
+// comma-separated lists), since (resume after
+// this sequence number; the Last-Event-ID header does the same).
+func (c *Controller) HandleWatchResources(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Parse filters and the resume point
+	filter, err := parseWatchFilter(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	resumeFrom := r.Header.Get("Last-Event-ID")
+	if v := r.URL.Query().Get("since"); v != "" {
+		resumeFrom = v
+	}
+	var since int64
+	if resumeFrom != "" {
+		if since, err = strconv.ParseInt(resumeFrom, 10, 64); err != nil || since < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "since (or Last-Event-ID) must be a non-negative event sequence number"})
+			return
+		}
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "streaming is not supported by this connection"})
+		return
+	}
+
+	// Step 2: Subscribe (and collect missed events to replay)
+	sub, replay, latest, err := c.watch.subscribe(filter, since, resumeFrom != "")
+	var expired *errWatchExpired
+	if errors.As(err, &expired) && c.eventLog != nil && since < expired.oldest {
+		// Older than the buffer: read the rest from the event log
+		sub, replay, latest, err = c.subscribeFromLog(filter, since)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusGone)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error(), "latest": latest})
+		return
+	}
+	defer c.watch.unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// WHY: Stop nginx-style proxies from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	redact := c.redactionFor(r)
+	redact.announce(w)
+	w.WriteHeader(http.StatusOK)
+
+	// Step 3: Replay what the client missed, then a bookmark so it knows
+	// where it stands
+	for _, event := range replay {
+		writeWatchEvent(w, redact.event(event))
+	}
+	writeWatchEvent(w, models.WatchEvent{Seq: latest, Type: models.WatchBookmark, Timestamp: c.Clock.Now()})
+	flusher.Flush()
+
+	// Step 4: Stream live events until the client goes away
+	// WHY REAL TIME: Bookmarks also keep idle connections open through
+	// proxies, so they follow the wall clock even in test mode
+	ticker := time.NewTicker(c.WatchBookmarkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, open := <-sub.events:
+			if !open {
+				fmt.Fprintf(w, "event: ERROR\ndata: {\"error\":\"watch fell too far behind; reconnect with Last-Event-ID to resume\"}\n\n")
+				flusher.Flush()
+				return
+			}
+			writeWatchEvent(w, redact.event(event))
+			flusher.Flush()
+		case <-ticker.C:
+			if seq, ok := c.watch.bookmark(sub); ok {
+				writeWatchEvent(w, models.WatchEvent{Seq: seq, Type: models.WatchBookmark, Timestamp: c.Clock.Now()})
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// writeWatchEvent writes one SSE frame.
+func writeWatchEvent(w http.ResponseWriter, event models.WatchEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		logger.Warnf("Watch: failed to encode event %d: %v", event.Seq, err)
+		return
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq, event.Type, data)
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// =============================================================================
+// YAML PAYLOADS
+// =============================================================================
+// Broadcast engineers keep device configs in YAML files. Every endpoint
+// that takes or returns JSON also speaks YAML:
+//
+//   curl -X POST /v1/resources -H 'Content-Type: application/yaml' --data-binary @cam-1.yaml
+//   curl /v1/resources/res-123 -H 'Accept: application/yaml'
+//
+// A YAML body (application/yaml, application/x-yaml, text/yaml) is turned
+// into JSON before the handler sees it, so it follows exactly the same
+// rules. PATCH takes application/merge-patch+yaml and
+// application/json-patch+yaml too. A response is written as YAML when
+// Accept prefers YAML to JSON; keys keep the order they have in JSON.
+//
+// POST /apply takes a YAML stream: its documents arrive as a JSON array
+// (see manifest.go).
+//
+// Watches stream JSON lines whatever Accept says, and non-JSON responses
+// (diagnostics bundles, pprof profiles) are left alone.
+// =============================================================================
+
+// yamlContentType is the media type of YAML responses.
+const yamlContentType = "application/yaml"
+
+// yamlBodyTypes maps YAML request media types to the JSON type the
+// handlers expect.
+var yamlBodyTypes = map[string]string{
+	"application/yaml":             "application/json",
+	"application/x-yaml":           "application/json",
+	"text/yaml":                    "application/json",
+	"text/x-yaml":                  "application/json",
+	"application/merge-patch+yaml": "application/merge-patch+json",
+	"application/json-patch+yaml":  jsonPatchContentType,
+}
+
+// wantsYAML reports whether r's Accept header ranks a YAML type above JSON.
+func wantsYAML(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return false
+	}
+	yamlQ, jsonQ := -1.0, -1.0
+	for _, entry := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(entry))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		switch {
+		case yamlBodyTypes[mediaType] == "application/json":
+			yamlQ = max(yamlQ, q)
+		case mediaType == "application/json" || mediaType == "*/*" || mediaType == "application/*":
+			jsonQ = max(jsonQ, q)
+		}
+	}
+	return yamlQ > 0 && yamlQ > jsonQ
+}
+
+// yamlToJSON converts one YAML document to JSON.
+func yamlToJSON(data []byte) ([]byte, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	var extra interface{}
+	if err := decoder.Decode(&extra); !errors.Is(err, io.EOF) {
+		return nil, errors.New("expected a single YAML document")
+	}
+	doc, err := jsonCompatible(doc)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// yamlStreamToJSON converts a YAML stream to a JSON array of its
+// documents. Empty documents (a trailing "---") are dropped; a stream of
+// one sequence is that sequence.
+func yamlStreamToJSON(data []byte) ([]byte, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	docs := []interface{}{}
+	for {
+		var doc interface{}
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %w", len(docs)+1, err)
+		}
+		if doc == nil {
+			continue
+		}
+		converted, err := jsonCompatible(doc)
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %w", len(docs)+1, err)
+		}
+		docs = append(docs, converted)
+	}
+	if len(docs) == 1 {
+		if list, ok := docs[0].([]interface{}); ok {
+			docs = list
+		}
+	}
+	return json.Marshal(docs)
+}
+
+// manifestRoutes take a YAML stream (see manifest.go).
+var manifestRoutes = map[string]bool{
+	"POST /apply":                 true,
+	"POST /namespaces/{ns}/apply": true,
+}
+
+// jsonCompatible checks v has only string mapping keys, which JSON objects
+// require.
+func jsonCompatible(v interface{}) (interface{}, error) {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			converted, err := jsonCompatible(child)
+			if err != nil {
+				return nil, err
+			}
+			value[key] = converted
+		}
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(value))
+		for key, child := range value {
+			name, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("mapping key %v must be a string", key)
+			}
+			c, err := jsonCompatible(child)
+			if err != nil {
+				return nil, err
+			}
+			converted[name] = c
+		}
+		return converted, nil
+	case []interface{}:
+		for i, child := range value {
+			converted, err := jsonCompatible(child)
+			if err != nil {
+				return nil, err
+			}
+			value[i] = converted
+		}
+	}
+	return v, nil
+}
+
+// jsonToYAML converts a JSON document to block-style YAML, keeping the
+// order of object keys.
+func jsonToYAML(data []byte) ([]byte, error) {
+	// WHY A NODE: JSON is YAML, and parsing it into a node keeps the key
+	// order a map would lose
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	plainStyle(&node)
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return nil, err
+	}
+	encoder.Close()
+	return out.Bytes(), nil
+}
+
+// yaml11Bools are strings YAML 1.1 readers (PyYAML, older tools) take for
+// booleans.
+var yaml11Bools = map[string]bool{
+	"y": true, "yes": true, "n": true, "no": true, "on": true, "off": true,
+}
+
+// plainStyle drops the flow style and quotes the JSON parse left on every
+// node; the encoder still quotes strings that would read as another type.
+func plainStyle(node *yaml.Node) {
+	node.Style = 0
+	// WHY: YAML 1.2 reads "yes" as a string, but a YAML 1.1 reader of the
+	// response wouldn't
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!str" && yaml11Bools[strings.ToLower(node.Value)] {
+		node.Style = yaml.DoubleQuotedStyle
+	}
+	for _, child := range node.Content {
+		plainStyle(child)
+	}
+}
+
+// yamlResponseWriter holds a response back to convert it to YAML.
+type yamlResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (y *yamlResponseWriter) WriteHeader(code int) {
+	if y.status == 0 {
+		y.status = code
+	}
+}
+
+func (y *yamlResponseWriter) Write(p []byte) (int, error) {
+	if y.status == 0 {
+		y.status = http.StatusOK
+	}
+	return y.body.Write(p)
+}
+
+// finish writes the held response, as YAML if it is JSON.
+func (y *yamlResponseWriter) finish() {
+	if y.status == 0 {
+		y.status = http.StatusOK
+	}
+	body := y.body.Bytes()
+	header := y.ResponseWriter.Header()
+	if mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type")); mediaType == "application/json" && len(body) > 0 {
+		if converted, err := jsonToYAML(body); err == nil {
+			body = converted
+			header.Set("Content-Type", yamlContentType)
+		} else {
+			logger.Warnf("Sending JSON: failed to convert the response to YAML: %v", err)
+		}
+	}
+	header.Del("Content-Length")
+	y.ResponseWriter.WriteHeader(y.status)
+	y.ResponseWriter.Write(body)
+}
+
+// YAMLMiddleware converts YAML request bodies to JSON and, when Accept
+// asks for it, JSON responses to YAML.
+func (c *Controller) YAMLMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// WHY NOT WATCHES: They stream; holding the response back would
+		// hold every event back
+		if strings.HasSuffix(c.routeKey(r), "/watch") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Accept")
+		if wantsYAML(r) {
+			yw := &yamlResponseWriter{ResponseWriter: w}
+			defer yw.finish()
+			w = yw
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		jsonType, isYAML := yamlBodyTypes[mediaType]
+		if !isYAML || r.Body == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		limit, convert := int64(maxResourceBodyBytes), yamlToJSON
+		if manifestRoutes[c.routeKey(r)] {
+			limit, convert = maxBatchCreateBodyBytes, yamlStreamToJSON
+		}
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		converted, err := convert(data)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid YAML: " + err.Error()})
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(converted))
+		r.ContentLength = int64(len(converted))
+		r.Header.Set("Content-Type", jsonType)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// FOLLOW (create --wait)
+// =============================================================================
+// After the create, forgectl follows the resource through the watch API
+// (GET /v1/resources/watch, Server-Sent Events) and prints every phase
+// change and status message:
+//
+//   camera/cam-1 created (res-123)
+//   Provisioning Device provisioning
+//   Running      Device running
+//
+// It stops at Running (exit 0), Failed or a deletion (exit 3), or when
+// --timeout runs out (exit 4).
+//
+// WHY WATCH, THEN READ: The create may settle between its response and
+// the watch being set up. Once the watch's first bookmark arrives every
+// later change will be streamed, so the resource is read once then; what
+// it shows can't be missed any more.
+//
+// A dropped stream is resumed with Last-Event-ID; a watch too far behind
+// to resume (410) starts over with a fresh read.
+// =============================================================================
+
+// maxWatchLine bounds one SSE line (a whole resource snapshot).
+const maxWatchLine = 4 << 20 // 4 MiB
+
+// errWatchExpired reports a watch that can't be resumed (410).
+var errWatchExpired = errors.New("watch expired")
+
+// follower prints a resource's progress and decides when it has settled.
+type follower struct {
+	id      string
+	phase   string
+	message string
+	status  int // set once settled
+}
+
+// observe prints res's phase and message if they changed, and settles on
+// Running or Failed.
+func (f *follower) observe(res *models.ForgeResource) {
+	if res.Status.Phase == f.phase && res.Status.Message == f.message {
+		return
+	}
+	f.phase, f.message = res.Status.Phase, res.Status.Message
+	fmt.Printf("%-12s %s\n", f.phase, f.message)
+	switch f.phase {
+	case "Running":
+		f.status = exitOK
+	case "Failed":
+		f.status = exitFailed
+	}
+}
+
+// settled reports whether the follow is over.
+func (f *follower) settled() bool {
+	return f.phase == "Running" || f.phase == "Failed" || f.status == exitFailed
+}
+
+// follow streams res's changes until it settles, and returns the exit
+// status.
+func (c *cli) follow(ctx context.Context, res *models.ForgeResource, timeout time.Duration) int {
+	f := &follower{id: res.ID}
+	f.observe(res)
+	lastSeq := int64(-1)
+	for !f.settled() {
+		err := c.watchOnce(ctx, res, f, &lastSeq)
+		switch {
+		case err == nil:
+			// The stream ended; resume it
+		case errors.Is(err, errWatchExpired):
+			lastSeq = -1
+		case ctx.Err() != nil:
+			fmt.Fprintf(os.Stderr, "forgectl: %s/%s is still %s after %s\n", res.Type, res.Name, f.phase, timeout)
+			return exitTimeout
+		default:
+			fmt.Fprintln(os.Stderr, "forgectl: watch:", err)
+			// WHY WAIT: A restarting controller refuses connections for a while
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+		}
+	}
+	return f.status
+}
+
+// watchOnce runs one watch connection until f settles or the stream ends.
+// lastSeq is where to resume (-1 for a fresh watch, which reads the
+// resource once the watch is set up).
+func (c *cli) watchOnce(ctx context.Context, res *models.ForgeResource, f *follower, lastSeq *int64) error {
+	// Step 1: Watch the resource's name in its namespace
+	query := url.Values{"namespace": {res.Namespace}, "name_prefix": {res.Name}, "type": {res.Type}}
+	req, err := c.request(ctx, http.MethodGet, "/resources/watch?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	fresh := *lastSeq < 0
+	if !fresh {
+		req.Header.Set("Last-Event-ID", fmt.Sprint(*lastSeq))
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusGone {
+		return errWatchExpired
+	}
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+
+	// Step 2: Read SSE frames; only data lines matter
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64<<10), maxWatchLine)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var event models.WatchEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil || event.Type == "" {
+			// An ERROR frame: the watch fell behind; resume it
+			return nil
+		}
+		*lastSeq = event.Seq
+		switch {
+		case event.Type == models.WatchBookmark && fresh:
+			// Step 3: The watch is set up; catch up with a read
+			fresh = false
+			var current models.ForgeResource
+			if err := c.do(ctx, http.MethodGet, "/resources/"+url.PathEscape(f.id), "", nil, &current); err != nil {
+				return err
+			}
+			f.observe(&current)
+		case event.Resource == nil || event.Resource.ID != f.id:
+			// Another resource whose name shares the prefix
+			continue
+		case event.Type == models.WatchDeleted:
+			fmt.Printf("%-12s %s\n", "Deleted", "the resource was deleted while waiting")
+			f.status = exitFailed
+		default:
+			f.observe(event.Resource)
+		}
+		if f.settled() {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
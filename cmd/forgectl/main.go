@@ -0,0 +1,237 @@
+// =============================================================================
+// FORGECTL
+// =============================================================================
+// forgectl is the command line client of the Forge Controller, for
+// deployment scripts and operators:
+//
+//	forgectl create -f cam-1.yaml --wait
+//
+// creates the resource in the manifest (YAML or JSON, "-" for stdin) and,
+// with --wait, follows it through the watch API until it is Running or
+// Failed (see follow.go). The exit status tells the script what happened
+// (see the exit* constants).
+//
+// The controller is FORGE_SERVER (default http://localhost:8080), or
+// --server; FORGE_TOKEN (or --token) is sent as a bearer API key. Before
+// anything else the controller's version is checked against the client's
+// (see pkg/version).
+// =============================================================================
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/Zhichengu1/mock-control-plane/pkg/version"
+)
+
+// apiVersion is the controller API version forgectl speaks.
+const apiVersion = "v1"
+
+// Exit statuses.
+// WHY DISTINCT: A deployment script retries a timeout, but not a device
+// the vendor refused
+const (
+	exitOK      = 0
+	exitError   = 1 // the request failed (connection, validation, ...)
+	exitUsage   = 2
+	exitFailed  = 3 // the resource ended Failed, or was deleted while waiting
+	exitTimeout = 4 // --wait gave up before the resource settled
+)
+
+// cli is a forgectl invocation's connection to the controller.
+type cli struct {
+	server string
+	token  string
+	http   *http.Client
+}
+
+func main() {
+	global := flag.NewFlagSet("forgectl", flag.ContinueOnError)
+	global.Usage = func() { usage(global.Output()) }
+	server := global.String("server", envOr("FORGE_SERVER", "http://localhost:8080"), "controller URL")
+	token := global.String("token", os.Getenv("FORGE_TOKEN"), "API key (sent as a bearer token)")
+	if err := global.Parse(os.Args[1:]); err != nil {
+		os.Exit(exitUsage)
+	}
+	if global.NArg() == 0 {
+		usage(os.Stderr)
+		os.Exit(exitUsage)
+	}
+
+	c := &cli{server: strings.TrimSuffix(*server, "/"), token: *token, http: &http.Client{}}
+	switch command := global.Arg(0); command {
+	case "create":
+		os.Exit(c.create(global.Args()[1:]))
+	case "version":
+		fmt.Println("forgectl", version.Version)
+		os.Exit(exitOK)
+	default:
+		fmt.Fprintf(os.Stderr, "forgectl: unknown command %q\n", command)
+		usage(os.Stderr)
+		os.Exit(exitUsage)
+	}
+}
+
+// usage prints the commands and global flags.
+func usage(w io.Writer) {
+	fmt.Fprint(w, `Usage: forgectl [--server URL] [--token KEY] <command> [flags]
+
+Commands:
+  create -f FILE [-n NAMESPACE] [--wait] [--timeout 10m]
+                 create the resource in FILE ("-" for stdin); --wait follows
+                 it until Running (exit 0) or Failed (exit 3)
+  version        print forgectl's version
+
+Exit status: 0 ok, 1 request failed, 2 usage, 3 resource failed, 4 timed out
+`)
+}
+
+// create runs "forgectl create" and returns the exit status.
+func (c *cli) create(args []string) int {
+	flags := flag.NewFlagSet("create", flag.ContinueOnError)
+	file := flags.String("f", "", "manifest file (YAML or JSON; - for stdin)")
+	namespace := flags.String("n", "", "namespace to create the resource in")
+	wait := flags.Bool("wait", false, "follow the resource until it is Running or Failed")
+	timeout := flags.Duration("timeout", 10*time.Minute, "how long --wait follows the resource")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *file == "" || flags.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, "forgectl create: -f FILE is required (and takes no other arguments)")
+		return exitUsage
+	}
+
+	// Step 1: Read the manifest
+	var manifest []byte
+	var err error
+	if *file == "-" {
+		manifest, err = io.ReadAll(os.Stdin)
+	} else {
+		manifest, err = os.ReadFile(*file)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "forgectl create:", err)
+		return exitError
+	}
+	// WHY YAML FOR STDIN: JSON is YAML too, and the controller converts it
+	contentType := "application/yaml"
+	if strings.EqualFold(filepath.Ext(*file), ".json") {
+		contentType = "application/json"
+	}
+
+	// Step 2: Check the controller speaks our API version
+	ctx := context.Background()
+	if err := c.checkVersion(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, "forgectl:", err)
+		return exitError
+	}
+
+	// Step 3: Create it
+	path := "/resources"
+	if *namespace != "" {
+		path = "/namespaces/" + url.PathEscape(*namespace) + path
+	}
+	var res models.ForgeResource
+	if err := c.do(ctx, http.MethodPost, path, contentType, strings.NewReader(string(manifest)), &res); err != nil {
+		fmt.Fprintln(os.Stderr, "forgectl create:", err)
+		return exitError
+	}
+	fmt.Printf("%s/%s created (%s)\n", res.Type, res.Name, res.ID)
+	if !*wait {
+		return exitOK
+	}
+
+	// Step 4: Follow it until it settles
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+	return c.follow(ctx, &res, *timeout)
+}
+
+// checkVersion refuses a controller that doesn't serve apiVersion and
+// prints skew warnings.
+func (c *cli) checkVersion(ctx context.Context) error {
+	info, err := version.Fetch(ctx, c.http, c.server)
+	if err != nil {
+		return err
+	}
+	compat := version.Check(*info, version.Client{Version: version.Version, APIVersion: apiVersion})
+	for _, warning := range compat.Warnings {
+		fmt.Fprintln(os.Stderr, "forgectl: warning:", warning)
+	}
+	return compat.Err
+}
+
+// request builds a request to path under the controller's API version.
+func (c *cli) request(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.server+"/"+apiVersion+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
+
+// do sends a request and decodes a successful JSON response into out. An
+// error response becomes an error with the controller's message.
+func (c *cli) do(ctx context.Context, method, path, contentType string, body io.Reader, out interface{}) error {
+	req, err := c.request(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return responseError(resp)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// responseError turns an error response into an error, with the
+// controller's "error" (and any violations) when it sent JSON.
+func responseError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var body struct {
+		Error      string `json:"error"`
+		Violations []struct {
+			Field   string `json:"field"`
+			Message string `json:"message"`
+		} `json:"violations"`
+	}
+	if json.Unmarshal(data, &body) != nil || body.Error == "" {
+		return fmt.Errorf("%s %s: %s", resp.Request.Method, resp.Request.URL.Path, resp.Status)
+	}
+	message := body.Error
+	for _, v := range body.Violations {
+		message += fmt.Sprintf("\n  %s: %s", v.Field, v.Message)
+	}
+	return errors.New(message)
+}
+
+// envOr returns the environment variable key, or def if it is unset.
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// DEVICE ACTION HANDLERS
+// =============================================================================
+// Simulates Sony's operational endpoints:
+//
+//   POST /devices/{id}/restart       → encoder restarts, stream comes back
+//   POST /devices/{id}/reboot        → power cycle; a stopped device comes
+//                                      back stopped
+//   POST /devices/{id}/stream/start  → output on air (409 unless active)
+//   POST /devices/{id}/stream/stop   → output off air, device keeps running
+//
+// Restarts and reboots happen instantly here.
+// =============================================================================
+
+// HandleRestartDevice restarts a device's encoder.
+func HandleRestartDevice(w http.ResponseWriter, r *http.Request) {
+	deviceAction(w, r, "restart", func(device *models.SonyDeviceResponse) string {
+		if device.Status != "active" {
+			return "device is " + device.Status + "; only an active encoder can restart"
+		}
+		device.Message = "Encoder restarted"
+		restartDevice(device)
+		return ""
+	})
+}
+
+// HandleRebootDevice power-cycles a device.
+func HandleRebootDevice(w http.ResponseWriter, r *http.Request) {
+	deviceAction(w, r, "reboot", func(device *models.SonyDeviceResponse) string {
+		if device.Status == "deleting" || device.Status == "provisioning" {
+			return "device is " + device.Status
+		}
+		device.Message = "Device rebooted"
+		if device.Status == "active" {
+			restartDevice(device)
+		}
+		return ""
+	})
+}
+
+// HandleStartStream puts a device's output on air.
+func HandleStartStream(w http.ResponseWriter, r *http.Request) {
+	deviceAction(w, r, "start stream", func(device *models.SonyDeviceResponse) string {
+		if device.Status != "active" {
+			return "device is " + device.Status + "; start it first"
+		}
+		if device.Configuration == nil {
+			return "device has no stream configured"
+		}
+		device.StreamStatus = simulateStreamStatus(device.Configuration.StreamConfig)
+		if device.StreamStatus == nil {
+			return "device has no stream configured"
+		}
+		device.Message = "Stream started"
+		return ""
+	})
+}
+
+// HandleStopStream takes a device's output off air.
+func HandleStopStream(w http.ResponseWriter, r *http.Request) {
+	deviceAction(w, r, "stop stream", func(device *models.SonyDeviceResponse) string {
+		if device.Status != "active" {
+			return "device is " + device.Status
+		}
+		device.StreamStatus = nil
+		device.Message = "Stream stopped"
+		return ""
+	})
+}
+
+// restartDevice brings the configured stream back.
+func restartDevice(device *models.SonyDeviceResponse) {
+	if device.Configuration != nil {
+		device.StreamStatus = simulateStreamStatus(device.Configuration.StreamConfig)
+	}
+}
+
+// deviceAction applies action to a device. apply returns a reason the
+// device can't do it ("" = done), answered with 409.
+func deviceAction(w http.ResponseWriter, r *http.Request, name string, apply func(device *models.SonyDeviceResponse) string) {
+	deviceID := mux.Vars(r)["id"]
+	var refused string
+	device, err := store.Update(deviceID, func(device *models.SonyDeviceResponse) {
+		before := *device
+		if refused = apply(device); refused != "" {
+			*device = before
+		}
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if refused != "" {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "cannot " + name + ": " + refused})
+		return
+	}
+	log.Printf("%s: %s", device.Message, deviceID)
+	json.NewEncoder(w).Encode(device)
+}
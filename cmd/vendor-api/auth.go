@@ -0,0 +1,123 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// SESSION LOGIN HANDLERS
+// =============================================================================
+// Simulates a vendor that hands out short-lived session tokens instead of
+// taking a static API key. Off unless MOCK_SESSION_USERS is set:
+//
+//   MOCK_SESSION_USERS="forge:secret"   username:password pairs
+//   MOCK_SESSION_TTL=15m                session lifetime
+//
+//   POST   /auth/login      {"username", "password"} → {"token", "expires_in"}
+//   DELETE /auth/sessions   drop every session, like a vendor restart
+//
+// Every other route (except /auth and /health) then needs
+// "Authorization: Bearer <token>" or "X-Session-Token: <token>", and
+// answers 401 without one.
+// =============================================================================
+
+var (
+	sessionMu    sync.Mutex
+	sessionUsers map[string]string    // username → password (nil = off)
+	sessions     map[string]time.Time // token → expiry
+	sessionTTL   = 15 * time.Minute
+)
+
+// configureSessions enables session auth from MOCK_SESSION_USERS.
+func configureSessions(spec string) {
+	for _, entry := range strings.Split(spec, ",") {
+		user, password, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || user == "" {
+			continue
+		}
+		if sessionUsers == nil {
+			sessionUsers = make(map[string]string)
+			sessions = make(map[string]time.Time)
+		}
+		sessionUsers[user] = password
+	}
+	if sessionUsers != nil {
+		log.Printf("Session auth enabled for %d users (sessions last %s)", len(sessionUsers), sessionTTL)
+	}
+}
+
+// HandleLogin issues a session token.
+func HandleLogin(w http.ResponseWriter, r *http.Request) {
+	var login struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewDecoder(r.Body).Decode(&login); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON"})
+		return
+	}
+	sessionMu.Lock()
+	password, known := sessionUsers[login.Username]
+	if !known || password != login.Password {
+		sessionMu.Unlock()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid username or password"})
+		return
+	}
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	token := hex.EncodeToString(buf)
+	sessions[token] = time.Now().Add(sessionTTL)
+	sessionMu.Unlock()
+
+	log.Printf("Session opened for %s", login.Username)
+	json.NewEncoder(w).Encode(map[string]interface{}{"token": token, "expires_in": int64(sessionTTL / time.Second)})
+}
+
+// HandleDropSessions forgets every session.
+func HandleDropSessions(w http.ResponseWriter, r *http.Request) {
+	sessionMu.Lock()
+	dropped := len(sessions)
+	sessions = make(map[string]time.Time)
+	sessionMu.Unlock()
+	log.Printf("Dropped %d sessions", dropped)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requireSession refuses requests without a live session token when
+// session auth is enabled.
+func requireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if sessionUsers == nil || strings.HasPrefix(r.URL.Path, "/auth/") || r.URL.Path == "/health" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token := r.Header.Get("X-Session-Token")
+		if token == "" {
+			token = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		}
+		sessionMu.Lock()
+		expires, ok := sessions[token]
+		if ok && time.Now().After(expires) {
+			delete(sessions, token)
+			ok = false
+		}
+		sessionMu.Unlock()
+		if !ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "session expired or invalid; log in again"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
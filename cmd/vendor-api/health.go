@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// HEALTH METRICS SIMULATION
+// =============================================================================
+// Devices report health_metrics (CPU, memory, temperature) with comfortable
+// readings. Real hardware runs hot or drops frames on its own; here a test
+// sets the readings it wants:
+//
+//   PUT /devices/{id}/health_metrics
+//   {"temperature_celsius": 82, "dropped_frames": 120, "uptime_seconds": 60}
+//
+// Fields left out keep their value. dropped_frames and uptime_seconds go
+// to the stream status (only while the device streams). Not part of the
+// real Sony API.
+// =============================================================================
+
+// simulateHealthMetrics returns the readings of a device at rest.
+func simulateHealthMetrics() *models.SonyHealthMetrics {
+	return &models.SonyHealthMetrics{
+		CPUUsagePercent:    35,
+		MemoryUsagePercent: 40,
+		Temperature:        45,
+		FanSpeedRPM:        2400,
+		LastChecked:        time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// HandleSetHealthMetrics overrides a device's health readings.
+func HandleSetHealthMetrics(w http.ResponseWriter, r *http.Request) {
+	deviceID := mux.Vars(r)["id"]
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	var readings struct {
+		*models.SonyHealthMetrics
+		DroppedFrames *int64 `json:"dropped_frames"`
+		UptimeSeconds *int64 `json:"uptime_seconds"`
+	}
+	// WHY CHECKED FIRST: The readings are merged onto the stored ones
+	// under the store's lock, where a bad body can't be reported
+	readings.SonyHealthMetrics = simulateHealthMetrics()
+	if err := json.Unmarshal(body, &readings); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid JSON"})
+		return
+	}
+
+	device, err := store.Update(deviceID, func(device *models.SonyDeviceResponse) {
+		metrics := simulateHealthMetrics()
+		if device.HealthMetrics != nil {
+			metrics = device.HealthMetrics
+		}
+		readings.SonyHealthMetrics = metrics
+		json.Unmarshal(body, &readings)
+		metrics.LastChecked = time.Now().UTC().Format(time.RFC3339)
+		device.HealthMetrics = metrics
+		if stream := device.StreamStatus; stream != nil {
+			if readings.DroppedFrames != nil {
+				stream.DroppedFrames = *readings.DroppedFrames
+			}
+			if readings.UptimeSeconds != nil {
+				stream.UptimeSeconds = *readings.UptimeSeconds
+			}
+		}
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	metrics := device.HealthMetrics
+	log.Printf("Set health metrics of %s: %.0f°C, CPU %.0f%%", deviceID, metrics.Temperature, metrics.CPUUsagePercent)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(device)
+}
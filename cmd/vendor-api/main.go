@@ -20,23 +20,21 @@
 package main
 
 import (
-	"encoding/json" // For JSON parsing - Sony API uses JSON
-	"fmt"           // For string formatting
-	"log"           // For logging requests (helpful for debugging)
-	"math/rand"     // For generating random device IDs
-	"net/http"      // For HTTP server
-	"time"          // For timestamps in device IDs
+	"encoding/base64" // For opaque page tokens
+	"encoding/json"   // For JSON parsing - Sony API uses JSON
+	"fmt"             // For string formatting
+	"log"             // For logging requests (helpful for debugging)
+	"math/rand"       // For generating random device IDs
+	"net/http"        // For HTTP server
+	"os"              // For reading configuration from environment
+	"sort"            // For listing devices in a stable order
+	"strconv"         // For parsing numeric environment variables
+	"time"            // For timestamps in device IDs
 
 	"github.com/Zhichengu1/mock-control-plane/pkg/models" // Sony data structures
 	"github.com/gorilla/mux"                              // Router with URL params support
 )
 
-// devices is our in-memory "database" for this mock server.
-// WHY A MAP: Simple key-value storage, device_id → device data
-// WHY GLOBAL: All handlers need access to the same data
-// NOTE: Data is lost when server restarts (that's fine for testing)
-var devices = make(map[string]*models.SonyDeviceResponse)
-
 // =============================================================================
 // CREATE DEVICE HANDLER
 // =============================================================================
@@ -77,25 +75,44 @@ func HandleCreateDevice(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(map[string]string{"error": "model is required"})
 		return
 	}
+	if sc := req.StreamConfig; sc != nil && len(sc.Profiles) > 1 && len(sc.Profiles) > models.SonyStreamProfileLimits[req.Model] {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("model %s cannot encode %d stream profiles", req.Model, len(sc.Profiles))})
+		return
+	}
 
 	// Generate random device_id
 	// WHY: Real Sony would assign an ID to the new device
 	// This ID is used for all future operations (get, update, delete)
 	deviceID := generateDeviceID()
 
+	// Assign an address when none was requested
+	// WHY: Real Sony devices get one via DHCP and report it back
+	if req.IPAddress == "" {
+		req.IPAddress = fmt.Sprintf("%s%d", dhcpPoolPrefix, dhcpPoolFirst+rand.Intn(dhcpPoolSize))
+	}
+
 	// Create device response
 	// WHY "active": Simulates that device was successfully provisioned
 	// Real Sony might return "provisioning" first, then "active" later
 	deviceResponse := &models.SonyDeviceResponse{
-		DeviceID: deviceID,
-		Status:   "active",
-		Message:  "Device provisioned successfully",
+		DeviceID:      deviceID,
+		Status:        "active",
+		Message:       "Device provisioned successfully",
+		Model:         req.Model,
+		IPAddress:     req.IPAddress,
+		StreamStatus:  simulateStreamStatus(req.StreamConfig),
+		SyncStatus:    simulateSyncStatus(req.Settings),
+		HealthMetrics: simulateHealthMetrics(),
+		// WHY KEEP THE REQUEST: Real Sony returns the stored configuration
+		// on GET/list, which discovery uses to reverse-map unmanaged devices
+		Configuration: &req,
 	}
 
-	// Store in devices map
+	// Store in the device store (see store.go)
 	// WHY: So we can retrieve/delete it later
 	// Real Sony would store in their database
-	devices[deviceID] = deviceResponse
+	store.Add(deviceResponse)
 
 	// WHY LOG: Helpful for debugging - see what requests came in
 	log.Printf("Created device: %s (name: %s, model: %s)", deviceID, req.DeviceName, req.Model)
@@ -132,9 +149,9 @@ func HandleGetDevice(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Look up in devices map
+	// Look up in the device store
 	// WHY: Check if device exists in our "database"
-	device, exists := devices[deviceID]
+	device, exists := store.Get(deviceID)
 
 	// Return 404 if not found
 	// WHY 404: REST convention - resource doesn't exist
@@ -152,6 +169,133 @@ func HandleGetDevice(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(device)
 }
 
+// =============================================================================
+// LIST DEVICES HANDLER
+// =============================================================================
+// HandleListDevices simulates Sony's device inventory endpoint.
+//
+// WHY CONTROLLER CALLS THIS:
+//   - Discovery: find devices that exist in Sony but aren't managed by Forge
+//     (created by hand, by another team, or orphaned by a failed delete)
+//   - Inventory sync pages through it with ?page_size= and ?page_token=
+func HandleListDevices(w http.ResponseWriter, r *http.Request) {
+	// WHY SORTED (by the store): Real vendor APIs page in a fixed order
+	list := models.SonyDeviceList{Devices: store.List()}
+
+	// Paging: ?page_size=N&page_token=T
+	// WHY TOKEN = LAST ID: Pages stay stable while devices are added or
+	// deleted between requests, like a real cursor
+	if size, err := strconv.Atoi(r.URL.Query().Get("page_size")); err == nil && size > 0 {
+		start := 0
+		if token := r.URL.Query().Get("page_token"); token != "" {
+			after, err := base64.RawURLEncoding.DecodeString(token)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid page_token"})
+				return
+			}
+			start = sort.Search(len(list.Devices), func(i int) bool { return list.Devices[i].DeviceID > string(after) })
+		}
+		list.TotalCount = len(list.Devices)
+		end := start + size
+		if end < len(list.Devices) {
+			list.NextPageToken = base64.RawURLEncoding.EncodeToString([]byte(list.Devices[end-1].DeviceID))
+		} else {
+			end = len(list.Devices)
+		}
+		list.Devices = list.Devices[start:end]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// =============================================================================
+// UPDATE DEVICE HANDLER
+// =============================================================================
+// HandleUpdateDevice simulates Sony's device reconfiguration endpoint.
+//
+// WHAT REAL SONY WOULD DO:
+// - Push the new settings to the device
+// - Restart the encoder if the stream settings changed
+//
+// WHAT WE DO:
+//   - Replace the stored configuration
+//   - Fail the encoder when the bitrate is beyond what it can sustain, so
+//     a bad setting can be exercised end to end (canary rollouts)
+func HandleUpdateDevice(w http.ResponseWriter, r *http.Request) {
+	deviceID := mux.Vars(r)["id"]
+	if !requireDevice(w, deviceID) {
+		return
+	}
+
+	var req models.SonyDeviceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+
+	device, err := store.Update(deviceID, func(device *models.SonyDeviceResponse) {
+		reconfigureDevice(device, req)
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	log.Printf("Updated device: %s (status: %s)", deviceID, device.Status)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(device)
+}
+
+// reconfigureDevice applies an update request to a stored device.
+func reconfigureDevice(device *models.SonyDeviceResponse, req models.SonyDeviceRequest) {
+
+	// WHY KEEP THE ADDRESS: The DHCP lease doesn't change on reconfiguration
+	if req.IPAddress == "" {
+		req.IPAddress = device.IPAddress
+	}
+	if req.Model == "" {
+		req.Model = device.Model
+	}
+
+	device.Configuration = &req
+	device.IPAddress = req.IPAddress
+	device.StreamStatus = simulateStreamStatus(req.StreamConfig)
+	device.SyncStatus = simulateSyncStatus(req.Settings)
+	device.Status = "active"
+	device.Message = "Device reconfigured successfully"
+
+	// WHY 80000 kbps: Above what the simulated encoder sustains; the device
+	// accepts the setting and then falls over, like real hardware
+	if load := encoderLoadKbps(req.StreamConfig); load > maxEncoderBitrateKbps {
+		device.Status = "error"
+		device.Message = fmt.Sprintf("Encoder overload: bitrate %d kbps exceeds %d kbps", load, maxEncoderBitrateKbps)
+		device.StreamStatus = nil
+	}
+}
+
+// encoderLoadKbps is the bitrate the encoder produces: the stream's, or the
+// sum of its profiles when it encodes several.
+func encoderLoadKbps(config *models.SonyStreamConfig) int {
+	if config == nil {
+		return 0
+	}
+	if len(config.Profiles) == 0 {
+		return config.Bitrate
+	}
+	total := 0
+	for _, profile := range config.Profiles {
+		total += profile.Bitrate
+	}
+	return total
+}
+
+// maxEncoderBitrateKbps is the highest bitrate the simulated encoder sustains
+const maxEncoderBitrateKbps = 80000
+
 // =============================================================================
 // DELETE DEVICE HANDLER
 // =============================================================================
@@ -178,15 +322,19 @@ func HandleDeleteDevice(w http.ResponseWriter, r *http.Request) {
 	// Check if device exists before deleting
 	// WHY CHECK: Some APIs return 404 for deleting non-existent resources
 	// Others return 204 (idempotent). We chose 404 for clarity.
-	if _, exists := devices[deviceID]; !exists {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(map[string]string{"error": "device not found"})
+	// Slow teardown: answer 202 and let the device linger (see teardown.go)
+	if teardownTime > 0 {
+		beginTeardown(w, deviceID)
 		return
 	}
 
-	// Delete from devices map
+	// Delete from the device store
 	// WHY: Remove the device from our "database"
-	delete(devices, deviceID)
+	if err := store.Delete(deviceID); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "device not found"})
+		return
+	}
 
 	// WHY LOG: Track what was deleted for debugging
 	log.Printf("Deleted device: %s", deviceID)
@@ -231,6 +379,52 @@ func generateDeviceID() string {
 	return fmt.Sprintf("sony-dev-%d-%04d", time.Now().Unix(), rand.Intn(10000))
 }
 
+// simulateStreamStatus reports a connected destination for an enabled
+// stream, the way a real device does once its output is flowing.
+func simulateStreamStatus(config *models.SonyStreamConfig) *models.SonyStreamStatus {
+	if config == nil || !config.Enabled || config.DestinationURL == "" {
+		return nil
+	}
+	return &models.SonyStreamStatus{
+		IsStreaming:    true,
+		CurrentBitrate: config.Bitrate,
+		DestinationStatus: []models.SonyDestinationStatus{
+			{URL: config.DestinationURL, Connected: true},
+		},
+	}
+}
+
+// seedUnmanagedDevices pre-populates the mock with devices that were not
+// created through Forge, so discovery/adoption can be exercised locally.
+//
+// WHY NO forge_* METADATA: That's what makes them "unmanaged" - the same
+// situation as a camera another team configured by hand in Sony's console
+func seedUnmanagedDevices(count int) {
+	sonyModels := []string{"HDC-5500", "HDC-3500", "HDC-P50"}
+	for i := 0; i < count; i++ {
+		deviceID := generateDeviceID()
+		config := &models.SonyDeviceRequest{
+			DeviceName: fmt.Sprintf("manual-cam-%d", i+1),
+			Model:      sonyModels[i%len(sonyModels)],
+			Settings: map[string]string{
+				"resolution": "1920x1080",
+				"frame_rate": "59.94",
+				"codec":      "H.264",
+			},
+			IPAddress: fmt.Sprintf("10.0.9.%d", 10+i),
+		}
+		store.Add(&models.SonyDeviceResponse{
+			DeviceID:      deviceID,
+			Status:        "active",
+			Message:       "Device configured manually",
+			Model:         config.Model,
+			IPAddress:     config.IPAddress,
+			Configuration: config,
+		})
+		log.Printf("Seeded unmanaged device: %s (name: %s)", deviceID, config.DeviceName)
+	}
+}
+
 // =============================================================================
 // MAIN - MOCK SERVER ENTRY POINT
 // =============================================================================
@@ -240,6 +434,28 @@ func main() {
 	// Without this, you'd get the same "random" numbers every time
 	rand.Seed(time.Now().UnixNano())
 
+	// Optionally seed devices that "already exist" in Sony
+	// WHY: Lets you try discovery/adoption without creating devices by hand
+	if n, err := strconv.Atoi(os.Getenv("MOCK_SEED_DEVICES")); err == nil && n > 0 {
+		seedUnmanagedDevices(n)
+	}
+	if n, err := strconv.Atoi(os.Getenv("MOCK_DEVICE_QUOTA")); err == nil && n > 0 {
+		deviceQuota = n
+	}
+	if d, err := time.ParseDuration(os.Getenv("MOCK_TEARDOWN_TIME")); err == nil && d > 0 {
+		teardownTime = d
+	}
+	if d, err := time.ParseDuration(os.Getenv("MOCK_STORE_LATENCY")); err == nil && d > 0 {
+		store.latency = d
+	}
+	if d, err := time.ParseDuration(os.Getenv("MOCK_STORE_JITTER")); err == nil && d > 0 {
+		store.jitter = d
+	}
+	if d, err := time.ParseDuration(os.Getenv("MOCK_SESSION_TTL")); err == nil && d > 0 {
+		sessionTTL = d
+	}
+	configureSessions(os.Getenv("MOCK_SESSION_USERS"))
+
 	// Set up HTTP router
 	// WHY GORILLA MUX: Supports URL parameters like {id}
 	r := mux.NewRouter()
@@ -250,9 +466,36 @@ func main() {
 	// DELETE /devices/{id} → Delete device
 	// GET /health        → Health check
 	r.HandleFunc("/devices", HandleCreateDevice).Methods("POST")
+	r.HandleFunc("/devices", HandleListDevices).Methods("GET")
+	r.HandleFunc("/devices/preflight", HandlePreflight).Methods("POST")
+	r.HandleFunc("/network/probe", HandleProbeDestination).Methods("POST")
+	r.HandleFunc("/account/capacity", HandleGetCapacity).Methods("GET")
 	r.HandleFunc("/devices/{id}", HandleGetDevice).Methods("GET")
+	r.HandleFunc("/devices/{id}", HandleUpdateDevice).Methods("PATCH")
 	r.HandleFunc("/devices/{id}", HandleDeleteDevice).Methods("DELETE")
+	r.HandleFunc("/devices/{id}/stop", HandleStopDevice).Methods("POST")
+	r.HandleFunc("/devices/{id}/start", HandleStartDevice).Methods("POST")
+	r.HandleFunc("/devices/{id}/restart", HandleRestartDevice).Methods("POST")
+	r.HandleFunc("/devices/{id}/reboot", HandleRebootDevice).Methods("POST")
+	r.HandleFunc("/devices/{id}/stream/start", HandleStartStream).Methods("POST")
+	r.HandleFunc("/devices/{id}/stream/stop", HandleStopStream).Methods("POST")
+	r.HandleFunc("/devices/{id}/recordings", HandleStartRecording).Methods("POST")
+	r.HandleFunc("/devices/{id}/recordings", HandleListRecordings).Methods("GET")
+	r.HandleFunc("/devices/{id}/recordings/{rid}/stop", HandleStopRecording).Methods("POST")
+	r.HandleFunc("/devices/{id}/recordings/{rid}", HandleDeleteRecording).Methods("DELETE")
+	r.HandleFunc("/devices/{id}/ptz", HandleGetPTZ).Methods("GET")
+	r.HandleFunc("/devices/{id}/ptz", HandleMovePTZ).Methods("POST")
+	r.HandleFunc("/devices/{id}/presets", HandleListPresets).Methods("GET")
+	r.HandleFunc("/devices/{id}/presets/{n}", HandleSavePreset).Methods("PUT")
+	r.HandleFunc("/devices/{id}/presets/{n}/recall", HandleRecallPreset).Methods("POST")
+	r.HandleFunc("/devices/{id}/health_metrics", HandleSetHealthMetrics).Methods("PUT")
+	r.HandleFunc("/devices/{id}/settings", HandleExportSettings).Methods("GET")
+	r.HandleFunc("/devices/{id}/settings", HandleImportSettings).Methods("PUT")
+	r.HandleFunc("/devices/{id}/factory_reset", HandleFactoryReset).Methods("POST")
+	r.HandleFunc("/auth/login", HandleLogin).Methods("POST")
+	r.HandleFunc("/auth/sessions", HandleDropSessions).Methods("DELETE")
 	r.HandleFunc("/health", HandleHealthCheck).Methods("GET")
+	r.Use(requireSession)
 
 	// Start the server on port 9000
 	// WHY 9000: Different from controller (8080) so both can run together
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// STOP / START HANDLERS
+// =============================================================================
+// Simulates Sony's power endpoints:
+//
+//   POST /devices/{id}/stop   → "inactive": configuration kept, no output
+//   POST /devices/{id}/start  → "active" again, stream restored
+//
+// Both are idempotent, like the real API.
+// =============================================================================
+
+// HandleStopDevice stops a device's encoder and output.
+func HandleStopDevice(w http.ResponseWriter, r *http.Request) {
+	deviceID := mux.Vars(r)["id"]
+	device, err := store.Update(deviceID, func(device *models.SonyDeviceResponse) {
+		device.Status = "inactive"
+		device.Message = "Device stopped"
+		device.StreamStatus = nil
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	log.Printf("Stopped device: %s", deviceID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(device)
+}
+
+// HandleStartDevice starts a stopped device with its stored configuration.
+func HandleStartDevice(w http.ResponseWriter, r *http.Request) {
+	deviceID := mux.Vars(r)["id"]
+	device, err := store.Update(deviceID, func(device *models.SonyDeviceResponse) {
+		device.Status = "active"
+		device.Message = "Device started"
+		if device.Configuration != nil {
+			device.StreamStatus = simulateStreamStatus(device.Configuration.StreamConfig)
+		}
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	log.Printf("Started device: %s", deviceID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(device)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/Zhichengu1/mock-control-plane/pkg/probe"
+)
+
+// =============================================================================
+// PREFLIGHT HANDLERS
+// =============================================================================
+// Simulates Sony's POST /devices/preflight: validates a device request
+// without provisioning it.
+//
+//   quota    the account holds fewer than MOCK_DEVICE_QUOTA devices
+//   network  a requested IP is private (reachable from the site gateway)
+//            and not used by another device
+//   model    the model is sold in this region
+//
+// GET /account/capacity reports the account's devices against the quota
+// and the DHCP pool devices without an ip_address lease from.
+// =============================================================================
+
+// availableModels are the models the mock "region" offers.
+var availableModels = map[string]bool{
+	"HDC-5500": true, "HDC-3500": true, "HDC-P50": true,
+	"FX3": true, "FX6": true, "FX9": true, "FR7": true,
+	"BRC-X400": true, "SRG-X400": true,
+}
+
+// deviceQuota caps devices per account (MOCK_DEVICE_QUOTA).
+var deviceQuota = 50
+
+// The DHCP pool HandleCreateDevice leases from (10.0.8.10-10.0.8.249).
+const (
+	dhcpPoolPrefix = "10.0.8."
+	dhcpPoolFirst  = 10
+	dhcpPoolSize   = 240
+)
+
+// inDHCPPool reports whether ip is a pool address.
+func inDHCPPool(ip string) bool {
+	host, found := strings.CutPrefix(ip, dhcpPoolPrefix)
+	if !found {
+		return false
+	}
+	n, err := strconv.Atoi(host)
+	return err == nil && n >= dhcpPoolFirst && n < dhcpPoolFirst+dhcpPoolSize
+}
+
+// HandlePreflight checks a device request without creating anything.
+func HandlePreflight(w http.ResponseWriter, r *http.Request) {
+	var req models.SonyDeviceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+
+	response := models.SonyPreflightResponse{Ready: true}
+	add := func(check string, pass bool, detail string) {
+		status := "pass"
+		if !pass {
+			status = "fail"
+			response.Ready = false
+		}
+		response.Checks = append(response.Checks, models.SonyPreflightCheck{Check: check, Status: status, Detail: detail})
+	}
+
+	// Quota
+	devices := store.List()
+	if len(devices) >= deviceQuota {
+		add("quota", false, fmt.Sprintf("account quota of %d devices reached", deviceQuota))
+	} else {
+		add("quota", true, fmt.Sprintf("%d of %d devices in use", len(devices), deviceQuota))
+	}
+
+	// Network
+	switch ip := net.ParseIP(req.IPAddress); {
+	case req.IPAddress == "":
+		add("network", true, "address will be assigned by DHCP")
+	case ip == nil:
+		add("network", false, fmt.Sprintf("%q is not an IP address", req.IPAddress))
+	case !ip.IsPrivate():
+		add("network", false, fmt.Sprintf("%s is not reachable from the site gateway (not a private address)", req.IPAddress))
+	default:
+		inUse := ""
+		for _, device := range devices {
+			if device.IPAddress == req.IPAddress {
+				inUse = device.DeviceID
+				break
+			}
+		}
+		if inUse != "" {
+			add("network", false, fmt.Sprintf("%s is already used by device %s", req.IPAddress, inUse))
+		} else {
+			add("network", true, req.IPAddress+" is reachable")
+		}
+	}
+
+	// Model
+	if availableModels[req.Model] {
+		add("model", true, req.Model+" is available")
+	} else {
+		add("model", false, fmt.Sprintf("model %q is not available in this region", req.Model))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleProbeDestination simulates Sony's POST /network/probe: probes a
+// stream destination from the site network (here, the mock's host).
+func HandleProbeDestination(w http.ResponseWriter, r *http.Request) {
+	var req models.SonyProbeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	result, err := probe.Destination(ctx, req.URL)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.SonyProbeResponse{
+		Reachable: result.Reachable,
+		Method:    result.Method,
+		Address:   result.Address,
+		RTTMs:     result.RTTMillis,
+		Detail:    result.Message,
+	})
+}
+
+// HandleGetCapacity simulates Sony's GET /account/capacity.
+func HandleGetCapacity(w http.ResponseWriter, r *http.Request) {
+	devices := store.List()
+	response := models.SonyCapacityResponse{
+		Devices:     len(devices),
+		DeviceQuota: deviceQuota,
+		DHCPPool: models.SonyDHCPPool{
+			Start: fmt.Sprintf("%s%d", dhcpPoolPrefix, dhcpPoolFirst),
+			End:   fmt.Sprintf("%s%d", dhcpPoolPrefix, dhcpPoolFirst+dhcpPoolSize-1),
+			Size:  dhcpPoolSize,
+		},
+		Addresses: []string{},
+	}
+	leased := make(map[string]bool)
+	for _, device := range devices {
+		if device.IPAddress == "" {
+			continue
+		}
+		response.Addresses = append(response.Addresses, device.IPAddress)
+		if inDHCPPool(device.IPAddress) {
+			leased[device.IPAddress] = true
+		}
+	}
+	response.DHCPPool.Leased = len(leased)
+	sort.Strings(response.Addresses)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// PTZ AND PRESET HANDLERS
+// =============================================================================
+// Simulates Sony's camera head control:
+//
+//   GET  /devices/{id}/ptz                  → current position
+//   POST /devices/{id}/ptz                  → absolute / relative / home / stop
+//   GET  /devices/{id}/presets              → saved presets
+//   PUT  /devices/{id}/presets/{n}          → save current position (0-based n)
+//   POST /devices/{id}/presets/{n}/recall   → move to a preset
+//
+// Moves complete instantly. Like real heads (e.g. BRC-X1000) the range is
+// limited: pan ±170°, tilt -30°..+90°; requests beyond it are clamped.
+// =============================================================================
+
+// Mechanical limits of the simulated camera head.
+const (
+	mockPanLimit    = 170.0
+	mockTiltMin     = -30.0
+	mockTiltMax     = 90.0
+	mockZoomMax     = 16384
+	mockPresetSlots = 100
+)
+
+var (
+	// ptzStates holds the head position per device ID (home = zero value)
+	ptzStates = make(map[string]*models.SonyPTZState)
+	// presets holds saved presets per device ID and slot
+	presets = make(map[string]map[int]models.SonyPreset)
+	ptzMu   sync.Mutex
+)
+
+// ptzStateLocked returns the device's head state. Caller must hold ptzMu.
+func ptzStateLocked(deviceID string) *models.SonyPTZState {
+	state, ok := ptzStates[deviceID]
+	if !ok {
+		state = &models.SonyPTZState{}
+		ptzStates[deviceID] = state
+	}
+	return state
+}
+
+// clampPTZ keeps a position within the head's mechanical limits.
+func clampPTZ(state *models.SonyPTZState) {
+	state.PanDegrees = math.Max(-mockPanLimit, math.Min(mockPanLimit, state.PanDegrees))
+	state.TiltDegrees = math.Max(mockTiltMin, math.Min(mockTiltMax, state.TiltDegrees))
+	if state.ZoomPosition < 0 {
+		state.ZoomPosition = 0
+	}
+	if state.ZoomPosition > mockZoomMax {
+		state.ZoomPosition = mockZoomMax
+	}
+}
+
+// requireDevice writes a 404 and returns false if the device doesn't exist
+// (or a 409 if it is being deleted).
+func requireDevice(w http.ResponseWriter, deviceID string) bool {
+	if err := store.Check(deviceID); err != nil {
+		writeStoreError(w, err)
+		return false
+	}
+	return true
+}
+
+// HandleGetPTZ returns a device's head position.
+func HandleGetPTZ(w http.ResponseWriter, r *http.Request) {
+	deviceID := mux.Vars(r)["id"]
+	if !requireDevice(w, deviceID) {
+		return
+	}
+
+	ptzMu.Lock()
+	state := *ptzStateLocked(deviceID)
+	ptzMu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
+
+// HandleMovePTZ executes a PTZ command.
+func HandleMovePTZ(w http.ResponseWriter, r *http.Request) {
+	deviceID := mux.Vars(r)["id"]
+	if !requireDevice(w, deviceID) {
+		return
+	}
+
+	var req models.SonyPTZRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+	if req.Speed < 0 || req.Speed > 24 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "speed must be 1-24"})
+		return
+	}
+
+	ptzMu.Lock()
+	state := ptzStateLocked(deviceID)
+	switch req.Command {
+	case "absolute":
+		if req.PanDegrees != nil {
+			state.PanDegrees = *req.PanDegrees
+		}
+		if req.TiltDegrees != nil {
+			state.TiltDegrees = *req.TiltDegrees
+		}
+		if req.ZoomPosition != nil {
+			state.ZoomPosition = *req.ZoomPosition
+		}
+	case "relative":
+		if req.PanDegrees != nil {
+			state.PanDegrees += *req.PanDegrees
+		}
+		if req.TiltDegrees != nil {
+			state.TiltDegrees += *req.TiltDegrees
+		}
+		if req.ZoomPosition != nil {
+			state.ZoomPosition += *req.ZoomPosition
+		}
+	case "home":
+		*state = models.SonyPTZState{}
+	case "stop":
+		// Moves are instant in the mock; nothing to stop
+	default:
+		ptzMu.Unlock()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "unknown command: " + req.Command})
+		return
+	}
+	clampPTZ(state)
+	result := *state
+	ptzMu.Unlock()
+
+	log.Printf("PTZ %s on %s → pan %.1f tilt %.1f zoom %d", req.Command, deviceID, result.PanDegrees, result.TiltDegrees, result.ZoomPosition)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// HandleListPresets lists a device's saved presets, lowest slot first.
+func HandleListPresets(w http.ResponseWriter, r *http.Request) {
+	deviceID := mux.Vars(r)["id"]
+	if !requireDevice(w, deviceID) {
+		return
+	}
+
+	ptzMu.Lock()
+	list := models.SonyPresetList{Presets: []models.SonyPreset{}}
+	for _, preset := range presets[deviceID] {
+		list.Presets = append(list.Presets, preset)
+	}
+	ptzMu.Unlock()
+
+	sort.Slice(list.Presets, func(i, j int) bool { return list.Presets[i].PresetNumber < list.Presets[j].PresetNumber })
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// presetSlot parses and range-checks the {n} path variable.
+func presetSlot(w http.ResponseWriter, r *http.Request) (int, bool) {
+	slot, err := strconv.Atoi(mux.Vars(r)["n"])
+	if err != nil || slot < 0 || slot >= mockPresetSlots {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "preset number must be 0-99"})
+		return 0, false
+	}
+	return slot, true
+}
+
+// HandleSavePreset saves the current head position into a preset slot.
+func HandleSavePreset(w http.ResponseWriter, r *http.Request) {
+	deviceID := mux.Vars(r)["id"]
+	if !requireDevice(w, deviceID) {
+		return
+	}
+	slot, ok := presetSlot(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+			return
+		}
+	}
+
+	ptzMu.Lock()
+	preset := models.SonyPreset{
+		PresetNumber: slot,
+		Name:         req.Name,
+		Position:     *ptzStateLocked(deviceID),
+		SavedAt:      time.Now().UTC().Format(time.RFC3339),
+	}
+	if presets[deviceID] == nil {
+		presets[deviceID] = make(map[int]models.SonyPreset)
+	}
+	presets[deviceID][slot] = preset
+	ptzMu.Unlock()
+
+	log.Printf("Saved preset %d (%q) on %s", slot, req.Name, deviceID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preset)
+}
+
+// HandleRecallPreset moves the head to a saved preset.
+func HandleRecallPreset(w http.ResponseWriter, r *http.Request) {
+	deviceID := mux.Vars(r)["id"]
+	if !requireDevice(w, deviceID) {
+		return
+	}
+	slot, ok := presetSlot(w, r)
+	if !ok {
+		return
+	}
+
+	ptzMu.Lock()
+	preset, exists := presets[deviceID][slot]
+	if exists {
+		*ptzStateLocked(deviceID) = preset.Position
+	}
+	ptzMu.Unlock()
+
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "preset " + strconv.Itoa(slot) + " is not set"})
+		return
+	}
+	log.Printf("Recalled preset %d on %s", slot, deviceID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preset.Position)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// RECORDING SESSION HANDLERS
+// =============================================================================
+// Simulates Sony's per-device recording API:
+//
+//   POST   /devices/{id}/recordings             → start a session
+//   POST   /devices/{id}/recordings/{rid}/stop  → stop it
+//   GET    /devices/{id}/recordings             → list sessions
+//   DELETE /devices/{id}/recordings/{rid}       → delete the file
+//
+// A device records one session at a time (409 otherwise), like real
+// cameras with a single recording pipeline.
+// =============================================================================
+
+// mockRecording is a session plus the fields needed to compute its
+// duration and size while it is still recording.
+type mockRecording struct {
+	models.SonyRecording
+	started time.Time
+	stopped time.Time
+}
+
+// recordingBytesPerSecond fakes file growth (~50 Mbps XAVC).
+const recordingBytesPerSecond = 50_000_000 / 8
+
+var (
+	// recordings holds sessions per device ID
+	recordings   = make(map[string][]*mockRecording)
+	recordingsMu sync.Mutex
+)
+
+// snapshot fills in the live duration/size for the response.
+func (rec *mockRecording) snapshot() models.SonyRecording {
+	end := rec.stopped
+	if end.IsZero() {
+		end = time.Now()
+	}
+	out := rec.SonyRecording
+	out.DurationSeconds = end.Sub(rec.started).Seconds()
+	out.SizeBytes = int64(out.DurationSeconds * recordingBytesPerSecond)
+	return out
+}
+
+// HandleStartRecording starts a recording session on a device.
+func HandleStartRecording(w http.ResponseWriter, r *http.Request) {
+	deviceID := mux.Vars(r)["id"]
+	if _, exists := store.Get(deviceID); !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "device not found"})
+		return
+	}
+
+	var req models.SonyRecordingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+	if req.StoragePath == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "storage_path is required"})
+		return
+	}
+
+	recordingsMu.Lock()
+	defer recordingsMu.Unlock()
+
+	for _, rec := range recordings[deviceID] {
+		if rec.Status == "recording" {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]string{"error": "device is already recording " + rec.RecordingID})
+			return
+		}
+	}
+
+	now := time.Now()
+	recordingID := fmt.Sprintf("rec-%d-%04d", now.Unix(), len(recordings[deviceID])+1)
+	name := req.Name
+	if name == "" {
+		name = recordingID
+	}
+	rec := &mockRecording{
+		SonyRecording: models.SonyRecording{
+			RecordingID:   recordingID,
+			DeviceID:      deviceID,
+			Name:          name,
+			Status:        "recording",
+			StoragePath:   req.StoragePath,
+			FilePath:      path.Join(req.StoragePath, name+"."+fileExtension(req.Format)),
+			Format:        req.Format,
+			StartedAt:     now.UTC().Format(time.RFC3339),
+			RetentionDays: req.RetentionDays,
+		},
+		started: now,
+	}
+	recordings[deviceID] = append(recordings[deviceID], rec)
+	log.Printf("Started recording %s on %s → %s", recordingID, deviceID, rec.FilePath)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rec.snapshot())
+}
+
+// HandleStopRecording stops an active recording session.
+func HandleStopRecording(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	recordingsMu.Lock()
+	defer recordingsMu.Unlock()
+
+	rec := findRecording(vars["id"], vars["rid"])
+	if rec == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "recording not found"})
+		return
+	}
+	if rec.Status != "recording" {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "recording is already " + rec.Status})
+		return
+	}
+
+	rec.stopped = time.Now()
+	rec.Status = "completed"
+	rec.StoppedAt = rec.stopped.UTC().Format(time.RFC3339)
+	log.Printf("Stopped recording %s on %s", rec.RecordingID, rec.DeviceID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rec.snapshot())
+}
+
+// HandleListRecordings lists a device's recording sessions, oldest first.
+func HandleListRecordings(w http.ResponseWriter, r *http.Request) {
+	deviceID := mux.Vars(r)["id"]
+	if _, exists := store.Get(deviceID); !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "device not found"})
+		return
+	}
+
+	recordingsMu.Lock()
+	list := models.SonyRecordingList{Recordings: []models.SonyRecording{}}
+	for _, rec := range recordings[deviceID] {
+		list.Recordings = append(list.Recordings, rec.snapshot())
+	}
+	recordingsMu.Unlock()
+
+	sort.Slice(list.Recordings, func(i, j int) bool {
+		return list.Recordings[i].StartedAt < list.Recordings[j].StartedAt
+	})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// HandleDeleteRecording deletes a completed recording.
+func HandleDeleteRecording(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	deviceID, recordingID := vars["id"], vars["rid"]
+
+	recordingsMu.Lock()
+	defer recordingsMu.Unlock()
+
+	rec := findRecording(deviceID, recordingID)
+	if rec == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "recording not found"})
+		return
+	}
+	if rec.Status == "recording" {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "stop the recording before deleting it"})
+		return
+	}
+
+	kept := recordings[deviceID][:0]
+	for _, other := range recordings[deviceID] {
+		if other != rec {
+			kept = append(kept, other)
+		}
+	}
+	recordings[deviceID] = kept
+	log.Printf("Deleted recording %s on %s", recordingID, deviceID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// findRecording looks up a session. Caller must hold recordingsMu.
+func findRecording(deviceID, recordingID string) *mockRecording {
+	for _, rec := range recordings[deviceID] {
+		if rec.RecordingID == recordingID {
+			return rec
+		}
+	}
+	return nil
+}
+
+// fileExtension maps a recording format to its file extension.
+func fileExtension(format string) string {
+	switch format {
+	case "ProRes":
+		return "mov"
+	case "MP4":
+		return "mp4"
+	default:
+		return "mxf" // MXF and XAVC
+	}
+}
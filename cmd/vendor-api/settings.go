@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+	"github.com/gorilla/mux"
+)
+
+// =============================================================================
+// SETTINGS DUMP / FACTORY RESET HANDLERS
+// =============================================================================
+// Simulates Sony's full settings backup:
+//
+//   GET  /devices/{id}/settings       → configuration + preset memories
+//   PUT  /devices/{id}/settings       → load a dump (restore)
+//   POST /devices/{id}/factory_reset  → wipe both, like the service menu
+//
+// A reset device keeps its address and model but loses its stream, sync
+// settings and presets, and stops until it is configured again.
+// =============================================================================
+
+// HandleExportSettings returns a device's settings dump.
+func HandleExportSettings(w http.ResponseWriter, r *http.Request) {
+	deviceID := mux.Vars(r)["id"]
+	device, exists := store.Get(deviceID)
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "device not found"})
+		return
+	}
+
+	dump := models.SonySettingsDump{
+		Model:           device.Model,
+		FirmwareVersion: device.FirmwareVersion,
+		Configuration:   device.Configuration,
+		Presets:         []models.SonyPreset{},
+		ExportedAt:      time.Now().UTC().Format(time.RFC3339),
+	}
+	ptzMu.Lock()
+	for _, preset := range presets[deviceID] {
+		dump.Presets = append(dump.Presets, preset)
+	}
+	ptzMu.Unlock()
+	sort.Slice(dump.Presets, func(i, j int) bool { return dump.Presets[i].PresetNumber < dump.Presets[j].PresetNumber })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dump)
+}
+
+// HandleImportSettings loads a settings dump onto a device.
+func HandleImportSettings(w http.ResponseWriter, r *http.Request) {
+	deviceID := mux.Vars(r)["id"]
+	if !requireDevice(w, deviceID) {
+		return
+	}
+
+	var dump models.SonySettingsDump
+	if err := json.NewDecoder(r.Body).Decode(&dump); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+	for _, preset := range dump.Presets {
+		if preset.PresetNumber < 0 || preset.PresetNumber >= mockPresetSlots {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "preset number must be 0-99"})
+			return
+		}
+	}
+
+	device, err := store.Update(deviceID, func(device *models.SonyDeviceResponse) {
+		if dump.Configuration != nil {
+			reconfigureDevice(device, *dump.Configuration)
+		}
+		device.Message = "Settings restored"
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	ptzMu.Lock()
+	restored := make(map[int]models.SonyPreset, len(dump.Presets))
+	for _, preset := range dump.Presets {
+		restored[preset.PresetNumber] = preset
+	}
+	presets[deviceID] = restored
+	ptzMu.Unlock()
+	log.Printf("Restored settings on device: %s (%d presets)", deviceID, len(dump.Presets))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(device)
+}
+
+// HandleFactoryReset wipes a device's settings and presets.
+func HandleFactoryReset(w http.ResponseWriter, r *http.Request) {
+	deviceID := mux.Vars(r)["id"]
+	device, err := store.Update(deviceID, func(device *models.SonyDeviceResponse) {
+		reset := &models.SonyDeviceRequest{Model: device.Model, IPAddress: device.IPAddress}
+		if device.Configuration != nil {
+			reset.DeviceName = device.Configuration.DeviceName
+		}
+		device.Configuration = reset
+		device.StreamStatus = nil
+		device.SyncStatus = nil
+		device.Status = "inactive"
+		device.Message = "Factory defaults restored"
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	ptzMu.Lock()
+	delete(presets, deviceID)
+	delete(ptzStates, deviceID)
+	ptzMu.Unlock()
+	log.Printf("Factory reset device: %s", deviceID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(device)
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// DEVICE STORE
+// =============================================================================
+// Every handler reads or changes devices, and net/http runs handlers
+// concurrently: a controller load test used to crash the mock with
+// "concurrent map writes". The store owns the devices (and their
+// teardowns) behind one mutex and hands out copies, so a response being
+// encoded never races the next update.
+//
+// Real vendor databases are slow under load. MOCK_STORE_LATENCY (plus up
+// to MOCK_STORE_JITTER) is spent inside the lock on every operation, so
+// concurrent requests queue behind each other like rows behind a lock:
+//
+//   MOCK_STORE_LATENCY=20ms MOCK_STORE_JITTER=30ms
+// =============================================================================
+
+// Store errors.
+var (
+	errDeviceNotFound = errors.New("device not found")
+	errDeviceDeleting = errors.New("device is being deleted")
+)
+
+// deviceStore is the mock's in-memory "database".
+type deviceStore struct {
+	mu      sync.Mutex
+	devices map[string]*models.SonyDeviceResponse
+
+	// teardowns maps device ID → when its teardown started (teardown.go)
+	teardowns map[string]time.Time
+
+	// latency and jitter are the artificial contention per operation
+	latency time.Duration
+	jitter  time.Duration
+}
+
+// store holds every device of the mock.
+// WHY GLOBAL: All handlers need access to the same data
+// NOTE: Data is lost when server restarts (that's fine for testing)
+var store = &deviceStore{
+	devices:   make(map[string]*models.SonyDeviceResponse),
+	teardowns: make(map[string]time.Time),
+}
+
+// lock takes the store's lock, spends the configured contention and
+// advances teardowns. Callers must unlock s.mu.
+func (s *deviceStore) lock() {
+	s.mu.Lock()
+	if delay := s.latency; delay > 0 || s.jitter > 0 {
+		if s.jitter > 0 {
+			delay += time.Duration(rand.Int63n(int64(s.jitter)))
+		}
+		time.Sleep(delay)
+	}
+	s.advanceTeardownsLocked()
+}
+
+// cloneDevice deep-copies a device so callers can use it without the lock.
+// WHY JSON: Devices are only ever served as JSON, and it copies every
+// nested pointer, map and slice
+func cloneDevice(device *models.SonyDeviceResponse) *models.SonyDeviceResponse {
+	data, err := json.Marshal(device)
+	if err != nil {
+		log.Printf("Failed to copy device %s: %v", device.DeviceID, err)
+		return device
+	}
+	var out models.SonyDeviceResponse
+	json.Unmarshal(data, &out)
+	return &out
+}
+
+// Add stores a new device.
+func (s *deviceStore) Add(device *models.SonyDeviceResponse) {
+	device = cloneDevice(device)
+	s.lock()
+	defer s.mu.Unlock()
+	s.devices[device.DeviceID] = device
+}
+
+// Get returns a copy of a device (including one being deleted).
+func (s *deviceStore) Get(deviceID string) (*models.SonyDeviceResponse, bool) {
+	s.lock()
+	defer s.mu.Unlock()
+	device, exists := s.devices[deviceID]
+	if !exists {
+		return nil, false
+	}
+	return cloneDevice(device), true
+}
+
+// Check returns errDeviceNotFound or errDeviceDeleting if a device can't
+// be operated on.
+func (s *deviceStore) Check(deviceID string) error {
+	s.lock()
+	defer s.mu.Unlock()
+	return s.checkLocked(deviceID)
+}
+
+func (s *deviceStore) checkLocked(deviceID string) error {
+	if _, exists := s.devices[deviceID]; !exists {
+		return errDeviceNotFound
+	}
+	if _, deleting := s.teardowns[deviceID]; deleting {
+		return errDeviceDeleting
+	}
+	return nil
+}
+
+// List returns copies of all devices, sorted by ID.
+func (s *deviceStore) List() []models.SonyDeviceResponse {
+	s.lock()
+	defer s.mu.Unlock()
+	list := make([]models.SonyDeviceResponse, 0, len(s.devices))
+	for _, device := range s.devices {
+		list = append(list, *cloneDevice(device))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].DeviceID < list[j].DeviceID })
+	return list
+}
+
+// Count returns the number of devices.
+func (s *deviceStore) Count() int {
+	s.lock()
+	defer s.mu.Unlock()
+	return len(s.devices)
+}
+
+// Update applies mutate to a device that is not being deleted and returns
+// a copy of the result. mutate runs with the lock held.
+func (s *deviceStore) Update(deviceID string, mutate func(device *models.SonyDeviceResponse)) (*models.SonyDeviceResponse, error) {
+	s.lock()
+	defer s.mu.Unlock()
+	if err := s.checkLocked(deviceID); err != nil {
+		return nil, err
+	}
+	device := s.devices[deviceID]
+	mutate(device)
+	return cloneDevice(device), nil
+}
+
+// Delete removes a device at once.
+func (s *deviceStore) Delete(deviceID string) error {
+	s.lock()
+	defer s.mu.Unlock()
+	if _, exists := s.devices[deviceID]; !exists {
+		return errDeviceNotFound
+	}
+	delete(s.devices, deviceID)
+	delete(s.teardowns, deviceID)
+	return nil
+}
+
+// writeStoreError writes the response for a store error.
+func writeStoreError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errDeviceDeleting) {
+		w.WriteHeader(http.StatusConflict)
+	} else {
+		w.WriteHeader(http.StatusNotFound)
+	}
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
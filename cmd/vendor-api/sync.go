@@ -0,0 +1,51 @@
+package main
+
+import (
+	"math/rand"
+	"net"
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// SYNC STATUS SIMULATION
+// =============================================================================
+// Devices report lock state for the timecode, NTP and genlock references
+// in their settings. Everything locks, except:
+//
+//   ntp_server under ".invalid" → never synchronizes (RFC 6761 reserves
+//                                 the name, so it can't be a real server),
+//                                 and NTP timecode can't lock either
+//
+// so losing sync can be exercised end to end.
+// =============================================================================
+
+// simulateSyncStatus reports lock state for the configured references
+// (nil if none are configured).
+func simulateSyncStatus(settings map[string]string) *models.SonySyncStatus {
+	timecode, ntp, genlock := settings["timecode_source"], settings["ntp_server"], settings["genlock_reference"]
+	if timecode == "" && ntp == "" && genlock == "" {
+		return nil
+	}
+	sync := &models.SonySyncStatus{
+		TimecodeSource:   timecode,
+		NTPServer:        ntp,
+		GenlockReference: genlock,
+		GenlockLocked:    genlock != "",
+	}
+	if ntp != "" {
+		host := ntp
+		if h, _, err := net.SplitHostPort(ntp); err == nil {
+			host = h
+		}
+		sync.NTPSynced = !strings.HasSuffix(strings.ToLower(host), ".invalid")
+		if sync.NTPSynced {
+			sync.NTPOffsetMs = float64(rand.Intn(400)-200) / 100
+		}
+	}
+	if timecode != "" {
+		sync.TimecodeLocked = timecode != "NTP" || sync.NTPSynced
+	}
+	return sync
+}
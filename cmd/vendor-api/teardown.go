@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// SLOW TEARDOWN
+// =============================================================================
+// Real cloud channels take minutes to delete: inputs are released, the
+// encoder drains, billing stops. With MOCK_TEARDOWN_TIME set, a DELETE
+// returns 202 and the device lingers as "deleting" with a
+// teardown_progress percentage until the time is up, then reads 404.
+//
+// WHY NO TIMER: Teardowns are advanced whenever the store is used (see
+// deviceStore.lock), so devices only change under the store's lock.
+// =============================================================================
+
+// teardownTime is how long a deleted device lingers (0 = deleted at once)
+var teardownTime time.Duration
+
+// beginTeardown starts (or reports) the teardown of deviceID and writes
+// 202 Accepted with the device.
+func beginTeardown(w http.ResponseWriter, deviceID string) {
+	device, err := store.BeginTeardown(deviceID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(device)
+}
+
+// BeginTeardown marks a device "deleting" (once) and returns a copy.
+func (s *deviceStore) BeginTeardown(deviceID string) (*models.SonyDeviceResponse, error) {
+	s.lock()
+	defer s.mu.Unlock()
+	device, exists := s.devices[deviceID]
+	if !exists {
+		return nil, errDeviceNotFound
+	}
+	if _, started := s.teardowns[deviceID]; !started {
+		s.teardowns[deviceID] = time.Now()
+		device.Status = "deleting"
+		device.Message = "Releasing inputs and draining encoder"
+		device.StreamStatus = nil
+		log.Printf("Deleting device: %s (teardown takes %s)", deviceID, teardownTime)
+	}
+	return cloneDevice(device), nil
+}
+
+// advanceTeardownsLocked removes devices whose teardown is over and
+// updates the progress of the rest. Caller must hold s.mu.
+func (s *deviceStore) advanceTeardownsLocked() {
+	now := time.Now()
+	for deviceID, started := range s.teardowns {
+		elapsed := now.Sub(started)
+		if elapsed >= teardownTime {
+			delete(s.devices, deviceID)
+			delete(s.teardowns, deviceID)
+			log.Printf("Deleted device: %s", deviceID)
+			continue
+		}
+		if device, exists := s.devices[deviceID]; exists {
+			device.TeardownProgress = int(elapsed * 100 / teardownTime)
+		}
+	}
+}
@@ -2,6 +2,9 @@ module github.com/Zhichengu1/mock-control-plane
 
 go 1.25.6
 
-require github.com/gorilla/mux v1.8.1
+require (
+	github.com/gorilla/mux v1.8.1
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 replace github.com/Zhichengu1/mock-control-plane => .
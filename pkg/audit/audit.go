@@ -0,0 +1,250 @@
+package audit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// AUDIT TRAIL
+// =============================================================================
+// The audit log is an append-only, bounded record of security- and
+// compliance-relevant activity. Its first job is proving what the
+// controller sent to vendors: every outbound vendor HTTP call is recorded
+// with its method, URL, a SHA-256 of the payload, the response status and
+// duration, and the ID of the API request that triggered it.
+//
+// WHAT IS NOT STORED:
+// - Request/response bodies (only their hash and size)
+// - Headers (Authorization carries vendor credentials)
+// - Secret-looking query parameters (values are redacted)
+//
+// The hash is enough to prove a specific payload was sent: anyone holding
+// the original payload can recompute it and compare. Entries are chained
+// by hash so that changes to the log itself show (see chain.go).
+// =============================================================================
+
+// Entry kinds.
+const (
+	// KindVendorCall is an outbound HTTP call to a vendor API.
+	KindVendorCall = "vendor-call"
+
+	// KindPassthrough is an admin's raw request forwarded to a vendor.
+	// The forwarded call itself is also recorded as a KindVendorCall
+	// with the same request ID.
+	KindPassthrough = "passthrough"
+)
+
+// Entry is one audit record.
+type Entry struct {
+	// ID is a sequence number unique within this log.
+	ID int64 `json:"id"`
+
+	// Time is when the recorded activity started.
+	Time time.Time `json:"time"`
+
+	// Kind categorizes the entry (e.g. KindVendorCall).
+	Kind string `json:"kind"`
+
+	// RequestID links the entry to the API request that caused it.
+	RequestID string `json:"request_id,omitempty"`
+
+	// ResourceID is the Forge resource involved, when known.
+	ResourceID string `json:"resource_id,omitempty"`
+
+	// Actor is the authenticated principal responsible, when known.
+	Actor string `json:"actor,omitempty"`
+
+	// Method and URL describe the call. URL has secrets redacted.
+	Method string `json:"method,omitempty"`
+	URL    string `json:"url,omitempty"`
+
+	// PayloadSHA256 is the hex SHA-256 of the request body ("" if none).
+	PayloadSHA256 string `json:"payload_sha256,omitempty"`
+
+	// PayloadBytes is the request body size.
+	PayloadBytes int `json:"payload_bytes,omitempty"`
+
+	// StatusCode is the HTTP response status (0 if no response).
+	StatusCode int `json:"status_code,omitempty"`
+
+	// DurationMS is how long the call took in milliseconds.
+	DurationMS int64 `json:"duration_ms"`
+
+	// Attempt is the 1-based retry attempt number.
+	Attempt int `json:"attempt,omitempty"`
+
+	// Error holds the transport error, if the call failed without a response.
+	Error string `json:"error,omitempty"`
+
+	// PrevHash is the Hash of the entry before this one, and Hash the
+	// SHA-256 of this entry (Hash left empty). See chain.go.
+	PrevHash string `json:"prev_hash,omitempty"`
+	Hash     string `json:"hash,omitempty"`
+}
+
+// Filter selects entries from the log. Zero values match everything.
+type Filter struct {
+	RequestID  string
+	ResourceID string
+	Kind       string
+	Actor      string
+	Since      time.Time
+	// Limit caps the number of (most recent) entries returned.
+	Limit int
+}
+
+// Log is a bounded in-memory audit log. When full, the oldest entries
+// are discarded. Safe for concurrent use.
+type Log struct {
+	mu         sync.RWMutex
+	maxEntries int
+	nextID     int64
+	entries    []Entry
+
+	// head is the Hash of the newest entry; anchor the PrevHash of the
+	// oldest retained one (the Hash of the last entry dropped)
+	head   string
+	anchor string
+
+	// signingKey signs a checkpoint every checkpointEvery entries (no
+	// checkpoints without a key)
+	signingKey      []byte
+	checkpointEvery int64
+	checkpoints     []Checkpoint
+}
+
+// NewLog creates a Log retaining at most maxEntries entries.
+func NewLog(maxEntries int) *Log {
+	return &Log{maxEntries: maxEntries, nextID: 1}
+}
+
+// Record appends an entry, assigning its ID. Returns the stored entry.
+func (l *Log) Record(e Entry) Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e.ID = l.nextID
+	l.nextID++
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	l.chain(&e)
+	l.entries = append(l.entries, e)
+	if l.maxEntries > 0 && len(l.entries) > l.maxEntries {
+		// WHY COPY: Re-slicing alone would keep the old backing array alive
+		trimmed := make([]Entry, l.maxEntries)
+		copy(trimmed, l.entries[len(l.entries)-l.maxEntries:])
+		l.entries = trimmed
+		l.anchor = trimmed[0].PrevHash
+	}
+	return e
+}
+
+// Query returns entries matching f, oldest first.
+func (l *Log) Query(f Filter) []Entry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	result := []Entry{}
+	for _, e := range l.entries {
+		if f.RequestID != "" && e.RequestID != f.RequestID {
+			continue
+		}
+		if f.ResourceID != "" && e.ResourceID != f.ResourceID {
+			continue
+		}
+		if f.Kind != "" && e.Kind != f.Kind {
+			continue
+		}
+		if f.Actor != "" && e.Actor != f.Actor {
+			continue
+		}
+		if !f.Since.IsZero() && e.Time.Before(f.Since) {
+			continue
+		}
+		result = append(result, e)
+	}
+	if f.Limit > 0 && len(result) > f.Limit {
+		result = result[len(result)-f.Limit:]
+	}
+	return result
+}
+
+// Trim discards all but the newest keep entries and returns how many
+// were dropped. Used to shed memory under pressure.
+func (l *Log) Trim(keep int) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if keep < 0 {
+		keep = 0
+	}
+	if len(l.entries) <= keep {
+		return 0
+	}
+	dropped := len(l.entries) - keep
+	l.anchor = l.entries[dropped-1].Hash
+	trimmed := make([]Entry, keep)
+	copy(trimmed, l.entries[dropped:])
+	l.entries = trimmed
+	return dropped
+}
+
+// Prune discards entries older than before (zero = no age limit) and then
+// all but the newest keep (0 = no count limit), returning what was
+// dropped, oldest first. Used by scheduled compaction.
+func (l *Log) Prune(before time.Time, keep int) []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	drop := 0
+	if !before.IsZero() {
+		for drop < len(l.entries) && l.entries[drop].Time.Before(before) {
+			drop++
+		}
+	}
+	if keep > 0 && len(l.entries)-drop > keep {
+		drop = len(l.entries) - keep
+	}
+	if drop == 0 {
+		return nil
+	}
+	dropped := make([]Entry, drop)
+	copy(dropped, l.entries[:drop])
+	l.anchor = dropped[drop-1].Hash
+	// WHY COPY: Re-slicing alone would keep the old backing array alive
+	kept := make([]Entry, len(l.entries)-drop)
+	copy(kept, l.entries[drop:])
+	l.entries = kept
+	return dropped
+}
+
+// Len returns the number of retained entries.
+func (l *Log) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.entries)
+}
+
+// =============================================================================
+// REQUEST ID PROPAGATION
+// =============================================================================
+// The controller assigns every API request an ID and stores it in the
+// context. Everything downstream (providers, the HTTP client) reads it
+// from the context so vendor calls can be tied back to the API request.
+// =============================================================================
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the API request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the API request ID stored in ctx, or "".
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
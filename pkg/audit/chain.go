@@ -0,0 +1,234 @@
+package audit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// =============================================================================
+// HASH CHAIN AND SIGNED CHECKPOINTS
+// =============================================================================
+// An audit log is only evidence if changes to it show. Every entry
+// carries the hash of the entry before it:
+//
+//   hash = hex(SHA-256(JSON of the entry with "hash" empty))
+//
+// so changing, removing or reordering an entry breaks the chain from that
+// point on. The chain alone can be rewritten by whoever can rewrite the
+// whole log; with a signing key, a checkpoint (entry ID, hash, time,
+// HMAC-SHA256 signature) is signed every N entries, and the rewritten
+// chain no longer matches them without the key.
+//
+// When entries are dropped (AUDIT_MAX_ENTRIES, compaction, memory
+// pressure) the oldest retained entry keeps the hash of the last dropped
+// one in prev_hash: the anchor the verification starts from.
+//
+// Verify checks the live log; VerifyChain checks an exported one (GET
+// /audit, unfiltered, and its checkpoints) without access to the log.
+// =============================================================================
+
+// maxCheckpoints caps the checkpoints kept. They outlive the entries they
+// cover: a checkpoint of a dropped entry still vouches for the anchor.
+const maxCheckpoints = 1000
+
+// Checkpoint is a signed statement of the chain's head at one entry.
+type Checkpoint struct {
+	EntryID int64     `json:"entry_id"`
+	Hash    string    `json:"hash"`
+	Time    time.Time `json:"time"`
+
+	// Signature is the hex HMAC-SHA256 of "<entry_id>|<hash>|<time>"
+	// (time in RFC 3339 with nanoseconds, UTC)
+	Signature string `json:"signature"`
+}
+
+// Problem is one finding of a verification.
+type Problem struct {
+	EntryID int64  `json:"entry_id,omitempty"`
+	Check   string `json:"check"`
+	Message string `json:"message"`
+}
+
+// Verification checks, as reported in problems.
+const (
+	CheckHash                = "hash"
+	CheckChain               = "chain"
+	CheckSequence            = "sequence"
+	CheckHead                = "head"
+	CheckCheckpointSignature = "checkpoint-signature"
+	CheckCheckpoint          = "checkpoint"
+)
+
+// Verification is the outcome of verifying a log.
+type Verification struct {
+	Valid   bool  `json:"valid"`
+	Entries int   `json:"entries"`
+	FirstID int64 `json:"first_id,omitempty"`
+	LastID  int64 `json:"last_id,omitempty"`
+
+	// Anchor is the prev_hash the chain starts from, Head its last hash
+	Anchor string `json:"anchor,omitempty"`
+	Head   string `json:"head,omitempty"`
+
+	// Checkpoints is how many were checked; Covered how many of them name
+	// a retained entry (the others only have their signature checked)
+	Signed      bool `json:"signed"`
+	Checkpoints int  `json:"checkpoints"`
+	Covered     int  `json:"covered"`
+
+	Problems []Problem `json:"problems"`
+}
+
+// HashEntry returns the chain hash of e (its Hash field is ignored).
+func HashEntry(e Entry) string {
+	e.Hash = ""
+	// WHY JSON: Exported entries can be hashed again by anyone; struct
+	// fields marshal in a fixed order
+	data, _ := json.Marshal(e)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// checkpointSignature signs cp with key.
+func checkpointSignature(cp Checkpoint, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%d|%s|%s", cp.EntryID, cp.Hash, cp.Time.UTC().Format(time.RFC3339Nano))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SetSigning signs a checkpoint every every entries with key, from the
+// next entry on. An empty key turns checkpoints off.
+func (l *Log) SetSigning(key []byte, every int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.signingKey = key
+	l.checkpointEvery = int64(every)
+}
+
+// chain links e to the log's head, and signs a checkpoint when one is
+// due. Caller must hold l.mu.
+func (l *Log) chain(e *Entry) {
+	e.PrevHash = l.head
+	e.Hash = HashEntry(*e)
+	l.head = e.Hash
+	if len(l.signingKey) > 0 && l.checkpointEvery > 0 && e.ID%l.checkpointEvery == 0 {
+		l.checkpointLocked(*e)
+	}
+}
+
+// checkpointLocked signs a checkpoint at e. Caller must hold l.mu.
+func (l *Log) checkpointLocked(e Entry) Checkpoint {
+	cp := Checkpoint{EntryID: e.ID, Hash: e.Hash, Time: time.Now().UTC()}
+	cp.Signature = checkpointSignature(cp, l.signingKey)
+	l.checkpoints = append(l.checkpoints, cp)
+	if len(l.checkpoints) > maxCheckpoints {
+		// WHY COPY: Re-slicing alone would keep the old backing array alive
+		kept := make([]Checkpoint, maxCheckpoints)
+		copy(kept, l.checkpoints[len(l.checkpoints)-maxCheckpoints:])
+		l.checkpoints = kept
+	}
+	return cp
+}
+
+// Checkpoint signs a checkpoint at the newest entry now. Returns false
+// without a signing key or entries.
+func (l *Log) Checkpoint() (Checkpoint, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.signingKey) == 0 || len(l.entries) == 0 {
+		return Checkpoint{}, false
+	}
+	last := l.entries[len(l.entries)-1]
+	if n := len(l.checkpoints); n > 0 && l.checkpoints[n-1].EntryID == last.ID {
+		return l.checkpoints[n-1], true
+	}
+	return l.checkpointLocked(last), true
+}
+
+// Checkpoints returns the retained checkpoints, oldest first.
+func (l *Log) Checkpoints() []Checkpoint {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append([]Checkpoint{}, l.checkpoints...)
+}
+
+// Verify checks the live log: the chain of the retained entries, that it
+// ends at the log's head, and the checkpoints.
+func (l *Log) Verify() Verification {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	v := VerifyChain(l.entries, l.checkpoints, l.signingKey)
+	if v.Entries == 0 {
+		return v
+	}
+	if v.Head != l.head {
+		v.Problems = append(v.Problems, Problem{EntryID: v.LastID, Check: CheckHead,
+			Message: "the newest entry isn't the head of the chain (entries were removed from the end)"})
+	}
+	if v.Anchor != l.anchor {
+		v.Problems = append(v.Problems, Problem{EntryID: v.FirstID, Check: CheckChain,
+			Message: "the oldest entry doesn't follow the last entry dropped"})
+	}
+	v.Valid = len(v.Problems) == 0
+	return v
+}
+
+// VerifyChain checks entries (consecutive, oldest first): every hash, the
+// links between them and their IDs, and checkpoints against the entries
+// they name. Signatures are checked only with key.
+func VerifyChain(entries []Entry, checkpoints []Checkpoint, key []byte) Verification {
+	v := Verification{Entries: len(entries), Signed: len(key) > 0, Checkpoints: len(checkpoints), Problems: []Problem{}}
+	byID := make(map[int64]Entry, len(entries))
+	for i, e := range entries {
+		if i == 0 {
+			v.FirstID, v.Anchor = e.ID, e.PrevHash
+		} else {
+			previous := entries[i-1]
+			if e.ID != previous.ID+1 {
+				v.Problems = append(v.Problems, Problem{EntryID: e.ID, Check: CheckSequence,
+					Message: fmt.Sprintf("entry %d follows entry %d (entries are missing or out of order)", e.ID, previous.ID)})
+			}
+			if e.PrevHash != previous.Hash {
+				v.Problems = append(v.Problems, Problem{EntryID: e.ID, Check: CheckChain,
+					Message: fmt.Sprintf("prev_hash doesn't match the hash of entry %d", previous.ID)})
+			}
+		}
+		if HashEntry(e) != e.Hash {
+			v.Problems = append(v.Problems, Problem{EntryID: e.ID, Check: CheckHash,
+				Message: "the entry was changed after it was recorded"})
+		}
+		byID[e.ID] = e
+		v.LastID, v.Head = e.ID, e.Hash
+	}
+	for _, cp := range checkpoints {
+		if len(key) > 0 && !hmac.Equal([]byte(checkpointSignature(cp, key)), []byte(cp.Signature)) {
+			v.Problems = append(v.Problems, Problem{EntryID: cp.EntryID, Check: CheckCheckpointSignature,
+				Message: fmt.Sprintf("the checkpoint at entry %d has an invalid signature", cp.EntryID)})
+			continue
+		}
+		e, retained := byID[cp.EntryID]
+		if !retained {
+			// WHY THE ANCHOR: The entry right after a dropped checkpoint
+			// entry must link to it
+			if cp.EntryID == v.FirstID-1 {
+				v.Covered++
+				if cp.Hash != v.Anchor {
+					v.Problems = append(v.Problems, Problem{EntryID: v.FirstID, Check: CheckCheckpoint,
+						Message: fmt.Sprintf("prev_hash doesn't match the checkpoint at entry %d", cp.EntryID)})
+				}
+			}
+			continue
+		}
+		v.Covered++
+		if e.Hash != cp.Hash {
+			v.Problems = append(v.Problems, Problem{EntryID: cp.EntryID, Check: CheckCheckpoint,
+				Message: fmt.Sprintf("the chain at entry %d differs from its signed checkpoint", cp.EntryID)})
+		}
+	}
+	v.Valid = len(v.Problems) == 0
+	return v
+}
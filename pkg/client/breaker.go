@@ -0,0 +1,243 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// PER-HOST CIRCUIT BREAKER AND CONNECTION RESET
+// =============================================================================
+// A vendor may be served by several hosts (regional endpoints, an
+// active/standby pair). Failures are tracked per host, so one bad host is
+// cut off without affecting the others:
+//
+//   closed    → requests flow; FailureThreshold consecutive failures
+//               (transport errors or 5xx) open the circuit
+//   open      → requests fail fast with ErrCircuitOpen for OpenFor
+//   half-open → one probe request is let through; success closes the
+//               circuit, failure opens it again
+//
+// WHY FAIL FAST: Without it every call to a dead host burns its full
+// retry budget (4 attempts with backoff) while holding a vendor slot.
+//
+// CONNECTION RESET (stale DNS): After a failover the vendor's DNS name
+// points at a new address, but pooled keep-alive connections still go to
+// the old one. After ConnResetAfter consecutive transport-level failures
+// the host's connection pool is replaced, so the next request dials
+// again and re-resolves the name.
+// =============================================================================
+
+// ErrCircuitOpen is returned (wrapped in a *CircuitOpenError) when a
+// request is refused because the host's circuit is open.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// Circuit states.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// CircuitOpenError reports a refused request and when to try again.
+type CircuitOpenError struct {
+	Host string
+	// RetryAfter is how long until the circuit lets a probe through
+	// (0 while a half-open probe is in flight).
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("circuit open for %s (retry in %s)", e.Host, e.RetryAfter.Round(time.Second))
+	}
+	return fmt.Sprintf("circuit open for %s (probe in progress)", e.Host)
+}
+
+func (e *CircuitOpenError) Unwrap() error { return ErrCircuitOpen }
+
+// BreakerConfig tunes the per-host breaker.
+type BreakerConfig struct {
+	// FailureThreshold consecutive failures open the circuit (0 disables
+	// the breaker).
+	FailureThreshold int
+
+	// OpenFor is how long an open circuit refuses requests before probing.
+	OpenFor time.Duration
+
+	// ConnResetAfter consecutive transport-level failures replace the
+	// host's connection pool (0 disables resets).
+	ConnResetAfter int
+}
+
+// DefaultBreakerConfig is used until SetBreakerConfig is called.
+var DefaultBreakerConfig = BreakerConfig{FailureThreshold: 5, OpenFor: 30 * time.Second, ConnResetAfter: 3}
+
+// HostState is a snapshot of one host's breaker.
+type HostState struct {
+	Host                string    `json:"host"`
+	State               string    `json:"state"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	Requests            int64     `json:"requests"`
+	Failures            int64     `json:"failures"`
+	Trips               int64     `json:"trips"`
+	ConnResets          int64     `json:"conn_resets"`
+	OpenUntil           time.Time `json:"open_until,omitempty"`
+	LastError           string    `json:"last_error,omitempty"`
+}
+
+// hostBreaker tracks one host. Guarded by breakersMu.
+type hostBreaker struct {
+	HostState
+	connFailures int
+	probing      bool
+	transport    *http.Transport
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   = make(map[string]*hostBreaker)
+	breakerCfg = DefaultBreakerConfig
+)
+
+// SetBreakerConfig replaces the breaker settings for all hosts.
+func SetBreakerConfig(cfg BreakerConfig) {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	breakerCfg = cfg
+}
+
+// HostStates returns a snapshot of every host seen so far, sorted by host.
+func HostStates() []HostState {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	states := make([]HostState, 0, len(breakers))
+	for _, b := range breakers {
+		states = append(states, b.HostState)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Host < states[j].Host })
+	return states
+}
+
+// hostLocked returns the host's breaker, creating it on first use.
+// Caller must hold breakersMu.
+func hostLocked(host string) *hostBreaker {
+	b, ok := breakers[host]
+	if !ok {
+		b = &hostBreaker{HostState: HostState{Host: host, State: CircuitClosed}}
+		breakers[host] = b
+	}
+	return b
+}
+
+// transportFor returns the host's own connection pool.
+// WHY PER HOST: Resetting one host's pool must not drop healthy
+// connections to other vendors.
+func transportFor(host string) *http.Transport {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	b := hostLocked(host)
+	if b.transport == nil {
+		b.transport = newTransport()
+	}
+	return b.transport
+}
+
+func newTransport() *http.Transport {
+	if base, ok := http.DefaultTransport.(*http.Transport); ok {
+		return base.Clone()
+	}
+	return &http.Transport{}
+}
+
+// allowRequest returns a *CircuitOpenError if the host's circuit refuses
+// the request.
+func allowRequest(host string) error {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	if breakerCfg.FailureThreshold <= 0 {
+		return nil
+	}
+	b := hostLocked(host)
+	now := currentClock().Now()
+	switch b.State {
+	case CircuitOpen:
+		if now.Before(b.OpenUntil) {
+			return &CircuitOpenError{Host: host, RetryAfter: b.OpenUntil.Sub(now)}
+		}
+		b.State = CircuitHalfOpen
+		b.probing = true
+		logger.Infof("Circuit for %s half-open: sending probe request", host)
+	case CircuitHalfOpen:
+		if b.probing {
+			return &CircuitOpenError{Host: host}
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// recordOutcome updates the host's breaker after an attempt.
+func recordOutcome(req *http.Request, resp *http.Response, err error) {
+	host := req.URL.Host
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	b := hostLocked(host)
+
+	// WHY IGNORE: The caller gave up (cancelled or timed out its context);
+	// that says nothing about the host
+	if err != nil && req.Context().Err() != nil {
+		b.probing = false
+		return
+	}
+	// Nor does a redirect the egress policy refused: the host answered
+	if errors.Is(err, ErrEgressDenied) {
+		b.probing = false
+		return
+	}
+	b.Requests++
+
+	if err == nil && resp.StatusCode < 500 {
+		if b.State != CircuitClosed {
+			logger.Infof("Circuit for %s closed: host recovered", host)
+		}
+		b.State = CircuitClosed
+		b.ConsecutiveFailures = 0
+		b.connFailures = 0
+		b.probing = false
+		b.OpenUntil = time.Time{}
+		return
+	}
+
+	b.Failures++
+	b.ConsecutiveFailures++
+	if err != nil {
+		b.LastError = err.Error()
+		b.connFailures++
+		if breakerCfg.ConnResetAfter > 0 && b.connFailures >= breakerCfg.ConnResetAfter && b.transport != nil {
+			// In-flight requests finish on the old pool; new ones dial afresh
+			b.transport.CloseIdleConnections()
+			b.transport = newTransport()
+			b.connFailures = 0
+			b.ConnResets++
+			logger.Warnf("Reset connections to %s after repeated connection failures (re-resolving DNS)", host)
+		}
+	} else {
+		b.LastError = fmt.Sprintf("HTTP %d", resp.StatusCode)
+	}
+
+	if breakerCfg.FailureThreshold > 0 &&
+		(b.State == CircuitHalfOpen || b.ConsecutiveFailures >= breakerCfg.FailureThreshold) {
+		now := currentClock().Now()
+		b.State = CircuitOpen
+		b.OpenUntil = now.Add(breakerCfg.OpenFor)
+		b.probing = false
+		b.Trips++
+		logger.Warnf("Circuit for %s open for %s after %d consecutive failures (last: %s)",
+			host, breakerCfg.OpenFor, b.ConsecutiveFailures, b.LastError)
+	}
+}
@@ -0,0 +1,39 @@
+package client
+
+import (
+	"sync"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/clock"
+)
+
+// =============================================================================
+// CLOCK
+// =============================================================================
+// Retry backoff and call timing read the package clock. Tests install a
+// clock.Fake and advance it to step through backoff without sleeping.
+// Like SetCallRecorder, this is package-level so provider signatures stay
+// unchanged.
+// =============================================================================
+
+var (
+	clockMu sync.RWMutex
+	clk     clock.Clock = clock.Real{}
+)
+
+// SetClock replaces the clock used for backoff and call timing.
+// Pass nil to restore the real clock.
+func SetClock(c clock.Clock) {
+	if c == nil {
+		c = clock.Real{}
+	}
+	clockMu.Lock()
+	defer clockMu.Unlock()
+	clk = c
+}
+
+// currentClock returns the installed clock.
+func currentClock() clock.Clock {
+	clockMu.RLock()
+	defer clockMu.RUnlock()
+	return clk
+}
@@ -0,0 +1,329 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// EGRESS POLICY
+// =============================================================================
+// Security wants the controller to talk to approved vendor endpoints and
+// nothing else: a typo'd SONY_API_URL, a compromised vendor answering with
+// a redirect to an internal service, or a passthrough path that escapes
+// the vendor's host must all be refused before a byte leaves.
+//
+// The policy maps a provider (see WithProvider) to the hosts it may reach:
+//
+//   {"sony": {"hosts": ["sony-api.internal", "*.sony.example.com"],
+//             "cidrs": ["10.20.0.0/16"]},
+//    "*":    {"hosts": ["auth.example.com"]}}
+//
+//   - hosts match the URL's host name; "*.example.com" matches any
+//     subdomain (not example.com itself)
+//   - cidrs match an IP address in the URL, or every address a host name
+//     resolves to (one address outside fails the request)
+//   - "*" applies to every provider in addition to its own entry, and to
+//     requests that don't name a provider
+//
+// Every request through DoWithRetry and Do is checked before it is sent
+// (including signer logins and token fetches), and again on each redirect.
+// A denial returns an *EgressDeniedError (never retried), is logged at
+// warn level, recorded as a failed call in the audit trail, and kept in
+// EgressDenials.
+//
+// WHY OFF BY DEFAULT: Without a policy nothing is checked, as before; an
+// empty policy ({}) denies everything.
+// =============================================================================
+
+// ErrEgressDenied is returned (wrapped in an *EgressDeniedError) when the
+// egress policy refuses a request.
+var ErrEgressDenied = errors.New("egress denied")
+
+// EgressDeniedError reports a refused request.
+type EgressDeniedError struct {
+	Provider string
+	Host     string
+	Reason   string
+}
+
+func (e *EgressDeniedError) Error() string {
+	return fmt.Sprintf("egress denied: %s may not reach %s (%s)", e.providerName(), e.Host, e.Reason)
+}
+
+func (e *EgressDeniedError) Unwrap() error { return ErrEgressDenied }
+
+func (e *EgressDeniedError) providerName() string {
+	if e.Provider == "" {
+		return "unnamed provider"
+	}
+	return e.Provider
+}
+
+// EgressRule lists what one provider may reach.
+type EgressRule struct {
+	// Hosts are host names, optionally "*.domain" for its subdomains
+	Hosts []string `json:"hosts,omitempty"`
+
+	// CIDRs are address ranges ("10.20.0.0/16", "192.0.2.7/32")
+	CIDRs []string `json:"cidrs,omitempty"`
+}
+
+// EgressPolicy maps a provider name (or "*" for all) to its rule.
+type EgressPolicy map[string]EgressRule
+
+// egressAnyProvider is the policy key that applies to every provider.
+const egressAnyProvider = "*"
+
+// EgressDenial is one refused request.
+type EgressDenial struct {
+	Time     time.Time `json:"time"`
+	Provider string    `json:"provider,omitempty"`
+	Method   string    `json:"method"`
+	URL      string    `json:"url"`
+	Redirect bool      `json:"redirect,omitempty"`
+	Reason   string    `json:"reason"`
+}
+
+// maxEgressDenials is how many denials EgressDenials keeps.
+const maxEgressDenials = 100
+
+// compiledRule is an EgressRule with parsed ranges.
+type compiledRule struct {
+	hosts []string
+	nets  []*net.IPNet
+}
+
+var (
+	egressMu      sync.RWMutex
+	egressPolicy  EgressPolicy
+	egressRules   map[string]compiledRule // nil = no policy
+	egressDenials []EgressDenial
+	egressDenied  int
+
+	// egressResolver looks up host names for CIDR rules
+	egressResolver = net.DefaultResolver
+)
+
+// SetEgressPolicy installs policy for all outbound calls. nil removes it.
+func SetEgressPolicy(policy EgressPolicy) error {
+	rules, err := compileEgressPolicy(policy)
+	if err != nil {
+		return err
+	}
+	egressMu.Lock()
+	defer egressMu.Unlock()
+	egressPolicy, egressRules = policy, rules
+	return nil
+}
+
+// CheckEgressPolicy returns the error SetEgressPolicy would, without
+// installing policy.
+func CheckEgressPolicy(policy EgressPolicy) error {
+	_, err := compileEgressPolicy(policy)
+	return err
+}
+
+// compileEgressPolicy parses policy's hosts and ranges (nil for no policy).
+func compileEgressPolicy(policy EgressPolicy) (map[string]compiledRule, error) {
+	var rules map[string]compiledRule
+	if policy != nil {
+		rules = make(map[string]compiledRule, len(policy))
+		for name, rule := range policy {
+			compiled := compiledRule{}
+			for _, host := range rule.Hosts {
+				host = strings.ToLower(strings.TrimSpace(host))
+				if host == "" || strings.Contains(host, "/") || strings.Contains(host, ":") && net.ParseIP(host) == nil {
+					return nil, fmt.Errorf("%s: invalid host %q (want a host name without scheme or port)", name, host)
+				}
+				if strings.Contains(strings.TrimPrefix(host, "*."), "*") {
+					return nil, fmt.Errorf("%s: invalid host %q (only a leading \"*.\" is allowed)", name, host)
+				}
+				compiled.hosts = append(compiled.hosts, host)
+			}
+			for _, cidr := range rule.CIDRs {
+				_, network, err := net.ParseCIDR(strings.TrimSpace(cidr))
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", name, err)
+				}
+				compiled.nets = append(compiled.nets, network)
+			}
+			rules[name] = compiled
+		}
+	}
+	return rules, nil
+}
+
+// CurrentEgressPolicy returns the installed policy (nil when none).
+func CurrentEgressPolicy() EgressPolicy {
+	egressMu.RLock()
+	defer egressMu.RUnlock()
+	return egressPolicy
+}
+
+// EgressDenials returns the total number of denials and the most recent
+// ones, oldest first.
+func EgressDenials() (int, []EgressDenial) {
+	egressMu.RLock()
+	defer egressMu.RUnlock()
+	return egressDenied, append([]EgressDenial(nil), egressDenials...)
+}
+
+type providerKey struct{}
+
+// WithProvider returns a context whose requests are checked against
+// provider's egress rule.
+func WithProvider(ctx context.Context, provider string) context.Context {
+	return context.WithValue(ctx, providerKey{}, provider)
+}
+
+func providerFrom(ctx context.Context) string {
+	provider, _ := ctx.Value(providerKey{}).(string)
+	return provider
+}
+
+// checkEgress returns an *EgressDeniedError if the policy doesn't let
+// provider reach req's host. redirect marks a redirect target. Denials
+// are logged, recorded and kept.
+func checkEgress(req *http.Request, provider string, redirect bool) error {
+	egressMu.RLock()
+	rules := egressRules
+	egressMu.RUnlock()
+	if rules == nil {
+		return nil
+	}
+
+	var applicable []compiledRule
+	if rule, ok := rules[provider]; ok && provider != "" {
+		applicable = append(applicable, rule)
+	}
+	if rule, ok := rules[egressAnyProvider]; ok {
+		applicable = append(applicable, rule)
+	}
+	reason := egressRefusal(req.Context(), strings.ToLower(req.URL.Hostname()), applicable)
+	if reason == "" {
+		return nil
+	}
+
+	denied := &EgressDeniedError{Provider: provider, Host: req.URL.Hostname(), Reason: reason}
+	denial := EgressDenial{
+		Time:     currentClock().Now(),
+		Provider: provider,
+		Method:   req.Method,
+		URL:      RedactURL(req.URL),
+		Redirect: redirect,
+		Reason:   reason,
+	}
+	egressMu.Lock()
+	egressDenied++
+	egressDenials = append(egressDenials, denial)
+	if len(egressDenials) > maxEgressDenials {
+		egressDenials = egressDenials[len(egressDenials)-maxEgressDenials:]
+	}
+	egressMu.Unlock()
+
+	kind := "request"
+	if redirect {
+		kind = "redirect"
+	}
+	logger.Warnf("Egress denied: %s %s %s for %s: %s", kind, req.Method, denial.URL, denied.providerName(), reason)
+	recordCall(req, "", 0, denial.Time, 1, nil, denied)
+	return denied
+}
+
+// egressRefusal returns why host may not be reached under rules, or ""
+// when it may.
+func egressRefusal(ctx context.Context, host string, rules []compiledRule) string {
+	if len(rules) == 0 {
+		return "no egress rule for this provider"
+	}
+	var nets []*net.IPNet
+	for _, rule := range rules {
+		for _, pattern := range rule.hosts {
+			if hostMatches(pattern, host) {
+				return ""
+			}
+		}
+		nets = append(nets, rule.nets...)
+	}
+	if len(nets) == 0 {
+		return "host not in allowlist"
+	}
+
+	var addrs []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		addrs = []net.IP{ip}
+	} else {
+		resolved, err := egressResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return "host not in allowlist and could not be resolved: " + err.Error()
+		}
+		for _, addr := range resolved {
+			addrs = append(addrs, addr.IP)
+		}
+	}
+	for _, addr := range addrs {
+		if !inNets(addr, nets) {
+			return "address " + addr.String() + " not in allowed ranges"
+		}
+	}
+	return ""
+}
+
+// hostMatches reports whether host matches pattern ("*.example.com"
+// matches subdomains only).
+func hostMatches(pattern, host string) bool {
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(host, pattern[1:])
+	}
+	return pattern == host
+}
+
+func inNets(ip net.IP, nets []*net.IPNet) bool {
+	for _, network := range nets {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// guardRedirects returns a copy of httpClient that checks every redirect
+// against provider's egress rule before following it.
+// WHY PASS provider: DoWithRetry clones requests onto the caller's
+// context, which may not carry it
+func guardRedirects(httpClient *http.Client, provider string) *http.Client {
+	guarded := *httpClient
+	next := httpClient.CheckRedirect
+	guarded.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if err := checkEgress(req, provider, true); err != nil {
+			return err
+		}
+		if next != nil {
+			return next(req, via)
+		}
+		// net/http's default limit
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	return &guarded
+}
+
+// Providers returns the provider names the policy has rules for, sorted.
+func (p EgressPolicy) Providers() []string {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -2,21 +2,47 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/logging"
 )
 
+// logger reports retries and failures for outbound vendor HTTP calls.
+// Switch it to debug via PUT /admin/loglevel to see every attempt.
+var logger = logging.For(logging.ComponentClient)
+
 // DoWithRetry executes HTTP request with exponential backoff
 func DoWithRetry(ctx context.Context, req *http.Request, maxRetries int) (*http.Response, error) {
 	var lastErr error
 	var resp *http.Response
 
-	client := &http.Client{
+	provider := providerFrom(req.Context())
+	client := guardRedirects(&http.Client{
 		Timeout: 30 * time.Second,
+		// Per-host connection pool, reset by the breaker on repeated
+		// connection failures (see breaker.go)
+		Transport: transportFor(req.URL.Host),
+	}, provider)
+
+	// Refuse hosts the egress policy doesn't allow (see egress.go)
+	if err := checkEgress(req, provider, false); err != nil {
+		return nil, err
 	}
 
+	// Hash the payload once up front for the audit trail
+	digest, size := payloadDigest(req)
+	clk := currentClock()
+	signer := signerFrom(req.Context())
+
+	// WHY ONCE: A 401 with a token we just fetched is a real refusal, not
+	// a stale session
+	refresher, _ := signer.(TokenRefresher)
+	reauthenticated := false
+
 	// Attempt the request with retries
 	for attempt := 0; attempt <= maxRetries; attempt++ {
 		// Check if context is cancelled before each retry
@@ -28,9 +54,48 @@ func DoWithRetry(ctx context.Context, req *http.Request, maxRetries int) (*http.
 
 		// Clone the request for each retry (required because request body can only be read once)
 		reqClone := req.Clone(ctx)
+		// Clone shares the Body, which the previous attempt consumed. GetBody
+		// returns a fresh copy so retries send the same payload.
+		if req.GetBody != nil {
+			if body, err := req.GetBody(); err == nil {
+				reqClone.Body = body
+			}
+		}
+		withRequestID(ctx, reqClone)
+		// Sign each attempt afresh (timestamps, expiring tokens)
+		if signer != nil {
+			if err := signer.Sign(reqClone, digest); err != nil {
+				return nil, fmt.Errorf("failed to sign request: %w", err)
+			}
+		}
+
+		// Fail fast while the host's circuit is open
+		if err := allowRequest(req.URL.Host); err != nil {
+			return nil, err
+		}
 
 		// Execute the HTTP request
+		logger.Debugf("%s %s (attempt %d/%d)", req.Method, RedactURL(req.URL), attempt+1, maxRetries+1)
+		started := clk.Now()
 		resp, lastErr = client.Do(reqClone)
+		recordCall(reqClone, digest, size, started, attempt+1, resp, lastErr)
+		recordOutcome(reqClone, resp, lastErr)
+
+		// A redirect the egress policy refused won't be allowed next time
+		if errors.Is(lastErr, ErrEgressDenied) {
+			return nil, lastErr
+		}
+
+		// A rejected token: log in again and repeat the attempt (it
+		// doesn't count as a retry)
+		if lastErr == nil && resp.StatusCode == http.StatusUnauthorized && refresher != nil && !reauthenticated {
+			reauthenticated = true
+			logger.Infof("%s %s returned 401; retrying with fresh credentials", req.Method, RedactURL(req.URL))
+			refresher.Invalidate(reqClone)
+			resp.Body.Close()
+			attempt--
+			continue
+		}
 
 		// If successful, return immediately
 		if lastErr == nil && resp.StatusCode < 500 {
@@ -51,19 +116,19 @@ func DoWithRetry(ctx context.Context, req *http.Request, maxRetries int) (*http.
 			backoffDelay = 5 * time.Second
 		}
 
-		// Log retry attempt (in production, use proper logger)
+		// Log retry attempt
 		if lastErr != nil {
-			fmt.Printf("Request failed (attempt %d/%d): %v. Retrying in %v...\n",
+			logger.Warnf("Request failed (attempt %d/%d): %v. Retrying in %v...",
 				attempt+1, maxRetries+1, lastErr, backoffDelay)
 		} else if resp != nil {
-			fmt.Printf("Request returned status %d (attempt %d/%d). Retrying in %v...\n",
+			logger.Warnf("Request returned status %d (attempt %d/%d). Retrying in %v...",
 				resp.StatusCode, attempt+1, maxRetries+1, backoffDelay)
 			resp.Body.Close() // Close the response body before retrying
 		}
 
 		// Wait for backoff period or context cancellation
 		select {
-		case <-time.After(backoffDelay):
+		case <-clk.After(backoffDelay):
 			// Continue to next retry
 		case <-ctx.Done():
 			return nil, fmt.Errorf("request cancelled during backoff: %w", ctx.Err())
@@ -0,0 +1,215 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/audit"
+)
+
+// =============================================================================
+// CALL RECORDING
+// =============================================================================
+// Every outbound vendor call made through this package (DoWithRetry and Do)
+// is reported to an optional CallRecorder, one record per attempt. The
+// controller installs a recorder that writes these into the audit log.
+//
+// WHY A HOOK (not a direct audit.Log dependency): Providers and the
+// client are constructed before (and independently of) the controller.
+// A package-level hook keeps their signatures unchanged.
+// =============================================================================
+
+// CallRecord describes one attempt of an outbound HTTP call.
+type CallRecord struct {
+	// RequestID is the triggering API request ID from the context, if any.
+	RequestID string
+
+	// ResourceID is taken from the X-Forge-Resource-ID request header.
+	ResourceID string
+
+	Method string
+
+	// URL has secret-looking query parameters and userinfo redacted.
+	URL string
+
+	// PayloadSHA256 is the hex SHA-256 of the request body ("" if none).
+	PayloadSHA256 string
+	PayloadBytes  int
+
+	// StatusCode is 0 when the call failed without a response.
+	StatusCode int
+	Started    time.Time
+	Duration   time.Duration
+	Attempt    int
+	Err        error
+}
+
+// CallRecorder receives a CallRecord for every outbound call attempt.
+// It is called synchronously and must not block.
+type CallRecorder func(rec CallRecord)
+
+var (
+	recorderMu sync.RWMutex
+	recorder   CallRecorder
+)
+
+// SetCallRecorder installs the recorder for all outbound calls.
+// Pass nil to disable recording.
+func SetCallRecorder(r CallRecorder) {
+	recorderMu.Lock()
+	defer recorderMu.Unlock()
+	recorder = r
+}
+
+// Do executes a single request without retries and records it.
+// Use it instead of httpClient.Do so the call shows up in the audit trail
+// and counts towards the host's circuit breaker.
+//
+// A 401 with a TokenRefresher signer is repeated once with a fresh token.
+// WHY SAFE WITHOUT RETRIES: The vendor refused the request before doing
+// anything with it.
+func Do(httpClient *http.Client, req *http.Request) (*http.Response, error) {
+	digest, size := payloadDigest(req)
+	withRequestID(req.Context(), req)
+	provider := providerFrom(req.Context())
+	if err := checkEgress(req, provider, false); err != nil {
+		return nil, err
+	}
+	if err := allowRequest(req.URL.Host); err != nil {
+		return nil, err
+	}
+	httpClient = guardRedirects(httpClient, provider)
+	if httpClient.Transport == nil {
+		// Use the host's own pool so breaker resets apply here too
+		httpClient.Transport = transportFor(req.URL.Host)
+	}
+	signer := signerFrom(req.Context())
+	refresher, _ := signer.(TokenRefresher)
+	for attempt := 1; ; attempt++ {
+		if signer != nil {
+			if err := signer.Sign(req, digest); err != nil {
+				return nil, fmt.Errorf("failed to sign request: %w", err)
+			}
+		}
+		started := currentClock().Now()
+		resp, err := httpClient.Do(req)
+		recordCall(req, digest, size, started, attempt, resp, err)
+		recordOutcome(req, resp, err)
+		if err != nil || resp.StatusCode != http.StatusUnauthorized || refresher == nil || attempt > 1 {
+			return resp, err
+		}
+		if req.Body != nil && req.GetBody == nil {
+			// The body was sent and can't be replayed
+			return resp, err
+		}
+		logger.Infof("%s %s returned 401; retrying with fresh credentials", req.Method, RedactURL(req.URL))
+		refresher.Invalidate(req)
+		resp.Body.Close()
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+	}
+}
+
+// recordCall builds a CallRecord and hands it to the recorder, if any.
+func recordCall(req *http.Request, digest string, size int, started time.Time, attempt int, resp *http.Response, err error) {
+	recorderMu.RLock()
+	r := recorder
+	recorderMu.RUnlock()
+	if r == nil {
+		return
+	}
+
+	rec := CallRecord{
+		RequestID:     audit.RequestID(req.Context()),
+		ResourceID:    req.Header.Get("X-Forge-Resource-ID"),
+		Method:        req.Method,
+		URL:           RedactURL(req.URL),
+		PayloadSHA256: digest,
+		PayloadBytes:  size,
+		Started:       started,
+		Duration:      currentClock().Since(started),
+		Attempt:       attempt,
+		Err:           err,
+	}
+	if resp != nil {
+		rec.StatusCode = resp.StatusCode
+	}
+	r(rec)
+}
+
+// payloadDigest hashes the request body without consuming it.
+// Requests built by http.NewRequest from a bytes/strings reader have
+// GetBody set, which returns a fresh copy of the body.
+func payloadDigest(req *http.Request) (string, int) {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody == nil {
+		return "", 0
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return "", 0
+	}
+	defer body.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, body)
+	if err != nil {
+		return "", 0
+	}
+	return hex.EncodeToString(h.Sum(nil)), int(n)
+}
+
+// secretParams are query parameter names whose values are never recorded.
+var secretParams = []string{"key", "token", "secret", "password", "signature", "auth", "credential"}
+
+// RedactURL renders u with userinfo and secret-looking query values
+// replaced by "REDACTED".
+func RedactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	redacted := *u
+	if redacted.User != nil {
+		redacted.User = url.User("REDACTED")
+	}
+	query := redacted.Query()
+	changed := false
+	for name := range query {
+		lower := strings.ToLower(name)
+		for _, secret := range secretParams {
+			if strings.Contains(lower, secret) {
+				query[name] = []string{"REDACTED"}
+				changed = true
+				break
+			}
+		}
+	}
+	if changed {
+		redacted.RawQuery = query.Encode()
+	}
+	return redacted.String()
+}
+
+// requestIDHeader forwards the API request ID to vendors so their logs can
+// be correlated with ours.
+const requestIDHeader = "X-Request-ID"
+
+// withRequestID sets the X-Request-ID header from the context if the
+// caller hasn't set one.
+func withRequestID(ctx context.Context, req *http.Request) {
+	if id := audit.RequestID(ctx); id != "" && req.Header.Get(requestIDHeader) == "" {
+		req.Header.Set(requestIDHeader, id)
+	}
+}
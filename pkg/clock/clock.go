@@ -0,0 +1,178 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// CLOCK
+// =============================================================================
+// Code that reads the time or waits takes a Clock instead of calling the
+// time package directly. Production uses Real; tests (and the controller's
+// FORGE_TEST_MODE) use Fake, which only moves when told to:
+//
+//	fake := clock.NewFake(time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC))
+//	go client.DoWithRetry(...)        // blocks in backoff on fake.After
+//	fake.Advance(200 * time.Millisecond) // backoff elapses instantly
+//
+// This makes timeouts, expiry and backoff testable without sleeping.
+// =============================================================================
+
+// Clock tells the time and schedules wake-ups.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// Since returns the time elapsed since t.
+	Since(t time.Time) time.Duration
+
+	// After returns a channel that receives the time once d has elapsed.
+	After(d time.Duration) <-chan time.Time
+
+	// AfterFunc calls f in its own goroutine once d has elapsed.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a scheduled AfterFunc call that can be cancelled.
+type Timer interface {
+	// Stop prevents the call; it reports whether the call was stopped
+	// before it fired.
+	Stop() bool
+}
+
+// Real is the system clock.
+type Real struct{}
+
+func (Real) Now() time.Time                         { return time.Now() }
+func (Real) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (Real) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (Real) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// =============================================================================
+// FAKE CLOCK
+// =============================================================================
+
+// Fake is a manually advanced clock. Safe for concurrent use.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+type fakeWaiter struct {
+	deadline time.Time
+	ch       chan time.Time // set for After
+	fn       func()         // set for AfterFunc
+	stopped  bool
+	fired    bool
+	clock    *Fake
+}
+
+// NewFake creates a fake clock set to start.
+func NewFake(start time.Time) *Fake {
+	return &Fake{now: start}
+}
+
+// Now returns the fake time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Since returns the fake time elapsed since t.
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+// After returns a channel that fires when the clock is advanced past d.
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	w := &fakeWaiter{ch: make(chan time.Time, 1), clock: f}
+	f.schedule(w, d)
+	return w.ch
+}
+
+// AfterFunc runs fn when the clock is advanced past d.
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	w := &fakeWaiter{fn: fn, clock: f}
+	f.schedule(w, d)
+	return w
+}
+
+func (f *Fake) schedule(w *fakeWaiter, d time.Duration) {
+	f.mu.Lock()
+	w.deadline = f.now.Add(d)
+	f.waiters = append(f.waiters, w)
+	f.mu.Unlock()
+	if d <= 0 {
+		f.Advance(0)
+	}
+}
+
+// Stop cancels a pending AfterFunc.
+func (w *fakeWaiter) Stop() bool {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+	if w.fired || w.stopped {
+		return false
+	}
+	w.stopped = true
+	return true
+}
+
+// Advance moves the clock forward by d and fires every waiter whose
+// deadline has been reached, in deadline order.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	f.now = f.now.Add(d)
+	now := f.now
+
+	var due, pending []*fakeWaiter
+	for _, w := range f.waiters {
+		switch {
+		case w.stopped:
+			// drop
+		case !w.deadline.After(now):
+			w.fired = true
+			due = append(due, w)
+		default:
+			pending = append(pending, w)
+		}
+	}
+	f.waiters = pending
+	f.mu.Unlock()
+
+	sort.SliceStable(due, func(i, j int) bool { return due[i].deadline.Before(due[j].deadline) })
+	for _, w := range due {
+		if w.ch != nil {
+			w.ch <- now
+		} else {
+			go w.fn()
+		}
+	}
+}
+
+// Set jumps the clock to t (forward only) and fires due waiters.
+func (f *Fake) Set(t time.Time) {
+	if d := t.Sub(f.Now()); d > 0 {
+		f.Advance(d)
+	}
+}
+
+// Pending returns how many After/AfterFunc waiters haven't fired yet.
+// Tests use it to wait until code under test is blocked on the clock.
+func (f *Fake) Pending() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	count := 0
+	for _, w := range f.waiters {
+		if !w.stopped {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,252 @@
+package convert
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// SPEC CONVERSION BETWEEN VENDORS
+// =============================================================================
+// Convert maps a spec written for one vendor to the closest equivalent
+// for another, e.g. to move a workload from an on-prem Sony device to an
+// AWS MediaLive channel:
+//
+//	result, err := convert.Convert(res.Spec, "aws")
+//	// result.Spec     → spec with vendor_type "aws"
+//	// result.Changes  → fields whose value had to change, and why
+//	// result.Unmapped → fields dropped because aws has no equivalent
+//
+// Field paths use the same dotted form as spec validation
+// ("spec.resolution", "spec.config.sony_model").
+// =============================================================================
+
+// Change is a field whose value was translated.
+type Change struct {
+	Field  string      `json:"field"`
+	From   interface{} `json:"from"`
+	To     interface{} `json:"to"`
+	Reason string      `json:"reason"`
+}
+
+// Unmapped is a field dropped because the target has no equivalent.
+type Unmapped struct {
+	Field  string      `json:"field"`
+	Value  interface{} `json:"value"`
+	Reason string      `json:"reason"`
+}
+
+// Result is the outcome of a conversion.
+type Result struct {
+	From     string              `json:"from"`
+	To       string              `json:"to"`
+	Spec     models.ResourceSpec `json:"spec"`
+	Changes  []Change            `json:"changes"`
+	Unmapped []Unmapped          `json:"unmapped"`
+}
+
+// Convert maps spec to the vendor to. It fails only if either vendor is
+// unknown or both are the same; lossy conversions succeed and are
+// described in the result.
+func Convert(spec models.ResourceSpec, to string) (*Result, error) {
+	source, ok := Profiles[spec.VendorType]
+	if !ok {
+		return nil, fmt.Errorf("unknown source vendor %q", spec.VendorType)
+	}
+	target, ok := Profiles[to]
+	if !ok {
+		return nil, fmt.Errorf("unknown target vendor %q (known: %s)", to, strings.Join(Vendors(), ", "))
+	}
+	if source == target {
+		return nil, fmt.Errorf("spec already targets %s", to)
+	}
+
+	out := spec
+	out.VendorType = target.Vendor
+	out.Config = make(map[string]interface{}, len(spec.Config))
+	result := &Result{From: source.Vendor, To: target.Vendor, Changes: []Change{}, Unmapped: []Unmapped{}}
+
+	convertResolution("spec.resolution", &out.Resolution, target, result)
+	convertCodec("spec.codec", &out.Codec, out.Resolution, target, result)
+	convertBitrate("spec.bitrate", &out.Bitrate, out.Resolution, target, result)
+	convertLadder(spec.Ladder, &out, target, result)
+	convertStream(&out, target, result)
+	convertRecording(&out, target, result)
+	convertConfig(spec.Config, &out, source, target, result)
+
+	result.Spec = out
+	return result, nil
+}
+
+// convertResolution picks the highest supported resolution not above the
+// requested one (or the lowest supported if all are above).
+func convertResolution(field string, resolution *string, target *Profile, result *Result) {
+	if *resolution == "" {
+		return
+	}
+	original := *resolution
+	normalized := NormalizeResolution(original)
+	if target.supportsResolution(normalized) {
+		if normalized != original {
+			result.change(field, original, normalized, "normalized to the Forge resolution name")
+		}
+		*resolution = normalized
+		return
+	}
+
+	rank := map[string]int{"SD": 1, "HD": 2, "FHD": 3, "4K": 4, "8K": 5}
+	requested, known := rank[normalized]
+	if !known {
+		result.unmapped(field, original, "unrecognized resolution")
+		*resolution = ""
+		return
+	}
+	best := target.Resolutions[0]
+	for _, candidate := range target.Resolutions {
+		if rank[candidate] <= requested {
+			best = candidate
+		}
+	}
+	*resolution = best
+	result.change(field, original, best,
+		fmt.Sprintf("%s does not support %s; %s is the closest", target.Vendor, normalized, best))
+}
+
+// convertCodec falls back to the target's preferred delivery codec, using
+// HEVC for 4K and above where available.
+func convertCodec(field string, codec *string, resolution string, target *Profile, result *Result) {
+	if *codec == "" {
+		return
+	}
+	original := *codec
+	normalized := NormalizeCodec(original)
+	if target.supportsCodec(normalized) {
+		if normalized != original {
+			result.change(field, original, normalized, "normalized to the Forge codec name")
+		}
+		*codec = normalized
+		return
+	}
+
+	replacement := target.Codecs[0]
+	if (resolution == "4K" || resolution == "8K") && target.supportsCodec("H.265/HEVC") {
+		replacement = "H.265/HEVC"
+	}
+	*codec = replacement
+	result.change(field, original, replacement,
+		fmt.Sprintf("%s has no %s encoder; %s is the closest delivery codec", target.Vendor, normalized, replacement))
+}
+
+// convertBitrate caps the bitrate at the target's limit for the resolution.
+func convertBitrate(field string, bitrate *int64, resolution string, target *Profile, result *Result) {
+	if *bitrate <= 0 {
+		return
+	}
+	limit, ok := target.MaxBitrate[resolution]
+	if !ok {
+		// No resolution: use the highest tier as the cap
+		for _, l := range target.MaxBitrate {
+			if l > limit {
+				limit = l
+			}
+		}
+	}
+	if limit > 0 && *bitrate > limit {
+		result.change(field, *bitrate, limit,
+			fmt.Sprintf("%s allows at most %d Mbps at %s", target.Vendor, limit/1_000_000, valueOr(resolution, "any resolution")))
+		*bitrate = limit
+	}
+}
+
+// convertLadder converts every rendition like the spec's own output. The
+// ladder is copied so the source spec keeps its renditions.
+func convertLadder(ladder []models.Rendition, out *models.ResourceSpec, target *Profile, result *Result) {
+	if len(ladder) == 0 {
+		return
+	}
+	out.Ladder = append([]models.Rendition(nil), ladder...)
+	for i := range out.Ladder {
+		rendition := &out.Ladder[i]
+		field := fmt.Sprintf("spec.ladder[%d]", i)
+		convertResolution(field+".resolution", &rendition.Resolution, target, result)
+		convertCodec(field+".codec", &rendition.Codec, rendition.Resolution, target, result)
+		convertBitrate(field+".bitrate", &rendition.Bitrate, rendition.Resolution, target, result)
+	}
+}
+
+// convertStream drops a stream URL whose protocol the target can't output.
+func convertStream(out *models.ResourceSpec, target *Profile, result *Result) {
+	if out.StreamURL == "" {
+		return
+	}
+	scheme := urlScheme(out.StreamURL)
+	if contains(target.StreamSchemes, scheme) {
+		return
+	}
+	result.unmapped("spec.stream_url", out.StreamURL,
+		fmt.Sprintf("%s cannot output %s (supported: %s)", target.Vendor, strings.ToUpper(valueOr(scheme, "this protocol")), strings.Join(target.StreamSchemes, ", ")))
+	out.StreamURL = ""
+}
+
+// convertRecording drops a recording path the target can't write to, and
+// with it recording_enabled (a recording needs a destination).
+func convertRecording(out *models.ResourceSpec, target *Profile, result *Result) {
+	if out.RecordingPath == "" || target.RecordingSchemes == nil {
+		return
+	}
+	if contains(target.RecordingSchemes, urlScheme(out.RecordingPath)) {
+		return
+	}
+	result.unmapped("spec.recording_path", out.RecordingPath,
+		fmt.Sprintf("%s records only to %s:// storage", target.Vendor, strings.Join(target.RecordingSchemes, "://, ")))
+	out.RecordingPath = ""
+	if out.RecordingEnabled {
+		result.unmapped("spec.recording_enabled", true, "recording needs a recording_path the target supports")
+		out.RecordingEnabled = false
+	}
+}
+
+// convertConfig carries generic config keys and drops keys specific to
+// the source vendor.
+func convertConfig(config map[string]interface{}, out *models.ResourceSpec, source, target *Profile, result *Result) {
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if source.hasConfigKey(key) && !target.hasConfigKey(key) {
+			result.unmapped("spec.config."+key, config[key],
+				fmt.Sprintf("%s-specific setting with no %s equivalent", source.Vendor, target.Vendor))
+			continue
+		}
+		out.Config[key] = config[key]
+	}
+}
+
+func (r *Result) change(field string, from, to interface{}, reason string) {
+	r.Changes = append(r.Changes, Change{Field: field, From: from, To: to, Reason: reason})
+}
+
+func (r *Result) unmapped(field string, value interface{}, reason string) {
+	r.Unmapped = append(r.Unmapped, Unmapped{Field: field, Value: value, Reason: reason})
+}
+
+// urlScheme returns the lowercase scheme of u ("" for plain paths).
+func urlScheme(u string) string {
+	if i := strings.Index(u, "://"); i > 0 {
+		return strings.ToLower(u[:i])
+	}
+	return ""
+}
+
+func valueOr(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,226 @@
+package convert
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// MEDIALIVE CHANNEL MAPPING
+// =============================================================================
+// MediaLiveChannel maps a spec for vendor "aws" to a MediaLive CreateChannel
+// request. Each ladder rendition (or the spec's single output) becomes a
+// VideoDescription; outputs are grouped by destination protocol:
+//
+//   http(s)://   one HLS output group, one output per rendition
+//                (name modifier "_<rendition>")
+//   rtmp(s)://   one RTMP output group; RTMP carries one rendition per
+//                connection, so each gets its own destination with the
+//                stream key suffixed "_<rendition>"
+//
+// Every output carries the same AAC audio description.
+// =============================================================================
+
+// MediaLive defaults.
+const (
+	mediaLiveDestination = "destination1"
+	mediaLiveAudio       = "audio_aac"
+	defaultAudioBitrate  = 128000
+)
+
+// MediaLiveChannel builds the CreateChannel request for a resource named
+// name with the given spec.
+func MediaLiveChannel(name string, spec models.ResourceSpec) models.AWSResourceRequest {
+	renditions := spec.Ladder
+	if len(renditions) == 0 {
+		renditions = []models.Rendition{{Name: "main", Resolution: spec.Resolution, Bitrate: spec.Bitrate}}
+	}
+
+	request := models.AWSResourceRequest{
+		ChannelName:        name,
+		ChannelClass:       configString(spec, "aws_channel_class", "SINGLE_PIPELINE"),
+		RoleArn:            configString(spec, "aws_role_arn", ""),
+		InputSpecification: mediaLiveInput(spec),
+		LogLevel:           "ERROR",
+	}
+	request.EncoderSettings.AudioDescriptions = []models.AWSAudioDescription{mediaLiveAudioDescription(spec)}
+
+	group := models.AWSOutputGroup{Name: "abr"}
+	rtmp := strings.HasPrefix(urlScheme(spec.StreamURL), "rtmp")
+	if rtmp {
+		group.OutputGroupSettings.RtmpGroupSettings = &models.AWSRtmpGroupSettings{AuthenticationScheme: "COMMON"}
+	} else {
+		group.OutputGroupSettings.HlsGroupSettings = &models.AWSHlsGroupSettings{
+			Destination:   models.AWSDestinationRef{DestinationRefId: mediaLiveDestination},
+			SegmentLength: 6,
+		}
+		request.Destinations = []models.AWSDestination{{
+			ID:       mediaLiveDestination,
+			Settings: []models.AWSDestinationSettings{{URL: spec.StreamURL}},
+		}}
+	}
+
+	for _, rendition := range renditions {
+		videoName := "video_" + rendition.RenditionName()
+		request.EncoderSettings.VideoDescriptions = append(request.EncoderSettings.VideoDescriptions,
+			mediaLiveVideoDescription(videoName, rendition, spec))
+
+		output := models.AWSOutput{
+			OutputName:            rendition.RenditionName(),
+			VideoDescriptionName:  videoName,
+			AudioDescriptionNames: []string{mediaLiveAudio},
+		}
+		if rtmp {
+			destinationID := "destination_" + rendition.RenditionName()
+			request.Destinations = append(request.Destinations, rtmpDestination(destinationID, spec.StreamURL, rendition.RenditionName(), len(renditions) > 1))
+			output.OutputSettings.RtmpOutputSettings = &models.AWSRtmpOutputSettings{
+				Destination: models.AWSDestinationRef{DestinationRefId: destinationID},
+				NumRetries:  10,
+			}
+		} else {
+			output.OutputSettings.HlsOutputSettings = &models.AWSHlsOutputSettings{
+				NameModifier: "_" + rendition.RenditionName(),
+				HlsSettings: models.AWSHlsSettings{
+					StandardHlsSettings: &models.AWSStandardHlsSettings{},
+				},
+			}
+		}
+		group.Outputs = append(group.Outputs, output)
+	}
+	request.EncoderSettings.OutputGroups = []models.AWSOutputGroup{group}
+	return request
+}
+
+// mediaLiveVideoDescription maps one rendition.
+func mediaLiveVideoDescription(name string, rendition models.Rendition, spec models.ResourceSpec) models.AWSVideoDescription {
+	width, height := pixelSize(rendition.Resolution)
+	numerator, denominator := framerateFraction(rendition.EffectiveFrameRate(spec))
+	description := models.AWSVideoDescription{Name: name, Width: width, Height: height}
+	bitrate := int(rendition.Bitrate)
+
+	switch NormalizeCodec(rendition.EffectiveCodec(spec)) {
+	case "H.265/HEVC":
+		description.CodecSettings.H265Settings = &models.AWSH265Settings{
+			Bitrate:              bitrate,
+			FramerateNumerator:   numerator,
+			FramerateDenominator: denominator,
+			Profile:              "MAIN",
+			Tier:                 "MAIN",
+			Level:                "H265_LEVEL_AUTO",
+		}
+	case "AV1":
+		description.CodecSettings.Av1Settings = &models.AWSAv1Settings{
+			Bitrate:              bitrate,
+			FramerateNumerator:   numerator,
+			FramerateDenominator: denominator,
+			Level:                "AV1_LEVEL_AUTO",
+		}
+	default:
+		description.CodecSettings.H264Settings = &models.AWSH264Settings{
+			Bitrate:              bitrate,
+			FramerateNumerator:   numerator,
+			FramerateDenominator: denominator,
+			Profile:              "HIGH",
+			Level:                "H264_LEVEL_AUTO",
+			RateControlMode:      "CBR",
+		}
+	}
+	return description
+}
+
+// mediaLiveAudioDescription maps the spec's audio settings to AAC.
+func mediaLiveAudioDescription(spec models.ResourceSpec) models.AWSAudioDescription {
+	bitrate := spec.AudioBitrate
+	if bitrate == 0 {
+		bitrate = defaultAudioBitrate
+	}
+	codingMode := "CODING_MODE_2_0"
+	switch {
+	case spec.AudioChannels == 1:
+		codingMode = "CODING_MODE_1_0"
+	case spec.AudioChannels >= 6:
+		codingMode = "CODING_MODE_5_1"
+	}
+	return models.AWSAudioDescription{
+		Name:              mediaLiveAudio,
+		AudioSelectorName: "default",
+		CodecSettings: models.AWSAudioCodecSettings{AacSettings: &models.AWSAacSettings{
+			Bitrate:    float64(bitrate),
+			SampleRate: 48000,
+			CodingMode: codingMode,
+		}},
+	}
+}
+
+// mediaLiveInput picks the input tier that fits the source.
+func mediaLiveInput(spec models.ResourceSpec) models.AWSInputSpec {
+	input := models.AWSInputSpec{Codec: "AVC", Resolution: "HD", MaximumBitrate: "MAX_20_MBPS"}
+	if NormalizeCodec(spec.Codec) == "H.265/HEVC" {
+		input.Codec = "HEVC"
+	}
+	switch _, height := pixelSize(spec.Resolution); {
+	case height > 1080:
+		input.Resolution, input.MaximumBitrate = "UHD", "MAX_50_MBPS"
+	case height > 0 && height <= 480:
+		input.Resolution, input.MaximumBitrate = "SD", "MAX_10_MBPS"
+	}
+	return input
+}
+
+// rtmpDestination points at url, with the stream key (its last path
+// element) suffixed by the rendition name when there are several.
+func rtmpDestination(id, url, rendition string, suffix bool) models.AWSDestination {
+	base, key := url, ""
+	if i := strings.LastIndex(url, "/"); i > len(urlScheme(url))+3 {
+		base, key = url[:i], url[i+1:]
+	}
+	if suffix {
+		key += "_" + rendition
+	}
+	return models.AWSDestination{ID: id, Settings: []models.AWSDestinationSettings{{URL: base, StreamName: key}}}
+}
+
+// pixelSize returns the frame size of a resolution name or WIDTHxHEIGHT
+// value (0x0 if unknown).
+func pixelSize(resolution string) (int, int) {
+	switch NormalizeResolution(resolution) {
+	case "SD":
+		return 720, 480
+	case "HD":
+		return 1280, 720
+	case "FHD":
+		return 1920, 1080
+	case "4K":
+		return 3840, 2160
+	case "8K":
+		return 7680, 4320
+	}
+	var width, height int
+	if _, err := fmt.Sscanf(strings.ToLower(resolution), "%dx%d", &width, &height); err != nil {
+		return 0, 0
+	}
+	return width, height
+}
+
+// framerateFraction expresses fps as MediaLive's numerator/denominator:
+// NTSC rates (29.97, 59.94, 23.976) over 1001, whole rates over 1.
+func framerateFraction(fps float64) (int, int) {
+	if fps <= 0 {
+		return 30, 1
+	}
+	if whole := math.Round(fps); math.Abs(fps-whole) < 0.001 {
+		return int(whole), 1
+	}
+	return int(math.Round(fps*1.001)) * 1000, 1001
+}
+
+// configString reads a string from spec.config.
+func configString(spec models.ResourceSpec, key, def string) string {
+	if v, ok := spec.Config[key].(string); ok && v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,148 @@
+package convert
+
+import (
+	"sort"
+	"strings"
+)
+
+// =============================================================================
+// VENDOR PROFILES
+// =============================================================================
+// A Profile describes what a vendor can do in Forge terms. Conversion
+// keeps everything the target supports and maps the rest to the closest
+// supported value, or drops it and reports it as unmappable.
+// =============================================================================
+
+// Profile describes one vendor's capabilities.
+type Profile struct {
+	// Vendor is the spec.vendor_type value ("sony", "aws").
+	Vendor string
+
+	// Description is a human-readable name for messages.
+	Description string
+
+	// Resolutions are the supported Forge resolution names, lowest first.
+	Resolutions []string
+
+	// Codecs are the supported Forge codec names, most preferred first.
+	Codecs []string
+
+	// MaxBitrate is the highest bitrate (bps) per resolution.
+	MaxBitrate map[string]int64
+
+	// StreamSchemes are the supported stream_url schemes.
+	StreamSchemes []string
+
+	// RecordingSchemes are the allowed recording_path URL schemes.
+	// "" allows plain filesystem paths; nil allows anything.
+	RecordingSchemes []string
+
+	// ConfigKeys are spec.config keys only this vendor understands.
+	// Keys not listed in any profile are considered generic and carried over.
+	ConfigKeys []string
+}
+
+// Profiles are the known vendors, keyed by vendor type.
+var Profiles = map[string]*Profile{
+	"sony": {
+		Vendor:      "sony",
+		Description: "Sony broadcast devices",
+		Resolutions: []string{"SD", "HD", "FHD", "4K", "8K"},
+		Codecs:      []string{"H.264", "H.265/HEVC", "ProRes", "DNxHD"},
+		// WHY SO HIGH: Mezzanine codecs (ProRes, DNxHD) run at hundreds of Mbps
+		MaxBitrate: map[string]int64{
+			"SD": 50_000_000, "HD": 220_000_000, "FHD": 440_000_000,
+			"4K": 1_000_000_000, "8K": 2_000_000_000,
+		},
+		StreamSchemes: []string{"rtmp", "srt", "rtsp", "ndi"},
+		ConfigKeys: []string{
+			"sony_model", "ip_address", "port",
+			"recording_format", "recording_quality",
+			"vlan_id", "network_interface", "mtu",
+			"tally_enabled", "tally_color", "tally_protocol", "tally_address",
+		},
+	},
+	"aws": {
+		Vendor:      "aws",
+		Description: "AWS MediaLive channels",
+		// MediaLive tops out at UHD
+		Resolutions: []string{"SD", "HD", "FHD", "4K"},
+		Codecs:      []string{"H.264", "H.265/HEVC", "AV1"},
+		// Matches the MediaLive input tiers (see models.AWSInputSpec)
+		MaxBitrate: map[string]int64{
+			"SD": 10_000_000, "HD": 20_000_000, "FHD": 20_000_000, "4K": 50_000_000,
+		},
+		StreamSchemes:    []string{"rtmp", "rtmps", "srt", "https", "http"},
+		RecordingSchemes: []string{"s3"},
+		ConfigKeys:       []string{"aws_region", "aws_channel_class", "aws_role_arn"},
+	},
+}
+
+// Vendors returns the known vendor types, sorted.
+func Vendors() []string {
+	names := make([]string, 0, len(Profiles))
+	for name := range Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NormalizeResolution maps aliases ("1080p", "UHD", "1920x1080") to Forge
+// resolution names. Unknown values are returned unchanged.
+func NormalizeResolution(resolution string) string {
+	switch strings.ToUpper(resolution) {
+	case "SD", "480P", "720X480":
+		return "SD"
+	case "HD", "720P", "1280X720":
+		return "HD"
+	case "FHD", "1080P", "1920X1080":
+		return "FHD"
+	case "4K", "UHD", "2160P", "3840X2160":
+		return "4K"
+	case "8K", "4320P", "7680X4320":
+		return "8K"
+	default:
+		return resolution
+	}
+}
+
+// NormalizeCodec maps aliases ("HEVC", "AVC", "h264") to Forge codec
+// names. Unknown values are returned unchanged.
+func NormalizeCodec(codec string) string {
+	switch strings.ToUpper(strings.ReplaceAll(codec, ".", "")) {
+	case "H264", "AVC":
+		return "H.264"
+	case "H265", "HEVC", "H265/HEVC":
+		return "H.265/HEVC"
+	case "AV1":
+		return "AV1"
+	case "PRORES":
+		return "ProRes"
+	case "DNXHD", "DNXHR":
+		return "DNxHD"
+	default:
+		return codec
+	}
+}
+
+func (p *Profile) supportsResolution(resolution string) bool {
+	return contains(p.Resolutions, resolution)
+}
+
+func (p *Profile) supportsCodec(codec string) bool {
+	return contains(p.Codecs, codec)
+}
+
+func (p *Profile) hasConfigKey(key string) bool {
+	return contains(p.ConfigKeys, key)
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
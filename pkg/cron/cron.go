@@ -0,0 +1,247 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// =============================================================================
+// CRON EXPRESSIONS
+// =============================================================================
+// A Schedule is a standard five-field cron expression:
+//
+//   ┌ minute (0-59)
+//   │ ┌ hour (0-23)
+//   │ │ ┌ day of month (1-31)
+//   │ │ │ ┌ month (1-12 or jan-dec)
+//   │ │ │ │ ┌ day of week (0-6 or sun-sat; 7 is Sunday too)
+//   0 3 * * *          03:00 every day
+//   */15 8-18 * * mon-fri   every 15 minutes in office hours
+//
+// Each field is "*", a value, a range "a-b", a list "a,b,c", and any of
+// these with a step "/n". Shortcuts: @hourly, @daily (@midnight), @weekly,
+// @monthly, @yearly (@annually).
+//
+// As in Vixie cron, when both day of month and day of week are
+// restricted, a day matching EITHER runs: "0 0 1 * mon" is the 1st and
+// every Monday. A field that allows every day ("*/1", "1-31") is not a
+// restriction: "0 0 */1 * mon" runs on Mondays only.
+//
+// Next works in the location of the time it is given, so a schedule
+// follows that zone's daylight saving changes: a time skipped by the
+// change doesn't run, a repeated one runs once.
+// =============================================================================
+
+// maxSearchYears bounds Next for expressions that rarely or never match
+// ("0 0 30 2 *").
+const maxSearchYears = 5
+
+// shortcuts are the @ forms and their expressions.
+var shortcuts = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// field describes one position of the expression.
+type field struct {
+	name     string
+	min, max int
+	names    []string // names for min, min+1, ... (nil = numbers only)
+}
+
+var fields = []field{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	// WHY max 7: Both 0 and 7 are Sunday; 7 is folded into 0 after parsing
+	{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+// Schedule is a parsed cron expression.
+type Schedule struct {
+	expr string
+
+	// One bit per allowed value
+	minute, hour, dom, month, dow uint64
+
+	// domAny/dowAny are set for a day field that allows every day ("*",
+	// "*/1", "1-31", "0-6", ...)
+	domAny, dowAny bool
+}
+
+// Parse parses a cron expression.
+func Parse(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	spec := expr
+	if strings.HasPrefix(spec, "@") {
+		var ok bool
+		if spec, ok = shortcuts[strings.ToLower(spec)]; !ok {
+			return nil, fmt.Errorf("unknown shortcut %q (want @hourly, @daily, @weekly, @monthly or @yearly)", expr)
+		}
+	}
+	parts := strings.Fields(spec)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("%q has %d fields, want 5 (minute hour day-of-month month day-of-week)", expr, len(parts))
+	}
+	masks := make([]uint64, len(fields))
+	for i, part := range parts {
+		mask, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fields[i].name, err)
+		}
+		masks[i] = mask
+	}
+	// Sunday as 7 is Sunday as 0
+	if masks[4]&(1<<7) != 0 {
+		masks[4] = masks[4]&^(1<<7) | 1
+	}
+	return &Schedule{
+		expr:   expr,
+		minute: masks[0],
+		hour:   masks[1],
+		dom:    masks[2],
+		month:  masks[3],
+		dow:    masks[4],
+		domAny: masks[2] == spanMask(fields[2].min, fields[2].max),
+		dowAny: masks[4] == spanMask(0, 6),
+	}, nil
+}
+
+// spanMask returns the mask allowing every value from lo to hi.
+func spanMask(lo, hi int) uint64 {
+	var mask uint64
+	for v := lo; v <= hi; v++ {
+		mask |= 1 << uint(v)
+	}
+	return mask
+}
+
+// parseField parses one comma-separated field into a bit mask.
+func parseField(part string, f field) (uint64, error) {
+	var mask uint64
+	for _, item := range strings.Split(part, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+			step = n
+		}
+		var lo, hi int
+		switch {
+		case rangePart == "*":
+			lo, hi = f.min, f.max
+		case strings.Contains(rangePart, "-"):
+			from, to, _ := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = parseValue(from, f); err != nil {
+				return 0, err
+			}
+			if hi, err = parseValue(to, f); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("range %q runs backwards", rangePart)
+			}
+		default:
+			v, err := parseValue(rangePart, f)
+			if err != nil {
+				return 0, err
+			}
+			// WHY: "5/15" means from 5 to the end in steps of 15
+			lo, hi = v, v
+			if hasStep {
+				hi = f.max
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			mask |= 1 << uint(v)
+		}
+	}
+	return mask, nil
+}
+
+// parseValue parses a number or name of field f.
+func parseValue(s string, f field) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("%d is out of range %d-%d", v, f.min, f.max)
+	}
+	return v, nil
+}
+
+// String returns the expression as it was parsed.
+func (s *Schedule) String() string {
+	return s.expr
+}
+
+// Next returns the first time after t that matches, in t's location, or
+// the zero time if nothing matches within five years.
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	// WHY Date, NOT Truncate: Truncate rounds absolute time, which is off
+	// for zones with a sub-hour offset
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc).Add(time.Minute)
+	limit := t.Year() + maxSearchYears
+	for t.Year() <= limit {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			next := time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			// WHY: When clocks go back, hour+1 can land on the same instant;
+			// then take minute 0 of the hour an hour on, by the local clock
+			if !next.After(t) {
+				next = t.Add(time.Hour)
+				next = next.Add(-time.Duration(next.Minute()) * time.Minute)
+			}
+			t = next
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		case repeated(t):
+			// The second pass through an hour the clocks went back over
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// repeated reports whether t's wall clock time already occurred an hour
+// earlier (clocks went back).
+func repeated(t time.Time) bool {
+	earlier := t.Add(-time.Hour)
+	return earlier.Hour() == t.Hour() && earlier.Day() == t.Day()
+}
+
+// dayMatches reports whether t's day passes the day-of-month and
+// day-of-week fields.
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,172 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/client"
+)
+
+// =============================================================================
+// DNS REGISTRATION BACKENDS
+// =============================================================================
+// Every provisioned device used to need a hand-made DNS record before
+// anyone could reach "cam-1.studio-a.devices.example.com". The controller
+// registers them itself (see cmd/controller/dns.go); this package is the
+// part that talks to the DNS system:
+//
+//   route53  Amazon Route 53 (ChangeResourceRecordSets, SigV4-signed)
+//   etcd     CoreDNS with the etcd plugin (SkyDNS keys via the etcd v3
+//            JSON gateway)
+//   rfc2136  any server taking dynamic updates (BIND, Knot, PowerDNS),
+//            optionally TSIG-signed
+//
+// A Backend only upserts and deletes single address records; which
+// records should exist is the controller's business.
+// =============================================================================
+
+// EgressName is the provider name backends' HTTP calls are checked under
+// (see client.WithProvider).
+const EgressName = "dns"
+
+// Record is one address record.
+type Record struct {
+	// Name is the fully qualified name, without the trailing dot
+	Name string `json:"name"`
+
+	// Type is "A" or "AAAA"
+	Type    string `json:"type"`
+	Address string `json:"address"`
+	TTL     int    `json:"ttl"`
+}
+
+// Backend publishes records in a DNS system.
+type Backend interface {
+	// Name identifies the backend ("route53", "etcd", "rfc2136").
+	Name() string
+
+	// Upsert creates rec, replacing any records of its name and type.
+	Upsert(ctx context.Context, rec Record) error
+
+	// Delete removes the records of rec's name and type. Records that
+	// don't exist are not an error.
+	Delete(ctx context.Context, rec Record) error
+}
+
+// Config configures a Backend. Which fields apply depends on Type.
+type Config struct {
+	// Type: "route53", "etcd" or "rfc2136"
+	Type string
+
+	// Zone is the DNS zone records are created in ("devices.example.com")
+	Zone string
+
+	// route53: the hosted zone of Zone; Endpoint overrides the API URL;
+	// Signer is a client.SigV4Signer (service route53)
+	HostedZoneID string
+	Endpoint     string
+	Signer       client.Signer
+
+	// etcd: the etcd URL and the CoreDNS etcd plugin's path (default /skydns)
+	EtcdURL    string
+	EtcdPrefix string
+
+	// rfc2136: the primary server ("ns1.example.com:53") and an optional
+	// TSIG key (secret base64, algorithm hmac-sha256 or hmac-sha512)
+	Server        string
+	TSIGKeyName   string
+	TSIGSecret    string
+	TSIGAlgorithm string
+}
+
+// Build creates the Backend described by the config.
+func (cfg Config) Build() (Backend, error) {
+	if cfg.Zone == "" {
+		return nil, fmt.Errorf("dns: a zone is required")
+	}
+	switch cfg.Type {
+	case "route53":
+		if cfg.HostedZoneID == "" || cfg.Signer == nil {
+			return nil, fmt.Errorf("route53 requires a hosted zone ID and AWS credentials")
+		}
+		endpoint := cfg.Endpoint
+		if endpoint == "" {
+			endpoint = Route53Endpoint
+		}
+		return &Route53{HostedZoneID: strings.TrimPrefix(cfg.HostedZoneID, "/hostedzone/"), Endpoint: strings.TrimSuffix(endpoint, "/"), Signer: cfg.Signer}, nil
+	case "etcd":
+		if cfg.EtcdURL == "" {
+			return nil, fmt.Errorf("etcd requires the etcd URL")
+		}
+		prefix := cfg.EtcdPrefix
+		if prefix == "" {
+			prefix = "/skydns"
+		}
+		return &Etcd{URL: strings.TrimSuffix(cfg.EtcdURL, "/"), Prefix: "/" + strings.Trim(prefix, "/")}, nil
+	case "rfc2136":
+		if cfg.Server == "" {
+			return nil, fmt.Errorf("rfc2136 requires the server")
+		}
+		server := cfg.Server
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+		backend := &RFC2136{Server: server, Zone: cfg.Zone}
+		if cfg.TSIGKeyName != "" {
+			key, err := newTSIGKey(cfg.TSIGKeyName, cfg.TSIGSecret, cfg.TSIGAlgorithm)
+			if err != nil {
+				return nil, err
+			}
+			backend.TSIG = key
+		}
+		return backend, nil
+	default:
+		return nil, fmt.Errorf("unknown DNS backend %q (want route53, etcd or rfc2136)", cfg.Type)
+	}
+}
+
+// NewRecord returns the record pointing name at address: A for IPv4,
+// AAAA for IPv6. It reports false if address isn't an IP.
+func NewRecord(name, address string, ttl int) (Record, bool) {
+	ip := net.ParseIP(strings.TrimSpace(address))
+	if ip == nil {
+		return Record{}, false
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return Record{Name: name, Type: "A", Address: ip4.String(), TTL: ttl}, true
+	}
+	return Record{Name: name, Type: "AAAA", Address: ip.String(), TTL: ttl}, true
+}
+
+// Hostname derives a resource's name in zone: "<name>.<namespace>.<zone>",
+// each label lowercased with anything but letters, digits and hyphens
+// turned into hyphens (and cut to 63 characters).
+func Hostname(name, namespace, zone string) string {
+	if namespace == "" {
+		namespace = "default"
+	}
+	return label(name) + "." + label(namespace) + "." + strings.Trim(strings.ToLower(zone), ".")
+}
+
+// label turns s into a valid DNS label.
+func label(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('-')
+		}
+	}
+	out := b.String()
+	if len(out) > 63 {
+		out = out[:63]
+	}
+	out = strings.Trim(out, "-")
+	if out == "" {
+		return "x"
+	}
+	return out
+}
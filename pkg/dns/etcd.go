@@ -0,0 +1,82 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/client"
+)
+
+// Etcd registers records for CoreDNS's etcd plugin. A name is stored under
+// its labels reversed:
+//
+//	/skydns/com/example/devices/studio-a/cam-1  {"host": "10.0.1.50", "ttl": 60}
+//
+// through the etcd v3 JSON gateway (/v3/kv/put, /v3/kv/deleterange).
+type Etcd struct {
+	URL    string
+	Prefix string
+}
+
+// Name implements Backend.
+func (e *Etcd) Name() string { return "etcd" }
+
+// key returns the SkyDNS key of name.
+func (e *Etcd) key(name string) string {
+	labels := strings.Split(strings.Trim(name, "."), ".")
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+	return e.Prefix + "/" + strings.Join(labels, "/")
+}
+
+// Upsert implements Backend.
+// WHY ONE KEY FOR A AND AAAA: A SkyDNS entry's host is either; CoreDNS
+// answers A or AAAA queries from it
+func (e *Etcd) Upsert(ctx context.Context, rec Record) error {
+	value, err := json.Marshal(map[string]interface{}{"host": rec.Address, "ttl": rec.TTL})
+	if err != nil {
+		return err
+	}
+	return e.call(ctx, "/v3/kv/put", map[string]string{
+		"key":   base64.StdEncoding.EncodeToString([]byte(e.key(rec.Name))),
+		"value": base64.StdEncoding.EncodeToString(value),
+	})
+}
+
+// Delete implements Backend.
+func (e *Etcd) Delete(ctx context.Context, rec Record) error {
+	return e.call(ctx, "/v3/kv/deleterange", map[string]string{
+		"key": base64.StdEncoding.EncodeToString([]byte(e.key(rec.Name))),
+	})
+}
+
+// call POSTs body to the gateway's path.
+func (e *Etcd) call(ctx context.Context, path string, body interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	ctx = client.WithProvider(ctx, EgressName)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.DoWithRetry(ctx, req, 2)
+	if err != nil {
+		return fmt.Errorf("etcd %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return fmt.Errorf("etcd %s: %s: %s", path, resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+package dns
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// RFC2136 registers records with DNS UPDATE messages (RFC 2136) sent to
+// the zone's primary server, signed with TSIG (RFC 8945) when a key is
+// set. An upsert deletes the name's records of the type and adds the new
+// one in the same message, so the server applies both or neither.
+//
+// NOTE: The server's TSIG signature on the response isn't verified; its
+// rcode is what counts.
+type RFC2136 struct {
+	Server string
+	Zone   string
+	TSIG   *tsigKey
+}
+
+// DNS wire constants.
+const (
+	dnsOpcodeUpdate = 5
+	dnsClassIN      = 1
+	dnsClassAny     = 255
+	dnsTypeA        = 1
+	dnsTypeSOA      = 6
+	dnsTypeAAAA     = 28
+	dnsTypeTSIG     = 250
+	dnsFlagTC       = 1 << 9
+	tsigFudge       = 300
+)
+
+// dnsRcodes names the response codes an update can fail with.
+var dnsRcodes = map[int]string{
+	1: "FORMERR", 2: "SERVFAIL", 3: "NXDOMAIN", 4: "NOTIMP", 5: "REFUSED",
+	6: "YXDOMAIN", 7: "YXRRSET", 8: "NXRRSET", 9: "NOTAUTH", 10: "NOTZONE",
+}
+
+// tsigKey is a TSIG key.
+type tsigKey struct {
+	name      string
+	algorithm string
+	secret    []byte
+	hash      func() hash.Hash
+}
+
+// newTSIGKey decodes a TSIG key (secret in base64).
+func newTSIGKey(name, secret, algorithm string) (*tsigKey, error) {
+	decoded, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil || len(decoded) == 0 {
+		return nil, fmt.Errorf("TSIG secret must be base64")
+	}
+	key := &tsigKey{name: strings.ToLower(strings.Trim(name, ".")), secret: decoded}
+	switch strings.ToLower(strings.Trim(algorithm, ".")) {
+	case "", "hmac-sha256":
+		key.algorithm, key.hash = "hmac-sha256", sha256.New
+	case "hmac-sha512":
+		key.algorithm, key.hash = "hmac-sha512", sha512.New
+	default:
+		return nil, fmt.Errorf("unsupported TSIG algorithm %q (want hmac-sha256 or hmac-sha512)", algorithm)
+	}
+	return key, nil
+}
+
+// Name implements Backend.
+func (u *RFC2136) Name() string { return "rfc2136" }
+
+// Upsert implements Backend.
+func (u *RFC2136) Upsert(ctx context.Context, rec Record) error {
+	rrType, rdata, err := recordData(rec)
+	if err != nil {
+		return err
+	}
+	updates := [][]byte{
+		resourceRecord(rec.Name, rrType, dnsClassAny, 0, nil),
+		resourceRecord(rec.Name, rrType, dnsClassIN, uint32(rec.TTL), rdata),
+	}
+	return u.send(ctx, rec.Name, updates)
+}
+
+// Delete implements Backend.
+func (u *RFC2136) Delete(ctx context.Context, rec Record) error {
+	rrType, _, err := recordData(rec)
+	if err != nil {
+		return err
+	}
+	return u.send(ctx, rec.Name, [][]byte{resourceRecord(rec.Name, rrType, dnsClassAny, 0, nil)})
+}
+
+// send builds, signs and sends an update of name, and checks the answer.
+func (u *RFC2136) send(ctx context.Context, name string, updates [][]byte) error {
+	zone := strings.ToLower(strings.Trim(u.Zone, "."))
+	if !strings.HasSuffix(strings.ToLower(name), "."+zone) {
+		return fmt.Errorf("rfc2136: %s is not in zone %s", name, zone)
+	}
+
+	// Step 1: Header, zone section, update section
+	var idBytes [2]byte
+	rand.Read(idBytes[:])
+	id := binary.BigEndian.Uint16(idBytes[:])
+	msg := make([]byte, 12)
+	binary.BigEndian.PutUint16(msg[0:], id)
+	binary.BigEndian.PutUint16(msg[2:], dnsOpcodeUpdate<<11)
+	binary.BigEndian.PutUint16(msg[4:], 1) // ZOCOUNT
+	binary.BigEndian.PutUint16(msg[8:], uint16(len(updates)))
+	msg = append(msg, encodeName(zone)...)
+	msg = binary.BigEndian.AppendUint16(msg, dnsTypeSOA)
+	msg = binary.BigEndian.AppendUint16(msg, dnsClassIN)
+	for _, rr := range updates {
+		msg = append(msg, rr...)
+	}
+
+	// Step 2: TSIG as the one additional record
+	if u.TSIG != nil {
+		msg = u.TSIG.sign(msg, id, time.Now())
+	}
+
+	// Step 3: UDP, or TCP if the answer didn't fit
+	answer, err := exchange(ctx, "udp", u.Server, msg)
+	if err == nil && len(answer) >= 4 && binary.BigEndian.Uint16(answer[2:])&dnsFlagTC != 0 {
+		answer, err = exchange(ctx, "tcp", u.Server, msg)
+	}
+	if err != nil {
+		return fmt.Errorf("rfc2136 update of %s: %w", name, err)
+	}
+	if len(answer) < 12 || binary.BigEndian.Uint16(answer) != id {
+		return fmt.Errorf("rfc2136 update of %s: malformed answer from %s", name, u.Server)
+	}
+	if rcode := int(binary.BigEndian.Uint16(answer[2:]) & 0xF); rcode != 0 {
+		text := dnsRcodes[rcode]
+		if text == "" {
+			text = fmt.Sprintf("rcode %d", rcode)
+		}
+		return fmt.Errorf("rfc2136 update of %s: %s refused it: %s", name, u.Server, text)
+	}
+	return nil
+}
+
+// sign appends a TSIG record to msg (whose ID is id) and counts it.
+func (k *tsigKey) sign(msg []byte, id uint16, now time.Time) []byte {
+	signed := uint64(now.Unix())
+	timeBytes := []byte{byte(signed >> 40), byte(signed >> 32), byte(signed >> 24), byte(signed >> 16), byte(signed >> 8), byte(signed)}
+
+	// The MAC covers the message and the TSIG variables (RFC 8945 4.3.3)
+	mac := hmac.New(k.hash, k.secret)
+	mac.Write(msg)
+	mac.Write(encodeName(k.name))
+	binary.Write(mac, binary.BigEndian, uint16(dnsClassAny))
+	binary.Write(mac, binary.BigEndian, uint32(0)) // TTL
+	mac.Write(encodeName(k.algorithm))
+	mac.Write(timeBytes)
+	binary.Write(mac, binary.BigEndian, uint16(tsigFudge))
+	binary.Write(mac, binary.BigEndian, uint16(0)) // error
+	binary.Write(mac, binary.BigEndian, uint16(0)) // other len
+	sum := mac.Sum(nil)
+
+	rdata := encodeName(k.algorithm)
+	rdata = append(rdata, timeBytes...)
+	rdata = binary.BigEndian.AppendUint16(rdata, tsigFudge)
+	rdata = binary.BigEndian.AppendUint16(rdata, uint16(len(sum)))
+	rdata = append(rdata, sum...)
+	rdata = binary.BigEndian.AppendUint16(rdata, id)
+	rdata = binary.BigEndian.AppendUint16(rdata, 0) // error
+	rdata = binary.BigEndian.AppendUint16(rdata, 0) // other len
+
+	msg = append(msg, resourceRecord(k.name, dnsTypeTSIG, dnsClassAny, 0, rdata)...)
+	binary.BigEndian.PutUint16(msg[10:], binary.BigEndian.Uint16(msg[10:])+1) // ARCOUNT
+	return msg
+}
+
+// exchange sends msg to server over network and returns the answer.
+func exchange(ctx context.Context, network, server string, msg []byte) ([]byte, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	if network == "tcp" {
+		framed := binary.BigEndian.AppendUint16(nil, uint16(len(msg)))
+		if _, err := conn.Write(append(framed, msg...)); err != nil {
+			return nil, err
+		}
+		var length [2]byte
+		if _, err := io.ReadFull(conn, length[:]); err != nil {
+			return nil, err
+		}
+		answer := make([]byte, binary.BigEndian.Uint16(length[:]))
+		_, err := io.ReadFull(conn, answer)
+		return answer, err
+	}
+	if _, err := conn.Write(msg); err != nil {
+		return nil, err
+	}
+	answer := make([]byte, 4096)
+	n, err := conn.Read(answer)
+	if err != nil {
+		return nil, err
+	}
+	return answer[:n], nil
+}
+
+// recordData returns rec's wire type and RDATA.
+func recordData(rec Record) (uint16, []byte, error) {
+	ip := net.ParseIP(rec.Address)
+	switch {
+	case ip == nil:
+		return 0, nil, fmt.Errorf("rfc2136: %q is not an IP address", rec.Address)
+	case rec.Type == "A" && ip.To4() != nil:
+		return dnsTypeA, ip.To4(), nil
+	case rec.Type == "AAAA" && ip.To4() == nil:
+		return dnsTypeAAAA, ip.To16(), nil
+	}
+	return 0, nil, errors.New("rfc2136: record type " + rec.Type + " doesn't match its address")
+}
+
+// resourceRecord encodes one resource record.
+func resourceRecord(name string, rrType, class uint16, ttl uint32, rdata []byte) []byte {
+	rr := encodeName(name)
+	rr = binary.BigEndian.AppendUint16(rr, rrType)
+	rr = binary.BigEndian.AppendUint16(rr, class)
+	rr = binary.BigEndian.AppendUint32(rr, ttl)
+	rr = binary.BigEndian.AppendUint16(rr, uint16(len(rdata)))
+	return append(rr, rdata...)
+}
+
+// encodeName encodes a domain name in wire format (no compression).
+func encodeName(name string) []byte {
+	var out []byte
+	for _, part := range strings.Split(strings.Trim(name, "."), ".") {
+		if part == "" {
+			continue
+		}
+		out = append(out, byte(len(part)))
+		out = append(out, part...)
+	}
+	return append(out, 0)
+}
@@ -0,0 +1,87 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/client"
+)
+
+// Route53Endpoint is the Route 53 API.
+const Route53Endpoint = "https://route53.amazonaws.com"
+
+// Route53 registers records in a Route 53 hosted zone.
+type Route53 struct {
+	HostedZoneID string
+	Endpoint     string
+
+	// Signer signs requests (SigV4, region us-east-1, service route53)
+	Signer client.Signer
+}
+
+// route53Change is the body of ChangeResourceRecordSets.
+type route53Change struct {
+	XMLName xml.Name `xml:"https://route53.amazonaws.com/doc/2013-04-01/ ChangeResourceRecordSetsRequest"`
+	Comment string   `xml:"ChangeBatch>Comment"`
+	Action  string   `xml:"ChangeBatch>Changes>Change>Action"`
+	Name    string   `xml:"ChangeBatch>Changes>Change>ResourceRecordSet>Name"`
+	Type    string   `xml:"ChangeBatch>Changes>Change>ResourceRecordSet>Type"`
+	TTL     int      `xml:"ChangeBatch>Changes>Change>ResourceRecordSet>TTL"`
+	Value   string   `xml:"ChangeBatch>Changes>Change>ResourceRecordSet>ResourceRecords>ResourceRecord>Value"`
+}
+
+// Name implements Backend.
+func (r *Route53) Name() string { return "route53" }
+
+// Upsert implements Backend.
+func (r *Route53) Upsert(ctx context.Context, rec Record) error {
+	return r.change(ctx, "UPSERT", rec)
+}
+
+// Delete implements Backend.
+// WHY THE WHOLE RECORD: Route 53 deletes only a record set that matches
+// exactly (TTL and value included)
+func (r *Route53) Delete(ctx context.Context, rec Record) error {
+	return r.change(ctx, "DELETE", rec)
+}
+
+// change sends one ChangeResourceRecordSets request.
+func (r *Route53) change(ctx context.Context, action string, rec Record) error {
+	body, err := xml.Marshal(route53Change{
+		Comment: "Forge controller",
+		Action:  action,
+		Name:    rec.Name + ".",
+		Type:    rec.Type,
+		TTL:     rec.TTL,
+		Value:   rec.Address,
+	})
+	if err != nil {
+		return err
+	}
+	ctx = client.WithSigner(client.WithProvider(ctx, EgressName), r.Signer)
+	url := r.Endpoint + "/2013-04-01/hostedzone/" + r.HostedZoneID + "/rrset"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(append([]byte(xml.Header), body...)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/xml")
+	resp, err := client.DoWithRetry(ctx, req, 2)
+	if err != nil {
+		return fmt.Errorf("route53 %s %s: %w", action, rec.Name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	// WHY: Deleting what is already gone leaves the zone as wanted
+	if action == "DELETE" && resp.StatusCode == http.StatusBadRequest && strings.Contains(string(message), "not found") {
+		return nil
+	}
+	return fmt.Errorf("route53 %s %s: %s: %s", action, rec.Name, resp.Status, strings.TrimSpace(string(message)))
+}
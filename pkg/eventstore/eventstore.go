@@ -0,0 +1,454 @@
+package eventstore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// APPEND-ONLY EVENT LOG
+// =============================================================================
+// The event-sourced store backend: every change to a resource is one
+// Event, appended to a JSON-lines file. Nothing in the file is ever
+// rewritten. Current state is a projection of
+// the events (the last event of each resource, unless it is a deletion),
+// so any past state can be rebuilt exactly by replaying up to a point:
+//
+//	log, err := eventstore.Open("/var/lib/forge/events.jsonl")
+//	err = log.Replay(func(e eventstore.Event) error { apply(e); return nil })
+//	err = log.Append(eventstore.Event{Seq: 43, Type: models.WatchModified, ...})
+//	err = log.Sync()
+//
+// Sequence numbers must increase by one; they double as the watch API's
+// event IDs, so a watch can be resumed from the log after its buffer (or
+// the process) is gone.
+//
+// SYNCING: Append only writes; Sync makes everything appended so far
+// durable. The caller syncs before it confirms a change, so changes made
+// together share one fsync (and Append, which callers make under their
+// own locks, never waits on the disk).
+//
+// TRANSACTIONS: AppendTx writes several events as one write, each marked
+// with the Seq of the transaction's last event (TxLast). They are all in
+// the log or none is: a failed write is cut back (below), and Open drops
+// the events of a transaction whose last event never made it.
+//
+// CRASH SAFETY: A crash mid-append leaves a torn last line. Open drops it
+// (the API never answered for that change) so the next append starts on a
+// clean line. A failed write is cut back off the file at once; if even
+// that fails the log refuses further appends, since the file no longer
+// ends where the next event would start.
+//
+// READS: Open indexes where each event starts, its time and its resource,
+// so Read, ReadFrom, SeqAt and ResourceSeqs read only the events asked
+// for instead of the whole file.
+// =============================================================================
+
+// Event is one recorded change to a resource.
+type Event struct {
+	// Seq numbers every event in the log, starting at 1
+	Seq int64 `json:"seq"`
+
+	// Type is models.WatchAdded, WatchModified or WatchDeleted
+	Type string `json:"type"`
+
+	// TxLast is set on every event of a transaction: its last event's Seq
+	TxLast int64 `json:"tx_last,omitempty"`
+
+	models.ResourceRevision
+}
+
+// ErrOutOfOrder is returned by Append for an event whose Seq doesn't
+// follow the last one.
+var ErrOutOfOrder = errors.New("event out of order")
+
+// Stats describes the log.
+type Stats struct {
+	Path    string `json:"path"`
+	Events  int64  `json:"events"`
+	Bytes   int64  `json:"bytes"`
+	LastSeq int64  `json:"last_seq"`
+
+	// Dropped is 1 if Open removed a torn last line
+	Dropped int `json:"dropped_torn_lines,omitempty"`
+
+	// DroppedTx counts the events Open removed because the rest of their
+	// transaction was missing
+	DroppedTx int64 `json:"dropped_tx_events,omitempty"`
+}
+
+// position locates one event in the file.
+type position struct {
+	offset int64  // where its line starts
+	end    int64  // just past its newline
+	time   int64  // its timestamp, Unix nanoseconds
+	id     string // its resource
+}
+
+// Log is an open event log. Safe for concurrent use.
+type Log struct {
+	mu    sync.Mutex
+	path  string
+	file  *os.File
+	stats Stats
+
+	// index[seq-1] is event seq; byResource lists each resource's events
+	index      []position
+	byResource map[string][]int64
+
+	// broken is set when a failed write couldn't be cut back off the file
+	broken error
+
+	// syncMu serializes Sync; synced is how far the file is known durable
+	syncMu sync.Mutex
+	synced int64
+}
+
+// Open opens (or creates) the log at path and checks it.
+func Open(path string) (*Log, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event log: %w", err)
+	}
+	l := &Log{path: path, file: file, stats: Stats{Path: path}, byResource: make(map[string][]int64)}
+
+	// Find the end of the last complete event, indexing the way there;
+	// open is the index of the first event of an unfinished transaction
+	var good int64
+	open := -1
+	err = scan(file, func(e Event, end int64) error {
+		if e.Seq != l.stats.LastSeq+1 {
+			return fmt.Errorf("%s: event %d follows %d: %w", path, e.Seq, l.stats.LastSeq, ErrOutOfOrder)
+		}
+		switch {
+		case e.TxLast <= e.Seq:
+			open = -1
+		case open < 0:
+			open = len(l.index)
+		}
+		l.indexLocked(e, good, end)
+		good = end
+		return nil
+	})
+	var torn *tornLineError
+	switch {
+	case errors.As(err, &torn):
+		l.stats.Dropped = 1
+	case err != nil:
+		file.Close()
+		return nil, err
+	}
+	if open >= 0 {
+		good = l.index[open].offset
+		l.stats.DroppedTx = int64(len(l.index) - open)
+		l.truncateIndexLocked(open)
+	}
+	if err := file.Truncate(good); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to truncate event log: %w", err)
+	}
+	if _, err := file.Seek(good, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	l.stats.Bytes, l.synced = good, good
+	return l, nil
+}
+
+// indexLocked records event e, stored at [offset, end). Caller must hold
+// l.mu (or own l, in Open).
+func (l *Log) indexLocked(e Event, offset, end int64) {
+	l.index = append(l.index, position{offset: offset, end: end, time: e.Timestamp.UnixNano(), id: e.Resource.ID})
+	l.byResource[e.Resource.ID] = append(l.byResource[e.Resource.ID], e.Seq)
+	l.stats.LastSeq = e.Seq
+	l.stats.Events++
+	l.stats.Bytes = end
+}
+
+// truncateIndexLocked forgets the events from index n on.
+func (l *Log) truncateIndexLocked(n int) {
+	for i := len(l.index) - 1; i >= n; i-- {
+		id := l.index[i].id
+		if seqs := l.byResource[id][:len(l.byResource[id])-1]; len(seqs) > 0 {
+			l.byResource[id] = seqs
+		} else {
+			delete(l.byResource, id)
+		}
+	}
+	l.index = l.index[:n]
+	l.stats.LastSeq = int64(n)
+	l.stats.Events = int64(n)
+	if n > 0 {
+		l.stats.Bytes = l.index[n-1].end
+	} else {
+		l.stats.Bytes = 0
+	}
+}
+
+// Append writes e to the log. It isn't durable until Sync.
+// On error nothing of e is left in the file (or the log is broken and
+// every later Append fails too).
+func (l *Log) Append(e Event) error {
+	return l.AppendTx([]Event{e})
+}
+
+// AppendTx writes events, numbered consecutively, as one transaction: a
+// reader after a crash sees all of them or none. It isn't durable until
+// Sync. On error nothing of them is left in the file.
+func (l *Log) AppendTx(events []Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	var data []byte
+	lines := make([]int, len(events))
+	for i, e := range events {
+		if len(events) > 1 {
+			e.TxLast = events[len(events)-1].Seq
+			events[i] = e
+		}
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+		lines[i] = len(data)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.broken != nil {
+		return l.broken
+	}
+	for i, e := range events {
+		if e.Seq != l.stats.LastSeq+1+int64(i) {
+			return fmt.Errorf("event %d after %d: %w", e.Seq, l.stats.LastSeq+int64(i), ErrOutOfOrder)
+		}
+	}
+	if _, err := l.file.Write(data); err != nil {
+		// WHY CUT BACK: Part of the line may have been written; left there,
+		// the next event would be glued onto it
+		if cutErr := l.cutLocked(); cutErr != nil {
+			l.broken = fmt.Errorf("event log is broken: %v (after %w)", cutErr, err)
+			return l.broken
+		}
+		return err
+	}
+	start, offset := l.stats.Bytes, 0
+	for i, e := range events {
+		l.indexLocked(e, start+int64(offset), start+int64(lines[i]))
+		offset = lines[i]
+	}
+	return nil
+}
+
+// cutLocked truncates the file back to the end of the last event.
+func (l *Log) cutLocked() error {
+	if err := l.file.Truncate(l.stats.Bytes); err != nil {
+		return err
+	}
+	_, err := l.file.Seek(l.stats.Bytes, io.SeekStart)
+	return err
+}
+
+// Sync makes every event appended so far durable. Concurrent callers
+// share one fsync.
+func (l *Log) Sync() error {
+	l.syncMu.Lock()
+	defer l.syncMu.Unlock()
+	l.mu.Lock()
+	size := l.stats.Bytes
+	l.mu.Unlock()
+	if l.synced >= size {
+		return nil
+	}
+	// WHY NOT UNDER l.mu: Appends carry on while the disk catches up;
+	// what they add is synced by the next call
+	if err := l.file.Sync(); err != nil {
+		return err
+	}
+	l.synced = size
+	return nil
+}
+
+// Unsynced returns how many bytes were appended since the last Sync.
+func (l *Log) Unsynced() int64 {
+	l.syncMu.Lock()
+	synced := l.synced
+	l.syncMu.Unlock()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stats.Bytes - synced
+}
+
+// Read returns event seq.
+func (l *Log) Read(seq int64) (Event, error) {
+	l.mu.Lock()
+	if seq < 1 || seq > int64(len(l.index)) {
+		l.mu.Unlock()
+		return Event{}, fmt.Errorf("no event %d (the log has %d)", seq, len(l.index))
+	}
+	pos := l.index[seq-1]
+	l.mu.Unlock()
+
+	data := make([]byte, pos.end-pos.offset)
+	if _, err := l.file.ReadAt(data, pos.offset); err != nil {
+		return Event{}, fmt.Errorf("failed to read event %d: %w", seq, err)
+	}
+	var e Event
+	if err := json.Unmarshal(bytes.TrimSpace(data), &e); err != nil {
+		return Event{}, fmt.Errorf("event log is corrupt at event %d: %w", seq, err)
+	}
+	return e, nil
+}
+
+// ReadFrom calls fn for every event after since, in order, up to what was
+// appended when it started, and returns the last sequence number that
+// covers. An error from fn stops it and is returned.
+func (l *Log) ReadFrom(since int64, fn func(Event) error) (int64, error) {
+	l.mu.Lock()
+	upTo, size := l.stats.LastSeq, l.stats.Bytes
+	var offset int64
+	if since < 0 {
+		since = 0
+	}
+	if since < int64(len(l.index)) {
+		offset = l.index[since].offset
+	} else {
+		offset = size
+	}
+	l.mu.Unlock()
+
+	// WHY A SECTION: ReadAt leaves the file position (where Append
+	// writes) alone
+	err := scan(io.NewSectionReader(l.file, offset, size-offset), func(e Event, end int64) error {
+		return fn(e)
+	})
+	return upTo, err
+}
+
+// SeqAt returns the last event at or before t (0 if there is none).
+// Events are appended in time order, so this is a binary search.
+func (l *Log) SeqAt(t time.Time) int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	at := t.UnixNano()
+	return int64(sort.Search(len(l.index), func(i int) bool { return l.index[i].time > at }))
+}
+
+// ResourceSeqs returns resource id's events, oldest first.
+func (l *Log) ResourceSeqs(id string) []int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]int64(nil), l.byResource[id]...)
+}
+
+// LastFor returns resource id's last event at or before upTo (0 if there
+// is none).
+func (l *Log) LastFor(id string, upTo int64) int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	seqs := l.byResource[id]
+	i := sort.Search(len(seqs), func(i int) bool { return seqs[i] > upTo })
+	if i == 0 {
+		return 0
+	}
+	return seqs[i-1]
+}
+
+// ResourceIDs returns every resource the log has events for.
+func (l *Log) ResourceIDs() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ids := make([]string, 0, len(l.byResource))
+	for id := range l.byResource {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Replay calls fn for every event in order. It reads what was appended
+// when it started. An error from fn stops the replay and is returned.
+func (l *Log) Replay(fn func(Event) error) error {
+	_, err := l.ReadFrom(0, fn)
+	return err
+}
+
+// Stats returns the log's size and position.
+func (l *Log) Stats() Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stats
+}
+
+// Close syncs and closes the file.
+func (l *Log) Close() error {
+	syncErr := l.Sync()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	return syncErr
+}
+
+// tornLineError reports an incomplete or unreadable last line.
+type tornLineError struct {
+	line int
+	err  error
+}
+
+func (e *tornLineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.line, e.err)
+}
+
+// scan decodes the events in r, passing each with the offset just past
+// its line. A bad line is a *tornLineError if nothing follows it, and
+// corruption otherwise.
+func scan(r io.Reader, fn func(e Event, end int64) error) error {
+	reader := bufio.NewReaderSize(r, 64*1024)
+	var offset int64
+	line := 0
+	for {
+		data, err := reader.ReadBytes('\n')
+		if len(data) == 0 && err == io.EOF {
+			return nil
+		}
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("failed to read event log: %w", err)
+		}
+		line++
+		offset += int64(len(data))
+
+		var e Event
+		decodeErr := json.Unmarshal(bytes.TrimSpace(data), &e)
+		if err == io.EOF || (decodeErr != nil && isLast(reader)) {
+			// No newline: the append never finished
+			if decodeErr == nil {
+				decodeErr = errors.New("missing newline")
+			}
+			return &tornLineError{line: line, err: decodeErr}
+		}
+		if decodeErr != nil {
+			return fmt.Errorf("event log is corrupt at line %d: %w", line, decodeErr)
+		}
+		if err := fn(e, offset); err != nil {
+			return err
+		}
+	}
+}
+
+// isLast reports whether reader has nothing left.
+func isLast(reader *bufio.Reader) bool {
+	_, err := reader.Peek(1)
+	return err == io.EOF
+}
@@ -0,0 +1,349 @@
+package fairqueue
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/clock"
+)
+
+// =============================================================================
+// WEIGHTED FAIR WORK QUEUE
+// =============================================================================
+// Queue hands out work items fairly across vendors, and within a vendor
+// across namespaces, instead of first-in first-out. A vendor with 10,000
+// resources queued gets its weighted share of the workers, not all of
+// them, so a 20-resource vendor behind it is still served right away.
+//
+// HOW: Start-time fair queuing, on two levels. Every flow (a vendor, or a
+// namespace within a vendor) has a virtual time that advances by
+// 1/weight each time it is served; the non-empty flow with the lowest
+// virtual time goes next. A flow that was idle rejoins at the current
+// virtual time, so it can't save up credit while it had nothing queued.
+//
+// Vendors are also capped at MaxPerVendor items in flight: a worker must
+// never sit waiting on a saturated vendor while others have work.
+//
+// Keys are deduplicated: adding a key that is already queued is a no-op,
+// and adding one that is being processed queues it again once it's Done.
+// =============================================================================
+
+// ErrShutDown is returned by Get once the queue is shut down.
+var ErrShutDown = errors.New("queue shut down")
+
+// Item is one unit of work.
+type Item struct {
+	// Key identifies the work (e.g. a resource ID); it is deduplicated
+	Key string
+
+	// Vendor and Namespace select the flow the item is queued in
+	Vendor    string
+	Namespace string
+
+	// Added is when the item was queued (set by Add)
+	Added time.Time
+}
+
+// Options configures a Queue.
+type Options struct {
+	// MaxPerVendor caps items in flight per vendor (0 = no cap)
+	MaxPerVendor int
+
+	// VendorWeights and NamespaceWeights set flow shares (default 1).
+	// A vendor with weight 2 is served twice as often as one with 1.
+	VendorWeights    map[string]float64
+	NamespaceWeights map[string]float64
+}
+
+// flow is a FIFO of items with a virtual time.
+type flow struct {
+	name   string
+	weight float64
+	vtime  float64
+}
+
+type namespaceFlow struct {
+	flow
+	items    []Item
+	inFlight int
+}
+
+type vendorFlow struct {
+	flow
+	namespaces map[string]*namespaceFlow
+	clock      float64 // virtual time of the namespace last served
+	queued     int
+	inFlight   int
+	processed  int64
+}
+
+// Queue is a weighted fair work queue. Safe for concurrent use.
+type Queue struct {
+	options Options
+	clock   clock.Clock
+
+	mu         sync.Mutex
+	vendors    map[string]*vendorFlow
+	vclock     float64 // virtual time of the vendor last served
+	queued     map[string]bool
+	processing map[string]Item
+	requeue    map[string]Item // added again while processing
+	wake       chan struct{}
+	shutDown   bool
+}
+
+// New creates an empty Queue.
+func New(options Options) *Queue {
+	return &Queue{
+		options:    options,
+		clock:      clock.Real{},
+		vendors:    make(map[string]*vendorFlow),
+		queued:     make(map[string]bool),
+		processing: make(map[string]Item),
+		requeue:    make(map[string]Item),
+		wake:       make(chan struct{}),
+	}
+}
+
+// SetClock replaces the clock used for item ages (tests use a clock.Fake).
+// Call it before the queue is in use.
+func (q *Queue) SetClock(c clock.Clock) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.clock = c
+}
+
+// weight returns the configured weight for name (default 1).
+func weight(weights map[string]float64, name string) float64 {
+	if w, ok := weights[name]; ok && w > 0 {
+		return w
+	}
+	return 1
+}
+
+// Add queues item. Returns false if its key was already queued.
+func (q *Queue) Add(item Item) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.shutDown || q.queued[item.Key] {
+		return false
+	}
+	if _, busy := q.processing[item.Key]; busy {
+		if _, again := q.requeue[item.Key]; again {
+			return false
+		}
+		q.requeue[item.Key] = item
+		return true
+	}
+	q.enqueueLocked(item)
+	return true
+}
+
+func (q *Queue) enqueueLocked(item Item) {
+	item.Added = q.clock.Now()
+	v, ok := q.vendors[item.Vendor]
+	if !ok {
+		v = &vendorFlow{
+			flow:       flow{name: item.Vendor, weight: weight(q.options.VendorWeights, item.Vendor)},
+			namespaces: make(map[string]*namespaceFlow),
+		}
+		q.vendors[item.Vendor] = v
+	}
+	// WHY CATCH UP: An idle flow rejoins at the current virtual time
+	// instead of spending credit saved up while it had nothing queued
+	if v.queued == 0 && v.vtime < q.vclock {
+		v.vtime = q.vclock
+	}
+	ns, ok := v.namespaces[item.Namespace]
+	if !ok {
+		ns = &namespaceFlow{flow: flow{name: item.Namespace, weight: weight(q.options.NamespaceWeights, item.Namespace), vtime: v.clock}}
+		v.namespaces[item.Namespace] = ns
+	}
+	if len(ns.items) == 0 && ns.vtime < v.clock {
+		ns.vtime = v.clock
+	}
+	ns.items = append(ns.items, item)
+	v.queued++
+	q.queued[item.Key] = true
+	q.notifyLocked()
+}
+
+// notifyLocked wakes every waiting Get.
+func (q *Queue) notifyLocked() {
+	close(q.wake)
+	q.wake = make(chan struct{})
+}
+
+// Get blocks until an item is available and returns it. The caller must
+// call Done with it when finished.
+func (q *Queue) Get(ctx context.Context) (Item, error) {
+	for {
+		q.mu.Lock()
+		if q.shutDown {
+			q.mu.Unlock()
+			return Item{}, ErrShutDown
+		}
+		if item, ok := q.pickLocked(); ok {
+			q.mu.Unlock()
+			return item, nil
+		}
+		wake := q.wake
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return Item{}, ctx.Err()
+		case <-wake:
+		}
+	}
+}
+
+// pickLocked dequeues the next item in fair order, if any is eligible.
+func (q *Queue) pickLocked() (Item, bool) {
+	var v *vendorFlow
+	for _, candidate := range q.vendors {
+		if candidate.queued == 0 {
+			continue
+		}
+		if q.options.MaxPerVendor > 0 && candidate.inFlight >= q.options.MaxPerVendor {
+			continue
+		}
+		if v == nil || before(&candidate.flow, &v.flow) {
+			v = candidate
+		}
+	}
+	if v == nil {
+		return Item{}, false
+	}
+	var ns *namespaceFlow
+	for _, candidate := range v.namespaces {
+		if len(candidate.items) > 0 && (ns == nil || before(&candidate.flow, &ns.flow)) {
+			ns = candidate
+		}
+	}
+
+	item := ns.items[0]
+	ns.items = ns.items[1:]
+	ns.inFlight++
+	v.queued--
+	v.inFlight++
+
+	q.vclock, v.vtime = v.vtime, v.vtime+1/v.weight
+	v.clock, ns.vtime = ns.vtime, ns.vtime+1/ns.weight
+
+	delete(q.queued, item.Key)
+	q.processing[item.Key] = item
+	return item, true
+}
+
+// before orders flows by virtual time, then name (for determinism).
+func before(a, b *flow) bool {
+	if a.vtime != b.vtime {
+		return a.vtime < b.vtime
+	}
+	return a.name < b.name
+}
+
+// Done marks item finished. If its key was added while it was being
+// processed, it is queued again.
+func (q *Queue) Done(item Item) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.processing[item.Key]; !ok {
+		return
+	}
+	delete(q.processing, item.Key)
+	if v, ok := q.vendors[item.Vendor]; ok {
+		v.inFlight--
+		v.processed++
+		if ns, ok := v.namespaces[item.Namespace]; ok {
+			ns.inFlight--
+			// WHY DELETE: Namespaces come and go; an empty flow has no
+			// state worth keeping (it rejoins at the current virtual time)
+			if len(ns.items) == 0 && ns.inFlight == 0 {
+				delete(v.namespaces, item.Namespace)
+			}
+		}
+	}
+	if again, ok := q.requeue[item.Key]; ok {
+		delete(q.requeue, item.Key)
+		if !q.shutDown {
+			q.enqueueLocked(again)
+			return
+		}
+	}
+	q.notifyLocked()
+}
+
+// ShutDown stops the queue: Get returns ErrShutDown and Add is refused.
+func (q *Queue) ShutDown() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.shutDown = true
+	q.notifyLocked()
+}
+
+// Len returns the number of queued (not in-flight) items.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.queued)
+}
+
+// FlowStats describes one namespace queue within a vendor.
+type FlowStats struct {
+	Namespace string  `json:"namespace"`
+	Weight    float64 `json:"weight"`
+	Depth     int     `json:"depth"`
+	InFlight  int     `json:"in_flight"`
+
+	// OldestAgeSeconds is how long the oldest queued item has waited
+	OldestAgeSeconds float64 `json:"oldest_age_seconds"`
+}
+
+// VendorStats describes a vendor's queues.
+type VendorStats struct {
+	Vendor           string      `json:"vendor"`
+	Weight           float64     `json:"weight"`
+	Depth            int         `json:"depth"`
+	InFlight         int         `json:"in_flight"`
+	OldestAgeSeconds float64     `json:"oldest_age_seconds"`
+	Processed        int64       `json:"processed"`
+	Namespaces       []FlowStats `json:"namespaces"`
+}
+
+// Stats returns per-vendor and per-namespace queue depth and age of the
+// oldest item, sorted by name.
+func (q *Queue) Stats() []VendorStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := q.clock.Now()
+	result := make([]VendorStats, 0, len(q.vendors))
+	for _, v := range q.vendors {
+		stats := VendorStats{
+			Vendor:     v.name,
+			Weight:     v.weight,
+			Depth:      v.queued,
+			InFlight:   v.inFlight,
+			Processed:  v.processed,
+			Namespaces: []FlowStats{},
+		}
+		for _, ns := range v.namespaces {
+			flowStats := FlowStats{Namespace: ns.name, Weight: ns.weight, Depth: len(ns.items), InFlight: ns.inFlight}
+			if len(ns.items) > 0 {
+				flowStats.OldestAgeSeconds = now.Sub(ns.items[0].Added).Seconds()
+			}
+			if flowStats.OldestAgeSeconds > stats.OldestAgeSeconds {
+				stats.OldestAgeSeconds = flowStats.OldestAgeSeconds
+			}
+			stats.Namespaces = append(stats.Namespaces, flowStats)
+		}
+		sort.Slice(stats.Namespaces, func(i, j int) bool { return stats.Namespaces[i].Namespace < stats.Namespaces[j].Namespace })
+		result = append(result, stats)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Vendor < result[j].Vendor })
+	return result
+}
@@ -0,0 +1,185 @@
+package fanout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// STRUCTURED FAN-OUT
+// =============================================================================
+// Batch creates and deletes, health checks across resources and
+// providers, and rollout stages all run one vendor call per item at once.
+// A Group gives them the same rules instead of a WaitGroup and semaphore
+// each (the errgroup pattern, without the dependency):
+//
+//	results, err := fanout.Map(ctx, ids, fanout.Options{Limit: 10, BranchTimeout: 5 * time.Second},
+//		func(ctx context.Context, id string) (*Item, error) {
+//			return check(ctx, id)
+//		})
+//
+//   - Limit bounds how many branches run at once; Go blocks for a slot
+//   - BranchTimeout gives every branch its own deadline
+//   - an ordinary error fails only its branch: Map keeps every result, so
+//     callers report partial success item by item
+//   - an error wrapped with Fatal (or a panic) cancels the branches still
+//     running and stops new ones from starting; Wait returns it
+//   - Wait returns only after every started branch has, so no goroutine
+//     outlives the request that spawned it
+// =============================================================================
+
+// Options configures a Group.
+type Options struct {
+	// Limit is the most branches running at once (0 = no limit)
+	Limit int
+
+	// BranchTimeout bounds each branch (0 = only the parent's deadline)
+	BranchTimeout time.Duration
+}
+
+// ErrNotStarted marks a branch that never ran because the group was
+// cancelled first (a fatal error elsewhere or the parent context).
+var ErrNotStarted = errors.New("not started")
+
+// fatalError marks an error that cancels the group.
+type fatalError struct {
+	err error
+}
+
+func (f fatalError) Error() string { return f.err.Error() }
+func (f fatalError) Unwrap() error { return f.err }
+
+// Fatal marks err as fatal to the whole group. Fatal(nil) is nil.
+func Fatal(err error) error {
+	if err == nil {
+		return nil
+	}
+	return fatalError{err}
+}
+
+// IsFatal reports whether err was marked with Fatal.
+func IsFatal(err error) bool {
+	var fatal fatalError
+	return errors.As(err, &fatal)
+}
+
+// Group runs branches concurrently under one context.
+type Group struct {
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	sem     chan struct{}
+	timeout time.Duration
+
+	wg   sync.WaitGroup
+	once sync.Once
+	err  error
+}
+
+// New returns a Group and the context its branches derive from. The
+// context is cancelled by the first fatal error or when Wait returns.
+func New(ctx context.Context, opts Options) (*Group, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	g := &Group{ctx: ctx, cancel: cancel, timeout: opts.BranchTimeout}
+	if opts.Limit > 0 {
+		g.sem = make(chan struct{}, opts.Limit)
+	}
+	return g, ctx
+}
+
+// Go runs fn in its own goroutine once a slot is free. It returns false
+// without running fn when the group is cancelled first.
+func (g *Group) Go(fn func(ctx context.Context) error) bool {
+	if g.sem != nil {
+		select {
+		case g.sem <- struct{}{}:
+		case <-g.ctx.Done():
+			return false
+		}
+	}
+	// WHY CHECK AGAIN: select picks at random when a slot frees up just
+	// as the group is cancelled
+	if g.ctx.Err() != nil {
+		g.release()
+		return false
+	}
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		defer g.release()
+		if err := g.run(fn); IsFatal(err) {
+			g.once.Do(func() {
+				g.err = err
+				g.cancel(err)
+			})
+		}
+	}()
+	return true
+}
+
+func (g *Group) release() {
+	if g.sem != nil {
+		<-g.sem
+	}
+}
+
+// run calls fn with the branch's context.
+func (g *Group) run(fn func(ctx context.Context) error) error {
+	ctx := g.ctx
+	if g.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.timeout)
+		defer cancel()
+	}
+	return protect(func() error { return fn(ctx) })
+}
+
+// protect turns a panic in fn into a fatal error.
+// WHY: A panic in a goroutine would take the whole controller down
+func protect(fn func() error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = Fatal(fmt.Errorf("panic: %v\n%s", p, debug.Stack()))
+		}
+	}()
+	return fn()
+}
+
+// Wait waits for every started branch and returns the first fatal error.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel(context.Canceled)
+	return g.err
+}
+
+// Result is one branch's outcome in Map.
+type Result[T any] struct {
+	Value T
+	Err   error
+}
+
+// Map runs fn for every item in a Group and returns the results in item
+// order, with the group's fatal error if there was one. Items that never
+// ran have an Err wrapping ErrNotStarted.
+func Map[In, Out any](ctx context.Context, items []In, opts Options, fn func(ctx context.Context, item In) (Out, error)) ([]Result[Out], error) {
+	results := make([]Result[Out], len(items))
+	g, groupCtx := New(ctx, opts)
+	for i, item := range items {
+		started := g.Go(func(ctx context.Context) error {
+			err := protect(func() error {
+				value, err := fn(ctx, item)
+				results[i].Value = value
+				return err
+			})
+			results[i].Err = err
+			return err
+		})
+		if !started {
+			results[i].Err = fmt.Errorf("%w: %w", ErrNotStarted, context.Cause(groupCtx))
+		}
+	}
+	return results, g.Wait()
+}
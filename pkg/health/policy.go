@@ -0,0 +1,283 @@
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// HEALTH POLICIES
+// =============================================================================
+// A vendor's status string only says whether a device is up. A camera that
+// is "active" while dropping frames at 80°C is not healthy on air. Health
+// policies roll the device's metrics (Status.Metrics) into HealthStatus:
+//
+//   {
+//     "default": {
+//       "temperature_celsius":       {"degraded": 70, "unhealthy": 85},
+//       "dropped_frames_per_minute": {"degraded": 1,  "unhealthy": 30}
+//     },
+//     "policies": [
+//       {"name": "outdoor-cameras",
+//        "match": {"type": "camera", "namespace": "outdoor"},
+//        "thresholds": {"temperature_celsius": {"degraded": 80, "unhealthy": 95}}}
+//     ]
+//   }
+//
+// The first policy whose match fits the resource applies (empty match keys
+// match anything); its thresholds replace the default ones metric by
+// metric. A reading at or above a threshold makes the device degraded or
+// unhealthy. The result is the worst of the vendor's health and every
+// signal, and each metric with a threshold is reported as a condition
+// (TemperatureWithinLimits, ...) naming its limit.
+//
+// Only devices the vendor reports as healthy or degraded are rolled up;
+// a stopped or failed device keeps the vendor's verdict.
+// =============================================================================
+
+// Health statuses, as used in ResourceStatus.HealthStatus.
+const (
+	Healthy   = "healthy"
+	Degraded  = "degraded"
+	Unhealthy = "unhealthy"
+)
+
+// severity orders health statuses from best to worst.
+var severity = map[string]int{Healthy: 0, Degraded: 1, Unhealthy: 2}
+
+// Threshold is where a metric turns a device degraded or unhealthy.
+// A zero limit is not checked.
+type Threshold struct {
+	Degraded  float64 `json:"degraded,omitempty"`
+	Unhealthy float64 `json:"unhealthy,omitempty"`
+}
+
+// Match selects resources for a policy. Empty keys match anything.
+type Match struct {
+	Type       string `json:"type,omitempty"`
+	VendorType string `json:"vendor_type,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+}
+
+// matches reports whether res fits m.
+func (m Match) matches(res *models.ForgeResource) bool {
+	return (m.Type == "" || strings.EqualFold(m.Type, res.Type)) &&
+		(m.VendorType == "" || strings.EqualFold(m.VendorType, res.Spec.VendorType)) &&
+		(m.Namespace == "" || m.Namespace == res.Namespace)
+}
+
+// Policy overrides the default thresholds for matching resources.
+type Policy struct {
+	Name       string               `json:"name"`
+	Match      Match                `json:"match"`
+	Thresholds map[string]Threshold `json:"thresholds"`
+}
+
+// Config is the set of health policies (the HEALTH_POLICY_CONFIG file).
+type Config struct {
+	// Default applies to every resource, unless a policy overrides a metric
+	Default map[string]Threshold `json:"default"`
+
+	// Policies are tried in order; the first match applies
+	Policies []Policy `json:"policies,omitempty"`
+}
+
+// DefaultConfig returns the thresholds used without HEALTH_POLICY_CONFIG.
+func DefaultConfig() *Config {
+	return &Config{Default: map[string]Threshold{
+		models.MetricDroppedFrames: {Degraded: 1, Unhealthy: 30},
+		models.MetricTemperature:   {Degraded: 70, Unhealthy: 85},
+		models.MetricCPU:           {Degraded: 90, Unhealthy: 98},
+	}}
+}
+
+// LoadConfig reads a Config from a JSON file and validates it.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read health policy config: %w", err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid health policy config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Validate checks metric names and threshold order.
+// WHY STRICT: A misspelled metric would silently never fire
+func (cfg *Config) Validate() error {
+	if err := validateThresholds("default", cfg.Default); err != nil {
+		return err
+	}
+	for i, policy := range cfg.Policies {
+		name := policy.Name
+		if name == "" {
+			name = fmt.Sprintf("policies[%d]", i)
+		}
+		if err := validateThresholds(name, policy.Thresholds); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateThresholds(policy string, thresholds map[string]Threshold) error {
+	for metric, t := range thresholds {
+		if _, known := models.MetricConditions[metric]; !known {
+			return fmt.Errorf("policy %s: unknown metric %q (known: %s)", policy, metric, strings.Join(Metrics(), ", "))
+		}
+		if t.Degraded < 0 || t.Unhealthy < 0 {
+			return fmt.Errorf("policy %s: %s thresholds must not be negative", policy, metric)
+		}
+		if t.Degraded > 0 && t.Unhealthy > 0 && t.Unhealthy < t.Degraded {
+			return fmt.Errorf("policy %s: %s unhealthy threshold %g is below degraded threshold %g",
+				policy, metric, t.Unhealthy, t.Degraded)
+		}
+	}
+	return nil
+}
+
+// Metrics lists the metric names policies can use, sorted.
+func Metrics() []string {
+	names := make([]string, 0, len(models.MetricConditions))
+	for name := range models.MetricConditions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ThresholdsFor returns the policy name ("default" if none matches) and
+// the thresholds that apply to res.
+func (cfg *Config) ThresholdsFor(res *models.ForgeResource) (string, map[string]Threshold) {
+	for _, policy := range cfg.Policies {
+		if !policy.Match.matches(res) {
+			continue
+		}
+		thresholds := make(map[string]Threshold, len(cfg.Default)+len(policy.Thresholds))
+		for metric, t := range cfg.Default {
+			thresholds[metric] = t
+		}
+		for metric, t := range policy.Thresholds {
+			thresholds[metric] = t
+		}
+		return policy.Name, thresholds
+	}
+	return "default", cfg.Default
+}
+
+// Signal is one metric compared with its threshold.
+type Signal struct {
+	Metric string  `json:"metric"`
+	Value  float64 `json:"value"`
+
+	// Health is Healthy, Degraded or Unhealthy
+	Health string `json:"health"`
+
+	// Limit is the threshold crossed (for Healthy, the degraded threshold)
+	Limit float64 `json:"limit,omitempty"`
+}
+
+// Evaluate compares metrics with thresholds. Metrics without a threshold,
+// and thresholds without a reading, are skipped. Sorted by metric.
+func Evaluate(thresholds map[string]Threshold, metrics map[string]float64) []Signal {
+	var signals []Signal
+	for metric, t := range thresholds {
+		value, ok := metrics[metric]
+		if !ok {
+			continue
+		}
+		signal := Signal{Metric: metric, Value: value, Health: Healthy, Limit: t.Degraded}
+		switch {
+		case t.Unhealthy > 0 && value >= t.Unhealthy:
+			signal.Health, signal.Limit = Unhealthy, t.Unhealthy
+		case t.Degraded > 0 && value >= t.Degraded:
+			signal.Health = Degraded
+		}
+		signals = append(signals, signal)
+	}
+	sort.Slice(signals, func(i, j int) bool { return signals[i].Metric < signals[j].Metric })
+	return signals
+}
+
+// condition reports s as its metric's condition.
+// WHY NO READING IN THE MESSAGE: The condition is part of the stored
+// status; a message that moved with every reading would make each poll a
+// new revision (and ETag, and watch event). The reading is in
+// Status.Metrics.
+func (s Signal) condition() models.Condition {
+	switch s.Health {
+	case Healthy:
+		message := s.Metric + " is within limits"
+		if s.Limit > 0 {
+			message = fmt.Sprintf("%s is below %g", s.Metric, s.Limit)
+		}
+		return models.Condition{Type: models.MetricConditions[s.Metric], Status: models.ConditionTrue,
+			Reason: "WithinLimits", Message: message}
+	case Degraded:
+		return models.Condition{Type: models.MetricConditions[s.Metric], Status: models.ConditionFalse,
+			Reason: "DegradedThresholdExceeded", Message: fmt.Sprintf("%s reached the degraded threshold %g", s.Metric, s.Limit)}
+	default:
+		return models.Condition{Type: models.MetricConditions[s.Metric], Status: models.ConditionFalse,
+			Reason: "UnhealthyThresholdExceeded", Message: fmt.Sprintf("%s reached the unhealthy threshold %g", s.Metric, s.Limit)}
+	}
+}
+
+// Apply rolls res's metrics up into its HealthStatus and sets one
+// condition per evaluated metric, replacing those of an earlier Apply.
+// Call it once per status read from the vendor. Returns the signals
+// evaluated.
+func (cfg *Config) Apply(res *models.ForgeResource) []Signal {
+	status := &res.Status
+	conditions := status.Conditions[:0:0]
+	for _, condition := range status.Conditions {
+		if !isMetricCondition(condition.Type) {
+			conditions = append(conditions, condition)
+		}
+	}
+	status.Conditions = conditions
+
+	if _, rolledUp := severity[status.HealthStatus]; !rolledUp || len(status.Metrics) == 0 {
+		return nil
+	}
+	_, thresholds := cfg.ThresholdsFor(res)
+	signals := Evaluate(thresholds, status.Metrics)
+
+	var problems []string
+	for _, signal := range signals {
+		condition := signal.condition()
+		status.Conditions = append(status.Conditions, condition)
+		if signal.Health == Healthy {
+			continue
+		}
+		problems = append(problems, condition.Message)
+		if severity[signal.Health] > severity[status.HealthStatus] {
+			status.HealthStatus = signal.Health
+		}
+	}
+	if len(problems) > 0 {
+		if status.HealthCheckMessage != "" {
+			problems = append([]string{status.HealthCheckMessage}, problems...)
+		}
+		status.HealthCheckMessage = strings.Join(problems, "; ")
+	}
+	return signals
+}
+
+func isMetricCondition(conditionType string) bool {
+	for _, t := range models.MetricConditions {
+		if t == conditionType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,261 @@
+package labels
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// =============================================================================
+// LABELS AND LABEL SELECTORS
+// =============================================================================
+// Labels are key/value pairs clients attach to resources to group them
+// ("env=prod", "site=stadium-a"). A selector picks resources by their
+// labels, with the Kubernetes syntax:
+//
+//   env=prod              label env is prod (== works too)
+//   env!=prod             env isn't prod (or isn't set)
+//   site in (a,b)         site is a or b
+//   site notin (a,b)      site is neither (or isn't set)
+//   tier                  label tier is set
+//   !tier                 label tier isn't set
+//
+// Requirements are separated by commas and must all hold:
+// "env=prod,site in (stadium-a,stadium-b),!retired".
+//
+// Keys and values follow the Kubernetes rules too (see ValidateKey and
+// ValidateValue), so labels can be copied to Kubernetes objects as-is.
+// =============================================================================
+
+// Requirement operators.
+const (
+	OpEquals       = "="
+	OpNotEquals    = "!="
+	OpIn           = "in"
+	OpNotIn        = "notin"
+	OpExists       = "exists"
+	OpDoesNotExist = "!"
+)
+
+// namePattern is a label name or value: alphanumerics, '-', '_' and '.',
+// starting and ending with an alphanumeric.
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+
+// prefixPattern is a DNS subdomain (the optional "example.com/" of a key).
+var prefixPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+
+// Length limits (Kubernetes).
+const (
+	maxNameLength   = 63
+	maxPrefixLength = 253
+)
+
+// ValidateKey checks a label key: an optional DNS subdomain prefix and a
+// slash, then a name of at most 63 characters ("example.com/site", "env").
+func ValidateKey(key string) error {
+	name := key
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		prefix := key[:i]
+		name = key[i+1:]
+		if prefix == "" || len(prefix) > maxPrefixLength || !prefixPattern.MatchString(prefix) {
+			return fmt.Errorf("label key %q: prefix must be a DNS subdomain of at most %d characters", key, maxPrefixLength)
+		}
+	}
+	if name == "" || len(name) > maxNameLength || !namePattern.MatchString(name) {
+		return fmt.Errorf("label key %q: name must be 1-%d characters of letters, digits, '-', '_' and '.', starting and ending with a letter or digit", key, maxNameLength)
+	}
+	return nil
+}
+
+// ValidateValue checks a label value: empty, or at most 63 characters of
+// letters, digits, '-', '_' and '.', starting and ending with a letter or
+// digit.
+func ValidateValue(value string) error {
+	if value == "" {
+		return nil
+	}
+	if len(value) > maxNameLength || !namePattern.MatchString(value) {
+		return fmt.Errorf("label value %q: must be at most %d characters of letters, digits, '-', '_' and '.', starting and ending with a letter or digit", value, maxNameLength)
+	}
+	return nil
+}
+
+// Requirement is one condition of a selector.
+type Requirement struct {
+	Key      string
+	Operator string
+	Values   []string
+}
+
+// Matches reports whether labels satisfy the requirement.
+func (r Requirement) Matches(labels map[string]string) bool {
+	value, set := labels[r.Key]
+	switch r.Operator {
+	case OpEquals, OpIn:
+		return set && contains(r.Values, value)
+	case OpNotEquals, OpNotIn:
+		return !set || !contains(r.Values, value)
+	case OpExists:
+		return set
+	case OpDoesNotExist:
+		return !set
+	}
+	return false
+}
+
+// String renders the requirement in selector syntax.
+func (r Requirement) String() string {
+	switch r.Operator {
+	case OpEquals, OpNotEquals:
+		return r.Key + r.Operator + r.Values[0]
+	case OpIn, OpNotIn:
+		return r.Key + " " + r.Operator + " (" + strings.Join(r.Values, ",") + ")"
+	case OpDoesNotExist:
+		return "!" + r.Key
+	}
+	return r.Key
+}
+
+// Selector is a parsed label selector; all requirements must hold. The
+// zero Selector matches everything.
+type Selector struct {
+	requirements []Requirement
+}
+
+// Empty reports whether the selector matches everything.
+func (s Selector) Empty() bool {
+	return len(s.requirements) == 0
+}
+
+// Matches reports whether labels satisfy every requirement.
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, r := range s.requirements {
+		if !r.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// Requirements returns the requirements, sorted by key.
+func (s Selector) Requirements() []Requirement {
+	return append([]Requirement(nil), s.requirements...)
+}
+
+// String renders the selector in canonical form (requirements sorted by
+// key, values sorted).
+func (s Selector) String() string {
+	parts := make([]string, len(s.requirements))
+	for i, r := range s.requirements {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// Parse parses a selector. An empty string matches everything.
+func Parse(selector string) (Selector, error) {
+	parts, err := splitRequirements(selector)
+	if err != nil {
+		return Selector{}, err
+	}
+	var s Selector
+	for _, part := range parts {
+		r, err := parseRequirement(part)
+		if err != nil {
+			return Selector{}, err
+		}
+		s.requirements = append(s.requirements, r)
+	}
+	sort.SliceStable(s.requirements, func(i, j int) bool { return s.requirements[i].Key < s.requirements[j].Key })
+	return s, nil
+}
+
+// splitRequirements splits selector at commas outside parentheses.
+func splitRequirements(selector string) ([]string, error) {
+	var parts []string
+	depth, start := 0, 0
+	for i, ch := range selector {
+		switch ch {
+		case '(':
+			depth++
+			if depth > 1 {
+				return nil, errors.New("label selector: nested parentheses")
+			}
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, errors.New("label selector: unbalanced parentheses")
+			}
+		case ',':
+			if depth == 0 {
+				parts = append(parts, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, errors.New("label selector: unbalanced parentheses")
+	}
+	parts = append(parts, selector[start:])
+	if len(parts) == 1 && strings.TrimSpace(parts[0]) == "" {
+		return nil, nil
+	}
+	for i, part := range parts {
+		if parts[i] = strings.TrimSpace(part); parts[i] == "" {
+			return nil, fmt.Errorf("label selector %q: empty requirement", selector)
+		}
+	}
+	return parts, nil
+}
+
+// setPattern matches "key in (values)" and "key notin (values)".
+var setPattern = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+
+// parseRequirement parses one comma-free requirement.
+func parseRequirement(text string) (Requirement, error) {
+	var r Requirement
+	switch {
+	case strings.HasPrefix(text, "!") && !strings.Contains(text, "="):
+		r = Requirement{Key: strings.TrimSpace(text[1:]), Operator: OpDoesNotExist}
+	case setPattern.MatchString(text):
+		m := setPattern.FindStringSubmatch(text)
+		r = Requirement{Key: m[1], Operator: m[2]}
+		for _, value := range strings.Split(m[3], ",") {
+			r.Values = append(r.Values, strings.TrimSpace(value))
+		}
+		sort.Strings(r.Values)
+	case strings.Contains(text, "!="):
+		key, value, _ := strings.Cut(text, "!=")
+		r = Requirement{Key: strings.TrimSpace(key), Operator: OpNotEquals, Values: []string{strings.TrimSpace(value)}}
+	case strings.Contains(text, "="):
+		key, value, _ := strings.Cut(text, "=")
+		value = strings.TrimPrefix(value, "=")
+		r = Requirement{Key: strings.TrimSpace(key), Operator: OpEquals, Values: []string{strings.TrimSpace(value)}}
+	default:
+		r = Requirement{Key: text, Operator: OpExists}
+	}
+
+	if err := ValidateKey(r.Key); err != nil {
+		return r, fmt.Errorf("label selector %q: %w", text, err)
+	}
+	for _, value := range r.Values {
+		if err := ValidateValue(value); err != nil {
+			return r, fmt.Errorf("label selector %q: %w", text, err)
+		}
+	}
+	if (r.Operator == OpIn || r.Operator == OpNotIn) && len(r.Values) == 1 && r.Values[0] == "" {
+		return r, fmt.Errorf("label selector %q: %s needs at least one value", text, r.Operator)
+	}
+	return r, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
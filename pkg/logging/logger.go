@@ -0,0 +1,337 @@
+package logging
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// LEVELED, COMPONENT-AWARE LOGGING
+// =============================================================================
+// This package wraps the standard library logger with log levels that can be
+// changed at runtime, globally or per component. It exists so an operator can
+// turn on debug output for one misbehaving subsystem (e.g. the Sony provider)
+// in production without restarting the controller and without drowning in
+// debug output from every other component.
+//
+// Overrides are time-boxed: every runtime change carries a TTL after which the
+// level automatically reverts to the configured baseline. This prevents a
+// forgotten "debug" switch from filling disks days later.
+//
+// Example Usage:
+//
+//	var logger = logging.For(logging.ComponentProviders)
+//	logger.Debugf("sending request to %s", url)
+//
+// =============================================================================
+
+// Level is the severity of a log message.
+type Level int
+
+const (
+	// LevelDebug is verbose diagnostic output (request payloads, retries).
+	LevelDebug Level = iota
+	// LevelInfo is normal operational output (resource created, server started).
+	LevelInfo
+	// LevelWarn indicates something unexpected that the system recovered from.
+	LevelWarn
+	// LevelError indicates a failed operation that needs attention.
+	LevelError
+)
+
+// Well-known component names. Loggers may be created for other names, but
+// these are the ones the admin API advertises.
+const (
+	ComponentController = "controller"
+	ComponentProviders  = "providers"
+	ComponentClient     = "client"
+	ComponentReconciler = "reconciler"
+)
+
+// KnownComponents lists the components that accept level overrides.
+var KnownComponents = []string{
+	ComponentController,
+	ComponentProviders,
+	ComponentClient,
+	ComponentReconciler,
+}
+
+// String returns the lowercase name of the level ("debug", "info", ...).
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	default:
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+}
+
+// ParseLevel converts a level name into a Level. Matching is case-insensitive
+// and accepts "warning" as an alias for "warn".
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	default:
+		return LevelInfo, fmt.Errorf("unknown log level %q (valid: debug, info, warn, error)", s)
+	}
+}
+
+// IsKnownComponent reports whether name is one of KnownComponents.
+func IsKnownComponent(name string) bool {
+	for _, c := range KnownComponents {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// =============================================================================
+// LEVEL REGISTRY
+// =============================================================================
+
+// override is a temporary level change that reverts when its timer fires.
+type override struct {
+	level     Level
+	expiresAt time.Time
+	timer     *time.Timer
+}
+
+// registry holds the baseline level and any active overrides.
+// The empty component name "" represents a global override.
+type registry struct {
+	mu        sync.RWMutex
+	baseline  Level
+	overrides map[string]*override
+}
+
+var levels = &registry{
+	baseline:  LevelInfo,
+	overrides: make(map[string]*override),
+}
+
+// SetBaseline sets the level used when no override is active.
+// This is normally called once at startup from configuration (LOG_LEVEL).
+func SetBaseline(level Level) {
+	levels.mu.Lock()
+	defer levels.mu.Unlock()
+	levels.baseline = level
+}
+
+// Override temporarily changes the level for a component (or globally when
+// component is ""). After ttl elapses the override is removed and the level
+// reverts to the baseline. Setting a new override for the same component
+// replaces the previous one and restarts the timer.
+//
+// Returns the time at which the override will expire.
+func Override(component string, level Level, ttl time.Duration) time.Time {
+	levels.mu.Lock()
+	defer levels.mu.Unlock()
+
+	if existing, ok := levels.overrides[component]; ok {
+		existing.timer.Stop()
+	}
+
+	o := &override{
+		level:     level,
+		expiresAt: time.Now().Add(ttl),
+	}
+	o.timer = time.AfterFunc(ttl, func() {
+		levels.mu.Lock()
+		// Only remove the override if it hasn't been replaced since.
+		if current, ok := levels.overrides[component]; ok && current == o {
+			delete(levels.overrides, component)
+		}
+		levels.mu.Unlock()
+		log.Printf("[INFO] [logging] level override for %s expired, reverted to baseline", displayComponent(component))
+	})
+	levels.overrides[component] = o
+	return o.expiresAt
+}
+
+// Reset removes any override for component immediately.
+// Returns false if there was no override to remove.
+func Reset(component string) bool {
+	levels.mu.Lock()
+	defer levels.mu.Unlock()
+
+	o, ok := levels.overrides[component]
+	if !ok {
+		return false
+	}
+	o.timer.Stop()
+	delete(levels.overrides, component)
+	return true
+}
+
+// EffectiveLevel returns the level currently in force for a component.
+// Precedence: component override → global override → baseline.
+func EffectiveLevel(component string) Level {
+	levels.mu.RLock()
+	defer levels.mu.RUnlock()
+
+	if o, ok := levels.overrides[component]; ok {
+		return o.level
+	}
+	if o, ok := levels.overrides[""]; ok {
+		return o.level
+	}
+	return levels.baseline
+}
+
+// OverrideInfo describes an active override for reporting via the admin API.
+type OverrideInfo struct {
+	Level     string    `json:"level"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// State is a point-in-time view of the logging configuration.
+type State struct {
+	// Baseline is the configured level when nothing is overridden.
+	Baseline string `json:"baseline"`
+
+	// Global is the active global override, if any.
+	Global *OverrideInfo `json:"global,omitempty"`
+
+	// Components maps each known component to its effective level.
+	Components map[string]string `json:"components"`
+
+	// Overrides lists active per-component overrides.
+	Overrides map[string]OverrideInfo `json:"overrides"`
+}
+
+// Snapshot returns the current logging configuration.
+func Snapshot() State {
+	levels.mu.RLock()
+	state := State{
+		Baseline:   levels.baseline.String(),
+		Components: make(map[string]string),
+		Overrides:  make(map[string]OverrideInfo),
+	}
+	for component, o := range levels.overrides {
+		info := OverrideInfo{Level: o.level.String(), ExpiresAt: o.expiresAt}
+		if component == "" {
+			state.Global = &info
+		} else {
+			state.Overrides[component] = info
+		}
+	}
+	levels.mu.RUnlock()
+
+	for _, component := range KnownComponents {
+		state.Components[component] = EffectiveLevel(component).String()
+	}
+	return state
+}
+
+func displayComponent(component string) string {
+	if component == "" {
+		return "all components"
+	}
+	return component
+}
+
+// =============================================================================
+// LOGGER
+// =============================================================================
+
+// Logger writes leveled messages for a single component.
+// Loggers are cheap and safe for concurrent use; the level is looked up on
+// every call so runtime changes take effect immediately.
+type Logger struct {
+	component string
+}
+
+// For returns a Logger for the named component.
+func For(component string) *Logger {
+	return &Logger{component: component}
+}
+
+// Enabled reports whether messages at level would currently be written.
+// Use it to skip building expensive debug output.
+func (l *Logger) Enabled(level Level) bool {
+	return level >= EffectiveLevel(l.component)
+}
+
+// Debugf logs at debug level.
+func (l *Logger) Debugf(format string, args ...interface{}) { l.logf(LevelDebug, format, args...) }
+
+// Infof logs at info level.
+func (l *Logger) Infof(format string, args ...interface{}) { l.logf(LevelInfo, format, args...) }
+
+// Warnf logs at warn level.
+func (l *Logger) Warnf(format string, args ...interface{}) { l.logf(LevelWarn, format, args...) }
+
+// Errorf logs at error level.
+func (l *Logger) Errorf(format string, args ...interface{}) { l.logf(LevelError, format, args...) }
+
+func (l *Logger) logf(level Level, format string, args ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	log.Printf("[%s] [%s] %s", strings.ToUpper(level.String()), l.component, fmt.Sprintf(format, args...))
+}
+
+// =============================================================================
+// RECENT OUTPUT
+// =============================================================================
+
+// Tail keeps the most recent lines written to it, so diagnostics can
+// include logs without access to the pod's stdout. Install it next to the
+// normal output:
+//
+//	log.SetOutput(io.MultiWriter(os.Stderr, tail))
+type Tail struct {
+	mu    sync.Mutex
+	lines []string
+	next  int
+	full  bool
+}
+
+// NewTail returns a Tail holding up to n lines.
+func NewTail(n int) *Tail {
+	if n < 1 {
+		n = 1
+	}
+	return &Tail{lines: make([]string, n)}
+}
+
+// Write records each line in p. The log package writes one entry per call.
+func (t *Tail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		t.lines[t.next] = line
+		t.next = (t.next + 1) % len(t.lines)
+		if t.next == 0 {
+			t.full = true
+		}
+	}
+	return len(p), nil
+}
+
+// Lines returns the recorded lines, oldest first.
+func (t *Tail) Lines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.full {
+		return append([]string(nil), t.lines[:t.next]...)
+	}
+	return append(append([]string(nil), t.lines[t.next:]...), t.lines[:t.next]...)
+}
@@ -0,0 +1,212 @@
+package memguard
+
+import (
+	"context"
+	"os"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// MEMORY GUARDRAILS
+// =============================================================================
+// The controller keeps everything in memory (resources, history, audit
+// trail). Rather than letting the kernel OOM-kill the process, the Guard
+// samples memory usage periodically and reports a pressure level:
+//
+//   Normal → usage below the soft watermark
+//   Soft   → usage above the soft watermark: shed caches, pause background work
+//   Hard   → usage above the hard watermark: also refuse new resources
+//
+// WHY HYSTERESIS: Shedding caches drops usage right below the watermark;
+// without a margin the level would flap on every sample. A level is only
+// left once usage falls hysteresis below its watermark.
+// =============================================================================
+
+// Level is a memory pressure level.
+type Level int
+
+const (
+	Normal Level = iota
+	Soft
+	Hard
+)
+
+// String returns the lowercase level name used in API responses.
+func (l Level) String() string {
+	switch l {
+	case Soft:
+		return "soft"
+	case Hard:
+		return "hard"
+	default:
+		return "normal"
+	}
+}
+
+// hysteresis is the fraction of the limit usage must drop below a
+// watermark before the guard leaves that level.
+const hysteresis = 0.05
+
+// Status is a point-in-time view of the guard, for admin endpoints.
+type Status struct {
+	Level         string    `json:"level"`
+	UsageBytes    uint64    `json:"usage_bytes"`
+	LimitBytes    uint64    `json:"limit_bytes"`
+	SoftWatermark float64   `json:"soft_watermark"`
+	HardWatermark float64   `json:"hard_watermark"`
+	LastCheck     time.Time `json:"last_check"`
+}
+
+// Guard tracks memory usage against a limit.
+type Guard struct {
+	limit    uint64
+	soft     float64
+	hard     float64
+	readUsed func() uint64
+
+	mu        sync.Mutex
+	level     Level
+	usage     uint64
+	lastCheck time.Time
+	listeners []func(from, to Level)
+}
+
+// New creates a Guard for a process allowed limitBytes of memory.
+// soft and hard are fractions of the limit (e.g. 0.80 and 0.90).
+func New(limitBytes uint64, soft, hard float64) *Guard {
+	return &Guard{
+		limit:    limitBytes,
+		soft:     soft,
+		hard:     hard,
+		readUsed: readRuntimeUsage,
+	}
+}
+
+// OnChange registers fn to be called (synchronously, from Check) whenever
+// the pressure level changes.
+func (g *Guard) OnChange(fn func(from, to Level)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.listeners = append(g.listeners, fn)
+}
+
+// Level returns the level from the most recent check.
+func (g *Guard) Level() Level {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.level
+}
+
+// Status returns the guard's current state.
+func (g *Guard) Status() Status {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return Status{
+		Level:         g.level.String(),
+		UsageBytes:    g.usage,
+		LimitBytes:    g.limit,
+		SoftWatermark: g.soft,
+		HardWatermark: g.hard,
+		LastCheck:     g.lastCheck,
+	}
+}
+
+// Check samples memory usage, updates the level and notifies listeners
+// if it changed. Returns the new level.
+func (g *Guard) Check() Level {
+	usage := g.readUsed()
+
+	g.mu.Lock()
+	from := g.level
+	ratio := float64(usage) / float64(g.limit)
+	to := from
+	switch {
+	case ratio >= g.hard:
+		to = Hard
+	case ratio >= g.soft:
+		// Stay in Hard until comfortably below the hard watermark
+		if from != Hard || ratio < g.hard-hysteresis {
+			to = Soft
+		}
+	default:
+		if from == Normal || ratio < g.soft-hysteresis {
+			to = Normal
+		}
+	}
+	g.level = to
+	g.usage = usage
+	g.lastCheck = time.Now()
+	listeners := append([]func(from, to Level){}, g.listeners...)
+	g.mu.Unlock()
+
+	// WHY OUTSIDE THE LOCK: Listeners may call back into the guard
+	if from != to {
+		for _, fn := range listeners {
+			fn(from, to)
+		}
+	}
+	return to
+}
+
+// Run checks memory every interval until ctx is cancelled.
+func (g *Guard) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	g.Check()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			g.Check()
+		}
+	}
+}
+
+// readRuntimeUsage returns the memory the Go runtime has mapped and not
+// returned to the OS, which tracks the process RSS closely.
+//
+// WHY runtime/metrics (not ReadMemStats): ReadMemStats stops the world;
+// metrics.Read doesn't.
+func readRuntimeUsage() uint64 {
+	samples := []metrics.Sample{
+		{Name: "/memory/classes/total:bytes"},
+		{Name: "/memory/classes/heap/released:bytes"},
+	}
+	metrics.Read(samples)
+	total, released := samples[0].Value.Uint64(), samples[1].Value.Uint64()
+	if released > total {
+		return 0
+	}
+	return total - released
+}
+
+// DetectLimit returns the container memory limit from cgroups, or 0 if
+// the process isn't limited (or the limit can't be read).
+func DetectLimit() uint64 {
+	// cgroup v2, then v1
+	for _, path := range []string{
+		"/sys/fs/cgroup/memory.max",
+		"/sys/fs/cgroup/memory/memory.limit_in_bytes",
+	} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		value := strings.TrimSpace(string(data))
+		if value == "max" {
+			return 0
+		}
+		limit, err := strconv.ParseUint(value, 10, 64)
+		// WHY THE UPPER BOUND: cgroup v1 reports ~2^63 for "unlimited"
+		if err != nil || limit == 0 || limit > 1<<50 {
+			return 0
+		}
+		return limit
+	}
+	return 0
+}
@@ -0,0 +1,34 @@
+package models
+
+// =============================================================================
+// DEVICE ACTIONS
+// =============================================================================
+// Operational commands that aren't a change to the resource's spec:
+// restarting a hung encoder, rebooting a camera, taking its output off air
+// without stopping the device. POST /resources/{id}/actions runs one on
+// providers that implement provider.ProviderAction.
+// =============================================================================
+
+// Device actions.
+const (
+	// DeviceActionRestart restarts the encoder; the device stays up and
+	// configured
+	DeviceActionRestart = "restart"
+
+	// DeviceActionReboot power-cycles the whole device
+	DeviceActionReboot = "reboot"
+
+	// DeviceActionStartStream and DeviceActionStopStream put the output
+	// stream on and off air; the device keeps running
+	DeviceActionStartStream = "start_stream"
+	DeviceActionStopStream  = "stop_stream"
+)
+
+// DeviceActions lists every action, in the order they are documented.
+var DeviceActions = []string{DeviceActionRestart, DeviceActionReboot, DeviceActionStartStream, DeviceActionStopStream}
+
+// ActionRequest is the body of POST /resources/{id}/actions.
+type ActionRequest struct {
+	// Action is one of DeviceActions
+	Action string `json:"action"`
+}
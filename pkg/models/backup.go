@@ -0,0 +1,66 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// =============================================================================
+// DEVICE CONFIGURATION BACKUPS
+// =============================================================================
+// A factory reset (or a swapped replacement unit) loses everything set on
+// the device itself: presets, paint settings, network and sync settings.
+// Providers that can dump a device's full settings export them as an
+// opaque DeviceConfig; the controller keeps them as numbered snapshots
+// tied to the resource version they were taken at, and can push one back
+// (POST /resources/{id}:restoreConfig?snapshot=N).
+// =============================================================================
+
+// DeviceConfig is a device's full settings in the vendor's own format.
+// Only the provider that exported it can read it.
+type DeviceConfig struct {
+	// Format names the vendor and layout ("sony-settings/v1"); a config
+	// is only imported by a provider that reads this format.
+	Format string `json:"format"`
+
+	// Data is the settings dump as the vendor returned it.
+	Data json.RawMessage `json:"data"`
+}
+
+// ConfigSnapshot is a DeviceConfig saved by the controller.
+type ConfigSnapshot struct {
+	// Number identifies the snapshot within its resource (1, 2, ...).
+	Number int `json:"number"`
+
+	// ResourceID is the resource the device belongs to.
+	ResourceID string `json:"resource_id"`
+
+	// ResourceVersion is the resource_version the snapshot was taken at:
+	// the spec the device was running (see GET /resources/{id}/revisions).
+	ResourceVersion int64 `json:"resource_version"`
+
+	// Trigger is what took the snapshot: "manual" (POST :backupConfig)
+	// or "change" (after a create or spec change).
+	Trigger string `json:"trigger"`
+
+	// TakenAt is when the settings were read from the device.
+	TakenAt time.Time `json:"taken_at"`
+
+	// Format, SizeBytes and Digest (sha256, hex) describe the dump
+	// without returning it.
+	Format    string `json:"format"`
+	SizeBytes int    `json:"size_bytes"`
+	Digest    string `json:"digest"`
+
+	// Config is the dump itself; left out of lists.
+	Config *DeviceConfig `json:"config,omitempty"`
+}
+
+// ConfigRestore is the response of POST /resources/{id}:restoreConfig.
+type ConfigRestore struct {
+	// Snapshot is the snapshot pushed to the device (without the dump).
+	Snapshot ConfigSnapshot `json:"snapshot"`
+
+	// Status is the device status reported after the restore.
+	Status ResourceStatus `json:"status"`
+}
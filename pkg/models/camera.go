@@ -0,0 +1,105 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// =============================================================================
+// CAMERA CONTROL (PTZ AND PRESETS)
+// =============================================================================
+// Live productions move cameras during the show: pan/tilt/zoom (PTZ) and
+// recalling saved positions (presets). These are the vendor-agnostic
+// views used by /resources/{id}/ptz and /resources/{id}/presets.
+//
+// Units are normalized across vendors:
+//   Pan   degrees, -180 (left) .. 180 (right)
+//   Tilt  degrees, -90 (down) .. 90 (up)
+//   Zoom  0 (widest) .. 1 (full telephoto)
+//   Speed 0 (slowest) .. 1 (fastest); 0 = vendor default
+// =============================================================================
+
+// PTZ actions.
+const (
+	PTZAbsolute = "absolute" // move to Pan/Tilt/Zoom
+	PTZRelative = "relative" // move by Pan/Tilt/Zoom
+	PTZHome     = "home"     // return to the home position
+	PTZStop     = "stop"     // stop any movement
+)
+
+// MaxPresets is the number of preset slots Forge exposes (1..MaxPresets).
+const MaxPresets = 100
+
+// PTZCommand is the body of POST /resources/{id}/ptz.
+// Omitted axes are left unchanged.
+type PTZCommand struct {
+	Action string   `json:"action"`
+	Pan    *float64 `json:"pan,omitempty"`
+	Tilt   *float64 `json:"tilt,omitempty"`
+	Zoom   *float64 `json:"zoom,omitempty"`
+	Speed  float64  `json:"speed,omitempty"`
+}
+
+// Validate checks the action and that values are within range.
+// Relative moves may span the full range in either direction.
+func (c *PTZCommand) Validate() error {
+	switch c.Action {
+	case PTZAbsolute, PTZRelative:
+		if c.Pan == nil && c.Tilt == nil && c.Zoom == nil {
+			return fmt.Errorf("%s requires at least one of pan, tilt, zoom", c.Action)
+		}
+	case PTZHome, PTZStop:
+		if c.Pan != nil || c.Tilt != nil || c.Zoom != nil {
+			return fmt.Errorf("%s takes no pan, tilt or zoom", c.Action)
+		}
+	default:
+		return fmt.Errorf("action must be one of absolute, relative, home, stop")
+	}
+
+	scale := 1.0
+	if c.Action == PTZRelative {
+		scale = 2 // e.g. pan from -180 to +180 is a relative move of 360
+	}
+	if c.Pan != nil && (*c.Pan < -180*scale || *c.Pan > 180*scale) {
+		return fmt.Errorf("pan %.1f out of range", *c.Pan)
+	}
+	if c.Tilt != nil && (*c.Tilt < -90*scale || *c.Tilt > 90*scale) {
+		return fmt.Errorf("tilt %.1f out of range", *c.Tilt)
+	}
+	if c.Zoom != nil {
+		low := 0.0
+		if c.Action == PTZRelative {
+			low = -1
+		}
+		if *c.Zoom < low || *c.Zoom > 1 {
+			return fmt.Errorf("zoom %.2f out of range", *c.Zoom)
+		}
+	}
+	if c.Speed < 0 || c.Speed > 1 {
+		return fmt.Errorf("speed must be between 0 and 1")
+	}
+	return nil
+}
+
+// PTZPosition is a camera's current (or saved) position.
+type PTZPosition struct {
+	Pan  float64 `json:"pan"`
+	Tilt float64 `json:"tilt"`
+	Zoom float64 `json:"zoom"`
+}
+
+// PresetRequest is the body of POST /resources/{id}/presets: it saves the
+// camera's current position into slot Number.
+type PresetRequest struct {
+	Number int    `json:"number"`
+	Name   string `json:"name,omitempty"`
+}
+
+// CameraPreset is a saved camera position.
+type CameraPreset struct {
+	// Number is the 1-based preset slot.
+	Number   int         `json:"number"`
+	Name     string      `json:"name,omitempty"`
+	Position PTZPosition `json:"position"`
+	SavedAt  time.Time   `json:"saved_at,omitempty"`
+}
@@ -0,0 +1,41 @@
+package models
+
+// =============================================================================
+// VENDOR CAPACITY
+// =============================================================================
+// How much room a vendor account has left: devices against the account
+// quota, and leases in the address pool devices without an ip_address get
+// their address from. Used to check a plan before anything is created.
+// =============================================================================
+
+// VendorCapacity is a vendor account's device and address usage.
+type VendorCapacity struct {
+	// Devices counts the account's devices; DeviceQuota caps them
+	// (0 = no quota)
+	Devices     int `json:"devices"`
+	DeviceQuota int `json:"device_quota,omitempty"`
+
+	// AddressPool is the pool DHCP leases come from (nil if the vendor
+	// doesn't assign addresses)
+	AddressPool *AddressPool `json:"address_pool,omitempty"`
+
+	// AddressesInUse are the addresses the account's devices hold, leased
+	// or configured
+	AddressesInUse []string `json:"addresses_in_use,omitempty"`
+}
+
+// AddressPool is a vendor's DHCP pool.
+type AddressPool struct {
+	// Range is the pool, e.g. "10.0.8.10-10.0.8.249"
+	Range  string `json:"range"`
+	Size   int    `json:"size"`
+	Leased int    `json:"leased"`
+}
+
+// Free returns how many addresses are left to lease.
+func (p *AddressPool) Free() int {
+	if p.Leased >= p.Size {
+		return 0
+	}
+	return p.Size - p.Leased
+}
@@ -0,0 +1,67 @@
+package models
+
+// =============================================================================
+// STATUS CONDITIONS
+// =============================================================================
+// Phase and HealthStatus summarize a resource in one word each. Conditions
+// say which part is (or isn't) working, in the spirit of Kubernetes
+// conditions:
+//
+//   {"type": "GenlockLocked", "status": "False", "reason": "Unlocked",
+//    "message": "Not locked to blackburst reference"}
+// =============================================================================
+
+// Condition statuses.
+const (
+	ConditionTrue    = "True"
+	ConditionFalse   = "False"
+	ConditionUnknown = "Unknown"
+)
+
+// Condition types.
+const (
+	// ConditionTimecodeLocked: timecode follows the configured source
+	ConditionTimecodeLocked = "TimecodeLocked"
+
+	// ConditionGenlockLocked: frame timing is locked to the reference
+	ConditionGenlockLocked = "GenlockLocked"
+
+	// ConditionClockSynchronized: the device clock is synchronized with
+	// the configured NTP server
+	ConditionClockSynchronized = "ClockSynchronized"
+
+	// ConditionMaintenanceWindow: the resource's vendor is in a declared
+	// maintenance window (set by the controller, not the vendor)
+	ConditionMaintenanceWindow = "MaintenanceWindow"
+
+	// Metric conditions: the reading is below the health policy's
+	// degraded threshold (set by the controller, see metrics.go)
+	ConditionDroppedFramesWithinLimits = "DroppedFramesWithinLimits"
+	ConditionTemperatureWithinLimits   = "TemperatureWithinLimits"
+	ConditionCPUWithinLimits           = "CPUWithinLimits"
+	ConditionMemoryWithinLimits        = "MemoryWithinLimits"
+)
+
+// Condition is the state of one aspect of a resource.
+type Condition struct {
+	Type string `json:"type"`
+
+	// Status is ConditionTrue, ConditionFalse or ConditionUnknown.
+	Status string `json:"status"`
+
+	// Reason is a short CamelCase machine-readable cause.
+	Reason string `json:"reason,omitempty"`
+
+	// Message is a human-readable description.
+	Message string `json:"message,omitempty"`
+}
+
+// Condition returns the condition of type t, or nil if it isn't reported.
+func (s *ResourceStatus) Condition(t string) *Condition {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == t {
+			return &s.Conditions[i]
+		}
+	}
+	return nil
+}
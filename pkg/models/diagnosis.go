@@ -0,0 +1,110 @@
+package models
+
+import "time"
+
+// =============================================================================
+// CONNECTION DIAGNOSIS
+// =============================================================================
+// "health check failed: context deadline exceeded" doesn't say whether the
+// vendor's name doesn't resolve, a firewall drops the connection, the
+// certificate expired or the API key was rotated. A diagnosis walks the
+// connection step by step and reports each step, so the failing one is
+// obvious. Steps after a failed one that depend on it are skipped.
+// =============================================================================
+
+// Connection check names, in the order they run.
+const (
+	DiagnoseDNS     = "dns"
+	DiagnoseTCP     = "tcp"
+	DiagnoseTLS     = "tls"
+	DiagnoseAuth    = "auth"
+	DiagnoseLatency = "latency"
+)
+
+// Connection check outcomes, best first.
+const (
+	CheckPassed  = "pass"
+	CheckSkipped = "skip"
+	CheckWarning = "warn"
+	CheckFailed  = "fail"
+)
+
+// ConnectionCheck is the outcome of one step.
+type ConnectionCheck struct {
+	Name       string  `json:"name"`
+	Status     string  `json:"status"`
+	DurationMS float64 `json:"duration_ms"`
+	Message    string  `json:"message,omitempty"`
+}
+
+// CertificateInfo describes a certificate the vendor presented.
+type CertificateInfo struct {
+	Subject       string    `json:"subject"`
+	Issuer        string    `json:"issuer"`
+	DNSNames      []string  `json:"dns_names,omitempty"`
+	NotBefore     time.Time `json:"not_before"`
+	NotAfter      time.Time `json:"not_after"`
+	ExpiresInDays int       `json:"expires_in_days"`
+}
+
+// TLSDetails describes the negotiated TLS session.
+type TLSDetails struct {
+	Version            string `json:"version"`
+	CipherSuite        string `json:"cipher_suite"`
+	ServerName         string `json:"server_name"`
+	NegotiatedProtocol string `json:"negotiated_protocol,omitempty"`
+
+	// Verified is false if the chain or host name didn't verify (the
+	// certificates are still reported)
+	Verified bool `json:"verified"`
+
+	// Certificates is the chain as presented, leaf first
+	Certificates []CertificateInfo `json:"certificates"`
+}
+
+// LatencyStats summarizes the round trips of repeated requests.
+type LatencyStats struct {
+	SamplesMS []float64 `json:"samples_ms"`
+	Failures  int       `json:"failures"`
+	MinMS     float64   `json:"min_ms"`
+	AvgMS     float64   `json:"avg_ms"`
+	P50MS     float64   `json:"p50_ms"`
+	MaxMS     float64   `json:"max_ms"`
+}
+
+// ConnectionDiagnosis is the report of POST /providers/{name}:diagnose.
+type ConnectionDiagnosis struct {
+	Provider string `json:"provider"`
+
+	// Endpoint is the vendor base URL (credentials redacted)
+	Endpoint string `json:"endpoint"`
+
+	// Status is the worst check outcome
+	Status     string    `json:"status"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMS float64   `json:"duration_ms"`
+
+	// Addresses the host name resolved to
+	Addresses []string `json:"addresses,omitempty"`
+
+	// AuthScheme is how requests are authenticated (bearer, hmac, ...)
+	AuthScheme string `json:"auth_scheme,omitempty"`
+
+	TLS     *TLSDetails       `json:"tls,omitempty"`
+	Latency *LatencyStats     `json:"latency,omitempty"`
+	Checks  []ConnectionCheck `json:"checks"`
+}
+
+// checkSeverity orders outcomes for the overall status.
+var checkSeverity = map[string]int{CheckPassed: 0, CheckSkipped: 0, CheckWarning: 1, CheckFailed: 2}
+
+// Add appends a check and updates the overall status.
+func (d *ConnectionDiagnosis) Add(check ConnectionCheck) {
+	d.Checks = append(d.Checks, check)
+	if d.Status == "" || checkSeverity[check.Status] > checkSeverity[d.Status] {
+		d.Status = check.Status
+	}
+	if d.Status == CheckSkipped {
+		d.Status = CheckPassed
+	}
+}
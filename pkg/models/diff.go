@@ -0,0 +1,72 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// FieldChange describes one field that differs between two specs.
+// Path uses the JSON field names, e.g. "spec.config.sony_model".
+type FieldChange struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// DiffSpecs returns the fields that differ between old and new, sorted by
+// path. Fields are compared by their JSON representation so omitempty
+// zero values and absent fields compare equal.
+func DiffSpecs(old, new ResourceSpec) []FieldChange {
+	oldFields := make(map[string]interface{})
+	newFields := make(map[string]interface{})
+	flattenJSON("spec", old, oldFields)
+	flattenJSON("spec", new, newFields)
+
+	var changes []FieldChange
+	for path, oldVal := range oldFields {
+		newVal, ok := newFields[path]
+		if !ok {
+			changes = append(changes, FieldChange{Path: path, Old: oldVal})
+			continue
+		}
+		if !reflect.DeepEqual(oldVal, newVal) {
+			changes = append(changes, FieldChange{Path: path, Old: oldVal, New: newVal})
+		}
+	}
+	for path, newVal := range newFields {
+		if _, ok := oldFields[path]; !ok {
+			changes = append(changes, FieldChange{Path: path, New: newVal})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// flattenJSON round-trips v through JSON and records every leaf value under
+// its dotted path. Arrays are treated as leaves.
+func flattenJSON(prefix string, v interface{}, out map[string]interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return
+	}
+	flattenValue(prefix, generic, out)
+}
+
+func flattenValue(prefix string, v interface{}, out map[string]interface{}) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		if v != nil {
+			out[prefix] = v
+		}
+		return
+	}
+	for key, child := range obj {
+		flattenValue(prefix+"."+key, child, out)
+	}
+}
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// =============================================================================
+// DISCOVERY AND ADOPTION MODELS
+// =============================================================================
+// Discovery finds devices that exist in a vendor system but are not managed
+// by Forge (created by hand, by another team, or left behind by a failed
+// delete). Instead of silently taking ownership, each unmanaged device
+// becomes an AdoptionProposal that an operator must explicitly approve.
+//
+// Adoption Lifecycle:
+// pending-adoption → approved (becomes a managed ForgeResource)
+//                  → rejected (ignored by future scans)
+// =============================================================================
+
+// Adoption proposal states.
+const (
+	AdoptionPending  = "pending-adoption"
+	AdoptionApproved = "approved"
+	AdoptionRejected = "rejected"
+)
+
+// DiscoveryPage is one page of a vendor's device inventory.
+type DiscoveryPage struct {
+	Devices []DiscoveredDevice
+
+	// NextCursor fetches the following page ("" on the last page).
+	NextCursor string
+
+	// Total is the vendor's count of all devices (0 if not reported).
+	Total int
+}
+
+// DiscoveredDevice is a device found in a vendor system by a provider's
+// discovery capability, already reverse-mapped into Forge terms.
+type DiscoveredDevice struct {
+	// VendorType is the provider that reported the device (e.g. "sony").
+	VendorType string `json:"vendor_type"`
+
+	// VendorID is the vendor's identifier for the device.
+	VendorID string `json:"vendor_id"`
+
+	// Name is the device name as registered with the vendor.
+	Name string `json:"name"`
+
+	// Type is the Forge resource type the device maps to (e.g. "camera").
+	Type string `json:"type"`
+
+	// Namespace is taken from vendor-side tags when present.
+	Namespace string `json:"namespace,omitempty"`
+
+	// ForgeID is the Forge resource ID the vendor has the device tagged with.
+	// A non-empty ForgeID that doesn't exist in the controller indicates an
+	// orphan left behind by a lost or failed delete.
+	ForgeID string `json:"forge_id,omitempty"`
+
+	// Annotations are the vendor-side metadata other than Forge's own
+	// tags (see ForgeResource.Annotations); kept on adoption.
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Spec is the reverse-mapped desired state matching the device's
+	// current vendor configuration.
+	Spec ResourceSpec `json:"spec"`
+
+	// Status is the device's observed state.
+	Status ResourceStatus `json:"status"`
+}
+
+// AdoptionProposal is an unmanaged vendor device awaiting an explicit
+// approval before the controller starts managing it.
+type AdoptionProposal struct {
+	// ID identifies the proposal (e.g. "adopt-sony-sony-dev-123").
+	ID string `json:"id"`
+
+	// State is one of AdoptionPending, AdoptionApproved, AdoptionRejected.
+	State string `json:"state"`
+
+	// Device is the discovered device, including its reverse-mapped spec.
+	Device DiscoveredDevice `json:"device"`
+
+	// Fingerprint is a hash of the proposed spec. Approvals must echo it
+	// back so an operator can't approve a spec they haven't seen.
+	Fingerprint string `json:"fingerprint"`
+
+	// Changes lists how the reverse-mapped spec changed since the
+	// proposal was first created (populated when re-scans see drift).
+	Changes []FieldChange `json:"changes,omitempty"`
+
+	// DiscoveredAt is when the device was first seen unmanaged.
+	DiscoveredAt time.Time `json:"discovered_at"`
+
+	// LastSeenAt is when the most recent scan saw the device.
+	LastSeenAt time.Time `json:"last_seen_at"`
+
+	// DecidedAt is when the proposal was approved or rejected.
+	DecidedAt time.Time `json:"decided_at,omitempty"`
+
+	// Reason is the operator-provided reason for the decision.
+	Reason string `json:"reason,omitempty"`
+
+	// ResourceID is the managed ForgeResource created on approval.
+	ResourceID string `json:"resource_id,omitempty"`
+}
+
+// SpecFingerprint returns a stable hash of a spec.
+// WHY JSON: encoding/json sorts map keys, so equal specs hash equally.
+func SpecFingerprint(spec ResourceSpec) string {
+	data, _ := json.Marshal(spec)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
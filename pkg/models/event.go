@@ -0,0 +1,105 @@
+package models
+
+import "time"
+
+// =============================================================================
+// RESOURCE EVENTS
+// =============================================================================
+// Events are short, human-readable records of what happened to a resource
+// ("Created", "PhaseChanged", "Deleted"), in the spirit of Kubernetes
+// events. They feed GET /resources/{id}/events and notifications.
+// =============================================================================
+
+// Event types.
+const (
+	EventNormal  = "Normal"
+	EventWarning = "Warning"
+)
+
+// Event reasons.
+const (
+	ReasonCreated          = "Created"
+	ReasonCreateFailed     = "CreateFailed"
+	ReasonPhaseChanged     = "PhaseChanged"
+	ReasonHealthChanged    = "HealthChanged"
+	ReasonConditionChanged = "ConditionChanged"
+	ReasonDeleted          = "Deleted"
+	ReasonAdopted          = "Adopted"
+	ReasonUpdated          = "Updated"
+	ReasonUpdateFailed     = "UpdateFailed"
+	ReasonQueued           = "Queued"
+
+	ReasonRecordingStarted = "RecordingStarted"
+	ReasonRecordingStopped = "RecordingStopped"
+	ReasonRecordingDeleted = "RecordingDeleted"
+
+	ReasonPresetSaved    = "PresetSaved"
+	ReasonPresetRecalled = "PresetRecalled"
+
+	ReasonIdle    = "Idle"
+	ReasonStopped = "Stopped"
+	ReasonStarted = "Started"
+
+	ReasonDeleting       = "Deleting"
+	ReasonDeleteProgress = "DeleteProgress"
+	ReasonDeleteFailed   = "DeleteFailed"
+
+	ReasonDeleteScheduled = "DeleteScheduled"
+	ReasonRestored        = "Restored"
+	ReasonFinalizing      = "Finalizing"
+	ReasonCascading       = "Cascading"
+	ReasonOrphaned        = "Orphaned"
+
+	ReasonMetadataUpdated = "MetadataUpdated"
+
+	ReasonLocked      = "Locked"
+	ReasonUnlocked    = "Unlocked"
+	ReasonLockExpired = "LockExpired"
+
+	ReasonShared       = "Shared"
+	ReasonShareRevoked = "ShareRevoked"
+
+	ReasonConfigBackedUp      = "ConfigBackedUp"
+	ReasonConfigBackupFailed  = "ConfigBackupFailed"
+	ReasonConfigRestored      = "ConfigRestored"
+	ReasonConfigRestoreFailed = "ConfigRestoreFailed"
+
+	ReasonActionRun    = "ActionRun"
+	ReasonActionFailed = "ActionFailed"
+
+	ReasonDNSRegistered = "DNSRegistered"
+	ReasonDNSFailed     = "DNSFailed"
+
+	ReasonConfigWarning = "ConfigWarning"
+
+	ReasonCloned = "Cloned"
+
+	ReasonVendorCallback = "VendorCallback"
+)
+
+// Event records something that happened to a resource.
+type Event struct {
+	// ID is unique per controller process (e.g. "evt-42").
+	ID string `json:"id"`
+
+	// ResourceID is the resource the event is about.
+	ResourceID string `json:"resource_id"`
+
+	// Time is when the event occurred.
+	Time time.Time `json:"time"`
+
+	// Type is EventNormal or EventWarning.
+	Type string `json:"type"`
+
+	// Reason is a short CamelCase machine-readable cause.
+	Reason string `json:"reason"`
+
+	// Message is a human-readable description.
+	Message string `json:"message"`
+
+	// Phase is set when the event moved the resource INTO this phase.
+	Phase string `json:"phase,omitempty"`
+
+	// Health is set when the event moved the resource INTO this health status.
+	Health string `json:"health,omitempty"`
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// =============================================================================
+// REVISION HISTORY
+// =============================================================================
+// Every change to a ForgeResource (create, status refresh, delete, ...) is
+// recorded as an immutable ResourceRevision holding a full snapshot. This
+// makes it possible to answer post-incident questions such as "what was
+// the bitrate configured as when frames started dropping?" by replaying
+// the resource as it was at any past point in time.
+// =============================================================================
+
+// ResourceRevision is a point-in-time snapshot of a ForgeResource.
+type ResourceRevision struct {
+	// Revision numbers a resource's snapshots starting at 1.
+	Revision int64 `json:"revision"`
+
+	// Timestamp is when the change was recorded.
+	Timestamp time.Time `json:"timestamp"`
+
+	// Reason describes what caused the change ("created", "status-refresh",
+	// "deleted", ...).
+	Reason string `json:"reason"`
+
+	// Deleted marks the tombstone revision recorded when a resource is
+	// removed; Resource holds its last known state.
+	Deleted bool `json:"deleted,omitempty"`
+
+	// Resource is the full snapshot (spec and status).
+	Resource ForgeResource `json:"resource"`
+}
+
+// DeepCopy returns a copy of the resource that shares no mutable state
+// (maps, slices) with the original.
+//
+// WHY JSON ROUND-TRIP: Config values are arbitrary JSON (nested maps and
+// slices) and the struct keeps growing; a round-trip clones everything
+// faithfully without a hand-written copy that must track every new field.
+func (r *ForgeResource) DeepCopy() *ForgeResource {
+	data, err := json.Marshal(r)
+	if err != nil {
+		out := *r
+		return &out
+	}
+	var out ForgeResource
+	if err := json.Unmarshal(data, &out); err != nil {
+		out = *r
+	}
+	return &out
+}
+
+// DeepCopy returns a copy of the status that shares no slices with the
+// original (see ForgeResource.DeepCopy).
+func (s *ResourceStatus) DeepCopy() *ResourceStatus {
+	data, err := json.Marshal(s)
+	if err != nil {
+		out := *s
+		return &out
+	}
+	var out ResourceStatus
+	if err := json.Unmarshal(data, &out); err != nil {
+		out = *s
+	}
+	return &out
+}
@@ -0,0 +1,63 @@
+package models
+
+// =============================================================================
+// ADAPTIVE BITRATE LADDER
+// =============================================================================
+// An adaptive (ABR) output is encoded several times at falling quality so
+// players can switch rungs as their bandwidth changes. spec.ladder lists
+// the renditions, best first:
+//
+//   "ladder": [
+//     {"name": "1080p", "resolution": "FHD", "bitrate": 6000000},
+//     {"name": "720p",  "resolution": "HD",  "bitrate": 3000000},
+//     {"name": "480p",  "resolution": "SD",  "bitrate": 1200000, "codec": "H.264"}
+//   ]
+//
+// Codec and frame rate default to the spec's own. Without a ladder the
+// spec describes a single output (Resolution, Bitrate, Codec).
+// =============================================================================
+
+// Rendition is one rung of an ABR ladder.
+type Rendition struct {
+	// Name identifies the rendition in vendor output names and playlists.
+	// Defaults to the resolution ("FHD").
+	Name string `json:"name,omitempty"`
+
+	// Resolution uses the same values as Spec.Resolution.
+	Resolution string `json:"resolution"`
+
+	// Bitrate is the video bitrate in bits per second.
+	Bitrate int64 `json:"bitrate"`
+
+	// Codec overrides Spec.Codec for this rendition.
+	Codec string `json:"codec,omitempty"`
+
+	// FrameRate overrides Spec.FrameRate for this rendition, e.g. 30 for
+	// the low rungs of a 60 fps ladder.
+	FrameRate float64 `json:"frame_rate,omitempty"`
+}
+
+// RenditionName returns the rendition's name, defaulting to its resolution.
+func (r Rendition) RenditionName() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.Resolution
+}
+
+// EffectiveCodec returns the rendition's codec, defaulting to spec's.
+func (r Rendition) EffectiveCodec(spec ResourceSpec) string {
+	if r.Codec != "" {
+		return r.Codec
+	}
+	return spec.Codec
+}
+
+// EffectiveFrameRate returns the rendition's frame rate, defaulting to
+// spec's.
+func (r Rendition) EffectiveFrameRate(spec ResourceSpec) float64 {
+	if r.FrameRate > 0 {
+		return r.FrameRate
+	}
+	return spec.FrameRate
+}
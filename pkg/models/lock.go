@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// =============================================================================
+// RESOURCE LOCKS
+// =============================================================================
+// An operator working on a device by hand (re-cabling, re-aiming, a
+// firmware update through the vendor console) takes a time-boxed lock on
+// its resource so nobody else changes or deletes it meanwhile.
+// =============================================================================
+
+// ResourceLock is an exclusive, expiring lock on one resource.
+type ResourceLock struct {
+	ResourceID string `json:"resource_id"`
+
+	// Owner is the principal holding the lock
+	Owner string `json:"owner"`
+
+	// Reason says what the manual work is
+	Reason string `json:"reason,omitempty"`
+
+	AcquiredAt time.Time `json:"acquired_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// ResourceMetadata is operational context people attach to a resource:
+// what on-call should know about it, and where its runbook is. It is
+// Forge-only; changing it never touches the vendor.
+type ResourceMetadata struct {
+	// Notes is free-form text ("spare body, swap with cam-4 if it fails").
+	Notes string `json:"notes,omitempty"`
+
+	// RunbookURL links the procedure for this device (http or https).
+	RunbookURL string `json:"runbook_url,omitempty"`
+
+	// UpdatedBy and UpdatedAt record the last change to the fields above
+	// (nil while they are empty). Set by the controller; ignored in
+	// requests.
+	UpdatedBy string     `json:"updated_by,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// IsZero reports whether no notes or runbook are set.
+func (m ResourceMetadata) IsZero() bool {
+	return m.Notes == "" && m.RunbookURL == ""
+}
@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// =============================================================================
+// HEALTH METRICS
+// =============================================================================
+// Providers report a device's health readings in Status.Metrics under
+// these names. The controller compares them with its health policy
+// thresholds; each metric with a threshold becomes a condition saying
+// whether the reading is within limits.
+// =============================================================================
+
+// Metric names.
+const (
+	// MetricDroppedFrames: frames dropped per minute of streaming
+	MetricDroppedFrames = "dropped_frames_per_minute"
+
+	// MetricTemperature: device temperature in degrees Celsius
+	MetricTemperature = "temperature_celsius"
+
+	// MetricCPU: CPU utilization in percent
+	MetricCPU = "cpu_percent"
+
+	// MetricMemory: memory utilization in percent
+	MetricMemory = "memory_percent"
+)
+
+// MetricConditions maps each metric to the condition type reporting it.
+var MetricConditions = map[string]string{
+	MetricDroppedFrames: ConditionDroppedFramesWithinLimits,
+	MetricTemperature:   ConditionTemperatureWithinLimits,
+	MetricCPU:           ConditionCPUWithinLimits,
+	MetricMemory:        ConditionMemoryWithinLimits,
+}
+
+// ResourceMetrics is a device's live readings, as returned by
+// GET /resources/{id}/metrics. Read from the vendor on every request and
+// never stored.
+type ResourceMetrics struct {
+	ResourceID string `json:"resource_id"`
+	VendorType string `json:"vendor_type"`
+	VendorID   string `json:"vendor_id"`
+
+	// CollectedAt is when the vendor was read
+	CollectedAt time.Time `json:"collected_at"`
+
+	// CurrentBitrate is the measured output bitrate in bps (0 when not
+	// streaming)
+	CurrentBitrate int64 `json:"current_bitrate"`
+
+	// DroppedFrames is the count of frames dropped since the stream started
+	DroppedFrames int64 `json:"dropped_frames"`
+
+	// UptimeSeconds is how long the device (or its stream) has been running
+	UptimeSeconds int64 `json:"uptime_seconds"`
+
+	// Metrics are the health readings by name (MetricTemperature, ...)
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+
+	// Health is the vendor's full health report, for vendors that have one
+	// (Sony: CPU, memory, temperature, fans, storage, network)
+	Health *SonyHealthMetrics `json:"health,omitempty"`
+}
@@ -0,0 +1,49 @@
+package models
+
+// =============================================================================
+// PREFLIGHT CHECKS
+// =============================================================================
+// A preflight asks the vendor whether a create would succeed before
+// anything is provisioned: a resource that can't work never exists, so
+// there is nothing to clean up.
+// =============================================================================
+
+// Preflight check names.
+const (
+	PreflightQuota        = "quota"
+	PreflightReachability = "ip-reachability"
+	PreflightModel        = "model-availability"
+)
+
+// PreflightCheck is the outcome of one check.
+type PreflightCheck struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+}
+
+// PreflightResult is the outcome of all checks for one create.
+type PreflightResult struct {
+	Checks []PreflightCheck `json:"checks"`
+}
+
+// Passed reports whether every check passed.
+func (r *PreflightResult) Passed() bool {
+	for _, check := range r.Checks {
+		if !check.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// Failed returns the checks that did not pass.
+func (r *PreflightResult) Failed() []PreflightCheck {
+	var failed []PreflightCheck
+	for _, check := range r.Checks {
+		if !check.Passed {
+			failed = append(failed, check)
+		}
+	}
+	return failed
+}
@@ -0,0 +1,37 @@
+package models
+
+// =============================================================================
+// DESTINATION PROBES
+// =============================================================================
+// A device provisioned with a stream destination nobody can reach streams
+// into the void. A probe connects to the destination the way the device
+// will before anything is provisioned.
+// =============================================================================
+
+// Probe methods.
+const (
+	ProbeTCPConnect    = "tcp-connect"
+	ProbeRTMPHandshake = "rtmp-handshake"
+	ProbeSRTHandshake  = "srt-handshake"
+	ProbeSkipped       = "skipped"
+)
+
+// DestinationProbe is the outcome of probing one stream destination.
+type DestinationProbe struct {
+	URL string `json:"url"`
+
+	// Address is the host:port that was probed
+	Address string `json:"address,omitempty"`
+
+	// Method is how it was probed (ProbeTCPConnect, ...); ProbeSkipped
+	// for destinations that can't be probed from the caller's side
+	// (SRT listener mode, multicast)
+	Method string `json:"method"`
+
+	// From says who probed: "controller" or the vendor's name
+	From string `json:"from"`
+
+	Reachable bool    `json:"reachable"`
+	RTTMillis float64 `json:"rtt_ms,omitempty"`
+	Message   string  `json:"message,omitempty"`
+}
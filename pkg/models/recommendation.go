@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// =============================================================================
+// RECOMMENDATIONS
+// =============================================================================
+// Recommendations are suggestions computed by the controller's analyzers,
+// returned by GET /recommendations. They are derived from current state,
+// not stored: a recommendation disappears once its cause does.
+// =============================================================================
+
+// Recommendation kinds.
+const (
+	// RecommendationIdle: Running with no viewers and no output bitrate
+	RecommendationIdle = "idle"
+)
+
+// Recommendation actions.
+const (
+	ActionStop = "stop"
+)
+
+// Recommendation is one suggested change to a resource.
+type Recommendation struct {
+	// ID is stable for as long as the recommendation applies
+	// ("idle-res-123"), so it can be applied by ID.
+	ID string `json:"id"`
+
+	Kind   string `json:"kind"`
+	Action string `json:"action"`
+	Reason string `json:"reason"`
+
+	ResourceID string `json:"resource_id"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+	VendorType string `json:"vendor_type"`
+
+	// IdleSince is when the resource was first seen idle
+	IdleSince time.Time `json:"idle_since"`
+
+	// AutoApply is true if policy will apply the action without a human
+	AutoApply bool `json:"auto_apply"`
+}
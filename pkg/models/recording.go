@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// =============================================================================
+// RECORDING SESSIONS
+// =============================================================================
+// A recording session is one start/stop cycle of recording on a device.
+// Sessions live in the vendor system; these are the vendor-agnostic views
+// returned by GET /resources/{id}/recordings.
+// =============================================================================
+
+// Recording session states.
+const (
+	RecordingActive  = "recording"
+	RecordingStopped = "stopped"
+)
+
+// RecordingRequest is the body of POST /resources/{id}/recordings.
+// Empty fields default to the resource spec (RecordingPath, RetentionDays,
+// config recording_format / recording_quality).
+type RecordingRequest struct {
+	Name          string `json:"name"`
+	StoragePath   string `json:"storage_path,omitempty"`
+	Format        string `json:"format,omitempty"`
+	Quality       string `json:"quality,omitempty"`
+	RetentionDays int    `json:"retention_days,omitempty"`
+}
+
+// RecordingSession is a recording as reported by the vendor.
+type RecordingSession struct {
+	// ID is the vendor's session identifier.
+	ID string `json:"id"`
+
+	// ResourceID is the Forge resource that recorded it.
+	ResourceID string `json:"resource_id"`
+
+	Name  string `json:"name"`
+	State string `json:"state"`
+
+	// StoragePath is where the recording is written; FilePath the file itself.
+	StoragePath string `json:"storage_path"`
+	FilePath    string `json:"file_path,omitempty"`
+	Format      string `json:"format,omitempty"`
+
+	StartedAt time.Time `json:"started_at"`
+	StoppedAt time.Time `json:"stopped_at,omitempty"`
+
+	// DurationSeconds is the length so far (active) or final length (stopped).
+	DurationSeconds float64 `json:"duration_seconds"`
+	SizeBytes       int64   `json:"size_bytes,omitempty"`
+
+	// RetentionDays is how long a stopped recording is kept (0 = forever).
+	RetentionDays int `json:"retention_days,omitempty"`
+}
+
+// ExpiresAt returns when a stopped recording falls out of retention,
+// or the zero time if it never expires (still active, or no retention).
+func (r *RecordingSession) ExpiresAt() time.Time {
+	if r.State != RecordingStopped || r.RetentionDays <= 0 || r.StoppedAt.IsZero() {
+		return time.Time{}
+	}
+	return r.StoppedAt.AddDate(0, 0, r.RetentionDays)
+}
@@ -60,6 +60,40 @@ type ForgeResource struct {
 	// This allows the same resource names in different environments.
 	Namespace string `json:"namespace"`
 
+	// DependsOn lists the IDs of resources this one needs to work, e.g. an
+	// encoder depending on the camera that feeds it. Dependencies must
+	// exist at creation time, and a resource can't be deleted while others
+	// depend on it (batch delete tears dependents down first).
+	DependsOn []string `json:"depends_on,omitempty"`
+
+	// OwnerRef is the ID of the resource this one belongs to, e.g. a
+	// production owning its cameras. The owner must exist in the same
+	// namespace; deleting it deletes what it owns first (cascading
+	// delete). See owners.go.
+	OwnerRef string `json:"owner_ref,omitempty"`
+
+	// Labels group resources ("env": "prod", "site": "stadium-a") for
+	// label selectors (?labelSelector=env=prod,site=stadium-a) on list,
+	// watch and delete. Forge-only; PATCH changes them without a vendor
+	// update. See pkg/labels.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations carry non-identifying information for integrators
+	// (ticket IDs, run-sheet references) that doesn't belong in
+	// Spec.Config. They can't be selected on; providers that support
+	// device metadata (Sony) receive them with the device.
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Finalizers name the external systems (recording archival, billing)
+	// that must finish with the resource before it is deleted
+	// ("archive.example.com/recordings"). A DELETE waits, Terminating,
+	// until every one is removed. Forge-only; set on create or with PATCH.
+	Finalizers []string `json:"finalizers,omitempty"`
+
+	// Metadata holds operational notes and a runbook link for on-call.
+	// Editable with PATCH without a vendor update. See metadata.go.
+	Metadata ResourceMetadata `json:"metadata"`
+
 	// Spec contains the desired state configuration for this resource.
 	// This is provided by the user and defines what they want.
 	Spec ResourceSpec `json:"spec"`
@@ -68,6 +102,11 @@ type ForgeResource struct {
 	// This is updated by the controller after vendor API interactions.
 	Status ResourceStatus `json:"status"`
 
+	// ResourceVersion increases with every change to the resource (it is
+	// the number of its latest revision). Returned as the ETag; PUT,
+	// PATCH and DELETE with If-Match only apply to the version they name.
+	ResourceVersion int64 `json:"resource_version"`
+
 	// CreatedAt records when this resource was first created in Forge.
 	// Set once during initial creation and never modified.
 	CreatedAt time.Time `json:"created_at"`
@@ -152,6 +191,11 @@ type ResourceSpec struct {
 	// Typical values: 128000 (128 kbps), 256000 (256 kbps), 320000 (320 kbps)
 	AudioBitrate int `json:"audio_bitrate,omitempty"`
 
+	// Ladder describes an adaptive bitrate output: several renditions,
+	// highest bitrate first (see Rendition). Resolution is the source;
+	// no rendition may be sharper.
+	Ladder []Rendition `json:"ladder,omitempty"`
+
 	// LatencyMode controls the encoding latency profile.
 	// Values: "low" (sub-second, for live interaction),
 	//         "normal" (2-5 seconds, balanced),
@@ -171,6 +215,43 @@ type ResourceSpec struct {
 	// After this period, recordings may be automatically deleted.
 	// Value of 0 means indefinite retention.
 	RetentionDays int `json:"retention_days,omitempty"`
+
+	// =========================================================================
+	// SYNC AND TIMING
+	// =========================================================================
+	// Broadcast devices must agree on time (timecode) and on frame timing
+	// (genlock) or cuts between sources glitch. Lock state is reported in
+	// Status.Conditions.
+	// =========================================================================
+
+	// TimecodeSource selects where the device's timecode comes from.
+	// Values: "internal" (free run), "ltc", "vitc", "ntp" (requires
+	// NTPServer), "ptp" (SMPTE 2059)
+	TimecodeSource string `json:"timecode_source,omitempty"`
+
+	// NTPServer is the time server the device synchronizes its clock with
+	// (host name or IP).
+	NTPServer string `json:"ntp_server,omitempty"`
+
+	// GenlockReference selects the reference signal frames are timed to.
+	// Values: "internal" (free run), "blackburst", "tri-level", "ptp"
+	GenlockReference string `json:"genlock_reference,omitempty"`
+
+	// Preflight asks the vendor to check the create before provisioning
+	// anything (quota, IP reachability, model availability). A failed
+	// check aborts the create with 422 instead of leaving a Failed resource.
+	Preflight bool `json:"preflight,omitempty"`
+
+	// ProbeDestination checks that StreamURL is reachable (RTMP or SRT
+	// handshake, else a TCP connect) before provisioning anything, from
+	// the vendor's side if the provider can, else from the controller.
+	// An unreachable destination aborts the create with 422.
+	ProbeDestination bool `json:"probe_destination,omitempty"`
+
+	// Environment selects the server-side overlay ("dev", "staging",
+	// "prod", ...) merged over this spec on create, so environments differ
+	// only where their profile says so (see SPEC_PROFILES_FILE).
+	Environment string `json:"environment,omitempty"`
 }
 
 // =============================================================================
@@ -214,6 +295,23 @@ type ResourceStatus struct {
 	// Example: Sony might use "device-12345", AWS uses "arn:aws:..."
 	VendorID string `json:"vendor_id"`
 
+	// Endpoints lists the network addresses the vendor assigned to the
+	// resource: where to reach the device, where it sends its output, and
+	// so on. Downstream systems use these to find streams without making
+	// vendor-specific calls. Refreshed on every read from the vendor.
+	Endpoints []Endpoint `json:"endpoints,omitempty"`
+
+	// DeleteAt is set while a deletion waits out its grace period
+	// (DELETE_GRACE_PERIOD): the vendor delete runs then, unless
+	// POST /resources/{id}/restore cancels it first. See softdelete.go.
+	DeleteAt *time.Time `json:"delete_at,omitempty"`
+
+	// Partial is set on a GET response that carries the stored status
+	// because the vendor read missed the route's latency budget or failed
+	// (the Forge-Degraded header says which). It is never stored: a
+	// background refresh brings the status up to date. See latency.go.
+	Partial bool `json:"partial,omitempty"`
+
 	// =========================================================================
 	// HEALTH CHECK FIELDS
 	// =========================================================================
@@ -275,6 +373,69 @@ type ResourceStatus struct {
 	// ErrorCount tracks the total errors encountered since resource creation.
 	// Includes both vendor API errors and operational errors.
 	ErrorCount int `json:"error_count,omitempty"`
+
+	// Metrics holds the device's latest health readings by name
+	// (MetricTemperature, MetricCPU, ...), as far as the vendor reports
+	// them. The controller's health policy rolls them up into HealthStatus.
+	// See metrics.go.
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+
+	// Conditions report the state of individual aspects of the resource
+	// (e.g. "GenlockLocked"), one entry per aspect the spec configures.
+	// Refreshed on every read from the vendor. See condition.go.
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// =============================================================================
+// ENDPOINTS
+// =============================================================================
+// Endpoints normalize the addresses vendors report in different shapes
+// (Sony's device IP and stream destinations, AWS egress source IPs) into
+// one list:
+//
+//   {"type": "ip",  "address": "10.0.9.12", "role": "control"}
+//   {"type": "url", "address": "srt://ingest:9000", "role": "output", "protocol": "srt"}
+// =============================================================================
+
+// Endpoint types.
+const (
+	EndpointTypeIP  = "ip"  // a bare IP address
+	EndpointTypeURL = "url" // a full URL
+)
+
+// Endpoint roles.
+const (
+	// EndpointRoleControl is where the device itself is reached (control
+	// protocol, web UI).
+	EndpointRoleControl = "control"
+	// EndpointRoleInput is where the resource accepts a stream (push to it).
+	EndpointRoleInput = "input"
+	// EndpointRoleOutput is where the resource delivers its stream (pull
+	// from it, or the destination it pushes to).
+	EndpointRoleOutput = "output"
+	// EndpointRoleEgress is a source address of outbound traffic, for
+	// firewall allow-lists.
+	EndpointRoleEgress = "egress"
+)
+
+// Endpoint is one vendor-assigned network address of a resource.
+type Endpoint struct {
+	// Type is "ip" or "url".
+	Type string `json:"type"`
+
+	// Address is the IP or URL.
+	Address string `json:"address"`
+
+	// Role says what the address is for: "control", "input", "output",
+	// or "egress".
+	Role string `json:"role"`
+
+	// Protocol is the stream protocol for URL endpoints ("rtmp", "srt").
+	Protocol string `json:"protocol,omitempty"`
+
+	// Connected reports whether the vendor currently has a working
+	// connection to the endpoint, when it tracks that.
+	Connected *bool `json:"connected,omitempty"`
 }
 
 // =============================================================================
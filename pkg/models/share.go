@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// =============================================================================
+// SHARE LINKS
+// =============================================================================
+// A field technician or freelancer checking a camera during an event gets
+// a signed, expiring link to its status instead of an API key.
+// =============================================================================
+
+// ShareLink grants read-only access to one resource's status until it
+// expires or is revoked.
+type ShareLink struct {
+	// ID names the link for revocation (e.g. "shr-42")
+	ID         string `json:"id"`
+	ResourceID string `json:"resource_id"`
+
+	// Label says who or what the link is for ("Dave, SDI truck 2")
+	Label string `json:"label,omitempty"`
+
+	// CreatedBy is the principal that minted the link
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// URL is only returned when the link is minted; it can't be read back
+	URL string `json:"url,omitempty"`
+}
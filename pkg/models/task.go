@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+// =============================================================================
+// TASKS
+// =============================================================================
+// A task tracks a long-running operation the API accepted but didn't
+// finish within the request (202 Accepted), such as deleting a cloud
+// channel whose vendor teardown takes minutes. GET /tasks/{id} reports
+// its progress.
+// =============================================================================
+
+// Task kinds.
+const (
+	TaskDelete = "delete"
+)
+
+// Task states.
+const (
+	TaskRunning   = "running"
+	TaskSucceeded = "succeeded"
+	TaskFailed    = "failed"
+
+	// TaskCancelled is a deletion restored during its grace period
+	TaskCancelled = "cancelled"
+)
+
+// Task is one long-running operation on a resource.
+type Task struct {
+	// ID is "task-..."
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+
+	ResourceID string `json:"resource_id"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+	VendorType string `json:"vendor_type"`
+
+	State string `json:"state"`
+
+	// Progress is 0-100; Message describes the current step
+	Progress int    `json:"progress"`
+	Message  string `json:"message"`
+
+	// Error is set when State is "failed"
+	Error string `json:"error,omitempty"`
+
+	RequestedBy string    `json:"requested_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// CompletedAt is set once the task is done
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// Done reports whether the task has finished, successfully or not (or
+// was cancelled).
+func (t *Task) Done() bool {
+	return t.State == TaskSucceeded || t.State == TaskFailed || t.State == TaskCancelled
+}
+
+// TeardownProgress is a vendor's report on a device being deleted.
+type TeardownProgress struct {
+	// Done is true once the device is gone from the vendor
+	Done bool
+
+	// Percent is 0-100 (an estimate; vendors report it coarsely)
+	Percent int
+
+	// Message is the vendor's description of the current step
+	Message string
+}
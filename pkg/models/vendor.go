@@ -115,6 +115,37 @@ type SonyStreamConfig struct {
 	// SRTLatency is the SRT latency in milliseconds.
 	// Typical values: 120-250ms for low latency, 500-1000ms for reliability.
 	SRTLatency int `json:"srt_latency,omitempty"`
+
+	// Profiles are additional encodings of the same output for adaptive
+	// streaming, highest bitrate first. Only models listed in
+	// SonyStreamProfileLimits encode more than one.
+	Profiles []SonyStreamProfile `json:"profiles,omitempty"`
+}
+
+// SonyStreamProfile is one encoding of a multi-profile stream.
+type SonyStreamProfile struct {
+	// Name labels the profile in Sony's console and the stream key suffix.
+	Name string `json:"name"`
+
+	// Resolution in Sony format ("1280x720").
+	Resolution string `json:"resolution"`
+
+	// Bitrate in kbps.
+	Bitrate int `json:"bitrate"`
+
+	// FrameRate is frames per second.
+	FrameRate float64 `json:"frame_rate,omitempty"`
+
+	// Codec in Sony format ("H.264", "H.265").
+	Codec string `json:"codec"`
+}
+
+// SonyStreamProfileLimits is how many stream profiles each Sony model
+// encodes at once. Models not listed encode a single stream.
+var SonyStreamProfileLimits = map[string]int{
+	"HDC-5500": 4,
+	"HDC-3500": 3,
+	"PXW-Z750": 2,
 }
 
 // SonyRecordingConfig defines recording settings for Sony devices.
@@ -196,7 +227,8 @@ type SonyDeviceResponse struct {
 	DeviceID string `json:"device_id"`
 
 	// Status indicates the device's current state.
-	// Sony values: "active", "inactive", "error", "provisioning", "maintenance"
+	// Sony values: "active", "inactive", "error", "provisioning", "maintenance",
+	// "deleting"
 	Status string `json:"status"`
 
 	// Message provides additional details about the status.
@@ -225,6 +257,13 @@ type SonyDeviceResponse struct {
 	// HealthMetrics contains device health information.
 	HealthMetrics *SonyHealthMetrics `json:"health_metrics,omitempty"`
 
+	// TeardownProgress is 0-100 while Status is "deleting".
+	TeardownProgress int `json:"teardown_progress,omitempty"`
+
+	// SyncStatus reports timecode, NTP and genlock lock state for the
+	// references set in Settings.
+	SyncStatus *SonySyncStatus `json:"sync_status,omitempty"`
+
 	// CreatedAt is when the device was registered in Sony's system.
 	CreatedAt string `json:"created_at,omitempty"`
 
@@ -237,6 +276,172 @@ type SonyDeviceResponse struct {
 
 	// ErrorDetails provides structured error information.
 	ErrorDetails *SonyErrorDetails `json:"error_details,omitempty"`
+
+	// Configuration is the device configuration as currently stored by Sony.
+	// Returned by GET and list calls; used to reverse-map unmanaged devices
+	// into a proposed ForgeResource during discovery.
+	Configuration *SonyDeviceRequest `json:"configuration,omitempty"`
+}
+
+// SonyDeviceList is Sony's response to GET /devices.
+type SonyDeviceList struct {
+	// Devices lists every device visible to the API key (or one page of
+	// them when page_size is set).
+	Devices []SonyDeviceResponse `json:"devices"`
+
+	// NextPageToken fetches the next page; empty on the last page.
+	NextPageToken string `json:"next_page_token,omitempty"`
+
+	// TotalCount is the number of devices across all pages.
+	TotalCount int `json:"total_count,omitempty"`
+}
+
+// SonyRecordingRequest starts a recording session on a Sony device
+// (POST /devices/{id}/recordings).
+type SonyRecordingRequest struct {
+	// Name labels the session (e.g. "evening-news-2026-01-30").
+	Name string `json:"name"`
+
+	// StoragePath, Format and Quality follow SonyRecordingConfig.
+	StoragePath string `json:"storage_path"`
+	Format      string `json:"format"`
+	Quality     string `json:"quality"`
+
+	// RetentionDays is how long the finished file should be kept (0 = forever).
+	RetentionDays int `json:"retention_days,omitempty"`
+}
+
+// SonyRecording describes a recording session as reported by Sony.
+type SonyRecording struct {
+	// RecordingID is Sony's identifier for the session.
+	RecordingID string `json:"recording_id"`
+
+	// DeviceID is the recording device.
+	DeviceID string `json:"device_id"`
+
+	Name string `json:"name"`
+
+	// Status: "recording" while active, "completed" once stopped
+	Status string `json:"status"`
+
+	// StoragePath is the destination directory; FilePath the actual file.
+	StoragePath string `json:"storage_path"`
+	FilePath    string `json:"file_path,omitempty"`
+	Format      string `json:"format"`
+
+	// StartedAt / StoppedAt are RFC 3339 timestamps.
+	StartedAt string `json:"started_at"`
+	StoppedAt string `json:"stopped_at,omitempty"`
+
+	// DurationSeconds is the recorded length so far (or final length).
+	DurationSeconds float64 `json:"duration_seconds"`
+
+	// SizeBytes is the file size so far.
+	SizeBytes int64 `json:"size_bytes"`
+
+	RetentionDays int `json:"retention_days,omitempty"`
+}
+
+// SonyRecordingList is Sony's response to GET /devices/{id}/recordings.
+type SonyRecordingList struct {
+	Recordings []SonyRecording `json:"recordings"`
+}
+
+// SonyPTZRequest moves a Sony camera head (POST /devices/{id}/ptz).
+//
+// Sony uses VISCA-style units: pan/tilt in degrees, zoom as a lens
+// position 0 (wide) .. 16384 (tele), speed 1 .. 24.
+type SonyPTZRequest struct {
+	// Command is "absolute", "relative", "home" or "stop".
+	Command string `json:"command"`
+
+	PanDegrees   *float64 `json:"pan_degrees,omitempty"`
+	TiltDegrees  *float64 `json:"tilt_degrees,omitempty"`
+	ZoomPosition *int     `json:"zoom_position,omitempty"`
+	Speed        int      `json:"speed,omitempty"`
+}
+
+// SonyPTZState is a Sony camera head's position (GET /devices/{id}/ptz).
+type SonyPTZState struct {
+	PanDegrees   float64 `json:"pan_degrees"`
+	TiltDegrees  float64 `json:"tilt_degrees"`
+	ZoomPosition int     `json:"zoom_position"`
+}
+
+// SonyPreset is a saved camera position. Sony numbers preset memories
+// from 0.
+type SonyPreset struct {
+	PresetNumber int          `json:"preset_number"`
+	Name         string       `json:"name,omitempty"`
+	Position     SonyPTZState `json:"position"`
+	SavedAt      string       `json:"saved_at,omitempty"` // RFC3339
+}
+
+// SonyPresetList is Sony's response to GET /devices/{id}/presets.
+type SonyPresetList struct {
+	Presets []SonyPreset `json:"presets"`
+}
+
+// SonySettingsFormat is the DeviceConfig format of Sony settings dumps.
+const SonySettingsFormat = "sony-settings/v1"
+
+// SonySettingsDump is a Sony device's full settings
+// (GET/PUT /devices/{id}/settings): its configuration and the preset
+// memories stored in the camera head.
+type SonySettingsDump struct {
+	Model           string             `json:"model,omitempty"`
+	FirmwareVersion string             `json:"firmware_version,omitempty"`
+	Configuration   *SonyDeviceRequest `json:"configuration,omitempty"`
+	Presets         []SonyPreset       `json:"presets"`
+	ExportedAt      string             `json:"exported_at,omitempty"` // RFC3339
+}
+
+// SonyPreflightCheck is one check in Sony's preflight response.
+type SonyPreflightCheck struct {
+	// Check is "quota", "network" or "model".
+	Check string `json:"check"`
+
+	// Status is "pass" or "fail".
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// SonyPreflightResponse is Sony's response to POST /devices/preflight,
+// which validates a SonyDeviceRequest without provisioning it.
+type SonyPreflightResponse struct {
+	Ready  bool                 `json:"ready"`
+	Checks []SonyPreflightCheck `json:"checks"`
+}
+
+// SonyProbeRequest asks Sony to probe a stream destination from the site
+// network (POST /network/probe).
+type SonyProbeRequest struct {
+	URL string `json:"url"`
+}
+
+// SonyProbeResponse is the outcome of POST /network/probe.
+type SonyProbeResponse struct {
+	Reachable bool    `json:"reachable"`
+	Method    string  `json:"method"`
+	Address   string  `json:"address,omitempty"`
+	RTTMs     float64 `json:"rtt_ms,omitempty"`
+	Detail    string  `json:"detail,omitempty"`
+}
+
+// SonyCapacityResponse is the account's usage (GET /account/capacity).
+type SonyCapacityResponse struct {
+	Devices     int          `json:"devices"`
+	DeviceQuota int          `json:"device_quota"`
+	DHCPPool    SonyDHCPPool `json:"dhcp_pool"`
+	Addresses   []string     `json:"addresses_in_use"`
+}
+
+// SonyDHCPPool is the site's DHCP pool.
+type SonyDHCPPool struct {
+	Start  string `json:"start"`
+	End    string `json:"end"`
+	Size   int    `json:"size"`
+	Leased int    `json:"leased"`
 }
 
 // SonyStreamStatus provides information about active streaming.
@@ -275,6 +480,27 @@ type SonyDestinationStatus struct {
 	BytesSent int64 `json:"bytes_sent,omitempty"`
 }
 
+// SonySyncStatus reports a Sony device's synchronization state. Each
+// field pair is only meaningful when the matching setting
+// ("timecode_source", "ntp_server", "genlock_reference") is configured.
+type SonySyncStatus struct {
+	// TimecodeSource is the Sony source code ("INT", "EXT-LTC",
+	// "EXT-VITC", "NTP", "PTP").
+	TimecodeSource string `json:"timecode_source,omitempty"`
+	TimecodeLocked bool   `json:"timecode_locked"`
+
+	NTPServer string `json:"ntp_server,omitempty"`
+	NTPSynced bool   `json:"ntp_synced"`
+
+	// NTPOffsetMs is the measured clock offset from the NTP server.
+	NTPOffsetMs float64 `json:"ntp_offset_ms,omitempty"`
+
+	// GenlockReference is the Sony reference code ("INT", "BB", "TRI",
+	// "PTP").
+	GenlockReference string `json:"genlock_reference,omitempty"`
+	GenlockLocked    bool   `json:"genlock_locked"`
+}
+
 // SonyHealthMetrics contains device health information.
 type SonyHealthMetrics struct {
 	// CPUUsagePercent is current CPU utilization.
@@ -447,6 +673,9 @@ type AWSVideoCodecSettings struct {
 
 	// H265Settings for H.265/HEVC encoding.
 	H265Settings *AWSH265Settings `json:"h265_settings,omitempty"`
+
+	// Av1Settings for AV1 encoding.
+	Av1Settings *AWSAv1Settings `json:"av1_settings,omitempty"`
 }
 
 // AWSH264Settings contains H.264 encoding parameters.
@@ -497,6 +726,22 @@ type AWSH265Settings struct {
 	Level string `json:"level"`
 }
 
+// AWSAv1Settings contains AV1 encoding parameters.
+type AWSAv1Settings struct {
+	// Bitrate is the output bitrate in bps.
+	Bitrate int `json:"bitrate"`
+
+	// FramerateDenominator for frame rate.
+	FramerateDenominator int `json:"framerate_denominator"`
+
+	// FramerateNumerator for frame rate.
+	FramerateNumerator int `json:"framerate_numerator"`
+
+	// Level is the AV1 level.
+	// Values: "AV1_LEVEL_AUTO", "AV1_LEVEL_5_1"
+	Level string `json:"level"`
+}
+
 // AWSAudioDescription defines audio encoding configuration.
 type AWSAudioDescription struct {
 	// Name is the identifier for this audio description.
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// =============================================================================
+// WATCH EVENTS
+// =============================================================================
+// GET /resources/watch streams changes to resources as they happen. Every
+// change the controller records (see ResourceRevision) is also published as
+// a WatchEvent with a controller-wide sequence number, so a client that
+// disconnects can resume exactly where it left off.
+// =============================================================================
+
+// Watch event types.
+const (
+	WatchAdded    = "ADDED"
+	WatchModified = "MODIFIED"
+	WatchDeleted  = "DELETED"
+
+	// WatchBookmark carries no resource; it tells the client the latest
+	// sequence number so it can resume from there even if every event in
+	// between was filtered out.
+	WatchBookmark = "BOOKMARK"
+)
+
+// WatchEvent is one change to a resource.
+type WatchEvent struct {
+	// Seq increases by one for every change to any resource. It is the
+	// SSE event ID: resume with Last-Event-ID or ?since=.
+	Seq int64 `json:"seq"`
+
+	Type string `json:"type"`
+
+	// Reason and Revision match the ResourceRevision recorded for the
+	// change ("created", "status-refresh", ...).
+	Reason   string `json:"reason,omitempty"`
+	Revision int64  `json:"revision,omitempty"`
+
+	Timestamp time.Time `json:"timestamp"`
+
+	// Resource is the full snapshot after the change (the last known state
+	// for DELETED). Nil for BOOKMARK.
+	Resource *ForgeResource `json:"resource,omitempty"`
+}
@@ -0,0 +1,206 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// =============================================================================
+// BUILT-IN CHANNELS
+// =============================================================================
+
+// ChannelConfig configures one channel. Which fields apply depends on Type.
+type ChannelConfig struct {
+	// Type: "webhook", "slack", "pagerduty" or "email"
+	Type string `json:"type"`
+
+	// webhook: URL receives the Notification as JSON
+	// slack:   WebhookURL is a Slack incoming webhook; Channel overrides its default
+	URL        string `json:"url,omitempty"`
+	WebhookURL string `json:"webhook_url,omitempty"`
+	Channel    string `json:"channel,omitempty"`
+
+	// pagerduty: Events API v2 integration key (URL overrides the endpoint)
+	RoutingKey string `json:"routing_key,omitempty"`
+
+	// email: SMTP relay "host:port", sender and recipients
+	SMTPAddr string   `json:"smtp_addr,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from,omitempty"`
+	To       []string `json:"to,omitempty"`
+}
+
+// Build creates the Channel described by the config.
+func (cc ChannelConfig) Build() (Channel, error) {
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	switch cc.Type {
+	case "webhook":
+		if cc.URL == "" {
+			return nil, fmt.Errorf("webhook channel requires url")
+		}
+		return &WebhookChannel{URL: cc.URL, HTTPClient: httpClient}, nil
+	case "slack":
+		if cc.WebhookURL == "" {
+			return nil, fmt.Errorf("slack channel requires webhook_url")
+		}
+		return &SlackChannel{WebhookURL: cc.WebhookURL, Channel: cc.Channel, HTTPClient: httpClient}, nil
+	case "pagerduty":
+		if cc.RoutingKey == "" {
+			return nil, fmt.Errorf("pagerduty channel requires routing_key")
+		}
+		url := cc.URL
+		if url == "" {
+			url = PagerDutyEventsURL
+		}
+		return &PagerDutyChannel{RoutingKey: cc.RoutingKey, URL: url, HTTPClient: httpClient}, nil
+	case "email":
+		if cc.SMTPAddr == "" || cc.From == "" || len(cc.To) == 0 {
+			return nil, fmt.Errorf("email channel requires smtp_addr, from and to")
+		}
+		return &EmailChannel{Addr: cc.SMTPAddr, Username: cc.Username, Password: cc.Password, From: cc.From, To: cc.To}, nil
+	default:
+		return nil, fmt.Errorf("unknown channel type %q", cc.Type)
+	}
+}
+
+// postJSON POSTs body as JSON and treats any non-2xx status as an error.
+func postJSON(ctx context.Context, httpClient *http.Client, url string, body interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
+
+// WebhookChannel POSTs the whole Notification as JSON.
+type WebhookChannel struct {
+	URL        string
+	HTTPClient *http.Client
+}
+
+func (c *WebhookChannel) Type() string { return "webhook" }
+
+func (c *WebhookChannel) Send(ctx context.Context, n Notification) error {
+	return postJSON(ctx, c.HTTPClient, c.URL, n)
+}
+
+// SlackChannel posts to a Slack incoming webhook.
+type SlackChannel struct {
+	WebhookURL string
+	Channel    string
+	HTTPClient *http.Client
+}
+
+func (c *SlackChannel) Type() string { return "slack" }
+
+func (c *SlackChannel) Send(ctx context.Context, n Notification) error {
+	msg := map[string]string{"text": "*" + n.Title + "*\n" + n.Text}
+	if c.Channel != "" {
+		msg["channel"] = c.Channel
+	}
+	return postJSON(ctx, c.HTTPClient, c.WebhookURL, msg)
+}
+
+// PagerDutyEventsURL is the PagerDuty Events API v2 endpoint.
+const PagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDutyChannel triggers PagerDuty incidents via the Events API v2.
+type PagerDutyChannel struct {
+	RoutingKey string
+	URL        string
+	HTTPClient *http.Client
+}
+
+func (c *PagerDutyChannel) Type() string { return "pagerduty" }
+
+func (c *PagerDutyChannel) Send(ctx context.Context, n Notification) error {
+	event := map[string]interface{}{
+		"routing_key":  c.RoutingKey,
+		"event_action": "trigger",
+		// WHY DEDUP PER RESOURCE: Repeated failures of the same resource
+		// update one incident instead of paging again and again
+		"dedup_key": "forge/" + n.Resource.ID,
+		"payload": map[string]interface{}{
+			"summary":   n.Title,
+			"source":    "forge-controller",
+			"severity":  n.Severity(), // critical/warning/info are valid PagerDuty severities
+			"component": n.Resource.Name,
+			"group":     n.Resource.Namespace,
+			"class":     n.Event.Reason,
+			"custom_details": map[string]interface{}{
+				"text":          n.Text,
+				"resource_id":   n.Resource.ID,
+				"vendor":        n.Resource.Spec.VendorType,
+				"phase":         n.Resource.Status.Phase,
+				"recent_events": n.RecentEvents,
+			},
+		},
+	}
+	if n.ResourceURL != "" {
+		event["links"] = []map[string]string{{"href": n.ResourceURL, "text": "Forge resource"}}
+	}
+	return postJSON(ctx, c.HTTPClient, c.URL, event)
+}
+
+// EmailChannel sends plain-text email through an SMTP relay.
+type EmailChannel struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+func (c *EmailChannel) Type() string { return "email" }
+
+func (c *EmailChannel) Send(ctx context.Context, n Notification) error {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", c.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(c.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", strings.ReplaceAll(n.Title, "\n", " "))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(n.Text, "\n", "\r\n"))
+	msg.WriteString("\r\n")
+
+	var auth smtp.Auth
+	if c.Username != "" {
+		host, _, _ := net.SplitHostPort(c.Addr)
+		auth = smtp.PlainAuth("", c.Username, c.Password, host)
+	}
+
+	// WHY A GOROUTINE: smtp.SendMail has no context support; don't let a
+	// hung relay outlive the delivery timeout
+	done := make(chan error, 1)
+	go func() { done <- smtp.SendMail(c.Addr, auth, c.From, c.To, msg.Bytes()) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
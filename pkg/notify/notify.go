@@ -0,0 +1,405 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/logging"
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// NOTIFICATIONS
+// =============================================================================
+// Resource events are routed to notification channels by declarative rules:
+//
+//   routes:
+//     {"match": {"phase": "Failed"},    "channels": ["oncall"]}     → PagerDuty
+//     {"match": {"health": "degraded"}, "channels": ["ops-slack"]}  → Slack
+//
+// Built-in channel types: webhook (generic JSON POST), slack, pagerduty,
+// email. Each notification is rendered from a text/template with a link
+// to the resource and its most recent events.
+//
+// MATCH KEYS (all listed keys must match, case-insensitive; "a|b" = either):
+//   phase, health   → the phase/health the event moved the resource INTO
+//                     (so "phase=Failed" fires once per failure, not on
+//                     every later event of a failed resource)
+//   reason, type    → event reason ("Deleted") / type ("Warning")
+//   namespace, vendor, resource_type → resource attributes
+//
+// Delivery is asynchronous: notifications go through an outbox (see
+// outbox.go) and are retried until the channel accepts them. Failures are
+// logged and counted, never surfaced to the API request that caused the
+// event.
+// =============================================================================
+
+var logger = logging.For(logging.ComponentController)
+
+// Notification is what channels receive.
+type Notification struct {
+	Event        models.Event         `json:"event"`
+	Resource     models.ForgeResource `json:"resource"`
+	ResourceURL  string               `json:"resource_url"`
+	RecentEvents []models.Event       `json:"recent_events"`
+
+	// Title and Text are rendered from the route's templates.
+	Title string `json:"title"`
+	Text  string `json:"text"`
+
+	// DeliveryID is the same on every attempt, so receivers can drop
+	// repeats (delivery is at least once)
+	DeliveryID string `json:"delivery_id"`
+}
+
+// Severity maps the event to a coarse severity ("critical", "warning", "info").
+func (n *Notification) Severity() string {
+	switch {
+	case n.Event.Phase == "Failed" || n.Event.Reason == models.ReasonCreateFailed:
+		return "critical"
+	case n.Event.Type == models.EventWarning:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+// Channel delivers notifications to one destination.
+type Channel interface {
+	// Type returns the channel type ("slack", "pagerduty", ...).
+	Type() string
+
+	// Send delivers n. It must respect ctx cancellation.
+	Send(ctx context.Context, n Notification) error
+}
+
+// Route sends matching events to channels.
+type Route struct {
+	Name     string            `json:"name,omitempty"`
+	Match    map[string]string `json:"match"`
+	Channels []string          `json:"channels"`
+
+	// Optional per-route templates (default: Config.Templates)
+	TitleTemplate string `json:"title_template,omitempty"`
+	TextTemplate  string `json:"text_template,omitempty"`
+
+	title *template.Template
+	text  *template.Template
+}
+
+// Matches reports whether the event/resource pair satisfies every match key.
+func (r *Route) Matches(event models.Event, res *models.ForgeResource) bool {
+	for key, want := range r.Match {
+		var got string
+		switch key {
+		case "phase":
+			got = event.Phase
+		case "health":
+			got = event.Health
+		case "reason":
+			got = event.Reason
+		case "type":
+			got = event.Type
+		case "namespace":
+			got = res.Namespace
+		case "vendor":
+			got = res.Spec.VendorType
+		case "resource_type":
+			got = res.Type
+		default:
+			return false // Unknown keys never match (validated at load)
+		}
+		if !matchesAny(got, want) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesAny(got, want string) bool {
+	if got == "" {
+		return false
+	}
+	for _, alt := range strings.Split(want, "|") {
+		if strings.EqualFold(strings.TrimSpace(alt), got) {
+			return true
+		}
+	}
+	return false
+}
+
+var matchKeys = map[string]bool{
+	"phase": true, "health": true, "reason": true, "type": true,
+	"namespace": true, "vendor": true, "resource_type": true,
+}
+
+// Default templates. Fields available: .Event, .Resource, .ResourceURL,
+// .RecentEvents.
+const (
+	DefaultTitleTemplate = `[{{.Event.Type}}] {{.Resource.Name}} ({{.Resource.ID}}): {{.Event.Reason}}`
+	DefaultTextTemplate  = `{{.Event.Message}}
+Resource: {{.Resource.Name}} ({{.Resource.Spec.VendorType}} {{.Resource.Type}}{{if .Resource.Namespace}}, namespace {{.Resource.Namespace}}{{end}})
+Phase: {{.Resource.Status.Phase}}{{if .Resource.Status.HealthStatus}}, health: {{.Resource.Status.HealthStatus}}{{end}}
+{{if .ResourceURL}}Link: {{.ResourceURL}}
+{{end}}{{if .RecentEvents}}Recent events:
+{{range .RecentEvents}}- {{.Time.Format "2006-01-02T15:04:05Z07:00"}} {{.Reason}}: {{.Message}}
+{{end}}{{end}}`
+)
+
+// Stats counts deliveries per channel.
+type Stats struct {
+	Sent      int       `json:"sent"`
+	Failed    int       `json:"failed"`
+	LastError string    `json:"last_error,omitempty"`
+	LastSent  time.Time `json:"last_sent,omitempty"`
+}
+
+// Router matches events against routes and delivers to channels.
+type Router struct {
+	baseURL  string
+	timeout  time.Duration
+	channels map[string]Channel
+	routes   []*Route
+
+	mu    sync.Mutex
+	stats map[string]*Stats
+
+	outbox   *Outbox
+	inflight sync.WaitGroup
+}
+
+// NewRouter creates a router. baseURL is the controller's public URL used
+// for resource links ("" = no links).
+func NewRouter(baseURL string, channels map[string]Channel, routes []Route) (*Router, error) {
+	r := &Router{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		timeout:  10 * time.Second,
+		channels: channels,
+		stats:    make(map[string]*Stats),
+	}
+	r.outbox, _ = OpenOutbox("", DefaultOutboxConfig)
+	for i := range routes {
+		route := routes[i]
+		if len(route.Channels) == 0 {
+			return nil, fmt.Errorf("route %d: at least one channel is required", i)
+		}
+		for key := range route.Match {
+			if !matchKeys[key] {
+				return nil, fmt.Errorf("route %d: unknown match key %q", i, key)
+			}
+		}
+		for _, name := range route.Channels {
+			if _, ok := channels[name]; !ok {
+				return nil, fmt.Errorf("route %d: unknown channel %q", i, name)
+			}
+		}
+		titleSrc, textSrc := route.TitleTemplate, route.TextTemplate
+		if titleSrc == "" {
+			titleSrc = DefaultTitleTemplate
+		}
+		if textSrc == "" {
+			textSrc = DefaultTextTemplate
+		}
+		var err error
+		if route.title, err = template.New("title").Parse(titleSrc); err != nil {
+			return nil, fmt.Errorf("route %d: invalid title template: %w", i, err)
+		}
+		if route.text, err = template.New("text").Parse(textSrc); err != nil {
+			return nil, fmt.Errorf("route %d: invalid text template: %w", i, err)
+		}
+		r.routes = append(r.routes, &route)
+	}
+	return r, nil
+}
+
+// ResourceURL returns the link for a resource, or "" without a base URL.
+func (r *Router) ResourceURL(id string) string {
+	if r.baseURL == "" {
+		return ""
+	}
+	return r.baseURL + "/resources/" + id
+}
+
+// SetOutbox replaces the router's in-memory outbox. Call it before Run.
+func (r *Router) SetOutbox(o *Outbox) {
+	r.outbox = o
+}
+
+// Outbox returns the router's outbox.
+func (r *Router) Outbox() *Outbox {
+	return r.outbox
+}
+
+// Dispatch adds a delivery to the outbox for every channel of every
+// matching route. A channel matched by several routes receives the
+// notification once (rendered with the first matching route's templates).
+// Run delivers them in the background; Dispatch only waits for the outbox
+// write.
+func (r *Router) Dispatch(event models.Event, res models.ForgeResource, recent []models.Event) error {
+	base := Notification{
+		Event:        event,
+		Resource:     res,
+		ResourceURL:  r.ResourceURL(res.ID),
+		RecentEvents: recent,
+	}
+
+	sent := make(map[string]bool)
+	var deliveries []*Delivery
+	for _, route := range r.routes {
+		if !route.Matches(event, &res) {
+			continue
+		}
+		n := base
+		n.Title = render(route.title, &base)
+		n.Text = render(route.text, &base)
+		for _, name := range route.Channels {
+			if sent[name] {
+				continue
+			}
+			sent[name] = true
+			d := &Delivery{ID: event.ID + "." + name, Channel: name, Notification: n, CreatedAt: time.Now()}
+			d.Notification.DeliveryID = d.ID
+			deliveries = append(deliveries, d)
+		}
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.outbox.Add(deliveries...)
+}
+
+// deliver makes one attempt at d and records the outcome.
+func (r *Router) deliver(d *Delivery) {
+	ch, ok := r.channels[d.Channel]
+	var err error
+	if !ok {
+		// WHY: The outbox outlives the config; a channel removed since
+		// can never accept the delivery
+		err = fmt.Errorf("channel %q is no longer configured", d.Channel)
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+		err = ch.Send(ctx, d.Notification)
+		cancel()
+	}
+	state := r.outbox.finish(d.ID, err, time.Now())
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats, ok := r.stats[d.Channel]
+	if !ok {
+		stats = &Stats{}
+		r.stats[d.Channel] = stats
+	}
+	if err != nil {
+		stats.Failed++
+		stats.LastError = err.Error()
+		if state == DeliveryDead {
+			logger.Errorf("Giving up on notification %s to %s for %s after %d attempts: %v", d.ID, d.Channel, d.Notification.Event.ResourceID, d.Attempts+1, err)
+		} else {
+			logger.Warnf("Notification %s to %s for %s failed (attempt %d, will retry): %v", d.ID, d.Channel, d.Notification.Event.ResourceID, d.Attempts+1, err)
+		}
+		return
+	}
+	stats.Sent++
+	stats.LastSent = time.Now()
+	logger.Debugf("Notified %s: %s", d.Channel, d.Notification.Title)
+}
+
+func render(t *template.Template, n *Notification) string {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, n); err != nil {
+		return fmt.Sprintf("(template error: %v)", err)
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// ChannelInfo describes a configured channel without its secrets.
+type ChannelInfo struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Stats Stats  `json:"stats"`
+}
+
+// Channels returns the configured channels with delivery stats, sorted by name.
+func (r *Router) Channels() []ChannelInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	infos := make([]ChannelInfo, 0, len(r.channels))
+	for name, ch := range r.channels {
+		info := ChannelInfo{Name: name, Type: ch.Type()}
+		if stats, ok := r.stats[name]; ok {
+			info.Stats = *stats
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// Routes returns the configured routes.
+func (r *Router) Routes() []Route {
+	routes := make([]Route, len(r.routes))
+	for i, route := range r.routes {
+		routes[i] = *route
+	}
+	return routes
+}
+
+// =============================================================================
+// CONFIGURATION
+// =============================================================================
+
+// Config is the JSON notification configuration (NOTIFY_CONFIG file).
+//
+// Example:
+//
+//	{
+//	  "base_url": "https://forge.example.com",
+//	  "channels": {
+//	    "oncall":    {"type": "pagerduty", "routing_key": "R0UT1NGKEY"},
+//	    "ops-slack": {"type": "slack", "webhook_url": "https://hooks.slack.com/services/...", "channel": "#ops"}
+//	  },
+//	  "routes": [
+//	    {"match": {"phase": "Failed"}, "channels": ["oncall"]},
+//	    {"match": {"health": "degraded"}, "channels": ["ops-slack"]}
+//	  ]
+//	}
+type Config struct {
+	BaseURL  string                   `json:"base_url"`
+	Channels map[string]ChannelConfig `json:"channels"`
+	Routes   []Route                  `json:"routes"`
+}
+
+// LoadConfig reads a Config from a JSON file and builds a Router.
+func LoadConfig(path string) (*Router, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read notification config: %w", err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid notification config: %w", err)
+	}
+	return cfg.Build()
+}
+
+// Build constructs the channels and the Router.
+func (cfg *Config) Build() (*Router, error) {
+	channels := make(map[string]Channel, len(cfg.Channels))
+	for name, cc := range cfg.Channels {
+		ch, err := cc.Build()
+		if err != nil {
+			return nil, fmt.Errorf("channel %q: %w", name, err)
+		}
+		channels[name] = ch
+	}
+	return NewRouter(cfg.BaseURL, channels, cfg.Routes)
+}
@@ -0,0 +1,519 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// OUTBOX
+// =============================================================================
+// Every notification is written to the outbox before the event that caused
+// it is visible (the controller records events under the same lock as the
+// mutation), and a background dispatcher delivers it from there:
+//
+//   recordEvent → Router.Dispatch → Outbox.Add (appended, fsynced)
+//                                        ↓
+//                 dispatcher: Send → done | retry with backoff | dead
+//
+// With NOTIFY_OUTBOX_FILE set, the outbox is an append-only JSON-lines log:
+// a controller that crashes right after a create delivers the pending
+// notifications when it starts again. Without it, deliveries still retry
+// but only live in memory.
+//
+// Delivery is at least once: a crash between Send and the "done" record
+// sends again. Webhook receivers can dedupe on delivery_id.
+//
+// RETRIES: Attempt n waits RetryBase·2^(n-1) (capped at RetryMax); after
+// MaxAttempts failures the delivery is dead: kept (and listed) but no
+// longer sent.
+// =============================================================================
+
+// Delivery states.
+const (
+	DeliveryPending  = "pending"
+	DeliveryInFlight = "in_flight"
+	DeliveryDead     = "dead"
+)
+
+// Delivery is one notification on its way to one channel.
+type Delivery struct {
+	ID           string       `json:"id"`
+	Channel      string       `json:"channel"`
+	Notification Notification `json:"notification"`
+	State        string       `json:"state"`
+	Attempts     int          `json:"attempts"`
+	CreatedAt    time.Time    `json:"created_at"`
+	NextAttempt  time.Time    `json:"next_attempt,omitempty"`
+	LastError    string       `json:"last_error,omitempty"`
+}
+
+// OutboxConfig tunes retries and the dispatcher.
+type OutboxConfig struct {
+	MaxAttempts int
+	RetryBase   time.Duration
+	RetryMax    time.Duration
+
+	// Concurrency bounds the deliveries in flight at once
+	Concurrency int
+}
+
+// DefaultOutboxConfig retries for about 40 minutes before giving up.
+var DefaultOutboxConfig = OutboxConfig{
+	MaxAttempts: 10,
+	RetryBase:   2 * time.Second,
+	RetryMax:    10 * time.Minute,
+	Concurrency: 8,
+}
+
+// OutboxStats summarises the outbox.
+type OutboxStats struct {
+	Path      string `json:"path,omitempty"`
+	Pending   int    `json:"pending"`
+	InFlight  int    `json:"in_flight"`
+	Dead      int    `json:"dead"`
+	Delivered int    `json:"delivered"`
+	Retried   int    `json:"retried"`
+	Replayed  int    `json:"replayed"`
+}
+
+// outboxRecord is one line of the outbox log.
+type outboxRecord struct {
+	Op       string    `json:"op"` // "add", "retry", "dead", "done"
+	Delivery *Delivery `json:"delivery,omitempty"`
+
+	// retry/dead/done name the delivery by ID
+	ID          string    `json:"id,omitempty"`
+	Attempts    int       `json:"attempts,omitempty"`
+	NextAttempt time.Time `json:"next_attempt,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// compactAfter is how many records the log may grow by before it is
+// rewritten with only the live deliveries.
+const compactAfter = 1000
+
+// Outbox holds deliveries until their channel accepts them.
+type Outbox struct {
+	cfg  OutboxConfig
+	path string // "" = memory only
+
+	mu         sync.Mutex
+	file       *os.File
+	appended   int // records since the last compaction
+	deliveries map[string]*Delivery
+	stats      OutboxStats
+	wake       chan struct{}
+}
+
+// OpenOutbox opens the outbox log at path ("" = memory only) and loads the
+// deliveries that weren't finished when the last process stopped.
+func OpenOutbox(path string, cfg OutboxConfig) (*Outbox, error) {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultOutboxConfig.MaxAttempts
+	}
+	if cfg.RetryBase <= 0 {
+		cfg.RetryBase = DefaultOutboxConfig.RetryBase
+	}
+	if cfg.RetryMax < cfg.RetryBase {
+		cfg.RetryMax = cfg.RetryBase
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = DefaultOutboxConfig.Concurrency
+	}
+	o := &Outbox{
+		cfg:        cfg,
+		path:       path,
+		deliveries: make(map[string]*Delivery),
+		stats:      OutboxStats{Path: path},
+		wake:       make(chan struct{}, 1),
+	}
+	if path == "" {
+		return o, nil
+	}
+	if err := o.load(); err != nil {
+		return nil, err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err := o.compactLocked(); err != nil {
+		return nil, err
+	}
+	o.stats.Replayed = len(o.deliveries)
+	return o, nil
+}
+
+// load replays the log into o.deliveries.
+func (o *Outbox) load() error {
+	f, err := os.Open(o.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open notification outbox: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		var rec outboxRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			// WHY TOLERATE: A crash mid-append leaves a torn last line;
+			// everything before it is intact
+			logger.Warnf("Ignoring outbox %s line %d: %v", o.path, line, err)
+			continue
+		}
+		o.apply(&rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read notification outbox: %w", err)
+	}
+	for _, d := range o.deliveries {
+		// WHY: A delivery in flight when the process stopped may or may
+		// not have arrived; at least once means sending it again
+		if d.State == DeliveryInFlight {
+			d.State = DeliveryPending
+		}
+	}
+	return nil
+}
+
+// apply replays one record.
+func (o *Outbox) apply(rec *outboxRecord) {
+	switch rec.Op {
+	case "add":
+		if rec.Delivery != nil {
+			o.deliveries[rec.Delivery.ID] = rec.Delivery
+		}
+	case "retry", "dead":
+		if d, ok := o.deliveries[rec.ID]; ok {
+			d.Attempts = rec.Attempts
+			d.NextAttempt = rec.NextAttempt
+			d.LastError = rec.LastError
+			d.State = DeliveryPending
+			if rec.Op == "dead" {
+				d.State = DeliveryDead
+			}
+		}
+	case "done":
+		delete(o.deliveries, rec.ID)
+	}
+}
+
+// appendLocked writes rec to the log and syncs it. Must be called with mu held.
+func (o *Outbox) appendLocked(rec *outboxRecord) error {
+	if o.file == nil {
+		return nil
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := o.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	// WHY SYNC: The point of the outbox is that a notification survives a
+	// crash right after the API answered
+	if err := o.file.Sync(); err != nil {
+		return err
+	}
+	o.appended++
+	if o.appended > compactAfter && o.appended > 2*len(o.deliveries) {
+		return o.compactLocked()
+	}
+	return nil
+}
+
+// compactLocked rewrites the log with only the live deliveries and reopens
+// it for appending. Must be called with mu held.
+// WHY RENAME: A crash mid-write must not lose the old log
+func (o *Outbox) compactLocked() error {
+	tmp := o.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to compact notification outbox: %w", err)
+	}
+	writer := bufio.NewWriter(f)
+	encoder := json.NewEncoder(writer)
+	for _, d := range o.sortedLocked() {
+		if err = encoder.Encode(&outboxRecord{Op: "add", Delivery: d}); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	f.Close()
+	if err == nil {
+		err = os.Rename(tmp, o.path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to compact notification outbox: %w", err)
+	}
+
+	if o.file != nil {
+		o.file.Close()
+	}
+	o.file, err = os.OpenFile(o.path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open notification outbox: %w", err)
+	}
+	o.appended = 0
+	return nil
+}
+
+// Add stores new deliveries and wakes the dispatcher. Once Add returns,
+// the deliveries survive a crash (with a log file).
+func (o *Outbox) Add(deliveries ...*Delivery) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var firstErr error
+	for _, d := range deliveries {
+		d.State = DeliveryPending
+		o.deliveries[d.ID] = d
+		if err := o.appendLocked(&outboxRecord{Op: "add", Delivery: d}); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	o.signal()
+	return firstErr
+}
+
+// signal wakes the dispatcher without blocking.
+func (o *Outbox) signal() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// due claims up to limit pending deliveries whose next attempt has come,
+// and returns when the next one after them is due (zero if none).
+func (o *Outbox) due(now time.Time, limit int) ([]*Delivery, time.Time) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var claimed []*Delivery
+	var next time.Time
+	for _, d := range o.sortedLocked() {
+		if d.State != DeliveryPending {
+			continue
+		}
+		if d.NextAttempt.After(now) || len(claimed) >= limit {
+			at := d.NextAttempt
+			if at.Before(now) {
+				at = now
+			}
+			if next.IsZero() || at.Before(next) {
+				next = at
+			}
+			continue
+		}
+		d.State = DeliveryInFlight
+		copied := *d
+		claimed = append(claimed, &copied)
+	}
+	return claimed, next
+}
+
+// finish records the outcome of an attempt and returns the delivery's new
+// state ("" once delivered).
+func (o *Outbox) finish(id string, sendErr error, now time.Time) string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	d, ok := o.deliveries[id]
+	if !ok {
+		return ""
+	}
+	var rec outboxRecord
+	if sendErr == nil {
+		delete(o.deliveries, id)
+		o.stats.Delivered++
+		rec = outboxRecord{Op: "done", ID: id}
+	} else {
+		d.Attempts++
+		d.LastError = sendErr.Error()
+		if d.Attempts >= o.cfg.MaxAttempts {
+			d.State = DeliveryDead
+			d.NextAttempt = time.Time{}
+			rec = outboxRecord{Op: "dead", ID: id, Attempts: d.Attempts, LastError: d.LastError}
+		} else {
+			d.State = DeliveryPending
+			d.NextAttempt = now.Add(o.backoff(d.Attempts))
+			o.stats.Retried++
+			rec = outboxRecord{Op: "retry", ID: id, Attempts: d.Attempts, NextAttempt: d.NextAttempt, LastError: d.LastError}
+		}
+	}
+	if err := o.appendLocked(&rec); err != nil {
+		logger.Warnf("Failed to write notification outbox: %v", err)
+	}
+	if d.State == DeliveryDead {
+		return DeliveryDead
+	}
+	if sendErr == nil {
+		return ""
+	}
+	return DeliveryPending
+}
+
+// backoff returns the wait after the given number of failed attempts.
+func (o *Outbox) backoff(attempts int) time.Duration {
+	wait := o.cfg.RetryBase
+	for i := 1; i < attempts && wait < o.cfg.RetryMax; i++ {
+		wait *= 2
+	}
+	if wait > o.cfg.RetryMax {
+		wait = o.cfg.RetryMax
+	}
+	return wait
+}
+
+// Deliveries returns the unfinished deliveries, oldest first.
+func (o *Outbox) Deliveries() []Delivery {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	sorted := o.sortedLocked()
+	items := make([]Delivery, len(sorted))
+	for i, d := range sorted {
+		items[i] = *d
+	}
+	return items
+}
+
+// Stats returns the outbox counters.
+func (o *Outbox) Stats() OutboxStats {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	stats := o.stats
+	stats.Pending, stats.InFlight, stats.Dead = 0, 0, 0
+	for _, d := range o.deliveries {
+		switch d.State {
+		case DeliveryPending:
+			stats.Pending++
+		case DeliveryInFlight:
+			stats.InFlight++
+		case DeliveryDead:
+			stats.Dead++
+		}
+	}
+	return stats
+}
+
+// Close closes the log. Unfinished deliveries stay in it for the next start.
+func (o *Outbox) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.file == nil {
+		return nil
+	}
+	err := o.file.Close()
+	o.file = nil
+	return err
+}
+
+func (o *Outbox) sortedLocked() []*Delivery {
+	sorted := make([]*Delivery, 0, len(o.deliveries))
+	for _, d := range o.deliveries {
+		sorted = append(sorted, d)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if !sorted[i].CreatedAt.Equal(sorted[j].CreatedAt) {
+			return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+	return sorted
+}
+
+// =============================================================================
+// DISPATCHER
+// =============================================================================
+
+// Run delivers from the outbox until ctx ends. Deliveries already in
+// flight finish (bounded by the router's timeout); see Drain.
+func (r *Router) Run(ctx context.Context) {
+	o := r.outbox
+	slots := make(chan struct{}, o.cfg.Concurrency)
+	for {
+		claimed, next := o.due(time.Now(), o.cfg.Concurrency-len(slots))
+		for _, d := range claimed {
+			slots <- struct{}{}
+			r.inflight.Add(1)
+			go func(d *Delivery) {
+				defer func() { <-slots; r.inflight.Done(); o.signal() }()
+				r.deliver(d)
+			}(d)
+		}
+
+		var timer *time.Timer
+		var fire <-chan time.Time
+		if !next.IsZero() {
+			timer = time.NewTimer(time.Until(next))
+			fire = timer.C
+		}
+		select {
+		case <-ctx.Done():
+		case <-o.wake:
+		case <-fire:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// Drain waits for deliveries in flight, then makes one last attempt at
+// pending deliveries that are due, until ctx ends. Whatever is left stays
+// in the outbox log for the next start.
+func (r *Router) Drain(ctx context.Context) {
+	o := r.outbox
+	done := make(chan struct{})
+	go func() {
+		r.inflight.Wait()
+		claimed, _ := o.due(time.Now(), math.MaxInt)
+		var wg sync.WaitGroup
+		for _, d := range claimed {
+			wg.Add(1)
+			go func(d *Delivery) {
+				defer wg.Done()
+				r.deliver(d)
+			}(d)
+		}
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		logger.Warnf("Notification drain cut short: %v", ctx.Err())
+	}
+	stats := o.Stats()
+	if left := stats.Pending + stats.InFlight; left > 0 {
+		if o.path != "" {
+			logger.Infof("%d notifications left in %s for the next start", left, o.path)
+		} else {
+			logger.Warnf("%d notifications not delivered (no NOTIFY_OUTBOX_FILE)", left)
+		}
+	}
+	if err := o.Close(); err != nil {
+		logger.Warnf("Failed to close notification outbox: %v", err)
+	}
+}
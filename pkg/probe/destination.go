@@ -0,0 +1,206 @@
+package probe
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// STREAM DESTINATION PROBES
+// =============================================================================
+// Destination checks that a stream URL answers the way the device will
+// talk to it, without sending any media:
+//
+//   rtmp://, rtmps://   TCP (TLS) connect, then the RTMP handshake (C0+C1
+//                       → S0+S1): something that speaks RTMP is listening
+//   srt://              the SRT caller handshake over UDP (an induction
+//                       request; a listener answers with its own)
+//   rtsp://, http(s)://, tcp:// and other schemes with a host: TCP connect
+//
+// Some destinations can't be probed from the caller's side: SRT in
+// listener or rendezvous mode (the device waits for the peer), udp:// and
+// multicast. Those are reported as skipped, not unreachable.
+//
+// WHY HANDSHAKES (not just a connect): A load balancer or a firewall
+// accepting every TCP connection would pass a connect, and UDP has no
+// connect at all.
+// =============================================================================
+
+// Default ports by scheme.
+var defaultPorts = map[string]string{
+	"rtmp":  "1935",
+	"rtmps": "443",
+	"rtsp":  "554",
+	"http":  "80",
+	"https": "443",
+}
+
+// rtmpHandshakeSize is the size of C1 and S1.
+const rtmpHandshakeSize = 1536
+
+// Destination probes rawURL until ctx ends. Failures to reach the
+// destination are reported in the result; the error is for URLs that
+// can't be probed at all (unparsable, no host).
+func Destination(ctx context.Context, rawURL string) (*models.DestinationProbe, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, fmt.Errorf("invalid stream URL: %w", err)
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("stream URL %q has no host", rawURL)
+	}
+	port := u.Port()
+	if port == "" {
+		port = defaultPorts[scheme]
+	}
+	result := &models.DestinationProbe{URL: rawURL, Address: net.JoinHostPort(u.Hostname(), port)}
+	if port == "" {
+		result.Method = models.ProbeSkipped
+		result.Message = "no port to probe"
+		return result, nil
+	}
+
+	started := time.Now()
+	switch {
+	case scheme == "srt":
+		if mode := strings.ToLower(u.Query().Get("mode")); mode == "listener" || mode == "rendezvous" {
+			result.Method = models.ProbeSkipped
+			result.Message = "SRT " + mode + " mode: the peer connects to the device"
+			return result, nil
+		}
+		result.Method = models.ProbeSRTHandshake
+		err = srtHandshake(ctx, result.Address)
+	case scheme == "udp" || scheme == "rtp":
+		result.Method = models.ProbeSkipped
+		result.Message = "connectionless " + scheme + ":// destinations can't be probed"
+		return result, nil
+	case scheme == "rtmp" || scheme == "rtmps":
+		result.Method = models.ProbeRTMPHandshake
+		err = rtmpHandshake(ctx, result.Address, scheme == "rtmps", u.Hostname())
+	default:
+		result.Method = models.ProbeTCPConnect
+		var conn net.Conn
+		var dialer net.Dialer
+		if conn, err = dialer.DialContext(ctx, "tcp", result.Address); err == nil {
+			conn.Close()
+		}
+	}
+	if err != nil {
+		result.Message = err.Error()
+		return result, nil
+	}
+	result.Reachable = true
+	result.RTTMillis = float64(time.Since(started).Microseconds()) / 1000
+	return result, nil
+}
+
+// rtmpHandshake connects to address and exchanges C0+C1 for S0+S1.
+func rtmpHandshake(ctx context.Context, address string, useTLS bool, serverName string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if useTLS {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: serverName})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return fmt.Errorf("TLS handshake: %w", err)
+		}
+		conn = tlsConn
+	}
+
+	// C0 is the version (3); C1 is time, zero and random bytes
+	c0c1 := make([]byte, 1+rtmpHandshakeSize)
+	c0c1[0] = 3
+	rand.Read(c0c1[9:])
+	if _, err := conn.Write(c0c1); err != nil {
+		return fmt.Errorf("RTMP handshake: %w", err)
+	}
+	s0s1 := make([]byte, 1+rtmpHandshakeSize)
+	if _, err := io.ReadFull(conn, s0s1); err != nil {
+		return fmt.Errorf("no RTMP handshake from %s: %w", address, err)
+	}
+	if s0s1[0] != 3 {
+		return fmt.Errorf("%s answered with RTMP version %d, not 3", address, s0s1[0])
+	}
+	return nil
+}
+
+// srtHandshake sends an SRT induction handshake to address and waits for
+// the listener's.
+func srtHandshake(ctx context.Context, address string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(3 * time.Second)
+	}
+	conn.SetDeadline(deadline)
+
+	// Control packet header (16 bytes) + handshake (48 bytes)
+	packet := make([]byte, 64)
+	binary.BigEndian.PutUint16(packet[0:], 0x8000) // control, type 0 = handshake
+	binary.BigEndian.PutUint32(packet[16:], 4)     // version 4 for the induction
+	binary.BigEndian.PutUint16(packet[22:], 2)     // extension field: SRT magic for induction
+	var random [8]byte
+	rand.Read(random[:])
+	binary.BigEndian.PutUint32(packet[24:], binary.BigEndian.Uint32(random[0:])&0x7fffffff) // initial sequence number
+	binary.BigEndian.PutUint32(packet[28:], 1500)                                           // MTU
+	binary.BigEndian.PutUint32(packet[32:], 8192)                                           // flow window
+	binary.BigEndian.PutUint32(packet[36:], 1)                                              // handshake type: induction
+	binary.BigEndian.PutUint32(packet[40:], binary.BigEndian.Uint32(random[4:])&0x7fffffff) // our socket ID
+
+	// WHY RESEND: UDP may drop the request; a listener answers every one
+	buf := make([]byte, 1500)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, err := conn.Write(packet); err != nil {
+			return err
+		}
+		conn.SetReadDeadline(minTime(deadline, time.Now().Add(500*time.Millisecond)))
+		n, err := conn.Read(buf)
+		if err == nil {
+			if n >= 16 && binary.BigEndian.Uint16(buf[0:]) == 0x8000 {
+				return nil
+			}
+			return fmt.Errorf("%s answered with something that isn't an SRT handshake", address)
+		}
+		var netErr net.Error
+		if !errors.As(err, &netErr) || !netErr.Timeout() {
+			return fmt.Errorf("no SRT listener at %s: %w", address, err)
+		}
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("no SRT handshake from %s before the timeout", address)
+		}
+	}
+}
+
+// minTime returns the earlier of a and b.
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,19 @@
+package provider
+
+import "errors"
+
+// Sentinel errors providers wrap (with fmt.Errorf("...: %w", ErrX)) so the
+// controller can map vendor outcomes to HTTP status codes with errors.Is
+// instead of parsing error strings.
+var (
+	// ErrNotFound means the vendor has no such object (vendor 404).
+	ErrNotFound = errors.New("not found in vendor system")
+
+	// ErrConflict means the vendor refused because of the object's current
+	// state (vendor 409), e.g. starting a second recording.
+	ErrConflict = errors.New("conflicts with vendor state")
+
+	// ErrInvalidRequest means the caller's request was rejected before
+	// reaching the vendor, e.g. a passthrough path outside the vendor API.
+	ErrInvalidRequest = errors.New("invalid vendor request")
+)
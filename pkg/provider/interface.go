@@ -2,6 +2,7 @@ package provider
 
 import (
 	"context"
+	"net/http"
 
 	"github.com/Zhichengu1/mock-control-plane/pkg/models"
 )
@@ -121,6 +122,9 @@ type VendorProvider interface {
 	// This method is idempotent - calling Delete on an already-deleted
 	// resource should not return an error. This simplifies cleanup logic.
 	//
+	// Providers implementing TeardownTracker may return as soon as the
+	// vendor has started tearing the device down.
+	//
 	// Parameters:
 	//   - ctx: Context for cancellation and timeout
 	//   - vendorID: The vendor's unique identifier for the resource
@@ -164,3 +168,194 @@ type VendorProvider interface {
 	//   }
 	HealthCheck(ctx context.Context) error
 }
+
+// =============================================================================
+// OPTIONAL CAPABILITIES
+// =============================================================================
+// Not every vendor API supports every operation. Optional capabilities are
+// expressed as small interfaces that a provider MAY implement in addition to
+// VendorProvider. The controller checks for them with a type assertion:
+//
+//	if d, ok := p.(provider.Discoverer); ok {
+//	    devices, err := d.Discover(ctx)
+//	}
+//
+// This keeps VendorProvider small so simple vendors stay easy to add.
+// =============================================================================
+
+// Discoverer is implemented by providers that can enumerate every device
+// visible in the vendor system, including devices Forge didn't create.
+//
+// Discovered devices are reverse-mapped into Forge terms (ResourceSpec)
+// so that unmanaged devices can be proposed for adoption.
+type Discoverer interface {
+	// Discover lists all devices in the vendor system.
+	//
+	// Returns:
+	//   - []models.DiscoveredDevice: Every device, managed or not. The
+	//     controller decides which ones are unmanaged.
+	//   - error: Non-nil if the vendor could not be listed.
+	Discover(ctx context.Context) ([]models.DiscoveredDevice, error)
+}
+
+// PagedDiscoverer is implemented by providers whose vendor lists devices
+// in pages. An inventory sync can then stop after any page and resume
+// from its cursor instead of listing everything again.
+type PagedDiscoverer interface {
+	// DiscoverPage returns up to limit devices, starting at cursor ("" for
+	// the first page). Returns ErrInvalidRequest if the vendor no longer
+	// accepts cursor (e.g. it expired).
+	DiscoverPage(ctx context.Context, cursor string, limit int) (*models.DiscoveryPage, error)
+}
+
+// Recorder is implemented by providers whose devices can record.
+// Recording sessions are managed in the vendor system; the controller
+// does not store them.
+type Recorder interface {
+	// StartRecording begins a named recording session on the device.
+	// Returns ErrConflict if the device is already recording.
+	StartRecording(ctx context.Context, vendorID string, req models.RecordingRequest) (*models.RecordingSession, error)
+
+	// StopRecording ends an active session and returns its final state.
+	// Returns ErrNotFound for unknown sessions.
+	StopRecording(ctx context.Context, vendorID, sessionID string) (*models.RecordingSession, error)
+
+	// ListRecordings returns every session (active and past) on the device.
+	ListRecordings(ctx context.Context, vendorID string) ([]models.RecordingSession, error)
+
+	// DeleteRecording removes a stopped session and its file.
+	// Returns ErrConflict for active sessions and ErrNotFound for unknown ones.
+	DeleteRecording(ctx context.Context, vendorID, sessionID string) error
+}
+
+// CameraController is implemented by providers whose cameras support
+// remote pan/tilt/zoom and preset memories. Preset numbers are 1-based.
+type CameraController interface {
+	// GetPTZ returns the camera's current position.
+	GetPTZ(ctx context.Context, vendorID string) (*models.PTZPosition, error)
+
+	// MovePTZ executes a PTZ command (already validated) and returns the
+	// resulting position.
+	MovePTZ(ctx context.Context, vendorID string, cmd models.PTZCommand) (*models.PTZPosition, error)
+
+	// ListPresets returns the saved presets, lowest number first.
+	ListPresets(ctx context.Context, vendorID string) ([]models.CameraPreset, error)
+
+	// SavePreset stores the current position in a preset slot,
+	// overwriting it if already set.
+	SavePreset(ctx context.Context, vendorID string, req models.PresetRequest) (*models.CameraPreset, error)
+
+	// RecallPreset moves the camera to a saved preset and returns the
+	// resulting position. Returns ErrNotFound for empty slots.
+	RecallPreset(ctx context.Context, vendorID string, number int) (*models.PTZPosition, error)
+}
+
+// PowerController is implemented by providers whose devices can be
+// stopped without being deleted (and started again), e.g. to stop paying
+// for an idle cloud channel while keeping its configuration.
+type PowerController interface {
+	// Stop stops the device and returns its resulting status.
+	Stop(ctx context.Context, vendorID string) (*models.ResourceStatus, error)
+
+	// Start starts a stopped device and returns its resulting status.
+	Start(ctx context.Context, vendorID string) (*models.ResourceStatus, error)
+}
+
+// TeardownTracker is implemented by providers whose vendor tears devices
+// down in the background: Delete returns once teardown has started, and
+// the device may linger (e.g. a cloud channel releasing its inputs) until
+// TeardownProgress reports Done.
+type TeardownTracker interface {
+	// TeardownProgress reports how far the deletion of a device got. A
+	// device that is already gone reports Done.
+	TeardownProgress(ctx context.Context, vendorID string) (*models.TeardownProgress, error)
+}
+
+// Preflighter is implemented by providers that can check whether a Create
+// would succeed without provisioning anything.
+type Preflighter interface {
+	// Preflight runs the vendor's checks for creating resource. Failed
+	// checks are reported in the result, not as an error; the error is
+	// for checks that couldn't run (vendor unreachable).
+	Preflight(ctx context.Context, resource *models.ForgeResource) (*models.PreflightResult, error)
+}
+
+// DestinationProber is implemented by providers that can probe a stream
+// destination from the vendor's side: the network the device will stream
+// from, which may reach places the controller can't (and vice versa).
+type DestinationProber interface {
+	// ProbeDestination probes streamURL (see pkg/probe). An unreachable
+	// destination is reported in the result, not as an error; the error
+	// is for probes that couldn't run (vendor unreachable).
+	ProbeDestination(ctx context.Context, streamURL string) (*models.DestinationProbe, error)
+}
+
+// ConfigBackuper is implemented by providers that can dump a device's
+// full settings and load them back, e.g. after a factory reset.
+type ConfigBackuper interface {
+	// ExportConfig reads the device's full settings.
+	ExportConfig(ctx context.Context, vendorID string) (*models.DeviceConfig, error)
+
+	// ImportConfig pushes settings exported earlier back to the device
+	// and returns its resulting status. Returns ErrInvalidRequest for a
+	// config in a format the provider doesn't read.
+	ImportConfig(ctx context.Context, vendorID string, config *models.DeviceConfig) (*models.ResourceStatus, error)
+}
+
+// ProviderAction is implemented by providers that can run operational
+// commands on a device beyond CRUD (models.DeviceActions: restart,
+// reboot, start_stream, stop_stream).
+type ProviderAction interface {
+	// Actions lists the actions the provider supports.
+	Actions() []string
+
+	// RunAction runs action on the device and returns its resulting
+	// status. Returns ErrConflict when the device's state doesn't allow
+	// it (starting the stream of a stopped device).
+	RunAction(ctx context.Context, vendorID, action string) (*models.ResourceStatus, error)
+}
+
+// MetricsReporter is implemented by providers that can read a device's
+// live metrics, including the vendor's own health report, without a full
+// status read.
+type MetricsReporter interface {
+	// Metrics reads the device's current metrics. Returns ErrNotFound when
+	// the device is gone.
+	Metrics(ctx context.Context, vendorID string) (*models.ResourceMetrics, error)
+}
+
+// CapacityReporter is implemented by providers that can report how much
+// room the vendor account has left (device quota, address pool).
+type CapacityReporter interface {
+	Capacity(ctx context.Context) (*models.VendorCapacity, error)
+}
+
+// HostLister is implemented by providers that can name the vendor API
+// hosts they call. The HTTP client tracks circuit breakers and call
+// records per host; this attributes them to the vendor.
+type HostLister interface {
+	// APIHosts returns "host" or "host:port" of every vendor API host the
+	// provider calls, as it appears in request URLs.
+	APIHosts() []string
+}
+
+// Passthrough is implemented by providers that can forward a raw request
+// to the vendor API using the provider's own credentials. It exists for
+// debugging vendor-side issues; the controller restricts it to admins.
+type Passthrough interface {
+	// Passthrough sends method + path (relative to the vendor base URL,
+	// optionally with a query string) with body and returns the vendor's
+	// response unmodified. The caller must close the response body.
+	// Returns ErrInvalidRequest for paths outside the vendor API.
+	Passthrough(ctx context.Context, method, path string, body []byte) (*http.Response, error)
+}
+
+// ConnectionDiagnoser is implemented by providers that can walk their
+// connection to the vendor API step by step (DNS, TCP, TLS, auth, latency)
+// to explain why it fails.
+type ConnectionDiagnoser interface {
+	// DiagnoseConnection runs every check, sending samples requests for
+	// the latency check. Failed checks are reported in the diagnosis, not
+	// as an error.
+	DiagnoseConnection(ctx context.Context, samples int) (*models.ConnectionDiagnosis, error)
+}
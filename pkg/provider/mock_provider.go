@@ -0,0 +1,365 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/clock"
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// MOCK PROVIDER
+// =============================================================================
+// MockProvider is a VendorProvider that keeps its "devices" in memory, in
+// the controller's own process. It exists for demos and load tests: the
+// controller runs on its own, with no cmd/vendor-api and no network, and
+// vendor behavior is tuned with MockOptions:
+//
+//   Latency + Jitter   every call takes Latency plus up to Jitter
+//   FailureRate        fraction of calls that fail like a flaky vendor
+//   ProvisionTime      how long a new device stays Provisioning
+//   TeardownTime       how long a deleted device takes to go away
+//
+// WHY NOT THE HTTP CLIENT: Nothing goes over the wire, so there are no
+// circuit breakers, retries or audit records for mock calls; load tests
+// measure the controller, not the network stack.
+// =============================================================================
+
+// MockOptions tunes the mock vendor's behavior.
+type MockOptions struct {
+	// Latency is added to every call; Jitter adds a random 0..Jitter more
+	Latency time.Duration
+	Jitter  time.Duration
+
+	// FailureRate is the probability (0..1) that a call fails
+	FailureRate float64
+
+	// ProvisionTime is how long a created device reports Provisioning
+	// before Running (0 = Running immediately)
+	ProvisionTime time.Duration
+
+	// TeardownTime is how long a deleted device lingers as Terminating
+	// before it is gone (0 = deleted immediately)
+	TeardownTime time.Duration
+
+	// Seed seeds latency jitter and failures (0 = seeded from the time)
+	Seed int64
+}
+
+// mockDevice is one device held by MockProvider.
+type mockDevice struct {
+	id        string
+	spec      models.ResourceSpec
+	ip        string
+	createdAt time.Time
+	startedAt time.Time
+	stopped   bool
+
+	// offAir is set by the stop_stream action until start_stream
+	offAir bool
+
+	// deletingAt is when teardown started (zero = not being deleted)
+	deletingAt time.Time
+}
+
+// MockProvider implements VendorProvider (and PowerController,
+// ProviderAction and TeardownTracker) in memory.
+type MockProvider struct {
+	Options MockOptions
+
+	// Clock stamps device and health check times; latency is always
+	// real time so load tests see it
+	Clock clock.Clock
+
+	mu      sync.Mutex
+	rand    *rand.Rand
+	nextID  int64
+	devices map[string]*mockDevice
+}
+
+// NewMockProvider creates a MockProvider with the given options.
+func NewMockProvider(options MockOptions) *MockProvider {
+	seed := options.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &MockProvider{
+		Options: options,
+		Clock:   clock.Real{},
+		rand:    rand.New(rand.NewSource(seed)),
+		devices: make(map[string]*mockDevice),
+	}
+}
+
+// SetOptions changes the options of later calls; devices are kept. The
+// seed only applies to a new provider.
+func (m *MockProvider) SetOptions(options MockOptions) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	options.Seed = m.Options.Seed
+	m.Options = options
+}
+
+// simulateCall waits out the call's latency and decides whether it fails.
+func (m *MockProvider) simulateCall(ctx context.Context, operation string) error {
+	m.mu.Lock()
+	delay := m.Options.Latency
+	if m.Options.Jitter > 0 {
+		delay += time.Duration(m.rand.Int63n(int64(m.Options.Jitter) + 1))
+	}
+	failureRate := m.Options.FailureRate
+	failed := failureRate > 0 && m.rand.Float64() < failureRate
+	m.mu.Unlock()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("mock vendor %s: %w", operation, ctx.Err())
+		case <-timer.C:
+		}
+	}
+	if failed {
+		logger.Debugf("mock: simulated %s failure", operation)
+		return fmt.Errorf("mock vendor %s: simulated failure (failure rate %.0f%%)", operation, failureRate*100)
+	}
+	return nil
+}
+
+// Create provisions an in-memory device.
+func (m *MockProvider) Create(ctx context.Context, resource *models.ForgeResource) (*models.ResourceStatus, error) {
+	if err := m.simulateCall(ctx, "create"); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	now := m.Clock.Now()
+	device := &mockDevice{
+		id:        fmt.Sprintf("mock-dev-%d", m.nextID),
+		spec:      resource.DeepCopy().Spec,
+		ip:        fmt.Sprintf("10.99.%d.%d", (m.nextID/250)%250, 10+m.nextID%240),
+		createdAt: now,
+		startedAt: now,
+	}
+	m.devices[device.id] = device
+	logger.Debugf("mock: created %s for %s", device.id, resource.Name)
+	return m.status(device), nil
+}
+
+// Read returns a device's current status. A missing device reads as
+// Failed, like a device deleted behind the controller's back.
+func (m *MockProvider) Read(ctx context.Context, vendorID string) (*models.ResourceStatus, error) {
+	if err := m.simulateCall(ctx, "read"); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	device, exists := m.devices[vendorID]
+	if !exists {
+		return &models.ResourceStatus{
+			Phase:        "Failed",
+			Message:      "Device not found in mock vendor",
+			VendorID:     vendorID,
+			HealthStatus: "unhealthy",
+		}, nil
+	}
+	return m.status(device), nil
+}
+
+// Update replaces a device's spec.
+func (m *MockProvider) Update(ctx context.Context, resource *models.ForgeResource) (*models.ResourceStatus, error) {
+	if resource.Status.VendorID == "" {
+		return nil, fmt.Errorf("cannot update resource without vendor ID")
+	}
+	if err := m.simulateCall(ctx, "update"); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	device, exists := m.devices[resource.Status.VendorID]
+	if !exists {
+		return nil, fmt.Errorf("mock device %s: %w", resource.Status.VendorID, ErrNotFound)
+	}
+	device.spec = resource.DeepCopy().Spec
+	return m.status(device), nil
+}
+
+// Delete removes a device, or starts its teardown when TeardownTime is
+// set. Deleting a missing device succeeds.
+func (m *MockProvider) Delete(ctx context.Context, vendorID string) error {
+	if err := m.simulateCall(ctx, "delete"); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	device, exists := m.devices[vendorID]
+	if !exists {
+		return nil
+	}
+	if m.Options.TeardownTime <= 0 {
+		delete(m.devices, vendorID)
+		return nil
+	}
+	if device.deletingAt.IsZero() {
+		device.deletingAt = m.Clock.Now()
+	}
+	return nil
+}
+
+// TeardownProgress reports how far a device's teardown got, removing the
+// device once TeardownTime has passed.
+func (m *MockProvider) TeardownProgress(ctx context.Context, vendorID string) (*models.TeardownProgress, error) {
+	if err := m.simulateCall(ctx, "read"); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	device, exists := m.devices[vendorID]
+	if !exists {
+		return &models.TeardownProgress{Done: true, Percent: 100, Message: "Device deleted"}, nil
+	}
+	if device.deletingAt.IsZero() {
+		return nil, fmt.Errorf("mock device %s is not being deleted: %w", vendorID, ErrConflict)
+	}
+	elapsed := m.Clock.Now().Sub(device.deletingAt)
+	if elapsed >= m.Options.TeardownTime {
+		delete(m.devices, vendorID)
+		return &models.TeardownProgress{Done: true, Percent: 100, Message: "Device deleted"}, nil
+	}
+	return &models.TeardownProgress{
+		Percent: int(elapsed * 100 / m.Options.TeardownTime),
+		Message: "Releasing device",
+	}, nil
+}
+
+// HealthCheck succeeds unless the simulated call fails.
+func (m *MockProvider) HealthCheck(ctx context.Context) error {
+	return m.simulateCall(ctx, "health check")
+}
+
+// Stop stops a device, keeping its configuration.
+func (m *MockProvider) Stop(ctx context.Context, vendorID string) (*models.ResourceStatus, error) {
+	return m.setStopped(ctx, vendorID, true)
+}
+
+// Start starts a stopped device.
+func (m *MockProvider) Start(ctx context.Context, vendorID string) (*models.ResourceStatus, error) {
+	return m.setStopped(ctx, vendorID, false)
+}
+
+func (m *MockProvider) setStopped(ctx context.Context, vendorID string, stopped bool) (*models.ResourceStatus, error) {
+	if err := m.simulateCall(ctx, "power"); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	device, exists := m.devices[vendorID]
+	if !exists {
+		return nil, fmt.Errorf("mock device %s: %w", vendorID, ErrNotFound)
+	}
+	if device.stopped && !stopped {
+		device.startedAt = m.Clock.Now()
+	}
+	device.stopped = stopped
+	return m.status(device), nil
+}
+
+// Actions implements ProviderAction.
+func (m *MockProvider) Actions() []string {
+	return models.DeviceActions
+}
+
+// RunAction implements ProviderAction. Restarts and reboots are instant
+// and start uptime over.
+func (m *MockProvider) RunAction(ctx context.Context, vendorID, action string) (*models.ResourceStatus, error) {
+	if err := m.simulateCall(ctx, action); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	device, exists := m.devices[vendorID]
+	if !exists {
+		return nil, fmt.Errorf("mock device %s: %w", vendorID, ErrNotFound)
+	}
+	if !device.deletingAt.IsZero() {
+		return nil, fmt.Errorf("mock device %s is being deleted: %w", vendorID, ErrConflict)
+	}
+	switch action {
+	case models.DeviceActionRestart, models.DeviceActionReboot:
+		if device.stopped && action == models.DeviceActionRestart {
+			return nil, fmt.Errorf("mock device %s is stopped: %w", vendorID, ErrConflict)
+		}
+		device.startedAt = m.Clock.Now()
+		device.offAir = false
+	case models.DeviceActionStartStream, models.DeviceActionStopStream:
+		if device.stopped {
+			return nil, fmt.Errorf("mock device %s is stopped: %w", vendorID, ErrConflict)
+		}
+		if device.spec.StreamURL == "" {
+			return nil, fmt.Errorf("mock device %s has no stream_url: %w", vendorID, ErrConflict)
+		}
+		device.offAir = action == models.DeviceActionStopStream
+	default:
+		return nil, fmt.Errorf("unknown action %q: %w", action, ErrInvalidRequest)
+	}
+	return m.status(device), nil
+}
+
+// status reports a device's state. Must be called with m.mu held.
+func (m *MockProvider) status(device *mockDevice) *models.ResourceStatus {
+	now := m.Clock.Now()
+	status := &models.ResourceStatus{
+		VendorID:                device.id,
+		LastHealthCheck:         now,
+		LastSuccessfulOperation: now,
+		Endpoints: []models.Endpoint{
+			{Type: models.EndpointTypeIP, Address: device.ip, Role: models.EndpointRoleControl},
+		},
+	}
+	switch {
+	case !device.deletingAt.IsZero():
+		status.Phase, status.HealthStatus, status.Message = "Terminating", "unknown", "Device being deleted"
+		return status
+	case device.stopped:
+		status.Phase, status.HealthStatus, status.Message = "Pending", "unknown", "Device stopped"
+		return status
+	case now.Sub(device.createdAt) < m.Options.ProvisionTime:
+		status.Phase, status.HealthStatus, status.Message = "Provisioning", "unknown", "Device provisioning"
+		return status
+	}
+
+	status.Phase, status.HealthStatus, status.Message = "Running", "healthy", "Device running"
+	status.Uptime = now.Sub(device.startedAt).Truncate(time.Second)
+	if device.spec.StreamURL != "" && !device.offAir {
+		connected := true
+		status.CurrentBitrate = device.spec.Bitrate
+		status.Endpoints = append(status.Endpoints, models.Endpoint{
+			Type:      models.EndpointTypeURL,
+			Address:   device.spec.StreamURL,
+			Role:      models.EndpointRoleOutput,
+			Protocol:  streamProtocol(device.spec.StreamURL),
+			Connected: &connected,
+		})
+	}
+
+	// Every configured sync reference locks
+	if device.spec.TimecodeSource != "" {
+		status.Conditions = append(status.Conditions, lockCondition(models.ConditionTimecodeLocked, true,
+			"Locked", "Unlocked", "timecode source "+device.spec.TimecodeSource))
+	}
+	if device.spec.NTPServer != "" {
+		status.Conditions = append(status.Conditions, lockCondition(models.ConditionClockSynchronized, true,
+			"Synchronized", "Unsynchronized", "NTP server "+device.spec.NTPServer))
+	}
+	if device.spec.GenlockReference != "" {
+		status.Conditions = append(status.Conditions, lockCondition(models.ConditionGenlockLocked, true,
+			"Locked", "Unlocked", device.spec.GenlockReference+" reference"))
+	}
+	return status
+}
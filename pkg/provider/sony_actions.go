@@ -0,0 +1,49 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// DEVICE ACTIONS (optional ProviderAction capability)
+// =============================================================================
+// Sony's operational endpoints:
+//
+//   restart        POST /devices/{id}/restart        encoder restart
+//   reboot         POST /devices/{id}/reboot         full power cycle
+//   start_stream   POST /devices/{id}/stream/start   output on air
+//   stop_stream    POST /devices/{id}/stream/stop    output off air
+//
+// Sony answers 409 when the device's state doesn't allow the action (the
+// stream of a stopped device can't start).
+// =============================================================================
+
+var sonyActionPaths = map[string]string{
+	models.DeviceActionRestart:     "restart",
+	models.DeviceActionReboot:      "reboot",
+	models.DeviceActionStartStream: "stream/start",
+	models.DeviceActionStopStream:  "stream/stop",
+}
+
+// Actions implements ProviderAction.
+func (s *SonyProvider) Actions() []string {
+	return models.DeviceActions
+}
+
+// RunAction implements ProviderAction.
+func (s *SonyProvider) RunAction(ctx context.Context, vendorID, action string) (*models.ResourceStatus, error) {
+	path, ok := sonyActionPaths[action]
+	if !ok {
+		return nil, fmt.Errorf("unknown action %q: %w", action, ErrInvalidRequest)
+	}
+	var device models.SonyDeviceResponse
+	if err := s.doDeviceCall(ctx, http.MethodPost, "/devices/"+url.PathEscape(vendorID)+"/"+path, nil, &device); err != nil {
+		return nil, fmt.Errorf("failed to %s device: %w", action, err)
+	}
+	return s.buildResourceStatus(&device), nil
+}
@@ -0,0 +1,46 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// CONFIGURATION BACKUP / RESTORE (optional ConfigBackuper capability)
+// =============================================================================
+// Sony devices dump and load their full settings in one call:
+//
+//   GET /devices/{id}/settings  → configuration, presets, firmware
+//   PUT /devices/{id}/settings  → load a dump; returns the device
+//
+// The dump is kept as Sony returned it (format "sony-settings/v1"), so a
+// restore sends back exactly what was read.
+// =============================================================================
+
+// ExportConfig reads a Sony device's settings dump.
+func (s *SonyProvider) ExportConfig(ctx context.Context, vendorID string) (*models.DeviceConfig, error) {
+	var dump json.RawMessage
+	path := "/devices/" + url.PathEscape(vendorID) + "/settings"
+	if err := s.doDeviceCall(ctx, http.MethodGet, path, nil, &dump); err != nil {
+		return nil, fmt.Errorf("failed to export settings: %w", err)
+	}
+	return &models.DeviceConfig{Format: models.SonySettingsFormat, Data: dump}, nil
+}
+
+// ImportConfig loads a settings dump onto a Sony device.
+func (s *SonyProvider) ImportConfig(ctx context.Context, vendorID string, config *models.DeviceConfig) (*models.ResourceStatus, error) {
+	if config.Format != models.SonySettingsFormat {
+		return nil, fmt.Errorf("settings in format %q can't be loaded onto a Sony device: %w", config.Format, ErrInvalidRequest)
+	}
+	var device models.SonyDeviceResponse
+	path := "/devices/" + url.PathEscape(vendorID) + "/settings"
+	if err := s.doDeviceCall(ctx, http.MethodPut, path, config.Data, &device); err != nil {
+		return nil, fmt.Errorf("failed to import settings: %w", err)
+	}
+	return s.buildResourceStatus(&device), nil
+}
@@ -0,0 +1,317 @@
+package provider
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/client"
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// CONNECTION DIAGNOSIS (optional ConnectionDiagnoser capability)
+// =============================================================================
+// Walks the path to the Sony API the way a request does:
+//
+//   dns      resolve the host of BaseURL
+//   tcp      connect to host:port
+//   tls      handshake (https only): version, cipher, certificate chain
+//   auth     GET /devices?page_size=1 with the provider's credentials
+//   latency  GET /health, samples times
+//
+// DNS, TCP and TLS dial directly, bypassing the circuit breaker, so they
+// show whether the vendor is reachable even while the breaker is open.
+// The auth and latency requests go through client.Do like every other
+// call: they are audited, and an open breaker is reported as such.
+// =============================================================================
+
+// certExpiryWarning warns about a certificate expiring this soon.
+const certExpiryWarning = 14 * 24 * time.Hour
+
+// diagnoseMillis converts d to milliseconds with two decimals.
+func diagnoseMillis(d time.Duration) float64 {
+	return math.Round(float64(d.Microseconds())/10) / 100
+}
+
+// DiagnoseConnection runs the connection checks against BaseURL.
+func (s *SonyProvider) DiagnoseConnection(ctx context.Context, samples int) (*models.ConnectionDiagnosis, error) {
+	base, err := url.Parse(s.BaseURL)
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("%w: invalid Sony base URL %q", ErrInvalidRequest, s.BaseURL)
+	}
+	if samples < 1 {
+		samples = 1
+	}
+	started := time.Now()
+	diagnosis := &models.ConnectionDiagnosis{
+		Endpoint:   client.RedactURL(base),
+		StartedAt:  s.Clock.Now(),
+		AuthScheme: s.authScheme(),
+		Checks:     []models.ConnectionCheck{},
+	}
+	host, port := base.Hostname(), base.Port()
+	if port == "" {
+		port = "80"
+		if base.Scheme == "https" {
+			port = "443"
+		}
+	}
+	address := net.JoinHostPort(host, port)
+
+	// Each step runs only if the one before it got through
+	reachable := s.diagnoseDNS(ctx, diagnosis, host)
+	reachable = reachable && s.diagnoseTCP(ctx, diagnosis, address)
+	switch {
+	case base.Scheme != "https":
+		diagnosis.Add(models.ConnectionCheck{Name: models.DiagnoseTLS, Status: models.CheckSkipped,
+			Message: "plain HTTP: requests and credentials are not encrypted"})
+	case reachable:
+		reachable = s.diagnoseTLS(ctx, diagnosis, host, address)
+	}
+	if reachable {
+		s.diagnoseAuth(ctx, diagnosis)
+		s.diagnoseLatency(ctx, diagnosis, samples)
+	}
+
+	// Name the checks that couldn't run
+	ran := make(map[string]bool)
+	for _, check := range diagnosis.Checks {
+		ran[check.Name] = true
+	}
+	for _, name := range []string{models.DiagnoseTCP, models.DiagnoseTLS, models.DiagnoseAuth, models.DiagnoseLatency} {
+		if !ran[name] {
+			diagnosis.Add(models.ConnectionCheck{Name: name, Status: models.CheckSkipped, Message: "vendor is not reachable"})
+		}
+	}
+	diagnosis.DurationMS = diagnoseMillis(time.Since(started))
+	return diagnosis, nil
+}
+
+// diagnoseDNS resolves host. Returns false if it doesn't resolve.
+func (s *SonyProvider) diagnoseDNS(ctx context.Context, d *models.ConnectionDiagnosis, host string) bool {
+	if net.ParseIP(host) != nil {
+		d.Addresses = []string{host}
+		d.Add(models.ConnectionCheck{Name: models.DiagnoseDNS, Status: models.CheckSkipped, Message: "host is an IP address"})
+		return true
+	}
+	started := time.Now()
+	addresses, err := net.DefaultResolver.LookupHost(ctx, host)
+	check := models.ConnectionCheck{Name: models.DiagnoseDNS, DurationMS: diagnoseMillis(time.Since(started))}
+	if err != nil {
+		check.Status, check.Message = models.CheckFailed, err.Error()
+		d.Add(check)
+		return false
+	}
+	sort.Strings(addresses)
+	d.Addresses = addresses
+	check.Status, check.Message = models.CheckPassed, fmt.Sprintf("%s resolves to %s", host, strings.Join(addresses, ", "))
+	d.Add(check)
+	return true
+}
+
+// diagnoseTCP connects to address. Returns false if it can't.
+func (s *SonyProvider) diagnoseTCP(ctx context.Context, d *models.ConnectionDiagnosis, address string) bool {
+	started := time.Now()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	check := models.ConnectionCheck{Name: models.DiagnoseTCP, DurationMS: diagnoseMillis(time.Since(started))}
+	if err != nil {
+		check.Status, check.Message = models.CheckFailed, err.Error()
+		d.Add(check)
+		return false
+	}
+	conn.Close()
+	check.Status, check.Message = models.CheckPassed, "connected to "+conn.RemoteAddr().String()
+	d.Add(check)
+	return true
+}
+
+// tlsConfig returns the TLS settings the provider's HTTP client uses.
+func (s *SonyProvider) tlsConfig(host string) *tls.Config {
+	config := &tls.Config{}
+	if transport, ok := s.HTTPClient.Transport.(*http.Transport); ok && transport.TLSClientConfig != nil {
+		config = transport.TLSClientConfig.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName = host
+	}
+	return config
+}
+
+// diagnoseTLS handshakes with address and describes the session. If the
+// certificate doesn't verify, it handshakes again without verification so
+// the chain can still be reported. Returns false if no session could be
+// established.
+func (s *SonyProvider) diagnoseTLS(ctx context.Context, d *models.ConnectionDiagnosis, host, address string) bool {
+	started := time.Now()
+	config := s.tlsConfig(host)
+	conn, verifyErr := (&tls.Dialer{Config: config}).DialContext(ctx, "tcp", address)
+	check := models.ConnectionCheck{Name: models.DiagnoseTLS, DurationMS: diagnoseMillis(time.Since(started))}
+	var certErr *tls.CertificateVerificationError
+	if errors.As(verifyErr, &certErr) {
+		insecure := config.Clone()
+		insecure.InsecureSkipVerify = true
+		conn, _ = (&tls.Dialer{Config: insecure}).DialContext(ctx, "tcp", address)
+	}
+	if conn == nil {
+		check.Status, check.Message = models.CheckFailed, verifyErr.Error()
+		d.Add(check)
+		return false
+	}
+	defer conn.Close()
+
+	state := conn.(*tls.Conn).ConnectionState()
+	details := &models.TLSDetails{
+		Version:            tls.VersionName(state.Version),
+		CipherSuite:        tls.CipherSuiteName(state.CipherSuite),
+		ServerName:         config.ServerName,
+		NegotiatedProtocol: state.NegotiatedProtocol,
+		Verified:           verifyErr == nil,
+		Certificates:       []models.CertificateInfo{},
+	}
+	now := s.Clock.Now()
+	for _, cert := range state.PeerCertificates {
+		details.Certificates = append(details.Certificates, models.CertificateInfo{
+			Subject:       cert.Subject.String(),
+			Issuer:        cert.Issuer.String(),
+			DNSNames:      cert.DNSNames,
+			NotBefore:     cert.NotBefore,
+			NotAfter:      cert.NotAfter,
+			ExpiresInDays: int(cert.NotAfter.Sub(now).Hours() / 24),
+		})
+	}
+	d.TLS = details
+
+	check.Status, check.Message = models.CheckPassed, details.Version+", "+details.CipherSuite
+	switch {
+	case verifyErr != nil:
+		// WHY STILL REACHABLE: The connection works; requests fail on the
+		// certificate, which auth and latency will show
+		check.Status, check.Message = models.CheckFailed, verifyErr.Error()
+	case len(state.PeerCertificates) > 0 && state.PeerCertificates[0].NotAfter.Sub(now) < certExpiryWarning:
+		check.Status = models.CheckWarning
+		check.Message = fmt.Sprintf("certificate expires in %d days (%s)",
+			details.Certificates[0].ExpiresInDays, state.PeerCertificates[0].NotAfter.UTC().Format(time.RFC3339))
+	}
+	d.Add(check)
+	return true
+}
+
+// authScheme names how the provider authenticates requests.
+func (s *SonyProvider) authScheme() string {
+	switch signer := s.Signer.(type) {
+	case nil:
+		if s.APIKey == "" {
+			return "none"
+		}
+		return "bearer"
+	case client.BearerSigner:
+		return "bearer"
+	case client.HMACSigner:
+		return "hmac"
+	case client.SigV4Signer:
+		return "sigv4"
+	case *client.OAuth2Signer:
+		return "oauth2"
+	default:
+		return fmt.Sprintf("%T", signer)
+	}
+}
+
+// diagnoseAuth sends an authenticated request that any valid credentials
+// may make and classifies the answer.
+func (s *SonyProvider) diagnoseAuth(ctx context.Context, d *models.ConnectionDiagnosis) {
+	started := time.Now()
+	check := models.ConnectionCheck{Name: models.DiagnoseAuth}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.BaseURL+"/devices?page_size=1", nil)
+	if err != nil {
+		check.Status, check.Message = models.CheckFailed, err.Error()
+		d.Add(check)
+		return
+	}
+	resp, err := client.Do(s.HTTPClient, s.authorize(req))
+	check.DurationMS = diagnoseMillis(time.Since(started))
+	if err != nil {
+		check.Status, check.Message = models.CheckFailed, err.Error()
+		d.Add(check)
+		return
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		check.Status = models.CheckFailed
+		check.Message = fmt.Sprintf("credentials rejected (%s auth, status %d): %s", d.AuthScheme, resp.StatusCode, strings.TrimSpace(string(body)))
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		check.Status, check.Message = models.CheckPassed, fmt.Sprintf("credentials accepted (%s auth)", d.AuthScheme)
+		if d.AuthScheme == "none" {
+			check.Status, check.Message = models.CheckWarning, "the vendor accepted a request without credentials"
+		}
+	default:
+		check.Status = models.CheckWarning
+		check.Message = fmt.Sprintf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	d.Add(check)
+}
+
+// diagnoseLatency times samples health requests.
+func (s *SonyProvider) diagnoseLatency(ctx context.Context, d *models.ConnectionDiagnosis, samples int) {
+	stats := &models.LatencyStats{SamplesMS: []float64{}}
+	var lastErr string
+	for i := 0; i < samples; i++ {
+		started := time.Now()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.BaseURL+"/health", nil)
+		var resp *http.Response
+		if err == nil {
+			resp, err = client.Do(s.HTTPClient, s.authorize(req))
+		}
+		elapsed := diagnoseMillis(time.Since(started))
+		if err != nil {
+			stats.Failures++
+			lastErr = err.Error()
+			continue
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			stats.Failures++
+			lastErr = fmt.Sprintf("status %d", resp.StatusCode)
+			continue
+		}
+		stats.SamplesMS = append(stats.SamplesMS, elapsed)
+	}
+
+	check := models.ConnectionCheck{Name: models.DiagnoseLatency, Status: models.CheckPassed}
+	if n := len(stats.SamplesMS); n > 0 {
+		sorted := append([]float64(nil), stats.SamplesMS...)
+		sort.Float64s(sorted)
+		total := 0.0
+		for _, ms := range sorted {
+			total += ms
+		}
+		stats.MinMS, stats.MaxMS, stats.P50MS = sorted[0], sorted[n-1], sorted[(n-1)/2]
+		stats.AvgMS = math.Round(total/float64(n)*100) / 100
+		check.DurationMS = math.Round(total*100) / 100
+		check.Message = fmt.Sprintf("%d/%d requests, avg %.2fms, max %.2fms", n, samples, stats.AvgMS, stats.MaxMS)
+	}
+	switch {
+	case stats.Failures == samples:
+		check.Status, check.Message = models.CheckFailed, fmt.Sprintf("all %d requests failed: %s", samples, lastErr)
+	case stats.Failures > 0:
+		check.Status = models.CheckWarning
+		check.Message += fmt.Sprintf("; %d failed: %s", stats.Failures, lastErr)
+	}
+	d.Latency = stats
+	d.Add(check)
+}
@@ -0,0 +1,30 @@
+package provider
+
+import (
+	"math"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// buildSonyMetrics collects the health readings of a Sony device into
+// Status.Metrics names. Returns nil if Sony reported none.
+//
+// WHY A RATE: Sony counts dropped frames since the stream started, so a
+// long-running stream with a few old drops would look worse than a fresh
+// one dropping frames right now.
+func buildSonyMetrics(response *models.SonyDeviceResponse) map[string]float64 {
+	metrics := make(map[string]float64)
+	if m := response.HealthMetrics; m != nil {
+		metrics[models.MetricTemperature] = m.Temperature
+		metrics[models.MetricCPU] = m.CPUUsagePercent
+		metrics[models.MetricMemory] = m.MemoryUsagePercent
+	}
+	if s := response.StreamStatus; s != nil && s.IsStreaming && s.UptimeSeconds > 0 {
+		rate := float64(s.DroppedFrames) / (float64(s.UptimeSeconds) / 60)
+		metrics[models.MetricDroppedFrames] = math.Round(rate*100) / 100
+	}
+	if len(metrics) == 0 {
+		return nil
+	}
+	return metrics
+}
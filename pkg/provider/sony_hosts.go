@@ -0,0 +1,16 @@
+package provider
+
+import "net/url"
+
+// =============================================================================
+// API HOSTS (optional HostLister capability)
+// =============================================================================
+
+// APIHosts returns the host of the configured Sony base URL.
+func (s *SonyProvider) APIHosts() []string {
+	u, err := url.Parse(s.BaseURL)
+	if err != nil || u.Host == "" {
+		return nil
+	}
+	return []string{u.Host}
+}
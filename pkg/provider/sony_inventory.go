@@ -0,0 +1,70 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/client"
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// PAGED INVENTORY (optional PagedDiscoverer capability)
+// =============================================================================
+// GET /devices?page_size=N&page_token=T returns one page of the inventory
+// and a next_page_token. Tokens are opaque; Sony answers 400 for one it
+// no longer accepts.
+// =============================================================================
+
+// DiscoverPage lists one page of Sony devices.
+func (s *SonyProvider) DiscoverPage(ctx context.Context, cursor string, limit int) (*models.DiscoveryPage, error) {
+	query := url.Values{}
+	query.Set("page_size", strconv.Itoa(limit))
+	if cursor != "" {
+		query.Set("page_token", cursor)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.BaseURL+"/devices?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req = s.authorize(req)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.DoWithRetry(ctx, req, 3)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute Sony API request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Sony API response: %w", err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusBadRequest:
+		return nil, fmt.Errorf("Sony API rejected page token: %s: %w", string(respBody), ErrInvalidRequest)
+	default:
+		return nil, fmt.Errorf("Sony API returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var list models.SonyDeviceList
+	if err := json.Unmarshal(respBody, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse Sony API response: %w", err)
+	}
+	page := &models.DiscoveryPage{
+		Devices:    make([]models.DiscoveredDevice, 0, len(list.Devices)),
+		NextCursor: list.NextPageToken,
+		Total:      list.TotalCount,
+	}
+	for i := range list.Devices {
+		page.Devices = append(page.Devices, s.buildDiscoveredDevice(&list.Devices[i]))
+	}
+	logger.Debugf("sony: discovered page of %d devices (next %q)", len(page.Devices), page.NextCursor)
+	return page, nil
+}
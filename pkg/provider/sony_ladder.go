@@ -0,0 +1,76 @@
+package provider
+
+import (
+	"fmt"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// ABR LADDER → STREAM PROFILES
+// =============================================================================
+// Sony's newer system cameras encode several profiles of the stream at
+// once (stream_config.profiles); each spec.ladder rendition becomes one.
+// Older models encode a single stream, and Sony encoders have no AV1, so
+// a ladder they can't encode is refused before anything is sent: the
+// device would otherwise stream only its first profile.
+// =============================================================================
+
+// sonyLadderCodecs are the codecs Sony encodes stream profiles in.
+var sonyLadderCodecs = map[string]bool{"H.264": true, "H.265": true}
+
+// checkSonyLadder returns an ErrInvalidRequest error if the resource's
+// Sony model can't encode its ladder.
+func (s *SonyProvider) checkSonyLadder(resource *models.ForgeResource) error {
+	ladder := resource.Spec.Ladder
+	if len(ladder) == 0 {
+		return nil
+	}
+	model := s.extractStringConfig(resource, "sony_model", "HDC-5500")
+	limit := models.SonyStreamProfileLimits[model]
+	if limit < 2 {
+		return fmt.Errorf("Sony %s encodes a single stream; a ladder needs a multi-profile model: %w", model, ErrInvalidRequest)
+	}
+	if len(ladder) > limit {
+		return fmt.Errorf("Sony %s encodes at most %d stream profiles (ladder has %d): %w", model, limit, len(ladder), ErrInvalidRequest)
+	}
+	for i, rendition := range ladder {
+		if codec := s.mapCodecToSony(rendition.EffectiveCodec(resource.Spec)); codec != "" && !sonyLadderCodecs[codec] {
+			return fmt.Errorf("ladder[%d]: Sony stream profiles can't use %s (H.264 or H.265 only): %w", i, codec, ErrInvalidRequest)
+		}
+	}
+	return nil
+}
+
+// buildSonyProfiles maps the ladder to stream profiles.
+func (s *SonyProvider) buildSonyProfiles(spec models.ResourceSpec) []models.SonyStreamProfile {
+	profiles := make([]models.SonyStreamProfile, 0, len(spec.Ladder))
+	for _, rendition := range spec.Ladder {
+		profiles = append(profiles, models.SonyStreamProfile{
+			Name:       rendition.RenditionName(),
+			Resolution: s.mapResolutionToSony(rendition.Resolution),
+			Bitrate:    int(rendition.Bitrate / 1000), // bps → kbps
+			FrameRate:  rendition.EffectiveFrameRate(spec),
+			Codec:      s.mapCodecToSony(rendition.EffectiveCodec(spec)),
+		})
+	}
+	return profiles
+}
+
+// ladderFromSonyProfiles is the inverse of buildSonyProfiles.
+func (s *SonyProvider) ladderFromSonyProfiles(profiles []models.SonyStreamProfile) []models.Rendition {
+	if len(profiles) == 0 {
+		return nil
+	}
+	ladder := make([]models.Rendition, 0, len(profiles))
+	for _, profile := range profiles {
+		ladder = append(ladder, models.Rendition{
+			Name:       profile.Name,
+			Resolution: s.mapResolutionFromSony(profile.Resolution),
+			Bitrate:    int64(profile.Bitrate) * 1000, // kbps → bps
+			Codec:      profile.Codec,
+			FrameRate:  profile.FrameRate,
+		})
+	}
+	return ladder
+}
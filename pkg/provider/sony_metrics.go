@@ -0,0 +1,64 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/client"
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// LIVE METRICS (optional MetricsReporter capability)
+// =============================================================================
+// GET /devices/{id} carries stream_status (bitrate in kbps, dropped
+// frames, uptime) and health_metrics. Read builds a status from them;
+// Metrics hands them over as they are, health report included, for
+// pollers that only want the numbers.
+// =============================================================================
+
+// Metrics reads a Sony device's current metrics.
+func (s *SonyProvider) Metrics(ctx context.Context, vendorID string) (*models.ResourceMetrics, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.BaseURL+"/devices/"+vendorID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req = s.authorize(req)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.DoWithRetry(ctx, req, 3)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute Sony API request: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Sony API response: %w", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("device %s: %w", vendorID, ErrNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Sony API returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var device models.SonyDeviceResponse
+	if err := json.Unmarshal(respBody, &device); err != nil {
+		return nil, fmt.Errorf("failed to parse Sony API response: %w", err)
+	}
+	metrics := &models.ResourceMetrics{
+		VendorID:    vendorID,
+		CollectedAt: s.Clock.Now(),
+		Metrics:     buildSonyMetrics(&device),
+		Health:      device.HealthMetrics,
+	}
+	if stream := device.StreamStatus; stream != nil {
+		metrics.CurrentBitrate = int64(stream.CurrentBitrate) * 1000 // kbps → bps
+		metrics.DroppedFrames = stream.DroppedFrames
+		metrics.UptimeSeconds = stream.UptimeSeconds
+	}
+	return metrics, nil
+}
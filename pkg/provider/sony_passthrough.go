@@ -0,0 +1,75 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/client"
+)
+
+// =============================================================================
+// RAW PASSTHROUGH (optional Passthrough capability)
+// =============================================================================
+// Forwards an arbitrary request to the Sony API with the provider's API
+// key, so engineers can poke the vendor without holding the credentials.
+//
+// WHY NO RETRIES: The request may not be idempotent, and the engineer
+// wants to see exactly what one call returns.
+// =============================================================================
+
+// Passthrough sends a raw request to the Sony API.
+func (s *SonyProvider) Passthrough(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	target, err := s.passthroughURL(path)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+	req = s.authorize(req)
+	req.Header.Set("Accept", "application/json")
+	if len(body) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(s.HTTPClient, req)
+	if err != nil {
+		return nil, fmt.Errorf("passthrough request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// passthroughURL resolves path against BaseURL.
+//
+// WHY STRICT: The API key must only ever be sent to the vendor. Absolute
+// URLs ("https://elsewhere/"), scheme-relative URLs ("//elsewhere/") and
+// ".." segments that climb out of the base path are rejected.
+func (s *SonyProvider) passthroughURL(path string) (string, error) {
+	if !strings.HasPrefix(path, "/") {
+		return "", fmt.Errorf("%w: path must start with /", ErrInvalidRequest)
+	}
+	ref, err := url.Parse(path)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+	if ref.Scheme != "" || ref.Host != "" || ref.User != nil {
+		return "", fmt.Errorf("%w: path must be relative to the vendor API", ErrInvalidRequest)
+	}
+	for _, segment := range strings.Split(ref.Path, "/") {
+		if segment == ".." {
+			return "", fmt.Errorf("%w: path must not contain ..", ErrInvalidRequest)
+		}
+	}
+
+	target := strings.TrimRight(s.BaseURL, "/") + ref.EscapedPath()
+	if ref.RawQuery != "" {
+		target += "?" + ref.RawQuery
+	}
+	return target, nil
+}
@@ -0,0 +1,36 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// STOP / START (optional PowerController capability)
+// =============================================================================
+// A stopped Sony device keeps its configuration but stops encoding and
+// streaming; Sony reports it as "inactive".
+// =============================================================================
+
+// Stop stops a Sony device.
+func (s *SonyProvider) Stop(ctx context.Context, vendorID string) (*models.ResourceStatus, error) {
+	return s.powerCall(ctx, vendorID, "stop")
+}
+
+// Start starts a stopped Sony device.
+func (s *SonyProvider) Start(ctx context.Context, vendorID string) (*models.ResourceStatus, error) {
+	return s.powerCall(ctx, vendorID, "start")
+}
+
+func (s *SonyProvider) powerCall(ctx context.Context, vendorID, action string) (*models.ResourceStatus, error) {
+	var device models.SonyDeviceResponse
+	path := "/devices/" + url.PathEscape(vendorID) + "/" + action
+	if err := s.doDeviceCall(ctx, http.MethodPost, path, nil, &device); err != nil {
+		return nil, fmt.Errorf("failed to %s device: %w", action, err)
+	}
+	return s.buildResourceStatus(&device), nil
+}
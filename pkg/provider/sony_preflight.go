@@ -0,0 +1,94 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// PREFLIGHT (optional Preflighter, DestinationProber and CapacityReporter
+// capabilities)
+// =============================================================================
+// Sony validates a device request against the account quota, the site
+// network and the models available in the region without provisioning
+// anything (POST /devices/preflight), probes stream destinations from the
+// site network (POST /network/probe) and reports the account's usage
+// (GET /account/capacity).
+// =============================================================================
+
+// sonyPreflightChecks maps Sony check names to Forge check names.
+var sonyPreflightChecks = map[string]string{
+	"quota":   models.PreflightQuota,
+	"network": models.PreflightReachability,
+	"model":   models.PreflightModel,
+}
+
+// Preflight asks Sony whether the device for resource can be created.
+func (s *SonyProvider) Preflight(ctx context.Context, resource *models.ForgeResource) (*models.PreflightResult, error) {
+	// WHY LOCAL: Sony's preflight doesn't check stream profiles
+	if err := s.checkSonyLadder(resource); err != nil {
+		return &models.PreflightResult{Checks: []models.PreflightCheck{{Name: models.PreflightModel, Message: err.Error()}}}, nil
+	}
+	var response models.SonyPreflightResponse
+	if err := s.doDeviceCall(ctx, http.MethodPost, "/devices/preflight", s.buildSonyRequest(resource), &response); err != nil {
+		return nil, fmt.Errorf("failed to run preflight: %w", err)
+	}
+
+	result := &models.PreflightResult{Checks: make([]models.PreflightCheck, 0, len(response.Checks))}
+	for _, check := range response.Checks {
+		name, known := sonyPreflightChecks[check.Check]
+		if !known {
+			name = check.Check
+		}
+		result.Checks = append(result.Checks, models.PreflightCheck{
+			Name:    name,
+			Passed:  check.Status == "pass",
+			Message: check.Detail,
+		})
+	}
+	// WHY: A "not ready" answer without a failed check must still fail
+	if !response.Ready && result.Passed() {
+		result.Checks = append(result.Checks, models.PreflightCheck{Name: "vendor", Message: "Sony reported the device as not ready"})
+	}
+	return result, nil
+}
+
+// ProbeDestination asks Sony to probe a stream destination from the site
+// network the device will stream from.
+func (s *SonyProvider) ProbeDestination(ctx context.Context, streamURL string) (*models.DestinationProbe, error) {
+	var response models.SonyProbeResponse
+	if err := s.doDeviceCall(ctx, http.MethodPost, "/network/probe", models.SonyProbeRequest{URL: streamURL}, &response); err != nil {
+		return nil, fmt.Errorf("failed to probe destination: %w", err)
+	}
+	return &models.DestinationProbe{
+		URL:       streamURL,
+		Address:   response.Address,
+		Method:    response.Method,
+		From:      "sony",
+		Reachable: response.Reachable,
+		RTTMillis: response.RTTMs,
+		Message:   response.Detail,
+	}, nil
+}
+
+// Capacity reports the account's devices against its quota and the site's
+// DHCP pool.
+func (s *SonyProvider) Capacity(ctx context.Context) (*models.VendorCapacity, error) {
+	var response models.SonyCapacityResponse
+	if err := s.doDeviceCall(ctx, http.MethodGet, "/account/capacity", nil, &response); err != nil {
+		return nil, fmt.Errorf("failed to get account capacity: %w", err)
+	}
+	return &models.VendorCapacity{
+		Devices:     response.Devices,
+		DeviceQuota: response.DeviceQuota,
+		AddressPool: &models.AddressPool{
+			Range:  response.DHCPPool.Start + "-" + response.DHCPPool.End,
+			Size:   response.DHCPPool.Size,
+			Leased: response.DHCPPool.Leased,
+		},
+		AddressesInUse: response.Addresses,
+	}, nil
+}
@@ -8,12 +8,21 @@ import (
 	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Zhichengu1/mock-control-plane/pkg/client"
+	"github.com/Zhichengu1/mock-control-plane/pkg/clock"
+	"github.com/Zhichengu1/mock-control-plane/pkg/labels"
+	"github.com/Zhichengu1/mock-control-plane/pkg/logging"
 	"github.com/Zhichengu1/mock-control-plane/pkg/models"
 )
 
+// logger is shared by all providers in this package. Raise it to debug
+// with PUT /admin/loglevel {"component": "providers", "level": "debug"}
+// to see vendor payloads and responses.
+var logger = logging.For(logging.ComponentProviders)
+
 // =============================================================================
 // SONY PROVIDER
 // =============================================================================
@@ -34,6 +43,10 @@ import (
 // SonyProvider implements VendorProvider for Sony device management.
 // It encapsulates all Sony-specific API communication logic.
 type SonyProvider struct {
+	// Name is the provider's registered name; outbound requests are
+	// checked against its egress rule (see client.EgressPolicy)
+	Name string
+
 	// BaseURL is the root URL for Sony's API (e.g., "https://api.sony.example.com/v1")
 	BaseURL string
 
@@ -41,9 +54,17 @@ type SonyProvider struct {
 	// Sent in the Authorization header as "Bearer <APIKey>"
 	APIKey string
 
+	// Signer authenticates requests instead of APIKey when set (HMAC,
+	// OAuth2 client credentials, ...; see client.Signer)
+	Signer client.Signer
+
 	// HTTPClient is a reusable HTTP client with connection pooling.
 	// Using a shared client improves performance through connection reuse.
 	HTTPClient *http.Client
+
+	// Clock stamps health check times. Defaults to the real clock;
+	// tests inject a clock.Fake for deterministic timestamps.
+	Clock clock.Clock
 }
 
 // NewSonyProvider creates a new SonyProvider instance with the given configuration.
@@ -61,6 +82,7 @@ type SonyProvider struct {
 //	provider := NewSonyProvider("https://api.sony.example.com", "secret-key")
 func NewSonyProvider(baseURL, apiKey string) *SonyProvider {
 	return &SonyProvider{
+		Name:    "sony",
 		BaseURL: baseURL,
 		APIKey:  apiKey,
 		HTTPClient: &http.Client{
@@ -68,7 +90,20 @@ func NewSonyProvider(baseURL, apiKey string) *SonyProvider {
 			// 30 seconds is generous for most API calls.
 			Timeout: 30 * time.Second,
 		},
+		Clock: clock.Real{},
+	}
+}
+
+// authorize attaches the provider's credentials and name to req;
+// client.DoWithRetry and client.Do sign every attempt with them and check
+// it against the egress policy.
+func (s *SonyProvider) authorize(req *http.Request) *http.Request {
+	var signer client.Signer = client.BearerSigner{Token: s.APIKey}
+	if s.Signer != nil {
+		signer = s.Signer
 	}
+	ctx := client.WithProvider(req.Context(), s.Name)
+	return req.WithContext(client.WithSigner(ctx, signer))
 }
 
 // =============================================================================
@@ -110,6 +145,9 @@ func (s *SonyProvider) Create(ctx context.Context, resource *models.ForgeResourc
 	// - Extracting vendor-specific config values
 	// - Building Sony-specific nested structures
 	// =========================================================================
+	if err := s.checkSonyLadder(resource); err != nil {
+		return nil, err
+	}
 	sonyRequest := s.buildSonyRequest(resource)
 
 	// =========================================================================
@@ -124,6 +162,7 @@ func (s *SonyProvider) Create(ctx context.Context, resource *models.ForgeResourc
 		// not a runtime issue. Wrap with context for debugging.
 		return nil, fmt.Errorf("failed to marshal Sony request: %w", err)
 	}
+	logger.Debugf("sony: create request for %s: %s", resource.ID, requestBody)
 
 	// =========================================================================
 	// STEP 3: Create HTTP POST request
@@ -143,11 +182,11 @@ func (s *SonyProvider) Create(ctx context.Context, resource *models.ForgeResourc
 	// STEP 4: Add required headers
 	// =========================================================================
 	// Set Content-Type to indicate we're sending JSON.
-	// Set Authorization with our API key for authentication.
+	// Attach our credentials (signed per attempt, see authorize).
 	// Some APIs may require additional headers (X-Request-ID, etc.)
 	// =========================================================================
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+s.APIKey)
+	req = s.authorize(req)
 	req.Header.Set("Accept", "application/json")
 	// Optional: Add request tracing header for debugging
 	req.Header.Set("X-Forge-Resource-ID", resource.ID)
@@ -185,6 +224,7 @@ func (s *SonyProvider) Create(ctx context.Context, resource *models.ForgeResourc
 	// Check for non-success status codes
 	// 201 Created is the expected success code for resource creation
 	// We also accept 200 OK as some APIs use that instead
+	logger.Debugf("sony: create response (status %d): %s", resp.StatusCode, respBody)
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("Sony API returned status %d: %s", resp.StatusCode, string(respBody))
 	}
@@ -219,7 +259,9 @@ func (s *SonyProvider) Create(ctx context.Context, resource *models.ForgeResourc
 // - resource.Name → DeviceName
 // - resource.Spec.Config["sony_model"] → Model
 // - resource.Spec.Resolution/Bitrate/etc → StreamConfig
+// - resource.Spec.Ladder → StreamConfig.Profiles (see sony_ladder.go)
 // - resource.Spec.Config (other keys) → Settings
+// - resource.Annotations → Metadata (next to the forge_* tracking keys)
 func (s *SonyProvider) buildSonyRequest(resource *models.ForgeResource) *models.SonyDeviceRequest {
 	// Initialize the request with basic fields
 	request := &models.SonyDeviceRequest{
@@ -233,6 +275,15 @@ func (s *SonyProvider) buildSonyRequest(resource *models.ForgeResource) *models.
 		},
 	}
 
+	// Annotations ride along in Sony's device metadata, so whoever looks
+	// at the device in Sony's console sees the ticket it belongs to.
+	// WHY forge_* WINS: The controller finds its devices by these keys
+	for key, value := range resource.Annotations {
+		if _, reserved := request.Metadata[key]; !reserved {
+			request.Metadata[key] = value
+		}
+	}
+
 	// Extract IP address if configured
 	if ip := s.extractStringConfig(resource, "ip_address", ""); ip != "" {
 		request.IPAddress = ip
@@ -254,6 +305,7 @@ func (s *SonyProvider) buildSonyRequest(resource *models.ForgeResource) *models.
 	if resource.Spec.Codec != "" {
 		request.Settings["codec"] = resource.Spec.Codec
 	}
+	addSonySyncSettings(request, resource.Spec)
 
 	// Build StreamConfig if streaming is configured
 	if resource.Spec.StreamURL != "" {
@@ -267,6 +319,22 @@ func (s *SonyProvider) buildSonyRequest(resource *models.ForgeResource) *models.
 			Codec:          s.mapCodecToSony(resource.Spec.Codec),
 			LatencyMode:    s.mapLatencyModeToSony(resource.Spec.LatencyMode),
 		}
+		// SRT-specific settings (validated up front: latency is required for SRT)
+		if request.StreamConfig.Protocol == "SRT" {
+			request.StreamConfig.SRTLatency = s.extractIntConfig(resource, "srt_latency", 0)
+			request.StreamConfig.SRTPassphrase = s.extractStringConfig(resource, "srt_passphrase", "")
+		}
+		if len(resource.Spec.Ladder) > 0 {
+			request.StreamConfig.Profiles = s.buildSonyProfiles(resource.Spec)
+			// The main stream is the top profile unless the spec sets its own
+			top := request.StreamConfig.Profiles[0]
+			if resource.Spec.Bitrate == 0 {
+				request.StreamConfig.Bitrate = top.Bitrate
+			}
+			if resource.Spec.Resolution == "" {
+				request.StreamConfig.Resolution = top.Resolution
+			}
+		}
 	}
 
 	// Build RecordingConfig if recording is enabled
@@ -336,13 +404,16 @@ func (s *SonyProvider) buildResourceStatus(response *models.SonyDeviceResponse)
 	case "maintenance":
 		status.Phase = "Updating"
 		status.HealthStatus = "degraded"
+	case "deleting":
+		status.Phase = "Terminating"
+		status.HealthStatus = "unknown"
 	default:
 		status.Phase = "Unknown"
 		status.HealthStatus = "unknown"
 	}
 
-	status.LastHealthCheck = time.Now()
-	status.LastSuccessfulOperation = time.Now()
+	status.LastHealthCheck = s.Clock.Now()
+	status.LastSuccessfulOperation = status.LastHealthCheck
 
 	// Extract streaming metrics if available
 	if response.StreamStatus != nil {
@@ -354,9 +425,64 @@ func (s *SonyProvider) buildResourceStatus(response *models.SonyDeviceResponse)
 		}
 	}
 
+	status.Endpoints = buildSonyEndpoints(response)
+	status.Metrics = buildSonyMetrics(response)
+	applySonySyncConditions(status, response.SyncStatus)
+
 	return status
 }
 
+// buildSonyEndpoints collects the device's addresses: its IP (control) and
+// its stream destinations (output). Live destination status is preferred;
+// without it the configured destination is reported.
+func buildSonyEndpoints(response *models.SonyDeviceResponse) []models.Endpoint {
+	var endpoints []models.Endpoint
+	if response.IPAddress != "" {
+		endpoints = append(endpoints, models.Endpoint{
+			Type:    models.EndpointTypeIP,
+			Address: response.IPAddress,
+			Role:    models.EndpointRoleControl,
+		})
+	}
+
+	if response.StreamStatus != nil && len(response.StreamStatus.DestinationStatus) > 0 {
+		for _, dest := range response.StreamStatus.DestinationStatus {
+			connected := dest.Connected
+			endpoints = append(endpoints, models.Endpoint{
+				Type:      models.EndpointTypeURL,
+				Address:   dest.URL,
+				Role:      models.EndpointRoleOutput,
+				Protocol:  streamProtocol(dest.URL),
+				Connected: &connected,
+			})
+		}
+	} else if cfg := response.Configuration; cfg != nil && cfg.StreamConfig != nil && cfg.StreamConfig.DestinationURL != "" {
+		endpoints = append(endpoints, models.Endpoint{
+			Type:     models.EndpointTypeURL,
+			Address:  cfg.StreamConfig.DestinationURL,
+			Role:     models.EndpointRoleOutput,
+			Protocol: valueOrDefault(streamProtocol(cfg.StreamConfig.DestinationURL), strings.ToLower(cfg.StreamConfig.Protocol)),
+		})
+	}
+	return endpoints
+}
+
+// valueOrDefault returns v, or def if v is empty.
+func valueOrDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+// streamProtocol returns the lowercase scheme of a stream URL.
+func streamProtocol(u string) string {
+	if i := strings.Index(u, "://"); i > 0 {
+		return strings.ToLower(u[:i])
+	}
+	return ""
+}
+
 // =============================================================================
 // READ OPERATION
 // =============================================================================
@@ -396,7 +522,7 @@ func (s *SonyProvider) Read(ctx context.Context, vendorID string) (*models.Resou
 	// =========================================================================
 	// STEP 3: Add authentication headers
 	// =========================================================================
-	req.Header.Set("Authorization", "Bearer "+s.APIKey)
+	req = s.authorize(req)
 	req.Header.Set("Accept", "application/json")
 
 	// =========================================================================
@@ -416,6 +542,8 @@ func (s *SonyProvider) Read(ctx context.Context, vendorID string) (*models.Resou
 		return nil, fmt.Errorf("failed to read Sony API response: %w", err)
 	}
 
+	logger.Debugf("sony: read %s response (status %d): %s", vendorID, resp.StatusCode, respBody)
+
 	// Handle 404 Not Found - device may have been deleted externally
 	if resp.StatusCode == http.StatusNotFound {
 		return &models.ResourceStatus{
@@ -474,12 +602,16 @@ func (s *SonyProvider) Update(ctx context.Context, resource *models.ForgeResourc
 	// =========================================================================
 	// STEP 2: Build the update request
 	// =========================================================================
+	if err := s.checkSonyLadder(resource); err != nil {
+		return nil, err
+	}
 	sonyRequest := s.buildSonyRequest(resource)
 
 	requestBody, err := json.Marshal(sonyRequest)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal Sony request: %w", err)
 	}
+	logger.Debugf("sony: update request for %s: %s", resource.Status.VendorID, requestBody)
 
 	// =========================================================================
 	// STEP 3: Create HTTP PATCH request
@@ -495,7 +627,7 @@ func (s *SonyProvider) Update(ctx context.Context, resource *models.ForgeResourc
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+s.APIKey)
+	req = s.authorize(req)
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("X-Forge-Resource-ID", resource.ID)
 
@@ -559,7 +691,7 @@ func (s *SonyProvider) Delete(ctx context.Context, vendorID string) error {
 		return fmt.Errorf("failed to create HTTP request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+s.APIKey)
+	req = s.authorize(req)
 
 	// =========================================================================
 	// STEP 2: Execute with retries
@@ -575,10 +707,12 @@ func (s *SonyProvider) Delete(ctx context.Context, vendorID string) error {
 	// =========================================================================
 	// 204 No Content - successful deletion
 	// 200 OK - some APIs return this with a body
+	// 202 Accepted - teardown started; see TeardownProgress (sony_teardown.go)
 	// 404 Not Found - already deleted, treat as success (idempotent)
 	// =========================================================================
+	logger.Debugf("sony: delete %s returned status %d", vendorID, resp.StatusCode)
 	switch resp.StatusCode {
-	case http.StatusNoContent, http.StatusOK, http.StatusNotFound:
+	case http.StatusNoContent, http.StatusOK, http.StatusAccepted, http.StatusNotFound:
 		return nil // Success (or already deleted)
 	default:
 		respBody, _ := io.ReadAll(resp.Body)
@@ -615,17 +749,18 @@ func (s *SonyProvider) HealthCheck(ctx context.Context) error {
 		return fmt.Errorf("failed to create HTTP request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+s.APIKey)
+	req = s.authorize(req)
 
 	// =========================================================================
 	// STEP 2: Execute request (no retries for health check)
 	// =========================================================================
-	// We use the HTTPClient directly instead of DoWithRetry because:
+	// We make a single attempt instead of using DoWithRetry because:
 	// - Health checks should be fast
 	// - Retries would hide transient issues
 	// - We want immediate feedback on connectivity
+	// client.Do still records the call in the audit trail.
 	// =========================================================================
-	resp, err := s.HTTPClient.Do(req)
+	resp, err := client.Do(s.HTTPClient, req)
 	if err != nil {
 		return fmt.Errorf("Sony API health check failed: %w", err)
 	}
@@ -642,6 +777,167 @@ func (s *SonyProvider) HealthCheck(ctx context.Context) error {
 	return nil
 }
 
+// =============================================================================
+// DISCOVERY (optional Discoverer capability)
+// =============================================================================
+
+// Discover lists every device registered in Sony's system and reverse-maps
+// each one into a DiscoveredDevice.
+//
+// Flow:
+//  1. GET /devices
+//  2. For each device, rebuild a ResourceSpec from its stored configuration
+//  3. Carry over the forge_* metadata tags so the controller can tell
+//     managed devices from unmanaged ones
+func (s *SonyProvider) Discover(ctx context.Context) ([]models.DiscoveredDevice, error) {
+	url := s.BaseURL + "/devices"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req = s.authorize(req)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.DoWithRetry(ctx, req, 3)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute Sony API request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Sony API response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Sony API returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var list models.SonyDeviceList
+	if err := json.Unmarshal(respBody, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse Sony API response: %w", err)
+	}
+
+	devices := make([]models.DiscoveredDevice, 0, len(list.Devices))
+	for i := range list.Devices {
+		devices = append(devices, s.buildDiscoveredDevice(&list.Devices[i]))
+	}
+	logger.Debugf("sony: discovered %d devices", len(devices))
+	return devices, nil
+}
+
+// buildDiscoveredDevice reverse-maps a Sony device into Forge terms.
+// This is the inverse of buildSonyRequest: every field that buildSonyRequest
+// writes is read back into the equivalent Spec field or Config key.
+func (s *SonyProvider) buildDiscoveredDevice(device *models.SonyDeviceResponse) models.DiscoveredDevice {
+	discovered := models.DiscoveredDevice{
+		VendorType: "sony",
+		VendorID:   device.DeviceID,
+		Type:       "camera", // Sony's device API only manages cameras
+		Status:     *s.buildResourceStatus(device),
+	}
+
+	cfg := device.Configuration
+	if cfg == nil {
+		// Nothing to reverse-map; propose a bare resource with the model only
+		discovered.Name = device.DeviceID
+		discovered.Spec = models.ResourceSpec{
+			VendorType: "sony",
+			Config:     map[string]interface{}{"sony_model": device.Model},
+		}
+		return discovered
+	}
+
+	discovered.Name = cfg.DeviceName
+	if cfg.Metadata != nil {
+		discovered.ForgeID = cfg.Metadata["forge_id"]
+		discovered.Namespace = cfg.Metadata["forge_namespace"]
+		if t := cfg.Metadata["forge_type"]; t != "" {
+			discovered.Type = t
+		}
+		// WHY SKIP INVALID KEYS: Devices registered outside Forge may carry
+		// any metadata; only valid keys can become annotations
+		for key, value := range cfg.Metadata {
+			if strings.HasPrefix(key, "forge_") || labels.ValidateKey(key) != nil {
+				continue
+			}
+			if discovered.Annotations == nil {
+				discovered.Annotations = make(map[string]string)
+			}
+			discovered.Annotations[key] = value
+		}
+	}
+
+	spec := models.ResourceSpec{
+		VendorType: "sony",
+		Config:     map[string]interface{}{"sony_model": cfg.Model},
+	}
+	if cfg.IPAddress != "" {
+		spec.Config["ip_address"] = cfg.IPAddress
+	}
+	if cfg.Port > 0 {
+		spec.Config["port"] = cfg.Port
+	}
+
+	if v := cfg.Settings["resolution"]; v != "" {
+		spec.Resolution = s.mapResolutionFromSony(v)
+	}
+	if v := cfg.Settings["frame_rate"]; v != "" {
+		if fps, err := strconv.ParseFloat(v, 64); err == nil {
+			spec.FrameRate = fps
+		}
+	}
+	if v := cfg.Settings["codec"]; v != "" {
+		spec.Codec = v
+	}
+	readSonySyncSettings(&spec, cfg.Settings)
+
+	if sc := cfg.StreamConfig; sc != nil && sc.Enabled {
+		spec.StreamURL = sc.DestinationURL
+		spec.Bitrate = int64(sc.Bitrate) * 1000 // kbps → bps
+		spec.LatencyMode = s.mapLatencyModeFromSony(sc.LatencyMode)
+		if spec.FrameRate == 0 {
+			spec.FrameRate = sc.FrameRate
+		}
+		if sc.SRTLatency > 0 {
+			spec.Config["srt_latency"] = sc.SRTLatency
+		}
+		if sc.SRTPassphrase != "" {
+			spec.Config["srt_passphrase"] = sc.SRTPassphrase
+		}
+		spec.Ladder = s.ladderFromSonyProfiles(sc.Profiles)
+	}
+
+	if rc := cfg.RecordingConfig; rc != nil && rc.Enabled {
+		spec.RecordingEnabled = true
+		spec.RecordingPath = rc.StoragePath
+		spec.RetentionDays = rc.RetentionDays
+		spec.Config["recording_format"] = rc.Format
+		spec.Config["recording_quality"] = rc.Quality
+	}
+
+	if nc := cfg.NetworkConfig; nc != nil {
+		spec.Config["network_interface"] = nc.PrimaryInterface
+		if nc.VLANID > 0 {
+			spec.Config["vlan_id"] = nc.VLANID
+		}
+		if nc.MTU > 0 {
+			spec.Config["mtu"] = nc.MTU
+		}
+	}
+
+	if tc := cfg.TallyConfig; tc != nil && tc.Enabled {
+		spec.Config["tally_enabled"] = true
+		spec.Config["tally_color"] = tc.Color
+		spec.Config["tally_protocol"] = tc.ControlProtocol
+		if tc.ControlAddress != "" {
+			spec.Config["tally_address"] = tc.ControlAddress
+		}
+	}
+
+	discovered.Spec = spec
+	return discovered
+}
+
 // =============================================================================
 // HELPER METHODS
 // =============================================================================
@@ -718,6 +1014,25 @@ func (s *SonyProvider) mapResolutionToSony(resolution string) string {
 	}
 }
 
+// mapResolutionFromSony converts Sony pixel dimensions back to Forge's
+// friendly names. Unknown values pass through unchanged.
+func (s *SonyProvider) mapResolutionFromSony(resolution string) string {
+	switch resolution {
+	case "720x480":
+		return "SD"
+	case "1280x720":
+		return "HD"
+	case "1920x1080":
+		return "FHD"
+	case "3840x2160":
+		return "4K"
+	case "7680x4320":
+		return "8K"
+	default:
+		return resolution
+	}
+}
+
 // mapCodecToSony converts Forge codec names to Sony's format.
 func (s *SonyProvider) mapCodecToSony(codec string) string {
 	switch codec {
@@ -742,6 +1057,20 @@ func (s *SonyProvider) mapLatencyModeToSony(mode string) string {
 	}
 }
 
+// mapLatencyModeFromSony is the inverse of mapLatencyModeToSony.
+func (s *SonyProvider) mapLatencyModeFromSony(mode string) string {
+	switch mode {
+	case "ultra_low":
+		return "low"
+	case "low":
+		return "normal"
+	case "normal":
+		return "high"
+	default:
+		return ""
+	}
+}
+
 // detectStreamProtocol determines the streaming protocol from a URL.
 // This is used when the user provides a stream URL without explicit protocol config.
 func (s *SonyProvider) detectStreamProtocol(url string) string {
@@ -0,0 +1,131 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// PTZ AND PRESETS (optional CameraController capability)
+// =============================================================================
+// Sony camera heads are controlled through per-device sub-resources:
+//
+//   GET  /devices/{id}/ptz                     → current position
+//   POST /devices/{id}/ptz                     → move / home / stop
+//   GET  /devices/{id}/presets                 → saved presets
+//   PUT  /devices/{id}/presets/{n}             → save current position
+//   POST /devices/{id}/presets/{n}/recall      → move to preset
+//
+// Unit translation (Forge → Sony):
+//   zoom 0..1   → zoom_position 0..16384
+//   speed 0..1  → speed 1..24 (0 = camera default)
+//   preset 1..N → preset_number 0..N-1
+// =============================================================================
+
+// Sony VISCA ranges.
+const (
+	sonyMaxZoomPosition = 16384
+	sonyMaxPTZSpeed     = 24
+)
+
+// GetPTZ returns the camera's current position.
+func (s *SonyProvider) GetPTZ(ctx context.Context, vendorID string) (*models.PTZPosition, error) {
+	var state models.SonyPTZState
+	path := "/devices/" + url.PathEscape(vendorID) + "/ptz"
+	if err := s.doDeviceCall(ctx, http.MethodGet, path, nil, &state); err != nil {
+		return nil, fmt.Errorf("failed to read PTZ position: %w", err)
+	}
+	return buildPTZPosition(state), nil
+}
+
+// MovePTZ executes a PTZ command.
+func (s *SonyProvider) MovePTZ(ctx context.Context, vendorID string, cmd models.PTZCommand) (*models.PTZPosition, error) {
+	sonyReq := models.SonyPTZRequest{
+		Command:     cmd.Action,
+		PanDegrees:  cmd.Pan,
+		TiltDegrees: cmd.Tilt,
+	}
+	if cmd.Zoom != nil {
+		position := int(math.Round(*cmd.Zoom * sonyMaxZoomPosition))
+		sonyReq.ZoomPosition = &position
+	}
+	if cmd.Speed > 0 {
+		sonyReq.Speed = 1 + int(math.Round(cmd.Speed*(sonyMaxPTZSpeed-1)))
+	}
+
+	var state models.SonyPTZState
+	path := "/devices/" + url.PathEscape(vendorID) + "/ptz"
+	if err := s.doDeviceCall(ctx, http.MethodPost, path, sonyReq, &state); err != nil {
+		return nil, fmt.Errorf("failed to move camera: %w", err)
+	}
+	return buildPTZPosition(state), nil
+}
+
+// ListPresets returns the camera's saved presets.
+func (s *SonyProvider) ListPresets(ctx context.Context, vendorID string) ([]models.CameraPreset, error) {
+	var list models.SonyPresetList
+	path := "/devices/" + url.PathEscape(vendorID) + "/presets"
+	if err := s.doDeviceCall(ctx, http.MethodGet, path, nil, &list); err != nil {
+		return nil, fmt.Errorf("failed to list presets: %w", err)
+	}
+
+	presets := make([]models.CameraPreset, 0, len(list.Presets))
+	for _, preset := range list.Presets {
+		presets = append(presets, buildCameraPreset(preset))
+	}
+	return presets, nil
+}
+
+// SavePreset stores the current position in a preset slot.
+func (s *SonyProvider) SavePreset(ctx context.Context, vendorID string, req models.PresetRequest) (*models.CameraPreset, error) {
+	var preset models.SonyPreset
+	body := map[string]string{"name": req.Name}
+	if err := s.doDeviceCall(ctx, http.MethodPut, sonyPresetPath(vendorID, req.Number), body, &preset); err != nil {
+		return nil, fmt.Errorf("failed to save preset %d: %w", req.Number, err)
+	}
+	saved := buildCameraPreset(preset)
+	return &saved, nil
+}
+
+// RecallPreset moves the camera to a saved preset.
+func (s *SonyProvider) RecallPreset(ctx context.Context, vendorID string, number int) (*models.PTZPosition, error) {
+	var state models.SonyPTZState
+	if err := s.doDeviceCall(ctx, http.MethodPost, sonyPresetPath(vendorID, number)+"/recall", nil, &state); err != nil {
+		return nil, fmt.Errorf("failed to recall preset %d: %w", number, err)
+	}
+	return buildPTZPosition(state), nil
+}
+
+// sonyPresetPath maps a 1-based Forge preset number to Sony's 0-based slot.
+func sonyPresetPath(vendorID string, number int) string {
+	return "/devices/" + url.PathEscape(vendorID) + "/presets/" + strconv.Itoa(number-1)
+}
+
+// buildPTZPosition maps a Sony position into Forge units.
+func buildPTZPosition(state models.SonyPTZState) *models.PTZPosition {
+	return &models.PTZPosition{
+		Pan:  state.PanDegrees,
+		Tilt: state.TiltDegrees,
+		Zoom: float64(state.ZoomPosition) / sonyMaxZoomPosition,
+	}
+}
+
+// buildCameraPreset maps a Sony preset into Forge terms.
+func buildCameraPreset(preset models.SonyPreset) models.CameraPreset {
+	out := models.CameraPreset{
+		Number:   preset.PresetNumber + 1,
+		Name:     preset.Name,
+		Position: *buildPTZPosition(preset.Position),
+	}
+	if t, err := time.Parse(time.RFC3339, preset.SavedAt); err == nil {
+		out.SavedAt = t
+	}
+	return out
+}
@@ -0,0 +1,172 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/client"
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// RECORDING SESSIONS (optional Recorder capability)
+// =============================================================================
+// Sony devices record through a per-device sub-resource:
+//
+//   POST   /devices/{id}/recordings              → start (409 if already recording)
+//   POST   /devices/{id}/recordings/{rid}/stop   → stop
+//   GET    /devices/{id}/recordings              → list all sessions
+//   DELETE /devices/{id}/recordings/{rid}        → delete file (409 if active)
+// =============================================================================
+
+// StartRecording begins a recording session on the device.
+func (s *SonyProvider) StartRecording(ctx context.Context, vendorID string, req models.RecordingRequest) (*models.RecordingSession, error) {
+	sonyReq := models.SonyRecordingRequest{
+		Name:          req.Name,
+		StoragePath:   req.StoragePath,
+		Format:        req.Format,
+		Quality:       req.Quality,
+		RetentionDays: req.RetentionDays,
+	}
+	// Same defaults as RecordingConfig in buildSonyRequest
+	if sonyReq.Format == "" {
+		sonyReq.Format = "MXF"
+	}
+	if sonyReq.Quality == "" {
+		sonyReq.Quality = "production"
+	}
+
+	var recording models.SonyRecording
+	path := "/devices/" + url.PathEscape(vendorID) + "/recordings"
+	if err := s.doDeviceCall(ctx, http.MethodPost, path, sonyReq, &recording); err != nil {
+		return nil, fmt.Errorf("failed to start recording: %w", err)
+	}
+	return s.buildRecordingSession(&recording), nil
+}
+
+// StopRecording ends an active recording session.
+func (s *SonyProvider) StopRecording(ctx context.Context, vendorID, sessionID string) (*models.RecordingSession, error) {
+	var recording models.SonyRecording
+	path := "/devices/" + url.PathEscape(vendorID) + "/recordings/" + url.PathEscape(sessionID) + "/stop"
+	if err := s.doDeviceCall(ctx, http.MethodPost, path, nil, &recording); err != nil {
+		return nil, fmt.Errorf("failed to stop recording: %w", err)
+	}
+	return s.buildRecordingSession(&recording), nil
+}
+
+// ListRecordings returns every recording session on the device.
+func (s *SonyProvider) ListRecordings(ctx context.Context, vendorID string) ([]models.RecordingSession, error) {
+	var list models.SonyRecordingList
+	path := "/devices/" + url.PathEscape(vendorID) + "/recordings"
+	if err := s.doDeviceCall(ctx, http.MethodGet, path, nil, &list); err != nil {
+		return nil, fmt.Errorf("failed to list recordings: %w", err)
+	}
+
+	sessions := make([]models.RecordingSession, 0, len(list.Recordings))
+	for i := range list.Recordings {
+		sessions = append(sessions, *s.buildRecordingSession(&list.Recordings[i]))
+	}
+	return sessions, nil
+}
+
+// DeleteRecording removes a stopped recording and its file.
+func (s *SonyProvider) DeleteRecording(ctx context.Context, vendorID, sessionID string) error {
+	path := "/devices/" + url.PathEscape(vendorID) + "/recordings/" + url.PathEscape(sessionID)
+	if err := s.doDeviceCall(ctx, http.MethodDelete, path, nil, nil); err != nil {
+		return fmt.Errorf("failed to delete recording: %w", err)
+	}
+	return nil
+}
+
+// doDeviceCall sends a JSON request to the Sony API and decodes the
+// response into out (if non-nil). Vendor 404/409 are wrapped in
+// ErrNotFound/ErrConflict.
+func (s *SonyProvider) doDeviceCall(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		logger.Debugf("sony: %s %s payload: %s", method, path, string(payload))
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, s.BaseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req = s.authorize(req)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.DoWithRetry(ctx, req, 3)
+	if err != nil {
+		return fmt.Errorf("failed to execute Sony API request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return fmt.Errorf("Sony API: %s: %w", readSonyError(resp), ErrNotFound)
+	case http.StatusConflict:
+		return fmt.Errorf("Sony API: %s: %w", readSonyError(resp), ErrConflict)
+	}
+	if err := client.ValidateResponse(resp); err != nil {
+		return err
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse Sony response: %w", err)
+	}
+	return nil
+}
+
+// readSonyError extracts the "error" message from a Sony error response.
+func readSonyError(resp *http.Response) string {
+	var body struct {
+		Error string `json:"error"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if json.Unmarshal(data, &body) == nil && body.Error != "" {
+		return body.Error
+	}
+	return fmt.Sprintf("HTTP %d", resp.StatusCode)
+}
+
+// buildRecordingSession maps a Sony recording into Forge terms.
+// Sony status "completed" becomes RecordingStopped.
+func (s *SonyProvider) buildRecordingSession(recording *models.SonyRecording) *models.RecordingSession {
+	session := &models.RecordingSession{
+		ID:              recording.RecordingID,
+		Name:            recording.Name,
+		State:           models.RecordingActive,
+		StoragePath:     recording.StoragePath,
+		FilePath:        recording.FilePath,
+		Format:          recording.Format,
+		DurationSeconds: recording.DurationSeconds,
+		SizeBytes:       recording.SizeBytes,
+		RetentionDays:   recording.RetentionDays,
+	}
+	if recording.Status != "recording" {
+		session.State = models.RecordingStopped
+	}
+	if t, err := time.Parse(time.RFC3339, recording.StartedAt); err == nil {
+		session.StartedAt = t
+	}
+	if t, err := time.Parse(time.RFC3339, recording.StoppedAt); err == nil {
+		session.StoppedAt = t
+	}
+	return session
+}
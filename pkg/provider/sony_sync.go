@@ -0,0 +1,141 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// SONY SYNC AND TIMING
+// =============================================================================
+// Spec.TimecodeSource, NTPServer and GenlockReference travel to Sony as
+// device settings:
+//
+//   timecode_source   "INT", "EXT-LTC", "EXT-VITC", "NTP", "PTP"
+//   ntp_server        host name or IP, unchanged
+//   genlock_reference "INT", "BB" (blackburst), "TRI" (tri-level), "PTP"
+//
+// Sony reports lock state in sync_status, which becomes one condition per
+// configured reference. A Running device that lost a lock is degraded:
+// it still produces pictures, but they won't cut cleanly with others.
+// =============================================================================
+
+// sonyTimecodeSources maps Forge timecode sources to Sony codes.
+var sonyTimecodeSources = map[string]string{
+	"internal": "INT",
+	"ltc":      "EXT-LTC",
+	"vitc":     "EXT-VITC",
+	"ntp":      "NTP",
+	"ptp":      "PTP",
+}
+
+// sonyGenlockReferences maps Forge genlock references to Sony codes.
+var sonyGenlockReferences = map[string]string{
+	"internal":   "INT",
+	"blackburst": "BB",
+	"tri-level":  "TRI",
+	"ptp":        "PTP",
+}
+
+// mapToSony looks up a Forge value (case-insensitively); unknown values
+// pass through unchanged.
+func mapToSony(codes map[string]string, value string) string {
+	if code, ok := codes[strings.ToLower(value)]; ok {
+		return code
+	}
+	return value
+}
+
+// mapFromSony is the inverse of mapToSony.
+func mapFromSony(codes map[string]string, code string) string {
+	for value, c := range codes {
+		if c == code {
+			return value
+		}
+	}
+	return code
+}
+
+// addSonySyncSettings adds the spec's sync settings to request.
+func addSonySyncSettings(request *models.SonyDeviceRequest, spec models.ResourceSpec) {
+	if spec.TimecodeSource != "" {
+		request.Settings["timecode_source"] = mapToSony(sonyTimecodeSources, spec.TimecodeSource)
+	}
+	if spec.NTPServer != "" {
+		request.Settings["ntp_server"] = spec.NTPServer
+	}
+	if spec.GenlockReference != "" {
+		request.Settings["genlock_reference"] = mapToSony(sonyGenlockReferences, spec.GenlockReference)
+	}
+}
+
+// readSonySyncSettings sets the spec's sync fields from Sony settings
+// (the inverse of addSonySyncSettings, used by discovery).
+func readSonySyncSettings(spec *models.ResourceSpec, settings map[string]string) {
+	if v := settings["timecode_source"]; v != "" {
+		spec.TimecodeSource = mapFromSony(sonyTimecodeSources, v)
+	}
+	spec.NTPServer = settings["ntp_server"]
+	if v := settings["genlock_reference"]; v != "" {
+		spec.GenlockReference = mapFromSony(sonyGenlockReferences, v)
+	}
+}
+
+// buildSonySyncConditions turns Sony's sync status into conditions, one
+// per configured reference.
+func buildSonySyncConditions(sync *models.SonySyncStatus) []models.Condition {
+	if sync == nil {
+		return nil
+	}
+	var conditions []models.Condition
+	if sync.TimecodeSource != "" {
+		source := mapFromSony(sonyTimecodeSources, sync.TimecodeSource)
+		conditions = append(conditions, lockCondition(models.ConditionTimecodeLocked, sync.TimecodeLocked,
+			"Locked", "Unlocked", "timecode source "+source))
+	}
+	if sync.NTPServer != "" {
+		condition := lockCondition(models.ConditionClockSynchronized, sync.NTPSynced,
+			"Synchronized", "Unsynchronized", "NTP server "+sync.NTPServer)
+		if sync.NTPSynced {
+			condition.Message += fmt.Sprintf(" (offset %.1f ms)", sync.NTPOffsetMs)
+		}
+		conditions = append(conditions, condition)
+	}
+	if sync.GenlockReference != "" {
+		reference := mapFromSony(sonyGenlockReferences, sync.GenlockReference)
+		conditions = append(conditions, lockCondition(models.ConditionGenlockLocked, sync.GenlockLocked,
+			"Locked", "Unlocked", reference+" reference"))
+	}
+	return conditions
+}
+
+// lockCondition builds a condition that is True when ok.
+func lockCondition(conditionType string, ok bool, okReason, failReason, subject string) models.Condition {
+	if ok {
+		return models.Condition{Type: conditionType, Status: models.ConditionTrue, Reason: okReason,
+			Message: okReason + " to " + subject}
+	}
+	return models.Condition{Type: conditionType, Status: models.ConditionFalse, Reason: failReason,
+		Message: "Not " + strings.ToLower(okReason) + " to " + subject}
+}
+
+// applySonySyncConditions sets status.Conditions and degrades a Running
+// device that lost a lock.
+func applySonySyncConditions(status *models.ResourceStatus, sync *models.SonySyncStatus) {
+	status.Conditions = buildSonySyncConditions(sync)
+	if status.Phase != "Running" {
+		return
+	}
+	var lost []string
+	for _, condition := range status.Conditions {
+		if condition.Status == models.ConditionFalse {
+			lost = append(lost, condition.Message)
+		}
+	}
+	if len(lost) > 0 {
+		status.HealthStatus = "degraded"
+		status.HealthCheckMessage = strings.Join(lost, "; ")
+	}
+}
@@ -0,0 +1,37 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// TEARDOWN PROGRESS (optional TeardownTracker capability)
+// =============================================================================
+// Sony accepts a DELETE with 202 when the device can't be released at
+// once; it then reports status "deleting" with teardown_progress until
+// the device disappears (404).
+// =============================================================================
+
+// TeardownProgress reports how far the deletion of a Sony device got.
+func (s *SonyProvider) TeardownProgress(ctx context.Context, vendorID string) (*models.TeardownProgress, error) {
+	var device models.SonyDeviceResponse
+	err := s.doDeviceCall(ctx, http.MethodGet, "/devices/"+url.PathEscape(vendorID), nil, &device)
+	if errors.Is(err, ErrNotFound) {
+		return &models.TeardownProgress{Done: true, Percent: 100, Message: "Device deleted"}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read device teardown: %w", err)
+	}
+	if device.Status != "deleting" {
+		// WHY ERROR: The device is still there and not going away; waiting
+		// longer won't help
+		return nil, fmt.Errorf("device %s is %q, not being deleted: %w", vendorID, device.Status, ErrConflict)
+	}
+	return &models.TeardownProgress{Percent: device.TeardownProgress, Message: device.Message}, nil
+}
@@ -0,0 +1,180 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/clock"
+)
+
+// =============================================================================
+// RATE LIMITING AND CONCURRENCY LIMITS
+// =============================================================================
+// This package provides the two primitives the controller uses for
+// back-pressure:
+//
+// 1. Limiter: a per-client fixed-window request counter. It answers "may
+//    this client make another request right now?" and reports how many
+//    requests remain and when the window resets, which the controller
+//    turns into X-RateLimit-* response headers.
+//
+// 2. Semaphore: a bounded pool of concurrency slots. The controller holds
+//    one per vendor so a burst of API traffic can't open hundreds of
+//    simultaneous connections to a vendor API with its own limits.
+//
+// Both are safe for concurrent use.
+// =============================================================================
+
+// Decision is the outcome of a Limiter check.
+type Decision struct {
+	// Allowed is false when the client has exhausted its quota.
+	Allowed bool
+
+	// Limit is the number of requests allowed per window.
+	Limit int
+
+	// Remaining is how many requests the client has left in this window.
+	Remaining int
+
+	// Reset is the time until the current window ends.
+	Reset time.Duration
+}
+
+// window tracks one client's usage in the current window.
+type window struct {
+	start time.Time
+	count int
+}
+
+// Limiter counts requests per key in fixed time windows.
+//
+// WHY FIXED WINDOWS: The X-RateLimit-Reset header tells clients exactly
+// when their quota refills. Fixed windows make that a single timestamp;
+// token buckets refill continuously and have no natural reset point.
+type Limiter struct {
+	limit   int
+	period  time.Duration
+	clock   clock.Clock
+	mu      sync.Mutex
+	windows map[string]*window
+}
+
+// NewLimiter creates a Limiter allowing limit requests per period per key.
+//
+// Example:
+//
+//	limiter := ratelimit.NewLimiter(600, time.Minute) // 10 req/s average
+func NewLimiter(limit int, period time.Duration) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		period:  period,
+		clock:   clock.Real{},
+		windows: make(map[string]*window),
+	}
+}
+
+// SetClock replaces the clock used for windows (tests use a clock.Fake).
+// Call it before the limiter is in use.
+func (l *Limiter) SetClock(c clock.Clock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.clock = c
+}
+
+// Limit returns the configured requests per window.
+func (l *Limiter) Limit() int { return l.limit }
+
+// Period returns the configured window length.
+func (l *Limiter) Period() time.Duration { return l.period }
+
+// Allow records a request for key and reports whether it is within quota.
+// Rejected requests don't consume quota.
+func (l *Limiter) Allow(key string) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.period {
+		w = &window{start: now}
+		l.windows[key] = w
+		// WHY PRUNE HERE: Keeps memory bounded by active clients without a
+		// background goroutine. Cheap because it only runs on new windows.
+		if len(l.windows) > 1024 {
+			l.pruneLocked(now)
+		}
+	}
+
+	decision := Decision{
+		Limit: l.limit,
+		Reset: w.start.Add(l.period).Sub(now),
+	}
+	if w.count >= l.limit {
+		decision.Allowed = false
+		decision.Remaining = 0
+		return decision
+	}
+	w.count++
+	decision.Allowed = true
+	decision.Remaining = l.limit - w.count
+	return decision
+}
+
+// pruneLocked drops windows that have expired. Caller must hold l.mu.
+func (l *Limiter) pruneLocked(now time.Time) {
+	for key, w := range l.windows {
+		if now.Sub(w.start) >= l.period {
+			delete(l.windows, key)
+		}
+	}
+}
+
+// =============================================================================
+// SEMAPHORE
+// =============================================================================
+
+// Semaphore limits the number of concurrent holders.
+type Semaphore struct {
+	slots chan struct{}
+}
+
+// NewSemaphore creates a Semaphore with the given number of slots.
+func NewSemaphore(capacity int) *Semaphore {
+	return &Semaphore{slots: make(chan struct{}, capacity)}
+}
+
+// Acquire blocks until a slot is free or ctx is done.
+// Every successful Acquire must be paired with a Release.
+func (s *Semaphore) Acquire(ctx context.Context) error {
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TryAcquire takes a slot only if one is immediately available.
+func (s *Semaphore) TryAcquire() bool {
+	select {
+	case s.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Release returns a slot to the pool.
+func (s *Semaphore) Release() {
+	<-s.slots
+}
+
+// Capacity returns the total number of slots.
+func (s *Semaphore) Capacity() int { return cap(s.slots) }
+
+// InUse returns the number of slots currently held.
+func (s *Semaphore) InUse() int { return len(s.slots) }
+
+// Available returns the number of free slots.
+func (s *Semaphore) Available() int { return cap(s.slots) - len(s.slots) }
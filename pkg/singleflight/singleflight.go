@@ -0,0 +1,148 @@
+package singleflight
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/clock"
+)
+
+// =============================================================================
+// CALL COALESCING
+// =============================================================================
+// A dashboard opening twenty panels on one camera fires twenty GETs for
+// the same resource at once, and every one of them used to read the
+// device from the vendor. A Group runs one call per key at a time; calls
+// for a key already in flight wait for it and share its result:
+//
+//	status, err, shared := reads.Do("sony/dev-42", func() (*Status, error) {
+//		return provider.Read(ctx, "dev-42")
+//	})
+//
+// With a Window, a successful result is also handed to calls that start
+// shortly after it finished (errors are never reused: the next call
+// retries). Forget drops a key after a write so the next read is fresh.
+//
+// Safe for concurrent use. Callers get the same value, so they must not
+// modify it (copy first).
+// =============================================================================
+
+// Stats counts what a Group did.
+type Stats struct {
+	// Calls is every Do
+	Calls int64 `json:"calls"`
+
+	// Executions ran the function
+	Executions int64 `json:"executions"`
+
+	// Coalesced joined a call already in flight
+	Coalesced int64 `json:"coalesced"`
+
+	// Reused got a result that finished within the window
+	Reused int64 `json:"reused"`
+
+	// InFlight is the number of calls running now
+	InFlight int `json:"in_flight"`
+}
+
+// call is one execution and everyone waiting for it.
+type call[T any] struct {
+	done     chan struct{}
+	val      T
+	err      error
+	finished time.Time
+}
+
+// Group coalesces calls by key.
+type Group[T any] struct {
+	// Window reuses a successful result for calls starting up to Window
+	// after it finished (0 = only calls in flight are joined)
+	Window time.Duration
+
+	// Clock measures the window (default: real time)
+	Clock clock.Clock
+
+	mu    sync.Mutex
+	calls map[string]*call[T]
+	stats Stats
+}
+
+func (g *Group[T]) clock() clock.Clock {
+	if g.Clock == nil {
+		return clock.Real{}
+	}
+	return g.Clock
+}
+
+// Do runs fn for key unless a call for key is in flight (or finished
+// within the window), in which case it returns that call's result.
+// shared is true if the result came from another caller's execution.
+func (g *Group[T]) Do(key string, fn func() (T, error)) (v T, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call[T])
+	}
+	g.stats.Calls++
+	if c, ok := g.calls[key]; ok {
+		select {
+		case <-c.done:
+			if c.err == nil && g.clock().Since(c.finished) < g.Window {
+				g.stats.Reused++
+				g.mu.Unlock()
+				return c.val, nil, true
+			}
+		default:
+			g.stats.Coalesced++
+			g.mu.Unlock()
+			<-c.done
+			return c.val, c.err, true
+		}
+	}
+	c := &call[T]{done: make(chan struct{})}
+	g.calls[key] = c
+	g.stats.Executions++
+	g.stats.InFlight++
+	g.mu.Unlock()
+
+	// WHY DEFER: Waiters must be released even if fn panics
+	defer func() {
+		g.mu.Lock()
+		g.stats.InFlight--
+		c.finished = g.clock().Now()
+		keep := c.err == nil && g.Window > 0
+		if !keep && g.calls[key] == c {
+			delete(g.calls, key)
+		}
+		close(c.done)
+		g.mu.Unlock()
+		if keep {
+			g.clock().AfterFunc(g.Window, func() { g.forget(key, c) })
+		}
+	}()
+	c.val, c.err = fn()
+	return c.val, c.err, false
+}
+
+// Forget drops key: the next Do runs fn even if a call is in flight or
+// finished within the window. Calls already waiting still get its result.
+func (g *Group[T]) Forget(key string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.calls, key)
+}
+
+// forget drops key if it still refers to c.
+func (g *Group[T]) forget(key string, c *call[T]) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+}
+
+// Stats returns the counters so far.
+func (g *Group[T]) Stats() Stats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.stats
+}
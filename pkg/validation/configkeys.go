@@ -0,0 +1,282 @@
+package validation
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// =============================================================================
+// CONFIG KEY REGISTRY
+// =============================================================================
+// spec.config is free-form, and providers read it leniently: a key they
+// don't know is ignored, a value of the wrong type is replaced by the
+// default. So {"sony_modle": "HDC-3500"} quietly gets an HDC-5500, and
+// {"tally_enabled": "true"} no tally. The registry lists the keys each
+// vendor understands, with their types and allowed values:
+//
+//   registry := validation.DefaultConfigKeys()
+//   registry.Register("sony", validation.ConfigKey{Name: "sony_iris", Type: validation.ConfigInteger})
+//   violations := registry.Check("sony", spec.Config)
+//
+// Common keys (vendor "") hold for every vendor. A vendor that was never
+// registered isn't checked for unknown keys, since its keys aren't
+// known; the common keys' types still are. Whether violations reject the
+// spec or only warn is the caller's policy.
+// =============================================================================
+
+// Config key types.
+const (
+	ConfigString  = "string"
+	ConfigInteger = "integer"
+	ConfigNumber  = "number"
+	ConfigBoolean = "boolean"
+)
+
+// Config key rules (Violation.Rule).
+const (
+	RuleConfigKeyUnknown = "config-key-unknown"
+	RuleConfigKeyType    = "config-key-type"
+	RuleConfigKeyValues  = "config-key-values"
+)
+
+// ConfigKey describes one spec.config key.
+type ConfigKey struct {
+	Name string `json:"name"`
+
+	// Type is ConfigString, ConfigInteger, ConfigNumber or ConfigBoolean.
+	// Integers and numbers may be numeric strings.
+	Type string `json:"type"`
+
+	// Values are the allowed values (compared case-insensitively); nil
+	// allows any value of the type
+	Values []string `json:"values,omitempty"`
+
+	// Default is what the provider uses when the key is absent
+	Default interface{} `json:"default,omitempty"`
+
+	Description string `json:"description,omitempty"`
+}
+
+// ConfigKeyRegistry maps vendors to the config keys they understand.
+//
+// WHY NOT LOCKED: Like Registry, it is filled at startup and only read
+// afterwards.
+type ConfigKeyRegistry struct {
+	// byVendor holds each vendor's keys; "" holds the common ones
+	byVendor map[string]map[string]ConfigKey
+}
+
+// NewConfigKeyRegistry returns an empty registry: nothing is checked.
+func NewConfigKeyRegistry() *ConfigKeyRegistry {
+	return &ConfigKeyRegistry{byVendor: make(map[string]map[string]ConfigKey)}
+}
+
+// DefaultConfigKeys returns a registry with the built-in keys
+// (CommonConfigKeys and VendorConfigKeys).
+func DefaultConfigKeys() *ConfigKeyRegistry {
+	registry := NewConfigKeyRegistry()
+	registry.Register("", CommonConfigKeys...)
+	for vendor, keys := range VendorConfigKeys {
+		registry.Register(vendor, keys...)
+	}
+	return registry
+}
+
+// Register adds keys to vendor ("" for every vendor). Registering a
+// vendor, even without keys, makes unknown keys of its specs violations.
+func (r *ConfigKeyRegistry) Register(vendor string, keys ...ConfigKey) {
+	if r.byVendor[vendor] == nil {
+		r.byVendor[vendor] = make(map[string]ConfigKey)
+	}
+	for _, key := range keys {
+		r.byVendor[vendor][key.Name] = key
+	}
+}
+
+// Vendors returns the registered vendors, sorted.
+func (r *ConfigKeyRegistry) Vendors() []string {
+	vendors := make([]string, 0, len(r.byVendor))
+	for vendor := range r.byVendor {
+		if vendor != "" {
+			vendors = append(vendors, vendor)
+		}
+	}
+	sort.Strings(vendors)
+	return vendors
+}
+
+// Known reports whether vendor's keys are registered.
+func (r *ConfigKeyRegistry) Known(vendor string) bool {
+	_, ok := r.byVendor[vendor]
+	return ok && vendor != ""
+}
+
+// Keys returns the keys vendor understands (its own and the common
+// ones), sorted by name.
+func (r *ConfigKeyRegistry) Keys(vendor string) []ConfigKey {
+	merged := make(map[string]ConfigKey)
+	for name, key := range r.byVendor[""] {
+		merged[name] = key
+	}
+	for name, key := range r.byVendor[vendor] {
+		merged[name] = key
+	}
+	keys := make([]ConfigKey, 0, len(merged))
+	for _, key := range merged {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Name < keys[j].Name })
+	return keys
+}
+
+// lookup returns vendor's key name (its own before the common one).
+func (r *ConfigKeyRegistry) lookup(vendor, name string) (ConfigKey, bool) {
+	if key, ok := r.byVendor[vendor][name]; ok {
+		return key, true
+	}
+	key, ok := r.byVendor[""][name]
+	return key, ok
+}
+
+// Check returns a violation for every key of config that vendor doesn't
+// understand, has the wrong type or a value that isn't allowed, sorted by
+// key (nil if all are fine).
+func (r *ConfigKeyRegistry) Check(vendor string, config map[string]interface{}) Violations {
+	names := make([]string, 0, len(config))
+	for name := range config {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var violations Violations
+	for _, name := range names {
+		field := "spec.config." + name
+		key, ok := r.lookup(vendor, name)
+		if !ok {
+			if r.Known(vendor) {
+				message := fmt.Sprintf("unknown config key %q for vendor %s", name, vendor)
+				if suggestion := r.closest(vendor, name); suggestion != "" {
+					message += fmt.Sprintf(" (did you mean %q?)", suggestion)
+				}
+				violations = append(violations, Violation{Field: field, Rule: RuleConfigKeyUnknown, Message: message})
+			}
+			continue
+		}
+		value := config[name]
+		if !hasConfigType(value, key.Type) {
+			violations = append(violations, Violation{Field: field, Rule: RuleConfigKeyType,
+				Message: fmt.Sprintf("%s must be a%s %s", name, article(key.Type), key.Type)})
+			continue
+		}
+		if len(key.Values) > 0 && !oneOfFold(fmt.Sprint(value), key.Values) {
+			violations = append(violations, Violation{Field: field, Rule: RuleConfigKeyValues,
+				Message: fmt.Sprintf("%s must be one of: %s", name, strings.Join(key.Values, ", "))})
+		}
+	}
+	return violations
+}
+
+// closest returns the key of vendor nearest to name within two edits
+// ("" if none), to point out typos.
+func (r *ConfigKeyRegistry) closest(vendor, name string) string {
+	best, bestDistance := "", 3
+	for _, key := range r.Keys(vendor) {
+		if d := editDistance(strings.ToLower(name), key.Name); d < bestDistance {
+			best, bestDistance = key.Name, d
+		}
+	}
+	return best
+}
+
+// hasConfigType reports whether value is of type keyType.
+func hasConfigType(value interface{}, keyType string) bool {
+	switch keyType {
+	case ConfigString:
+		_, ok := value.(string)
+		return ok
+	case ConfigInteger:
+		n, ok := number(value)
+		return ok && n == math.Trunc(n)
+	case ConfigNumber:
+		_, ok := number(value)
+		return ok
+	case ConfigBoolean:
+		_, ok := value.(bool)
+		return ok
+	}
+	return true
+}
+
+// oneOfFold reports whether value is one of values, ignoring case.
+func oneOfFold(value string, values []string) bool {
+	for _, allowed := range values {
+		if strings.EqualFold(value, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// article returns " n" before a vowel, "" otherwise ("an integer").
+func article(word string) string {
+	if word != "" && strings.ContainsRune("aeiou", rune(word[0])) {
+		return "n"
+	}
+	return ""
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+// CommonConfigKeys are understood whatever the vendor: the controller
+// itself reads them (network checks, uniqueness, SRT rules).
+var CommonConfigKeys = []ConfigKey{
+	{Name: "ip_address", Type: ConfigString, Description: "Fixed device address (IPv4 or IPv6); unique in the namespace"},
+	{Name: "subnet", Type: ConfigString, Description: "Network of ip_address in CIDR notation"},
+	{Name: "port", Type: ConfigInteger, Description: "Device control port"},
+	{Name: "vlan_id", Type: ConfigInteger, Description: "VLAN tag (1-4094); absent means untagged"},
+	{Name: "mtu", Type: ConfigInteger, Default: 1500, Description: "MTU (576-9216; 9000 for jumbo frames)"},
+	{Name: "srt_latency", Type: ConfigInteger, Description: "SRT latency in milliseconds (20-8000), for srt:// stream URLs"},
+	{Name: "srt_passphrase", Type: ConfigString, Description: "SRT encryption passphrase (10-79 characters)"},
+}
+
+// VendorConfigKeys are the keys each built-in vendor understands besides
+// the common ones. The mock vendor understands only those.
+var VendorConfigKeys = map[string][]ConfigKey{
+	"sony": {
+		{Name: "sony_model", Type: ConfigString, Default: "HDC-5500", Description: "Camera model (decides the encoding ladder)"},
+		{Name: "recording_format", Type: ConfigString, Values: []string{"MXF", "XAVC", "ProRes", "MP4"}, Default: "MXF", Description: "Recording container"},
+		{Name: "recording_quality", Type: ConfigString, Values: []string{"proxy", "production", "master"}, Default: "production", Description: "Recording quality preset"},
+		{Name: "network_interface", Type: ConfigString, Values: []string{"eth0", "eth1", "bond0"}, Default: "eth0", Description: "Interface the VLAN is configured on"},
+		{Name: "tally_enabled", Type: ConfigBoolean, Default: false, Description: "Enables tally control"},
+		{Name: "tally_color", Type: ConfigString, Values: []string{"red", "green", "yellow"}, Default: "red", Description: "Tally light color when active"},
+		{Name: "tally_protocol", Type: ConfigString, Values: []string{"TSL", "GPIO", "IP"}, Default: "TSL", Description: "How tally is controlled"},
+		{Name: "tally_address", Type: ConfigString, Description: "Tally control address (required for IP)"},
+	},
+	"aws": {
+		{Name: "aws_region", Type: ConfigString, Description: "MediaLive region"},
+		{Name: "aws_channel_class", Type: ConfigString, Values: []string{"STANDARD", "SINGLE_PIPELINE"}, Default: "SINGLE_PIPELINE", Description: "Channel redundancy"},
+		{Name: "aws_role_arn", Type: ConfigString, Description: "IAM role MediaLive assumes"},
+	},
+	"mock": {},
+}
@@ -0,0 +1,34 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/labels"
+)
+
+// MaxFinalizers caps the finalizers of one resource.
+const MaxFinalizers = 16
+
+// ValidateFinalizers checks finalizer names: label keys ("example.com/
+// archive"), each listed once.
+func ValidateFinalizers(finalizers []string) Violations {
+	var violations Violations
+	if len(finalizers) > MaxFinalizers {
+		violations = append(violations, Violation{Field: "finalizers", Rule: "finalizers-count",
+			Message: fmt.Sprintf("at most %d finalizers (got %d)", MaxFinalizers, len(finalizers))})
+	}
+	seen := make(map[string]bool, len(finalizers))
+	for i, name := range finalizers {
+		field := fmt.Sprintf("finalizers[%d]", i)
+		if err := labels.ValidateKey(name); err != nil {
+			violations = append(violations, Violation{Field: field, Rule: "finalizer-format",
+				Message: strings.Replace(err.Error(), "label key", "finalizer", 1)})
+		} else if seen[name] {
+			violations = append(violations, Violation{Field: field, Rule: "finalizer-duplicate",
+				Message: fmt.Sprintf("finalizer %q is listed twice", name)})
+		}
+		seen[name] = true
+	}
+	return violations
+}
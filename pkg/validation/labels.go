@@ -0,0 +1,56 @@
+package validation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/labels"
+)
+
+// ValidateLabels checks the format of every label key and value (the
+// number of labels is checked by CheckSize).
+func ValidateLabels(set map[string]string) Violations {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var violations Violations
+	for _, key := range keys {
+		if err := labels.ValidateKey(key); err != nil {
+			violations = append(violations, Violation{Field: "labels", Rule: "label-key-format", Message: err.Error()})
+			continue
+		}
+		if err := labels.ValidateValue(set[key]); err != nil {
+			violations = append(violations, Violation{Field: "labels." + key, Rule: "label-value-format", Message: err.Error()})
+		}
+	}
+	return violations
+}
+
+// reservedAnnotationPrefix marks the keys the controller itself stores in
+// vendor device metadata (forge_id, forge_namespace, ...).
+const reservedAnnotationPrefix = "forge_"
+
+// ValidateAnnotations checks annotation keys (the label key rules, minus
+// the reserved forge_ prefix). Values are free-form; their total size is
+// checked by CheckSize.
+func ValidateAnnotations(set map[string]string) Violations {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var violations Violations
+	for _, key := range keys {
+		if err := labels.ValidateKey(key); err != nil {
+			violations = append(violations, Violation{Field: "annotations", Rule: "annotation-key-format",
+				Message: strings.Replace(err.Error(), "label key", "annotation key", 1)})
+		} else if strings.HasPrefix(key, reservedAnnotationPrefix) {
+			violations = append(violations, Violation{Field: "annotations." + key, Rule: "annotation-key-reserved",
+				Message: fmt.Sprintf("annotation keys starting with %q are reserved for the controller", reservedAnnotationPrefix)})
+		}
+	}
+	return violations
+}
@@ -0,0 +1,193 @@
+package validation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// ABR LADDER CONSISTENCY
+// =============================================================================
+// A ladder (spec.ladder, see models.Rendition) only works if players can
+// step down it: every rung must cost less than the one above and be no
+// sharper, and its bitrate must suit its resolution and codec. A 4K rung
+// at 2 Mbps is unwatchable; an SD rung at 20 Mbps wastes the bandwidth the
+// ladder exists to save. Vendors accept both and the problem only shows
+// on viewers' screens, so the ladder is checked up front.
+// =============================================================================
+
+// Ladder limits.
+const (
+	MinRenditions = 2
+	MaxRenditions = 8
+)
+
+// LadderCodecs are the delivery codecs a rendition may use. Mezzanine
+// codecs (ProRes, DNxHD) are for contribution, not for players.
+var LadderCodecs = []string{"H.264", "H.265", "H.265/HEVC", "HEVC", "AV1"}
+
+// BitrateRange bounds a rendition's bitrate in bits per second.
+type BitrateRange struct {
+	Min int64 `json:"min"`
+	Max int64 `json:"max"`
+}
+
+// RenditionBitrates are the H.264 bitrate ranges by vertical resolution
+// (the smallest entry at or above the rendition's height applies). HEVC
+// and AV1 need about half the bitrate for the same picture, so their
+// minimum is halved.
+var RenditionBitrates = []struct {
+	Height int
+	BitrateRange
+}{
+	{480, BitrateRange{250_000, 4_000_000}},
+	{720, BitrateRange{800_000, 8_000_000}},
+	{1080, BitrateRange{1_500_000, 15_000_000}},
+	{2160, BitrateRange{6_000_000, 50_000_000}},
+	{4320, BitrateRange{20_000_000, 120_000_000}},
+}
+
+// ValidateLadder checks spec.ladder and returns every violation (nil if
+// there is no ladder or it is consistent).
+func ValidateLadder(spec models.ResourceSpec) Violations {
+	if len(spec.Ladder) == 0 {
+		return nil
+	}
+	var violations Violations
+	add := func(field, rule, format string, args ...interface{}) {
+		violations = append(violations, Violation{Field: field, Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(spec.Ladder) < MinRenditions || len(spec.Ladder) > MaxRenditions {
+		add("spec.ladder", "ladder-size", "ladder must have %d to %d renditions (has %d); use resolution and bitrate for a single output",
+			MinRenditions, MaxRenditions, len(spec.Ladder))
+	}
+	if spec.StreamURL == "" {
+		add("spec.stream_url", "ladder-requires-stream-url", "stream_url is required with a ladder: the renditions are encodings of the stream")
+	}
+	sourceHeight, hasSource := ResolutionHeight(spec.Resolution)
+	names := make(map[string]int, len(spec.Ladder))
+	var prev *models.Rendition
+	prevHeight := 0
+
+	for i := range spec.Ladder {
+		rendition := spec.Ladder[i]
+		field := fmt.Sprintf("spec.ladder[%d]", i)
+
+		name := rendition.RenditionName()
+		if first, dup := names[name]; dup && name != "" {
+			add(field+".name", "ladder-names-unique", "name %q is already used by spec.ladder[%d]", name, first)
+		} else {
+			names[name] = i
+		}
+
+		height, knownResolution := ResolutionHeight(rendition.Resolution)
+		if !knownResolution {
+			add(field+".resolution", "rendition-resolution", "resolution must be one of %s, or WIDTHxHEIGHT (e.g. 1280x720)", strings.Join(Resolutions, ", "))
+		}
+		if rendition.Bitrate < MinBitrate || rendition.Bitrate > MaxBitrate {
+			add(field+".bitrate", "rendition-bitrate", "bitrate must be between %d and %d bits per second", MinBitrate, MaxBitrate)
+		}
+		codec := rendition.EffectiveCodec(spec)
+		if codec != "" && !containsFold(LadderCodecs, codec) {
+			add(field+".codec", "rendition-codec", "%s is not a delivery codec; renditions must use one of: %s", codec, strings.Join(LadderCodecs, ", "))
+		}
+		if fps := rendition.FrameRate; fps != 0 && (fps < MinFrameRate || fps > MaxFrameRate) {
+			add(field+".frame_rate", "rendition-frame-rate", "frame_rate must be between %d and %d frames per second", MinFrameRate, MaxFrameRate)
+		}
+
+		// Supported combination of resolution, codec and bitrate
+		if knownResolution && rendition.Bitrate > 0 {
+			limits := RenditionBitrateRange(height, codec)
+			if rendition.Bitrate < limits.Min || rendition.Bitrate > limits.Max {
+				add(field+".bitrate", "rendition-bitrate-for-resolution", "%s %s needs %s to %s (has %s)",
+					rendition.Resolution, valueOr(codec, "H.264"), mbps(limits.Min), mbps(limits.Max), mbps(rendition.Bitrate))
+			}
+		}
+
+		// WHY NO UPSCALING: A rung sharper than the source (or faster than
+		// its frame rate) costs bandwidth without adding any picture
+		if knownResolution && hasSource && height > sourceHeight {
+			add(field+".resolution", "rendition-above-source", "%s is above the source resolution %s", rendition.Resolution, spec.Resolution)
+		}
+		if rendition.FrameRate > 0 && spec.FrameRate > 0 && rendition.FrameRate > spec.FrameRate {
+			add(field+".frame_rate", "rendition-above-source", "%g fps is above the source frame rate %g", rendition.FrameRate, spec.FrameRate)
+		}
+
+		// Step down from the rung above
+		if prev != nil {
+			if rendition.Bitrate >= prev.Bitrate {
+				add(field+".bitrate", "ladder-bitrate-order", "bitrates must descend: %s is not below %s of spec.ladder[%d]",
+					mbps(rendition.Bitrate), mbps(prev.Bitrate), i-1)
+			}
+			if knownResolution && prevHeight > 0 && height > prevHeight {
+				add(field+".resolution", "ladder-resolution-order", "resolutions must not increase: %s is above %s of spec.ladder[%d]",
+					rendition.Resolution, prev.Resolution, i-1)
+			}
+		}
+		prev = &spec.Ladder[i]
+		prevHeight = height
+	}
+	return violations
+}
+
+// RenditionBitrateRange returns the acceptable bitrates for a rendition of
+// the given height and codec.
+func RenditionBitrateRange(height int, codec string) BitrateRange {
+	limits := RenditionBitrates[len(RenditionBitrates)-1].BitrateRange
+	for _, tier := range RenditionBitrates {
+		if height <= tier.Height {
+			limits = tier.BitrateRange
+			break
+		}
+	}
+	if !strings.EqualFold(codec, "H.264") && codec != "" {
+		limits.Min /= 2
+	}
+	return limits
+}
+
+// ResolutionHeight returns the vertical resolution of a resolution name
+// ("FHD", "1080p") or WIDTHxHEIGHT value.
+func ResolutionHeight(resolution string) (int, bool) {
+	switch strings.ToUpper(resolution) {
+	case "SD", "480P":
+		return 480, true
+	case "HD", "720P":
+		return 720, true
+	case "FHD", "1080P":
+		return 1080, true
+	case "4K", "UHD", "2160P":
+		return 2160, true
+	case "8K", "4320P":
+		return 4320, true
+	}
+	if !pixelResolution.MatchString(resolution) {
+		return 0, false
+	}
+	height, err := strconv.Atoi(resolution[strings.IndexByte(resolution, 'x')+1:])
+	return height, err == nil
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func mbps(bps int64) string {
+	return strconv.FormatFloat(float64(bps)/1_000_000, 'f', -1, 64) + " Mbps"
+}
+
+func valueOr(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,192 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// SIZE AND COMPLEXITY LIMITS
+// =============================================================================
+// spec.config is free-form, so nothing stops a client from putting a
+// 5 MB blob or a 40-level nested object in it. Every byte ends up in the
+// store, in every history revision, and in the vendor payload. These
+// limits keep resources bounded:
+//
+//   config keys       how many top-level keys spec.config may have
+//   config key length characters per key, at any depth
+//   config value size encoded JSON bytes per top-level value
+//   config size       encoded JSON bytes of the whole config
+//   config depth      nesting depth (a flat {"k": "v"} is depth 1)
+//   name / namespace / type / depends_on   metadata sizes
+//   metadata.notes    characters of operational notes
+//   labels            how many labels a resource may have
+//   annotations       bytes of annotation keys and values together
+//
+// Violations say how big the offending field is and what the limit is, so
+// the client knows how much to trim.
+// =============================================================================
+
+// SizeLimits bounds the size of a resource. A zero field disables that
+// limit.
+type SizeLimits struct {
+	MaxConfigKeys       int `json:"max_config_keys"`
+	MaxConfigKeyLength  int `json:"max_config_key_length"`
+	MaxConfigValueBytes int `json:"max_config_value_bytes"`
+	MaxConfigBytes      int `json:"max_config_bytes"`
+	MaxConfigDepth      int `json:"max_config_depth"`
+	MaxNameLength       int `json:"max_name_length"`
+	MaxNamespaceLength  int `json:"max_namespace_length"`
+	MaxTypeLength       int `json:"max_type_length"`
+	MaxDependencies     int `json:"max_dependencies"`
+	MaxNotesLength      int `json:"max_notes_length"`
+	MaxLabels           int `json:"max_labels"`
+	MaxAnnotationBytes  int `json:"max_annotation_bytes"`
+}
+
+// DefaultSizeLimits are generous for real device settings and far below
+// anything that strains the store.
+//
+// WHY 253/63: The Kubernetes limits for object names and namespaces, so
+// Forge names can be reused as Kubernetes names.
+var DefaultSizeLimits = SizeLimits{
+	MaxConfigKeys:       64,
+	MaxConfigKeyLength:  128,
+	MaxConfigValueBytes: 4 << 10,  // 4 KiB
+	MaxConfigBytes:      64 << 10, // 64 KiB
+	MaxConfigDepth:      4,
+	MaxNameLength:       253,
+	MaxNamespaceLength:  63,
+	MaxTypeLength:       63,
+	MaxDependencies:     32,
+	MaxNotesLength:      4 << 10,
+	MaxLabels:           64,
+	MaxAnnotationBytes:  16 << 10, // 16 KiB
+}
+
+// CheckSize checks res against limits and returns every violation (nil if
+// it fits).
+func CheckSize(res *models.ForgeResource, limits SizeLimits) Violations {
+	var violations Violations
+	add := func(field, rule, format string, args ...interface{}) {
+		violations = append(violations, Violation{Field: field, Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// Metadata
+	checkLength := func(field, value string, limit int) {
+		if limit > 0 && len(value) > limit {
+			add(field, field+"-length", "%s is %d characters; at most %d are allowed", field, len(value), limit)
+		}
+	}
+	checkLength("name", res.Name, limits.MaxNameLength)
+	checkLength("namespace", res.Namespace, limits.MaxNamespaceLength)
+	checkLength("type", res.Type, limits.MaxTypeLength)
+	if limits.MaxDependencies > 0 && len(res.DependsOn) > limits.MaxDependencies {
+		add("depends_on", "depends-on-count", "%d dependencies listed; at most %d are allowed", len(res.DependsOn), limits.MaxDependencies)
+	}
+
+	if limits.MaxNotesLength > 0 && len(res.Metadata.Notes) > limits.MaxNotesLength {
+		add("metadata.notes", "notes-length", "notes are %d characters; at most %d are allowed (link longer documents with runbook_url)",
+			len(res.Metadata.Notes), limits.MaxNotesLength)
+	}
+
+	if limits.MaxLabels > 0 && len(res.Labels) > limits.MaxLabels {
+		add("labels", "label-count", "%d labels set; at most %d are allowed", len(res.Labels), limits.MaxLabels)
+	}
+	if limits.MaxAnnotationBytes > 0 {
+		size := 0
+		for key, value := range res.Annotations {
+			size += len(key) + len(value)
+		}
+		if size > limits.MaxAnnotationBytes {
+			add("annotations", "annotations-size", "annotations are %s; at most %s is allowed (link larger documents)",
+				formatBytes(size), formatBytes(limits.MaxAnnotationBytes))
+		}
+	}
+
+	// Config
+	config := res.Spec.Config
+	if limits.MaxConfigKeys > 0 && len(config) > limits.MaxConfigKeys {
+		add("spec.config", "config-key-count", "config has %d keys; at most %d are allowed", len(config), limits.MaxConfigKeys)
+	}
+	if limits.MaxConfigBytes > 0 {
+		if data, err := json.Marshal(config); err == nil && len(data) > limits.MaxConfigBytes {
+			add("spec.config", "config-size", "config is %s encoded; at most %s is allowed", formatBytes(len(data)), formatBytes(limits.MaxConfigBytes))
+		}
+	}
+
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		field := "spec.config." + key
+		if limits.MaxConfigValueBytes > 0 {
+			if data, err := json.Marshal(config[key]); err == nil && len(data) > limits.MaxConfigValueBytes {
+				add(field, "config-value-size",
+					"value is %s encoded; at most %s is allowed per value (store large data elsewhere and reference it by URL)",
+					formatBytes(len(data)), formatBytes(limits.MaxConfigValueBytes))
+			}
+		}
+		checkConfigTree(field, key, config[key], 1, limits, add)
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool { return violations[i].Field < violations[j].Field })
+	return violations
+}
+
+// checkConfigTree checks key lengths and nesting depth below a config
+// key. depth is the nesting level of key (1 = top level). Only the first
+// too-deep path of each branch is reported.
+func checkConfigTree(field, key string, value interface{}, depth int, limits SizeLimits,
+	add func(field, rule, format string, args ...interface{})) {
+	if limits.MaxConfigKeyLength > 0 && len(key) > limits.MaxConfigKeyLength {
+		add(field, "config-key-length", "key is %d characters; at most %d are allowed", len(key), limits.MaxConfigKeyLength)
+	}
+
+	var children map[string]interface{}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		children = v
+	case []interface{}:
+		// Array elements count as one level; objects inside are checked
+		children = make(map[string]interface{}, len(v))
+		for i, elem := range v {
+			children[fmt.Sprint(i)] = elem
+		}
+	default:
+		return
+	}
+	if len(children) == 0 {
+		return
+	}
+	if limits.MaxConfigDepth > 0 && depth >= limits.MaxConfigDepth {
+		add(field, "config-depth", "nested %d levels deep; at most %d levels are allowed", depth+1, limits.MaxConfigDepth)
+		return
+	}
+
+	names := make([]string, 0, len(children))
+	for name := range children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		checkConfigTree(field+"."+name, name, children[name], depth+1, limits, add)
+	}
+}
+
+// formatBytes renders n as "512 B", "4.0 KiB" or "1.5 MiB".
+func formatBytes(n int) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
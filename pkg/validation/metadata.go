@@ -0,0 +1,27 @@
+package validation
+
+import (
+	"net/url"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// ValidateMetadata checks the format of a resource's metadata (sizes are
+// checked by CheckSize).
+//
+// WHY ONLY HTTP(S): The link is rendered for on-call engineers to click;
+// "javascript:" or "file:" URLs have no business there
+func ValidateMetadata(meta models.ResourceMetadata) Violations {
+	if meta.RunbookURL == "" {
+		return nil
+	}
+	u, err := url.Parse(meta.RunbookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Violations{{
+			Field:   "metadata.runbook_url",
+			Rule:    "runbook-url-format",
+			Message: "runbook_url must be an absolute http or https URL",
+		}}
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package validation
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// NETWORK CONFIG
+// =============================================================================
+// Devices on a studio network are addressed through spec.config:
+//
+//   ip_address   "10.0.1.50", "fd00::50" (or "10.0.1.50/24", see below)
+//   subnet       "10.0.1.0/24" (optional; used for overlap detection only)
+//   port         1-65535
+//   vlan_id      1-4094 (leave out for untagged)
+//   mtu          576-9216 (at least 1280 with IPv6)
+//
+// The format and range rules are in SpecRules. NormalizeNetwork rewrites
+// the values into one canonical form first, so "010.000.001.050",
+// " 10.0.1.50" and "10.0.1.50" are the same address, and "1500" is 1500.
+//
+// CheckNetworkOverlap compares a device against the others the controller
+// manages: an address used twice on one VLAN, or one subnet spread over
+// two VLANs, breaks things on air in ways the vendor API never reports.
+// =============================================================================
+
+// Network config bounds.
+const (
+	MinPort    = 1
+	MaxPort    = 65535
+	MinVLANID  = 1
+	MaxVLANID  = 4094
+	MinMTU     = 576 // smallest datagram every IPv4 host must accept
+	MinIPv6MTU = 1280
+	MaxMTU     = 9216 // largest jumbo frame common switches forward
+)
+
+// networkIntKeys are the spec.config keys holding whole numbers.
+var networkIntKeys = []string{"port", "vlan_id", "mtu"}
+
+// NormalizeNetwork returns spec with its network config in canonical form:
+// addresses in their shortest standard notation, subnets as network
+// addresses, numeric strings as numbers. An ip_address in CIDR notation is
+// split into ip_address and subnet. Values that don't parse are left for
+// the rules to report. spec.Config is copied, never modified in place.
+func NormalizeNetwork(spec models.ResourceSpec) models.ResourceSpec {
+	if len(spec.Config) == 0 {
+		return spec
+	}
+	config := make(map[string]interface{}, len(spec.Config))
+	for k, v := range spec.Config {
+		config[k] = v
+	}
+
+	if s, ok := config["ip_address"].(string); ok {
+		s = strings.TrimSpace(s)
+		addr, prefix, isCIDR := strings.Cut(s, "/")
+		config["ip_address"] = s
+		if ip := parseIP(addr); ip != nil {
+			if !isCIDR {
+				config["ip_address"] = canonicalIP(ip)
+			} else if _, network, err := net.ParseCIDR(canonicalIP(ip) + "/" + prefix); err == nil {
+				config["ip_address"] = canonicalIP(ip)
+				if _, set := config["subnet"]; !set {
+					config["subnet"] = network.String()
+				}
+			}
+		}
+	}
+	if s, ok := config["subnet"].(string); ok {
+		if _, network, err := net.ParseCIDR(strings.TrimSpace(s)); err == nil {
+			config["subnet"] = network.String()
+		}
+	}
+	for _, key := range networkIntKeys {
+		if s, ok := config[key].(string); ok {
+			if n, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+				config[key] = n
+			}
+		}
+	}
+
+	spec.Config = config
+	return spec
+}
+
+// parseIP parses an address, also accepting zero-padded IPv4 octets
+// ("010.000.001.050"), which some device consoles display.
+func parseIP(s string) net.IP {
+	if ip := net.ParseIP(s); ip != nil {
+		return ip
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) != 4 {
+		return nil
+	}
+	octets := make([]byte, 4)
+	for i, part := range parts {
+		n, err := strconv.ParseUint(part, 10, 8)
+		if err != nil {
+			return nil
+		}
+		octets[i] = byte(n)
+	}
+	return net.IPv4(octets[0], octets[1], octets[2], octets[3])
+}
+
+// canonicalIP formats ip in its standard notation; IPv4-mapped IPv6
+// addresses become plain IPv4.
+func canonicalIP(ip net.IP) string {
+	if v4 := ip.To4(); v4 != nil {
+		return v4.String()
+	}
+	return ip.String()
+}
+
+// NetworkEndpoint is a managed device's place on the network.
+type NetworkEndpoint struct {
+	ResourceID string
+	Name       string
+
+	// VLAN is the vlan_id (0 = untagged)
+	VLAN int
+
+	IP     net.IP
+	Subnet *net.IPNet // nil if not declared
+}
+
+// EndpointOf extracts the network endpoint from a (normalized) spec.
+// Returns false if the spec has no valid ip_address.
+func EndpointOf(id, name string, spec models.ResourceSpec) (NetworkEndpoint, bool) {
+	addr, _ := spec.Config["ip_address"].(string)
+	ip := net.ParseIP(strings.TrimSpace(addr))
+	if ip == nil {
+		return NetworkEndpoint{}, false
+	}
+	endpoint := NetworkEndpoint{ResourceID: id, Name: name, IP: ip}
+	if vlan, ok := number(spec.Config["vlan_id"]); ok {
+		endpoint.VLAN = int(vlan)
+	}
+	if cidr, ok := spec.Config["subnet"].(string); ok {
+		if _, network, err := net.ParseCIDR(strings.TrimSpace(cidr)); err == nil {
+			endpoint.Subnet = network
+		}
+	}
+	return endpoint, true
+}
+
+func (e NetworkEndpoint) vlanName() string {
+	if e.VLAN == 0 {
+		return "the untagged network"
+	}
+	return fmt.Sprintf("VLAN %d", e.VLAN)
+}
+
+func (e NetworkEndpoint) owner() string {
+	return fmt.Sprintf("%s (%s)", e.ResourceID, e.Name)
+}
+
+// networksOverlap reports whether a and b share any address.
+func networksOverlap(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// CheckNetworkOverlap compares candidate with the other managed endpoints
+// and returns a violation for each conflict:
+//
+//	ip-address-in-use   same address on the same VLAN
+//	subnet-overlap      candidate's address or subnet is in a subnet used
+//	                    on another VLAN (or the reverse)
+//	subnet-mismatch     same VLAN, overlapping but different subnets
+//
+// Endpoints with candidate's ResourceID are skipped (the device itself).
+func CheckNetworkOverlap(candidate NetworkEndpoint, others []NetworkEndpoint) Violations {
+	var violations Violations
+	for _, other := range others {
+		if other.ResourceID == candidate.ResourceID {
+			continue
+		}
+		if other.VLAN == candidate.VLAN {
+			if other.IP.Equal(candidate.IP) {
+				violations = append(violations, Violation{
+					Field: "spec.config.ip_address",
+					Rule:  "ip-address-in-use",
+					Message: fmt.Sprintf("%s is already used on %s by %s",
+						canonicalIP(candidate.IP), candidate.vlanName(), other.owner()),
+				})
+			}
+			if candidate.Subnet != nil && other.Subnet != nil &&
+				candidate.Subnet.String() != other.Subnet.String() && networksOverlap(candidate.Subnet, other.Subnet) {
+				violations = append(violations, Violation{
+					Field: "spec.config.subnet",
+					Rule:  "subnet-mismatch",
+					Message: fmt.Sprintf("%s overlaps %s, which %s uses on %s; devices on one VLAN need the same subnet",
+						candidate.Subnet, other.Subnet, other.owner(), other.vlanName()),
+				})
+			}
+			continue
+		}
+
+		switch {
+		case candidate.Subnet != nil && other.Subnet != nil && networksOverlap(candidate.Subnet, other.Subnet):
+			violations = append(violations, Violation{
+				Field: "spec.config.subnet",
+				Rule:  "subnet-overlap",
+				Message: fmt.Sprintf("%s overlaps %s on %s (%s); a subnet can only be on one VLAN",
+					candidate.Subnet, other.Subnet, other.vlanName(), other.owner()),
+			})
+		case other.Subnet != nil && other.Subnet.Contains(candidate.IP):
+			violations = append(violations, Violation{
+				Field: "spec.config.ip_address",
+				Rule:  "subnet-overlap",
+				Message: fmt.Sprintf("%s is in %s, which is on %s (%s)",
+					canonicalIP(candidate.IP), other.Subnet, other.vlanName(), other.owner()),
+			})
+		case candidate.Subnet != nil && candidate.Subnet.Contains(other.IP):
+			violations = append(violations, Violation{
+				Field: "spec.config.subnet",
+				Rule:  "subnet-overlap",
+				Message: fmt.Sprintf("%s contains %s, which %s uses on %s",
+					candidate.Subnet, canonicalIP(other.IP), other.owner(), other.vlanName()),
+			})
+		}
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		if violations[i].Field != violations[j].Field {
+			return violations[i].Field < violations[j].Field
+		}
+		return violations[i].Rule < violations[j].Rule
+	})
+	return violations
+}
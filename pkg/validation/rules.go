@@ -0,0 +1,323 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// =============================================================================
+// DECLARATIVE RULES ENGINE
+// =============================================================================
+// Cross-field rules ("X requires Y", "X only makes sense with Y") are
+// written as data instead of nested if-statements:
+//
+//   Rule{
+//       Name:    "recording-path-required",
+//       Field:   "spec.recording_path",
+//       When:    IsTrue("spec.recording_enabled"),
+//       Require: Present("spec.recording_path"),
+//       Message: "recording_path is required when recording_enabled is true",
+//   }
+//
+// Rules are evaluated against a Document: the object flattened into
+// dotted JSON paths ("spec.config.tally_protocol" → "IP"). Paths therefore
+// match exactly what API clients send, and every violation names the
+// field to fix.
+//
+// WHY ALL VIOLATIONS AT ONCE: Fixing one error only to be told about the
+// next is a miserable API experience. Validate returns every violation.
+// =============================================================================
+
+// Violation describes one failed rule.
+type Violation struct {
+	// Field is the dotted JSON path of the offending field.
+	Field string `json:"field"`
+
+	// Rule is the name of the rule that failed.
+	Rule string `json:"rule"`
+
+	// Message explains the problem in user terms.
+	Message string `json:"message"`
+}
+
+// Violations is a list of violations; it implements error.
+type Violations []Violation
+
+func (v Violations) Error() string {
+	msgs := make([]string, len(v))
+	for i, violation := range v {
+		msgs[i] = violation.Field + ": " + violation.Message
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Document is an object flattened into dotted JSON paths.
+type Document map[string]interface{}
+
+// NewDocument flattens v (anything JSON-serializable) under prefix.
+func NewDocument(prefix string, v interface{}) (Document, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode document: %w", err)
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, fmt.Errorf("failed to decode document: %w", err)
+	}
+	doc := make(Document)
+	flatten(prefix, generic, doc)
+	return doc, nil
+}
+
+func flatten(prefix string, v interface{}, out Document) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		if v != nil {
+			out[prefix] = v
+		}
+		return
+	}
+	for key, child := range obj {
+		flatten(prefix+"."+key, child, out)
+	}
+}
+
+// Condition is a predicate over a Document.
+type Condition func(doc Document) bool
+
+// Rule is one declarative validation rule: When holds, Require must hold.
+type Rule struct {
+	// Name identifies the rule in violations (kebab-case).
+	Name string
+
+	// Field is the path reported in the violation.
+	Field string
+
+	// When gates the rule; nil means always.
+	When Condition
+
+	// Require must hold whenever When does.
+	Require Condition
+
+	// Message is returned to the user when the rule fails.
+	Message string
+}
+
+// Validate evaluates all rules against doc and returns every violation,
+// sorted by field then rule name. Returns nil if doc is valid.
+func Validate(doc Document, rules []Rule) Violations {
+	var violations Violations
+	for _, rule := range rules {
+		if rule.When != nil && !rule.When(doc) {
+			continue
+		}
+		if !rule.Require(doc) {
+			violations = append(violations, Violation{Field: rule.Field, Rule: rule.Name, Message: rule.Message})
+		}
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		if violations[i].Field != violations[j].Field {
+			return violations[i].Field < violations[j].Field
+		}
+		return violations[i].Rule < violations[j].Rule
+	})
+	return violations
+}
+
+// =============================================================================
+// CONDITIONS
+// =============================================================================
+
+// Present holds when path is set to a non-empty value.
+func Present(path string) Condition {
+	return func(doc Document) bool {
+		v, ok := doc[path]
+		if !ok {
+			return false
+		}
+		if s, isString := v.(string); isString {
+			return strings.TrimSpace(s) != ""
+		}
+		return true
+	}
+}
+
+// IsTrue holds when path is the boolean true.
+func IsTrue(path string) Condition {
+	return func(doc Document) bool {
+		b, ok := doc[path].(bool)
+		return ok && b
+	}
+}
+
+// EqualsFold holds when path is a string equal to value, ignoring case.
+func EqualsFold(path, value string) Condition {
+	return func(doc Document) bool {
+		s, ok := doc[path].(string)
+		return ok && strings.EqualFold(s, value)
+	}
+}
+
+// OneOfFold holds when path is a string equal to one of values, ignoring case.
+func OneOfFold(path string, values ...string) Condition {
+	return func(doc Document) bool {
+		s, ok := doc[path].(string)
+		if !ok {
+			return false
+		}
+		for _, v := range values {
+			if strings.EqualFold(s, v) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// HasPrefixFold holds when path is a string starting with prefix, ignoring case.
+func HasPrefixFold(path, prefix string) Condition {
+	return func(doc Document) bool {
+		s, ok := doc[path].(string)
+		return ok && len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+	}
+}
+
+// Matches holds when path is a string matching pattern.
+func Matches(path string, pattern *regexp.Regexp) Condition {
+	return func(doc Document) bool {
+		s, ok := doc[path].(string)
+		return ok && pattern.MatchString(s)
+	}
+}
+
+// InRange holds when path is a number (or numeric string) in [min, max].
+func InRange(path string, min, max float64) Condition {
+	return func(doc Document) bool {
+		n, ok := number(doc[path])
+		return ok && n >= min && n <= max
+	}
+}
+
+// IsInteger holds when path is a whole number (or numeric string).
+func IsInteger(path string) Condition {
+	return func(doc Document) bool {
+		n, ok := number(doc[path])
+		return ok && n == math.Trunc(n)
+	}
+}
+
+// IsIP holds when path is an IPv4 or IPv6 address.
+func IsIP(path string) Condition {
+	return func(doc Document) bool {
+		s, ok := doc[path].(string)
+		return ok && net.ParseIP(strings.TrimSpace(s)) != nil
+	}
+}
+
+// IsIPv6 holds when path is an IPv6 (not IPv4 or IPv4-mapped) address.
+func IsIPv6(path string) Condition {
+	return func(doc Document) bool {
+		s, ok := doc[path].(string)
+		if !ok {
+			return false
+		}
+		ip := net.ParseIP(strings.TrimSpace(s))
+		return ip != nil && ip.To4() == nil
+	}
+}
+
+// IsCIDR holds when path is a network in CIDR notation ("10.0.1.0/24").
+func IsCIDR(path string) Condition {
+	return func(doc Document) bool {
+		s, ok := doc[path].(string)
+		if !ok {
+			return false
+		}
+		_, _, err := net.ParseCIDR(strings.TrimSpace(s))
+		return err == nil
+	}
+}
+
+// IPInNetwork holds when the address at ipPath is inside the CIDR network
+// at networkPath.
+func IPInNetwork(ipPath, networkPath string) Condition {
+	return func(doc Document) bool {
+		addr, _ := doc[ipPath].(string)
+		cidr, _ := doc[networkPath].(string)
+		ip := net.ParseIP(strings.TrimSpace(addr))
+		_, network, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		return ip != nil && err == nil && network.Contains(ip)
+	}
+}
+
+// LengthBetween holds when path is a string of min..max characters.
+func LengthBetween(path string, min, max int) Condition {
+	return func(doc Document) bool {
+		s, ok := doc[path].(string)
+		return ok && len(s) >= min && len(s) <= max
+	}
+}
+
+// Not negates a condition.
+func Not(c Condition) Condition {
+	return func(doc Document) bool { return !c(doc) }
+}
+
+// All holds when every condition holds.
+func All(conds ...Condition) Condition {
+	return func(doc Document) bool {
+		for _, c := range conds {
+			if !c(doc) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Any holds when at least one condition holds.
+func Any(conds ...Condition) Condition {
+	return func(doc Document) bool {
+		for _, c := range conds {
+			if c(doc) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// AtMostOne holds when no more than one of paths is present.
+// Use it for mutually exclusive fields.
+func AtMostOne(paths ...string) Condition {
+	return func(doc Document) bool {
+		count := 0
+		for _, path := range paths {
+			if Present(path)(doc) {
+				count++
+			}
+		}
+		return count <= 1
+	}
+}
+
+// number converts JSON numbers, ints and numeric strings to float64.
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
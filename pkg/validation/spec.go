@@ -0,0 +1,158 @@
+package validation
+
+import (
+	"strings"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// SRT latency bounds in milliseconds, as accepted by libsrt.
+const (
+	MinSRTLatency = 20
+	MaxSRTLatency = 8000
+)
+
+// Accepted sync references (see ResourceSpec.TimecodeSource and
+// GenlockReference).
+var (
+	TimecodeSources   = []string{"internal", "ltc", "vitc", "ntp", "ptp"}
+	GenlockReferences = []string{"internal", "blackburst", "tri-level", "ptp"}
+)
+
+// isSRT holds when the stream destination uses the SRT protocol.
+// Providers detect the protocol from the URL scheme, so the rules do too.
+var isSRT = HasPrefixFold("spec.stream_url", "srt://")
+
+// SpecRules are the cross-field rules every ResourceSpec must satisfy.
+//
+// WHY HERE (not in providers): These are properties of the Forge API
+// itself. Rejecting them up front means the vendor never receives a
+// request it would half-apply or silently ignore.
+var SpecRules = []Rule{
+	{
+		Name:    "recording-path-required",
+		Field:   "spec.recording_path",
+		When:    IsTrue("spec.recording_enabled"),
+		Require: Present("spec.recording_path"),
+		Message: "recording_path is required when recording_enabled is true",
+	},
+	{
+		Name:    "srt-latency-required",
+		Field:   "spec.config.srt_latency",
+		When:    isSRT,
+		Require: Present("spec.config.srt_latency"),
+		Message: "srt_latency (milliseconds) is required for srt:// stream URLs",
+	},
+	{
+		Name:    "srt-latency-range",
+		Field:   "spec.config.srt_latency",
+		When:    All(isSRT, Present("spec.config.srt_latency")),
+		Require: InRange("spec.config.srt_latency", MinSRTLatency, MaxSRTLatency),
+		Message: "srt_latency must be a number between 20 and 8000 milliseconds",
+	},
+	{
+		Name:    "srt-passphrase-requires-srt",
+		Field:   "spec.config.srt_passphrase",
+		When:    Present("spec.config.srt_passphrase"),
+		Require: isSRT,
+		Message: "srt_passphrase is only valid with an srt:// stream URL",
+	},
+	{
+		// WHY 10-79: SRT rejects passphrases outside this range at connect
+		// time, long after the device has been provisioned
+		Name:    "srt-passphrase-length",
+		Field:   "spec.config.srt_passphrase",
+		When:    Present("spec.config.srt_passphrase"),
+		Require: LengthBetween("spec.config.srt_passphrase", 10, 79),
+		Message: "srt_passphrase must be 10 to 79 characters",
+	},
+	{
+		Name:    "tally-address-required",
+		Field:   "spec.config.tally_address",
+		When:    EqualsFold("spec.config.tally_protocol", "IP"),
+		Require: Present("spec.config.tally_address"),
+		Message: "tally_address is required when tally_protocol is IP",
+	},
+	{
+		Name:    "timecode-source-values",
+		Field:   "spec.timecode_source",
+		When:    Present("spec.timecode_source"),
+		Require: OneOfFold("spec.timecode_source", TimecodeSources...),
+		Message: "timecode_source must be one of: " + strings.Join(TimecodeSources, ", "),
+	},
+	{
+		Name:    "ntp-server-required",
+		Field:   "spec.ntp_server",
+		When:    EqualsFold("spec.timecode_source", "ntp"),
+		Require: Present("spec.ntp_server"),
+		Message: "ntp_server is required when timecode_source is ntp",
+	},
+	{
+		Name:    "ip-address-format",
+		Field:   "spec.config.ip_address",
+		When:    Present("spec.config.ip_address"),
+		Require: IsIP("spec.config.ip_address"),
+		Message: "ip_address must be an IPv4 or IPv6 address (e.g. 10.0.1.50 or fd00::50)",
+	},
+	{
+		Name:    "subnet-format",
+		Field:   "spec.config.subnet",
+		When:    Present("spec.config.subnet"),
+		Require: IsCIDR("spec.config.subnet"),
+		Message: "subnet must be in CIDR notation (e.g. 10.0.1.0/24)",
+	},
+	{
+		Name:    "ip-address-in-subnet",
+		Field:   "spec.config.ip_address",
+		When:    All(IsIP("spec.config.ip_address"), IsCIDR("spec.config.subnet")),
+		Require: IPInNetwork("spec.config.ip_address", "spec.config.subnet"),
+		Message: "ip_address must be inside subnet",
+	},
+	{
+		Name:    "port-range",
+		Field:   "spec.config.port",
+		When:    Present("spec.config.port"),
+		Require: All(IsInteger("spec.config.port"), InRange("spec.config.port", MinPort, MaxPort)),
+		Message: "port must be a whole number between 1 and 65535",
+	},
+	{
+		// WHY NOT 0 OR 4095: Reserved by 802.1Q; untagged traffic is
+		// configured by leaving vlan_id out
+		Name:    "vlan-id-range",
+		Field:   "spec.config.vlan_id",
+		When:    Present("spec.config.vlan_id"),
+		Require: All(IsInteger("spec.config.vlan_id"), InRange("spec.config.vlan_id", MinVLANID, MaxVLANID)),
+		Message: "vlan_id must be a whole number between 1 and 4094",
+	},
+	{
+		Name:    "mtu-range",
+		Field:   "spec.config.mtu",
+		When:    Present("spec.config.mtu"),
+		Require: All(IsInteger("spec.config.mtu"), InRange("spec.config.mtu", MinMTU, MaxMTU)),
+		Message: "mtu must be a whole number between 576 and 9216 (1500 standard, 9000 jumbo frames)",
+	},
+	{
+		Name:    "mtu-ipv6-minimum",
+		Field:   "spec.config.mtu",
+		When:    All(IsIPv6("spec.config.ip_address"), Present("spec.config.mtu")),
+		Require: InRange("spec.config.mtu", MinIPv6MTU, MaxMTU),
+		Message: "mtu must be at least 1280 for an IPv6 ip_address",
+	},
+	{
+		Name:    "genlock-reference-values",
+		Field:   "spec.genlock_reference",
+		When:    Present("spec.genlock_reference"),
+		Require: OneOfFold("spec.genlock_reference", GenlockReferences...),
+		Message: "genlock_reference must be one of: " + strings.Join(GenlockReferences, ", "),
+	},
+}
+
+// ValidateSpec checks spec against SpecRules and its ladder (see
+// ValidateLadder) and returns every violation (nil if the spec is valid).
+func ValidateSpec(spec models.ResourceSpec) Violations {
+	doc, err := NewDocument("spec", spec)
+	if err != nil {
+		return Violations{{Field: "spec", Rule: "encodable", Message: err.Error()}}
+	}
+	return append(Validate(doc, SpecRules), ValidateLadder(spec)...)
+}
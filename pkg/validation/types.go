@@ -0,0 +1,88 @@
+package validation
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Video parameter bounds.
+const (
+	// MinBitrate and MaxBitrate bound spec.bitrate in bits per second
+	// (64 kbps is a thumbnail stream; 200 Mbps covers 8K contribution)
+	MinBitrate = 64_000
+	MaxBitrate = 200_000_000
+
+	MinFrameRate = 1
+	MaxFrameRate = 120
+)
+
+// Accepted video parameters. Resolutions are the names providers map to
+// vendor formats; pixel dimensions ("1920x1080") pass through too.
+var (
+	Resolutions  = []string{"SD", "HD", "FHD", "4K", "UHD", "8K", "480p", "720p", "1080p", "2160p", "4320p"}
+	Codecs       = []string{"H.264", "H.265", "H.265/HEVC", "HEVC", "AV1", "ProRes", "DNxHD"}
+	LatencyModes = []string{"low", "normal", "high"}
+)
+
+// pixelResolution matches "WIDTHxHEIGHT".
+var pixelResolution = regexp.MustCompile(`^[1-9][0-9]{2,4}x[1-9][0-9]{2,4}$`)
+
+// VideoRules apply to resources that produce video.
+var VideoRules = RuleSet{
+	{
+		Name:    "resolution-values",
+		Field:   "spec.resolution",
+		When:    Present("spec.resolution"),
+		Require: Any(OneOfFold("spec.resolution", Resolutions...), Matches("spec.resolution", pixelResolution)),
+		Message: "resolution must be one of " + strings.Join(Resolutions, ", ") + ", or WIDTHxHEIGHT (e.g. 1920x1080)",
+	},
+	{
+		Name:    "bitrate-range",
+		Field:   "spec.bitrate",
+		When:    Present("spec.bitrate"),
+		Require: InRange("spec.bitrate", MinBitrate, MaxBitrate),
+		Message: "bitrate must be between 64000 and 200000000 bits per second",
+	},
+	{
+		Name:    "frame-rate-range",
+		Field:   "spec.frame_rate",
+		When:    Present("spec.frame_rate"),
+		Require: InRange("spec.frame_rate", MinFrameRate, MaxFrameRate),
+		Message: "frame_rate must be between 1 and 120 frames per second",
+	},
+	{
+		Name:    "codec-values",
+		Field:   "spec.codec",
+		When:    Present("spec.codec"),
+		Require: OneOfFold("spec.codec", Codecs...),
+		Message: "codec must be one of: " + strings.Join(Codecs, ", "),
+	},
+	{
+		Name:    "latency-mode-values",
+		Field:   "spec.latency_mode",
+		When:    Present("spec.latency_mode"),
+		Require: OneOfFold("spec.latency_mode", LatencyModes...),
+		Message: "latency_mode must be one of: " + strings.Join(LatencyModes, ", "),
+	},
+}
+
+// ContributionRules apply to resources that send their stream off site.
+var ContributionRules = RuleSet{
+	{
+		// WHY: An unencrypted SRT stream to a public ingest can be picked
+		// up by anyone who learns the address
+		Name:    "srt-passphrase-required",
+		Field:   "spec.config.srt_passphrase",
+		When:    isSRT,
+		Require: Present("spec.config.srt_passphrase"),
+		Message: "srt_passphrase is required for srt:// stream URLs of this resource type",
+	},
+}
+
+// TypeRules are the built-in validators by resource type.
+var TypeRules = map[string]RuleSet{
+	"camera":        VideoRules,
+	"video-stream":  VideoRules,
+	"encoder":       append(append(RuleSet{}, VideoRules...), ContributionRules...),
+	"cloud-channel": append(append(RuleSet{}, VideoRules...), ContributionRules...),
+}
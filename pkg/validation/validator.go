@@ -0,0 +1,104 @@
+package validation
+
+import (
+	"sort"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/models"
+)
+
+// =============================================================================
+// PER-TYPE VALIDATORS
+// =============================================================================
+// SpecRules hold for every resource. What else a spec must satisfy depends
+// on what the resource is: a camera's bitrate and resolution mean
+// something, a recorder's don't. A Validator checks the specs of one
+// resource type, and a Registry runs the right ones:
+//
+//   registry := validation.DefaultRegistry()
+//   registry.Register("encoder", validation.RuleSet(myRules))
+//   violations := registry.Validate("encoder", spec)
+//
+// Validate runs SpecRules, then every validator registered for the type,
+// and returns all violations sorted by field, like Validate. Types without
+// validators only get SpecRules.
+// =============================================================================
+
+// Validator checks a spec and returns every violation (nil if valid).
+type Validator interface {
+	ValidateSpec(spec models.ResourceSpec) Violations
+}
+
+// ValidatorFunc adapts a function to a Validator.
+type ValidatorFunc func(spec models.ResourceSpec) Violations
+
+// ValidateSpec calls f.
+func (f ValidatorFunc) ValidateSpec(spec models.ResourceSpec) Violations {
+	return f(spec)
+}
+
+// RuleSet is a Validator made of declarative rules.
+type RuleSet []Rule
+
+// ValidateSpec evaluates the rules against spec.
+func (rs RuleSet) ValidateSpec(spec models.ResourceSpec) Violations {
+	doc, err := NewDocument("spec", spec)
+	if err != nil {
+		return Violations{{Field: "spec", Rule: "encodable", Message: err.Error()}}
+	}
+	return Validate(doc, rs)
+}
+
+// Registry maps resource types to their validators.
+//
+// WHY NOT LOCKED: Validators are registered at startup, before the
+// controller serves requests; after that the registry is only read.
+type Registry struct {
+	byType map[string][]Validator
+}
+
+// NewRegistry returns a registry without validators: only SpecRules apply.
+func NewRegistry() *Registry {
+	return &Registry{byType: make(map[string][]Validator)}
+}
+
+// DefaultRegistry returns a registry with the built-in validators
+// (see TypeRules).
+func DefaultRegistry() *Registry {
+	registry := NewRegistry()
+	for resourceType, rules := range TypeRules {
+		registry.Register(resourceType, rules)
+	}
+	return registry
+}
+
+// Register adds v to the validators of resourceType.
+func (r *Registry) Register(resourceType string, v Validator) {
+	r.byType[resourceType] = append(r.byType[resourceType], v)
+}
+
+// Types returns the resource types with validators, sorted.
+func (r *Registry) Types() []string {
+	types := make([]string, 0, len(r.byType))
+	for resourceType := range r.byType {
+		types = append(types, resourceType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Validate checks spec against SpecRules and the validators of
+// resourceType, and returns every violation sorted by field then rule
+// (nil if the spec is valid).
+func (r *Registry) Validate(resourceType string, spec models.ResourceSpec) Violations {
+	violations := ValidateSpec(spec)
+	for _, v := range r.byType[resourceType] {
+		violations = append(violations, v.ValidateSpec(spec)...)
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		if violations[i].Field != violations[j].Field {
+			return violations[i].Field < violations[j].Field
+		}
+		return violations[i].Rule < violations[j].Rule
+	})
+	return violations
+}
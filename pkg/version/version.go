@@ -0,0 +1,220 @@
+package version
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// =============================================================================
+// VERSION AND BUILD INFO
+// =============================================================================
+// Mixed-version deployments (a new CLI against an old controller, two
+// controller builds behind one ingress) fail in confusing ways: unknown
+// fields are dropped, new query parameters ignored. The controller
+// reports what it is at GET /version, and clients compare it with what
+// they are before doing anything else:
+//
+//	info, err := version.Fetch(ctx, http.DefaultClient, "http://forge:8080")
+//	compat := version.Check(*info, version.Client{Version: version.Version, APIVersion: "v1"})
+//	if compat.Err != nil { ... }                  // refuses to talk to it
+//	for _, w := range compat.Warnings { ... }     // skew worth a warning
+//
+// Release builds stamp the version at link time:
+//
+//	go build -ldflags "-X github.com/Zhichengu1/mock-control-plane/pkg/version.Version=1.4.0 \
+//	  -X github.com/Zhichengu1/mock-control-plane/pkg/version.BuildDate=2026-10-16T00:00:00Z" ./cmd/controller
+//
+// Without -ldflags, Commit comes from the VCS stamp go build adds inside a
+// git checkout, and BuildDate falls back to that commit's time.
+// =============================================================================
+
+// Set with -ldflags "-X .../pkg/version.Version=..." (see above).
+var (
+	// Version is the semantic version of the build ("dev" = unreleased)
+	Version = "dev"
+
+	// Commit is the git commit the build was made from
+	Commit = ""
+
+	// BuildDate is when the build was made (RFC 3339)
+	BuildDate = ""
+)
+
+// Info describes a controller build. It is the body of GET /version.
+type Info struct {
+	// Version is the semantic version ("1.4.0") or "dev"
+	Version string `json:"version"`
+
+	// Commit is the git commit ("" = unknown); Modified marks a build
+	// from a tree with uncommitted changes
+	Commit   string `json:"commit,omitempty"`
+	Modified bool   `json:"modified,omitempty"`
+
+	// BuildDate is when the binary was built ("" = unknown)
+	BuildDate string `json:"build_date,omitempty"`
+
+	// GoVersion is the Go toolchain that built it
+	GoVersion string `json:"go_version"`
+
+	// APIVersions lists the HTTP API versions served, oldest first;
+	// CurrentAPIVersion is the one unversioned paths alias
+	APIVersions       []string `json:"api_versions"`
+	CurrentAPIVersion string   `json:"current_api_version"`
+}
+
+// Get returns the running binary's Info, serving the given API versions.
+func Get(apiVersions []string, current string) Info {
+	info := Info{
+		Version:           Version,
+		Commit:            Commit,
+		BuildDate:         BuildDate,
+		GoVersion:         runtime.Version(),
+		APIVersions:       apiVersions,
+		CurrentAPIVersion: current,
+	}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+	return info
+}
+
+// Client describes the SDK or CLI talking to a controller.
+type Client struct {
+	// Version is the client's semantic version ("dev" = unreleased)
+	Version string
+
+	// APIVersion is the HTTP API version the client speaks ("v1")
+	APIVersion string
+}
+
+// Compatibility is the outcome of Check.
+type Compatibility struct {
+	// Err is set when the client can't work with the controller at all
+	Err error
+
+	// Warnings describe version skew that may cause surprises
+	Warnings []string
+}
+
+// Check compares a client with the controller described by server.
+//
+// The client is refused (Err) when the controller doesn't serve its API
+// version. Otherwise, differing major versions and a client newer than
+// the controller are warned about: the client may send what the
+// controller doesn't understand yet. Dev builds skip the version
+// comparison.
+func Check(server Info, client Client) Compatibility {
+	var compat Compatibility
+	served := false
+	for _, v := range server.APIVersions {
+		served = served || v == client.APIVersion
+	}
+	if !served {
+		compat.Err = fmt.Errorf("controller %s serves API versions %s, not %s; upgrade the %s",
+			server.Version, strings.Join(server.APIVersions, ", "), client.APIVersion, olderSide(server.Version, client.Version))
+		return compat
+	}
+	serverVersion, serverOK := parse(server.Version)
+	clientVersion, clientOK := parse(client.Version)
+	if !serverOK || !clientOK {
+		return compat
+	}
+	switch {
+	case serverVersion[0] != clientVersion[0]:
+		compat.Warnings = append(compat.Warnings, fmt.Sprintf("client %s and controller %s differ in major version", client.Version, server.Version))
+	case compare(clientVersion, serverVersion) > 0:
+		compat.Warnings = append(compat.Warnings, fmt.Sprintf("client %s is newer than controller %s; newer options may be ignored or refused", client.Version, server.Version))
+	}
+	return compat
+}
+
+// olderSide names which side an upgrade should target.
+func olderSide(server, client string) string {
+	serverVersion, serverOK := parse(server)
+	clientVersion, clientOK := parse(client)
+	if serverOK && clientOK && compare(serverVersion, clientVersion) < 0 {
+		return "controller"
+	}
+	return "client"
+}
+
+// parse reads "1.4.0" or "v1.4.0" (pre-release and build suffixes are
+// ignored). It reports false for "dev" and other non-versions.
+func parse(v string) ([3]int, bool) {
+	var parsed [3]int
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return parsed, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return parsed, false
+		}
+		parsed[i] = n
+	}
+	return parsed, true
+}
+
+// compare orders two parsed versions.
+func compare(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// Fetch reads a controller's Info from GET {baseURL}/version.
+func Fetch(ctx context.Context, client *http.Client, baseURL string) (*Info, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(baseURL, "/")+"/version", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read controller version: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		// WHY AN ERROR: A controller without /version predates it; that
+		// is skew too
+		return nil, fmt.Errorf("failed to read controller version: GET /version returned %d", resp.StatusCode)
+	}
+	var info Info
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("failed to decode controller version: %w", err)
+	}
+	return &info, nil
+}
@@ -0,0 +1,218 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zhichengu1/mock-control-plane/pkg/clock"
+)
+
+// =============================================================================
+// SIGNED INCOMING WEBHOOKS
+// =============================================================================
+// Verifies requests that external systems push to us (vendor callbacks,
+// admission webhooks). Each sender ("source") shares a secret with the
+// controller and signs every request:
+//
+//	X-Forge-Source:    sony
+//	X-Forge-Timestamp: 1767225600            (Unix seconds)
+//	X-Forge-Nonce:     8f14e45fceea167a      (unique per request)
+//	X-Forge-Signature: v1=<hex HMAC-SHA256(secret, timestamp + "." + nonce + "." + body)>
+//
+// A request is rejected if the signature doesn't match (forged or
+// altered), the timestamp is outside the window (old captured request),
+// or the nonce was already seen within the window (replayed request).
+//
+// POST /callbacks/{vendor} is wrapped in Middleware, with each vendor's
+// secret from the controller's secrets backend (see
+// cmd/controller/callbacks.go). There are no admission webhooks in this
+// tree; one would wrap itself the same way, as another source.
+// =============================================================================
+
+// Header names used for signing.
+const (
+	HeaderSource    = "X-Forge-Source"
+	HeaderTimestamp = "X-Forge-Timestamp"
+	HeaderNonce     = "X-Forge-Nonce"
+	HeaderSignature = "X-Forge-Signature"
+)
+
+// DefaultWindow is how far a timestamp may be from now (either direction).
+// WHY 5 MINUTES: Tolerates clock skew and sender retries while keeping the
+// nonce cache small.
+const DefaultWindow = 5 * time.Minute
+
+// maxBodyBytes bounds the body read for verification.
+const maxBodyBytes = 1 << 20 // 1 MiB
+
+// Verification errors.
+var (
+	ErrMissingHeaders = errors.New("missing signature headers")
+	ErrUnknownSource  = errors.New("unknown source")
+	ErrBadSignature   = errors.New("signature mismatch")
+	ErrStale          = errors.New("timestamp outside the allowed window")
+	ErrReplayed       = errors.New("nonce already used")
+)
+
+// Secrets looks up the shared secret of a source.
+type Secrets interface {
+	// Secret returns the source's secret, or false if the source is unknown.
+	Secret(source string) ([]byte, bool)
+}
+
+// StaticSecrets is a fixed set of secrets keyed by source.
+type StaticSecrets map[string][]byte
+
+// Secret implements Secrets.
+func (s StaticSecrets) Secret(source string) ([]byte, bool) {
+	secret, ok := s[source]
+	return secret, ok && len(secret) > 0
+}
+
+// Verifier checks signatures and tracks nonces. Safe for concurrent use.
+type Verifier struct {
+	secrets Secrets
+	window  time.Duration
+	clock   clock.Clock
+
+	mu        sync.Mutex
+	nonces    map[string]time.Time // source + "/" + nonce → expiry
+	lastPrune time.Time
+}
+
+// NewVerifier creates a Verifier. window <= 0 uses DefaultWindow; a nil
+// clock uses the system clock.
+func NewVerifier(secrets Secrets, window time.Duration, clk clock.Clock) *Verifier {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	if clk == nil {
+		clk = clock.Real{}
+	}
+	return &Verifier{secrets: secrets, window: window, clock: clk, nonces: make(map[string]time.Time)}
+}
+
+// Sign returns the X-Forge-Signature value for a request. Senders (and
+// tests) use it; Verify uses it to compute the expected value.
+func Sign(secret []byte, timestamp, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write([]byte(nonce))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a request's signature, timestamp and nonce. It returns the
+// authenticated source; the body is restored so handlers can read it.
+func (v *Verifier) Verify(r *http.Request) (string, error) {
+	source := r.Header.Get(HeaderSource)
+	timestamp := r.Header.Get(HeaderTimestamp)
+	nonce := r.Header.Get(HeaderNonce)
+	signature := r.Header.Get(HeaderSignature)
+	if source == "" || timestamp == "" || nonce == "" || signature == "" {
+		return "", ErrMissingHeaders
+	}
+
+	secret, ok := v.secrets.Secret(source)
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownSource, source)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes+1))
+	r.Body.Close()
+	if err != nil {
+		return "", fmt.Errorf("reading body: %w", err)
+	}
+	if len(body) > maxBodyBytes {
+		return "", fmt.Errorf("body exceeds %d bytes", maxBodyBytes)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	// Step 1: Signature first, so unauthenticated requests can't fill the
+	// nonce cache. Several "v1=" values may be sent during secret rotation.
+	valid := false
+	expected := Sign(secret, timestamp, nonce, body)
+	for _, candidate := range strings.Split(signature, ",") {
+		if hmac.Equal([]byte(strings.TrimSpace(candidate)), []byte(expected)) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return "", ErrBadSignature
+	}
+
+	// Step 2: Timestamp window
+	secs, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid timestamp %q", ErrStale, timestamp)
+	}
+	now := v.clock.Now()
+	skew := now.Sub(time.Unix(secs, 0))
+	if skew > v.window || skew < -v.window {
+		return "", fmt.Errorf("%w (off by %s)", ErrStale, skew.Round(time.Second))
+	}
+
+	// Step 3: Nonce. WHY EXPIRE AFTER 2×WINDOW: A request is only accepted
+	// while its timestamp is within ±window, so its nonce can be forgotten
+	// once the timestamp can no longer pass Step 2.
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.pruneLocked(now)
+	key := source + "/" + nonce
+	if expiry, seen := v.nonces[key]; seen && now.Before(expiry) {
+		return "", ErrReplayed
+	}
+	v.nonces[key] = now.Add(2 * v.window)
+	return source, nil
+}
+
+// pruneLocked drops expired nonces, at most once per window.
+func (v *Verifier) pruneLocked(now time.Time) {
+	if now.Sub(v.lastPrune) < v.window {
+		return
+	}
+	for key, expiry := range v.nonces {
+		if !now.Before(expiry) {
+			delete(v.nonces, key)
+		}
+	}
+	v.lastPrune = now
+}
+
+type sourceKey struct{}
+
+// SourceFrom returns the source authenticated by Middleware.
+func SourceFrom(ctx context.Context) (string, bool) {
+	source, ok := ctx.Value(sourceKey{}).(string)
+	return source, ok
+}
+
+// Middleware rejects requests that fail verification with 401 and passes
+// the authenticated source to next via the request context.
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		source, err := v.Verify(r)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "webhook verification failed: " + err.Error()})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), sourceKey{}, source)))
+	})
+}